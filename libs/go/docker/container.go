@@ -1,6 +1,7 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -15,6 +16,7 @@ import (
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/api/types/mount"
 	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 )
 
@@ -31,6 +33,42 @@ type ContainerConfig struct {
 	AutoRemove bool
 	Privileged bool
 	OpenStdin  bool
+	Resources  ContainerResources
+}
+
+// ContainerResources limits a container's resource usage. Zero values are unlimited.
+type ContainerResources struct {
+	NanoCPUs    int64 // CPU quota in units of 1e-9 CPUs
+	MemoryBytes int64 // Hard memory limit; swap is disabled when set
+	PidsLimit   int64
+	Ulimits     []Ulimit
+}
+
+// Ulimit is a named process limit, e.g. nofile
+type Ulimit struct {
+	Name string
+	Soft int64
+	Hard int64
+}
+
+// toDocker converts the limits into the Docker API representation
+func (r ContainerResources) toDocker() container.Resources {
+	resources := container.Resources{
+		NanoCPUs: r.NanoCPUs,
+		Memory:   r.MemoryBytes,
+	}
+	if r.MemoryBytes > 0 {
+		// Equal to Memory disables swap, so the limit is a real cap
+		resources.MemorySwap = r.MemoryBytes
+	}
+	if r.PidsLimit > 0 {
+		pids := r.PidsLimit
+		resources.PidsLimit = &pids
+	}
+	for _, u := range r.Ulimits {
+		resources.Ulimits = append(resources.Ulimits, &container.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	return resources
 }
 
 // CreateContainer creates a new Docker container
@@ -108,6 +146,7 @@ func (c *Client) CreateContainer(ctx context.Context, config ContainerConfig) (s
 		AutoRemove:    config.AutoRemove,
 		Privileged:    config.Privileged,
 		RestartPolicy: container.RestartPolicy{Name: "no"},
+		Resources:     config.Resources.toDocker(),
 	}
 
 	var networkingConfig *network.NetworkingConfig
@@ -204,6 +243,18 @@ func (c *Client) GetContainerStatus(ctx context.Context, containerID string) (*C
 		Running:     info.State.Running,
 		ExitCode:    info.State.ExitCode,
 		Labels:      info.Config.Labels,
+		ImageID:     info.Image,
+		Env:         info.Config.Env,
+		Command:     info.Config.Cmd,
+	}
+
+	if info.NetworkSettings != nil {
+		for _, endpoint := range info.NetworkSettings.Networks {
+			if endpoint != nil && endpoint.IPAddress != "" {
+				status.IPAddress = endpoint.IPAddress
+				break
+			}
+		}
 	}
 
 	if info.State.StartedAt != "" {
@@ -240,6 +291,10 @@ type ContainerStatus struct {
 	StartedAt   *time.Time        // When container started
 	FinishedAt  *time.Time        // When container finished
 	Labels      map[string]string // Container labels
+	IPAddress   string            // IP on the container's first network; empty unless inspected
+	ImageID     string            // ID of the image the container was created from
+	Env         []string          // KEY=VALUE env, including the image's; empty unless inspected
+	Command     []string          // command the container runs; empty unless inspected
 }
 
 // ListContainers lists containers matching the given filters
@@ -265,10 +320,13 @@ func (c *Client) ListContainers(ctx context.Context, labelFilters map[string]str
 			name = cnt.Names[0]
 		}
 		statuses = append(statuses, ContainerStatus{
-			ID:      cnt.ID,
-			Name:    name,
-			Status:  cnt.Status,
-			Running: cnt.State == "running",
+			ContainerID: cnt.ID,
+			ID:          cnt.ID,
+			Name:        name,
+			Status:      cnt.Status,
+			Running:     cnt.State == "running",
+			Labels:      cnt.Labels,
+			ImageID:     cnt.ImageID,
 		})
 	}
 
@@ -320,6 +378,36 @@ func (c *Client) GetContainerLogs(ctx context.Context, containerID string, follo
 	return c.cli.ContainerLogs(ctx, containerID, options)
 }
 
+// ExecInContainer runs a command in a running container and waits for it to finish.
+// Returns the command's exit code and combined output.
+func (c *Client) ExecInContainer(ctx context.Context, containerID string, cmd []string) (int, string, error) {
+	execResp, err := c.cli.ContainerExecCreate(ctx, containerID, container.ExecOptions{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to create exec: %w", err)
+	}
+
+	attach, err := c.cli.ContainerExecAttach(ctx, execResp.ID, container.ExecAttachOptions{})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to attach to exec: %w", err)
+	}
+	defer attach.Close()
+
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, attach.Reader); err != nil {
+		return 0, "", fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := c.cli.ContainerExecInspect(ctx, execResp.ID)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to inspect exec: %w", err)
+	}
+	return inspect.ExitCode, output.String(), nil
+}
+
 // AttachToContainer attaches to a running container for stdin input only.
 // Stdout/Stderr are not attached here because container output is read
 // independently via GetContainerLogs; attaching them with no reader would
@@ -75,3 +75,34 @@ func TestVolumeMountTypeDetection(t *testing.T) {
 		})
 	}
 }
+
+func TestContainerResourcesToDocker(t *testing.T) {
+	t.Run("unset is unlimited", func(t *testing.T) {
+		r := ContainerResources{}.toDocker()
+		if r.NanoCPUs != 0 || r.Memory != 0 || r.MemorySwap != 0 || r.PidsLimit != nil || len(r.Ulimits) != 0 {
+			t.Errorf("Expected no limits, got %+v", r)
+		}
+	})
+
+	t.Run("limits are mapped", func(t *testing.T) {
+		r := ContainerResources{
+			NanoCPUs:    1_500_000_000,
+			MemoryBytes: 2 << 30,
+			PidsLimit:   512,
+			Ulimits:     []Ulimit{{Name: "nofile", Soft: 1024, Hard: 4096}},
+		}.toDocker()
+
+		if r.NanoCPUs != 1_500_000_000 {
+			t.Errorf("NanoCPUs = %d", r.NanoCPUs)
+		}
+		if r.Memory != 2<<30 || r.MemorySwap != 2<<30 {
+			t.Errorf("Expected memory and swap of %d, got %d and %d", 2<<30, r.Memory, r.MemorySwap)
+		}
+		if r.PidsLimit == nil || *r.PidsLimit != 512 {
+			t.Errorf("Expected pids limit 512, got %v", r.PidsLimit)
+		}
+		if len(r.Ulimits) != 1 || r.Ulimits[0].Name != "nofile" || r.Ulimits[0].Soft != 1024 || r.Ulimits[0].Hard != 4096 {
+			t.Errorf("Unexpected ulimits: %v", r.Ulimits)
+		}
+	})
+}
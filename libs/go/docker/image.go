@@ -0,0 +1,174 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/containerd/errdefs"
+	"github.com/distribution/reference"
+	"github.com/docker/docker/api/types/image"
+)
+
+// Image pull policies for EnsureImage
+const (
+	PullAlways    = "always"     // pull every time; fall back to the local copy if the pull fails
+	PullIfMissing = "if-missing" // pull only when there is no local copy
+)
+
+// ImageInfo describes an image in the local image store
+type ImageInfo struct {
+	ID          string
+	RepoTags    []string
+	RepoDigests []string
+	Created     time.Time
+	SizeBytes   int64
+}
+
+// EnsureImage makes imageRef available locally according to policy, trying the pull up to
+// attempts times. If every pull fails but a local copy exists, that copy is used and cached
+// is true, so a registry outage doesn't stop anything that has run on this host before.
+func (c *Client) EnsureImage(ctx context.Context, imageRef, policy string, attempts int) (cached bool, err error) {
+	exists, err := c.ImageExists(ctx, imageRef)
+	if err != nil {
+		return false, err
+	}
+	if exists && policy == PullIfMissing {
+		return true, nil
+	}
+
+	var pullErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		pullErr = c.PullImage(ctx, imageRef)
+		if pullErr == nil {
+			return false, nil
+		}
+		log.Printf("Failed to pull image %s (attempt %d/%d): %v", imageRef, attempt, attempts, pullErr)
+		if attempt < attempts {
+			select {
+			case <-ctx.Done():
+				return false, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+	}
+	if exists {
+		log.Printf("Using cached image %s after pull failures", imageRef)
+		return true, nil
+	}
+	return false, pullErr
+}
+
+// ImageExists reports whether imageRef (a reference or image ID) is in the local image store
+func (c *Client) ImageExists(ctx context.Context, imageRef string) (bool, error) {
+	_, err := c.cli.ImageInspect(ctx, imageRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to inspect image %s: %w", imageRef, err)
+	}
+	return true, nil
+}
+
+// ImageID returns the ID of the local copy of imageRef, "" if there is none
+func (c *Client) ImageID(ctx context.Context, imageRef string) (string, error) {
+	inspect, err := c.cli.ImageInspect(ctx, imageRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to inspect image %s: %w", imageRef, err)
+	}
+	return inspect.ID, nil
+}
+
+// LocalImageDigest returns the registry digest of the local copy of imageRef, "" if there
+// is no local copy or it was never pulled from a registry
+func (c *Client) LocalImageDigest(ctx context.Context, imageRef string) (string, error) {
+	inspect, err := c.cli.ImageInspect(ctx, imageRef)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to inspect image %s: %w", imageRef, err)
+	}
+	return RepoDigestFor(imageRef, inspect.RepoDigests), nil
+}
+
+// ImageIDDigest returns the registry digest imageID was pulled as for imageRef's repository.
+// Use it with ContainerStatus.ImageID to find what a container actually runs.
+func (c *Client) ImageIDDigest(ctx context.Context, imageID, imageRef string) (string, error) {
+	inspect, err := c.cli.ImageInspect(ctx, imageID)
+	if err != nil {
+		if errdefs.IsNotFound(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to inspect image %s: %w", imageID, err)
+	}
+	return RepoDigestFor(imageRef, inspect.RepoDigests), nil
+}
+
+// RemoteImageDigest asks the registry for the digest imageRef currently points at
+func (c *Client) RemoteImageDigest(ctx context.Context, imageRef string) (string, error) {
+	dist, err := c.cli.DistributionInspect(ctx, imageRef, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect %s in its registry: %w", imageRef, err)
+	}
+	return dist.Descriptor.Digest.String(), nil
+}
+
+// ListImages lists the images in the local image store
+func (c *Client) ListImages(ctx context.Context) ([]ImageInfo, error) {
+	summaries, err := c.cli.ImageList(ctx, image.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+	images := make([]ImageInfo, 0, len(summaries))
+	for _, s := range summaries {
+		images = append(images, ImageInfo{
+			ID:          s.ID,
+			RepoTags:    s.RepoTags,
+			RepoDigests: s.RepoDigests,
+			Created:     time.Unix(s.Created, 0),
+			SizeBytes:   s.Size,
+		})
+	}
+	return images, nil
+}
+
+// RemoveImage removes an image by ID. It fails if a container still uses the image.
+func (c *Client) RemoveImage(ctx context.Context, imageID string) error {
+	if _, err := c.cli.ImageRemove(ctx, imageID, image.RemoveOptions{PruneChildren: true}); err != nil {
+		return fmt.Errorf("failed to remove image %s: %w", imageID, err)
+	}
+	return nil
+}
+
+// ImageRepository returns the fully qualified repository of imageRef, without tag or
+// digest ("nginx:1.27" gives "docker.io/library/nginx"), or "" if it doesn't parse
+func ImageRepository(imageRef string) string {
+	named, err := reference.ParseNormalizedNamed(imageRef)
+	if err != nil {
+		return ""
+	}
+	return named.Name()
+}
+
+// RepoDigestFor picks the digest of imageRef's repository out of an image's repo digests
+// ("nginx@sha256:..." entries), "" if none matches
+func RepoDigestFor(imageRef string, repoDigests []string) string {
+	repo := ImageRepository(imageRef)
+	for _, rd := range repoDigests {
+		name, digest, ok := strings.Cut(rd, "@")
+		if !ok {
+			continue
+		}
+		if ImageRepository(name) == repo {
+			return digest
+		}
+	}
+	return ""
+}
@@ -0,0 +1,40 @@
+package docker
+
+import "testing"
+
+func TestImageRepository(t *testing.T) {
+	tests := map[string]string{
+		"nginx":                                  "docker.io/library/nginx",
+		"nginx:1.27":                             "docker.io/library/nginx",
+		"itzg/minecraft-server:java21":           "docker.io/itzg/minecraft-server",
+		"ghcr.io/whale-net/manman:latest":        "ghcr.io/whale-net/manman",
+		"registry.local:5000/game@sha256:" + sha: "registry.local:5000/game",
+		"Not A Reference":                        "",
+	}
+	for ref, want := range tests {
+		if got := ImageRepository(ref); got != want {
+			t.Errorf("ImageRepository(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+const sha = "4c5fc8a1c9f0b1e1b2f7cdb0b1a3a6ab9c7a5e2b8d1e0f3a6c9b2d5e8f1a4b7c"
+
+func TestRepoDigestFor(t *testing.T) {
+	digests := []string{
+		"itzg/minecraft-server@sha256:" + sha,
+		"ghcr.io/whale-net/mirror@sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	}
+	if got := RepoDigestFor("itzg/minecraft-server:java21", digests); got != "sha256:"+sha {
+		t.Errorf("Expected the minecraft-server digest, got %q", got)
+	}
+	if got := RepoDigestFor("docker.io/itzg/minecraft-server", digests); got != "sha256:"+sha {
+		t.Errorf("Expected a fully qualified reference to match, got %q", got)
+	}
+	if got := RepoDigestFor("nginx", digests); got != "" {
+		t.Errorf("Expected no digest for another repository, got %q", got)
+	}
+	if got := RepoDigestFor("nginx", nil); got != "" {
+		t.Errorf("Expected no digest for a locally built image, got %q", got)
+	}
+}
@@ -0,0 +1,99 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+// ContainerStats is a point-in-time sample of a container's resource usage. CPU, network
+// and block I/O are cumulative counters; compare two samples to get rates.
+type ContainerStats struct {
+	Read             time.Time
+	CPUUsageNanos    uint64 // CPU time the container has used
+	SystemCPUNanos   uint64 // CPU time the host has used, across all CPUs
+	OnlineCPUs       uint32
+	MemoryBytes      uint64 // usage less reclaimable page cache, as docker stats reports it
+	MemoryLimitBytes uint64 // the container's limit, or the host's memory when unlimited
+	NetworkRxBytes   uint64
+	NetworkTxBytes   uint64
+	BlockReadBytes   uint64
+	BlockWriteBytes  uint64
+}
+
+// ContainerStats samples a container's resource usage without streaming
+func (c *Client) ContainerStats(ctx context.Context, containerID string) (*ContainerStats, error) {
+	resp, err := c.cli.ContainerStatsOneShot(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container stats: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var raw container.StatsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode container stats: %w", err)
+	}
+	stats := statsFromDocker(&raw)
+	return &stats, nil
+}
+
+func statsFromDocker(raw *container.StatsResponse) ContainerStats {
+	stats := ContainerStats{
+		Read:             raw.Read,
+		CPUUsageNanos:    raw.CPUStats.CPUUsage.TotalUsage,
+		SystemCPUNanos:   raw.CPUStats.SystemUsage,
+		OnlineCPUs:       raw.CPUStats.OnlineCPUs,
+		MemoryBytes:      raw.MemoryStats.Usage,
+		MemoryLimitBytes: raw.MemoryStats.Limit,
+	}
+	if stats.OnlineCPUs == 0 {
+		stats.OnlineCPUs = uint32(len(raw.CPUStats.CPUUsage.PercpuUsage))
+	}
+
+	// cgroup v2 reports inactive_file, v1 total_inactive_file
+	cache, ok := raw.MemoryStats.Stats["inactive_file"]
+	if !ok {
+		cache = raw.MemoryStats.Stats["total_inactive_file"]
+	}
+	if cache < stats.MemoryBytes {
+		stats.MemoryBytes -= cache
+	}
+
+	for _, n := range raw.Networks {
+		stats.NetworkRxBytes += n.RxBytes
+		stats.NetworkTxBytes += n.TxBytes
+	}
+	for _, entry := range raw.BlkioStats.IoServiceBytesRecursive {
+		switch strings.ToLower(entry.Op) {
+		case "read":
+			stats.BlockReadBytes += entry.Value
+		case "write":
+			stats.BlockWriteBytes += entry.Value
+		}
+	}
+	return stats
+}
+
+// CPUPercent is the CPU a container used between two samples, where 100 is one full
+// core. Returns 0 when the samples can't be compared.
+func CPUPercent(prev, cur *ContainerStats) float64 {
+	if prev == nil || cur == nil || cur.CPUUsageNanos < prev.CPUUsageNanos || cur.SystemCPUNanos <= prev.SystemCPUNanos {
+		return 0
+	}
+	cpuDelta := float64(cur.CPUUsageNanos - prev.CPUUsageNanos)
+	systemDelta := float64(cur.SystemCPUNanos - prev.SystemCPUNanos)
+	return cpuDelta / systemDelta * float64(cur.OnlineCPUs) * 100
+}
+
+// ByteRate is the per-second rate of a cumulative byte counter between two samples.
+// Returns 0 when the counter went backwards, as it does when a container restarts.
+func ByteRate(prev, cur uint64, elapsed time.Duration) float64 {
+	if cur < prev || elapsed <= 0 {
+		return 0
+	}
+	return float64(cur-prev) / elapsed.Seconds()
+}
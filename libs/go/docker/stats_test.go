@@ -0,0 +1,71 @@
+package docker
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestStatsFromDocker(t *testing.T) {
+	raw := &container.StatsResponse{
+		CPUStats: container.CPUStats{
+			CPUUsage:    container.CPUUsage{TotalUsage: 5e9, PercpuUsage: []uint64{1, 2, 3, 4}},
+			SystemUsage: 100e9,
+		},
+		MemoryStats: container.MemoryStats{
+			Usage: 600 << 20,
+			Limit: 2 << 30,
+			Stats: map[string]uint64{"inactive_file": 100 << 20},
+		},
+		Networks: map[string]container.NetworkStats{
+			"eth0": {RxBytes: 1000, TxBytes: 2000},
+			"eth1": {RxBytes: 10, TxBytes: 20},
+		},
+		BlkioStats: container.BlkioStats{IoServiceBytesRecursive: []container.BlkioStatEntry{
+			{Op: "read", Value: 4096},
+			{Op: "Write", Value: 8192},
+			{Op: "total", Value: 12288},
+		}},
+	}
+
+	got := statsFromDocker(raw)
+	want := ContainerStats{
+		CPUUsageNanos:    5e9,
+		SystemCPUNanos:   100e9,
+		OnlineCPUs:       4,
+		MemoryBytes:      500 << 20,
+		MemoryLimitBytes: 2 << 30,
+		NetworkRxBytes:   1010,
+		NetworkTxBytes:   2020,
+		BlockReadBytes:   4096,
+		BlockWriteBytes:  8192,
+	}
+	if got != want {
+		t.Errorf("statsFromDocker = %+v, want %+v", got, want)
+	}
+}
+
+func TestCPUPercent(t *testing.T) {
+	prev := &ContainerStats{CPUUsageNanos: 1e9, SystemCPUNanos: 100e9, OnlineCPUs: 4}
+	cur := &ContainerStats{CPUUsageNanos: 3e9, SystemCPUNanos: 104e9, OnlineCPUs: 4}
+	if got := CPUPercent(prev, cur); math.Abs(got-200) > 0.001 {
+		t.Errorf("CPUPercent = %v, want 200 (two cores)", got)
+	}
+	if got := CPUPercent(nil, cur); got != 0 {
+		t.Errorf("CPUPercent without a previous sample = %v, want 0", got)
+	}
+	if got := CPUPercent(cur, prev); got != 0 {
+		t.Errorf("CPUPercent backwards = %v, want 0", got)
+	}
+}
+
+func TestByteRate(t *testing.T) {
+	if got := ByteRate(1000, 4000, 30*time.Second); got != 100 {
+		t.Errorf("ByteRate = %v, want 100", got)
+	}
+	if got := ByteRate(4000, 1000, 30*time.Second); got != 0 {
+		t.Errorf("ByteRate after a reset = %v, want 0", got)
+	}
+}
@@ -18,6 +18,7 @@ const (
 // Claims holds authenticated user/service account claims
 type Claims struct {
 	Subject  string
+	Username string // preferred_username, empty when the token doesn't carry it
 	Roles    []string
 	Audience []string
 }
@@ -94,7 +95,8 @@ func (v *oidcVerifier) Verify(ctx context.Context, token string) (*Claims, error
 	}
 
 	var rawClaims struct {
-		RealmAccess struct {
+		PreferredUsername string `json:"preferred_username"`
+		RealmAccess       struct {
 			Roles []string `json:"roles"`
 		} `json:"realm_access"`
 	}
@@ -104,6 +106,7 @@ func (v *oidcVerifier) Verify(ctx context.Context, token string) (*Claims, error
 
 	return &Claims{
 		Subject:  idToken.Subject,
+		Username: rawClaims.PreferredUsername,
 		Roles:    rawClaims.RealmAccess.Roles,
 		Audience: idToken.Audience,
 	}, nil
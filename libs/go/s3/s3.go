@@ -153,6 +153,19 @@ func (c *Client) PresignPutURL(ctx context.Context, key string, ttl time.Duratio
 	return req.URL, nil
 }
 
+// PresignGetURL generates a pre-signed GET URL for the given key.
+// Like PresignPutURL, it always uses the primary (internal) endpoint.
+func (c *Client) PresignGetURL(ctx context.Context, key string, ttl time.Duration) (string, error) {
+	req, err := c.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(c.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(ttl))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign GET URL: %w", err)
+	}
+	return req.URL, nil
+}
+
 // noSeekReader wraps an io.Reader to prevent the AWS SDK from seeking it.
 type noSeekReader struct{ r io.Reader }
 
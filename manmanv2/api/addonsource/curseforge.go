@@ -0,0 +1,133 @@
+package addonsource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultCurseForgeURL is the public CurseForge API
+const DefaultCurseForgeURL = "https://api.curseforge.com"
+
+// curseForgeHashSHA1 is CurseForge's algo value for SHA-1 file hashes (2 is MD5)
+const curseForgeHashSHA1 = 1
+
+// CurseForgeSource fetches mods from a CurseForge-compatible API. Addon metadata may pin
+// "file_id", or narrow the newest file by "game_version" and "loader" (a CurseForge
+// modLoaderType number).
+type CurseForgeSource struct {
+	baseURL    string
+	apiKey     string
+	httpClient *http.Client
+}
+
+// NewCurseForgeSource creates a CurseForge source for the API at baseURL
+func NewCurseForgeSource(baseURL, apiKey string, timeout time.Duration) *CurseForgeSource {
+	return &CurseForgeSource{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		apiKey:     apiKey,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type curseForgeMod struct {
+	ID           int64     `json:"id"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	Summary      string    `json:"summary"`
+	DateModified time.Time `json:"dateModified"`
+}
+
+type curseForgeFile struct {
+	ID          int64  `json:"id"`
+	FileName    string `json:"fileName"`
+	FileLength  int64  `json:"fileLength"`
+	DownloadURL string `json:"downloadUrl"`
+	Hashes      []struct {
+		Value string `json:"value"`
+		Algo  int    `json:"algo"`
+	} `json:"hashes"`
+}
+
+func (s *CurseForgeSource) get(ctx context.Context, path string, out interface{}) error {
+	return getJSON(ctx, s.httpClient, s.baseURL+path, map[string]string{"x-api-key": s.apiKey}, out)
+}
+
+// FetchMetadata looks up a mod by its numeric ID
+func (s *CurseForgeSource) FetchMetadata(ctx context.Context, id string) (*Metadata, error) {
+	var resp struct {
+		Data curseForgeMod `json:"data"`
+	}
+	if err := s.get(ctx, "/v1/mods/"+url.PathEscape(id), &resp); err != nil {
+		return nil, fmt.Errorf("failed to fetch curseforge mod %s: %w", id, err)
+	}
+
+	return &Metadata{
+		ID:          fmt.Sprintf("%d", resp.Data.ID),
+		Title:       resp.Data.Name,
+		Description: resp.Data.Summary,
+		TimeUpdated: resp.Data.DateModified,
+		Extra: map[string]interface{}{
+			"mod_slug": resp.Data.Slug,
+		},
+	}, nil
+}
+
+// ResolveDownload picks the pinned file, or the newest file matching the game version and
+// loader filters
+func (s *CurseForgeSource) ResolveDownload(ctx context.Context, id string, opts map[string]interface{}) (*Download, error) {
+	var file curseForgeFile
+	if fileID := stringOpt(opts, "file_id"); fileID != "" {
+		var resp struct {
+			Data curseForgeFile `json:"data"`
+		}
+		if err := s.get(ctx, "/v1/mods/"+url.PathEscape(id)+"/files/"+url.PathEscape(fileID), &resp); err != nil {
+			return nil, fmt.Errorf("failed to fetch curseforge file %s: %w", fileID, err)
+		}
+		file = resp.Data
+	} else {
+		query := url.Values{}
+		if gameVersion := stringOpt(opts, "game_version"); gameVersion != "" {
+			query.Set("gameVersion", gameVersion)
+		}
+		if loader := stringOpt(opts, "loader"); loader != "" {
+			query.Set("modLoaderType", loader)
+		}
+		path := "/v1/mods/" + url.PathEscape(id) + "/files"
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+
+		// Files are listed newest first
+		var resp struct {
+			Data []curseForgeFile `json:"data"`
+		}
+		if err := s.get(ctx, path, &resp); err != nil {
+			return nil, fmt.Errorf("failed to list curseforge files for %s: %w", id, err)
+		}
+		if len(resp.Data) == 0 {
+			return nil, fmt.Errorf("no curseforge files of %s match the game version and loader", id)
+		}
+		file = resp.Data[0]
+	}
+
+	// Authors can opt out of third-party downloads, which leaves the URL empty
+	if file.DownloadURL == "" {
+		return nil, fmt.Errorf("curseforge file %d of %s does not allow third-party downloads", file.ID, id)
+	}
+
+	download := &Download{
+		URL:      file.DownloadURL,
+		FileName: file.FileName,
+		Version:  fmt.Sprintf("%d", file.ID),
+	}
+	for _, h := range file.Hashes {
+		if h.Algo == curseForgeHashSHA1 {
+			download.ChecksumAlgorithm, download.Checksum = ChecksumSHA1, h.Value
+		}
+	}
+	return download, nil
+}
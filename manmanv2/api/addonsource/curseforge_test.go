@@ -0,0 +1,78 @@
+package addonsource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCurseForgeStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/mods/238222", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"id":238222,"name":"JEI","slug":"jei","summary":"Item viewer","dateModified":"2024-03-02T08:00:00Z"}}`))
+	})
+	mux.HandleFunc("/v1/mods/238222/files", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "1.20.1", r.URL.Query().Get("gameVersion"))
+		w.Write([]byte(`{"data":[
+			{"id":5101,"fileName":"jei-1.20.1.jar","downloadUrl":"https://edge.example/jei.jar",
+			 "hashes":[{"value":"md5hash","algo":2},{"value":"sha1hash","algo":1}]},
+			{"id":5000,"fileName":"jei-old.jar","downloadUrl":"https://edge.example/old.jar","hashes":[]}
+		]}`))
+	})
+	mux.HandleFunc("/v1/mods/238222/files/4000", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":{"id":4000,"fileName":"jei-restricted.jar","downloadUrl":null}}`))
+	})
+
+	// Every request must carry the API key
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test-key" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestCurseForgeSource_FetchMetadata(t *testing.T) {
+	srv := newCurseForgeStub(t)
+	source := NewCurseForgeSource(srv.URL, "test-key", 5*time.Second)
+
+	metadata, err := source.FetchMetadata(context.Background(), "238222")
+	require.NoError(t, err)
+	assert.Equal(t, "238222", metadata.ID)
+	assert.Equal(t, "JEI", metadata.Title)
+	assert.Equal(t, "Item viewer", metadata.Description)
+	assert.Equal(t, "jei", metadata.Extra["mod_slug"])
+
+	badKey := NewCurseForgeSource(srv.URL, "wrong", 5*time.Second)
+	_, err = badKey.FetchMetadata(context.Background(), "238222")
+	assert.ErrorContains(t, err, "status 403")
+}
+
+func TestCurseForgeSource_ResolveDownload(t *testing.T) {
+	srv := newCurseForgeStub(t)
+	source := NewCurseForgeSource(srv.URL, "test-key", 5*time.Second)
+	ctx := context.Background()
+
+	download, err := source.ResolveDownload(ctx, "238222", map[string]interface{}{"game_version": "1.20.1"})
+	require.NoError(t, err)
+	assert.Equal(t, &Download{
+		URL:               "https://edge.example/jei.jar",
+		FileName:          "jei-1.20.1.jar",
+		Version:           "5101",
+		ChecksumAlgorithm: ChecksumSHA1,
+		Checksum:          "sha1hash",
+	}, download)
+
+	// A file ID stored as a JSON number still pins the file
+	_, err = source.ResolveDownload(ctx, "238222", map[string]interface{}{"file_id": float64(4000)})
+	assert.ErrorContains(t, err, "does not allow third-party downloads")
+}
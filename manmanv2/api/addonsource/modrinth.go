@@ -0,0 +1,131 @@
+package addonsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultModrinthURL is the public Modrinth API
+const DefaultModrinthURL = "https://api.modrinth.com"
+
+// ModrinthSource fetches mods from a Modrinth-compatible API. Addon metadata may pin
+// "version_id", or narrow the newest version by "game_version" and "loader".
+type ModrinthSource struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewModrinthSource creates a Modrinth source for the API at baseURL
+func NewModrinthSource(baseURL string, timeout time.Duration) *ModrinthSource {
+	return &ModrinthSource{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+	}
+}
+
+type modrinthProject struct {
+	ID          string    `json:"id"`
+	Slug        string    `json:"slug"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Updated     time.Time `json:"updated"`
+	ProjectType string    `json:"project_type"`
+}
+
+type modrinthVersion struct {
+	ID            string `json:"id"`
+	VersionNumber string `json:"version_number"`
+	Files         []struct {
+		URL      string            `json:"url"`
+		Filename string            `json:"filename"`
+		Primary  bool              `json:"primary"`
+		Size     int64             `json:"size"`
+		Hashes   map[string]string `json:"hashes"`
+	} `json:"files"`
+}
+
+// FetchMetadata looks up a project by ID or slug
+func (s *ModrinthSource) FetchMetadata(ctx context.Context, id string) (*Metadata, error) {
+	var project modrinthProject
+	if err := getJSON(ctx, s.httpClient, s.baseURL+"/v2/project/"+url.PathEscape(id), nil, &project); err != nil {
+		return nil, fmt.Errorf("failed to fetch modrinth project %s: %w", id, err)
+	}
+
+	return &Metadata{
+		ID:          project.ID,
+		Title:       project.Title,
+		Description: project.Description,
+		TimeUpdated: project.Updated,
+		Extra: map[string]interface{}{
+			"project_slug": project.Slug,
+			"project_type": project.ProjectType,
+		},
+	}, nil
+}
+
+// ResolveDownload picks the primary file of the pinned version, or of the newest version
+// matching the game version and loader filters
+func (s *ModrinthSource) ResolveDownload(ctx context.Context, id string, opts map[string]interface{}) (*Download, error) {
+	var version modrinthVersion
+	if versionID := stringOpt(opts, "version_id"); versionID != "" {
+		if err := getJSON(ctx, s.httpClient, s.baseURL+"/v2/version/"+url.PathEscape(versionID), nil, &version); err != nil {
+			return nil, fmt.Errorf("failed to fetch modrinth version %s: %w", versionID, err)
+		}
+	} else {
+		query := url.Values{}
+		if gameVersion := stringOpt(opts, "game_version"); gameVersion != "" {
+			query.Set("game_versions", jsonList(gameVersion))
+		}
+		if loader := stringOpt(opts, "loader"); loader != "" {
+			query.Set("loaders", jsonList(loader))
+		}
+		apiURL := s.baseURL + "/v2/project/" + url.PathEscape(id) + "/version"
+		if len(query) > 0 {
+			apiURL += "?" + query.Encode()
+		}
+
+		// Versions are listed newest first
+		var versions []modrinthVersion
+		if err := getJSON(ctx, s.httpClient, apiURL, nil, &versions); err != nil {
+			return nil, fmt.Errorf("failed to list modrinth versions for %s: %w", id, err)
+		}
+		if len(versions) == 0 {
+			return nil, fmt.Errorf("no modrinth versions of %s match the game version and loader", id)
+		}
+		version = versions[0]
+	}
+
+	if len(version.Files) == 0 {
+		return nil, fmt.Errorf("modrinth version %s has no files", version.ID)
+	}
+	file := version.Files[0]
+	for _, f := range version.Files {
+		if f.Primary {
+			file = f
+			break
+		}
+	}
+
+	download := &Download{
+		URL:      file.URL,
+		FileName: file.Filename,
+		Version:  version.ID,
+	}
+	if h := file.Hashes[ChecksumSHA512]; h != "" {
+		download.ChecksumAlgorithm, download.Checksum = ChecksumSHA512, h
+	} else if h := file.Hashes[ChecksumSHA1]; h != "" {
+		download.ChecksumAlgorithm, download.Checksum = ChecksumSHA1, h
+	}
+	return download, nil
+}
+
+// jsonList encodes a single value as the JSON array Modrinth expects in filters
+func jsonList(v string) string {
+	b, _ := json.Marshal([]string{v})
+	return string(b)
+}
@@ -0,0 +1,83 @@
+package addonsource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newModrinthStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v2/project/sodium", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"AANobbMI","slug":"sodium","title":"Sodium","description":"Rendering engine",
+			"updated":"2024-05-01T12:00:00Z","project_type":"mod"}`))
+	})
+	mux.HandleFunc("/v2/project/sodium/version", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("game_versions") == `["1.19.2"]` {
+			w.Write([]byte(`[]`))
+			return
+		}
+		assert.Equal(t, `["1.20.1"]`, r.URL.Query().Get("game_versions"))
+		assert.Equal(t, `["fabric"]`, r.URL.Query().Get("loaders"))
+		w.Write([]byte(`[
+			{"id":"new","version_number":"0.5.3","files":[
+				{"url":"https://cdn.example/sources.jar","filename":"sodium-sources.jar","primary":false,"hashes":{"sha1":"aa"}},
+				{"url":"https://cdn.example/sodium.jar","filename":"sodium.jar","primary":true,"size":10,"hashes":{"sha1":"bb","sha512":"cc"}}
+			]},
+			{"id":"old","version_number":"0.5.2","files":[]}
+		]`))
+	})
+	mux.HandleFunc("/v2/version/pinned", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"pinned","files":[{"url":"https://cdn.example/old.jar","filename":"old.jar","hashes":{"sha1":"dd"}}]}`))
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestModrinthSource_FetchMetadata(t *testing.T) {
+	srv := newModrinthStub(t)
+	source := NewModrinthSource(srv.URL+"/", 5*time.Second)
+
+	metadata, err := source.FetchMetadata(context.Background(), "sodium")
+	require.NoError(t, err)
+	assert.Equal(t, "AANobbMI", metadata.ID)
+	assert.Equal(t, "Sodium", metadata.Title)
+	assert.Equal(t, "Rendering engine", metadata.Description)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), metadata.TimeUpdated.UTC())
+	assert.Equal(t, "sodium", metadata.Extra["project_slug"])
+
+	_, err = source.FetchMetadata(context.Background(), "missing")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestModrinthSource_ResolveDownload(t *testing.T) {
+	srv := newModrinthStub(t)
+	source := NewModrinthSource(srv.URL, 5*time.Second)
+	ctx := context.Background()
+
+	download, err := source.ResolveDownload(ctx, "sodium", map[string]interface{}{"game_version": "1.20.1", "loader": "fabric"})
+	require.NoError(t, err)
+	assert.Equal(t, &Download{
+		URL:               "https://cdn.example/sodium.jar",
+		FileName:          "sodium.jar",
+		Version:           "new",
+		ChecksumAlgorithm: ChecksumSHA512,
+		Checksum:          "cc",
+	}, download)
+
+	pinned, err := source.ResolveDownload(ctx, "sodium", map[string]interface{}{"version_id": "pinned"})
+	require.NoError(t, err)
+	assert.Equal(t, "old.jar", pinned.FileName)
+	assert.Equal(t, ChecksumSHA1, pinned.ChecksumAlgorithm)
+	assert.Equal(t, "dd", pinned.Checksum)
+
+	_, err = source.ResolveDownload(ctx, "sodium", map[string]interface{}{"game_version": "1.19.2"})
+	assert.ErrorContains(t, err, "no modrinth versions")
+}
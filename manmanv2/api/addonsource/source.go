@@ -0,0 +1,93 @@
+package addonsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Checksum algorithms a Download can be verified with
+const (
+	ChecksumSHA1   = "sha1"
+	ChecksumSHA256 = "sha256"
+	ChecksumSHA512 = "sha512"
+)
+
+// Source is a platform that addons can be fetched from over HTTP. Steam Workshop addons
+// are downloaded by SteamCMD on the host instead, so Steam is not a Source.
+type Source interface {
+	// FetchMetadata looks up an addon by its platform ID
+	FetchMetadata(ctx context.Context, id string) (*Metadata, error)
+	// ResolveDownload finds the file to install for an addon. opts is the addon's stored
+	// metadata, which may pin a version or filter by game version and loader.
+	ResolveDownload(ctx context.Context, id string, opts map[string]interface{}) (*Download, error)
+}
+
+// Metadata describes an addon on its platform
+type Metadata struct {
+	ID          string
+	Title       string
+	Description string
+	FileSize    int64
+	TimeUpdated time.Time
+	// Extra is kept in the addon's metadata and handed back to ResolveDownload
+	Extra map[string]interface{}
+}
+
+// Download is a file the host manager fetches and verifies before installing
+type Download struct {
+	URL               string
+	FileName          string
+	Version           string
+	ChecksumAlgorithm string
+	Checksum          string // hex encoded
+	// Extract unpacks the file as an archive instead of installing it as is
+	Extract bool
+}
+
+// getJSON decodes the JSON body of a GET request to apiURL into out
+func getJSON(ctx context.Context, client *http.Client, apiURL string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("addon not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, body)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// stringOpt reads an option from an addon's metadata. IDs may have been stored as JSON
+// numbers, so those are formatted back into strings.
+func stringOpt(opts map[string]interface{}, key string) string {
+	switch v := opts[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
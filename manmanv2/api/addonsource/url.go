@@ -0,0 +1,138 @@
+package addonsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// URLSource installs a file downloaded from a plain URL, which is the addon's ID.
+// FetchMetadata downloads the file once and records its SHA-256 in "sha256", so later
+// installs fail if the file behind the URL changes. Archives are unpacked unless
+// "extract" is false.
+type URLSource struct {
+	httpClient *http.Client
+}
+
+// NewURLSource creates a plain URL source
+func NewURLSource(timeout time.Duration) *URLSource {
+	return &URLSource{httpClient: &http.Client{Timeout: timeout}}
+}
+
+// FetchMetadata downloads the file to measure and checksum it
+func (s *URLSource) FetchMetadata(ctx context.Context, id string) (*Metadata, error) {
+	fileName, err := urlFileName(id)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, id, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", id, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download of %s returned status %d", id, resp.StatusCode)
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", id, err)
+	}
+
+	metadata := &Metadata{
+		ID:       id,
+		Title:    fileName,
+		FileSize: size,
+		Extra: map[string]interface{}{
+			ChecksumSHA256: hex.EncodeToString(hash.Sum(nil)),
+			"file_name":    fileName,
+		},
+	}
+	if modified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		metadata.TimeUpdated = modified
+	}
+	return metadata, nil
+}
+
+// ResolveDownload returns the URL itself, verified against the recorded checksum
+func (s *URLSource) ResolveDownload(ctx context.Context, id string, opts map[string]interface{}) (*Download, error) {
+	fileName := stringOpt(opts, "file_name")
+	if fileName == "" {
+		var err error
+		if fileName, err = urlFileName(id); err != nil {
+			return nil, err
+		}
+	}
+	if err := CheckURLAddon(id, opts); err != nil {
+		return nil, err
+	}
+	checksum := stringOpt(opts, ChecksumSHA256)
+
+	extract := isArchive(fileName)
+	if v, ok := opts["extract"].(bool); ok {
+		extract = v
+	}
+
+	return &Download{
+		URL:               id,
+		FileName:          fileName,
+		Version:           checksum,
+		ChecksumAlgorithm: ChecksumSHA256,
+		Checksum:          checksum,
+		Extract:           extract,
+	}, nil
+}
+
+// CheckURLAddon reports whether a URL addon can be installed: its ID must be an http(s)
+// URL naming a file and its metadata must carry the file's SHA-256, as FetchMetadata
+// records it, for the host to verify the download against
+func CheckURLAddon(id string, metadata map[string]interface{}) error {
+	if _, err := urlFileName(id); err != nil {
+		return err
+	}
+	checksum := stringOpt(metadata, ChecksumSHA256)
+	if checksum == "" {
+		return fmt.Errorf("addon metadata has no %s for %s; fetch its metadata again", ChecksumSHA256, id)
+	}
+	if raw, err := hex.DecodeString(checksum); err != nil || len(raw) != sha256.Size {
+		return fmt.Errorf("addon metadata %s for %s is not a hex encoded SHA-256", ChecksumSHA256, id)
+	}
+	return nil
+}
+
+// urlFileName validates an http(s) URL and returns the last element of its path
+func urlFileName(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", fmt.Errorf("%q is not an http or https URL", rawURL)
+	}
+	name := path.Base(u.Path)
+	if name == "/" || name == "." {
+		return "", fmt.Errorf("URL %s does not name a file", rawURL)
+	}
+	return name, nil
+}
+
+// isArchive reports whether the host manager can unpack fileName
+func isArchive(fileName string) bool {
+	name := strings.ToLower(fileName)
+	for _, ext := range []string{".zip", ".tar.gz", ".tgz", ".tar"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package addonsource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLSource_FetchMetadata(t *testing.T) {
+	content := []byte("necesse mod archive")
+	sum := sha256.Sum256(content)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/mods/quality-of-life.zip" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Last-Modified", "Wed, 01 May 2024 12:00:00 GMT")
+		w.Write(content)
+	}))
+	defer srv.Close()
+	source := NewURLSource(5 * time.Second)
+
+	metadata, err := source.FetchMetadata(context.Background(), srv.URL+"/mods/quality-of-life.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "quality-of-life.zip", metadata.Title)
+	assert.Equal(t, int64(len(content)), metadata.FileSize)
+	assert.Equal(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), metadata.TimeUpdated)
+	assert.Equal(t, hex.EncodeToString(sum[:]), metadata.Extra["sha256"])
+	assert.Equal(t, "quality-of-life.zip", metadata.Extra["file_name"])
+
+	_, err = source.FetchMetadata(context.Background(), srv.URL+"/mods/missing.zip")
+	assert.ErrorContains(t, err, "status 404")
+
+	_, err = source.FetchMetadata(context.Background(), "ftp://example.com/mod.zip")
+	assert.ErrorContains(t, err, "not an http or https URL")
+}
+
+func TestURLSource_ResolveDownload(t *testing.T) {
+	source := NewURLSource(5 * time.Second)
+	ctx := context.Background()
+	sum := strings.Repeat("ab", sha256.Size)
+
+	download, err := source.ResolveDownload(ctx, "https://example.com/mods/pack.tar.gz", map[string]interface{}{"sha256": sum})
+	require.NoError(t, err)
+	assert.Equal(t, &Download{
+		URL:               "https://example.com/mods/pack.tar.gz",
+		FileName:          "pack.tar.gz",
+		Version:           sum,
+		ChecksumAlgorithm: ChecksumSHA256,
+		Checksum:          sum,
+		Extract:           true,
+	}, download)
+
+	jar, err := source.ResolveDownload(ctx, "https://example.com/mods/mod.jar", map[string]interface{}{"sha256": sum})
+	require.NoError(t, err)
+	assert.False(t, jar.Extract)
+
+	kept, err := source.ResolveDownload(ctx, "https://example.com/mods/maps.zip", map[string]interface{}{"sha256": sum, "extract": false})
+	require.NoError(t, err)
+	assert.False(t, kept.Extract)
+
+	_, err = source.ResolveDownload(ctx, "https://example.com/mods/mod.jar", nil)
+	assert.ErrorContains(t, err, "no sha256")
+}
+
+func TestCheckURLAddon(t *testing.T) {
+	sum := strings.Repeat("ab", sha256.Size)
+	assert.NoError(t, CheckURLAddon("https://example.com/mods/pack.zip", map[string]interface{}{"sha256": sum}))
+	assert.ErrorContains(t, CheckURLAddon("https://example.com/mods/pack.zip", nil), "no sha256")
+	assert.ErrorContains(t, CheckURLAddon("https://example.com/mods/pack.zip", map[string]interface{}{"sha256": "abc"}), "not a hex encoded SHA-256")
+	assert.ErrorContains(t, CheckURLAddon("ftp://example.com/pack.zip", map[string]interface{}{"sha256": sum}), "not an http or https URL")
+}
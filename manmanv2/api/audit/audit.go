@@ -0,0 +1,387 @@
+// Package audit records every mutating ManManAPI and WorkshopService call to the
+// append-only audit_events table: who called which RPC, the IDs it targeted, a
+// redacted copy of the request, what an update changed and how it ended.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	redacted = "[redacted]"
+
+	// maxStringLen caps recorded string values; config files and log lines don't
+	// belong in the audit log
+	maxStringLen = 256
+
+	writeTimeout = 5 * time.Second
+)
+
+// auditedServices are the services whose mutating methods are recorded
+var auditedServices = map[string]bool{
+	pb.ManManAPI_ServiceDesc.ServiceName:       true,
+	pb.WorkshopService_ServiceDesc.ServiceName: true,
+}
+
+// readPrefixes mark methods that don't change anything
+var readPrefixes = []string{"Get", "List", "Validate", "Preview", "Fetch", "Search"}
+
+// skippedMethods change state but are host chatter that would drown out everything else
+var skippedMethods = map[string]bool{
+	"Heartbeat":       true,
+	"SendBatchedLogs": true,
+}
+
+// sensitiveKeyParts mark request fields whose values are never recorded
+var sensitiveKeyParts = []string{"password", "secret", "token", "api_key", "apikey", "credential", "private_key"}
+
+// sensitiveKeys are exact field names never recorded: a webhook's url often carries
+// its credentials (Discord's and Slack's do)
+var sensitiveKeys = map[string]bool{"url": true}
+
+// contentKeys are free-text fields, such as configuration patches and console input,
+// recorded only as their length and sha256: enough to tell whether two calls sent the
+// same text without copying whatever secrets it holds
+var contentKeys = map[string]bool{"content": true, "patch_content": true, "base_template": true, "input": true}
+
+// Recorder writes audit events
+type Recorder struct {
+	events   repository.AuditEventRepository
+	sessions repository.SessionRepository
+	readers  []interface{}
+}
+
+// NewRecorder records to events. Calls that target a session are also attributed to its
+// server game config, looked up in sessions.
+func NewRecorder(events repository.AuditEventRepository, sessions repository.SessionRepository) *Recorder {
+	return &Recorder{events: events, sessions: sessions}
+}
+
+// SetReaders gives the services whose GetX methods are called before and after each
+// UpdateX to record what the update changed
+func (r *Recorder) SetReaders(servers ...interface{}) {
+	r.readers = servers
+}
+
+// UnaryInterceptor records mutating unary calls. Chain it after grpcauth's
+// interceptor, which puts the claims in the context, and before the authorizer so
+// denied calls are recorded too. A failed write is logged and never fails the call.
+func (r *Recorder) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		service, method := splitMethod(info.FullMethod)
+		if !auditedServices[service] || !IsMutating(method) {
+			return handler(ctx, req)
+		}
+
+		start := time.Now()
+		before := r.snapshot(ctx, method, req)
+		resp, err := handler(ctx, req)
+
+		var changes map[string]interface{}
+		if before != nil && err == nil {
+			changes = diff(before, r.snapshot(ctx, method, req))
+		}
+		r.record(ctx, method, req, resp, changes, err, start)
+		return resp, err
+	}
+}
+
+// Record writes an audit event for a call the interceptor can't see, such as a line of
+// input sent over a stream. method is the bare method name.
+func (r *Recorder) Record(ctx context.Context, method string, req interface{}, callErr error, start time.Time) {
+	r.record(ctx, method, req, nil, nil, callErr, start)
+}
+
+func (r *Recorder) record(ctx context.Context, method string, req, resp interface{}, changes map[string]interface{}, callErr error, start time.Time) {
+	event := &manman.AuditEvent{
+		OccurredAt: start,
+		Method:     method,
+		StatusCode: status.Code(callErr).String(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if claims, ok := grpcauth.ClaimsFromContext(ctx); ok {
+		event.Subject = claims.Subject
+		event.Username = claims.Username
+	}
+	if callErr != nil {
+		msg := truncate(status.Convert(callErr).Message())
+		event.Error = &msg
+	}
+
+	// The call's context may already be cancelled; the record should still land
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), writeTimeout)
+	defer cancel()
+
+	reqMap := toMap(req)
+	event.Entities = EntityIDs(reqMap, toMap(resp))
+	r.addSessionSGC(writeCtx, event.Entities)
+	if reqMap != nil {
+		event.Request = manman.JSONB(Redact(reqMap))
+	}
+	if len(changes) > 0 {
+		event.Changes = manman.JSONB(changes)
+	}
+
+	if _, err := r.events.Create(writeCtx, event); err != nil {
+		log.Printf("Warning: failed to write audit event for %s by %q: %v", method, event.Subject, err)
+	}
+}
+
+// IsMutating reports whether the RPC named method changes state and so is audited
+func IsMutating(method string) bool {
+	if skippedMethods[method] {
+		return false
+	}
+	for _, p := range readPrefixes {
+		if strings.HasPrefix(method, p) {
+			return false
+		}
+	}
+	return method != ""
+}
+
+// Redact returns a copy of a request map with secrets replaced, free text reduced to
+// its length and sha256 and long strings truncated. Every field is kept, including those
+// an update's update_paths leave out; what the update changed is recorded separately.
+func Redact(req map[string]interface{}) map[string]interface{} {
+	return redactValue(req).(map[string]interface{})
+}
+
+// summarizeContent stands in for a free-text value. Console input is bytes, which
+// protojson renders as base64; the text itself is measured.
+func summarizeContent(key, value string) map[string]interface{} {
+	text := []byte(value)
+	if key == "input" {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			text = decoded
+		}
+	}
+	sum := sha256.Sum256(text)
+	return map[string]interface{}{"length": len(text), "sha256": hex.EncodeToString(sum[:])}
+}
+
+// snapshot returns the entity an UpdateX call targets, read through the matching GetX
+// method of a reader and redacted, or nil for other calls or if it can't be read
+func (r *Recorder) snapshot(ctx context.Context, method string, req interface{}) map[string]interface{} {
+	entity, ok := strings.CutPrefix(method, "Update")
+	msg, isProto := req.(proto.Message)
+	if !ok || !isProto {
+		return nil
+	}
+	raw, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	for _, server := range r.readers {
+		get := reflect.ValueOf(server).MethodByName("Get" + entity)
+		if !get.IsValid() || get.Type().NumIn() != 2 || get.Type().NumOut() != 2 || get.Type().In(1).Kind() != reflect.Pointer {
+			continue
+		}
+		getReq, ok := reflect.New(get.Type().In(1).Elem()).Interface().(proto.Message)
+		if !ok {
+			continue
+		}
+		// The update's ID fields share their names with the get's
+		if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(raw, getReq); err != nil {
+			return nil
+		}
+		out := get.Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(getReq)})
+		if err, _ := out[1].Interface().(error); err != nil {
+			return nil
+		}
+		resp := toMap(out[0].Interface())
+		// Get responses wrap the entity in their only field
+		if len(resp) == 1 {
+			for _, v := range resp {
+				if m, ok := v.(map[string]interface{}); ok {
+					resp = m
+				}
+			}
+		}
+		return Redact(resp)
+	}
+	return nil
+}
+
+// diff returns {"field": {"before": ..., "after": ...}} for each top-level field that
+// differs between two snapshots
+func diff(before, after map[string]interface{}) map[string]interface{} {
+	if after == nil {
+		return nil
+	}
+	changes := map[string]interface{}{}
+	for k, b := range before {
+		if a := after[k]; !reflect.DeepEqual(a, b) {
+			changes[k] = map[string]interface{}{"before": b, "after": a}
+		}
+	}
+	for k, a := range after {
+		if _, ok := before[k]; !ok {
+			changes[k] = map[string]interface{}{"before": nil, "after": a}
+		}
+	}
+	return changes
+}
+
+// addSessionSGC attributes a call that targets a session to the session's server game
+// config too, so it shows in the SGC's activity
+func (r *Recorder) addSessionSGC(ctx context.Context, ids map[string]int64) {
+	sessionID, ok := ids["session_id"]
+	if !ok || ids["server_game_config_id"] != 0 || r.sessions == nil {
+		return
+	}
+	session, err := r.sessions.Get(ctx, sessionID)
+	if err != nil {
+		return
+	}
+	ids["server_game_config_id"] = session.SGCID
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, val := range v {
+			if isSensitive(k) {
+				out[k] = redacted
+				continue
+			}
+			if s, ok := val.(string); ok && contentKeys[k] {
+				out[k] = summarizeContent(k, s)
+				continue
+			}
+			out[k] = redactValue(val)
+		}
+		// Key/value pairs, e.g. parameters: {"key": "rcon_password", "value": "..."}
+		for _, nameKey := range []string{"key", "name"} {
+			if name, ok := v[nameKey].(string); ok && isSensitive(name) {
+				if _, ok := out["value"]; ok {
+					out["value"] = redacted
+				}
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, val := range v {
+			out[i] = redactValue(val)
+		}
+		return out
+	case string:
+		return truncate(v)
+	default:
+		return v
+	}
+}
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	if sensitiveKeys[key] {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(s string) string {
+	if len(s) <= maxStringLen {
+		return s
+	}
+	return s[:maxStringLen] + "…"
+}
+
+// EntityIDs collects the non-zero *_id fields of a request, plus those of the
+// response's top-level messages for IDs only known once the call returns (e.g. the
+// session a StartSession created). Request values win.
+func EntityIDs(req, resp map[string]interface{}) map[string]int64 {
+	ids := map[string]int64{}
+	collectIDs(ids, req)
+	for _, v := range resp {
+		if m, ok := v.(map[string]interface{}); ok {
+			collectIDs(ids, m)
+		}
+	}
+	return ids
+}
+
+func collectIDs(ids map[string]int64, m map[string]interface{}) {
+	for k, v := range m {
+		if !strings.HasSuffix(k, "_id") {
+			continue
+		}
+		if _, seen := ids[k]; seen {
+			continue
+		}
+		if id := asInt64(v); id != 0 {
+			ids[k] = id
+		}
+	}
+}
+
+// asInt64 accepts both JSON numbers and protojson's quoted int64s
+func asInt64(v interface{}) int64 {
+	switch v := v.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		id, _ := strconv.ParseInt(v, 10, 64)
+		return id
+	default:
+		return 0
+	}
+}
+
+// toMap renders a message as a JSON object keyed by proto field names
+func toMap(msg interface{}) map[string]interface{} {
+	if msg == nil {
+		return nil
+	}
+	var (
+		raw []byte
+		err error
+	)
+	if m, ok := msg.(proto.Message); ok {
+		raw, err = protojson.MarshalOptions{UseProtoNames: true}.Marshal(m)
+	} else {
+		raw, err = json.Marshal(msg)
+	}
+	if err != nil {
+		return nil
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil
+	}
+	return out
+}
+
+// splitMethod splits "/pkg.Service/Method" into "pkg.Service" and "Method"
+func splitMethod(fullMethod string) (string, string) {
+	parts := strings.SplitN(strings.TrimPrefix(fullMethod, "/"), "/", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+	return parts[0], parts[1]
+}
@@ -0,0 +1,266 @@
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+type fakeEvents struct {
+	repository.AuditEventRepository
+	created []*manman.AuditEvent
+	err     error
+}
+
+func (f *fakeEvents) Create(ctx context.Context, e *manman.AuditEvent) (*manman.AuditEvent, error) {
+	f.created = append(f.created, e)
+	return e, f.err
+}
+
+type fakeSessions struct {
+	repository.SessionRepository
+	sessions map[int64]*manman.Session
+}
+
+func (f *fakeSessions) Get(ctx context.Context, sessionID int64) (*manman.Session, error) {
+	if s, ok := f.sessions[sessionID]; ok {
+		return s, nil
+	}
+	return nil, errors.New("not found")
+}
+
+// fakeGames serves GetGame for the update diff
+type fakeGames struct {
+	game *pb.Game
+}
+
+func (f *fakeGames) GetGame(ctx context.Context, req *pb.GetGameRequest) (*pb.GetGameResponse, error) {
+	if req.GameId != f.game.GameId {
+		return nil, status.Error(codes.NotFound, "game not found")
+	}
+	return &pb.GetGameResponse{Game: proto.Clone(f.game).(*pb.Game)}, nil
+}
+
+func TestIsMutating(t *testing.T) {
+	tests := map[string]bool{
+		"StartSession":         true,
+		"DeleteGame":           true,
+		"InstallAddon":         true,
+		"GetSession":           false,
+		"ListGames":            false,
+		"PreviewConfiguration": false,
+		"Heartbeat":            false,
+		"SendBatchedLogs":      false,
+		"":                     false,
+	}
+	for method, want := range tests {
+		if got := IsMutating(method); got != want {
+			t.Errorf("IsMutating(%q) = %v, want %v", method, got, want)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	req := map[string]interface{}{
+		"server_game_config_id": "7",
+		"rcon_password":         "hunter2",
+		"parameters": []interface{}{
+			map[string]interface{}{"key": "RCON_PASSWORD", "value": "hunter2"},
+			map[string]interface{}{"key": "max_players", "value": "16"},
+		},
+		"nested": map[string]interface{}{"steam_api_key": "abc"},
+		"url":    "https://discord.com/api/webhooks/1/token",
+		"motd":   strings.Repeat("x", maxStringLen+10),
+	}
+	got := Redact(req)
+
+	if got["rcon_password"] != redacted {
+		t.Errorf("rcon_password = %v", got["rcon_password"])
+	}
+	params := got["parameters"].([]interface{})
+	if params[0].(map[string]interface{})["value"] != redacted {
+		t.Errorf("sensitive parameter value kept: %v", params[0])
+	}
+	if params[1].(map[string]interface{})["value"] != "16" {
+		t.Errorf("plain parameter value lost: %v", params[1])
+	}
+	if got["url"] != redacted {
+		t.Errorf("url = %v", got["url"])
+	}
+	if got["nested"].(map[string]interface{})["steam_api_key"] != redacted {
+		t.Errorf("nested secret kept: %v", got["nested"])
+	}
+	if len(got["motd"].(string)) > maxStringLen+len("…") {
+		t.Errorf("long string not truncated")
+	}
+	// The input is left alone
+	if req["rcon_password"] != "hunter2" {
+		t.Errorf("Redact modified its input")
+	}
+}
+
+func TestRedactUpdatePaths(t *testing.T) {
+	got := Redact(map[string]interface{}{
+		"game_id":      "3",
+		"name":         "New name",
+		"steam_app_id": "12345",
+		"metadata":     map[string]interface{}{"a": "b"},
+		"update_paths": []interface{}{"name"},
+	})
+	// Fields the update leaves alone are kept; the record's changes show what it did
+	want := map[string]interface{}{
+		"game_id":      "3",
+		"name":         "New name",
+		"steam_app_id": "12345",
+		"metadata":     map[string]interface{}{"a": "b"},
+		"update_paths": []interface{}{"name"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Redact() = %v, want %v", got, want)
+	}
+}
+
+func TestRedactContent(t *testing.T) {
+	got := Redact(map[string]interface{}{
+		"patch_content": "rcon_password hunter2",
+		"patches": []interface{}{
+			map[string]interface{}{"patch_content": "hostname x"},
+		},
+		"input": base64.StdEncoding.EncodeToString([]byte("say hi")),
+	})
+
+	summary := func(text string) map[string]interface{} {
+		sum := sha256.Sum256([]byte(text))
+		return map[string]interface{}{"length": len(text), "sha256": hex.EncodeToString(sum[:])}
+	}
+	if want := summary("rcon_password hunter2"); !reflect.DeepEqual(got["patch_content"], want) {
+		t.Errorf("patch_content = %v, want %v", got["patch_content"], want)
+	}
+	patch := got["patches"].([]interface{})[0].(map[string]interface{})
+	if want := summary("hostname x"); !reflect.DeepEqual(patch["patch_content"], want) {
+		t.Errorf("patches[0].patch_content = %v, want %v", patch["patch_content"], want)
+	}
+	// Console input is measured after decoding
+	if want := summary("say hi"); !reflect.DeepEqual(got["input"], want) {
+		t.Errorf("input = %v, want %v", got["input"], want)
+	}
+}
+
+func TestEntityIDs(t *testing.T) {
+	req := map[string]interface{}{
+		"server_game_config_id": "7",
+		"restore_backup_id":     "0",
+		"name":                  "x",
+	}
+	resp := map[string]interface{}{
+		"session": map[string]interface{}{"session_id": "12", "server_game_config_id": "99"},
+		"count":   float64(1),
+	}
+	got := EntityIDs(req, resp)
+	want := map[string]int64{"server_game_config_id": 7, "session_id": 12}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("EntityIDs() = %v, want %v", got, want)
+	}
+}
+
+func TestUnaryInterceptor(t *testing.T) {
+	events := &fakeEvents{}
+	sessions := &fakeSessions{sessions: map[int64]*manman.Session{70: {SessionID: 70, SGCID: 5}}}
+	intercept := NewRecorder(events, sessions).UnaryInterceptor()
+	ctx := grpcauth.ContextWithClaims(context.Background(), &grpcauth.Claims{Subject: "u-1", Username: "alice"})
+	req := map[string]interface{}{"session_id": 70, "input": "say hi"}
+
+	denied := status.Error(codes.PermissionDenied, "nope")
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return nil, denied }
+	info := &grpc.UnaryServerInfo{FullMethod: pb.ManManAPI_SendInput_FullMethodName}
+	if _, err := intercept(ctx, req, info, handler); err != denied {
+		t.Fatalf("interceptor changed the error: %v", err)
+	}
+
+	if len(events.created) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events.created))
+	}
+	e := events.created[0]
+	if e.Subject != "u-1" || e.Username != "alice" || e.Method != "SendInput" || e.StatusCode != "PermissionDenied" {
+		t.Errorf("unexpected event %+v", e)
+	}
+	// The session's SGC is recorded too, so the call shows in the SGC's activity
+	if e.Entities["session_id"] != 70 || e.Entities["server_game_config_id"] != 5 {
+		t.Errorf("entities = %v", e.Entities)
+	}
+	if e.Error == nil || *e.Error != "nope" {
+		t.Errorf("error = %v", e.Error)
+	}
+
+	// Reads aren't recorded
+	info = &grpc.UnaryServerInfo{FullMethod: pb.ManManAPI_GetSession_FullMethodName}
+	if _, err := intercept(ctx, req, info, handler); err != denied {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events.created) != 1 {
+		t.Errorf("read was recorded")
+	}
+}
+
+func TestUnaryInterceptorWriteFailure(t *testing.T) {
+	events := &fakeEvents{err: errors.New("db down")}
+	intercept := NewRecorder(events, nil).UnaryInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+	info := &grpc.UnaryServerInfo{FullMethod: pb.ManManAPI_StopSession_FullMethodName}
+
+	resp, err := intercept(context.Background(), map[string]interface{}{}, info, handler)
+	if err != nil || resp != "ok" {
+		t.Errorf("a failed audit write must not fail the call: %v, %v", resp, err)
+	}
+}
+
+func TestUnaryInterceptorChanges(t *testing.T) {
+	events := &fakeEvents{}
+	games := &fakeGames{game: &pb.Game{GameId: 3, Name: "Old name", SteamAppId: "12345"}}
+	recorder := NewRecorder(events, nil)
+	recorder.SetReaders(games)
+	intercept := recorder.UnaryInterceptor()
+
+	update := func(ctx context.Context, req interface{}) (interface{}, error) {
+		games.game.Name = req.(*pb.UpdateGameRequest).Name
+		return &pb.UpdateGameResponse{Game: games.game}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: pb.ManManAPI_UpdateGame_FullMethodName}
+	req := &pb.UpdateGameRequest{GameId: 3, Name: "New name", UpdatePaths: []string{"name"}}
+	if _, err := intercept(context.Background(), req, info, update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(events.created) != 1 {
+		t.Fatalf("recorded %d events, want 1", len(events.created))
+	}
+	want := manman.JSONB{"name": map[string]interface{}{"before": "Old name", "after": "New name"}}
+	if got := events.created[0].Changes; !reflect.DeepEqual(got, want) {
+		t.Errorf("changes = %v, want %v", got, want)
+	}
+
+	// A failed update changed nothing
+	failed := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.InvalidArgument, "bad name")
+	}
+	if _, err := intercept(context.Background(), req, info, failed); err == nil {
+		t.Fatal("expected the handler's error")
+	}
+	if got := events.created[1].Changes; got != nil {
+		t.Errorf("failed update recorded changes %v", got)
+	}
+}
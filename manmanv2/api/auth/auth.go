@@ -0,0 +1,292 @@
+// Package auth implements the ManManV2 API's role model and enforces it in gRPC
+// interceptors on ManManAPI and WorkshopService, on top of libs/go/grpcauth.
+//
+// There are three realm roles, each implying the ones below it:
+//
+//	manman-viewer    read anything except rendered configuration (which carries secrets)
+//	manman-operator  start, stop, back up and send input or actions to any SGC's sessions
+//	manman-admin     everything, including the host-facing RPCs
+//
+// An SGCGrant gives a subject operator rights on a single SGC. Methods not listed in
+// the policy below require admin, so a new RPC is closed until someone opens it.
+package auth
+
+import (
+	"context"
+
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Realm role names, exactly as configured in Keycloak
+const (
+	RoleViewer   = "manman-viewer"
+	RoleOperator = "manman-operator"
+	RoleAdmin    = "manman-admin"
+)
+
+// Level orders the roles; a principal holds every level up to its highest role
+type Level int
+
+const (
+	LevelNone Level = iota
+	LevelViewer
+	LevelOperator
+	LevelAdmin
+)
+
+// String returns the level's short name as reported in CallerAccess.role
+func (l Level) String() string {
+	switch l {
+	case LevelViewer:
+		return "viewer"
+	case LevelOperator:
+		return "operator"
+	case LevelAdmin:
+		return "admin"
+	default:
+		return ""
+	}
+}
+
+// ParseLevel is the inverse of String: it returns the level a CallerAccess.role names, or
+// LevelNone for anything else
+func ParseLevel(role string) Level {
+	for _, l := range []Level{LevelViewer, LevelOperator, LevelAdmin} {
+		if role == l.String() {
+			return l
+		}
+	}
+	return LevelNone
+}
+
+// LevelOf returns the highest level claims hold
+func LevelOf(claims *grpcauth.Claims) Level {
+	level := LevelNone
+	if claims == nil {
+		return level
+	}
+	for _, r := range claims.Roles {
+		var l Level
+		switch r {
+		case RoleViewer:
+			l = LevelViewer
+		case RoleOperator:
+			l = LevelOperator
+		case RoleAdmin:
+			l = LevelAdmin
+		}
+		if l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// rule is what a method requires. sgcScoped methods also admit anyone granted the
+// SGC the request targets, resolved from its server_game_config_id or session_id.
+type rule struct {
+	level     Level
+	sgcScoped bool
+}
+
+var (
+	anyone   = rule{level: LevelNone}
+	viewer   = rule{level: LevelViewer}
+	operator = rule{level: LevelOperator, sgcScoped: true}
+)
+
+// policy maps full method names to their rule; unlisted methods of the guarded
+// services require admin
+var policy = map[string]rule{
+	pb.ManManAPI_GetCallerAccess_FullMethodName: anyone,
+
+	pb.ManManAPI_ListServers_FullMethodName:                 viewer,
+	pb.ManManAPI_GetServer_FullMethodName:                   viewer,
+	pb.ManManAPI_GetServerMetrics_FullMethodName:            viewer,
+	pb.ManManAPI_ListGames_FullMethodName:                   viewer,
+	pb.ManManAPI_GetGame_FullMethodName:                     viewer,
+	pb.ManManAPI_ListGameConfigs_FullMethodName:             viewer,
+	pb.ManManAPI_GetGameConfig_FullMethodName:               viewer,
+	pb.ManManAPI_ExportGameBundle_FullMethodName:            viewer,
+	pb.ManManAPI_ListServerGameConfigs_FullMethodName:       viewer,
+	pb.ManManAPI_GetServerGameConfig_FullMethodName:         viewer,
+	pb.ManManAPI_ListSGCSchedules_FullMethodName:            viewer,
+	pb.ManManAPI_GetSGCMigration_FullMethodName:             viewer,
+	pb.ManManAPI_ListSGCMigrations_FullMethodName:           viewer,
+	pb.ManManAPI_ListSessions_FullMethodName:                viewer,
+	pb.ManManAPI_GetSession_FullMethodName:                  viewer,
+	pb.ManManAPI_ListSessionPlayers_FullMethodName:          viewer,
+	pb.ManManAPI_GetPlayerStats_FullMethodName:              viewer,
+	pb.ManManAPI_ListBackups_FullMethodName:                 viewer,
+	pb.ManManAPI_GetBackup_FullMethodName:                   viewer,
+	pb.ManManAPI_GetBackupConfig_FullMethodName:             viewer,
+	pb.ManManAPI_ListBackupConfigs_FullMethodName:           viewer,
+	pb.ManManAPI_ListBackupConfigActions_FullMethodName:     viewer,
+	pb.ManManAPI_GetHistoricalLogs_FullMethodName:           viewer,
+	pb.ManManAPI_GetLogHistogram_FullMethodName:             viewer,
+	pb.ManManAPI_SearchLogs_FullMethodName:                  viewer,
+	pb.ManManAPI_ValidateDeployment_FullMethodName:          viewer,
+	pb.ManManAPI_ListConfigurationStrategies_FullMethodName: viewer,
+	pb.ManManAPI_ListConfigurationPatches_FullMethodName:    viewer,
+	pb.ManManAPI_GetGameConfigVolume_FullMethodName:         viewer,
+	pb.ManManAPI_ListGameConfigVolumes_FullMethodName:       viewer,
+	pb.ManManAPI_GetSessionActions_FullMethodName:           viewer,
+	pb.ManManAPI_ListActionDefinitions_FullMethodName:       viewer,
+	pb.ManManAPI_GetActionDefinition_FullMethodName:         viewer,
+	pb.ManManAPI_ListAuditEvents_FullMethodName:             viewer,
+	pb.ManManAPI_ListAlertRules_FullMethodName:              viewer,
+	pb.ManManAPI_ListAlertEvents_FullMethodName:             viewer,
+	// Watching is open to viewers; the handler authorizes each line of input as SendInput
+	pb.ManManAPI_AttachSession_FullMethodName: viewer,
+
+	pb.ManManAPI_StartSession_FullMethodName:  operator,
+	pb.ManManAPI_StopSession_FullMethodName:   operator,
+	pb.ManManAPI_SendInput_FullMethodName:     operator,
+	pb.ManManAPI_ExecuteAction_FullMethodName: operator,
+	pb.ManManAPI_CreateBackup_FullMethodName:  operator,
+	pb.ManManAPI_TriggerBackup_FullMethodName: operator,
+
+	pb.WorkshopService_GetAddon_FullMethodName:                 viewer,
+	pb.WorkshopService_ListAddons_FullMethodName:               viewer,
+	pb.WorkshopService_GetInstallation_FullMethodName:          viewer,
+	pb.WorkshopService_ListInstallations_FullMethodName:        viewer,
+	pb.WorkshopService_GetLibrary_FullMethodName:               viewer,
+	pb.WorkshopService_ListLibraries_FullMethodName:            viewer,
+	pb.WorkshopService_GetLibraryAddons_FullMethodName:         viewer,
+	pb.WorkshopService_GetChildLibraries_FullMethodName:        viewer,
+	pb.WorkshopService_ListSGCLibraries_FullMethodName:         viewer,
+	pb.WorkshopService_GetSGCLibraryAttachments_FullMethodName: viewer,
+	pb.WorkshopService_GetAddonPathPreset_FullMethodName:       viewer,
+	pb.WorkshopService_ListAddonPathPresets_FullMethodName:     viewer,
+}
+
+// guardedServices are the services whose every method is authorized; anything else
+// on the server (reflection, health) passes through
+var guardedServices = map[string]bool{
+	pb.ManManAPI_ServiceDesc.ServiceName:       true,
+	pb.WorkshopService_ServiceDesc.ServiceName: true,
+}
+
+// Authorizer enforces the policy. It needs the grant and session repositories to
+// resolve SGC-scoped requests.
+type Authorizer struct {
+	grants   repository.SGCGrantRepository
+	sessions repository.SessionRepository
+}
+
+func NewAuthorizer(grants repository.SGCGrantRepository, sessions repository.SessionRepository) *Authorizer {
+	return &Authorizer{grants: grants, sessions: sessions}
+}
+
+// UnaryInterceptor authorizes unary calls. Chain it after grpcauth's interceptor,
+// which puts the claims in the context.
+func (a *Authorizer) UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := a.Authorize(ctx, info.FullMethod, req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamInterceptor authorizes streaming calls. The request isn't known when a stream
+// opens, so SGC-scoped streams need the global role unless the handler calls
+// Authorize itself with the first message.
+func (a *Authorizer) StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := a.Authorize(ss.Context(), info.FullMethod, nil); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// Authorize returns nil if ctx's principal may call method with req,
+// codes.Unauthenticated if ctx carries no claims, and codes.PermissionDenied otherwise
+func (a *Authorizer) Authorize(ctx context.Context, method string, req interface{}) error {
+	if !guardedServices[serviceName(method)] {
+		return nil
+	}
+	claims, ok := grpcauth.ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "request carries no credentials")
+	}
+
+	r, ok := policy[method]
+	if !ok {
+		r = rule{level: LevelAdmin}
+	}
+	// Overrides change what runs on the host (image, env, rendered config), which a grant
+	// on the SGC doesn't cover
+	if start, ok := req.(*pb.StartSessionRequest); ok && (len(start.Env) > 0 || start.ImageTag != "" || len(start.Patches) > 0) {
+		r = rule{level: LevelOperator}
+	}
+	// Starting a game config rather than an SGC deploys a new SGC, which is admin work
+	if start, ok := req.(*pb.StartSessionRequest); ok && start.ServerGameConfigId == 0 {
+		r = rule{level: LevelAdmin}
+	}
+	// Restarting a crashed session is the host's job, and frees the crashed session's ports
+	if start, ok := req.(*pb.StartSessionRequest); ok && start.PreviousSessionId != 0 {
+		r = rule{level: LevelAdmin}
+	}
+	if LevelOf(claims) >= r.level {
+		return nil
+	}
+	if r.sgcScoped && req != nil {
+		sgcID, err := a.targetSGC(ctx, req)
+		if err != nil {
+			return err
+		}
+		if sgcID != 0 {
+			granted, err := a.grants.Exists(ctx, sgcID, claims.Subject)
+			if err != nil {
+				return status.Errorf(codes.Internal, "failed to check grants: %v", err)
+			}
+			if granted {
+				return nil
+			}
+		}
+	}
+	return status.Errorf(codes.PermissionDenied, "requires role %q", roleFor(r.level))
+}
+
+// targetSGC resolves the SGC a request acts on, 0 if it names none
+func (a *Authorizer) targetSGC(ctx context.Context, req interface{}) (int64, error) {
+	if r, ok := req.(interface{ GetServerGameConfigId() int64 }); ok && r.GetServerGameConfigId() != 0 {
+		return r.GetServerGameConfigId(), nil
+	}
+	if r, ok := req.(interface{ GetSessionId() int64 }); ok && r.GetSessionId() != 0 {
+		session, err := a.sessions.Get(ctx, r.GetSessionId())
+		if err != nil {
+			return 0, status.Errorf(codes.NotFound, "session not found: %v", err)
+		}
+		return session.SGCID, nil
+	}
+	return 0, nil
+}
+
+func roleFor(level Level) string {
+	switch level {
+	case LevelViewer:
+		return RoleViewer
+	case LevelOperator:
+		return RoleOperator
+	default:
+		return RoleAdmin
+	}
+}
+
+// serviceName extracts "pkg.Service" from "/pkg.Service/Method"
+func serviceName(fullMethod string) string {
+	for i := 1; i < len(fullMethod); i++ {
+		if fullMethod[i] == '/' {
+			return fullMethod[1:i]
+		}
+	}
+	return ""
+}
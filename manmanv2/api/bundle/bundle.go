@@ -0,0 +1,484 @@
+// Package bundle reads and writes game bundles: a Game with its GameConfigs, volumes,
+// backup configs, configuration strategies, patches, actions and addon path presets as one
+// versioned YAML or JSON document, so game definitions can live in git and move between
+// environments.
+//
+// Entities are matched on natural keys (names within their parent) rather than IDs, which
+// makes importing a bundle an idempotent upsert. Nothing is ever deleted by an import.
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/whale-net/everything/manmanv2/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Version is the bundle format written by Marshal. Parse rejects any other version.
+const Version = 1
+
+// Bundle formats
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
+// The bundle types carry only json tags: YAML is read and written by way of JSON, so both
+// formats share one schema, including the model types embedded below.
+
+// Bundle is the top-level document
+type Bundle struct {
+	Version int  `json:"version"`
+	Game    Game `json:"game"`
+}
+
+// Game is keyed by name
+type Game struct {
+	Name             string                      `json:"name"`
+	SteamAppID       string                      `json:"steam_app_id,omitempty"`
+	Metadata         map[string]interface{}      `json:"metadata,omitempty"`
+	PlayerEvents     *manman.PlayerEventPatterns `json:"player_events,omitempty"`
+	Strategies       []Strategy                  `json:"strategies,omitempty"`
+	Actions          []Action                    `json:"actions,omitempty"` // game-level actions
+	AddonPathPresets []AddonPathPreset           `json:"addon_path_presets,omitempty"`
+	Configs          []GameConfig                `json:"configs,omitempty"`
+}
+
+// GameConfig is keyed by name within the game
+type GameConfig struct {
+	Name           string                 `json:"name"`
+	Image          string                 `json:"image"`
+	ArgsTemplate   string                 `json:"args_template,omitempty"`
+	Env            map[string]string      `json:"env,omitempty"`
+	Entrypoint     []string               `json:"entrypoint,omitempty"`
+	Command        []string               `json:"command,omitempty"`
+	Resources      *manman.ResourceLimits `json:"resources,omitempty"`
+	ReadinessProbe *manman.ReadinessProbe `json:"readiness_probe,omitempty"`
+	InputTransport *manman.InputTransport `json:"input_transport,omitempty"`
+	StatusQuery    *manman.StatusQuery    `json:"status_query,omitempty"`
+	Volumes        []Volume               `json:"volumes,omitempty"`
+	Patches        []Patch                `json:"patches,omitempty"`
+	Actions        []Action               `json:"actions,omitempty"` // game_config-level actions
+}
+
+// Volume is keyed by name within its GameConfig
+type Volume struct {
+	Name          string   `json:"name"`
+	Description   string   `json:"description,omitempty"`
+	ContainerPath string   `json:"container_path"`
+	HostSubpath   string   `json:"host_subpath,omitempty"`
+	ReadOnly      bool     `json:"read_only,omitempty"`
+	Type          string   `json:"type,omitempty"` // bind (default) or named
+	Backups       []Backup `json:"backups,omitempty"`
+}
+
+// Backup is a backup config, keyed by path within its volume
+type Backup struct {
+	Path           string     `json:"path"`
+	CadenceMinutes int        `json:"cadence_minutes"`
+	Disabled       bool       `json:"disabled,omitempty"`
+	Retention      *Retention `json:"retention,omitempty"`
+}
+
+// Retention mirrors BackupConfig's retention rules; 0 disables a rule
+type Retention struct {
+	KeepLast      int   `json:"keep_last,omitempty"`
+	DailyDays     int   `json:"daily_days,omitempty"`
+	WeeklyWeeks   int   `json:"weekly_weeks,omitempty"`
+	MonthlyMonths int   `json:"monthly_months,omitempty"`
+	MaxTotalBytes int64 `json:"max_total_bytes,omitempty"`
+}
+
+// Strategy is a configuration strategy, keyed by name within the game
+type Strategy struct {
+	Name          string                 `json:"name"`
+	Description   string                 `json:"description,omitempty"`
+	Type          string                 `json:"type"`
+	TargetPath    string                 `json:"target_path,omitempty"`
+	BaseTemplate  string                 `json:"base_template,omitempty"`
+	RenderOptions map[string]interface{} `json:"render_options,omitempty"`
+	ApplyOrder    int                    `json:"apply_order,omitempty"`
+}
+
+// Patch is a GameConfig-level configuration patch, keyed by strategy and order within its
+// GameConfig
+type Patch struct {
+	Strategy     string `json:"strategy"`
+	Order        int    `json:"order,omitempty"`
+	Format       string `json:"format,omitempty"` // template (default)
+	Content      string `json:"content"`
+	Volume       string `json:"volume,omitempty"` // name of one of the GameConfig's volumes
+	PathOverride string `json:"path_override,omitempty"`
+}
+
+// Action is an action definition, keyed by name within its game or GameConfig
+type Action struct {
+	Name                 string       `json:"name"`
+	Label                string       `json:"label"`
+	Description          string       `json:"description,omitempty"`
+	Command              string       `json:"command"`
+	DisplayOrder         int          `json:"display_order,omitempty"`
+	Group                string       `json:"group,omitempty"`
+	ButtonStyle          string       `json:"button_style,omitempty"` // primary (default)
+	Icon                 string       `json:"icon,omitempty"`
+	RequiresConfirmation bool         `json:"requires_confirmation,omitempty"`
+	ConfirmationMessage  string       `json:"confirmation_message,omitempty"`
+	Disabled             bool         `json:"disabled,omitempty"`
+	Inputs               []InputField `json:"inputs,omitempty"`
+}
+
+// InputField is one of an action's parameters
+type InputField struct {
+	Name         string        `json:"name"`
+	Label        string        `json:"label"`
+	Type         string        `json:"type"`
+	Required     bool          `json:"required,omitempty"`
+	Placeholder  string        `json:"placeholder,omitempty"`
+	HelpText     string        `json:"help_text,omitempty"`
+	Default      string        `json:"default,omitempty"`
+	DisplayOrder int           `json:"display_order,omitempty"`
+	Pattern      string        `json:"pattern,omitempty"`
+	Min          *float64      `json:"min,omitempty"`
+	Max          *float64      `json:"max,omitempty"`
+	MinLength    *int          `json:"min_length,omitempty"`
+	MaxLength    *int          `json:"max_length,omitempty"`
+	Options      []InputOption `json:"options,omitempty"`
+}
+
+// InputOption is a choice of a select or radio field
+type InputOption struct {
+	Value        string `json:"value"`
+	Label        string `json:"label"`
+	DisplayOrder int    `json:"display_order,omitempty"`
+	Default      bool   `json:"default,omitempty"`
+}
+
+// AddonPathPreset is keyed by name within the game
+type AddonPathPreset struct {
+	Name             string `json:"name"`
+	Description      string `json:"description,omitempty"`
+	InstallationPath string `json:"installation_path"`
+}
+
+// Parse decodes a YAML or JSON bundle and validates it. Unknown fields are errors, so a
+// typo doesn't silently drop a setting.
+func Parse(data []byte) (*Bundle, error) {
+	// JSON is YAML, so everything goes through the YAML decoder into plain values first
+	var raw interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("bundle is empty")
+	}
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(encoded))
+	dec.DisallowUnknownFields()
+	var b Bundle
+	if err := dec.Decode(&b); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %w", err)
+	}
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Marshal encodes a bundle as YAML or JSON
+func Marshal(b *Bundle, format string) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.MarshalIndent(b, "", "  ")
+	case FormatYAML, "":
+	default:
+		return nil, fmt.Errorf("unknown bundle format %q", format)
+	}
+
+	// Going through a node keeps the struct field order; clearing the JSON styles lets the
+	// encoder pick plain scalars, block collections and literal blocks for templates
+	encoded, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(encoded, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// identifierPattern matches action and input field names, as the database requires
+var identifierPattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// Validate checks a bundle's version, required fields, enums and that names are unique
+// within their parent. Errors name the offending entity by its key.
+func (b *Bundle) Validate() error {
+	if b.Version != Version {
+		return fmt.Errorf("unsupported bundle version %d (expected %d)", b.Version, Version)
+	}
+	g := &b.Game
+	if g.Name == "" {
+		return fmt.Errorf("game.name is required")
+	}
+	if g.PlayerEvents != nil {
+		if err := g.PlayerEvents.Validate(); err != nil {
+			return fmt.Errorf("game.player_events: %w", err)
+		}
+	}
+
+	strategies := make(map[string]bool, len(g.Strategies))
+	for _, s := range g.Strategies {
+		key := "strategies/" + s.Name
+		if s.Name == "" {
+			return fmt.Errorf("strategies: name is required")
+		}
+		if strategies[s.Name] {
+			return fmt.Errorf("%s: duplicate name", key)
+		}
+		strategies[s.Name] = true
+		if !isValidStrategyType(s.Type) {
+			return fmt.Errorf("%s: unknown type %q", key, s.Type)
+		}
+	}
+
+	if err := validateActions("actions", g.Actions); err != nil {
+		return err
+	}
+
+	presets := make(map[string]bool, len(g.AddonPathPresets))
+	for _, p := range g.AddonPathPresets {
+		key := "addon_path_presets/" + p.Name
+		if p.Name == "" {
+			return fmt.Errorf("addon_path_presets: name is required")
+		}
+		if presets[p.Name] {
+			return fmt.Errorf("%s: duplicate name", key)
+		}
+		presets[p.Name] = true
+		if p.InstallationPath == "" {
+			return fmt.Errorf("%s: installation_path is required", key)
+		}
+	}
+
+	configs := make(map[string]bool, len(g.Configs))
+	for i := range g.Configs {
+		c := &g.Configs[i]
+		if c.Name == "" {
+			return fmt.Errorf("configs: name is required")
+		}
+		if configs[c.Name] {
+			return fmt.Errorf("configs/%s: duplicate name", c.Name)
+		}
+		configs[c.Name] = true
+		if err := validateConfig(c, strategies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateConfig(c *GameConfig, strategies map[string]bool) error {
+	key := "configs/" + c.Name
+	if c.Image == "" {
+		return fmt.Errorf("%s: image is required", key)
+	}
+	if r := c.Resources; r != nil {
+		if r.CPUMillicores < 0 || r.MemoryMB < 0 || r.PidsLimit < 0 {
+			return fmt.Errorf("%s: resources must not be negative", key)
+		}
+		ulimits := make(map[string]bool, len(r.Ulimits))
+		for _, u := range r.Ulimits {
+			if u.Name == "" || ulimits[u.Name] {
+				return fmt.Errorf("%s: ulimit names must be set and unique", key)
+			}
+			ulimits[u.Name] = true
+			if u.Soft < 0 || u.Hard < 0 || u.Soft > u.Hard {
+				return fmt.Errorf("%s: ulimit %s must have 0 <= soft <= hard", key, u.Name)
+			}
+		}
+	}
+	if c.ReadinessProbe != nil {
+		if err := c.ReadinessProbe.Validate(); err != nil {
+			return fmt.Errorf("%s: readiness_probe: %w", key, err)
+		}
+	}
+	if c.InputTransport != nil {
+		if err := c.InputTransport.Validate(); err != nil {
+			return fmt.Errorf("%s: input_transport: %w", key, err)
+		}
+	}
+	if c.StatusQuery != nil {
+		if err := c.StatusQuery.Validate(); err != nil {
+			return fmt.Errorf("%s: status_query: %w", key, err)
+		}
+	}
+
+	volumes := make(map[string]bool, len(c.Volumes))
+	for _, v := range c.Volumes {
+		vkey := key + "/volumes/" + v.Name
+		if v.Name == "" {
+			return fmt.Errorf("%s/volumes: name is required", key)
+		}
+		if volumes[v.Name] {
+			return fmt.Errorf("%s: duplicate name", vkey)
+		}
+		volumes[v.Name] = true
+		if v.ContainerPath == "" {
+			return fmt.Errorf("%s: container_path is required", vkey)
+		}
+		if v.Type != "" && v.Type != "bind" && v.Type != "named" {
+			return fmt.Errorf("%s: type must be bind or named", vkey)
+		}
+		paths := make(map[string]bool, len(v.Backups))
+		for _, bk := range v.Backups {
+			if bk.Path == "" {
+				return fmt.Errorf("%s/backups: path is required", vkey)
+			}
+			if paths[bk.Path] {
+				return fmt.Errorf("%s/backups/%s: duplicate path", vkey, bk.Path)
+			}
+			paths[bk.Path] = true
+			if bk.CadenceMinutes <= 0 {
+				return fmt.Errorf("%s/backups/%s: cadence_minutes must be > 0", vkey, bk.Path)
+			}
+			if r := bk.Retention; r != nil && (r.KeepLast < 0 || r.DailyDays < 0 || r.WeeklyWeeks < 0 || r.MonthlyMonths < 0 || r.MaxTotalBytes < 0) {
+				return fmt.Errorf("%s/backups/%s: retention must not be negative", vkey, bk.Path)
+			}
+		}
+	}
+
+	patches := make(map[string]bool, len(c.Patches))
+	for _, p := range c.Patches {
+		pkey := patchKey(key, p)
+		if !strategies[p.Strategy] {
+			return fmt.Errorf("%s: strategy %q is not in the bundle", pkey, p.Strategy)
+		}
+		if patches[pkey] {
+			return fmt.Errorf("%s: duplicate strategy and order", pkey)
+		}
+		patches[pkey] = true
+		if p.Volume != "" && !volumes[p.Volume] {
+			return fmt.Errorf("%s: volume %q is not one of the config's volumes", pkey, p.Volume)
+		}
+		if p.Format != "" && !isValidPatchFormat(p.Format) {
+			return fmt.Errorf("%s: unknown format %q", pkey, p.Format)
+		}
+	}
+
+	return validateActions(key+"/actions", c.Actions)
+}
+
+func validateActions(prefix string, actions []Action) error {
+	names := make(map[string]bool, len(actions))
+	for _, a := range actions {
+		key := prefix + "/" + a.Name
+		if !identifierPattern.MatchString(a.Name) {
+			return fmt.Errorf("%s: name must be snake_case", key)
+		}
+		if names[a.Name] {
+			return fmt.Errorf("%s: duplicate name", key)
+		}
+		names[a.Name] = true
+		if a.Label == "" || a.Command == "" {
+			return fmt.Errorf("%s: label and command are required", key)
+		}
+		if a.ButtonStyle != "" && !isValidButtonStyle(a.ButtonStyle) {
+			return fmt.Errorf("%s: unknown button_style %q", key, a.ButtonStyle)
+		}
+
+		fields := make(map[string]bool, len(a.Inputs))
+		for _, f := range a.Inputs {
+			fkey := key + "/inputs/" + f.Name
+			if !identifierPattern.MatchString(f.Name) {
+				return fmt.Errorf("%s: name must be snake_case", fkey)
+			}
+			if fields[f.Name] {
+				return fmt.Errorf("%s: duplicate name", fkey)
+			}
+			fields[f.Name] = true
+			if f.Label == "" {
+				return fmt.Errorf("%s: label is required", fkey)
+			}
+			if !isValidFieldType(f.Type) {
+				return fmt.Errorf("%s: unknown type %q", fkey, f.Type)
+			}
+			if f.Pattern != "" {
+				if _, err := regexp.Compile(f.Pattern); err != nil {
+					return fmt.Errorf("%s: invalid pattern: %w", fkey, err)
+				}
+			}
+			values := make(map[string]bool, len(f.Options))
+			for _, o := range f.Options {
+				if values[o.Value] {
+					return fmt.Errorf("%s: duplicate option %q", fkey, o.Value)
+				}
+				values[o.Value] = true
+			}
+		}
+	}
+	return nil
+}
+
+func isValidStrategyType(t string) bool {
+	switch t {
+	case manman.StrategyTypeCLIArgs, manman.StrategyTypeEnvVars, manman.StrategyTypeFileProperties,
+		manman.StrategyTypeFileJSON, manman.StrategyTypeFileYAML, manman.StrategyTypeFileINI,
+		manman.StrategyTypeFileXML, manman.StrategyTypeFileLua, manman.StrategyTypeFileCustom,
+		manman.StrategyTypeVolume:
+		return true
+	}
+	return false
+}
+
+func isValidPatchFormat(f string) bool {
+	switch f {
+	case manman.PatchFormatTemplate, manman.PatchFormatJSONMergePatch, manman.PatchFormatJSONPatch,
+		manman.PatchFormatYAMLMerge, manman.PatchFormatProperties:
+		return true
+	}
+	return false
+}
+
+func isValidFieldType(t string) bool {
+	switch t {
+	case manman.FieldTypeText, manman.FieldTypeNumber, manman.FieldTypeSelect, manman.FieldTypeTextarea,
+		manman.FieldTypeCheckbox, manman.FieldTypeRadio, manman.FieldTypeEmail, manman.FieldTypeURL:
+		return true
+	}
+	return false
+}
+
+func isValidButtonStyle(s string) bool {
+	switch s {
+	case manman.ButtonStylePrimary, manman.ButtonStyleSecondary, manman.ButtonStyleSuccess,
+		manman.ButtonStyleDanger, manman.ButtonStyleWarning, manman.ButtonStyleInfo,
+		manman.ButtonStyleLight, manman.ButtonStyleDark:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package bundle
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cs2Bundle = `
+version: 1
+game:
+  name: Counter-Strike 2
+  steam_app_id: "730"
+  metadata:
+    genre: FPS
+    tags: [cs2, competitive]
+  strategies:
+    - name: server.cfg
+      type: file_properties
+      target_path: /home/steam/cs2-dedicated/game/csgo/cfg/server.cfg
+      base_template: |
+        hostname "{{ .hostname }}"
+        sv_cheats 0
+  actions:
+    - name: change_map
+      label: Change Map
+      command: changelevel {{.map}}
+      inputs:
+        - name: map
+          label: Map
+          type: select
+          required: true
+          options:
+            - {value: de_dust2, label: Dust II, default: true}
+            - {value: de_inferno, label: Inferno, display_order: 1}
+  addon_path_presets:
+    - name: maps
+      installation_path: /home/steam/cs2-dedicated/game/csgo/maps
+  configs:
+    - name: Competitive
+      image: joedwards32/cs2:latest
+      env:
+        CS2_PORT: "27015"
+        CS2_RCONPW: changeme
+      resources:
+        memory_mb: 4096
+        ulimits:
+          - {name: nofile, soft: 1024, hard: 4096}
+      readiness_probe:
+        type: log
+        log_pattern: "GC Connection established"
+      input_transport:
+        type: rcon
+        port: 27015
+        password_env: CS2_RCONPW
+      volumes:
+        - name: cs2-data
+          container_path: /home/steam/cs2-dedicated
+          host_subpath: cs2-data
+          backups:
+            - path: game/csgo/save
+              cadence_minutes: 60
+              retention: {keep_last: 5}
+      patches:
+        - strategy: server.cfg
+          content: |
+            sv_cheats 0
+          volume: cs2-data
+      actions:
+        - name: restart_round
+          label: Restart Round
+          command: mp_restartgame 1
+          button_style: warning
+`
+
+func TestParse(t *testing.T) {
+	b, err := Parse([]byte(cs2Bundle))
+	require.NoError(t, err)
+	assert.Equal(t, "Counter-Strike 2", b.Game.Name)
+	require.Len(t, b.Game.Configs, 1)
+	c := b.Game.Configs[0]
+	assert.Equal(t, "27015", c.Env["CS2_PORT"])
+	assert.Equal(t, int32(4096), c.Resources.MemoryMB)
+	assert.Equal(t, "GC Connection established", c.ReadinessProbe.LogPattern)
+	assert.Equal(t, 5, c.Volumes[0].Backups[0].Retention.KeepLast)
+	assert.Len(t, b.Game.Actions[0].Inputs[0].Options, 2)
+
+	// JSON is read the same way
+	j, err := Parse([]byte(`{"version": 1, "game": {"name": "Minecraft", "configs": [{"name": "Vanilla", "image": "itzg/minecraft-server"}]}}`))
+	require.NoError(t, err)
+	assert.Equal(t, "Vanilla", j.Game.Configs[0].Name)
+
+	for name, tc := range map[string]struct{ bundle, err string }{
+		"wrong version":    {`{version: 2, game: {name: x}}`, "unsupported bundle version 2"},
+		"unknown field":    {`{version: 1, game: {name: x, colour: red}}`, `unknown field "colour"`},
+		"missing name":     {`{version: 1, game: {}}`, "game.name is required"},
+		"missing strategy": {`{version: 1, game: {name: x, configs: [{name: c, image: i, patches: [{strategy: nope, content: a}]}]}}`, `configs/c/patches/nope/0: strategy "nope" is not in the bundle`},
+		"unknown volume":   {`{version: 1, game: {name: x, strategies: [{name: s, type: cli_args}], configs: [{name: c, image: i, patches: [{strategy: s, volume: v}]}]}}`, `volume "v" is not one of the config's volumes`},
+		"bad action name":  {`{version: 1, game: {name: x, actions: [{name: Say Hi, label: l, command: say}]}}`, "actions/Say Hi: name must be snake_case"},
+		"bad probe":        {`{version: 1, game: {name: x, configs: [{name: c, image: i, readiness_probe: {type: tcp}}]}}`, "configs/c: readiness_probe: port must be between"},
+		"duplicate backup": {`{version: 1, game: {name: x, configs: [{name: c, image: i, volumes: [{name: v, container_path: /d, backups: [{path: a, cadence_minutes: 5}, {path: a, cadence_minutes: 9}]}]}]}}`, "backups/a: duplicate path"},
+		"empty":            {``, "bundle is empty"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(tc.bundle))
+			assert.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	b, err := Parse([]byte(cs2Bundle))
+	require.NoError(t, err)
+
+	for _, format := range []string{FormatYAML, FormatJSON} {
+		data, err := Marshal(b, format)
+		require.NoError(t, err)
+		back, err := Parse(data)
+		require.NoError(t, err, format)
+		assert.Equal(t, b, back, format)
+	}
+
+	yml, err := Marshal(b, FormatYAML)
+	require.NoError(t, err)
+	assert.Contains(t, string(yml), "version: 1\ngame:\n  name: Counter-Strike 2\n", "fields keep their order")
+	assert.Contains(t, string(yml), "base_template: |", "templates are literal blocks")
+	assert.Contains(t, string(yml), `steam_app_id: "730"`, "numeric strings stay strings")
+
+	_, err = Marshal(b, "toml")
+	assert.ErrorContains(t, err, "unknown bundle format")
+}
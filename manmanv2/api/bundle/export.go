@@ -0,0 +1,309 @@
+package bundle
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// ActionStore is the part of the action repository bundles use
+type ActionStore interface {
+	Get(ctx context.Context, actionID int64) (*manman.ActionDefinition, []*postgres.ActionInputFieldWithOptions, error)
+	ListByLevel(ctx context.Context, level string, entityID int64) ([]*manman.ActionDefinition, error)
+	Create(ctx context.Context, action *manman.ActionDefinition, fields []*manman.ActionInputField, options []*manman.ActionInputOption) (int64, error)
+	Update(ctx context.Context, action *manman.ActionDefinition, fields []*manman.ActionInputField, options []*manman.ActionInputOption) error
+}
+
+// Service exports games as bundles and imports bundles into the database
+type Service struct {
+	repo    *repository.Repository
+	actions ActionStore
+}
+
+func NewService(repo *repository.Repository, actions ActionStore) *Service {
+	return &Service{repo: repo, actions: actions}
+}
+
+// configPageSize is how many GameConfigs are fetched per List call
+const configPageSize = 100
+
+// Export builds the bundle for a game. SGC-level patches and actions aren't part of a
+// bundle; they belong to a deployment, not the game definition.
+func (s *Service) Export(ctx context.Context, gameID int64) (*Bundle, error) {
+	game, err := s.repo.Games.Get(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	b := &Bundle{Version: Version, Game: gameToBundle(game)}
+
+	strategies, err := s.repo.ConfigurationStrategies.ListByGame(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list strategies: %w", err)
+	}
+	for _, st := range strategies {
+		b.Game.Strategies = append(b.Game.Strategies, strategyToBundle(st))
+	}
+
+	if b.Game.Actions, err = s.exportActions(ctx, manman.ActionLevelGame, gameID); err != nil {
+		return nil, err
+	}
+
+	presets, err := s.repo.AddonPathPresets.ListByGame(ctx, gameID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list addon path presets: %w", err)
+	}
+	for _, p := range presets {
+		b.Game.AddonPathPresets = append(b.Game.AddonPathPresets, presetToBundle(p))
+	}
+
+	configs, err := s.listConfigs(ctx, gameID)
+	if err != nil {
+		return nil, err
+	}
+	for _, gc := range configs {
+		config, err := s.exportConfig(ctx, gc, strategies)
+		if err != nil {
+			return nil, err
+		}
+		b.Game.Configs = append(b.Game.Configs, config)
+	}
+	return b, nil
+}
+
+func (s *Service) exportConfig(ctx context.Context, gc *manman.GameConfig, strategies []*manman.ConfigurationStrategy) (GameConfig, error) {
+	config := configToBundle(gc)
+
+	volumes, err := s.repo.GameConfigVolumes.ListByGameConfig(ctx, gc.ConfigID)
+	if err != nil {
+		return config, fmt.Errorf("failed to list volumes of config %s: %w", gc.Name, err)
+	}
+	volumeNames := make(map[int64]string, len(volumes))
+	for _, v := range volumes {
+		volumeNames[v.VolumeID] = v.Name
+		volume := volumeToBundle(v)
+		backups, err := s.repo.BackupConfigs.List(ctx, v.VolumeID)
+		if err != nil {
+			return config, fmt.Errorf("failed to list backup configs of volume %s: %w", v.Name, err)
+		}
+		for _, bc := range backups {
+			volume.Backups = append(volume.Backups, backupToBundle(bc))
+		}
+		config.Volumes = append(config.Volumes, volume)
+	}
+
+	for _, st := range strategies {
+		patches, err := s.repo.ConfigurationPatches.ListByStrategyAndEntity(ctx, st.StrategyID, manman.PatchLevelGameConfig, gc.ConfigID)
+		if err != nil {
+			return config, fmt.Errorf("failed to list patches of config %s: %w", gc.Name, err)
+		}
+		for _, p := range patches {
+			config.Patches = append(config.Patches, patchToBundle(p, st.Name, volumeNames))
+		}
+	}
+
+	if config.Actions, err = s.exportActions(ctx, manman.ActionLevelGameConfig, gc.ConfigID); err != nil {
+		return config, err
+	}
+	return config, nil
+}
+
+func (s *Service) exportActions(ctx context.Context, level string, entityID int64) ([]Action, error) {
+	definitions, err := s.actions.ListByLevel(ctx, level, entityID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s actions: %w", level, err)
+	}
+	var actions []Action
+	for _, def := range definitions {
+		_, fields, err := s.actions.Get(ctx, def.ActionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get action %s: %w", def.Name, err)
+		}
+		actions = append(actions, actionToBundle(def, fields))
+	}
+	return actions, nil
+}
+
+// listConfigs pages through all of a game's GameConfigs
+func (s *Service) listConfigs(ctx context.Context, gameID int64) ([]*manman.GameConfig, error) {
+	var configs []*manman.GameConfig
+	for offset := 0; ; offset += configPageSize {
+		page, err := s.repo.GameConfigs.List(ctx, &gameID, configPageSize, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list game configs: %w", err)
+		}
+		configs = append(configs, page...)
+		if len(page) < configPageSize {
+			return configs, nil
+		}
+	}
+}
+
+// ============================================================================
+// Model to bundle conversions. Child collections are filled in by the caller.
+// ============================================================================
+
+func gameToBundle(g *manman.Game) Game {
+	return Game{
+		Name:         g.Name,
+		SteamAppID:   deref(g.SteamAppID),
+		Metadata:     g.Metadata,
+		PlayerEvents: manman.PlayerEventPatternsFromJSONB(g.PlayerEvents),
+	}
+}
+
+func configToBundle(gc *manman.GameConfig) GameConfig {
+	config := GameConfig{
+		Name:           gc.Name,
+		Image:          gc.Image,
+		ArgsTemplate:   deref(gc.ArgsTemplate),
+		Entrypoint:     jsonbToStrings(gc.Entrypoint),
+		Command:        jsonbToStrings(gc.Command),
+		ReadinessProbe: manman.ReadinessProbeFromJSONB(gc.ReadinessProbe),
+		InputTransport: manman.InputTransportFromJSONB(gc.InputTransport),
+		StatusQuery:    manman.StatusQueryFromJSONB(gc.StatusQuery),
+	}
+	if len(gc.EnvTemplate) > 0 {
+		config.Env = make(map[string]string, len(gc.EnvTemplate))
+		for k, v := range gc.EnvTemplate {
+			if str, ok := v.(string); ok {
+				config.Env[k] = str
+			}
+		}
+	}
+	if limits := manman.ResolveResourceLimits(gc, nil); !limits.IsZero() {
+		config.Resources = &limits
+	}
+	return config
+}
+
+func volumeToBundle(v *manman.GameConfigVolume) Volume {
+	return Volume{
+		Name:          v.Name,
+		Description:   deref(v.Description),
+		ContainerPath: v.ContainerPath,
+		HostSubpath:   deref(v.HostSubpath),
+		ReadOnly:      v.ReadOnly,
+		Type:          v.VolumeType,
+	}
+}
+
+func backupToBundle(bc *manman.BackupConfig) Backup {
+	backup := Backup{
+		Path:           bc.BackupPath,
+		CadenceMinutes: bc.CadenceMinutes,
+		Disabled:       !bc.Enabled,
+	}
+	if bc.HasRetention() {
+		backup.Retention = &Retention{
+			KeepLast:      bc.RetentionKeepLast,
+			DailyDays:     bc.RetentionDailyDays,
+			WeeklyWeeks:   bc.RetentionWeeklyWeeks,
+			MonthlyMonths: bc.RetentionMonthlyMonths,
+			MaxTotalBytes: bc.RetentionMaxTotalBytes,
+		}
+	}
+	return backup
+}
+
+func strategyToBundle(st *manman.ConfigurationStrategy) Strategy {
+	return Strategy{
+		Name:          st.Name,
+		Description:   deref(st.Description),
+		Type:          st.StrategyType,
+		TargetPath:    deref(st.TargetPath),
+		BaseTemplate:  deref(st.BaseTemplate),
+		RenderOptions: st.RenderOptions,
+		ApplyOrder:    st.ApplyOrder,
+	}
+}
+
+func patchToBundle(p *manman.ConfigurationPatch, strategy string, volumeNames map[int64]string) Patch {
+	patch := Patch{
+		Strategy:     strategy,
+		Order:        p.PatchOrder,
+		Format:       p.PatchFormat,
+		Content:      deref(p.PatchContent),
+		PathOverride: deref(p.PathOverride),
+	}
+	if p.VolumeID != nil {
+		patch.Volume = volumeNames[*p.VolumeID]
+	}
+	return patch
+}
+
+func actionToBundle(def *manman.ActionDefinition, fields []*postgres.ActionInputFieldWithOptions) Action {
+	action := Action{
+		Name:                 def.Name,
+		Label:                def.Label,
+		Description:          deref(def.Description),
+		Command:              def.CommandTemplate,
+		DisplayOrder:         def.DisplayOrder,
+		Group:                deref(def.GroupName),
+		ButtonStyle:          def.ButtonStyle,
+		Icon:                 deref(def.Icon),
+		RequiresConfirmation: def.RequiresConfirmation,
+		ConfirmationMessage:  deref(def.ConfirmationMessage),
+		Disabled:             !def.Enabled,
+	}
+	for _, fw := range fields {
+		f := fw.Field
+		input := InputField{
+			Name:         f.Name,
+			Label:        f.Label,
+			Type:         f.FieldType,
+			Required:     f.Required,
+			Placeholder:  deref(f.Placeholder),
+			HelpText:     deref(f.HelpText),
+			Default:      deref(f.DefaultValue),
+			DisplayOrder: f.DisplayOrder,
+			Pattern:      deref(f.Pattern),
+			Min:          f.MinValue,
+			Max:          f.MaxValue,
+			MinLength:    f.MinLength,
+			MaxLength:    f.MaxLength,
+		}
+		for _, o := range fw.Options {
+			input.Options = append(input.Options, InputOption{
+				Value:        o.Value,
+				Label:        o.Label,
+				DisplayOrder: o.DisplayOrder,
+				Default:      o.IsDefault,
+			})
+		}
+		action.Inputs = append(action.Inputs, input)
+	}
+	return action
+}
+
+func presetToBundle(p *manman.GameAddonPathPreset) AddonPathPreset {
+	return AddonPathPreset{
+		Name:             p.Name,
+		Description:      deref(p.Description),
+		InstallationPath: p.InstallationPath,
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// jsonbToStrings decodes an entrypoint or command column ({"items": [...]})
+func jsonbToStrings(j manman.JSONB) []string {
+	items, ok := j["items"].([]interface{})
+	if !ok {
+		return nil
+	}
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
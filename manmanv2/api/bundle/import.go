@@ -0,0 +1,692 @@
+package bundle
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// Kinds of entity a Change can be about
+const (
+	KindGame            = "game"
+	KindGameConfig      = "game_config"
+	KindVolume          = "volume"
+	KindBackupConfig    = "backup_config"
+	KindStrategy        = "strategy"
+	KindPatch           = "patch"
+	KindAction          = "action"
+	KindAddonPathPreset = "addon_path_preset"
+)
+
+// What an import does to an entity
+const (
+	OpCreate    = "create"
+	OpUpdate    = "update"
+	OpUnchanged = "unchanged"
+)
+
+// Change is what an import did, or would do, to one entity of the bundle
+type Change struct {
+	Kind   string
+	Key    string   // e.g. configs/Competitive/volumes/cs2-data
+	Op     string   // OpCreate | OpUpdate | OpUnchanged
+	Fields []string // bundle fields an update changes, sorted
+}
+
+// Result is the outcome of an import
+type Result struct {
+	GameID  int64 // 0 on a dry run that would create the game
+	Changes []Change
+}
+
+// Import upserts a validated bundle: each entity is looked up by its natural key, created
+// when missing and updated when it differs. Entities that exist but aren't in the bundle are
+// left alone. With dryRun nothing is written; children of entities that would be created
+// are all reported as creates.
+//
+// A real import runs in one transaction, so one that fails part way leaves the database as
+// it was. Import fills in the bundle's defaults.
+func (s *Service) Import(ctx context.Context, b *Bundle, dryRun bool) (*Result, error) {
+	b.setDefaults()
+	if dryRun {
+		return s.importBundle(ctx, b, true)
+	}
+	if s.repo.InTx == nil {
+		return nil, errors.New("bundle import needs a repository that supports transactions")
+	}
+
+	var result *Result
+	err := s.repo.InTx(ctx, func(tx *repository.Repository) error {
+		actions, ok := tx.Actions.(ActionStore)
+		if !ok {
+			return fmt.Errorf("actions repository %T can't store bundle actions", tx.Actions)
+		}
+		var err error
+		result, err = (&Service{repo: tx, actions: actions}).importBundle(ctx, b, false)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *Service) importBundle(ctx context.Context, b *Bundle, dryRun bool) (*Result, error) {
+	im := &importer{Service: s, dryRun: dryRun, strategyIDs: make(map[string]int64)}
+	if err := im.importGame(ctx, &b.Game); err != nil {
+		return nil, err
+	}
+	return &im.result, nil
+}
+
+type importer struct {
+	*Service
+	dryRun      bool
+	result      Result
+	strategyIDs map[string]int64 // by name; 0 when the strategy is only created on a real run
+}
+
+// record notes the change for one entity and returns its op. current is nil when the
+// entity doesn't exist; otherwise it and desired are compared field by field.
+func (im *importer) record(kind, key string, current, desired interface{}) string {
+	change := Change{Kind: kind, Key: key, Op: OpCreate}
+	if current != nil {
+		change.Fields = changedFields(current, desired)
+		change.Op = OpUnchanged
+		if len(change.Fields) > 0 {
+			change.Op = OpUpdate
+		}
+	}
+	im.result.Changes = append(im.result.Changes, change)
+	return change.Op
+}
+
+// write reports whether an op needs writing
+func (im *importer) write(op string) bool {
+	return !im.dryRun && op != OpUnchanged
+}
+
+func (im *importer) importGame(ctx context.Context, g *Game) error {
+	existing, err := im.repo.Games.GetByName(ctx, g.Name)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to look up game %s: %w", g.Name, err)
+	}
+	var current interface{}
+	if existing != nil {
+		current = gameToBundle(existing)
+		im.result.GameID = existing.GameID
+	}
+	desired := *g
+	desired.Strategies, desired.Actions, desired.AddonPathPresets, desired.Configs = nil, nil, nil, nil
+
+	op := im.record(KindGame, g.Name, current, desired)
+	if im.write(op) {
+		game := &manman.Game{
+			GameID:       im.result.GameID,
+			Name:         g.Name,
+			SteamAppID:   optional(g.SteamAppID),
+			Metadata:     g.Metadata,
+			PlayerEvents: g.PlayerEvents.ToJSONB(),
+		}
+		if op == OpCreate {
+			if game, err = im.repo.Games.Create(ctx, game); err != nil {
+				return fmt.Errorf("failed to create game %s: %w", g.Name, err)
+			}
+			im.result.GameID = game.GameID
+		} else if err := im.repo.Games.Update(ctx, game); err != nil {
+			return fmt.Errorf("failed to update game %s: %w", g.Name, err)
+		}
+	}
+	gameID := im.result.GameID
+
+	if err := im.importStrategies(ctx, gameID, g.Strategies); err != nil {
+		return err
+	}
+	if err := im.importActions(ctx, "actions", manman.ActionLevelGame, gameID, g.Actions); err != nil {
+		return err
+	}
+	if err := im.importPresets(ctx, gameID, g.AddonPathPresets); err != nil {
+		return err
+	}
+
+	existingConfigs := make(map[string]*manman.GameConfig)
+	if gameID != 0 {
+		configs, err := im.listConfigs(ctx, gameID)
+		if err != nil {
+			return err
+		}
+		for _, gc := range configs {
+			existingConfigs[gc.Name] = gc
+		}
+	}
+	for i := range g.Configs {
+		if err := im.importConfig(ctx, gameID, existingConfigs[g.Configs[i].Name], &g.Configs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (im *importer) importStrategies(ctx context.Context, gameID int64, strategies []Strategy) error {
+	existing := make(map[string]*manman.ConfigurationStrategy)
+	if gameID != 0 {
+		list, err := im.repo.ConfigurationStrategies.ListByGame(ctx, gameID)
+		if err != nil {
+			return fmt.Errorf("failed to list strategies: %w", err)
+		}
+		for _, st := range list {
+			existing[st.Name] = st
+		}
+	}
+
+	for _, st := range strategies {
+		var current interface{}
+		var strategyID int64
+		if e, ok := existing[st.Name]; ok {
+			current = strategyToBundle(e)
+			strategyID = e.StrategyID
+		}
+		op := im.record(KindStrategy, "strategies/"+st.Name, current, st)
+		if im.write(op) {
+			strategy := &manman.ConfigurationStrategy{
+				StrategyID:    strategyID,
+				GameID:        gameID,
+				Name:          st.Name,
+				Description:   optional(st.Description),
+				StrategyType:  st.Type,
+				TargetPath:    optional(st.TargetPath),
+				BaseTemplate:  optional(st.BaseTemplate),
+				RenderOptions: st.RenderOptions,
+				ApplyOrder:    st.ApplyOrder,
+			}
+			if op == OpCreate {
+				created, err := im.repo.ConfigurationStrategies.Create(ctx, strategy)
+				if err != nil {
+					return fmt.Errorf("failed to create strategy %s: %w", st.Name, err)
+				}
+				strategyID = created.StrategyID
+			} else if err := im.repo.ConfigurationStrategies.Update(ctx, strategy); err != nil {
+				return fmt.Errorf("failed to update strategy %s: %w", st.Name, err)
+			}
+		}
+		im.strategyIDs[st.Name] = strategyID
+	}
+	return nil
+}
+
+func (im *importer) importPresets(ctx context.Context, gameID int64, presets []AddonPathPreset) error {
+	existing := make(map[string]*manman.GameAddonPathPreset)
+	if gameID != 0 {
+		list, err := im.repo.AddonPathPresets.ListByGame(ctx, gameID)
+		if err != nil {
+			return fmt.Errorf("failed to list addon path presets: %w", err)
+		}
+		for _, p := range list {
+			existing[p.Name] = p
+		}
+	}
+
+	for _, p := range presets {
+		var current interface{}
+		var presetID int64
+		if e, ok := existing[p.Name]; ok {
+			current = presetToBundle(e)
+			presetID = e.PresetID
+		}
+		op := im.record(KindAddonPathPreset, "addon_path_presets/"+p.Name, current, p)
+		if !im.write(op) {
+			continue
+		}
+		preset := &manman.GameAddonPathPreset{
+			PresetID:         presetID,
+			GameID:           gameID,
+			Name:             p.Name,
+			Description:      optional(p.Description),
+			InstallationPath: p.InstallationPath,
+		}
+		if op == OpCreate {
+			if _, err := im.repo.AddonPathPresets.Create(ctx, preset); err != nil {
+				return fmt.Errorf("failed to create addon path preset %s: %w", p.Name, err)
+			}
+		} else if err := im.repo.AddonPathPresets.Update(ctx, preset); err != nil {
+			return fmt.Errorf("failed to update addon path preset %s: %w", p.Name, err)
+		}
+	}
+	return nil
+}
+
+func (im *importer) importConfig(ctx context.Context, gameID int64, existing *manman.GameConfig, c *GameConfig) error {
+	key := "configs/" + c.Name
+	var current interface{}
+	var configID int64
+	if existing != nil {
+		current = configToBundle(existing)
+		configID = existing.ConfigID
+	}
+	desired := *c
+	desired.Volumes, desired.Patches, desired.Actions = nil, nil, nil
+
+	op := im.record(KindGameConfig, key, current, desired)
+	if im.write(op) {
+		config := &manman.GameConfig{
+			ConfigID:       configID,
+			GameID:         gameID,
+			Name:           c.Name,
+			Image:          c.Image,
+			ArgsTemplate:   optional(c.ArgsTemplate),
+			Entrypoint:     stringsToJSONB(c.Entrypoint),
+			Command:        stringsToJSONB(c.Command),
+			ReadinessProbe: c.ReadinessProbe.ToJSONB(),
+			InputTransport: c.InputTransport.ToJSONB(),
+			StatusQuery:    c.StatusQuery.ToJSONB(),
+		}
+		if len(c.Env) > 0 {
+			config.EnvTemplate = make(manman.JSONB, len(c.Env))
+			for k, v := range c.Env {
+				config.EnvTemplate[k] = v
+			}
+		}
+		if r := c.Resources; r != nil {
+			config.CPUMillicores = optionalInt32(r.CPUMillicores)
+			config.MemoryMB = optionalInt32(r.MemoryMB)
+			config.PidsLimit = optionalInt32(r.PidsLimit)
+			config.Ulimits = manman.UlimitsToJSONB(r.Ulimits)
+		}
+		if op == OpCreate {
+			created, err := im.repo.GameConfigs.Create(ctx, config)
+			if err != nil {
+				return fmt.Errorf("failed to create game config %s: %w", c.Name, err)
+			}
+			configID = created.ConfigID
+		} else if err := im.repo.GameConfigs.Update(ctx, config); err != nil {
+			return fmt.Errorf("failed to update game config %s: %w", c.Name, err)
+		}
+	}
+
+	volumes, err := im.importVolumes(ctx, key, configID, c.Volumes)
+	if err != nil {
+		return err
+	}
+	if err := im.importPatches(ctx, key, configID, volumes, c.Patches); err != nil {
+		return err
+	}
+	return im.importActions(ctx, key+"/actions", manman.ActionLevelGameConfig, configID, c.Actions)
+}
+
+// importVolumes upserts a config's volumes and their backup configs
+func (im *importer) importVolumes(ctx context.Context, configKey string, configID int64, volumes []Volume) (*volumeIndex, error) {
+	index := &volumeIndex{ids: make(map[string]int64), names: make(map[int64]string)}
+	existing := make(map[string]*manman.GameConfigVolume)
+	if configID != 0 {
+		list, err := im.repo.GameConfigVolumes.ListByGameConfig(ctx, configID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list volumes of %s: %w", configKey, err)
+		}
+		for _, v := range list {
+			existing[v.Name] = v
+			index.names[v.VolumeID] = v.Name
+		}
+	}
+
+	for _, v := range volumes {
+		key := configKey + "/volumes/" + v.Name
+		var current interface{}
+		var volumeID int64
+		if e, ok := existing[v.Name]; ok {
+			current = volumeToBundle(e)
+			volumeID = e.VolumeID
+		}
+		desired := v
+		desired.Backups = nil
+
+		op := im.record(KindVolume, key, current, desired)
+		if im.write(op) {
+			volume := &manman.GameConfigVolume{
+				VolumeID:      volumeID,
+				ConfigID:      configID,
+				Name:          v.Name,
+				Description:   optional(v.Description),
+				ContainerPath: v.ContainerPath,
+				HostSubpath:   optional(v.HostSubpath),
+				ReadOnly:      v.ReadOnly,
+				VolumeType:    v.Type,
+			}
+			if op == OpCreate {
+				created, err := im.repo.GameConfigVolumes.Create(ctx, volume)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create volume %s: %w", key, err)
+				}
+				volumeID = created.VolumeID
+			} else if err := im.repo.GameConfigVolumes.Update(ctx, volume); err != nil {
+				return nil, fmt.Errorf("failed to update volume %s: %w", key, err)
+			}
+		}
+		index.ids[v.Name] = volumeID
+
+		if err := im.importBackups(ctx, key, volumeID, v.Backups); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+// volumeIndex resolves a config's volumes for its patches
+type volumeIndex struct {
+	ids   map[string]int64 // bundle volumes by name; 0 when only created on a real run
+	names map[int64]string // existing volumes by ID
+}
+
+func (im *importer) importBackups(ctx context.Context, volumeKey string, volumeID int64, backups []Backup) error {
+	existing := make(map[string]*manman.BackupConfig)
+	if volumeID != 0 {
+		list, err := im.repo.BackupConfigs.List(ctx, volumeID)
+		if err != nil {
+			return fmt.Errorf("failed to list backup configs of %s: %w", volumeKey, err)
+		}
+		for _, bc := range list {
+			existing[bc.BackupPath] = bc
+		}
+	}
+
+	for _, bk := range backups {
+		key := volumeKey + "/backups/" + bk.Path
+		var current interface{}
+		cfg := &manman.BackupConfig{VolumeID: volumeID}
+		if e, ok := existing[bk.Path]; ok {
+			current = backupToBundle(e)
+			cfg = e
+		}
+		op := im.record(KindBackupConfig, key, current, bk)
+		if !im.write(op) {
+			continue
+		}
+
+		cfg.BackupPath = bk.Path
+		cfg.CadenceMinutes = bk.CadenceMinutes
+		cfg.Enabled = !bk.Disabled
+		var retention Retention
+		if bk.Retention != nil {
+			retention = *bk.Retention
+		}
+		cfg.RetentionKeepLast = retention.KeepLast
+		cfg.RetentionDailyDays = retention.DailyDays
+		cfg.RetentionWeeklyWeeks = retention.WeeklyWeeks
+		cfg.RetentionMonthlyMonths = retention.MonthlyMonths
+		cfg.RetentionMaxTotalBytes = retention.MaxTotalBytes
+		if op == OpCreate {
+			if _, err := im.repo.BackupConfigs.Create(ctx, cfg); err != nil {
+				return fmt.Errorf("failed to create backup config %s: %w", key, err)
+			}
+		} else if err := im.repo.BackupConfigs.Update(ctx, cfg); err != nil {
+			return fmt.Errorf("failed to update backup config %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (im *importer) importPatches(ctx context.Context, configKey string, configID int64, volumes *volumeIndex, patches []Patch) error {
+	// A config's patches for one strategy, by order
+	existing := make(map[string]map[int]*manman.ConfigurationPatch)
+	for _, p := range patches {
+		key := patchKey(configKey, p)
+		strategyID := im.strategyIDs[p.Strategy]
+
+		byOrder, listed := existing[p.Strategy]
+		if !listed {
+			byOrder = make(map[int]*manman.ConfigurationPatch)
+			if strategyID != 0 && configID != 0 {
+				list, err := im.repo.ConfigurationPatches.ListByStrategyAndEntity(ctx, strategyID, manman.PatchLevelGameConfig, configID)
+				if err != nil {
+					return fmt.Errorf("failed to list patches of %s: %w", configKey, err)
+				}
+				for _, e := range list {
+					if _, dup := byOrder[e.PatchOrder]; !dup {
+						byOrder[e.PatchOrder] = e
+					}
+				}
+			}
+			existing[p.Strategy] = byOrder
+		}
+
+		var current interface{}
+		var patchID int64
+		if e, ok := byOrder[p.Order]; ok {
+			current = patchToBundle(e, p.Strategy, volumes.names)
+			patchID = e.PatchID
+		}
+		op := im.record(KindPatch, key, current, p)
+		if !im.write(op) {
+			continue
+		}
+
+		content := p.Content
+		patch := &manman.ConfigurationPatch{
+			PatchID:      patchID,
+			StrategyID:   strategyID,
+			PatchLevel:   manman.PatchLevelGameConfig,
+			EntityID:     configID,
+			PatchContent: &content,
+			PatchFormat:  p.Format,
+			PathOverride: optional(p.PathOverride),
+			PatchOrder:   p.Order,
+		}
+		if p.Volume != "" {
+			volumeID := volumes.ids[p.Volume]
+			patch.VolumeID = &volumeID
+		}
+		if op == OpCreate {
+			if _, err := im.repo.ConfigurationPatches.Create(ctx, patch); err != nil {
+				return fmt.Errorf("failed to create patch %s: %w", key, err)
+			}
+		} else if err := im.repo.ConfigurationPatches.Update(ctx, patch); err != nil {
+			return fmt.Errorf("failed to update patch %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func patchKey(configKey string, p Patch) string {
+	return fmt.Sprintf("%s/patches/%s/%d", configKey, p.Strategy, p.Order)
+}
+
+func (im *importer) importActions(ctx context.Context, prefix, level string, entityID int64, actions []Action) error {
+	existing := make(map[string]*manman.ActionDefinition)
+	if entityID != 0 {
+		list, err := im.actions.ListByLevel(ctx, level, entityID)
+		if err != nil {
+			return fmt.Errorf("failed to list %s actions: %w", level, err)
+		}
+		for _, def := range list {
+			existing[def.Name] = def
+		}
+	}
+
+	for _, a := range actions {
+		key := prefix + "/" + a.Name
+		var current interface{}
+		var actionID int64
+		if e, ok := existing[a.Name]; ok {
+			_, fields, err := im.actions.Get(ctx, e.ActionID)
+			if err != nil {
+				return fmt.Errorf("failed to get action %s: %w", key, err)
+			}
+			current = actionToBundle(e, fields)
+			actionID = e.ActionID
+		}
+		op := im.record(KindAction, key, current, a)
+		if !im.write(op) {
+			continue
+		}
+
+		def, fields, options := actionModel(a, level, entityID)
+		def.ActionID = actionID
+		if op == OpCreate {
+			if _, err := im.actions.Create(ctx, def, fields, options); err != nil {
+				return fmt.Errorf("failed to create action %s: %w", key, err)
+			}
+		} else if err := im.actions.Update(ctx, def, fields, options); err != nil {
+			return fmt.Errorf("failed to update action %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// actionModel converts an action with its inputs. The repository only writes fields it is
+// given and pairs options with fields by FieldID, so each field gets a placeholder ID that
+// its options share.
+func actionModel(a Action, level string, entityID int64) (*manman.ActionDefinition, []*manman.ActionInputField, []*manman.ActionInputOption) {
+	def := &manman.ActionDefinition{
+		DefinitionLevel:      level,
+		EntityID:             entityID,
+		Name:                 a.Name,
+		Label:                a.Label,
+		Description:          optional(a.Description),
+		CommandTemplate:      a.Command,
+		DisplayOrder:         a.DisplayOrder,
+		GroupName:            optional(a.Group),
+		ButtonStyle:          a.ButtonStyle,
+		Icon:                 optional(a.Icon),
+		RequiresConfirmation: a.RequiresConfirmation,
+		ConfirmationMessage:  optional(a.ConfirmationMessage),
+		Enabled:              !a.Disabled,
+	}
+
+	var fields []*manman.ActionInputField
+	var options []*manman.ActionInputOption
+	for i, in := range a.Inputs {
+		placeholderID := int64(i + 1)
+		fields = append(fields, &manman.ActionInputField{
+			FieldID:      placeholderID,
+			Name:         in.Name,
+			Label:        in.Label,
+			FieldType:    in.Type,
+			Required:     in.Required,
+			Placeholder:  optional(in.Placeholder),
+			HelpText:     optional(in.HelpText),
+			DefaultValue: optional(in.Default),
+			DisplayOrder: in.DisplayOrder,
+			Pattern:      optional(in.Pattern),
+			MinValue:     in.Min,
+			MaxValue:     in.Max,
+			MinLength:    in.MinLength,
+			MaxLength:    in.MaxLength,
+		})
+		for _, o := range in.Options {
+			options = append(options, &manman.ActionInputOption{
+				FieldID:      placeholderID,
+				Value:        o.Value,
+				Label:        o.Label,
+				DisplayOrder: o.DisplayOrder,
+				IsDefault:    o.Default,
+			})
+		}
+	}
+	return def, fields, options
+}
+
+// setDefaults fills in what the database would, so an entity written without its default
+// compares equal to the stored one
+func (b *Bundle) setDefaults() {
+	for i := range b.Game.Actions {
+		setActionDefaults(&b.Game.Actions[i])
+	}
+	for i := range b.Game.Configs {
+		c := &b.Game.Configs[i]
+		if c.Resources != nil {
+			sort.Slice(c.Resources.Ulimits, func(x, y int) bool { return c.Resources.Ulimits[x].Name < c.Resources.Ulimits[y].Name })
+		}
+		for j := range c.Volumes {
+			if c.Volumes[j].Type == "" {
+				c.Volumes[j].Type = "bind"
+			}
+		}
+		for j := range c.Patches {
+			if c.Patches[j].Format == "" {
+				c.Patches[j].Format = manman.PatchFormatTemplate
+			}
+		}
+		for j := range c.Actions {
+			setActionDefaults(&c.Actions[j])
+		}
+	}
+}
+
+func setActionDefaults(a *Action) {
+	if a.ButtonStyle == "" {
+		a.ButtonStyle = manman.ButtonStylePrimary
+	}
+}
+
+// changedFields returns the bundle field names whose values differ between two entities of
+// the same type. Empty objects and lists count as unset.
+func changedFields(current, desired interface{}) []string {
+	cur, des := jsonFields(current), jsonFields(desired)
+	var changed []string
+	for name, value := range des {
+		if !sameJSON(cur[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	for name, value := range cur {
+		if _, ok := des[name]; !ok && !sameJSON(value, nil) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+func jsonFields(v interface{}) map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(data, &fields)
+	return fields
+}
+
+func sameJSON(a, b json.RawMessage) bool {
+	return canonical(a) == canonical(b)
+}
+
+func canonical(raw json.RawMessage) string {
+	switch s := string(raw); s {
+	case "", "{}", "[]":
+		return "null"
+	default:
+		return s
+	}
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalInt32(v int32) *int32 {
+	if v == 0 {
+		return nil
+	}
+	return &v
+}
+
+// stringsToJSONB encodes an entrypoint or command column
+func stringsToJSONB(arr []string) manman.JSONB {
+	if len(arr) == 0 {
+		return nil
+	}
+	items := make([]interface{}, len(arr))
+	for i, s := range arr {
+		items[i] = s
+	}
+	return manman.JSONB{"items": items}
+}
@@ -0,0 +1,501 @@
+package bundle
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// fakeDB holds every table the bundle service touches. IDs come from one sequence.
+type fakeDB struct {
+	nextID     int64
+	games      []*manman.Game
+	configs    []*manman.GameConfig
+	volumes    []*manman.GameConfigVolume
+	backups    []*manman.BackupConfig
+	strategies []*manman.ConfigurationStrategy
+	patches    []*manman.ConfigurationPatch
+	presets    []*manman.GameAddonPathPreset
+	actions    []*manman.ActionDefinition
+	fields     map[int64][]*postgres.ActionInputFieldWithOptions
+	writes     int
+
+	failAction string // Create fails for the action of this name
+}
+
+func (db *fakeDB) id() int64 {
+	db.nextID++
+	db.writes++
+	return db.nextID
+}
+
+func (db *fakeDB) repo() *repository.Repository {
+	return &repository.Repository{
+		Games:                   fakeGames{fakeDB: db},
+		GameConfigs:             fakeConfigs{fakeDB: db},
+		GameConfigVolumes:       fakeVolumes{fakeDB: db},
+		BackupConfigs:           fakeBackups{fakeDB: db},
+		ConfigurationStrategies: fakeStrategies{fakeDB: db},
+		ConfigurationPatches:    fakePatches{fakeDB: db},
+		AddonPathPresets:        fakePresets{fakeDB: db},
+		Actions:                 fakeActions{db},
+		InTx:                    db.inTx,
+	}
+}
+
+// inTx puts the tables back as they were when fn fails, as rolling back a transaction
+// would. Entities are only appended or replaced, so restoring the slices is enough.
+func (db *fakeDB) inTx(ctx context.Context, fn func(tx *repository.Repository) error) error {
+	saved := *db
+	saved.fields = make(map[int64][]*postgres.ActionInputFieldWithOptions, len(db.fields))
+	for id, f := range db.fields {
+		saved.fields[id] = f
+	}
+	if err := fn(db.repo()); err != nil {
+		*db = saved
+		return err
+	}
+	return nil
+}
+
+type fakeGames struct {
+	repository.GameRepository
+	*fakeDB
+}
+
+func (f fakeGames) Create(_ context.Context, g *manman.Game) (*manman.Game, error) {
+	g.GameID = f.id()
+	f.games = append(f.games, g)
+	return g, nil
+}
+
+func (f fakeGames) Get(_ context.Context, id int64) (*manman.Game, error) {
+	for _, g := range f.games {
+		if g.GameID == id {
+			return g, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f fakeGames) GetByName(_ context.Context, name string) (*manman.Game, error) {
+	for _, g := range f.games {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f fakeGames) Update(_ context.Context, g *manman.Game) error {
+	f.writes++
+	for i, e := range f.games {
+		if e.GameID == g.GameID {
+			f.games[i] = g
+		}
+	}
+	return nil
+}
+
+type fakeConfigs struct {
+	repository.GameConfigRepository
+	*fakeDB
+}
+
+func (f fakeConfigs) Create(_ context.Context, c *manman.GameConfig) (*manman.GameConfig, error) {
+	c.ConfigID = f.id()
+	f.configs = append(f.configs, c)
+	return c, nil
+}
+
+func (f fakeConfigs) List(_ context.Context, gameID *int64, limit, offset int) ([]*manman.GameConfig, error) {
+	var out []*manman.GameConfig
+	for _, c := range f.configs {
+		if c.GameID == *gameID {
+			out = append(out, c)
+		}
+	}
+	if offset >= len(out) {
+		return nil, nil
+	}
+	return out[offset:min(offset+limit, len(out))], nil
+}
+
+func (f fakeConfigs) Update(_ context.Context, c *manman.GameConfig) error {
+	f.writes++
+	for i, e := range f.configs {
+		if e.ConfigID == c.ConfigID {
+			f.configs[i] = c
+		}
+	}
+	return nil
+}
+
+type fakeVolumes struct {
+	repository.GameConfigVolumeRepository
+	*fakeDB
+}
+
+func (f fakeVolumes) Create(_ context.Context, v *manman.GameConfigVolume) (*manman.GameConfigVolume, error) {
+	v.VolumeID = f.id()
+	f.volumes = append(f.volumes, v)
+	return v, nil
+}
+
+func (f fakeVolumes) ListByGameConfig(_ context.Context, configID int64) ([]*manman.GameConfigVolume, error) {
+	var out []*manman.GameConfigVolume
+	for _, v := range f.volumes {
+		if v.ConfigID == configID {
+			out = append(out, v)
+		}
+	}
+	return out, nil
+}
+
+func (f fakeVolumes) Update(_ context.Context, v *manman.GameConfigVolume) error {
+	f.writes++
+	for i, e := range f.volumes {
+		if e.VolumeID == v.VolumeID {
+			f.volumes[i] = v
+		}
+	}
+	return nil
+}
+
+type fakeBackups struct {
+	repository.BackupConfigRepository
+	*fakeDB
+}
+
+func (f fakeBackups) Create(_ context.Context, c *manman.BackupConfig) (*manman.BackupConfig, error) {
+	c.BackupConfigID = f.id()
+	f.backups = append(f.backups, c)
+	return c, nil
+}
+
+func (f fakeBackups) List(_ context.Context, volumeID int64) ([]*manman.BackupConfig, error) {
+	var out []*manman.BackupConfig
+	for _, c := range f.backups {
+		if c.VolumeID == volumeID {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+func (f fakeBackups) Update(_ context.Context, c *manman.BackupConfig) error {
+	f.writes++
+	return nil
+}
+
+type fakeStrategies struct {
+	repository.ConfigurationStrategyRepository
+	*fakeDB
+}
+
+func (f fakeStrategies) Create(_ context.Context, s *manman.ConfigurationStrategy) (*manman.ConfigurationStrategy, error) {
+	s.StrategyID = f.id()
+	f.strategies = append(f.strategies, s)
+	return s, nil
+}
+
+func (f fakeStrategies) ListByGame(_ context.Context, gameID int64) ([]*manman.ConfigurationStrategy, error) {
+	var out []*manman.ConfigurationStrategy
+	for _, s := range f.strategies {
+		if s.GameID == gameID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f fakeStrategies) Update(_ context.Context, s *manman.ConfigurationStrategy) error {
+	f.writes++
+	for i, e := range f.strategies {
+		if e.StrategyID == s.StrategyID {
+			f.strategies[i] = s
+		}
+	}
+	return nil
+}
+
+type fakePatches struct {
+	repository.ConfigurationPatchRepository
+	*fakeDB
+}
+
+func (f fakePatches) Create(_ context.Context, p *manman.ConfigurationPatch) (*manman.ConfigurationPatch, error) {
+	p.PatchID = f.id()
+	f.patches = append(f.patches, p)
+	return p, nil
+}
+
+func (f fakePatches) ListByStrategyAndEntity(_ context.Context, strategyID int64, level string, entityID int64) ([]*manman.ConfigurationPatch, error) {
+	var out []*manman.ConfigurationPatch
+	for _, p := range f.patches {
+		if p.StrategyID == strategyID && p.PatchLevel == level && p.EntityID == entityID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f fakePatches) Update(_ context.Context, p *manman.ConfigurationPatch) error {
+	f.writes++
+	for i, e := range f.patches {
+		if e.PatchID == p.PatchID {
+			f.patches[i] = p
+		}
+	}
+	return nil
+}
+
+type fakePresets struct {
+	repository.AddonPathPresetRepository
+	*fakeDB
+}
+
+func (f fakePresets) Create(_ context.Context, p *manman.GameAddonPathPreset) (*manman.GameAddonPathPreset, error) {
+	p.PresetID = f.id()
+	f.presets = append(f.presets, p)
+	return p, nil
+}
+
+func (f fakePresets) ListByGame(_ context.Context, gameID int64) ([]*manman.GameAddonPathPreset, error) {
+	var out []*manman.GameAddonPathPreset
+	for _, p := range f.presets {
+		if p.GameID == gameID {
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+func (f fakePresets) Update(_ context.Context, p *manman.GameAddonPathPreset) error {
+	f.writes++
+	return nil
+}
+
+// fakeActions stores fields with their options the way the postgres repository pairs
+// them: by the FieldID placeholders given to Create
+type fakeActions struct {
+	*fakeDB
+}
+
+func (f fakeActions) Get(_ context.Context, actionID int64) (*manman.ActionDefinition, []*postgres.ActionInputFieldWithOptions, error) {
+	for _, a := range f.actions {
+		if a.ActionID == actionID {
+			return a, f.fields[actionID], nil
+		}
+	}
+	return nil, nil, pgx.ErrNoRows
+}
+
+func (f fakeActions) ListByLevel(_ context.Context, level string, entityID int64) ([]*manman.ActionDefinition, error) {
+	var out []*manman.ActionDefinition
+	for _, a := range f.actions {
+		if a.DefinitionLevel == level && a.EntityID == entityID {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (f fakeActions) Create(_ context.Context, a *manman.ActionDefinition, fields []*manman.ActionInputField, options []*manman.ActionInputOption) (int64, error) {
+	if a.Name == f.failAction {
+		return 0, errors.New("action insert failed")
+	}
+	a.ActionID = f.id()
+	f.actions = append(f.actions, a)
+	f.setFields(a.ActionID, fields, options)
+	return a.ActionID, nil
+}
+
+func (f fakeActions) Update(_ context.Context, a *manman.ActionDefinition, fields []*manman.ActionInputField, options []*manman.ActionInputOption) error {
+	f.writes++
+	for i, e := range f.actions {
+		if e.ActionID == a.ActionID {
+			f.actions[i] = a
+		}
+	}
+	f.setFields(a.ActionID, fields, options)
+	return nil
+}
+
+func (f fakeActions) setFields(actionID int64, fields []*manman.ActionInputField, options []*manman.ActionInputOption) {
+	var stored []*postgres.ActionInputFieldWithOptions
+	for _, field := range fields {
+		fw := &postgres.ActionInputFieldWithOptions{Field: field}
+		for _, o := range options {
+			if o.FieldID == field.FieldID || o.FieldID == 0 {
+				fw.Options = append(fw.Options, o)
+			}
+		}
+		stored = append(stored, fw)
+	}
+	f.fields[actionID] = stored
+}
+
+func newFakeService() (*Service, *fakeDB) {
+	db := &fakeDB{fields: make(map[int64][]*postgres.ActionInputFieldWithOptions)}
+	return NewService(db.repo(), fakeActions{db}), db
+}
+
+func ops(result *Result) map[string]string {
+	out := make(map[string]string, len(result.Changes))
+	for _, c := range result.Changes {
+		out[c.Key] = c.Op
+	}
+	return out
+}
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	svc, db := newFakeService()
+	parse := func() *Bundle {
+		b, err := Parse([]byte(cs2Bundle))
+		require.NoError(t, err)
+		return b
+	}
+
+	// A dry run against an empty database creates everything and writes nothing
+	result, err := svc.Import(ctx, parse(), true)
+	require.NoError(t, err)
+	assert.Zero(t, result.GameID)
+	assert.Zero(t, db.writes)
+	assert.Equal(t, map[string]string{
+		"Counter-Strike 2":                     OpCreate,
+		"strategies/server.cfg":                OpCreate,
+		"actions/change_map":                   OpCreate,
+		"addon_path_presets/maps":              OpCreate,
+		"configs/Competitive":                  OpCreate,
+		"configs/Competitive/volumes/cs2-data": OpCreate,
+		"configs/Competitive/volumes/cs2-data/backups/game/csgo/save": OpCreate,
+		"configs/Competitive/patches/server.cfg/0":                    OpCreate,
+		"configs/Competitive/actions/restart_round":                   OpCreate,
+	}, ops(result))
+
+	result, err = svc.Import(ctx, parse(), false)
+	require.NoError(t, err)
+	gameID := result.GameID
+	require.NotZero(t, gameID)
+	require.Len(t, db.patches, 1)
+	assert.Equal(t, db.volumes[0].VolumeID, *db.patches[0].VolumeID, "patch points at the volume by name")
+	require.Len(t, db.fields[db.actions[0].ActionID], 1)
+	assert.Len(t, db.fields[db.actions[0].ActionID][0].Options, 2)
+
+	// Importing the same bundle again changes nothing
+	writes := db.writes
+	result, err = svc.Import(ctx, parse(), false)
+	require.NoError(t, err)
+	assert.Equal(t, gameID, result.GameID)
+	for _, c := range result.Changes {
+		assert.Equal(t, OpUnchanged, c.Op, c.Key)
+	}
+	assert.Equal(t, writes, db.writes)
+
+	// An export of what was imported reads back as the same bundle
+	exported, err := svc.Export(ctx, gameID)
+	require.NoError(t, err)
+	data, err := Marshal(exported, FormatYAML)
+	require.NoError(t, err)
+	reparsed, err := Parse(data)
+	require.NoError(t, err)
+	result, err = svc.Import(ctx, reparsed, true)
+	require.NoError(t, err)
+	for _, c := range result.Changes {
+		assert.Equal(t, OpUnchanged, c.Op, c.Key)
+	}
+
+	// Edits show up as field level updates next to new entities
+	b := parse()
+	b.Game.Configs[0].Image = "joedwards32/cs2:v2"
+	b.Game.Configs[0].Env["CS2_MAXPLAYERS"] = "12"
+	b.Game.Configs[0].Volumes = append(b.Game.Configs[0].Volumes, Volume{Name: "demos", ContainerPath: "/demos"})
+	b.Game.Actions[0].Inputs[0].Options[1].Label = "Inferno (new)"
+	result, err = svc.Import(ctx, b, true)
+	require.NoError(t, err)
+	assert.Equal(t, writes, db.writes)
+	for _, c := range result.Changes {
+		switch c.Key {
+		case "configs/Competitive":
+			assert.Equal(t, OpUpdate, c.Op)
+			assert.Equal(t, []string{"env", "image"}, c.Fields)
+		case "actions/change_map":
+			assert.Equal(t, []string{"inputs"}, c.Fields)
+		case "configs/Competitive/volumes/demos":
+			assert.Equal(t, OpCreate, c.Op)
+		default:
+			assert.Equal(t, OpUnchanged, c.Op, c.Key)
+		}
+	}
+
+	result, err = svc.Import(ctx, b, false)
+	require.NoError(t, err)
+	assert.Equal(t, "joedwards32/cs2:v2", db.configs[0].Image)
+	assert.Len(t, db.volumes, 2)
+	assert.Equal(t, "Inferno (new)", db.fields[db.actions[0].ActionID][0].Options[1].Label)
+}
+
+func TestImportFailureWritesNothing(t *testing.T) {
+	ctx := context.Background()
+	svc, db := newFakeService()
+	b, err := Parse([]byte(cs2Bundle))
+	require.NoError(t, err)
+
+	// The config-level action is written after the game, its strategy and config
+	db.failAction = "restart_round"
+	_, err = svc.Import(ctx, b, false)
+	require.Error(t, err)
+	assert.Empty(t, db.games)
+	assert.Empty(t, db.configs)
+	assert.Empty(t, db.strategies)
+	assert.Empty(t, db.actions)
+
+	// Once the cause is fixed the import goes through as a whole
+	db.failAction = ""
+	result, err := svc.Import(ctx, b, false)
+	require.NoError(t, err)
+	assert.NotZero(t, result.GameID)
+	assert.Len(t, db.actions, 2)
+}
+
+// The bundles shipped in manmanv2/bundles import cleanly and re-import as unchanged
+func TestImportShippedBundles(t *testing.T) {
+	paths, err := filepath.Glob("../../bundles/*.yaml")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	for _, path := range paths {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			ctx := context.Background()
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			b, err := Parse(data)
+			require.NoError(t, err)
+
+			svc, _ := newFakeService()
+			_, err = svc.Import(ctx, b, false)
+			require.NoError(t, err)
+			result, err := svc.Import(ctx, b, true)
+			require.NoError(t, err)
+			for _, c := range result.Changes {
+				assert.Equal(t, OpUnchanged, c.Op, c.Key)
+			}
+		})
+	}
+}
+
+func TestExportNotFound(t *testing.T) {
+	svc, _ := newFakeService()
+	_, err := svc.Export(context.Background(), 42)
+	assert.ErrorIs(t, err, pgx.ErrNoRows)
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/manmanv2/api/auth"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AccessHandler reports the caller's access and manages per-SGC grants. Enforcement
+// happens in the auth package's interceptors, not here.
+type AccessHandler struct {
+	grantRepo repository.SGCGrantRepository
+	sgcRepo   repository.ServerGameConfigRepository
+}
+
+func NewAccessHandler(grantRepo repository.SGCGrantRepository, sgcRepo repository.ServerGameConfigRepository) *AccessHandler {
+	return &AccessHandler{
+		grantRepo: grantRepo,
+		sgcRepo:   sgcRepo,
+	}
+}
+
+func (h *AccessHandler) GetCallerAccess(ctx context.Context, req *pb.GetCallerAccessRequest) (*pb.GetCallerAccessResponse, error) {
+	claims, ok := grpcauth.ClaimsFromContext(ctx)
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "request carries no credentials")
+	}
+
+	access := &pb.CallerAccess{
+		Subject: claims.Subject,
+		Role:    auth.LevelOf(claims).String(),
+	}
+	sgcIDs, err := h.grantRepo.ListSGCIDs(ctx, claims.Subject)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list grants: %v", err)
+	}
+	access.OperatorSgcIds = sgcIDs
+	return &pb.GetCallerAccessResponse{Access: access}, nil
+}
+
+func (h *AccessHandler) ListSGCGrants(ctx context.Context, req *pb.ListSGCGrantsRequest) (*pb.ListSGCGrantsResponse, error) {
+	grants, err := h.grantRepo.List(ctx, req.ServerGameConfigId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list grants: %v", err)
+	}
+	pbGrants := make([]*pb.SGCGrant, len(grants))
+	for i, g := range grants {
+		pbGrants[i] = sgcGrantToProto(g)
+	}
+	return &pb.ListSGCGrantsResponse{Grants: pbGrants}, nil
+}
+
+func (h *AccessHandler) CreateSGCGrant(ctx context.Context, req *pb.CreateSGCGrantRequest) (*pb.CreateSGCGrantResponse, error) {
+	subject := strings.TrimSpace(req.Subject)
+	if subject == "" {
+		return nil, status.Error(codes.InvalidArgument, "subject is required")
+	}
+	if _, err := h.sgcRepo.Get(ctx, req.ServerGameConfigId); err != nil {
+		return nil, status.Errorf(codes.NotFound, "server game config not found: %v", err)
+	}
+
+	grant := &manman.SGCGrant{
+		SGCID:   req.ServerGameConfigId,
+		Subject: subject,
+		Note:    req.Note,
+	}
+	if claims, ok := grpcauth.ClaimsFromContext(ctx); ok {
+		grant.CreatedBy = claims.Subject
+	}
+	grant, err := h.grantRepo.Create(ctx, grant)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create grant: %v", err)
+	}
+	return &pb.CreateSGCGrantResponse{Grant: sgcGrantToProto(grant)}, nil
+}
+
+func (h *AccessHandler) DeleteSGCGrant(ctx context.Context, req *pb.DeleteSGCGrantRequest) (*pb.DeleteSGCGrantResponse, error) {
+	if err := h.grantRepo.Delete(ctx, req.GrantId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete grant: %v", err)
+	}
+	return &pb.DeleteSGCGrantResponse{}, nil
+}
+
+func sgcGrantToProto(g *manman.SGCGrant) *pb.SGCGrant {
+	return &pb.SGCGrant{
+		GrantId:            g.GrantID,
+		ServerGameConfigId: g.SGCID,
+		Subject:            g.Subject,
+		Note:               g.Note,
+		CreatedBy:          g.CreatedBy,
+		CreatedAt:          g.CreatedAt.Unix(),
+	}
+}
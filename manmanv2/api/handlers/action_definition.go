@@ -23,8 +23,8 @@ func (h *ActionHandler) GetSessionActions(ctx context.Context, req *pb.GetSessio
 		return nil, status.Errorf(codes.NotFound, "session not found: %v", err)
 	}
 
-	// Only allow actions for running sessions
-	if session.Status != manman.SessionStatusRunning {
+	// Only allow actions for live sessions
+	if !session.IsLive() {
 		return &pb.GetSessionActionsResponse{
 			Actions: []*pb.ActionDefinition{},
 		}, nil
@@ -58,7 +58,7 @@ func (h *ActionHandler) ExecuteAction(ctx context.Context, req *pb.ExecuteAction
 		return nil, status.Errorf(codes.NotFound, "session not found: %v", err)
 	}
 
-	if session.Status != manman.SessionStatusRunning {
+	if !session.IsLive() {
 		return nil, status.Errorf(codes.FailedPrecondition, "session is not running (status: %s)", session.Status)
 	}
 
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultAlertWindowSeconds   = 60
+	defaultAlertCooldownSeconds = 900
+)
+
+// AlertHandler manages log alert rules and their history. The log-processor evaluates
+// the rules against live logs and the processor records what fires.
+type AlertHandler struct {
+	ruleRepo         repository.AlertRuleRepository
+	eventRepo        repository.AlertEventRepository
+	gameRepo         repository.GameRepository
+	sgcRepo          repository.ServerGameConfigRepository
+	backupConfigRepo repository.BackupConfigRepository
+	actionRepo       *postgres.ActionRepository
+}
+
+func NewAlertHandler(repo *repository.Repository) *AlertHandler {
+	return &AlertHandler{
+		ruleRepo:         repo.AlertRules,
+		eventRepo:        repo.AlertEvents,
+		gameRepo:         repo.Games,
+		sgcRepo:          repo.ServerGameConfigs,
+		backupConfigRepo: repo.BackupConfigs,
+		actionRepo:       repo.Actions.(*postgres.ActionRepository),
+	}
+}
+
+func (h *AlertHandler) ListAlertRules(ctx context.Context, req *pb.ListAlertRulesRequest) (*pb.ListAlertRulesResponse, error) {
+	var rules []*manman.AlertRule
+	var err error
+	if req.ServerGameConfigId != 0 {
+		rules, err = h.ruleRepo.ListApplicable(ctx, req.ServerGameConfigId)
+	} else {
+		rules, err = h.ruleRepo.List(ctx, optionalID(req.GameId), nil)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list alert rules: %v", err)
+	}
+	pbRules := make([]*pb.AlertRule, len(rules))
+	for i, rule := range rules {
+		pbRules[i] = alertRuleToProto(rule)
+	}
+	return &pb.ListAlertRulesResponse{Rules: pbRules}, nil
+}
+
+func (h *AlertHandler) CreateAlertRule(ctx context.Context, req *pb.CreateAlertRuleRequest) (*pb.CreateAlertRuleResponse, error) {
+	rule := &manman.AlertRule{
+		GameID:          optionalID(req.GameId),
+		SGCID:           optionalID(req.ServerGameConfigId),
+		Name:            req.Name,
+		Pattern:         req.Pattern,
+		Threshold:       req.Threshold,
+		WindowSeconds:   req.WindowSeconds,
+		CooldownSeconds: req.CooldownSeconds,
+		ActionID:        optionalID(req.ActionId),
+		BackupConfigID:  optionalID(req.BackupConfigId),
+		Enabled:         req.Enabled,
+	}
+	if rule.Threshold == 0 {
+		rule.Threshold = 1
+	}
+	if rule.WindowSeconds == 0 {
+		rule.WindowSeconds = defaultAlertWindowSeconds
+	}
+	if rule.CooldownSeconds == 0 {
+		rule.CooldownSeconds = defaultAlertCooldownSeconds
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid alert rule: %v", err)
+	}
+	if err := h.checkReferences(ctx, rule); err != nil {
+		return nil, err
+	}
+
+	rule, err := h.ruleRepo.Create(ctx, rule)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create alert rule: %v", err)
+	}
+	return &pb.CreateAlertRuleResponse{Rule: alertRuleToProto(rule)}, nil
+}
+
+func (h *AlertHandler) UpdateAlertRule(ctx context.Context, req *pb.UpdateAlertRuleRequest) (*pb.UpdateAlertRuleResponse, error) {
+	rule, err := h.ruleRepo.Get(ctx, req.RuleId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "alert rule not found: %v", err)
+	}
+
+	// Apply field paths
+	if len(req.UpdatePaths) == 0 {
+		// Update all provided fields
+		if req.Name != "" {
+			rule.Name = req.Name
+		}
+		if req.Pattern != "" {
+			rule.Pattern = req.Pattern
+		}
+		if req.Threshold != 0 {
+			rule.Threshold = req.Threshold
+		}
+		if req.WindowSeconds != 0 {
+			rule.WindowSeconds = req.WindowSeconds
+		}
+		if req.CooldownSeconds != 0 {
+			rule.CooldownSeconds = req.CooldownSeconds
+		}
+		if req.ActionId != 0 {
+			rule.ActionID = &req.ActionId
+		}
+		if req.BackupConfigId != 0 {
+			rule.BackupConfigID = &req.BackupConfigId
+		}
+		// Only update enabled if explicitly provided
+		if req.Enabled {
+			rule.Enabled = req.Enabled
+		}
+	} else {
+		// Update only specified fields
+		for _, path := range req.UpdatePaths {
+			switch path {
+			case "name":
+				rule.Name = req.Name
+			case "pattern":
+				rule.Pattern = req.Pattern
+			case "threshold":
+				rule.Threshold = req.Threshold
+			case "window_seconds":
+				rule.WindowSeconds = req.WindowSeconds
+			case "cooldown_seconds":
+				rule.CooldownSeconds = req.CooldownSeconds
+			case "action_id":
+				rule.ActionID = optionalID(req.ActionId)
+			case "backup_config_id":
+				rule.BackupConfigID = optionalID(req.BackupConfigId)
+			case "enabled":
+				rule.Enabled = req.Enabled
+			}
+		}
+	}
+
+	if err := rule.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid alert rule: %v", err)
+	}
+	if err := h.checkReferences(ctx, rule); err != nil {
+		return nil, err
+	}
+	if err := h.ruleRepo.Update(ctx, rule); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update alert rule: %v", err)
+	}
+	return &pb.UpdateAlertRuleResponse{Rule: alertRuleToProto(rule)}, nil
+}
+
+func (h *AlertHandler) DeleteAlertRule(ctx context.Context, req *pb.DeleteAlertRuleRequest) (*pb.DeleteAlertRuleResponse, error) {
+	if err := h.ruleRepo.Delete(ctx, req.RuleId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete alert rule: %v", err)
+	}
+	return &pb.DeleteAlertRuleResponse{}, nil
+}
+
+func (h *AlertHandler) ListAlertEvents(ctx context.Context, req *pb.ListAlertEventsRequest) (*pb.ListAlertEventsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := 0
+	if req.PageToken != "" {
+		var err error
+		offset, err = decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %v", err)
+		}
+	}
+
+	filters := &repository.AlertEventFilters{
+		RuleID:    req.RuleId,
+		SGCID:     req.ServerGameConfigId,
+		SessionID: req.SessionId,
+	}
+	events, err := h.eventRepo.List(ctx, filters, pageSize+1, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list alert events: %v", err)
+	}
+
+	var nextPageToken string
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		nextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	pbEvents := make([]*pb.AlertEvent, len(events))
+	for i, e := range events {
+		pbEvents[i] = alertEventToProto(e)
+	}
+	return &pb.ListAlertEventsResponse{
+		Events:        pbEvents,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+// checkReferences makes sure the rule's scope, action and backup config exist. The
+// processor runs the action without input, so it can't have required fields.
+func (h *AlertHandler) checkReferences(ctx context.Context, rule *manman.AlertRule) error {
+	if rule.GameID != nil {
+		if _, err := h.gameRepo.Get(ctx, *rule.GameID); err != nil {
+			return status.Errorf(codes.NotFound, "game not found: %v", err)
+		}
+	}
+	if rule.SGCID != nil {
+		if _, err := h.sgcRepo.Get(ctx, *rule.SGCID); err != nil {
+			return status.Errorf(codes.NotFound, "server game config not found: %v", err)
+		}
+	}
+	if rule.ActionID != nil {
+		_, fields, err := h.actionRepo.Get(ctx, *rule.ActionID)
+		if err != nil {
+			return status.Errorf(codes.NotFound, "action not found: %v", err)
+		}
+		if err := requireNoActionInput(fields); err != nil {
+			return status.Errorf(codes.InvalidArgument, "action can't run from an alert: %v", err)
+		}
+	}
+	if rule.BackupConfigID != nil {
+		if _, err := h.backupConfigRepo.Get(ctx, *rule.BackupConfigID); err != nil {
+			return status.Errorf(codes.NotFound, "backup config not found: %v", err)
+		}
+	}
+	return nil
+}
+
+func requireNoActionInput(fields []*postgres.ActionInputFieldWithOptions) error {
+	for _, f := range fields {
+		if f.Field.Required {
+			return fmt.Errorf("field '%s' is required", f.Field.Label)
+		}
+	}
+	return nil
+}
+
+// optionalID maps the proto's zero ID to nil
+func optionalID(id int64) *int64 {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+func alertRuleToProto(rule *manman.AlertRule) *pb.AlertRule {
+	pbRule := &pb.AlertRule{
+		RuleId:          rule.RuleID,
+		Name:            rule.Name,
+		Pattern:         rule.Pattern,
+		Threshold:       rule.Threshold,
+		WindowSeconds:   rule.WindowSeconds,
+		CooldownSeconds: rule.CooldownSeconds,
+		Enabled:         rule.Enabled,
+		CreatedAt:       rule.CreatedAt.Unix(),
+		UpdatedAt:       rule.UpdatedAt.Unix(),
+	}
+	if rule.GameID != nil {
+		pbRule.GameId = *rule.GameID
+	}
+	if rule.SGCID != nil {
+		pbRule.ServerGameConfigId = *rule.SGCID
+	}
+	if rule.ActionID != nil {
+		pbRule.ActionId = *rule.ActionID
+	}
+	if rule.BackupConfigID != nil {
+		pbRule.BackupConfigId = *rule.BackupConfigID
+	}
+	return pbRule
+}
+
+func alertEventToProto(e *manman.AlertEvent) *pb.AlertEvent {
+	pbEvent := &pb.AlertEvent{
+		AlertId:            e.AlertID,
+		RuleId:             e.RuleID,
+		SessionId:          e.SessionID,
+		ServerGameConfigId: e.SGCID,
+		MatchCount:         e.MatchCount,
+		Line:               e.Line,
+		FirstMatchAt:       e.FirstMatchAt.Unix(),
+		FiredAt:            e.FiredAt.Unix(),
+	}
+	if e.ActionExecutionID != nil {
+		pbEvent.ActionExecutionId = *e.ActionExecutionID
+	}
+	if e.BackupID != nil {
+		pbEvent.BackupId = *e.BackupID
+	}
+	if e.ResponseError != nil {
+		pbEvent.ResponseError = *e.ResponseError
+	}
+	return pbEvent
+}
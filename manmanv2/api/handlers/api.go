@@ -6,6 +6,7 @@ import (
 
 	"github.com/whale-net/everything/libs/go/rmq"
 	"github.com/whale-net/everything/libs/go/s3"
+	"github.com/whale-net/everything/manmanv2/api/bundle"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
 	"github.com/whale-net/everything/manmanv2/api/workshop"
@@ -20,6 +21,7 @@ type APIServer struct {
 	serverHandler           *ServerHandler
 	gameHandler             *GameHandler
 	gameConfigHandler       *GameConfigHandler
+	gameBundleHandler       *GameBundleHandler
 	serverGameConfigHandler *ServerGameConfigHandler
 	sessionHandler          *SessionHandler
 	registrationHandler     *RegistrationHandler
@@ -27,10 +29,19 @@ type APIServer struct {
 	logsHandler             *LogsHandler
 	backupHandler           *BackupHandler
 	backupConfigHandler     *BackupConfigHandler
+	scheduleHandler         *SGCScheduleHandler
+	migrationHandler        *MigrationHandler
+	accessHandler           *AccessHandler
+	auditHandler            *AuditHandler
+	webhookHandler          *WebhookHandler
+	alertHandler            *AlertHandler
+	metricsHandler          *MetricsHandler
+	playerHandler           *PlayerHandler
 	strategyHandler         *ConfigurationStrategyHandler
 	patchHandler            *ConfigurationPatchHandler
 	volumeHandler           *GameConfigVolumeHandler
 	actionHandler           *ActionHandler
+	consoleHandler          *ConsoleHandler
 }
 
 func NewAPIServer(repo *repository.Repository, s3Client *s3.Client, rmqConn *rmq.Connection, workshopManager workshop.WorkshopManagerInterface) *APIServer {
@@ -48,25 +59,56 @@ func NewAPIServer(repo *repository.Repository, s3Client *s3.Client, rmqConn *rmq
 		}()
 	}
 
+	sessionHandler := NewSessionHandler(repo, commandPublisher, workshopManager)
+
+	// Console echoes and attendees are shared with the other API instances
+	consoleHandler := NewConsoleHandler(sessionHandler)
+	consoleFanout, err := NewConsoleFanout(rmqConn, consoleHandler)
+	if err != nil {
+		log.Printf("Warning: Failed to create console fanout, consoles only see this instance: %v", err)
+	} else {
+		consoleHandler.SetPeers(context.Background(), consoleFanout)
+		go func() {
+			if err := consoleFanout.Start(context.Background()); err != nil {
+				log.Printf("Warning: Console fanout consumer stopped: %v", err)
+			}
+		}()
+	}
+
 	return &APIServer{
 		repo:                    repo,
 		serverHandler:           NewServerHandler(repo.Servers),
 		gameHandler:             NewGameHandler(repo.Games),
 		gameConfigHandler:       NewGameConfigHandler(repo.GameConfigs),
-		serverGameConfigHandler: NewServerGameConfigHandler(repo.ServerGameConfigs, repo.ServerPorts),
-		sessionHandler:          NewSessionHandler(repo, commandPublisher, workshopManager),
+		gameBundleHandler:       NewGameBundleHandler(bundle.NewService(repo, repo.Actions.(*postgres.ActionRepository))),
+		serverGameConfigHandler: NewServerGameConfigHandler(repo.ServerGameConfigs, repo.ServerPorts, repo.SGCImageStatuses, NewPlacer(repo)),
+		sessionHandler:          sessionHandler,
 		registrationHandler:     NewRegistrationHandler(repo.Servers, repo.ServerCapabilities),
-		validationHandler:       NewValidationHandler(repo.Servers, repo.GameConfigs),
+		validationHandler:       NewValidationHandler(repo),
 		logsHandler:             NewLogsHandler(repo.LogReferences, s3Client),
-		backupHandler:           NewBackupHandler(repo.Backups, repo.Sessions, s3Client),
+		backupHandler:           NewBackupHandler(repo.Backups, repo.Sessions, repo.ServerGameConfigs, repo.GameConfigVolumes, sessionHandler, commandPublisher, s3Client),
 		backupConfigHandler:     NewBackupConfigHandler(repo.BackupConfigs, repo.Backups, repo.ServerGameConfigs, repo.Sessions, repo.GameConfigVolumes, repo.Servers, repo.Actions.(*postgres.ActionRepository), commandPublisher, s3Client),
+		scheduleHandler:         NewSGCScheduleHandler(repo.SGCSchedules, repo.ServerGameConfigs, repo.BackupConfigs),
+		migrationHandler:        NewMigrationHandler(repo, NewPlacer(repo)),
+		accessHandler:           NewAccessHandler(repo.SGCGrants, repo.ServerGameConfigs),
+		auditHandler:            NewAuditHandler(repo.AuditEvents),
+		webhookHandler:          NewWebhookHandler(repo.Webhooks, repo.WebhookDeliveries),
+		alertHandler:            NewAlertHandler(repo),
+		metricsHandler:          NewMetricsHandler(repo),
+		playerHandler:           NewPlayerHandler(repo.PlayerSessions, repo.Sessions, repo.ServerGameConfigs),
 		strategyHandler:         NewConfigurationStrategyHandler(repo.ConfigurationStrategies),
 		patchHandler:            NewConfigurationPatchHandler(repo.ConfigurationPatches),
 		volumeHandler:           NewGameConfigVolumeHandler(repo.GameConfigVolumes),
 		actionHandler:           NewActionHandler(repo.Actions.(*postgres.ActionRepository), repo.Sessions, repo.ServerGameConfigs, repo.GameConfigs, commandPublisher),
+		consoleHandler:          consoleHandler,
 	}
 }
 
+// EnableConsole turns on AttachSession, which streams output from the log-processor
+func (s *APIServer) EnableConsole(logs pb.LogProcessorClient, authorize AuthorizeFunc, record RecordFunc) {
+	s.consoleHandler.Enable(logs, authorize, record)
+}
+
 // Server RPCs
 func (s *APIServer) ListServers(ctx context.Context, req *pb.ListServersRequest) (*pb.ListServersResponse, error) {
 	return s.serverHandler.ListServers(ctx, req)
@@ -88,6 +130,10 @@ func (s *APIServer) DeleteServer(ctx context.Context, req *pb.DeleteServerReques
 	return s.serverHandler.DeleteServer(ctx, req)
 }
 
+func (s *APIServer) GetServerMetrics(ctx context.Context, req *pb.GetServerMetricsRequest) (*pb.GetServerMetricsResponse, error) {
+	return s.metricsHandler.GetServerMetrics(ctx, req)
+}
+
 // Game RPCs
 func (s *APIServer) ListGames(ctx context.Context, req *pb.ListGamesRequest) (*pb.ListGamesResponse, error) {
 	return s.gameHandler.ListGames(ctx, req)
@@ -130,6 +176,15 @@ func (s *APIServer) DeleteGameConfig(ctx context.Context, req *pb.DeleteGameConf
 	return s.gameConfigHandler.DeleteGameConfig(ctx, req)
 }
 
+// Game bundle RPCs
+func (s *APIServer) ExportGameBundle(ctx context.Context, req *pb.ExportGameBundleRequest) (*pb.ExportGameBundleResponse, error) {
+	return s.gameBundleHandler.ExportGameBundle(ctx, req)
+}
+
+func (s *APIServer) ImportGameBundle(ctx context.Context, req *pb.ImportGameBundleRequest) (*pb.ImportGameBundleResponse, error) {
+	return s.gameBundleHandler.ImportGameBundle(ctx, req)
+}
+
 // ServerGameConfig RPCs
 func (s *APIServer) ListServerGameConfigs(ctx context.Context, req *pb.ListServerGameConfigsRequest) (*pb.ListServerGameConfigsResponse, error) {
 	return s.serverGameConfigHandler.ListServerGameConfigs(ctx, req)
@@ -151,6 +206,104 @@ func (s *APIServer) DeleteServerGameConfig(ctx context.Context, req *pb.DeleteSe
 	return s.serverGameConfigHandler.DeleteServerGameConfig(ctx, req)
 }
 
+// SGC migration RPCs
+func (s *APIServer) MigrateServerGameConfig(ctx context.Context, req *pb.MigrateServerGameConfigRequest) (*pb.MigrateServerGameConfigResponse, error) {
+	return s.migrationHandler.MigrateServerGameConfig(ctx, req)
+}
+
+func (s *APIServer) GetSGCMigration(ctx context.Context, req *pb.GetSGCMigrationRequest) (*pb.GetSGCMigrationResponse, error) {
+	return s.migrationHandler.GetSGCMigration(ctx, req)
+}
+
+func (s *APIServer) ListSGCMigrations(ctx context.Context, req *pb.ListSGCMigrationsRequest) (*pb.ListSGCMigrationsResponse, error) {
+	return s.migrationHandler.ListSGCMigrations(ctx, req)
+}
+
+// SGC schedule RPCs
+func (s *APIServer) CreateSGCSchedule(ctx context.Context, req *pb.CreateSGCScheduleRequest) (*pb.CreateSGCScheduleResponse, error) {
+	return s.scheduleHandler.CreateSGCSchedule(ctx, req)
+}
+
+func (s *APIServer) ListSGCSchedules(ctx context.Context, req *pb.ListSGCSchedulesRequest) (*pb.ListSGCSchedulesResponse, error) {
+	return s.scheduleHandler.ListSGCSchedules(ctx, req)
+}
+
+func (s *APIServer) UpdateSGCSchedule(ctx context.Context, req *pb.UpdateSGCScheduleRequest) (*pb.UpdateSGCScheduleResponse, error) {
+	return s.scheduleHandler.UpdateSGCSchedule(ctx, req)
+}
+
+func (s *APIServer) DeleteSGCSchedule(ctx context.Context, req *pb.DeleteSGCScheduleRequest) (*pb.DeleteSGCScheduleResponse, error) {
+	return s.scheduleHandler.DeleteSGCSchedule(ctx, req)
+}
+
+// Access RPCs
+func (s *APIServer) GetCallerAccess(ctx context.Context, req *pb.GetCallerAccessRequest) (*pb.GetCallerAccessResponse, error) {
+	return s.accessHandler.GetCallerAccess(ctx, req)
+}
+
+func (s *APIServer) ListSGCGrants(ctx context.Context, req *pb.ListSGCGrantsRequest) (*pb.ListSGCGrantsResponse, error) {
+	return s.accessHandler.ListSGCGrants(ctx, req)
+}
+
+func (s *APIServer) CreateSGCGrant(ctx context.Context, req *pb.CreateSGCGrantRequest) (*pb.CreateSGCGrantResponse, error) {
+	return s.accessHandler.CreateSGCGrant(ctx, req)
+}
+
+func (s *APIServer) DeleteSGCGrant(ctx context.Context, req *pb.DeleteSGCGrantRequest) (*pb.DeleteSGCGrantResponse, error) {
+	return s.accessHandler.DeleteSGCGrant(ctx, req)
+}
+
+// Audit RPCs
+func (s *APIServer) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	return s.auditHandler.ListAuditEvents(ctx, req)
+}
+
+// Webhook RPCs
+func (s *APIServer) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	return s.webhookHandler.ListWebhooks(ctx, req)
+}
+
+func (s *APIServer) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	return s.webhookHandler.CreateWebhook(ctx, req)
+}
+
+func (s *APIServer) UpdateWebhook(ctx context.Context, req *pb.UpdateWebhookRequest) (*pb.UpdateWebhookResponse, error) {
+	return s.webhookHandler.UpdateWebhook(ctx, req)
+}
+
+func (s *APIServer) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	return s.webhookHandler.DeleteWebhook(ctx, req)
+}
+
+func (s *APIServer) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	return s.webhookHandler.ListWebhookDeliveries(ctx, req)
+}
+
+func (s *APIServer) RedeliverWebhookDelivery(ctx context.Context, req *pb.RedeliverWebhookDeliveryRequest) (*pb.RedeliverWebhookDeliveryResponse, error) {
+	return s.webhookHandler.RedeliverWebhookDelivery(ctx, req)
+}
+
+// Alert RPCs
+func (s *APIServer) ListAlertRules(ctx context.Context, req *pb.ListAlertRulesRequest) (*pb.ListAlertRulesResponse, error) {
+	return s.alertHandler.ListAlertRules(ctx, req)
+}
+
+func (s *APIServer) CreateAlertRule(ctx context.Context, req *pb.CreateAlertRuleRequest) (*pb.CreateAlertRuleResponse, error) {
+	return s.alertHandler.CreateAlertRule(ctx, req)
+}
+
+func (s *APIServer) UpdateAlertRule(ctx context.Context, req *pb.UpdateAlertRuleRequest) (*pb.UpdateAlertRuleResponse, error) {
+	return s.alertHandler.UpdateAlertRule(ctx, req)
+}
+
+func (s *APIServer) DeleteAlertRule(ctx context.Context, req *pb.DeleteAlertRuleRequest) (*pb.DeleteAlertRuleResponse, error) {
+	return s.alertHandler.DeleteAlertRule(ctx, req)
+}
+
+func (s *APIServer) ListAlertEvents(ctx context.Context, req *pb.ListAlertEventsRequest) (*pb.ListAlertEventsResponse, error) {
+	return s.alertHandler.ListAlertEvents(ctx, req)
+}
+
 // Session RPCs
 func (s *APIServer) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsResponse, error) {
 	return s.sessionHandler.ListSessions(ctx, req)
@@ -172,6 +325,19 @@ func (s *APIServer) SendInput(ctx context.Context, req *pb.SendInputRequest) (*p
 	return s.sessionHandler.SendInput(ctx, req)
 }
 
+func (s *APIServer) AttachSession(stream pb.ManManAPI_AttachSessionServer) error {
+	return s.consoleHandler.AttachSession(stream)
+}
+
+// Player RPCs
+func (s *APIServer) ListSessionPlayers(ctx context.Context, req *pb.ListSessionPlayersRequest) (*pb.ListSessionPlayersResponse, error) {
+	return s.playerHandler.ListSessionPlayers(ctx, req)
+}
+
+func (s *APIServer) GetPlayerStats(ctx context.Context, req *pb.GetPlayerStatsRequest) (*pb.GetPlayerStatsResponse, error) {
+	return s.playerHandler.GetPlayerStats(ctx, req)
+}
+
 // Registration RPCs
 func (s *APIServer) RegisterServer(ctx context.Context, req *pb.RegisterServerRequest) (*pb.RegisterServerResponse, error) {
 	return s.registrationHandler.RegisterServer(ctx, req)
@@ -194,6 +360,10 @@ func (s *APIServer) GetLogHistogram(ctx context.Context, req *pb.GetLogHistogram
 	return s.logsHandler.GetLogHistogram(ctx, req)
 }
 
+func (s *APIServer) SearchLogs(ctx context.Context, req *pb.SearchLogsRequest) (*pb.SearchLogsResponse, error) {
+	return s.logsHandler.SearchLogs(ctx, req)
+}
+
 // Validation RPCs
 func (s *APIServer) ValidateDeployment(ctx context.Context, req *pb.ValidateDeploymentRequest) (*pb.ValidateDeploymentResponse, error) {
 	return s.validationHandler.ValidateDeployment(ctx, req)
@@ -220,6 +390,10 @@ func (s *APIServer) TriggerBackup(ctx context.Context, req *pb.TriggerBackupRequ
 	return s.backupConfigHandler.TriggerBackup(ctx, req)
 }
 
+func (s *APIServer) RestoreBackup(ctx context.Context, req *pb.RestoreBackupRequest) (*pb.RestoreBackupResponse, error) {
+	return s.backupHandler.RestoreBackup(ctx, req)
+}
+
 // BackupConfig RPCs
 func (s *APIServer) CreateBackupConfig(ctx context.Context, req *pb.CreateBackupConfigRequest) (*pb.CreateBackupConfigResponse, error) {
 	return s.backupConfigHandler.CreateBackupConfig(ctx, req)
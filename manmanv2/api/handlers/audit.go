@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuditHandler serves the audit log. Events are written by the audit package's
+// interceptor, never through the API.
+type AuditHandler struct {
+	auditRepo repository.AuditEventRepository
+}
+
+func NewAuditHandler(auditRepo repository.AuditEventRepository) *AuditHandler {
+	return &AuditHandler{auditRepo: auditRepo}
+}
+
+func (h *AuditHandler) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := 0
+	if req.PageToken != "" {
+		var err error
+		offset, err = decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %v", err)
+		}
+	}
+
+	if (req.EntityKey == "") != (req.EntityId == 0) {
+		return nil, status.Error(codes.InvalidArgument, "entity_key and entity_id must be set together")
+	}
+
+	filters := &repository.AuditEventFilters{
+		Subject:   req.Subject,
+		Method:    req.Method,
+		EntityKey: req.EntityKey,
+		EntityID:  req.EntityId,
+	}
+	if req.Since > 0 {
+		t := time.Unix(req.Since, 0)
+		filters.Since = &t
+	}
+	if req.Until > 0 {
+		t := time.Unix(req.Until, 0)
+		filters.Until = &t
+	}
+
+	events, err := h.auditRepo.List(ctx, filters, pageSize+1, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list audit events: %v", err)
+	}
+
+	var nextPageToken string
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		nextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	pbEvents := make([]*pb.AuditEvent, len(events))
+	for i, e := range events {
+		pbEvents[i] = auditEventToProto(e)
+	}
+
+	return &pb.ListAuditEventsResponse{
+		Events:        pbEvents,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func auditEventToProto(e *manman.AuditEvent) *pb.AuditEvent {
+	pbEvent := &pb.AuditEvent{
+		AuditEventId: e.AuditEventID,
+		OccurredAt:   e.OccurredAt.Unix(),
+		Subject:      e.Subject,
+		Username:     e.Username,
+		Method:       e.Method,
+		Entities:     e.Entities,
+		StatusCode:   e.StatusCode,
+		DurationMs:   e.DurationMs,
+	}
+	if e.Error != nil {
+		pbEvent.Error = *e.Error
+	}
+	if e.Request != nil {
+		if raw, err := json.Marshal(e.Request); err == nil {
+			pbEvent.RequestJson = string(raw)
+		}
+	}
+	if e.Changes != nil {
+		if raw, err := json.Marshal(e.Changes); err == nil {
+			pbEvent.ChangesJson = string(raw)
+		}
+	}
+	return pbEvent
+}
@@ -2,27 +2,48 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
+	"strings"
+	"time"
 
 	"github.com/whale-net/everything/libs/go/s3"
 	"github.com/whale-net/everything/manmanv2/models"
 	"github.com/whale-net/everything/manmanv2/api/repository"
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
 	pb "github.com/whale-net/everything/manmanv2/protos"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 type BackupHandler struct {
-	backupRepo  repository.BackupRepository
-	sessionRepo repository.SessionRepository
-	s3Client    *s3.Client
+	backupRepo       repository.BackupRepository
+	sessionRepo      repository.SessionRepository
+	sgcRepo          repository.ServerGameConfigRepository
+	volumeRepo       repository.GameConfigVolumeRepository
+	sessionHandler   *SessionHandler
+	commandPublisher *CommandPublisher
+	s3Client         *s3.Client
 }
 
-func NewBackupHandler(backupRepo repository.BackupRepository, sessionRepo repository.SessionRepository, s3Client *s3.Client) *BackupHandler {
+func NewBackupHandler(
+	backupRepo repository.BackupRepository,
+	sessionRepo repository.SessionRepository,
+	sgcRepo repository.ServerGameConfigRepository,
+	volumeRepo repository.GameConfigVolumeRepository,
+	sessionHandler *SessionHandler,
+	commandPublisher *CommandPublisher,
+	s3Client *s3.Client,
+) *BackupHandler {
 	return &BackupHandler{
-		backupRepo:  backupRepo,
-		sessionRepo: sessionRepo,
-		s3Client:    s3Client,
+		backupRepo:       backupRepo,
+		sessionRepo:      sessionRepo,
+		sgcRepo:          sgcRepo,
+		volumeRepo:       volumeRepo,
+		sessionHandler:   sessionHandler,
+		commandPublisher: commandPublisher,
+		s3Client:         s3Client,
 	}
 }
 
@@ -92,7 +113,7 @@ func (h *BackupHandler) ListBackups(ctx context.Context, req *pb.ListBackupsRequ
 		sessionID = &req.SessionId
 	}
 
-	backups, err := h.backupRepo.List(ctx, sgcID, sessionID, pageSize+1, offset)
+	backups, err := h.backupRepo.List(ctx, sgcID, sessionID, req.IncludePruned, pageSize+1, offset)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to list backups: %v", err)
 	}
@@ -154,6 +175,98 @@ func (h *BackupHandler) DeleteBackup(ctx context.Context, req *pb.DeleteBackupRe
 	return &pb.DeleteBackupResponse{}, nil
 }
 
+// restoreURLExpiry bounds how long the host has to start downloading a restore archive.
+// It matches the broker TTL on the restore command itself.
+const restoreURLExpiry = backupCommandExpiry
+
+// RestoreBackup dispatches a RestoreBackupCommand to the host-manager that owns the backup's SGC.
+// The host stops any running session, unpacks the archive into the source volume and, when
+// start_session is set, starts the session created here with restored_from_backup_id recorded.
+func (h *BackupHandler) RestoreBackup(ctx context.Context, req *pb.RestoreBackupRequest) (*pb.RestoreBackupResponse, error) {
+	backup, err := h.backupRepo.Get(ctx, req.BackupId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "backup not found: %v", err)
+	}
+	if backup.Status != manman.BackupStatusCompleted || backup.S3URL == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "backup %d is not restorable (status: %s)", backup.BackupID, backup.Status)
+	}
+	if backup.VolumeID == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "backup %d has no source volume", backup.BackupID)
+	}
+	if h.commandPublisher == nil {
+		return nil, status.Error(codes.Unavailable, "command publisher is not available")
+	}
+
+	volume, err := h.volumeRepo.Get(ctx, *backup.VolumeID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get volume: %v", err)
+	}
+
+	sgc, err := h.sgcRepo.Get(ctx, backup.ServerGameConfigID)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "SGC not found: %v", err)
+	}
+
+	if h.s3Client == nil {
+		return nil, status.Error(codes.FailedPrecondition, "object storage is not configured")
+	}
+
+	// The host reports s3_url as s3://{key}, with no bucket segment.
+	s3Key := strings.TrimPrefix(*backup.S3URL, "s3://")
+	presignedURL, err := h.s3Client.PresignGetURL(ctx, s3Key, restoreURLExpiry)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to generate presigned URL: %v", err)
+	}
+
+	cmd := &hostrmq.RestoreBackupCommand{
+		BackupID:       backup.BackupID,
+		SGCID:          sgc.SGCID,
+		VolumeType:     volume.VolumeType,
+		VolumeHostPath: buildVolumeHostPath(volume.HostSubpath),
+		VolumeName:     volume.Name,
+		S3Key:          s3Key,
+		PresignedURL:   presignedURL,
+		CreatedAt:      time.Now(),
+	}
+
+	// The host stops whatever is running before it unpacks and reports it stopped; until then
+	// the session is stopping, as for StopSession. A new session is created with force
+	// semantics, which does the same for the SGC's other sessions.
+	var restored *manman.Session
+	if req.StartSession {
+		session, _, startCmd, err := h.sessionHandler.createSession(ctx, &manman.Session{SGCID: sgc.SGCID, RestoredFromBackupID: &backup.BackupID}, true, nil)
+		if err != nil {
+			return nil, err
+		}
+		body, err := json.Marshal(startCmd)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to encode start session command: %v", err)
+		}
+		cmd.StartSession = body
+		restored = session
+	}
+
+	if err := h.commandPublisher.PublishRestoreBackup(ctx, sgc.ServerID, cmd); err != nil {
+		if restored != nil {
+			if err := h.sessionRepo.UpdateStatus(ctx, restored.SessionID, manman.SessionStatusCrashed); err != nil {
+				log.Printf("Warning: Failed to mark session %d crashed after failed restore dispatch: %v", restored.SessionID, err)
+			}
+		}
+		return nil, status.Errorf(codes.Internal, "failed to dispatch restore command: %v", err)
+	}
+	if restored == nil {
+		if err := h.sessionHandler.stopOtherLiveSessions(ctx, 0, sgc.SGCID); err != nil {
+			log.Printf("Warning: Failed to mark sessions of SGC %d stopping for restore: %v", sgc.SGCID, err)
+		}
+	}
+
+	resp := &pb.RestoreBackupResponse{}
+	if restored != nil {
+		resp.Session = sessionToProto(restored)
+	}
+	return resp, nil
+}
+
 func backupToProto(b *manman.Backup) *pb.Backup {
 	pbBackup := &pb.Backup{
 		BackupId:           b.BackupID,
@@ -171,6 +284,12 @@ func backupToProto(b *manman.Backup) *pb.Backup {
 	if b.Description != nil {
 		pbBackup.Description = *b.Description
 	}
+	if b.Checksum != nil {
+		pbBackup.Checksum = *b.Checksum
+	}
+	if b.PrunedReason != nil {
+		pbBackup.PrunedReason = *b.PrunedReason
+	}
 
 	return pbBackup
 }
@@ -65,12 +65,17 @@ func (h *BackupConfigHandler) CreateBackupConfig(ctx context.Context, req *pb.Cr
 		return nil, status.Error(codes.InvalidArgument, "backup_path is required")
 	}
 
+	if err := validateBackupRetention(req.Retention); err != nil {
+		return nil, err
+	}
+
 	cfg := &manman.BackupConfig{
 		VolumeID:       req.VolumeId,
 		CadenceMinutes: int(req.CadenceMinutes),
 		BackupPath:     req.BackupPath,
 		Enabled:        req.Enabled,
 	}
+	applyBackupRetention(cfg, req.Retention)
 	cfg, err := h.backupConfigRepo.Create(ctx, cfg)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create backup config: %v", err)
@@ -110,6 +115,12 @@ func (h *BackupConfigHandler) UpdateBackupConfig(ctx context.Context, req *pb.Up
 		cfg.BackupPath = req.BackupPath
 	}
 	cfg.Enabled = req.Enabled
+	if req.Retention != nil {
+		if err := validateBackupRetention(req.Retention); err != nil {
+			return nil, err
+		}
+		applyBackupRetention(cfg, req.Retention)
+	}
 	if err := h.backupConfigRepo.Update(ctx, cfg); err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update backup config: %v", err)
 	}
@@ -259,9 +270,41 @@ func backupConfigToProto(c *manman.BackupConfig) *pb.BackupConfig {
 	if c.LastBackupAt != nil {
 		pb.LastBackupAt = c.LastBackupAt.Unix()
 	}
+	if c.HasRetention() {
+		pb.Retention = backupRetentionToProto(c)
+	}
 	return pb
 }
 
+func backupRetentionToProto(c *manman.BackupConfig) *pb.BackupRetention {
+	return &pb.BackupRetention{
+		KeepLast:          int32(c.RetentionKeepLast),
+		DailyDays:         int32(c.RetentionDailyDays),
+		WeeklyWeeks:       int32(c.RetentionWeeklyWeeks),
+		MonthlyMonths:     int32(c.RetentionMonthlyMonths),
+		MaxTotalSizeBytes: c.RetentionMaxTotalBytes,
+	}
+}
+
+func validateBackupRetention(r *pb.BackupRetention) error {
+	if r == nil {
+		return nil
+	}
+	if r.KeepLast < 0 || r.DailyDays < 0 || r.WeeklyWeeks < 0 || r.MonthlyMonths < 0 || r.MaxTotalSizeBytes < 0 {
+		return status.Error(codes.InvalidArgument, "retention values must be >= 0")
+	}
+	return nil
+}
+
+// applyBackupRetention copies retention rules onto the model; nil clears them.
+func applyBackupRetention(cfg *manman.BackupConfig, r *pb.BackupRetention) {
+	cfg.RetentionKeepLast = int(r.GetKeepLast())
+	cfg.RetentionDailyDays = int(r.GetDailyDays())
+	cfg.RetentionWeeklyWeeks = int(r.GetWeeklyWeeks())
+	cfg.RetentionMonthlyMonths = int(r.GetMonthlyMonths())
+	cfg.RetentionMaxTotalBytes = r.GetMaxTotalSizeBytes()
+}
+
 func buildVolumeHostPath(hostSubpath *string) string {
 	if hostSubpath != nil {
 		return *hostSubpath
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type mockBackupRepo struct {
+	repository.BackupRepository
+	backup *manman.Backup
+}
+
+func (m *mockBackupRepo) Get(ctx context.Context, backupID int64) (*manman.Backup, error) {
+	return m.backup, nil
+}
+
+type mockVolumeGetRepo struct {
+	repository.GameConfigVolumeRepository
+}
+
+func (m *mockVolumeGetRepo) Get(ctx context.Context, volumeID int64) (*manman.GameConfigVolume, error) {
+	return &manman.GameConfigVolume{VolumeID: volumeID, Name: "data"}, nil
+}
+
+func TestRestoreBackupWithoutObjectStorage(t *testing.T) {
+	s3URL := "s3://backups/1.tar.gz"
+	volumeID := int64(3)
+	sessionRepo := &MockSessionRepo{sessions: []*manman.Session{
+		{SessionID: 1, SGCID: 100, Status: manman.SessionStatusRunning},
+	}}
+	h := NewBackupHandler(
+		&mockBackupRepo{backup: &manman.Backup{
+			BackupID:           7,
+			ServerGameConfigID: 100,
+			Status:             manman.BackupStatusCompleted,
+			S3URL:              &s3URL,
+			VolumeID:           &volumeID,
+		}},
+		sessionRepo,
+		&MockSGCRepo{},
+		&mockVolumeGetRepo{},
+		nil,
+		&CommandPublisher{},
+		nil,
+	)
+
+	_, err := h.RestoreBackup(context.Background(), &pb.RestoreBackupRequest{BackupId: 7})
+	if status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition, got %v", err)
+	}
+	if got := sessionRepo.sessions[0].Status; got != manman.SessionStatusRunning {
+		t.Errorf("running session should be left alone, got %s", got)
+	}
+}
+
+func TestBackupToProtoPrunedReason(t *testing.T) {
+	reason := "retained backups exceed max_total_bytes=1073741824"
+	got := backupToProto(&manman.Backup{BackupID: 4, Status: manman.BackupStatusCompleted, PrunedReason: &reason})
+	if got.PrunedReason != reason {
+		t.Errorf("PrunedReason = %q, want %q", got.PrunedReason, reason)
+	}
+
+	if got := backupToProto(&manman.Backup{BackupID: 5}); got.PrunedReason != "" {
+		t.Errorf("unpruned backup has PrunedReason %q", got.PrunedReason)
+	}
+}
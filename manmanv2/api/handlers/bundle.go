@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/bundle"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GameBundleHandler exports games as bundles and imports bundles
+type GameBundleHandler struct {
+	bundles *bundle.Service
+}
+
+func NewGameBundleHandler(bundles *bundle.Service) *GameBundleHandler {
+	return &GameBundleHandler{bundles: bundles}
+}
+
+func (h *GameBundleHandler) ExportGameBundle(ctx context.Context, req *pb.ExportGameBundleRequest) (*pb.ExportGameBundleResponse, error) {
+	if req.GameId == 0 {
+		return nil, status.Error(codes.InvalidArgument, "game_id is required")
+	}
+	if req.Format != "" && req.Format != bundle.FormatYAML && req.Format != bundle.FormatJSON {
+		return nil, status.Errorf(codes.InvalidArgument, "format must be %s or %s", bundle.FormatYAML, bundle.FormatJSON)
+	}
+
+	b, err := h.bundles.Export(ctx, req.GameId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Errorf(codes.NotFound, "game not found: %d", req.GameId)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to export game: %v", err)
+	}
+	data, err := bundle.Marshal(b, req.Format)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to encode bundle: %v", err)
+	}
+	return &pb.ExportGameBundleResponse{Bundle: string(data)}, nil
+}
+
+func (h *GameBundleHandler) ImportGameBundle(ctx context.Context, req *pb.ImportGameBundleRequest) (*pb.ImportGameBundleResponse, error) {
+	b, err := bundle.Parse([]byte(req.Bundle))
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+	}
+
+	result, err := h.bundles.Import(ctx, b, req.DryRun)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to import bundle: %v", err)
+	}
+
+	resp := &pb.ImportGameBundleResponse{GameId: result.GameID}
+	for _, c := range result.Changes {
+		resp.Changes = append(resp.Changes, &pb.GameBundleChange{
+			Kind:   c.Kind,
+			Key:    c.Key,
+			Op:     c.Op,
+			Fields: c.Fields,
+		})
+	}
+	return resp, nil
+}
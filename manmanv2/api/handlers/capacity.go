@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// checkServerCapacity reports whether a container with the requested limits fits in what
+// the server's last reported capacity leaves free. Limits are reservations: the limits of
+// every active session on the server, other than those of excludeSGCID, are taken out.
+// Returns a description of the overcommit, or "" if the start fits. Servers that have not
+// reported capabilities are not checked. This is advisory; starts are checked again with
+// the server locked by SessionRepository.CreateWithinCapacity.
+func checkServerCapacity(ctx context.Context, repo *repository.Repository, serverID, excludeSGCID int64, requested manman.ResourceLimits) (string, error) {
+	if requested.CPUMillicores == 0 && requested.MemoryMB == 0 {
+		return "", nil
+	}
+
+	capability, err := repo.ServerCapabilities.Get(ctx, serverID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to fetch server capabilities: %w", err)
+	}
+
+	load, err := loadOnServer(ctx, repo, serverID, excludeSGCID)
+	if err != nil {
+		return "", err
+	}
+	return capability.Overcommit(load.committed, requested), nil
+}
+
+// serverLoad is what a server's active sessions hold
+type serverLoad struct {
+	committed    manman.ResourceLimits
+	sessions     int
+	gameSessions map[int64]int // active sessions by game ID
+}
+
+// loadOnServer tallies the active sessions on a server, skipping those of excludeSGCID.
+func loadOnServer(ctx context.Context, repo *repository.Repository, serverID, excludeSGCID int64) (*serverLoad, error) {
+	load := &serverLoad{gameSessions: make(map[int64]int)}
+
+	sessions, err := repo.Sessions.ListWithFilters(ctx, &repository.SessionFilters{
+		ServerID:     &serverID,
+		StatusFilter: manman.CapacityReservingStatuses,
+	}, 1000, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active sessions: %w", err)
+	}
+
+	sgcs := make(map[int64]*manman.ServerGameConfig)
+	gcs := make(map[int64]*manman.GameConfig)
+	for _, s := range sessions {
+		if s.SGCID == excludeSGCID {
+			continue
+		}
+		sgc, ok := sgcs[s.SGCID]
+		if !ok {
+			if sgc, err = repo.ServerGameConfigs.Get(ctx, s.SGCID); err != nil {
+				return nil, fmt.Errorf("failed to fetch server game config %d: %w", s.SGCID, err)
+			}
+			sgcs[s.SGCID] = sgc
+		}
+		if sgc.ServerID != serverID {
+			continue
+		}
+		gc, ok := gcs[sgc.GameConfigID]
+		if !ok {
+			if gc, err = repo.GameConfigs.Get(ctx, sgc.GameConfigID); err != nil {
+				return nil, fmt.Errorf("failed to fetch game config %d: %w", sgc.GameConfigID, err)
+			}
+			gcs[sgc.GameConfigID] = gc
+		}
+
+		limits := manman.ResolveResourceLimits(gc, sgc)
+		load.committed.CPUMillicores += limits.CPUMillicores
+		load.committed.MemoryMB += limits.MemoryMB
+		load.sessions++
+		load.gameSessions[gc.GameID]++
+	}
+	return load, nil
+}
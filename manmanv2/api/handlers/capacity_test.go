@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// limitedGCRepo returns game configs that reserve 1 core and 1GB
+type limitedGCRepo struct {
+	repository.GameConfigRepository
+}
+
+func (m *limitedGCRepo) Get(ctx context.Context, id int64) (*manman.GameConfig, error) {
+	cpu, mem := int32(1000), int32(1024)
+	return &manman.GameConfig{ConfigID: id, GameID: 1, CPUMillicores: &cpu, MemoryMB: &mem}, nil
+}
+
+func TestCheckServerCapacity(t *testing.T) {
+	sessionRepo := &MockSessionRepo{}
+	repo := &repository.Repository{
+		Sessions:           sessionRepo,
+		ServerGameConfigs:  &MockSGCRepo{},
+		GameConfigs:        &limitedGCRepo{},
+		ServerCapabilities: &MockServerCapabilityRepo{cap: &manman.ServerCapability{ServerID: 1, CPUCores: 2, TotalMemoryMB: 2048}},
+	}
+	requested := manman.ResourceLimits{CPUMillicores: 1000, MemoryMB: 1024}
+
+	// Another SGC on the server already holds 1 core and 1GB; what's left fits exactly
+	sessionRepo.sessions = []*manman.Session{{SessionID: 1, SGCID: 200, Status: manman.SessionStatusRunning}}
+	if msg, err := checkServerCapacity(context.Background(), repo, 1, 0, requested); err != nil || msg != "" {
+		t.Fatalf("Expected the start to fit, got %q, %v", msg, err)
+	}
+
+	// A second one leaves nothing free
+	sessionRepo.sessions = append(sessionRepo.sessions, &manman.Session{SessionID: 2, SGCID: 100, Status: manman.SessionStatusRunning})
+	msg, err := checkServerCapacity(context.Background(), repo, 1, 0, requested)
+	if err != nil || !strings.Contains(msg, "0m of 2000m free") {
+		t.Errorf("Expected no cpu free, got %q, %v", msg, err)
+	}
+
+	// An SGC's own sessions don't count against it
+	if msg, err := checkServerCapacity(context.Background(), repo, 1, 100, requested); err != nil || msg != "" {
+		t.Errorf("Expected the SGC's own reservation to be excluded, got %q, %v", msg, err)
+	}
+}
+
+func TestStartSessionRejectsOvercommit(t *testing.T) {
+	sessionRepo := &MockSessionRepo{overcommit: "cpu: 1000m requested, 0m of 2000m free"}
+	gcRepo := &limitedGCRepo{}
+
+	repo := &repository.Repository{
+		Sessions:          sessionRepo,
+		ServerGameConfigs: &MockSGCRepo{},
+		GameConfigs:       gcRepo,
+		ServerPorts:       &MockServerPortRepo{},
+		GameConfigVolumes: &MockGameConfigVolumeRepo{},
+	}
+	h := &SessionHandler{repo: repo, sessionRepo: sessionRepo, sgcRepo: repo.ServerGameConfigs, gcRepo: gcRepo}
+
+	_, err := h.StartSession(context.Background(), &pb.StartSessionRequest{ServerGameConfigId: 300})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition, got %v", err)
+	}
+	if len(sessionRepo.created) != 0 {
+		t.Error("Expected no session to be created when the server is full")
+	}
+	// The SGC's resolved limits are what's checked against the server
+	if sessionRepo.limits.CPUMillicores != 1000 || sessionRepo.limits.MemoryMB != 1024 {
+		t.Errorf("Expected the game config's limits to be checked, got %+v", sessionRepo.limits)
+	}
+}
@@ -87,6 +87,13 @@ func (p *CommandPublisher) PublishBackup(ctx context.Context, serverID int64, cm
 	return p.publisher.PublishWithExpiry(ctx, "manman", routingKey, cmd, backupCommandExpiry)
 }
 
+func (p *CommandPublisher) PublishRestoreBackup(ctx context.Context, serverID int64, cmd interface{}) error {
+	routingKey := fmt.Sprintf("command.host.%d.backup.restore", serverID)
+	// Fire-and-forget like backups: the restore runs async on the host and any
+	// follow-up session reports its progress through the usual status updates.
+	return p.publisher.PublishWithExpiry(ctx, "manman", routingKey, cmd, backupCommandExpiry)
+}
+
 func (p *CommandPublisher) publishAndWait(ctx context.Context, routingKey string, data interface{}, timeout time.Duration) error {
 	// Generate correlation ID
 	correlationID := uuid.New().String()
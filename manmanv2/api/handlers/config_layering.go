@@ -0,0 +1,897 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"gopkg.in/yaml.v3"
+)
+
+// patchLevelBase labels values that came from the strategy's base template in conflicts.
+const patchLevelBase = "base"
+
+// layerConfigurationPatches applies a strategy's patches on top of its base template, in the
+// order given (game_config → server_game_config → session, each by patch_order), honouring
+// each patch's format. It returns the composed overrides sent to the host as rendered
+// content, plus one PatchLayer per patch describing what it changed.
+//
+// A patch that cannot be applied (bad syntax, failed json_patch op) is reported on its layer
+// and skipped rather than failing the whole render, so one broken patch doesn't stop a
+// session from starting with the rest of its configuration.
+func layerConfigurationPatches(strategyType, baseContent string, patches []*manman.ConfigurationPatch) (string, []*pb.PatchLayer) {
+	switch strategyType {
+	case manman.StrategyTypeFileJSON, manman.StrategyTypeFileYAML:
+		return layerDocumentPatches(strategyType, baseContent, patches)
+	case manman.StrategyTypeFileProperties, manman.StrategyTypeEnvVars, manman.StrategyTypeCLIArgs:
+		return layerKeyValuePatches(strategyType, baseContent, patches)
+	}
+
+	// No layering engine for this strategy type yet: pass the patches through in order.
+	var parts []string
+	layers := make([]*pb.PatchLayer, 0, len(patches))
+	for _, p := range patches {
+		layer := newPatchLayer(p, p.PatchFormat)
+		if layer.PatchContent != "" {
+			parts = append(parts, layer.PatchContent)
+		}
+		layers = append(layers, layer)
+	}
+	return strings.Join(parts, "\n"), layers
+}
+
+// resolvePatchFormat maps a patch's declared format to the one used to apply it. "template",
+// "properties" and unset formats mean the strategy's native format.
+func resolvePatchFormat(strategyType, format string) string {
+	switch format {
+	case manman.PatchFormatJSONMergePatch, manman.PatchFormatJSONPatch, manman.PatchFormatYAMLMerge:
+		return format
+	}
+	switch strategyType {
+	case manman.StrategyTypeFileJSON:
+		return manman.PatchFormatJSONMergePatch
+	case manman.StrategyTypeFileYAML:
+		return manman.PatchFormatYAMLMerge
+	}
+	return manman.PatchFormatProperties
+}
+
+func newPatchLayer(p *manman.ConfigurationPatch, format string) *pb.PatchLayer {
+	layer := &pb.PatchLayer{
+		Level:       p.PatchLevel,
+		PatchId:     p.PatchID,
+		PatchFormat: format,
+		PatchOrder:  int32(p.PatchOrder),
+	}
+	if p.PatchContent != nil {
+		layer.PatchContent = *p.PatchContent
+	}
+	return layer
+}
+
+// ============================================================================
+// Key/value strategies: file_properties, env_vars, cli_args
+// ============================================================================
+
+type keyValueEntry struct {
+	value string
+	level string
+}
+
+type keyValueOp struct {
+	key    string
+	value  string
+	remove bool
+}
+
+// layerKeyValuePatches layers patches for line-based strategies. Every format is reduced to
+// a list of key overrides; the output is one line per key set by any patch, in the order the
+// keys were first set, with the last value winning.
+func layerKeyValuePatches(strategyType, baseContent string, patches []*manman.ConfigurationPatch) (string, []*pb.PatchLayer) {
+	current := make(map[string]keyValueEntry)
+	for _, op := range parseKeyValueLines(strategyType, baseContent) {
+		current[op.key] = keyValueEntry{value: op.value, level: patchLevelBase}
+	}
+
+	var order []string
+	overridden := make(map[string]bool)
+	layers := make([]*pb.PatchLayer, 0, len(patches))
+
+	for _, p := range patches {
+		format := resolvePatchFormat(strategyType, p.PatchFormat)
+		layer := newPatchLayer(p, format)
+		layers = append(layers, layer)
+
+		ops, err := parseKeyValuePatch(strategyType, format, layer.PatchContent, current)
+		if err != nil {
+			layer.Error = err.Error()
+			continue
+		}
+
+		// The host merges overrides into the base/existing file and has no way to drop a
+		// line, so removals can't be honoured for these strategies.
+		if key, ok := firstRemoval(ops); ok {
+			layer.Error = fmt.Sprintf("cannot remove %q: %s strategies only support overriding keys", key, strategyType)
+			continue
+		}
+
+		for _, op := range ops {
+			prev, exists := current[op.key]
+			if exists && prev.value != op.value {
+				layer.Conflicts = append(layer.Conflicts, &pb.PatchConflict{
+					Path:          op.key,
+					PreviousValue: prev.value,
+					NewValue:      op.value,
+					PreviousLevel: prev.level,
+				})
+			}
+			current[op.key] = keyValueEntry{value: op.value, level: p.PatchLevel}
+			if !overridden[op.key] {
+				overridden[op.key] = true
+				order = append(order, op.key)
+			}
+		}
+	}
+
+	lines := make([]string, 0, len(order))
+	for _, key := range order {
+		lines = append(lines, formatKeyValueLine(strategyType, key, current[key].value))
+	}
+	return strings.Join(lines, "\n"), layers
+}
+
+func firstRemoval(ops []keyValueOp) (string, bool) {
+	for _, op := range ops {
+		if op.remove {
+			return op.key, true
+		}
+	}
+	return "", false
+}
+
+// parseKeyValuePatch turns one patch into key overrides. current is the state before the
+// patch, used by json_patch "test", "move" and "copy".
+func parseKeyValuePatch(strategyType, format, content string, current map[string]keyValueEntry) ([]keyValueOp, error) {
+	switch format {
+	case manman.PatchFormatJSONMergePatch, manman.PatchFormatYAMLMerge:
+		var obj map[string]interface{}
+		if err := decodePatchDocument(format, content, &obj); err != nil {
+			return nil, err
+		}
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		ops := make([]keyValueOp, 0, len(keys))
+		for _, key := range keys {
+			if obj[key] == nil {
+				ops = append(ops, keyValueOp{key: key, remove: true})
+				continue
+			}
+			value, err := scalarString(obj[key])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			ops = append(ops, keyValueOp{key: key, value: value})
+		}
+		return ops, nil
+
+	case manman.PatchFormatJSONPatch:
+		jsonOps, err := parseJSONPatch(content)
+		if err != nil {
+			return nil, err
+		}
+		var ops []keyValueOp
+		for _, op := range jsonOps {
+			key, err := flatPointerKey(op.Path)
+			if err != nil {
+				return nil, err
+			}
+			switch op.Op {
+			case "add", "replace":
+				if _, exists := current[key]; op.Op == "replace" && !exists {
+					return nil, fmt.Errorf("replace %s: key does not exist", op.Path)
+				}
+				value, err := scalarString(op.Value)
+				if err != nil {
+					return nil, fmt.Errorf("%s %s: %w", op.Op, op.Path, err)
+				}
+				ops = append(ops, keyValueOp{key: key, value: value})
+			case "remove":
+				ops = append(ops, keyValueOp{key: key, remove: true})
+			case "test":
+				want, err := scalarString(op.Value)
+				if err != nil {
+					return nil, fmt.Errorf("test %s: %w", op.Path, err)
+				}
+				if entry, exists := current[key]; !exists || entry.value != want {
+					return nil, fmt.Errorf("test %s failed", op.Path)
+				}
+			case "move", "copy":
+				from, err := flatPointerKey(op.From)
+				if err != nil {
+					return nil, err
+				}
+				entry, exists := current[from]
+				if !exists {
+					return nil, fmt.Errorf("%s from %s: key does not exist", op.Op, op.From)
+				}
+				ops = append(ops, keyValueOp{key: key, value: entry.value})
+				if op.Op == "move" {
+					ops = append(ops, keyValueOp{key: from, remove: true})
+				}
+			default:
+				return nil, fmt.Errorf("unsupported json_patch op %q", op.Op)
+			}
+		}
+		return ops, nil
+	}
+
+	return parseKeyValueLines(strategyType, content), nil
+}
+
+// parseKeyValueLines parses a strategy's native line format. It mirrors the host renderer:
+// properties split on the first '=' or ':', env vars on '=' (with optional "export " and
+// quotes), and CLI args on the first space between flag and value.
+func parseKeyValueLines(strategyType, content string) []keyValueOp {
+	var ops []keyValueOp
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var key, value string
+		switch strategyType {
+		case manman.StrategyTypeCLIArgs:
+			key, value, _ = strings.Cut(line, " ")
+			value = strings.TrimSpace(value)
+		case manman.StrategyTypeEnvVars:
+			line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+			var found bool
+			key, value, found = strings.Cut(line, "=")
+			if !found {
+				continue
+			}
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+				value = value[1 : len(value)-1]
+			}
+		default:
+			if strings.HasPrefix(line, "!") {
+				continue
+			}
+			sepIndex := strings.IndexAny(line, "=:")
+			if sepIndex == -1 {
+				continue
+			}
+			key, value = strings.TrimSpace(line[:sepIndex]), strings.TrimSpace(line[sepIndex+1:])
+		}
+
+		if key != "" {
+			ops = append(ops, keyValueOp{key: key, value: value})
+		}
+	}
+	return ops
+}
+
+func formatKeyValueLine(strategyType, key, value string) string {
+	if strategyType == manman.StrategyTypeCLIArgs {
+		if value == "" {
+			return key
+		}
+		return key + " " + value
+	}
+	return key + "=" + value
+}
+
+// flatPointerKey returns the single key addressed by a JSON pointer like "/max-players".
+func flatPointerKey(pointer string) (string, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return "", err
+	}
+	if len(tokens) != 1 {
+		return "", fmt.Errorf("path %q must address a single top-level key", pointer)
+	}
+	return tokens[0], nil
+}
+
+// scalarString renders a patch value as a line value: strings as-is, other scalars in
+// their JSON form.
+func scalarString(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case map[string]interface{}, []interface{}:
+		return "", fmt.Errorf("value must be a scalar")
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ============================================================================
+// Structured strategies: file_json, file_yaml
+// ============================================================================
+
+// layerDocumentPatches layers patches onto a JSON/YAML document.
+//
+// With a base template the final document is known, so the output is an RFC 7396 merge
+// patch from the base to it; applying it on the host reproduces the layered result exactly.
+// Without a base (merge mode) the host merges into whatever file the game generated, so the
+// output is the patches composed into a single merge patch, keeping nulls for removals.
+func layerDocumentPatches(strategyType, baseContent string, patches []*manman.ConfigurationPatch) (string, []*pb.PatchLayer) {
+	layers := make([]*pb.PatchLayer, 0, len(patches))
+
+	baseFormat := resolvePatchFormat(strategyType, "")
+	var base interface{}
+	mergeMode := strings.TrimSpace(baseContent) == ""
+	if !mergeMode {
+		if err := decodePatchDocument(baseFormat, baseContent, &base); err != nil {
+			// The host will fail to render the bad base itself; there is nothing to diff
+			// against here, so compose the patches as in merge mode.
+			mergeMode = true
+			base = nil
+		}
+	}
+	if base == nil {
+		base = map[string]interface{}{}
+	}
+
+	doc := deepCopyValue(base)
+	var overlay interface{}
+	owners := make(map[string]string)
+
+	for _, p := range patches {
+		format := resolvePatchFormat(strategyType, p.PatchFormat)
+		layer := newPatchLayer(p, format)
+		layers = append(layers, layer)
+
+		if strings.TrimSpace(layer.PatchContent) == "" {
+			continue
+		}
+
+		before := doc
+		var after interface{}
+		switch format {
+		case manman.PatchFormatJSONPatch:
+			ops, err := parseJSONPatch(layer.PatchContent)
+			if err != nil {
+				layer.Error = err.Error()
+				continue
+			}
+			after, err = applyJSONPatch(deepCopyValue(before), ops)
+			if err != nil {
+				layer.Error = err.Error()
+				continue
+			}
+			if mergeMode {
+				if diff, changed := mergePatchDiff(before, after); changed {
+					overlay = composeMergePatch(overlay, diff)
+				}
+			}
+		default:
+			var patch interface{}
+			if err := decodePatchDocument(format, layer.PatchContent, &patch); err != nil {
+				layer.Error = err.Error()
+				continue
+			}
+			after = applyMergePatch(deepCopyValue(before), patch)
+			if mergeMode {
+				overlay = composeMergePatch(overlay, patch)
+			}
+		}
+
+		var changes []documentChange
+		diffDocuments("", before, true, after, true, &changes)
+		for _, c := range changes {
+			if c.oldExists {
+				prevLevel := ownerOf(owners, c.path)
+				if prevLevel == "" {
+					prevLevel = patchLevelBase
+				}
+				conflict := &pb.PatchConflict{
+					Path:          c.path,
+					PreviousValue: displayValue(c.oldValue),
+					PreviousLevel: prevLevel,
+					Removed:       !c.newExists,
+				}
+				if c.newExists {
+					conflict.NewValue = displayValue(c.newValue)
+				}
+				layer.Conflicts = append(layer.Conflicts, conflict)
+			}
+			setOwner(owners, c.path, p.PatchLevel)
+		}
+		doc = after
+	}
+
+	var out interface{}
+	if mergeMode {
+		out = overlay
+	} else if diff, changed := mergePatchDiff(base, doc); changed {
+		out = diff
+	}
+	if out == nil {
+		return "", layers
+	}
+
+	content, err := encodeDocument(strategyType, out)
+	if err != nil {
+		// Values all came from decoded JSON/YAML, so this shouldn't happen; surface it on
+		// the last layer rather than dropping the error.
+		if len(layers) > 0 {
+			layers[len(layers)-1].Error = fmt.Sprintf("failed to encode layered document: %v", err)
+		}
+		return "", layers
+	}
+	return content, layers
+}
+
+// decodePatchDocument decodes a JSON or YAML document into out. Both are normalised to
+// JSON types (map[string]interface{}, json.Number) so values compare consistently across
+// formats.
+func decodePatchDocument(format, content string, out interface{}) error {
+	data := []byte(content)
+	if format == manman.PatchFormatYAMLMerge {
+		var v interface{}
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return fmt.Errorf("invalid YAML: %w", err)
+		}
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return fmt.Errorf("YAML cannot be represented as JSON: %w", err)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(out); err != nil {
+		return fmt.Errorf("invalid JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("invalid JSON: unexpected data after document")
+	}
+	return nil
+}
+
+// encodeDocument renders a layered document in the strategy's file format.
+func encodeDocument(strategyType string, v interface{}) (string, error) {
+	if strategyType == manman.StrategyTypeFileYAML {
+		out, err := yaml.Marshal(yamlNumbers(v))
+		return string(out), err
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	return string(out), err
+}
+
+// yamlNumbers converts json.Number values to ints/floats so YAML doesn't quote them.
+func yamlNumbers(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			out[k] = yamlNumbers(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = yamlNumbers(child)
+		}
+		return out
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i
+		}
+		if f, err := val.Float64(); err == nil {
+			return f
+		}
+		return val.String()
+	}
+	return v
+}
+
+// applyMergePatch applies an RFC 7396 merge patch to target.
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetMap, ok := target.(map[string]interface{})
+	if !ok {
+		targetMap = make(map[string]interface{}, len(patchMap))
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+			continue
+		}
+		targetMap[key] = applyMergePatch(targetMap[key], value)
+	}
+	return targetMap
+}
+
+// composeMergePatch combines two merge patches into one with the same effect as applying a
+// then b. Nulls are kept so the combined patch still removes keys.
+func composeMergePatch(a, b interface{}) interface{} {
+	bMap, ok := b.(map[string]interface{})
+	if !ok {
+		return deepCopyValue(b)
+	}
+	aMap, ok := a.(map[string]interface{})
+	if !ok {
+		aMap = make(map[string]interface{}, len(bMap))
+	}
+	for key, value := range bMap {
+		if _, isMap := value.(map[string]interface{}); isMap {
+			if existing, ok := aMap[key].(map[string]interface{}); ok {
+				aMap[key] = composeMergePatch(existing, value)
+				continue
+			}
+		}
+		aMap[key] = deepCopyValue(value)
+	}
+	return aMap
+}
+
+// mergePatchDiff returns the merge patch that turns from into to, and whether they differ.
+func mergePatchDiff(from, to interface{}) (interface{}, bool) {
+	fromMap, fromOK := from.(map[string]interface{})
+	toMap, toOK := to.(map[string]interface{})
+	if !fromOK || !toOK {
+		if reflect.DeepEqual(from, to) {
+			return nil, false
+		}
+		return deepCopyValue(to), true
+	}
+
+	patch := make(map[string]interface{})
+	for key := range fromMap {
+		if _, exists := toMap[key]; !exists {
+			patch[key] = nil
+		}
+	}
+	for key, value := range toMap {
+		old, exists := fromMap[key]
+		if !exists {
+			patch[key] = deepCopyValue(value)
+			continue
+		}
+		if sub, changed := mergePatchDiff(old, value); changed {
+			patch[key] = sub
+		}
+	}
+	return patch, len(patch) > 0
+}
+
+// documentChange is a value added, replaced or removed at a JSON pointer.
+type documentChange struct {
+	path      string
+	oldValue  interface{}
+	oldExists bool
+	newValue  interface{}
+	newExists bool
+}
+
+// diffDocuments records changes between two documents. Objects are compared key by key;
+// any other value (including arrays) is compared as a whole.
+func diffDocuments(path string, oldValue interface{}, oldExists bool, newValue interface{}, newExists bool, out *[]documentChange) {
+	oldMap, oldIsMap := oldValue.(map[string]interface{})
+	newMap, newIsMap := newValue.(map[string]interface{})
+	if oldExists && newExists && oldIsMap && newIsMap {
+		keys := make([]string, 0, len(oldMap)+len(newMap))
+		for key := range oldMap {
+			keys = append(keys, key)
+		}
+		for key := range newMap {
+			if _, exists := oldMap[key]; !exists {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			o, oOK := oldMap[key]
+			n, nOK := newMap[key]
+			diffDocuments(path+"/"+escapeJSONPointer(key), o, oOK, n, nOK, out)
+		}
+		return
+	}
+
+	if oldExists == newExists && reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+	*out = append(*out, documentChange{path: path, oldValue: oldValue, oldExists: oldExists, newValue: newValue, newExists: newExists})
+}
+
+// ownerOf returns the level that last set path or its nearest ancestor.
+func ownerOf(owners map[string]string, path string) string {
+	for {
+		if level, ok := owners[path]; ok {
+			return level
+		}
+		if path == "" {
+			return ""
+		}
+		path = path[:strings.LastIndex(path, "/")]
+	}
+}
+
+// setOwner records level as the owner of path, replacing owners of anything beneath it.
+func setOwner(owners map[string]string, path, level string) {
+	for p := range owners {
+		if strings.HasPrefix(p, path+"/") {
+			delete(owners, p)
+		}
+	}
+	owners[path] = level
+}
+
+// displayValue renders a value for conflict reporting: strings bare, everything else as JSON.
+func displayValue(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(out)
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			out[k] = deepCopyValue(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			out[i] = deepCopyValue(child)
+		}
+		return out
+	}
+	return v
+}
+
+// ============================================================================
+// RFC 6902 JSON Patch
+// ============================================================================
+
+type jsonPatchOp struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	From  string      `json:"from,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func parseJSONPatch(content string) ([]jsonPatchOp, error) {
+	var ops []jsonPatchOp
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	if err := dec.Decode(&ops); err != nil {
+		return nil, fmt.Errorf("invalid json_patch: %w", err)
+	}
+	return ops, nil
+}
+
+// applyJSONPatch applies RFC 6902 operations in order. doc may be modified.
+func applyJSONPatch(doc interface{}, ops []jsonPatchOp) (interface{}, error) {
+	for i, op := range ops {
+		path, err := parseJSONPointer(op.Path)
+		if err != nil {
+			return nil, fmt.Errorf("op %d: %w", i, err)
+		}
+
+		switch op.Op {
+		case "add":
+			doc, err = pointerAdd(doc, path, deepCopyValue(op.Value))
+		case "remove":
+			doc, err = pointerRemove(doc, path)
+		case "replace":
+			if _, err = pointerGet(doc, path); err == nil {
+				doc, err = pointerReplace(doc, path, deepCopyValue(op.Value))
+			}
+		case "move", "copy":
+			var from []string
+			var value interface{}
+			if from, err = parseJSONPointer(op.From); err != nil {
+				break
+			}
+			if value, err = pointerGet(doc, from); err != nil {
+				break
+			}
+			value = deepCopyValue(value)
+			if op.Op == "move" {
+				if doc, err = pointerRemove(doc, from); err != nil {
+					break
+				}
+			}
+			doc, err = pointerAdd(doc, path, value)
+		case "test":
+			var value interface{}
+			if value, err = pointerGet(doc, path); err == nil && !reflect.DeepEqual(value, op.Value) {
+				err = fmt.Errorf("test failed: value is %s", displayValue(value))
+			}
+		default:
+			err = fmt.Errorf("unsupported op %q", op.Op)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("op %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+	return doc, nil
+}
+
+// parseJSONPointer splits an RFC 6901 pointer into unescaped reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(t, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func escapeJSONPointer(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
+
+func pointerGet(doc interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			child, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("path not found")
+			}
+			doc = child
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[idx]
+		default:
+			return nil, fmt.Errorf("path not found")
+		}
+	}
+	return doc, nil
+}
+
+// pointerUpdate rebuilds doc with fn applied to the container holding the last token.
+// Arrays are rebuilt rather than mutated since inserting or removing changes the slice.
+func pointerUpdate(doc interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return fn(doc, path[0])
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[path[0]]
+		if !ok {
+			return nil, fmt.Errorf("path not found")
+		}
+		updated, err := pointerUpdate(child, path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[path[0]] = updated
+		return node, nil
+	case []interface{}:
+		idx, err := arrayIndex(path[0], len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := pointerUpdate(node[idx], path[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[idx] = updated
+		return node, nil
+	}
+	return nil, fmt.Errorf("path not found")
+}
+
+func pointerAdd(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			idx := len(node)
+			if token != "-" {
+				var err error
+				if idx, err = arrayIndex(token, len(node)); err != nil {
+					return nil, err
+				}
+			}
+			out := make([]interface{}, 0, len(node)+1)
+			out = append(out, node[:idx]...)
+			out = append(out, value)
+			return append(out, node[idx:]...), nil
+		}
+		return nil, fmt.Errorf("parent is not an object or array")
+	})
+}
+
+func pointerRemove(doc interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := node[token]; !ok {
+				return nil, fmt.Errorf("path not found")
+			}
+			delete(node, token)
+			return node, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			out := make([]interface{}, 0, len(node)-1)
+			out = append(out, node[:idx]...)
+			return append(out, node[idx+1:]...), nil
+		}
+		return nil, fmt.Errorf("path not found")
+	})
+}
+
+func pointerReplace(doc interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return pointerUpdate(doc, path, func(parent interface{}, token string) (interface{}, error) {
+		switch node := parent.(type) {
+		case map[string]interface{}:
+			node[token] = value
+			return node, nil
+		case []interface{}:
+			idx, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			node[idx] = value
+			return node, nil
+		}
+		return nil, fmt.Errorf("path not found")
+	})
+}
+
+// arrayIndex parses an array reference token, allowing indexes up to max.
+func arrayIndex(token string, max int) (int, error) {
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if idx > max {
+		return 0, fmt.Errorf("array index %d out of range", idx)
+	}
+	return idx, nil
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testPatch(id int64, level, format, content string) *manman.ConfigurationPatch {
+	return &manman.ConfigurationPatch{
+		PatchID:      id,
+		PatchLevel:   level,
+		PatchFormat:  format,
+		PatchContent: stringPtr(content),
+	}
+}
+
+func TestLayerConfigurationPatches_PropertiesOverride(t *testing.T) {
+	base := "motd=Base\nmax-players=10\npvp=true"
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelGameConfig, manman.PatchFormatTemplate, "motd=Game Config\ndifficulty=normal"),
+		testPatch(2, manman.PatchLevelServerGameConfig, manman.PatchFormatProperties, "motd=SGC\nmax-players=10"),
+		testPatch(3, manman.PatchLevelSession, manman.PatchFormatJSONMergePatch, `{"max-players": 16}`),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeFileProperties, base, patches)
+
+	if want := "motd=SGC\ndifficulty=normal\nmax-players=16"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if len(layers) != 3 {
+		t.Fatalf("Expected 3 layers, got %d", len(layers))
+	}
+
+	// game_config overrides the base motd
+	if len(layers[0].Conflicts) != 1 || layers[0].Conflicts[0].PreviousLevel != patchLevelBase || layers[0].Conflicts[0].PreviousValue != "Base" {
+		t.Errorf("Unexpected game_config conflicts: %v", layers[0].Conflicts)
+	}
+	// SGC overrides game_config's motd; max-players=10 matches the base so isn't a conflict
+	if len(layers[1].Conflicts) != 1 || layers[1].Conflicts[0].Path != "motd" || layers[1].Conflicts[0].PreviousLevel != manman.PatchLevelGameConfig {
+		t.Errorf("Unexpected server_game_config conflicts: %v", layers[1].Conflicts)
+	}
+	if layers[1].PatchFormat != manman.PatchFormatProperties {
+		t.Errorf("Expected properties format, got %q", layers[1].PatchFormat)
+	}
+	if len(layers[2].Conflicts) != 1 || layers[2].Conflicts[0].NewValue != "16" {
+		t.Errorf("Unexpected session conflicts: %v", layers[2].Conflicts)
+	}
+}
+
+func TestLayerConfigurationPatches_PropertiesRejectsRemoval(t *testing.T) {
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelGameConfig, manman.PatchFormatJSONPatch, `[{"op":"remove","path":"/motd"}]`),
+		testPatch(2, manman.PatchLevelServerGameConfig, manman.PatchFormatTemplate, "pvp=false"),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeFileProperties, "motd=Base", patches)
+
+	if content != "pvp=false" {
+		t.Errorf("content = %q, want %q", content, "pvp=false")
+	}
+	if layers[0].Error == "" {
+		t.Error("Expected an error for removing a property")
+	}
+	if layers[1].Error != "" {
+		t.Errorf("Unexpected error on second layer: %s", layers[1].Error)
+	}
+}
+
+func TestLayerConfigurationPatches_CLIArgs(t *testing.T) {
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelGameConfig, "", "-maxplayers 10\n+map de_dust2"),
+		testPatch(2, manman.PatchLevelServerGameConfig, "", "-maxplayers 16"),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeCLIArgs, "-dedicated", patches)
+
+	if want := "-maxplayers 16\n+map de_dust2"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if len(layers[1].Conflicts) != 1 || layers[1].Conflicts[0].Path != "-maxplayers" {
+		t.Errorf("Unexpected conflicts: %v", layers[1].Conflicts)
+	}
+}
+
+func TestLayerConfigurationPatches_JSONWithBase(t *testing.T) {
+	base := `{"name":"base","settings":{"pvp":true,"difficulty":"easy"},"admins":["root"]}`
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelGameConfig, manman.PatchFormatJSONMergePatch, `{"settings":{"difficulty":"normal"}}`),
+		testPatch(2, manman.PatchLevelServerGameConfig, manman.PatchFormatJSONPatch, `[
+			{"op":"replace","path":"/settings/difficulty","value":"hard"},
+			{"op":"add","path":"/admins/-","value":"alice"},
+			{"op":"remove","path":"/name"}
+		]`),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeFileJSON, base, patches)
+
+	// Merge patch from base to final document: removals become null
+	want := `{
+  "admins": [
+    "root",
+    "alice"
+  ],
+  "name": null,
+  "settings": {
+    "difficulty": "hard"
+  }
+}`
+	if content != want {
+		t.Errorf("content mismatch.\nGot:\n%s\nWant:\n%s", content, want)
+	}
+
+	if len(layers[0].Conflicts) != 1 || layers[0].Conflicts[0].Path != "/settings/difficulty" || layers[0].Conflicts[0].PreviousLevel != patchLevelBase {
+		t.Errorf("Unexpected game_config conflicts: %v", layers[0].Conflicts)
+	}
+
+	conflicts := map[string]bool{}
+	for _, c := range layers[1].Conflicts {
+		conflicts[c.Path] = c.Removed
+	}
+	if removed, ok := conflicts["/name"]; !ok || !removed {
+		t.Errorf("Expected /name to be reported removed, got %v", layers[1].Conflicts)
+	}
+	if _, ok := conflicts["/settings/difficulty"]; !ok {
+		t.Errorf("Expected /settings/difficulty conflict, got %v", layers[1].Conflicts)
+	}
+	if _, ok := conflicts["/admins"]; !ok {
+		t.Errorf("Expected /admins conflict, got %v", layers[1].Conflicts)
+	}
+}
+
+func TestLayerConfigurationPatches_JSONMergeModeKeepsRemovals(t *testing.T) {
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelGameConfig, manman.PatchFormatJSONMergePatch, `{"motd":null,"port":7777}`),
+		testPatch(2, manman.PatchLevelSession, manman.PatchFormatJSONMergePatch, `{"port":7778}`),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeFileJSON, "", patches)
+
+	want := "{\n  \"motd\": null,\n  \"port\": 7778\n}"
+	if content != want {
+		t.Errorf("content mismatch.\nGot:\n%s\nWant:\n%s", content, want)
+	}
+	if len(layers[1].Conflicts) != 1 || layers[1].Conflicts[0].PreviousValue != "7777" || layers[1].Conflicts[0].PreviousLevel != manman.PatchLevelGameConfig {
+		t.Errorf("Unexpected session conflicts: %v", layers[1].Conflicts)
+	}
+}
+
+func TestLayerConfigurationPatches_YAMLMerge(t *testing.T) {
+	base := "server:\n  port: 25565\n  name: base\nworld: overworld\n"
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelServerGameConfig, manman.PatchFormatYAMLMerge, "server:\n  name: Whale Net\n  port: 25565\n"),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeFileYAML, base, patches)
+
+	if want := "server:\n    name: Whale Net\n"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	// port is unchanged, so only name conflicts
+	if len(layers[0].Conflicts) != 1 || layers[0].Conflicts[0].Path != "/server/name" {
+		t.Errorf("Unexpected conflicts: %v", layers[0].Conflicts)
+	}
+}
+
+func TestLayerConfigurationPatches_InvalidPatchIsSkipped(t *testing.T) {
+	patches := []*manman.ConfigurationPatch{
+		testPatch(1, manman.PatchLevelGameConfig, manman.PatchFormatJSONMergePatch, `{"port":`),
+		testPatch(2, manman.PatchLevelServerGameConfig, manman.PatchFormatJSONPatch, `[{"op":"replace","path":"/missing","value":1}]`),
+		testPatch(3, manman.PatchLevelSession, manman.PatchFormatJSONMergePatch, `{"port":7778}`),
+	}
+
+	content, layers := layerConfigurationPatches(manman.StrategyTypeFileJSON, `{"port":7777}`, patches)
+
+	if layers[0].Error == "" || layers[1].Error == "" {
+		t.Errorf("Expected errors on invalid layers, got %q and %q", layers[0].Error, layers[1].Error)
+	}
+	if want := "{\n  \"port\": 7778\n}"; content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	var doc interface{}
+	if err := decodePatchDocument(manman.PatchFormatJSONMergePatch, `{"a":{"b":1},"list":[1,2,3]}`, &doc); err != nil {
+		t.Fatal(err)
+	}
+	ops, err := parseJSONPatch(`[
+		{"op":"test","path":"/a/b","value":1},
+		{"op":"move","from":"/a/b","path":"/c"},
+		{"op":"copy","from":"/c","path":"/a/d"},
+		{"op":"remove","path":"/list/0"},
+		{"op":"add","path":"/list/1","value":9},
+		{"op":"add","path":"/x~1y","value":true}
+	]`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := applyJSONPatch(doc, ops)
+	if err != nil {
+		t.Fatalf("applyJSONPatch failed: %v", err)
+	}
+
+	var want interface{}
+	if err := decodePatchDocument(manman.PatchFormatJSONMergePatch, `{"a":{"d":1},"c":1,"list":[2,9,3],"x/y":true}`, &want); err != nil {
+		t.Fatal(err)
+	}
+	if displayValue(result) != displayValue(want) {
+		t.Errorf("result = %s, want %s", displayValue(result), displayValue(want))
+	}
+}
+
+func TestPreviewConfigurationOverrides(t *testing.T) {
+	strategies := &MockStrategyRepo{strategies: []*manman.ConfigurationStrategy{
+		{StrategyID: 5, GameID: 1, Name: "Server Properties", StrategyType: manman.StrategyTypeFileProperties, BaseTemplate: stringPtr("difficulty=easy\nmax-players=10")},
+	}}
+	patches := &MockPatchRepo{patches: []*manman.ConfigurationPatch{
+		{PatchID: 1, StrategyID: 5, PatchLevel: manman.PatchLevelGameConfig, EntityID: 1, PatchContent: stringPtr("difficulty=normal")},
+	}}
+	repo := &repository.Repository{
+		Sessions:             &MockSessionRepo{sessions: []*manman.Session{{SessionID: 7, SGCID: 100}}},
+		ServerGameConfigs:    &MockSGCRepo{},
+		GameConfigs:          &MockGCRepo{},
+		ConfigurationPatches: patches,
+	}
+	h := NewConfigurationStrategyHandler(strategies)
+
+	resp, err := h.PreviewConfiguration(context.Background(), &pb.PreviewConfigurationRequest{
+		SessionId:          7,
+		ParameterOverrides: []*pb.SessionPatchOverride{{StrategyId: 5, PatchContent: "difficulty=hard"}},
+	}, repo)
+	if err != nil {
+		t.Fatalf("PreviewConfiguration failed: %v", err)
+	}
+	config := resp.Configurations[0]
+	if config.StrategyId != 5 || !strings.Contains(config.RenderedContent, "difficulty=hard") {
+		t.Errorf("Expected the override rendered, got %q", config.RenderedContent)
+	}
+	if len(config.Patches) != 2 {
+		t.Fatalf("Expected the game config and override layers, got %d", len(config.Patches))
+	}
+	override := config.Patches[1]
+	if override.Level != manman.PatchLevelSession || len(override.Conflicts) != 1 || override.Conflicts[0].PreviousValue != "normal" || override.Conflicts[0].PreviousLevel != manman.PatchLevelGameConfig {
+		t.Errorf("Expected the override to conflict with the game config's value, got %+v", override)
+	}
+	if len(patches.patches) != 1 {
+		t.Error("Expected the override not to be saved")
+	}
+
+	// Overrides for another game's strategy are refused
+	_, err = h.PreviewConfiguration(context.Background(), &pb.PreviewConfigurationRequest{
+		SessionId:          7,
+		ParameterOverrides: []*pb.SessionPatchOverride{{StrategyId: 99, PatchContent: "difficulty=hard"}},
+	}, repo)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument, got %v", err)
+	}
+}
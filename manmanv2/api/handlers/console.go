@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// consoleBuffer is how many events a client may fall behind before input echoes and
+	// attendee updates for it are dropped; its own output is never dropped
+	consoleBuffer = 256
+
+	// Instances announce who is attached through them this often; attendees an instance
+	// hasn't announced for consolePresenceTTL (it went away) are dropped
+	consolePresenceInterval = 30 * time.Second
+	consolePresenceTTL      = 3 * consolePresenceInterval
+)
+
+// AuthorizeFunc checks whether ctx's caller may call method with req
+type AuthorizeFunc func(ctx context.Context, method string, req interface{}) error
+
+// RecordFunc records a call the audit interceptor can't see
+type RecordFunc func(ctx context.Context, method string, req interface{}, callErr error, start time.Time)
+
+// ConsoleHandler serves AttachSession. Each client's output is its own
+// StreamSessionLogs stream from the log-processor; input goes through SendInput and
+// is echoed with who sent it to every client attached to the session. Clients may be
+// attached through other API instances, so echoes and attendees are shared with them
+// through peers.
+type ConsoleHandler struct {
+	sessionHandler *SessionHandler
+	logs           pb.LogProcessorClient
+	authorize      AuthorizeFunc
+	record         RecordFunc
+	instance       string
+	peers          consolePeers // nil when this is the only instance
+
+	mu       sync.Mutex
+	consoles map[int64]map[*consoleClient]bool     // session ID → attached clients
+	remote   map[int64]map[string]*remoteAttendees // session ID → instance → its attendees
+}
+
+// remoteAttendees are the clients another instance last said were attached through it
+type remoteAttendees struct {
+	usernames []string
+	seen      time.Time
+}
+
+type consoleClient struct {
+	username string
+	events   chan *pb.AttachSessionEvent
+}
+
+func NewConsoleHandler(sessionHandler *SessionHandler) *ConsoleHandler {
+	return &ConsoleHandler{
+		sessionHandler: sessionHandler,
+		instance:       uuid.New().String(),
+		consoles:       make(map[int64]map[*consoleClient]bool),
+		remote:         make(map[int64]map[string]*remoteAttendees),
+	}
+}
+
+// SetPeers shares this instance's console events with the other API instances and keeps
+// its attendee announcements fresh until ctx ends
+func (h *ConsoleHandler) SetPeers(ctx context.Context, peers consolePeers) {
+	h.peers = peers
+	go func() {
+		ticker := time.NewTicker(consolePresenceInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.refreshPresence(ctx)
+			}
+		}
+	}()
+}
+
+// Enable turns AttachSession on. Input is authorized per line, so viewers can watch
+// but only those allowed to SendInput can type.
+func (h *ConsoleHandler) Enable(logs pb.LogProcessorClient, authorize AuthorizeFunc, record RecordFunc) {
+	h.logs = logs
+	h.authorize = authorize
+	h.record = record
+}
+
+func (h *ConsoleHandler) AttachSession(stream pb.ManManAPI_AttachSessionServer) error {
+	if h.logs == nil {
+		return status.Error(codes.Unavailable, "the console is not configured on this API (LOG_PROCESSOR_ADDRESS)")
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	sessionID := first.SessionId
+	if sessionID <= 0 {
+		return status.Error(codes.InvalidArgument, "the first message must name a session_id")
+	}
+	if _, err := h.sessionHandler.sessionRepo.Get(stream.Context(), sessionID); err != nil {
+		return status.Errorf(codes.NotFound, "session not found: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	output, err := h.logs.StreamSessionLogs(forwardToken(ctx), &pb.StreamSessionLogsRequest{
+		SessionId:           sessionID,
+		AfterSequenceNumber: first.AfterSequenceNumber,
+	})
+	if err != nil {
+		return status.Errorf(codes.Unavailable, "failed to stream session output: %v", err)
+	}
+
+	client := &consoleClient{
+		username: consoleUsername(ctx),
+		events:   make(chan *pb.AttachSessionEvent, consoleBuffer),
+	}
+	h.attach(sessionID, client)
+	defer h.detach(sessionID, client)
+	log.Printf("console: %s attached to session %d", client.username, sessionID)
+
+	done := make(chan error, 2)
+	go func() {
+		for {
+			msg, err := output.Recv()
+			if err != nil {
+				done <- err
+				return
+			}
+			select {
+			case client.events <- &pb.AttachSessionEvent{Output: msg}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		if len(first.Input) > 0 {
+			h.input(ctx, sessionID, client, first.Input)
+		}
+		for {
+			req, err := stream.Recv()
+			if err != nil {
+				done <- err
+				return
+			}
+			if len(req.Input) > 0 {
+				h.input(ctx, sessionID, client, req.Input)
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-done:
+			// The client hanging up or the session's output ending both close the console
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		case ev := <-client.events:
+			if err := stream.Send(ev); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// input delivers a line from client and echoes it. Refused or failed input is echoed
+// to the sender alone.
+func (h *ConsoleHandler) input(ctx context.Context, sessionID int64, client *consoleClient, input []byte) {
+	start := time.Now()
+	line := strings.TrimRight(string(input), "\r\n")
+	req := &pb.SendInputRequest{SessionId: sessionID, Input: []byte(line + "\n")}
+
+	err := h.authorize(ctx, pb.ManManAPI_SendInput_FullMethodName, req)
+	if err == nil {
+		_, err = h.sessionHandler.SendInput(ctx, req)
+	}
+	if h.record != nil {
+		h.record(ctx, "AttachSession", map[string]interface{}{"session_id": sessionID, "input": line}, err, start)
+	}
+
+	echo := &pb.ConsoleInput{
+		Username:  client.username,
+		Input:     line,
+		Timestamp: start.UnixMilli(),
+	}
+	if claims, ok := grpcauth.ClaimsFromContext(ctx); ok {
+		echo.Subject = claims.Subject
+	}
+	if err != nil {
+		echo.Error = status.Convert(err).Message()
+		deliver(client, &pb.AttachSessionEvent{Input: echo})
+		return
+	}
+	h.broadcast(sessionID, &pb.AttachSessionEvent{Input: echo})
+	h.publish(ctx, &consoleEvent{
+		SessionID: sessionID,
+		Kind:      consoleEventInput,
+		Username:  echo.Username,
+		Subject:   echo.Subject,
+		Input:     echo.Input,
+		Timestamp: echo.Timestamp,
+	})
+}
+
+func (h *ConsoleHandler) attach(sessionID int64, client *consoleClient) {
+	h.mu.Lock()
+	first := h.consoles[sessionID] == nil
+	if first {
+		h.consoles[sessionID] = make(map[*consoleClient]bool)
+	}
+	h.consoles[sessionID][client] = true
+	h.broadcastAttendeesLocked(sessionID)
+	usernames := h.localUsernamesLocked(sessionID)
+	h.mu.Unlock()
+
+	// The first client here needs to hear who is attached elsewhere
+	kind := consoleEventAttendees
+	if first {
+		kind = consoleEventSync
+	}
+	h.publish(context.Background(), &consoleEvent{SessionID: sessionID, Kind: kind, Attendees: usernames})
+}
+
+func (h *ConsoleHandler) detach(sessionID int64, client *consoleClient) {
+	h.mu.Lock()
+	delete(h.consoles[sessionID], client)
+	if len(h.consoles[sessionID]) == 0 {
+		delete(h.consoles, sessionID)
+		delete(h.remote, sessionID)
+	} else {
+		h.broadcastAttendeesLocked(sessionID)
+	}
+	usernames := h.localUsernamesLocked(sessionID)
+	h.mu.Unlock()
+
+	h.publish(context.Background(), &consoleEvent{SessionID: sessionID, Kind: consoleEventAttendees, Attendees: usernames})
+}
+
+// receive handles an event from another instance. Sessions nobody here is attached to
+// are ignored.
+func (h *ConsoleHandler) receive(ev *consoleEvent) {
+	if ev.Instance == h.instance {
+		return
+	}
+	h.mu.Lock()
+	if h.consoles[ev.SessionID] == nil {
+		h.mu.Unlock()
+		return
+	}
+	if ev.Kind == consoleEventInput {
+		for c := range h.consoles[ev.SessionID] {
+			deliver(c, &pb.AttachSessionEvent{Input: &pb.ConsoleInput{
+				Username:  ev.Username,
+				Subject:   ev.Subject,
+				Input:     ev.Input,
+				Timestamp: ev.Timestamp,
+			}})
+		}
+		h.mu.Unlock()
+		return
+	}
+
+	if len(ev.Attendees) == 0 {
+		delete(h.remote[ev.SessionID], ev.Instance)
+	} else {
+		if h.remote[ev.SessionID] == nil {
+			h.remote[ev.SessionID] = make(map[string]*remoteAttendees)
+		}
+		h.remote[ev.SessionID][ev.Instance] = &remoteAttendees{usernames: ev.Attendees, seen: time.Now()}
+	}
+	h.broadcastAttendeesLocked(ev.SessionID)
+	usernames := h.localUsernamesLocked(ev.SessionID)
+	h.mu.Unlock()
+
+	if ev.Kind == consoleEventSync {
+		h.publish(context.Background(), &consoleEvent{SessionID: ev.SessionID, Kind: consoleEventAttendees, Attendees: usernames})
+	}
+}
+
+// refreshPresence re-announces this instance's attendees and drops those of instances
+// that have stopped announcing theirs
+func (h *ConsoleHandler) refreshPresence(ctx context.Context) {
+	h.mu.Lock()
+	announce := make(map[int64][]string, len(h.consoles))
+	for sessionID := range h.consoles {
+		expired := false
+		for instance, r := range h.remote[sessionID] {
+			if time.Since(r.seen) > consolePresenceTTL {
+				delete(h.remote[sessionID], instance)
+				expired = true
+			}
+		}
+		if expired {
+			h.broadcastAttendeesLocked(sessionID)
+		}
+		announce[sessionID] = h.localUsernamesLocked(sessionID)
+	}
+	h.mu.Unlock()
+
+	for sessionID, usernames := range announce {
+		h.publish(ctx, &consoleEvent{SessionID: sessionID, Kind: consoleEventAttendees, Attendees: usernames})
+	}
+}
+
+// publish sends ev to the other instances, if there are any
+func (h *ConsoleHandler) publish(ctx context.Context, ev *consoleEvent) {
+	if h.peers == nil {
+		return
+	}
+	ev.Instance = h.instance
+	if err := h.peers.Publish(ctx, ev); err != nil {
+		log.Printf("console: failed to share %s event for session %d: %v", ev.Kind, ev.SessionID, err)
+	}
+}
+
+func (h *ConsoleHandler) broadcast(sessionID int64, ev *pb.AttachSessionEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.consoles[sessionID] {
+		deliver(c, ev)
+	}
+}
+
+// broadcastAttendeesLocked tells the session's clients here who is attached through
+// every instance
+func (h *ConsoleHandler) broadcastAttendeesLocked(sessionID int64) {
+	usernames := h.localUsernamesLocked(sessionID)
+	for _, r := range h.remote[sessionID] {
+		usernames = append(usernames, r.usernames...)
+	}
+	sort.Strings(usernames)
+	ev := &pb.AttachSessionEvent{Attendees: &pb.ConsoleAttendees{Usernames: usernames}}
+	for c := range h.consoles[sessionID] {
+		deliver(c, ev)
+	}
+}
+
+func (h *ConsoleHandler) localUsernamesLocked(sessionID int64) []string {
+	clients := h.consoles[sessionID]
+	usernames := make([]string, 0, len(clients))
+	for c := range clients {
+		usernames = append(usernames, c.username)
+	}
+	return usernames
+}
+
+// deliver queues ev for c without blocking; a client that far behind misses it
+func deliver(c *consoleClient, ev *pb.AttachSessionEvent) {
+	select {
+	case c.events <- ev:
+	default:
+		log.Printf("console: dropped event for slow client %s", c.username)
+	}
+}
+
+func consoleUsername(ctx context.Context) string {
+	claims, ok := grpcauth.ClaimsFromContext(ctx)
+	if !ok || claims == nil {
+		return "anonymous"
+	}
+	if claims.Username != "" {
+		return claims.Username
+	}
+	return claims.Subject
+}
+
+// forwardToken passes the caller's bearer token on to calls made on its behalf
+func forwardToken(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(v, "Bearer "); ok {
+			return grpcauth.WithUserToken(ctx, token)
+		}
+	}
+	return ctx
+}
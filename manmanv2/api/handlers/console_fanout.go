@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/whale-net/everything/libs/go/rmq"
+)
+
+const (
+	consoleEventInput     = "input"     // a line someone sent
+	consoleEventAttendees = "attendees" // who is attached through the sending instance
+	consoleEventSync      = "sync"      // attendees, and a request for everyone else's
+
+	// consoleEventExpiry drops events nobody picked up; a late echo is of no use
+	consoleEventExpiry = 30 * time.Second
+)
+
+// consoleEvent is what API instances tell each other about a session's console
+type consoleEvent struct {
+	Instance  string   `json:"instance"`
+	SessionID int64    `json:"session_id"`
+	Kind      string   `json:"kind"`
+	Username  string   `json:"username,omitempty"`
+	Subject   string   `json:"subject,omitempty"`
+	Input     string   `json:"input,omitempty"`
+	Timestamp int64    `json:"timestamp,omitempty"`
+	Attendees []string `json:"attendees,omitempty"`
+}
+
+// consolePeers carries console events to the other API instances
+type consolePeers interface {
+	Publish(ctx context.Context, ev *consoleEvent) error
+}
+
+// ConsoleFanout shares console events between API instances over the manman exchange.
+// Each instance consumes every console event on its own auto-delete queue.
+type ConsoleFanout struct {
+	publisher *rmq.Publisher
+	consumer  *rmq.Consumer
+}
+
+// NewConsoleFanout connects h to the consoles of the other API instances
+func NewConsoleFanout(conn *rmq.Connection, h *ConsoleHandler) (*ConsoleFanout, error) {
+	publisher, err := rmq.NewPublisher(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publisher: %w", err)
+	}
+	consumer, err := rmq.NewConsumerWithOpts(conn, fmt.Sprintf("api-console-%s", uuid.New().String()), false, true, 0, 0)
+	if err != nil {
+		publisher.Close()
+		return nil, fmt.Errorf("failed to create console consumer: %w", err)
+	}
+	if err := consumer.BindExchange("manman", []string{"console.session.#"}); err != nil {
+		consumer.Close()
+		publisher.Close()
+		return nil, fmt.Errorf("failed to bind console consumer: %w", err)
+	}
+
+	consumer.RegisterHandler("#", func(ctx context.Context, msg rmq.Message) error {
+		var ev consoleEvent
+		if err := json.Unmarshal(msg.Body, &ev); err != nil {
+			return &rmq.PermanentError{Err: fmt.Errorf("failed to unmarshal console event: %w", err)}
+		}
+		h.receive(&ev)
+		return nil
+	})
+	return &ConsoleFanout{publisher: publisher, consumer: consumer}, nil
+}
+
+// Start starts consuming the other instances' events
+func (f *ConsoleFanout) Start(ctx context.Context) error {
+	return f.consumer.Start(ctx)
+}
+
+// Publish sends ev to every API instance, this one included
+func (f *ConsoleFanout) Publish(ctx context.Context, ev *consoleEvent) error {
+	routingKey := fmt.Sprintf("console.session.%d", ev.SessionID)
+	return f.publisher.PublishWithExpiry(ctx, "manman", routingKey, ev, consoleEventExpiry)
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestConsole(authorize AuthorizeFunc) (*ConsoleHandler, *[]string) {
+	sessionRepo := &MockSessionRepo{sessions: []*manman.Session{{SessionID: 1, SGCID: 10, Status: manman.SessionStatusStopped}}}
+	h := NewConsoleHandler(&SessionHandler{sessionRepo: sessionRepo, sgcRepo: &MockSGCRepo{}})
+	var recorded []string
+	h.Enable(nil, authorize, func(ctx context.Context, method string, req interface{}, callErr error, start time.Time) {
+		recorded = append(recorded, method+" "+status.Code(callErr).String())
+	})
+	return h, &recorded
+}
+
+// drain returns the events queued for c
+func drain(c *consoleClient) []*pb.AttachSessionEvent {
+	var events []*pb.AttachSessionEvent
+	for {
+		select {
+		case ev := <-c.events:
+			events = append(events, ev)
+		default:
+			return events
+		}
+	}
+}
+
+func TestConsoleAttendees(t *testing.T) {
+	h, _ := newTestConsole(nil)
+	alice := &consoleClient{username: "alice", events: make(chan *pb.AttachSessionEvent, consoleBuffer)}
+	bob := &consoleClient{username: "bob", events: make(chan *pb.AttachSessionEvent, consoleBuffer)}
+
+	h.attach(1, alice)
+	h.attach(1, bob)
+	events := drain(alice)
+	if len(events) != 2 {
+		t.Fatalf("alice got %d events, want 2", len(events))
+	}
+	if got := events[1].Attendees.GetUsernames(); len(got) != 2 || got[0] != "alice" || got[1] != "bob" {
+		t.Errorf("attendees = %v, want [alice bob]", got)
+	}
+
+	h.detach(1, bob)
+	events = drain(alice)
+	if len(events) != 1 || len(events[0].Attendees.GetUsernames()) != 1 {
+		t.Fatalf("after bob left alice got %v", events)
+	}
+
+	h.detach(1, alice)
+	if _, ok := h.consoles[1]; ok {
+		t.Error("console of a session with nobody attached was kept")
+	}
+}
+
+// linkedPeers delivers events straight to the other handlers, as the fanout does
+type linkedPeers struct{ handlers []*ConsoleHandler }
+
+func (p *linkedPeers) Publish(ctx context.Context, ev *consoleEvent) error {
+	for _, h := range p.handlers {
+		h.receive(ev)
+	}
+	return nil
+}
+
+func TestConsoleSharedBetweenInstances(t *testing.T) {
+	a, _ := newTestConsole(nil)
+	b, _ := newTestConsole(nil)
+	peers := &linkedPeers{handlers: []*ConsoleHandler{a, b}}
+	a.peers, b.peers = peers, peers
+
+	alice := &consoleClient{username: "alice", events: make(chan *pb.AttachSessionEvent, consoleBuffer)}
+	bob := &consoleClient{username: "bob", events: make(chan *pb.AttachSessionEvent, consoleBuffer)}
+	a.attach(1, alice)
+	b.attach(1, bob)
+
+	for name, c := range map[string]*consoleClient{"alice": alice, "bob": bob} {
+		events := drain(c)
+		last := events[len(events)-1].Attendees.GetUsernames()
+		if len(last) != 2 || last[0] != "alice" || last[1] != "bob" {
+			t.Errorf("%s sees attendees %v, want [alice bob]", name, last)
+		}
+	}
+
+	a.publish(context.Background(), &consoleEvent{SessionID: 1, Kind: consoleEventInput, Username: "alice", Input: "status"})
+	events := drain(bob)
+	if len(events) != 1 || events[0].Input.GetInput() != "status" || events[0].Input.GetUsername() != "alice" {
+		t.Fatalf("bob got %v, want alice's input echoed", events)
+	}
+
+	b.detach(1, bob)
+	events = drain(alice)
+	if len(events) != 1 || len(events[0].Attendees.GetUsernames()) != 1 {
+		t.Fatalf("after bob left alice got %v", events)
+	}
+
+	// An instance that stops announcing its attendees is forgotten
+	b.attach(1, bob)
+	drain(alice)
+	a.mu.Lock()
+	a.remote[1][b.instance].seen = time.Now().Add(-2 * consolePresenceTTL)
+	a.mu.Unlock()
+	a.peers = nil
+	a.refreshPresence(context.Background())
+	events = drain(alice)
+	if len(events) != 1 || len(events[0].Attendees.GetUsernames()) != 1 {
+		t.Fatalf("after bob's instance went quiet alice got %v", events)
+	}
+}
+
+func TestConsoleInputRefused(t *testing.T) {
+	tests := []struct {
+		name      string
+		authorize AuthorizeFunc
+		wantCode  codes.Code
+	}{
+		{
+			name: "viewer can't type",
+			authorize: func(ctx context.Context, method string, req interface{}) error {
+				return status.Error(codes.PermissionDenied, `requires role "manman-operator"`)
+			},
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:      "session not running",
+			authorize: func(ctx context.Context, method string, req interface{}) error { return nil },
+			wantCode:  codes.FailedPrecondition,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, recorded := newTestConsole(tt.authorize)
+			alice := &consoleClient{username: "alice", events: make(chan *pb.AttachSessionEvent, consoleBuffer)}
+			bob := &consoleClient{username: "bob", events: make(chan *pb.AttachSessionEvent, consoleBuffer)}
+			h.attach(1, alice)
+			h.attach(1, bob)
+			drain(alice)
+			drain(bob)
+
+			ctx := grpcauth.ContextWithClaims(context.Background(), &grpcauth.Claims{Subject: "sub-alice", Username: "alice"})
+			h.input(ctx, 1, alice, []byte("say hi\n"))
+
+			events := drain(alice)
+			if len(events) != 1 || events[0].Input == nil {
+				t.Fatalf("alice got %v, want her input echoed", events)
+			}
+			echo := events[0].Input
+			if echo.Input != "say hi" || echo.Username != "alice" || echo.Subject != "sub-alice" || echo.Error == "" {
+				t.Errorf("echo = %+v, want alice's line with an error", echo)
+			}
+			if got := drain(bob); len(got) != 0 {
+				t.Errorf("bob got %v, refused input should only go back to its sender", got)
+			}
+			if want := "AttachSession " + tt.wantCode.String(); len(*recorded) != 1 || (*recorded)[0] != want {
+				t.Errorf("recorded %v, want [%s]", *recorded, want)
+			}
+		})
+	}
+}
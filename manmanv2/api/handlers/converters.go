@@ -184,6 +184,354 @@ func jsonbToStringArray(j manman.JSONB) []string {
 	return result
 }
 
+// ============================================================================
+// Resource limit conversions
+// ============================================================================
+
+// validateResourceLimits rejects negative limits and malformed ulimits. nil is valid.
+func validateResourceLimits(l *pb.ResourceLimits) error {
+	if l == nil {
+		return nil
+	}
+	if l.GetCpuMillicores() < 0 || l.GetMemoryMb() < 0 || l.GetPidsLimit() < 0 {
+		return fmt.Errorf("resource limits must not be negative")
+	}
+	seen := make(map[string]bool, len(l.Ulimits))
+	for _, u := range l.Ulimits {
+		if u.Name == "" {
+			return fmt.Errorf("ulimit name is required")
+		}
+		if seen[u.Name] {
+			return fmt.Errorf("duplicate ulimit %q", u.Name)
+		}
+		seen[u.Name] = true
+		if u.Soft > u.Hard && u.Hard >= 0 {
+			return fmt.Errorf("ulimit %q soft limit %d exceeds hard limit %d", u.Name, u.Soft, u.Hard)
+		}
+	}
+	return nil
+}
+
+// resourceLimitsToColumns splits a game config's proto limits into their nullable columns.
+// Unset and zero values are NULL, meaning unlimited.
+func resourceLimitsToColumns(l *pb.ResourceLimits) (cpu, mem, pids *int32, ulimits manman.JSONB) {
+	if l == nil {
+		return nil, nil, nil, nil
+	}
+	return positiveInt32Ptr(l.GetCpuMillicores()), positiveInt32Ptr(l.GetMemoryMb()), positiveInt32Ptr(l.GetPidsLimit()),
+		manman.UlimitsToJSONB(resourceLimitsFromProto(l).Ulimits)
+}
+
+// resourceLimitOverridesToColumns splits an SGC's proto limits into their nullable columns.
+// Unset values are NULL and inherit the game config's; a 0 is kept and lifts its limit.
+func resourceLimitOverridesToColumns(l *pb.ResourceLimits) (cpu, mem, pids *int32, ulimits manman.JSONB) {
+	if l == nil {
+		return nil, nil, nil, nil
+	}
+	return copyInt32Ptr(l.CpuMillicores), copyInt32Ptr(l.MemoryMb), copyInt32Ptr(l.PidsLimit),
+		manman.UlimitsToJSONB(resourceLimitsFromProto(l).Ulimits)
+}
+
+// resourceLimitsFromColumns is the inverse of resourceLimitsToColumns and
+// resourceLimitOverridesToColumns. Returns nil when nothing is set.
+func resourceLimitsFromColumns(cpu, mem, pids *int32, ulimits manman.JSONB) *pb.ResourceLimits {
+	if cpu == nil && mem == nil && pids == nil && len(ulimits) == 0 {
+		return nil
+	}
+	pbLimits := &pb.ResourceLimits{
+		CpuMillicores: copyInt32Ptr(cpu),
+		MemoryMb:      copyInt32Ptr(mem),
+		PidsLimit:     copyInt32Ptr(pids),
+	}
+	for _, u := range manman.ResolveResourceLimits(&manman.GameConfig{Ulimits: ulimits}, nil).Ulimits {
+		pbLimits.Ulimits = append(pbLimits.Ulimits, &pb.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	return pbLimits
+}
+
+func resourceLimitsFromProto(l *pb.ResourceLimits) manman.ResourceLimits {
+	var limits manman.ResourceLimits
+	if l == nil {
+		return limits
+	}
+	limits.CPUMillicores = l.GetCpuMillicores()
+	limits.MemoryMB = l.GetMemoryMb()
+	limits.PidsLimit = l.GetPidsLimit()
+	for _, u := range l.Ulimits {
+		limits.Ulimits = append(limits.Ulimits, manman.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	return limits
+}
+
+// ============================================================================
+// Readiness probe conversions
+// ============================================================================
+
+// readinessProbeFromProto converts and validates a proto probe. nil or an empty type clears the probe.
+func readinessProbeFromProto(p *pb.ReadinessProbe) (*manman.ReadinessProbe, error) {
+	if p == nil || p.Type == "" {
+		return nil, nil
+	}
+	probe := &manman.ReadinessProbe{
+		Type:            p.Type,
+		LogPattern:      p.LogPattern,
+		Port:            p.Port,
+		Command:         p.Command,
+		TimeoutSeconds:  p.TimeoutSeconds,
+		IntervalSeconds: p.IntervalSeconds,
+	}
+	if err := probe.Validate(); err != nil {
+		return nil, err
+	}
+	return probe, nil
+}
+
+func readinessProbeToProto(j manman.JSONB) *pb.ReadinessProbe {
+	probe := manman.ReadinessProbeFromJSONB(j)
+	if probe == nil {
+		return nil
+	}
+	return &pb.ReadinessProbe{
+		Type:            probe.Type,
+		LogPattern:      probe.LogPattern,
+		Port:            probe.Port,
+		Command:         probe.Command,
+		TimeoutSeconds:  probe.TimeoutSeconds,
+		IntervalSeconds: probe.IntervalSeconds,
+	}
+}
+
+// ============================================================================
+// Input transport and status query conversions
+// ============================================================================
+
+// inputTransportFromProto converts and validates a proto transport. nil or an empty type clears it (stdin).
+func inputTransportFromProto(t *pb.InputTransport) (*manman.InputTransport, error) {
+	if t == nil || t.Type == "" {
+		return nil, nil
+	}
+	transport := &manman.InputTransport{
+		Type:         t.Type,
+		Port:         t.Port,
+		PasswordEnv:  t.PasswordEnv,
+		PasswordArg:  t.PasswordArg,
+		PasswordFile: t.PasswordFile,
+		PasswordKey:  t.PasswordKey,
+	}
+	if err := transport.Validate(); err != nil {
+		return nil, err
+	}
+	return transport, nil
+}
+
+func inputTransportToProto(j manman.JSONB) *pb.InputTransport {
+	transport := manman.InputTransportFromJSONB(j)
+	if transport == nil {
+		return nil
+	}
+	return &pb.InputTransport{
+		Type:         transport.Type,
+		Port:         transport.Port,
+		PasswordEnv:  transport.PasswordEnv,
+		PasswordArg:  transport.PasswordArg,
+		PasswordFile: transport.PasswordFile,
+		PasswordKey:  transport.PasswordKey,
+	}
+}
+
+// statusQueryFromProto converts and validates a proto query. nil or an empty type clears it.
+func statusQueryFromProto(q *pb.StatusQuery) (*manman.StatusQuery, error) {
+	if q == nil || q.Type == "" {
+		return nil, nil
+	}
+	query := &manman.StatusQuery{
+		Type:            q.Type,
+		Port:            q.Port,
+		IntervalSeconds: q.IntervalSeconds,
+	}
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+func statusQueryToProto(j manman.JSONB) *pb.StatusQuery {
+	query := manman.StatusQueryFromJSONB(j)
+	if query == nil {
+		return nil
+	}
+	return &pb.StatusQuery{
+		Type:            query.Type,
+		Port:            query.Port,
+		IntervalSeconds: query.IntervalSeconds,
+	}
+}
+
+// ============================================================================
+// Restart policy conversions
+// ============================================================================
+
+// restartPolicyFromProto converts and validates a proto policy. nil or an empty mode clears the policy.
+func restartPolicyFromProto(p *pb.RestartPolicy) (*manman.RestartPolicy, error) {
+	if p == nil || p.Mode == "" {
+		return nil, nil
+	}
+	policy := &manman.RestartPolicy{
+		Mode:               p.Mode,
+		MaxAttempts:        p.MaxAttempts,
+		BackoffSeconds:     p.BackoffSeconds,
+		MaxBackoffSeconds:  p.MaxBackoffSeconds,
+		CrashWindowSeconds: p.CrashWindowSeconds,
+		MaxCrashesInWindow: p.MaxCrashesInWindow,
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func restartPolicyToProto(j manman.JSONB) *pb.RestartPolicy {
+	policy := manman.RestartPolicyFromJSONB(j)
+	if policy == nil {
+		return nil
+	}
+	return &pb.RestartPolicy{
+		Mode:               policy.Mode,
+		MaxAttempts:        policy.MaxAttempts,
+		BackoffSeconds:     policy.BackoffSeconds,
+		MaxBackoffSeconds:  policy.MaxBackoffSeconds,
+		CrashWindowSeconds: policy.CrashWindowSeconds,
+		MaxCrashesInWindow: policy.MaxCrashesInWindow,
+	}
+}
+
+// ============================================================================
+// Idle shutdown conversions
+// ============================================================================
+
+// idleShutdownFromProto converts and validates a proto rule. nil or a zero idle_minutes clears the rule.
+func idleShutdownFromProto(r *pb.IdleShutdown) (*manman.IdleShutdown, error) {
+	if r == nil || r.IdleMinutes == 0 {
+		return nil, nil
+	}
+	rule := &manman.IdleShutdown{IdleMinutes: r.IdleMinutes}
+	if p := r.PlayerCount; p != nil {
+		rule.PlayerCount = manman.PlayerCountProbe{
+			Type:            p.Type,
+			JoinPattern:     p.JoinPattern,
+			LeavePattern:    p.LeavePattern,
+			CountPattern:    p.CountPattern,
+			Command:         p.Command,
+			IntervalSeconds: p.IntervalSeconds,
+		}
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+func idleShutdownToProto(j manman.JSONB) *pb.IdleShutdown {
+	rule := manman.IdleShutdownFromJSONB(j)
+	if rule == nil {
+		return nil
+	}
+	return &pb.IdleShutdown{
+		IdleMinutes: rule.IdleMinutes,
+		PlayerCount: &pb.PlayerCountProbe{
+			Type:            rule.PlayerCount.Type,
+			JoinPattern:     rule.PlayerCount.JoinPattern,
+			LeavePattern:    rule.PlayerCount.LeavePattern,
+			CountPattern:    rule.PlayerCount.CountPattern,
+			Command:         rule.PlayerCount.Command,
+			IntervalSeconds: rule.PlayerCount.IntervalSeconds,
+		},
+	}
+}
+
+// ============================================================================
+// Player event conversions
+// ============================================================================
+
+// playerEventPatternsFromProto converts and validates proto patterns. nil or an empty join
+// pattern clears them.
+func playerEventPatternsFromProto(p *pb.PlayerEventPatterns) (*manman.PlayerEventPatterns, error) {
+	if p == nil || (p.JoinPattern == "" && p.LeavePattern == "" && p.ChatPattern == "") {
+		return nil, nil
+	}
+	patterns := &manman.PlayerEventPatterns{
+		JoinPattern:  p.JoinPattern,
+		LeavePattern: p.LeavePattern,
+		ChatPattern:  p.ChatPattern,
+	}
+	if err := patterns.Validate(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+func playerEventPatternsToProto(j manman.JSONB) *pb.PlayerEventPatterns {
+	patterns := manman.PlayerEventPatternsFromJSONB(j)
+	if patterns == nil {
+		return nil
+	}
+	return &pb.PlayerEventPatterns{
+		JoinPattern:  patterns.JoinPattern,
+		LeavePattern: patterns.LeavePattern,
+		ChatPattern:  patterns.ChatPattern,
+	}
+}
+
+func playerSessionToProto(p *manman.PlayerSession) *pb.PlayerSession {
+	pbPlayer := &pb.PlayerSession{
+		PlayerSessionId:    p.PlayerSessionID,
+		SessionId:          p.SessionID,
+		ServerGameConfigId: p.SGCID,
+		PlayerName:         p.PlayerName,
+		JoinedAt:           p.JoinedAt.Unix(),
+		ChatMessages:       p.ChatMessages,
+		LastSeenAt:         p.LastSeenAt.Unix(),
+	}
+	if p.PlayerID != nil {
+		pbPlayer.PlayerId = *p.PlayerID
+	}
+	if p.LeftAt != nil {
+		pbPlayer.LeftAt = p.LeftAt.Unix()
+	}
+	return pbPlayer
+}
+
+func playerStatsToProto(s *manman.PlayerStats) *pb.PlayerStats {
+	pbStats := &pb.PlayerStats{
+		ServerGameConfigId:   s.SGCID,
+		Since:                s.Since.Unix(),
+		Until:                s.Until.Unix(),
+		UniquePlayers:        int32(s.UniquePlayers),
+		TotalPlaytimeSeconds: int64(s.TotalPlaytime.Seconds()),
+		PeakConcurrency:      int32(s.Peak),
+	}
+	if !s.PeakAt.IsZero() {
+		pbStats.PeakAt = s.PeakAt.Unix()
+	}
+	for _, p := range s.Players {
+		pbStats.Players = append(pbStats.Players, &pb.PlayerTotal{
+			PlayerName:      p.PlayerName,
+			PlayerId:        p.PlayerID,
+			Sessions:        int32(p.Sessions),
+			PlaytimeSeconds: int64(p.Playtime.Seconds()),
+			ChatMessages:    p.ChatMessages,
+			LastSeenAt:      p.LastSeenAt.Unix(),
+		})
+	}
+	for _, c := range s.Concurrency {
+		pbStats.Concurrency = append(pbStats.Concurrency, &pb.ConcurrencyPoint{
+			BucketStart: c.BucketStart.Unix(),
+			Players:     int32(c.Players),
+		})
+	}
+	return pbStats
+}
+
 // ============================================================================
 // Helper functions
 // ============================================================================
@@ -194,3 +542,18 @@ func stringPtr(s string) *string {
 	}
 	return &s
 }
+
+func positiveInt32Ptr(v int32) *int32 {
+	if v <= 0 {
+		return nil
+	}
+	return &v
+}
+
+func copyInt32Ptr(p *int32) *int32 {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestJsonbToStringArray(t *testing.T) {
@@ -60,3 +62,26 @@ func TestJsonbToStringArray(t *testing.T) {
 		})
 	}
 }
+
+func TestResourceLimitColumns(t *testing.T) {
+	limits := &pb.ResourceLimits{CpuMillicores: proto.Int32(0), MemoryMb: proto.Int32(2048)}
+
+	// On a game config 0 is unlimited, which is NULL
+	cpu, mem, pids, _ := resourceLimitsToColumns(limits)
+	if cpu != nil || mem == nil || *mem != 2048 || pids != nil {
+		t.Errorf("game config columns = %v, %v, %v", cpu, mem, pids)
+	}
+
+	// On an SGC 0 overrides the game config's limit and unset inherits it
+	cpu, mem, pids, ulimits := resourceLimitOverridesToColumns(limits)
+	if cpu == nil || *cpu != 0 || mem == nil || *mem != 2048 || pids != nil {
+		t.Errorf("SGC columns = %v, %v, %v", cpu, mem, pids)
+	}
+	if got := resourceLimitsFromColumns(cpu, mem, pids, ulimits); !proto.Equal(got, limits) {
+		t.Errorf("round trip = %v, want %v", got, limits)
+	}
+
+	if resourceLimitsFromColumns(nil, nil, nil, nil) != nil {
+		t.Error("Expected nil limits when nothing is set")
+	}
+}
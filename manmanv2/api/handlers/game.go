@@ -74,13 +74,19 @@ func (h *GameHandler) CreateGame(ctx context.Context, req *pb.CreateGameRequest)
 		return nil, status.Error(codes.InvalidArgument, "name is required")
 	}
 
+	playerEvents, err := playerEventPatternsFromProto(req.PlayerEvents)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid player_events: %v", err)
+	}
+
 	game := &manman.Game{
-		Name:       req.Name,
-		SteamAppID: stringPtr(req.SteamAppId),
-		Metadata:   metadataToJSONB(req.Metadata),
+		Name:         req.Name,
+		SteamAppID:   stringPtr(req.SteamAppId),
+		Metadata:     metadataToJSONB(req.Metadata),
+		PlayerEvents: playerEvents.ToJSONB(),
 	}
 
-	game, err := h.repo.Create(ctx, game)
+	game, err = h.repo.Create(ctx, game)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create game: %v", err)
 	}
@@ -96,6 +102,11 @@ func (h *GameHandler) UpdateGame(ctx context.Context, req *pb.UpdateGameRequest)
 		return nil, status.Errorf(codes.NotFound, "game not found: %v", err)
 	}
 
+	playerEvents, err := playerEventPatternsFromProto(req.PlayerEvents)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid player_events: %v", err)
+	}
+
 	// Apply field paths
 	if len(req.UpdatePaths) == 0 {
 		// Update all provided fields
@@ -108,6 +119,9 @@ func (h *GameHandler) UpdateGame(ctx context.Context, req *pb.UpdateGameRequest)
 		if req.Metadata != nil {
 			game.Metadata = metadataToJSONB(req.Metadata)
 		}
+		if req.PlayerEvents != nil {
+			game.PlayerEvents = playerEvents.ToJSONB()
+		}
 	} else {
 		// Update only specified fields
 		for _, path := range req.UpdatePaths {
@@ -118,6 +132,8 @@ func (h *GameHandler) UpdateGame(ctx context.Context, req *pb.UpdateGameRequest)
 				game.SteamAppID = stringPtr(req.SteamAppId)
 			case "metadata":
 				game.Metadata = metadataToJSONB(req.Metadata)
+			case "player_events":
+				game.PlayerEvents = playerEvents.ToJSONB()
 			}
 		}
 	}
@@ -141,9 +157,10 @@ func (h *GameHandler) DeleteGame(ctx context.Context, req *pb.DeleteGameRequest)
 
 func gameToProto(g *manman.Game) *pb.Game {
 	pbGame := &pb.Game{
-		GameId:   g.GameID,
-		Name:     g.Name,
-		Metadata: jsonbToMetadata(g.Metadata),
+		GameId:       g.GameID,
+		Name:         g.Name,
+		Metadata:     jsonbToMetadata(g.Metadata),
+		PlayerEvents: playerEventPatternsToProto(g.PlayerEvents),
 	}
 
 	if g.SteamAppID != nil {
@@ -82,18 +82,37 @@ func (h *GameConfigHandler) CreateGameConfig(ctx context.Context, req *pb.Create
 	if req.Image == "" {
 		return nil, status.Error(codes.InvalidArgument, "image is required")
 	}
-
-	config := &manman.GameConfig{
-		GameID:       req.GameId,
-		Name:         req.Name,
-		Image:        req.Image,
-		ArgsTemplate: stringPtr(req.ArgsTemplate),
-		EnvTemplate:  mapToJSONB(req.EnvTemplate),
-		Entrypoint:   stringArrayToJSONB(req.Entrypoint),
-		Command:      stringArrayToJSONB(req.Command),
+	if err := validateResourceLimits(req.ResourceLimits); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid resource_limits: %v", err)
+	}
+	probe, err := readinessProbeFromProto(req.ReadinessProbe)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid readiness_probe: %v", err)
+	}
+	transport, err := inputTransportFromProto(req.InputTransport)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid input_transport: %v", err)
+	}
+	query, err := statusQueryFromProto(req.StatusQuery)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status_query: %v", err)
 	}
 
-	config, err := h.repo.Create(ctx, config)
+	config := &manman.GameConfig{
+		GameID:         req.GameId,
+		Name:           req.Name,
+		Image:          req.Image,
+		ArgsTemplate:   stringPtr(req.ArgsTemplate),
+		EnvTemplate:    mapToJSONB(req.EnvTemplate),
+		Entrypoint:     stringArrayToJSONB(req.Entrypoint),
+		Command:        stringArrayToJSONB(req.Command),
+		ReadinessProbe: probe.ToJSONB(),
+		InputTransport: transport.ToJSONB(),
+		StatusQuery:    query.ToJSONB(),
+	}
+	config.CPUMillicores, config.MemoryMB, config.PidsLimit, config.Ulimits = resourceLimitsToColumns(req.ResourceLimits)
+
+	config, err = h.repo.Create(ctx, config)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create game config: %v", err)
 	}
@@ -104,6 +123,22 @@ func (h *GameConfigHandler) CreateGameConfig(ctx context.Context, req *pb.Create
 }
 
 func (h *GameConfigHandler) UpdateGameConfig(ctx context.Context, req *pb.UpdateGameConfigRequest) (*pb.UpdateGameConfigResponse, error) {
+	if err := validateResourceLimits(req.ResourceLimits); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid resource_limits: %v", err)
+	}
+	probe, err := readinessProbeFromProto(req.ReadinessProbe)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid readiness_probe: %v", err)
+	}
+	transport, err := inputTransportFromProto(req.InputTransport)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid input_transport: %v", err)
+	}
+	query, err := statusQueryFromProto(req.StatusQuery)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid status_query: %v", err)
+	}
+
 	config, err := h.repo.Get(ctx, req.ConfigId)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "game config not found: %v", err)
@@ -130,6 +165,18 @@ func (h *GameConfigHandler) UpdateGameConfig(ctx context.Context, req *pb.Update
 		if req.Command != nil {
 			config.Command = stringArrayToJSONB(req.Command)
 		}
+		if req.ResourceLimits != nil {
+			config.CPUMillicores, config.MemoryMB, config.PidsLimit, config.Ulimits = resourceLimitsToColumns(req.ResourceLimits)
+		}
+		if req.ReadinessProbe != nil {
+			config.ReadinessProbe = probe.ToJSONB()
+		}
+		if req.InputTransport != nil {
+			config.InputTransport = transport.ToJSONB()
+		}
+		if req.StatusQuery != nil {
+			config.StatusQuery = query.ToJSONB()
+		}
 	} else {
 		// Update only specified fields
 		for _, path := range req.UpdatePaths {
@@ -146,6 +193,14 @@ func (h *GameConfigHandler) UpdateGameConfig(ctx context.Context, req *pb.Update
 				config.Entrypoint = stringArrayToJSONB(req.Entrypoint)
 			case "command":
 				config.Command = stringArrayToJSONB(req.Command)
+			case "resource_limits":
+				config.CPUMillicores, config.MemoryMB, config.PidsLimit, config.Ulimits = resourceLimitsToColumns(req.ResourceLimits)
+			case "readiness_probe":
+				config.ReadinessProbe = probe.ToJSONB()
+			case "input_transport":
+				config.InputTransport = transport.ToJSONB()
+			case "status_query":
+				config.StatusQuery = query.ToJSONB()
 			}
 		}
 	}
@@ -169,13 +224,17 @@ func (h *GameConfigHandler) DeleteGameConfig(ctx context.Context, req *pb.Delete
 
 func gameConfigToProto(c *manman.GameConfig) *pb.GameConfig {
 	pbConfig := &pb.GameConfig{
-		ConfigId:    c.ConfigID,
-		GameId:      c.GameID,
-		Name:        c.Name,
-		Image:       c.Image,
-		EnvTemplate: jsonbToMap(c.EnvTemplate),
-		Entrypoint:  jsonbToStringArray(c.Entrypoint),
-		Command:     jsonbToStringArray(c.Command),
+		ConfigId:       c.ConfigID,
+		GameId:         c.GameID,
+		Name:           c.Name,
+		Image:          c.Image,
+		EnvTemplate:    jsonbToMap(c.EnvTemplate),
+		Entrypoint:     jsonbToStringArray(c.Entrypoint),
+		Command:        jsonbToStringArray(c.Command),
+		ResourceLimits: resourceLimitsFromColumns(c.CPUMillicores, c.MemoryMB, c.PidsLimit, c.Ulimits),
+		ReadinessProbe: readinessProbeToProto(c.ReadinessProbe),
+		InputTransport: inputTransportToProto(c.InputTransport),
+		StatusQuery:    statusQueryToProto(c.StatusQuery),
 	}
 
 	if c.ArgsTemplate != nil {
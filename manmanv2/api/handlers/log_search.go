@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultLogSearchPageSize = 100
+	maxLogSearchPageSize     = 1000
+	maxLogSearchContextLines = 10
+	// logSearchWindowsPerPage bounds how many archived windows one page reads from S3.
+	// A page that reaches it ends early, with a token to carry on from there.
+	logSearchWindowsPerPage = 200
+)
+
+// logSearch is a parsed SearchLogsRequest
+type logSearch struct {
+	start, end   time.Time
+	sources      map[string]bool // archived source names; empty matches all
+	terms        []string
+	re           *regexp.Regexp
+	contextLines int
+}
+
+func (s *logSearch) matches(line manman.ArchivedLogLine) bool {
+	if line.Timestamp.Before(s.start) || line.Timestamp.After(s.end) {
+		return false
+	}
+	if len(s.sources) > 0 && !s.sources[line.Source] {
+		return false
+	}
+	if s.re != nil {
+		return s.re.MatchString(line.Message)
+	}
+	words := make(map[string]bool)
+	for _, w := range manman.LogTerms(line.Message) {
+		words[w] = true
+	}
+	for _, term := range s.terms {
+		if !words[term] {
+			return false
+		}
+	}
+	return true
+}
+
+// SearchLogs finds archived log lines across sessions. The index the archiver writes for
+// each window narrows a term query to the windows holding every word; regex queries
+// scan every window the other filters leave.
+func (h *LogsHandler) SearchLogs(ctx context.Context, req *pb.SearchLogsRequest) (*pb.SearchLogsResponse, error) {
+	search, filters, err := parseLogSearch(req, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultLogSearchPageSize
+	}
+	if pageSize > maxLogSearchPageSize {
+		pageSize = maxLogSearchPageSize
+	}
+
+	var cursor *logSearchCursor
+	if req.PageToken != "" {
+		if cursor, err = decodeLogSearchCursor(req.PageToken); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page_token: %v", err)
+		}
+		filters.After = &manman.ArchivedLogWindow{MinuteTimestamp: cursor.minute, SessionID: cursor.sessionID}
+	}
+
+	windows, err := h.logRefRepo.SearchWindows(ctx, filters, logSearchWindowsPerPage)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to find log windows: %v", err)
+	}
+
+	resp := &pb.SearchLogsResponse{}
+	for _, window := range windows {
+		startLine := 0
+		if cursor != nil && cursor.minute.Equal(window.MinuteTimestamp) && cursor.sessionID == window.SessionID {
+			startLine = cursor.line
+		}
+
+		content, err := h.downloadWindow(ctx, window.FilePath)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to read log window %s: %v", window.FilePath, err)
+		}
+		resp.WindowsScanned++
+
+		matches, next := searchWindow(content, window, search, startLine, pageSize-len(resp.Matches))
+		resp.Matches = append(resp.Matches, matches...)
+		if next >= 0 {
+			resp.NextPageToken = encodeLogSearchCursor(logSearchCursor{minute: window.MinuteTimestamp, sessionID: window.SessionID, line: next})
+			return resp, nil
+		}
+	}
+
+	// More windows may follow the last one read; carry on from the session after it
+	if len(windows) == logSearchWindowsPerPage {
+		last := windows[len(windows)-1]
+		resp.NextPageToken = encodeLogSearchCursor(logSearchCursor{minute: last.MinuteTimestamp, sessionID: last.SessionID + 1})
+	}
+	return resp, nil
+}
+
+func parseLogSearch(req *pb.SearchLogsRequest, now time.Time) (*logSearch, *repository.LogSearchFilters, error) {
+	if strings.TrimSpace(req.Query) == "" {
+		return nil, nil, status.Error(codes.InvalidArgument, "query is required")
+	}
+	if req.StartTimestamp <= 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "start_timestamp is required")
+	}
+	search := &logSearch{
+		start:        time.Unix(req.StartTimestamp, 0).UTC(),
+		end:          now.UTC(),
+		sources:      make(map[string]bool),
+		contextLines: int(req.ContextLines),
+	}
+	if req.EndTimestamp != 0 {
+		search.end = time.Unix(req.EndTimestamp, 0).UTC()
+	}
+	if search.end.Before(search.start) {
+		return nil, nil, status.Error(codes.InvalidArgument, "end_timestamp is before start_timestamp")
+	}
+	if search.contextLines < 0 {
+		search.contextLines = 0
+	}
+	if search.contextLines > maxLogSearchContextLines {
+		search.contextLines = maxLogSearchContextLines
+	}
+
+	filters := &repository.LogSearchFilters{
+		Start:      search.start,
+		End:        search.end,
+		SessionIDs: req.SessionIds,
+		SGCIDs:     req.SgcIds,
+		ServerIDs:  req.ServerIds,
+	}
+	for _, source := range req.Sources {
+		name := archivedSourceName(source)
+		if name == "" {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "unknown source %s", source)
+		}
+		if !search.sources[name] {
+			search.sources[name] = true
+			filters.Sources = append(filters.Sources, name)
+		}
+	}
+
+	if req.Regex {
+		re, err := regexp.Compile(req.Query)
+		if err != nil {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid regex: %v", err)
+		}
+		search.re = re
+		return search, filters, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, term := range manman.LogTerms(req.Query) {
+		if !seen[term] {
+			seen[term] = true
+			search.terms = append(search.terms, term)
+		}
+	}
+	if len(search.terms) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "query has no words to search for; use regex for punctuation")
+	}
+	filters.Terms = search.terms
+	return search, filters, nil
+}
+
+// searchWindow returns up to limit matches in an archived window from line startLine on.
+// When it stops at limit, next is the line to resume from; otherwise it is -1.
+func searchWindow(content []byte, window *manman.ArchivedLogWindow, search *logSearch, startLine, limit int) (matches []*pb.LogSearchMatch, next int) {
+	var lines []manman.ArchivedLogLine
+	for _, raw := range strings.Split(string(content), "\n") {
+		if line, ok := manman.ParseArchivedLogLine(raw); ok {
+			lines = append(lines, line)
+		}
+	}
+
+	for i := startLine; i < len(lines); i++ {
+		if !search.matches(lines[i]) {
+			continue
+		}
+		match := &pb.LogSearchMatch{
+			SessionId: window.SessionID,
+			SgcId:     window.SGCID,
+			ServerId:  window.ServerID,
+			Line:      logSearchLine(lines[i]),
+		}
+		for j := max(0, i-search.contextLines); j < i; j++ {
+			match.Before = append(match.Before, logSearchLine(lines[j]))
+		}
+		for j := i + 1; j < len(lines) && j <= i+search.contextLines; j++ {
+			match.After = append(match.After, logSearchLine(lines[j]))
+		}
+		matches = append(matches, match)
+		if len(matches) == limit {
+			return matches, i + 1
+		}
+	}
+	return matches, -1
+}
+
+func (h *LogsHandler) downloadWindow(ctx context.Context, filePath string) ([]byte, error) {
+	s3Key, err := parseS3Key(filePath)
+	if err != nil {
+		return nil, err
+	}
+	compressed, err := h.s3Client.Download(ctx, s3Key)
+	if err != nil {
+		return nil, err
+	}
+	return decompressLogs(compressed)
+}
+
+func logSearchLine(line manman.ArchivedLogLine) *pb.LogSearchLine {
+	return &pb.LogSearchLine{
+		Timestamp: line.Timestamp.Unix(),
+		Source:    archivedLogSource(line.Source),
+		Message:   line.Message,
+	}
+}
+
+// The archiver names the wrapper's own output "host"
+func archivedSourceName(source pb.LogSource) string {
+	switch source {
+	case pb.LogSource_LOG_SOURCE_STDOUT:
+		return "stdout"
+	case pb.LogSource_LOG_SOURCE_STDERR:
+		return "stderr"
+	case pb.LogSource_LOG_SOURCE_WRAPPER:
+		return "host"
+	default:
+		return ""
+	}
+}
+
+func archivedLogSource(name string) pb.LogSource {
+	switch name {
+	case "stdout":
+		return pb.LogSource_LOG_SOURCE_STDOUT
+	case "stderr":
+		return pb.LogSource_LOG_SOURCE_STDERR
+	case "host":
+		return pb.LogSource_LOG_SOURCE_WRAPPER
+	default:
+		return pb.LogSource_LOG_SOURCE_UNSPECIFIED
+	}
+}
+
+// logSearchCursor is where a search page stopped: a line of the window of a session
+// starting at minute
+type logSearchCursor struct {
+	minute    time.Time
+	sessionID int64
+	line      int
+}
+
+func encodeLogSearchCursor(c logSearchCursor) string {
+	return base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d:%d", c.minute.Unix(), c.sessionID, c.line)))
+}
+
+func decodeLogSearchCursor(token string) (*logSearchCursor, error) {
+	data, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	var minute, sessionID int64
+	var line int
+	if _, err := fmt.Sscanf(string(data), "%d:%d:%d", &minute, &sessionID, &line); err != nil {
+		return nil, err
+	}
+	return &logSearchCursor{minute: time.Unix(minute, 0).UTC(), sessionID: sessionID, line: line}, nil
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var searchMinute = time.Date(2026, 2, 10, 15, 30, 0, 0, time.UTC)
+
+// searchContent renders messages as an archived window, one second apart, appended to
+// once after the third line
+func searchContent(lines ...[2]string) []byte {
+	var b strings.Builder
+	for i, l := range lines {
+		if i == 3 {
+			b.WriteString("\n--- APPENDED AT 2026-02-10T15:33:00Z ---\n")
+		}
+		b.WriteString(manman.FormatArchivedLogLine(searchMinute.Add(time.Duration(i)*time.Second), l[0], l[1]))
+	}
+	return []byte(b.String())
+}
+
+func TestParseLogSearch(t *testing.T) {
+	now := searchMinute.Add(time.Hour)
+	tests := []struct {
+		name      string
+		req       *pb.SearchLogsRequest
+		wantCode  codes.Code
+		wantTerms []string
+	}{
+		{
+			name:      "terms are lowercased and deduplicated",
+			req:       &pb.SearchLogsRequest{Query: "Alice joined alice", StartTimestamp: searchMinute.Unix()},
+			wantTerms: []string{"alice", "joined"},
+		},
+		{
+			name:     "no start",
+			req:      &pb.SearchLogsRequest{Query: "alice"},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "end before start",
+			req:      &pb.SearchLogsRequest{Query: "alice", StartTimestamp: searchMinute.Unix(), EndTimestamp: searchMinute.Unix() - 1},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "punctuation only",
+			req:      &pb.SearchLogsRequest{Query: "[!]", StartTimestamp: searchMinute.Unix()},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name:     "bad regex",
+			req:      &pb.SearchLogsRequest{Query: "(", Regex: true, StartTimestamp: searchMinute.Unix()},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "regex leaves the index unused",
+			req:  &pb.SearchLogsRequest{Query: `\[!\]`, Regex: true, StartTimestamp: searchMinute.Unix()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			search, filters, err := parseLogSearch(tt.req, now)
+			if code := status.Code(err); code != tt.wantCode {
+				t.Fatalf("code = %v, want %v (%v)", code, tt.wantCode, err)
+			}
+			if err != nil {
+				return
+			}
+			if strings.Join(filters.Terms, ",") != strings.Join(tt.wantTerms, ",") {
+				t.Errorf("index terms = %v, want %v", filters.Terms, tt.wantTerms)
+			}
+			if !search.end.Equal(now) {
+				t.Errorf("end = %v, want now", search.end)
+			}
+		})
+	}
+}
+
+func TestSearchWindow(t *testing.T) {
+	content := searchContent(
+		[2]string{"stdout", "Server started"},
+		[2]string{"stdout", "Player Alice joined"},
+		[2]string{"stderr", "ERROR alice timed out"},
+		[2]string{"host", "Player Alice joined"},
+		[2]string{"stdout", "Server stopping"},
+	)
+	window := &manman.ArchivedLogWindow{SessionID: 7, SGCID: 3, ServerID: 1, MinuteTimestamp: searchMinute}
+
+	search, _, err := parseLogSearch(&pb.SearchLogsRequest{
+		Query:          "alice JOINED",
+		StartTimestamp: searchMinute.Unix(),
+		EndTimestamp:   searchMinute.Add(time.Minute).Unix(),
+		ContextLines:   1,
+	}, searchMinute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	matches, next := searchWindow(content, window, search, 0, 10)
+	if next != -1 || len(matches) != 2 {
+		t.Fatalf("got %d matches and next %d, want 2 and -1", len(matches), next)
+	}
+	m := matches[1]
+	if m.SessionId != 7 || m.SgcId != 3 || m.ServerId != 1 || m.Line.Source != pb.LogSource_LOG_SOURCE_WRAPPER {
+		t.Errorf("match = %+v, want session 7's wrapper line", m)
+	}
+	// Context skips the append separator
+	if len(m.Before) != 1 || m.Before[0].Message != "ERROR alice timed out" || len(m.After) != 1 || m.After[0].Message != "Server stopping" {
+		t.Errorf("context = %v / %v, want the lines either side", m.Before, m.After)
+	}
+
+	// A full page says where to carry on, and carrying on finds the rest
+	matches, next = searchWindow(content, window, search, 0, 1)
+	if len(matches) != 1 || next != 2 {
+		t.Fatalf("got %d matches and next %d, want 1 and 2", len(matches), next)
+	}
+	matches, next = searchWindow(content, window, search, next, 1)
+	if len(matches) != 1 || matches[0].Line.Timestamp != searchMinute.Add(3*time.Second).Unix() {
+		t.Fatalf("resumed search got %v, want the wrapper line", matches)
+	}
+
+	// Sources and time range narrow the lines
+	search.sources = map[string]bool{"stdout": true}
+	if matches, _ := searchWindow(content, window, search, 0, 10); len(matches) != 1 || matches[0].Line.Source != pb.LogSource_LOG_SOURCE_STDOUT {
+		t.Errorf("stdout-only search got %v", matches)
+	}
+	search.start = searchMinute.Add(2 * time.Second)
+	if matches, _ := searchWindow(content, window, search, 0, 10); len(matches) != 0 {
+		t.Errorf("search after the stdout join got %v", matches)
+	}
+}
+
+func TestLogSearchCursor(t *testing.T) {
+	want := logSearchCursor{minute: searchMinute, sessionID: 7, line: 42}
+	got, err := decodeLogSearchCursor(encodeLogSearchCursor(want))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.minute.Equal(want.minute) || got.sessionID != want.sessionID || got.line != want.line {
+		t.Errorf("cursor = %+v, want %+v", *got, want)
+	}
+	if _, err := decodeLogSearchCursor("not a token"); err == nil {
+		t.Error("decoding garbage succeeded")
+	}
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultMetricsRange = 24 * time.Hour
+	// A session or disk sample older than this doesn't count as current usage
+	currentUsageWindow = 5 * time.Minute
+)
+
+// MetricsHandler serves the resource usage host managers sample, and what remains of a
+// server's capacity
+type MetricsHandler struct {
+	repo *repository.Repository
+}
+
+func NewMetricsHandler(repo *repository.Repository) *MetricsHandler {
+	return &MetricsHandler{repo: repo}
+}
+
+func (h *MetricsHandler) GetServerMetrics(ctx context.Context, req *pb.GetServerMetricsRequest) (*pb.GetServerMetricsResponse, error) {
+	if _, err := h.repo.Servers.Get(ctx, req.ServerId); err != nil {
+		return nil, status.Errorf(codes.NotFound, "server not found: %v", err)
+	}
+
+	now := time.Now()
+	end := now
+	if req.EndTimestamp != 0 {
+		end = time.Unix(req.EndTimestamp, 0)
+	}
+	start := end.Add(-defaultMetricsRange)
+	if req.StartTimestamp != 0 {
+		start = time.Unix(req.StartTimestamp, 0)
+	}
+	if !start.Before(end) {
+		return nil, status.Error(codes.InvalidArgument, "start_timestamp must be before end_timestamp")
+	}
+
+	hostBuckets, err := h.repo.Metrics.ListHost(ctx, req.ServerId, start, end)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list host metrics: %v", err)
+	}
+	sessionBuckets, err := h.repo.Metrics.ListSessions(ctx, req.ServerId, start, end)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list session metrics: %v", err)
+	}
+
+	capacity, err := h.serverCapacity(ctx, req.ServerId, now)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &pb.GetServerMetricsResponse{
+		Sessions: sessionMetricsSeries(sessionBuckets),
+		Capacity: capacity,
+	}
+	for _, b := range hostBuckets {
+		resp.Disk = append(resp.Disk, &pb.HostMetricsPoint{
+			Timestamp:         b.BucketStart.Unix(),
+			ResolutionSeconds: b.ResolutionSeconds,
+			DiskTotalBytes:    b.DiskTotalBytes,
+			DiskUsedBytes:     b.DiskUsedBytesMax,
+		})
+	}
+	return resp, nil
+}
+
+// serverCapacity sets the server's reported totals against the limits its active
+// sessions reserve and the usage last measured
+func (h *MetricsHandler) serverCapacity(ctx context.Context, serverID int64, now time.Time) (*pb.ServerCapacity, error) {
+	capacity := &pb.ServerCapacity{}
+
+	capability, err := h.repo.ServerCapabilities.Get(ctx, serverID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Errorf(codes.Internal, "failed to fetch server capabilities: %v", err)
+	}
+	if capability != nil {
+		capacity.TotalCpuMillicores = capability.CPUCores * 1000
+		capacity.TotalMemoryMb = capability.TotalMemoryMB
+	}
+
+	load, err := loadOnServer(ctx, h.repo, serverID, 0)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "%v", err)
+	}
+	capacity.CommittedCpuMillicores = load.committed.CPUMillicores
+	capacity.CommittedMemoryMb = load.committed.MemoryMB
+	capacity.ActiveSessions = int32(load.sessions)
+
+	since := now.Add(-currentUsageWindow)
+	hostBuckets, err := h.repo.Metrics.ListHost(ctx, serverID, since, now.Add(time.Minute))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list host metrics: %v", err)
+	}
+	sessionBuckets, err := h.repo.Metrics.ListSessions(ctx, serverID, since, now.Add(time.Minute))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list session metrics: %v", err)
+	}
+	applyCurrentUsage(capacity, hostBuckets, sessionBuckets)
+	return capacity, nil
+}
+
+// applyCurrentUsage adds the newest disk bucket and the newest bucket of each session to
+// capacity. Sessions that stopped reporting in the window still count until it passes.
+func applyCurrentUsage(capacity *pb.ServerCapacity, hostBuckets []*manman.HostMetricsBucket, sessionBuckets []*manman.SessionMetricsBucket) {
+	var measuredAt time.Time
+	if n := len(hostBuckets); n > 0 {
+		latest := hostBuckets[n-1]
+		capacity.DiskTotalBytes = latest.DiskTotalBytes
+		capacity.DiskUsedBytes = latest.DiskUsedBytesMax
+		measuredAt = latest.BucketStart
+	}
+
+	latest := make(map[int64]*manman.SessionMetricsBucket)
+	for _, b := range sessionBuckets {
+		if cur, ok := latest[b.SessionID]; !ok || b.BucketStart.After(cur.BucketStart) {
+			latest[b.SessionID] = b
+		}
+	}
+	for _, b := range latest {
+		capacity.UsedCpuPercent += b.Avg(b.CPUPercentSum)
+		capacity.UsedMemoryBytes += int64(b.Avg(b.MemoryBytesSum))
+		if b.BucketStart.After(measuredAt) {
+			measuredAt = b.BucketStart
+		}
+	}
+	if !measuredAt.IsZero() {
+		capacity.MeasuredAt = measuredAt.Unix()
+	}
+}
+
+// sessionMetricsSeries groups buckets, ordered by session and then time, into a series
+// per session
+func sessionMetricsSeries(buckets []*manman.SessionMetricsBucket) []*pb.SessionMetricsSeries {
+	var series []*pb.SessionMetricsSeries
+	var cur *pb.SessionMetricsSeries
+	for _, b := range buckets {
+		if cur == nil || cur.SessionId != b.SessionID {
+			cur = &pb.SessionMetricsSeries{SessionId: b.SessionID, SgcId: b.SGCID}
+			series = append(series, cur)
+		}
+		cur.Points = append(cur.Points, &pb.SessionMetricsPoint{
+			Timestamp:             b.BucketStart.Unix(),
+			ResolutionSeconds:     b.ResolutionSeconds,
+			CpuPercentAvg:         b.Avg(b.CPUPercentSum),
+			CpuPercentMax:         b.CPUPercentMax,
+			MemoryBytesAvg:        int64(b.Avg(b.MemoryBytesSum)),
+			MemoryBytesMax:        b.MemoryBytesMax,
+			MemoryLimitBytes:      b.MemoryLimitBytes,
+			NetworkRxBytesPerSec:  b.Avg(b.NetworkRxBytesPerSecSum),
+			NetworkTxBytesPerSec:  b.Avg(b.NetworkTxBytesPerSecSum),
+			BlockReadBytesPerSec:  b.Avg(b.BlockReadBytesPerSecSum),
+			BlockWriteBytesPerSec: b.Avg(b.BlockWriteBytesPerSecSum),
+		})
+	}
+	return series
+}
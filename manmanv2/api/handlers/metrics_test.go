@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func TestSessionMetricsSeries(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	buckets := []*manman.SessionMetricsBucket{
+		{SessionID: 1, SGCID: 10, BucketStart: t0, ResolutionSeconds: 60, Samples: 2, CPUPercentSum: 100, CPUPercentMax: 80, MemoryBytesSum: 2048},
+		{SessionID: 1, SGCID: 10, BucketStart: t0.Add(time.Minute), ResolutionSeconds: 60, Samples: 1, CPUPercentSum: 30},
+		{SessionID: 2, SGCID: 20, BucketStart: t0, ResolutionSeconds: 60},
+	}
+
+	series := sessionMetricsSeries(buckets)
+	if len(series) != 2 || len(series[0].Points) != 2 || len(series[1].Points) != 1 {
+		t.Fatalf("got %d series, want session 1 with 2 points and session 2 with 1", len(series))
+	}
+	p := series[0].Points[0]
+	if p.CpuPercentAvg != 50 || p.CpuPercentMax != 80 || p.MemoryBytesAvg != 1024 || p.Timestamp != t0.Unix() {
+		t.Errorf("first point = %+v, want averages over its 2 samples", p)
+	}
+	// A bucket without samples averages to 0 rather than dividing by zero
+	if p := series[1].Points[0]; p.CpuPercentAvg != 0 {
+		t.Errorf("empty bucket cpu = %v, want 0", p.CpuPercentAvg)
+	}
+}
+
+func TestApplyCurrentUsage(t *testing.T) {
+	t0 := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	capacity := &pb.ServerCapacity{}
+	applyCurrentUsage(capacity,
+		[]*manman.HostMetricsBucket{
+			{BucketStart: t0, DiskTotalBytes: 100, DiskUsedBytesMax: 40},
+			{BucketStart: t0.Add(time.Minute), DiskTotalBytes: 100, DiskUsedBytesMax: 45},
+		},
+		[]*manman.SessionMetricsBucket{
+			{SessionID: 1, BucketStart: t0, Samples: 1, CPUPercentSum: 500, MemoryBytesSum: 5000},
+			{SessionID: 1, BucketStart: t0.Add(2 * time.Minute), Samples: 2, CPUPercentSum: 100, MemoryBytesSum: 2000},
+			{SessionID: 2, BucketStart: t0.Add(time.Minute), Samples: 1, CPUPercentSum: 25, MemoryBytesSum: 500},
+		})
+
+	// Only the newest bucket of each session counts
+	if capacity.UsedCpuPercent != 75 || capacity.UsedMemoryBytes != 1500 {
+		t.Errorf("used = %v%% cpu, %d bytes, want 75%% and 1500", capacity.UsedCpuPercent, capacity.UsedMemoryBytes)
+	}
+	if capacity.DiskUsedBytes != 45 || capacity.DiskTotalBytes != 100 {
+		t.Errorf("disk = %d/%d, want 45/100", capacity.DiskUsedBytes, capacity.DiskTotalBytes)
+	}
+	if capacity.MeasuredAt != t0.Add(2*time.Minute).Unix() {
+		t.Errorf("measured at %d, want the newest bucket", capacity.MeasuredAt)
+	}
+}
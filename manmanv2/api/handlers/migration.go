@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MigrationHandler validates and records SGC migrations. The processor carries them out:
+// it stops the session, backs up each volume on the source server, recreates the SGC on
+// the target, restores the backups there and checks their checksums, then copies the
+// source's settings across before switching over.
+type MigrationHandler struct {
+	repo   *repository.Repository
+	placer *Placer
+}
+
+func NewMigrationHandler(repo *repository.Repository, placer *Placer) *MigrationHandler {
+	return &MigrationHandler{repo: repo, placer: placer}
+}
+
+func (h *MigrationHandler) MigrateServerGameConfig(ctx context.Context, req *pb.MigrateServerGameConfigRequest) (*pb.MigrateServerGameConfigResponse, error) {
+	sgc, err := h.repo.ServerGameConfigs.Get(ctx, req.ServerGameConfigId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "server game config not found: %v", err)
+	}
+	switch sgc.Status {
+	case manman.SGCStatusMigrating:
+		return nil, status.Errorf(codes.FailedPrecondition, "server game config %d is already being migrated", sgc.SGCID)
+	case manman.SGCStatusMigrated:
+		return nil, status.Errorf(codes.FailedPrecondition, "server game config %d has already been migrated", sgc.SGCID)
+	}
+	if req.TargetServerId == sgc.ServerID {
+		return nil, status.Error(codes.InvalidArgument, "target_server_id is the server the config is already on")
+	}
+
+	gc, err := h.repo.GameConfigs.Get(ctx, sgc.GameConfigID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get game config: %v", err)
+	}
+
+	// Ports are picked afresh on the target; the source's host ports may be taken there
+	ports := jsonbToPortBindings(sgc.PortBindings)
+	for _, p := range ports {
+		p.HostPort = 0
+	}
+
+	constraints := &pb.PlacementConstraints{}
+	if req.TargetServerId == 0 && req.Placement != nil {
+		constraints = req.Placement
+	}
+	constraints.ExcludeServerIds = append(constraints.ExcludeServerIds, sgc.ServerID)
+
+	candidates, err := h.placer.Rank(ctx, placementRequest{
+		gc:          gc,
+		limits:      manman.ResolveResourceLimits(gc, sgc),
+		ports:       ports,
+		constraints: constraints,
+		serverID:    req.TargetServerId,
+	})
+	if err != nil {
+		if req.TargetServerId != 0 {
+			return nil, status.Errorf(codes.NotFound, "target server not found: %v", err)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to evaluate servers: %v", err)
+	}
+	if len(candidates) == 0 || !candidates[0].Eligible {
+		return nil, status.Errorf(codes.FailedPrecondition, "no server can take server game config %d: %s", sgc.SGCID, summarizeRejections(candidates))
+	}
+	target := candidates[0]
+
+	volumes, err := h.repo.GameConfigVolumes.ListByGameConfig(ctx, gc.ConfigID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list volumes: %v", err)
+	}
+	migrationVolumes := make([]manman.MigrationVolume, len(volumes))
+	for i, v := range volumes {
+		migrationVolumes[i] = manman.MigrationVolume{VolumeID: v.VolumeID, Name: v.Name}
+	}
+
+	bindings := make([]manman.PortBinding, len(target.PortBindings))
+	for i, b := range target.PortBindings {
+		bindings[i] = manman.PortBinding{ContainerPort: b.ContainerPort, HostPort: b.HostPort, Protocol: b.Protocol}
+	}
+
+	migration, err := h.repo.SGCMigrations.Create(ctx, &manman.SGCMigration{
+		SourceSGCID:    sgc.SGCID,
+		SourceServerID: sgc.ServerID,
+		SourceStatus:   sgc.Status,
+		TargetServerID: target.ServerId,
+		PortBindings:   bindings,
+		Volumes:        migrationVolumes,
+		StartSession:   req.StartSession,
+	})
+	if errors.Is(err, repository.ErrStatusChanged) {
+		return nil, status.Errorf(codes.FailedPrecondition, "server game config %d changed status while the migration was being set up", sgc.SGCID)
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create migration: %v", err)
+	}
+
+	log.Printf("migration %d: SGC %d from server %d to server %d requested", migration.MigrationID, sgc.SGCID, sgc.ServerID, target.ServerId)
+	return &pb.MigrateServerGameConfigResponse{Migration: h.migrationToProto(ctx, migration)}, nil
+}
+
+func (h *MigrationHandler) GetSGCMigration(ctx context.Context, req *pb.GetSGCMigrationRequest) (*pb.GetSGCMigrationResponse, error) {
+	migration, err := h.repo.SGCMigrations.Get(ctx, req.MigrationId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "migration not found: %v", err)
+	}
+	return &pb.GetSGCMigrationResponse{Migration: h.migrationToProto(ctx, migration)}, nil
+}
+
+func (h *MigrationHandler) ListSGCMigrations(ctx context.Context, req *pb.ListSGCMigrationsRequest) (*pb.ListSGCMigrationsResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := 0
+	if req.PageToken != "" {
+		var err error
+		offset, err = decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %v", err)
+		}
+	}
+
+	migrations, err := h.repo.SGCMigrations.List(ctx, req.ServerGameConfigId, pageSize+1, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list migrations: %v", err)
+	}
+
+	var nextPageToken string
+	if len(migrations) > pageSize {
+		migrations = migrations[:pageSize]
+		nextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	pbMigrations := make([]*pb.SGCMigration, len(migrations))
+	for i, m := range migrations {
+		pbMigrations[i] = h.migrationToProto(ctx, m)
+	}
+	return &pb.ListSGCMigrationsResponse{Migrations: pbMigrations, NextPageToken: nextPageToken}, nil
+}
+
+// migrationToProto converts m, filling in each volume's backup status and checksum
+func (h *MigrationHandler) migrationToProto(ctx context.Context, m *manman.SGCMigration) *pb.SGCMigration {
+	out := &pb.SGCMigration{
+		MigrationId:              m.MigrationID,
+		SourceServerGameConfigId: m.SourceSGCID,
+		SourceServerId:           m.SourceServerID,
+		TargetServerId:           m.TargetServerID,
+		StartSession:             m.StartSession,
+		Status:                   m.Status,
+		CreatedAt:                m.CreatedAt.Unix(),
+		UpdatedAt:                m.UpdatedAt.Unix(),
+	}
+	if m.TargetSGCID != nil {
+		out.TargetServerGameConfigId = *m.TargetSGCID
+	}
+	if m.ErrorMessage != nil {
+		out.ErrorMessage = *m.ErrorMessage
+	}
+	if m.CompletedAt != nil {
+		out.CompletedAt = m.CompletedAt.Unix()
+	}
+	for _, b := range m.PortBindings {
+		out.PortBindings = append(out.PortBindings, &pb.PortBinding{ContainerPort: b.ContainerPort, HostPort: b.HostPort, Protocol: b.Protocol})
+	}
+	for _, v := range m.Volumes {
+		pv := &pb.SGCMigrationVolume{
+			VolumeId:         v.VolumeID,
+			Name:             v.Name,
+			BackupId:         v.BackupID,
+			Restored:         v.Restored,
+			RestoredChecksum: v.RestoredChecksum,
+			Error:            v.Error,
+		}
+		if v.BackupID != 0 {
+			if backup, err := h.repo.Backups.Get(ctx, v.BackupID); err == nil {
+				pv.BackupStatus = backup.Status
+				if backup.Checksum != nil {
+					pv.Checksum = *backup.Checksum
+				}
+			}
+		}
+		out.Volumes = append(out.Volumes, pv)
+	}
+	return out
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// migrationRepo records the migrations it is asked to create, marking the source SGC
+// migrating as the postgres repository does. beforeCreate runs first, standing in for a
+// concurrent request.
+type migrationRepo struct {
+	repository.SGCMigrationRepository
+	sgcs         *MockSGCRepo
+	beforeCreate func()
+	created      []*manman.SGCMigration
+}
+
+func (m *migrationRepo) Create(ctx context.Context, migration *manman.SGCMigration) (*manman.SGCMigration, error) {
+	if m.beforeCreate != nil {
+		m.beforeCreate()
+	}
+	source := m.sgcs.sgcs[migration.SourceSGCID]
+	if source.Status != migration.SourceStatus {
+		return nil, repository.ErrStatusChanged
+	}
+	source.Status = manman.SGCStatusMigrating
+	migration.MigrationID = int64(len(m.created) + 1)
+	migration.Status = manman.MigrationStatusPending
+	m.created = append(m.created, migration)
+	return migration, nil
+}
+
+// migrationVolumeRepo gives every game config a single "world" volume
+type migrationVolumeRepo struct {
+	repository.GameConfigVolumeRepository
+}
+
+func (m *migrationVolumeRepo) ListByGameConfig(ctx context.Context, configID int64) ([]*manman.GameConfigVolume, error) {
+	return []*manman.GameConfigVolume{{VolumeID: 7, ConfigID: configID, Name: "world"}}, nil
+}
+
+func newMigrationHandler(f *placementFixture) (*MigrationHandler, *migrationRepo) {
+	migrations := &migrationRepo{sgcs: f.sgcs}
+	f.repo.SGCMigrations = migrations
+	f.repo.GameConfigVolumes = &migrationVolumeRepo{}
+	return NewMigrationHandler(f.repo, NewPlacer(f.repo)), migrations
+}
+
+func TestMigrateServerGameConfig(t *testing.T) {
+	f := newPlacementFixture()
+	f.sgcs.sgcs[10] = &manman.ServerGameConfig{
+		SGCID:        10,
+		ServerID:     1,
+		GameConfigID: 1,
+		Status:       manman.SGCStatusActive,
+		PortBindings: portBindingsToJSONB([]*pb.PortBinding{{ContainerPort: 25565, HostPort: 25565, Protocol: "TCP"}}),
+	}
+	// Another SGC on beta already has the port
+	f.ports.allocated = append(f.ports.allocated, &manman.ServerPort{ServerID: 2, Port: 25565, Protocol: "TCP"})
+	h, migrations := newMigrationHandler(f)
+
+	resp, err := h.MigrateServerGameConfig(context.Background(), &pb.MigrateServerGameConfigRequest{ServerGameConfigId: 10})
+	if err != nil {
+		t.Fatalf("MigrateServerGameConfig failed: %v", err)
+	}
+	if len(migrations.created) != 1 {
+		t.Fatalf("Expected one migration, got %d", len(migrations.created))
+	}
+	m := migrations.created[0]
+	if m.TargetServerID != 2 || resp.Migration.TargetServerId != 2 {
+		t.Errorf("Expected beta as the target, got server %d", m.TargetServerID)
+	}
+	if m.SourceStatus != manman.SGCStatusActive {
+		t.Errorf("Expected the source status to be kept for a rollback, got %q", m.SourceStatus)
+	}
+	if len(m.PortBindings) != 1 || m.PortBindings[0].HostPort == 25565 {
+		t.Errorf("Expected a fresh host port on the target, got %+v", m.PortBindings)
+	}
+	if len(m.Volumes) != 1 || m.Volumes[0].VolumeID != 7 {
+		t.Errorf("Expected the world volume to be migrated, got %+v", m.Volumes)
+	}
+	if got := f.sgcs.sgcs[10].Status; got != manman.SGCStatusMigrating {
+		t.Errorf("Expected the source SGC to be migrating, got %q", got)
+	}
+
+	// A second request while the first is running is refused
+	_, err = h.MigrateServerGameConfig(context.Background(), &pb.MigrateServerGameConfigRequest{ServerGameConfigId: 10})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for an SGC already migrating, got %v", err)
+	}
+}
+
+func TestMigrateServerGameConfigRejectsTarget(t *testing.T) {
+	f := newPlacementFixture()
+	f.sgcs.sgcs[10] = &manman.ServerGameConfig{SGCID: 10, ServerID: 1, GameConfigID: 1, Status: manman.SGCStatusActive}
+	h, migrations := newMigrationHandler(f)
+
+	_, err := h.MigrateServerGameConfig(context.Background(), &pb.MigrateServerGameConfigRequest{ServerGameConfigId: 10, TargetServerId: 1})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for the source server, got %v", err)
+	}
+
+	_, err = h.MigrateServerGameConfig(context.Background(), &pb.MigrateServerGameConfigRequest{ServerGameConfigId: 10, TargetServerId: 3})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for an offline server, got %v", err)
+	}
+
+	if len(migrations.created) != 0 {
+		t.Errorf("Expected no migration created, got %d", len(migrations.created))
+	}
+	if got := f.sgcs.sgcs[10].Status; got != manman.SGCStatusActive {
+		t.Errorf("Expected the source SGC to be untouched, got %q", got)
+	}
+}
+
+func TestMigrateServerGameConfigLosesRace(t *testing.T) {
+	f := newPlacementFixture()
+	f.sgcs.sgcs[10] = &manman.ServerGameConfig{SGCID: 10, ServerID: 1, GameConfigID: 1, Status: manman.SGCStatusActive}
+	h, migrations := newMigrationHandler(f)
+	// Another request starts migrating the SGC after this one checked its status
+	migrations.beforeCreate = func() { f.sgcs.sgcs[10].Status = manman.SGCStatusMigrating }
+
+	_, err := h.MigrateServerGameConfig(context.Background(), &pb.MigrateServerGameConfigRequest{ServerGameConfigId: 10})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+		t.Errorf("Expected FailedPrecondition for an SGC taken by another request, got %v", err)
+	}
+	if len(migrations.created) != 0 {
+		t.Errorf("Expected no migration created, got %d", len(migrations.created))
+	}
+}
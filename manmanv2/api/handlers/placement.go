@@ -0,0 +1,390 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultPlacementPortRange is searched for free host ports on servers that haven't
+// reported the ranges they keep open for game servers
+var defaultPlacementPortRange = manman.PortRange{Start: 20000, End: 29999}
+
+// Placement score weights. Headroom is worth up to 40 points each for CPU and memory.
+const (
+	scoreHeadroom       = 40
+	scorePreferredLabel = 15
+	scorePerSession     = -10
+	scorePerSameGame    = -30 // only with spread
+	scoreDefaultServer  = 5
+)
+
+// Placer picks the server a game config should be deployed to
+type Placer struct {
+	repo *repository.Repository
+}
+
+func NewPlacer(repo *repository.Repository) *Placer {
+	return &Placer{repo: repo}
+}
+
+// placementRequest is what's being placed
+type placementRequest struct {
+	gc          *manman.GameConfig
+	limits      manman.ResourceLimits // resolved from the game config and SGC overrides
+	ports       []*pb.PortBinding     // a host_port of 0 is picked per server
+	constraints *pb.PlacementConstraints
+	serverID    int64 // evaluate only this server; 0 for all
+}
+
+// Rank evaluates every server for req, returning eligible servers best first followed
+// by the rejected ones
+func (p *Placer) Rank(ctx context.Context, req placementRequest) ([]*pb.PlacementCandidate, error) {
+	var servers []*manman.Server
+	if req.serverID != 0 {
+		server, err := p.repo.Servers.Get(ctx, req.serverID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get server %d: %w", req.serverID, err)
+		}
+		servers = []*manman.Server{server}
+	} else {
+		var err error
+		servers, err = p.repo.Servers.List(ctx, 1000, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list servers: %w", err)
+		}
+	}
+
+	constraints := req.constraints
+	if constraints == nil {
+		constraints = &pb.PlacementConstraints{}
+	}
+	excluded := make(map[int64]bool)
+	for _, id := range constraints.ExcludeServerIds {
+		excluded[id] = true
+	}
+
+	candidates := make([]*pb.PlacementCandidate, 0, len(servers))
+	for _, server := range servers {
+		c := &pb.PlacementCandidate{ServerId: server.ServerID, ServerName: server.Name}
+		candidates = append(candidates, c)
+
+		switch {
+		case excluded[server.ServerID]:
+			c.Reasons = append(c.Reasons, "excluded by request")
+			continue
+		case server.Status != manman.ServerStatusOnline:
+			c.Reasons = append(c.Reasons, fmt.Sprintf("server is %s", server.Status))
+			continue
+		case !server.HasLabels(constraints.RequiredLabels):
+			c.Reasons = append(c.Reasons, fmt.Sprintf("missing required labels %s", formatLabels(missingLabels(server, constraints.RequiredLabels))))
+			continue
+		}
+
+		if err := p.evaluate(ctx, c, server, req, constraints); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Eligible != candidates[j].Eligible {
+			return candidates[i].Eligible
+		}
+		return candidates[i].Score > candidates[j].Score
+	})
+	return candidates, nil
+}
+
+// evaluate checks capacity and ports on an online server that meets the label
+// constraints and scores it
+func (p *Placer) evaluate(ctx context.Context, c *pb.PlacementCandidate, server *manman.Server, req placementRequest, constraints *pb.PlacementConstraints) error {
+	capability, err := p.repo.ServerCapabilities.Get(ctx, server.ServerID)
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to fetch capabilities of server %d: %w", server.ServerID, err)
+		}
+		capability = nil
+	}
+	load, err := loadOnServer(ctx, p.repo, server.ServerID, 0)
+	if err != nil {
+		return err
+	}
+
+	if capability != nil {
+		if overcommit := capability.Overcommit(load.committed, req.limits); overcommit != "" {
+			c.Reasons = append(c.Reasons, "not enough capacity: "+overcommit)
+			return nil
+		}
+	}
+
+	ports, conflict, err := pickHostPorts(ctx, p.repo, server.ServerID, portRanges(capability), req.ports)
+	if err != nil {
+		return err
+	}
+	if conflict != "" {
+		c.Reasons = append(c.Reasons, conflict)
+		return nil
+	}
+	c.PortBindings = ports
+	c.Eligible = true
+
+	// Headroom left after this deployment; an unreported total scores as half free
+	if capability != nil && capability.CPUCores > 0 {
+		free := 1 - float64(load.committed.CPUMillicores+req.limits.CPUMillicores)/(float64(capability.CPUCores)*1000)
+		c.Score += int32(free * scoreHeadroom)
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%.0f%% cpu free after placement", free*100))
+	} else {
+		c.Score += scoreHeadroom / 2
+		c.Reasons = append(c.Reasons, "cpu capacity not reported")
+	}
+	if capability != nil && capability.TotalMemoryMB > 0 {
+		free := 1 - float64(load.committed.MemoryMB+req.limits.MemoryMB)/float64(capability.TotalMemoryMB)
+		c.Score += int32(free * scoreHeadroom)
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%.0f%% memory free after placement", free*100))
+	} else {
+		c.Score += scoreHeadroom / 2
+		c.Reasons = append(c.Reasons, "memory capacity not reported")
+	}
+
+	if load.sessions > 0 {
+		c.Score += int32(load.sessions * scorePerSession)
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%d active sessions", load.sessions))
+	}
+	if constraints.Spread && req.gc != nil {
+		if n := load.gameSessions[req.gc.GameID]; n > 0 {
+			c.Score += int32(n * scorePerSameGame)
+			c.Reasons = append(c.Reasons, fmt.Sprintf("already runs %d sessions of this game", n))
+		}
+	}
+	if len(constraints.PreferredLabels) > 0 {
+		var matched []string
+		for k, v := range constraints.PreferredLabels {
+			if server.HasLabels(map[string]string{k: v}) {
+				matched = append(matched, k+"="+server.Labels[k])
+			}
+		}
+		if len(matched) > 0 {
+			sort.Strings(matched)
+			c.Score += int32(len(matched) * scorePreferredLabel)
+			c.Reasons = append(c.Reasons, "has preferred labels "+strings.Join(matched, ", "))
+		}
+	}
+	if server.IsDefault {
+		c.Score += scoreDefaultServer
+		c.Reasons = append(c.Reasons, "default server")
+	}
+	return nil
+}
+
+// deployAttempts is how many times Deploy places again when a port it picked is taken
+// by a concurrent deployment before the SGC is saved
+const deployAttempts = 3
+
+// Deploy places sgc's game config and creates sgc on the chosen server with the picked
+// host ports, which are checked again under the server's lock as sgc is saved.
+// Everything else about sgc (limits, policies, status) is saved as given.
+func (p *Placer) Deploy(ctx context.Context, sgc *manman.ServerGameConfig, ports []*pb.PortBinding, constraints *pb.PlacementConstraints) (*manman.ServerGameConfig, *pb.PlacementCandidate, error) {
+	gc, err := p.repo.GameConfigs.Get(ctx, sgc.GameConfigID)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.NotFound, "game config not found: %v", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		candidates, err := p.Rank(ctx, placementRequest{
+			gc:          gc,
+			limits:      manman.ResolveResourceLimits(gc, sgc),
+			ports:       ports,
+			constraints: constraints,
+		})
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Internal, "failed to place game config: %v", err)
+		}
+		if len(candidates) == 0 || !candidates[0].Eligible {
+			return nil, nil, status.Errorf(codes.FailedPrecondition, "no server can run game config %d: %s", gc.ConfigID, summarizeRejections(candidates))
+		}
+		chosen := candidates[0]
+
+		// Only ports placement picked must stay unique; explicit ones may be shared by SGCs
+		var reserve []*manman.PortBinding
+		for i, b := range chosen.PortBindings {
+			if ports[i].HostPort == 0 {
+				reserve = append(reserve, &manman.PortBinding{ContainerPort: b.ContainerPort, HostPort: b.HostPort, Protocol: b.Protocol})
+			}
+		}
+
+		sgc.ServerID = chosen.ServerId
+		sgc.PortBindings = portBindingsToJSONB(chosen.PortBindings)
+		created, err := p.repo.ServerGameConfigs.CreateReservingPorts(ctx, sgc, reserve)
+		if errors.Is(err, repository.ErrPortsTaken) && attempt < deployAttempts {
+			continue
+		}
+		if errors.Is(err, repository.ErrPortsTaken) {
+			return nil, nil, status.Errorf(codes.Aborted, "ports kept being taken while deploying game config %d: %v", gc.ConfigID, err)
+		}
+		if err != nil {
+			return nil, nil, status.Errorf(codes.Internal, "failed to deploy game config: %v", err)
+		}
+		return created, chosen, nil
+	}
+}
+
+// portRanges are the host port ranges a server reported, nil if it hasn't
+func portRanges(capability *manman.ServerCapability) []manman.PortRange {
+	if capability == nil {
+		return nil
+	}
+	return capability.PortRanges
+}
+
+// pickHostPorts resolves the host port of each binding on a server. An explicit host
+// port conflicts only with a port allocated to a running session, since SGCs may share
+// port definitions. A host_port of 0 becomes the container port if no session or other
+// SGC on the server uses it, else the first such free port in the server's reported
+// ranges for the protocol. Returns a description of the conflict if a binding can't be
+// satisfied.
+func pickHostPorts(ctx context.Context, repo *repository.Repository, serverID int64, ranges []manman.PortRange, bindings []*pb.PortBinding) ([]*pb.PortBinding, string, error) {
+	if len(bindings) == 0 {
+		return nil, "", nil
+	}
+
+	key := func(port int32, protocol string) string { return fmt.Sprintf("%d/%s", port, protocol) }
+	allocated := make(map[string]bool) // held by sessions
+	taken := make(map[string]bool)     // held by sessions, bound by SGCs or picked already
+
+	ports, err := repo.ServerPorts.ListAllocatedPorts(ctx, serverID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list allocated ports on server %d: %w", serverID, err)
+	}
+	for _, p := range ports {
+		allocated[key(int32(p.Port), p.Protocol)] = true
+		taken[key(int32(p.Port), p.Protocol)] = true
+	}
+	sgcs, err := repo.ServerGameConfigs.List(ctx, &serverID, 1000, 0)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list server game configs on server %d: %w", serverID, err)
+	}
+	for _, sgc := range sgcs {
+		for _, b := range jsonbToPortBindings(sgc.PortBindings) {
+			taken[key(b.HostPort, b.Protocol)] = true
+		}
+	}
+
+	inRange := func(port int32, protocol string) bool {
+		found := false
+		for _, r := range ranges {
+			if r.Protocol != protocol {
+				continue
+			}
+			found = true
+			if port >= r.Start && port <= r.End {
+				return true
+			}
+		}
+		// Without reported ranges any port may be used
+		return !found
+	}
+
+	picked := make([]*pb.PortBinding, len(bindings))
+	for i, b := range bindings {
+		protocol := strings.ToUpper(b.Protocol)
+		if protocol == "" {
+			protocol = manman.ProtocolTCP
+		}
+		out := &pb.PortBinding{ContainerPort: b.ContainerPort, HostPort: b.HostPort, Protocol: protocol}
+
+		switch {
+		case out.HostPort != 0:
+			if allocated[key(out.HostPort, protocol)] {
+				return nil, fmt.Sprintf("port %s is in use on this server", key(out.HostPort, protocol)), nil
+			}
+		case !taken[key(out.ContainerPort, protocol)] && inRange(out.ContainerPort, protocol):
+			out.HostPort = out.ContainerPort
+		default:
+			port, err := freePortInRanges(ctx, repo, serverID, protocol, ranges, taken, key)
+			if err != nil {
+				return nil, "", err
+			}
+			if port == 0 {
+				return nil, fmt.Sprintf("no free %s port for container port %d", protocol, out.ContainerPort), nil
+			}
+			out.HostPort = port
+		}
+
+		taken[key(out.HostPort, protocol)] = true
+		picked[i] = out
+	}
+	return picked, "", nil
+}
+
+// freePortInRanges returns the lowest port in the server's ranges for protocol that
+// isn't taken, 0 if there is none
+func freePortInRanges(ctx context.Context, repo *repository.Repository, serverID int64, protocol string, ranges []manman.PortRange, taken map[string]bool, key func(int32, string) string) (int32, error) {
+	var search []manman.PortRange
+	for _, r := range ranges {
+		if r.Protocol == protocol {
+			search = append(search, r)
+		}
+	}
+	if len(search) == 0 {
+		search = []manman.PortRange{defaultPlacementPortRange}
+	}
+
+	for _, r := range search {
+		// Allocated ports are already filtered out; leave room for ones bound by SGCs
+		free, err := repo.ServerPorts.GetAvailablePortsInRange(ctx, serverID, protocol, int(r.Start), int(r.End), len(taken)+1)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find free ports on server %d: %w", serverID, err)
+		}
+		for _, port := range free {
+			if !taken[key(int32(port), protocol)] {
+				return int32(port), nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+func missingLabels(server *manman.Server, required map[string]string) map[string]string {
+	missing := make(map[string]string)
+	for k, v := range required {
+		if !server.HasLabels(map[string]string{k: v}) {
+			missing[k] = v
+		}
+	}
+	return missing
+}
+
+// formatLabels renders labels as sorted "k=v" pairs; an empty value renders as just "k"
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		if v == "" {
+			pairs = append(pairs, k)
+		} else {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// summarizeRejections explains in one line why no candidate was eligible
+func summarizeRejections(candidates []*pb.PlacementCandidate) string {
+	if len(candidates) == 0 {
+		return "no servers are registered"
+	}
+	parts := make([]string, len(candidates))
+	for i, c := range candidates {
+		parts[i] = fmt.Sprintf("%s: %s", c.ServerName, strings.Join(c.Reasons, "; "))
+	}
+	return strings.Join(parts, " | ")
+}
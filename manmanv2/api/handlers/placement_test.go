@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type placementFixture struct {
+	repo     *repository.Repository
+	sessions *MockSessionRepo
+	sgcs     *MockSGCRepo
+	ports    *MockServerPortRepo
+	caps     *MockServerCapabilityRepo
+}
+
+// newPlacementFixture has two online 4-core/8GB servers, "alpha" (1) and "beta" (2), and
+// an offline one, "gamma" (3). Game configs reserve 1 core and 1GB.
+func newPlacementFixture() *placementFixture {
+	f := &placementFixture{
+		sessions: &MockSessionRepo{},
+		sgcs:     &MockSGCRepo{sgcs: make(map[int64]*manman.ServerGameConfig)},
+		ports:    &MockServerPortRepo{},
+		caps: &MockServerCapabilityRepo{caps: map[int64]*manman.ServerCapability{
+			1: {ServerID: 1, CPUCores: 4, TotalMemoryMB: 8192},
+			2: {ServerID: 2, CPUCores: 4, TotalMemoryMB: 8192},
+		}},
+	}
+	f.repo = &repository.Repository{
+		Servers: &MockServerRepo{servers: []*manman.Server{
+			{ServerID: 1, Name: "alpha", Status: manman.ServerStatusOnline, Labels: map[string]string{"region": "us-east"}},
+			{ServerID: 2, Name: "beta", Status: manman.ServerStatusOnline, Labels: map[string]string{"region": "eu-west", "gpu": "true"}},
+			{ServerID: 3, Name: "gamma", Status: manman.ServerStatusOffline, Labels: map[string]string{"region": "us-east"}},
+		}},
+		ServerCapabilities: f.caps,
+		ServerGameConfigs:  f.sgcs,
+		GameConfigs:        &limitedGCRepo{},
+		Sessions:           f.sessions,
+		ServerPorts:        f.ports,
+	}
+	return f
+}
+
+// runSession puts a running session of a new SGC on serverID
+func (f *placementFixture) runSession(sgcID, serverID int64) {
+	f.sgcs.sgcs[sgcID] = &manman.ServerGameConfig{SGCID: sgcID, ServerID: serverID, GameConfigID: 1}
+	f.sessions.sessions = append(f.sessions.sessions, &manman.Session{SessionID: int64(len(f.sessions.sessions) + 1), SGCID: sgcID, Status: manman.SessionStatusRunning})
+}
+
+func rankFor(t *testing.T, f *placementFixture, constraints *pb.PlacementConstraints, ports []*pb.PortBinding) []*pb.PlacementCandidate {
+	t.Helper()
+	gc, _ := f.repo.GameConfigs.Get(context.Background(), 1)
+	candidates, err := NewPlacer(f.repo).Rank(context.Background(), placementRequest{
+		gc:          gc,
+		limits:      manman.ResolveResourceLimits(gc, &manman.ServerGameConfig{}),
+		ports:       ports,
+		constraints: constraints,
+	})
+	if err != nil {
+		t.Fatalf("Rank failed: %v", err)
+	}
+	return candidates
+}
+
+func candidateFor(candidates []*pb.PlacementCandidate, serverID int64) *pb.PlacementCandidate {
+	for _, c := range candidates {
+		if c.ServerId == serverID {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestRankPrefersLessLoadedServer(t *testing.T) {
+	f := newPlacementFixture()
+	f.runSession(10, 1)
+
+	candidates := rankFor(t, f, nil, nil)
+	if len(candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d", len(candidates))
+	}
+	if candidates[0].ServerId != 2 || !candidates[0].Eligible {
+		t.Errorf("Expected beta first, got %+v", candidates[0])
+	}
+	if !candidates[1].Eligible || candidates[1].Score >= candidates[0].Score {
+		t.Errorf("Expected alpha eligible with a lower score, got %+v", candidates[1])
+	}
+	gamma := candidates[2]
+	if gamma.ServerId != 3 || gamma.Eligible || !strings.Contains(strings.Join(gamma.Reasons, ";"), "offline") {
+		t.Errorf("Expected offline gamma last and rejected, got %+v", gamma)
+	}
+}
+
+func TestRankLabels(t *testing.T) {
+	f := newPlacementFixture()
+
+	candidates := rankFor(t, f, &pb.PlacementConstraints{RequiredLabels: map[string]string{"region": "us-east"}}, nil)
+	if candidates[0].ServerId != 1 || !candidates[0].Eligible {
+		t.Errorf("Expected alpha to be the only match, got %+v", candidates[0])
+	}
+	if beta := candidateFor(candidates, 2); beta.Eligible || !strings.Contains(beta.Reasons[0], "region=us-east") {
+		t.Errorf("Expected beta rejected for its region, got %+v", beta)
+	}
+
+	// A required label with an empty value only needs the key
+	candidates = rankFor(t, f, &pb.PlacementConstraints{RequiredLabels: map[string]string{"gpu": ""}}, nil)
+	if candidates[0].ServerId != 2 || candidateFor(candidates, 1).Eligible {
+		t.Errorf("Expected only beta to have a gpu label, got %+v", candidates)
+	}
+
+	// Preferred labels break the tie between otherwise equal servers
+	candidates = rankFor(t, f, &pb.PlacementConstraints{PreferredLabels: map[string]string{"region": "us-east"}}, nil)
+	if candidates[0].ServerId != 1 {
+		t.Errorf("Expected alpha preferred, got %+v", candidates[0])
+	}
+
+	candidates = rankFor(t, f, &pb.PlacementConstraints{ExcludeServerIds: []int64{1, 2}}, nil)
+	for _, c := range candidates {
+		if c.Eligible {
+			t.Errorf("Expected every server excluded or offline, got %+v", c)
+		}
+	}
+}
+
+func TestRankCapacity(t *testing.T) {
+	f := newPlacementFixture()
+	f.caps.caps[1].CPUCores = 1
+	f.runSession(10, 1)
+
+	candidates := rankFor(t, f, nil, nil)
+	alpha := candidateFor(candidates, 1)
+	if alpha.Eligible || !strings.Contains(alpha.Reasons[0], "not enough capacity") {
+		t.Errorf("Expected full alpha rejected, got %+v", alpha)
+	}
+
+	// Unreported capacity isn't checked
+	delete(f.caps.caps, 1)
+	if alpha := candidateFor(rankFor(t, f, nil, nil), 1); !alpha.Eligible {
+		t.Errorf("Expected alpha eligible without reported capacity, got %+v", alpha)
+	}
+}
+
+func TestRankSpread(t *testing.T) {
+	f := newPlacementFixture()
+	f.runSession(10, 2)
+	// Beta has far more headroom
+	f.caps.caps[2].CPUCores = 64
+	f.caps.caps[2].TotalMemoryMB = 131072
+
+	if candidates := rankFor(t, f, nil, nil); candidates[0].ServerId != 2 {
+		t.Fatalf("Expected beta first on headroom alone, got %+v", candidates[0])
+	}
+	if candidates := rankFor(t, f, &pb.PlacementConstraints{Spread: true}, nil); candidates[0].ServerId != 1 {
+		t.Errorf("Expected spread to move the game to alpha, got %+v", candidates[0])
+	}
+}
+
+func TestPickHostPorts(t *testing.T) {
+	f := newPlacementFixture()
+	sessionID := int64(1)
+	f.ports.allocated = []*manman.ServerPort{{ServerID: 1, Port: 27015, Protocol: "UDP", SessionID: &sessionID}}
+	f.sgcs.sgcs[10] = &manman.ServerGameConfig{SGCID: 10, ServerID: 1, PortBindings: portBindingsToJSONB([]*pb.PortBinding{{ContainerPort: 25565, HostPort: 25565, Protocol: "TCP"}})}
+	ranges := []manman.PortRange{{Start: 27000, End: 27100, Protocol: "UDP"}}
+
+	ports, conflict, err := pickHostPorts(context.Background(), f.repo, 1, ranges, []*pb.PortBinding{
+		{ContainerPort: 27015, Protocol: "udp"}, // allocated, so picked from the range
+		{ContainerPort: 27016, Protocol: "UDP"}, // free and in range
+		{ContainerPort: 25565},                  // bound by another SGC, no TCP range reported
+	})
+	if err != nil || conflict != "" {
+		t.Fatalf("Expected ports to be picked, got conflict %q, err %v", conflict, err)
+	}
+	want := []int32{27000, 27016, defaultPlacementPortRange.Start}
+	for i, p := range ports {
+		if p.HostPort != want[i] {
+			t.Errorf("Binding %d: expected host port %d, got %d", i, want[i], p.HostPort)
+		}
+	}
+	if ports[0].Protocol != "UDP" || ports[2].Protocol != "TCP" {
+		t.Errorf("Expected protocols normalized, got %s and %s", ports[0].Protocol, ports[2].Protocol)
+	}
+
+	// An explicit host port only conflicts with a session's allocation
+	_, conflict, _ = pickHostPorts(context.Background(), f.repo, 1, ranges, []*pb.PortBinding{{ContainerPort: 25565, HostPort: 25565, Protocol: "TCP"}})
+	if conflict != "" {
+		t.Errorf("Expected a port shared with another SGC to be allowed, got %q", conflict)
+	}
+	_, conflict, _ = pickHostPorts(context.Background(), f.repo, 1, ranges, []*pb.PortBinding{{ContainerPort: 27015, HostPort: 27015, Protocol: "UDP"}})
+	if conflict == "" {
+		t.Error("Expected a conflict with an allocated port")
+	}
+}
+
+func TestPlacerDeploy(t *testing.T) {
+	f := newPlacementFixture()
+	f.runSession(10, 1)
+
+	sgc, chosen, err := NewPlacer(f.repo).Deploy(context.Background(), &manman.ServerGameConfig{GameConfigID: 1}, []*pb.PortBinding{{ContainerPort: 25565}}, nil)
+	if err != nil {
+		t.Fatalf("Deploy failed: %v", err)
+	}
+	if sgc.ServerID != 2 || chosen.ServerId != 2 {
+		t.Errorf("Expected placement on beta, got server %d", sgc.ServerID)
+	}
+	if got := jsonbToPortBindings(sgc.PortBindings); len(got) != 1 || got[0].HostPort != 25565 {
+		t.Errorf("Expected the container port as host port, got %+v", got)
+	}
+
+	_, _, err = NewPlacer(f.repo).Deploy(context.Background(), &manman.ServerGameConfig{GameConfigID: 1}, nil, &pb.PlacementConstraints{RequiredLabels: map[string]string{"region": "ap-south"}})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition, got %v", err)
+	}
+	if len(f.sgcs.created) != 1 {
+		t.Errorf("Expected no SGC created when nothing fits, got %d", len(f.sgcs.created))
+	}
+
+	// A port taken by a concurrent deployment is picked again
+	f.sgcs.portsTaken = 1
+	if _, _, err := NewPlacer(f.repo).Deploy(context.Background(), &manman.ServerGameConfig{GameConfigID: 1}, []*pb.PortBinding{{ContainerPort: 25565}}, nil); err != nil {
+		t.Fatalf("Expected deploy to retry a taken port, got %v", err)
+	}
+	f.sgcs.portsTaken = deployAttempts
+	_, _, err = NewPlacer(f.repo).Deploy(context.Background(), &manman.ServerGameConfig{GameConfigID: 1}, []*pb.PortBinding{{ContainerPort: 25565}}, nil)
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.Aborted {
+		t.Fatalf("Expected Aborted once retries run out, got %v", err)
+	}
+}
+
+func TestStartSessionReusesDeployedSGC(t *testing.T) {
+	f := newPlacementFixture()
+	f.repo.GameConfigVolumes = &MockGameConfigVolumeRepo{}
+	h := &SessionHandler{repo: f.repo, sessionRepo: f.sessions, sgcRepo: f.sgcs, gcRepo: f.repo.GameConfigs, placer: NewPlacer(f.repo)}
+
+	resp, err := h.StartSession(context.Background(), &pb.StartSessionRequest{GameConfigId: 1})
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	if len(f.sgcs.created) != 1 || resp.Placement == nil {
+		t.Fatalf("Expected the game config deployed once, got %d SGCs", len(f.sgcs.created))
+	}
+	sgcID := resp.Session.ServerGameConfigId
+
+	// Starting it again uses the same SGC, which already has an active session
+	_, err = h.StartSession(context.Background(), &pb.StartSessionRequest{GameConfigId: 1})
+	if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+		t.Fatalf("Expected FailedPrecondition for the running SGC, got %v", err)
+	}
+	f.sessions.sessions[0].Status = manman.SessionStatusStopped
+	resp, err = h.StartSession(context.Background(), &pb.StartSessionRequest{GameConfigId: 1})
+	if err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	if len(f.sgcs.created) != 1 || resp.Session.ServerGameConfigId != sgcID {
+		t.Errorf("Expected SGC %d reused, got SGC %d after %d deploys", sgcID, resp.Session.ServerGameConfigId, len(f.sgcs.created))
+	}
+}
+
+func TestValidateDeploymentUsesServerPortRanges(t *testing.T) {
+	f := newPlacementFixture()
+	f.caps.caps[1].PortRanges = []manman.PortRange{{Start: 27000, End: 27100, Protocol: "UDP"}}
+	sessionID := int64(1)
+	f.ports.allocated = []*manman.ServerPort{{ServerID: 1, Port: 27015, Protocol: "UDP", SessionID: &sessionID}}
+
+	resp, err := NewValidationHandler(f.repo).ValidateDeployment(context.Background(), &pb.ValidateDeploymentRequest{
+		ServerId:     1,
+		GameConfigId: 1,
+		PortBindings: []*pb.PortBinding{{ContainerPort: 27015, Protocol: "UDP"}},
+	})
+	if err != nil {
+		t.Fatalf("ValidateDeployment failed: %v", err)
+	}
+	if got := resp.Estimate.GetAllocatedPorts(); len(got) != 1 || got[0] != 27000 {
+		t.Errorf("Expected a port from the server's range, got %v", got)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Player stats limits
+const (
+	defaultPlayerStatsWindow = 24 * time.Hour
+	defaultTopPlayers        = 10
+	maxConcurrencyBuckets    = 1000
+)
+
+// PlayerHandler serves the player stays the log-processor records from session logs
+type PlayerHandler struct {
+	playerRepo  repository.PlayerSessionRepository
+	sessionRepo repository.SessionRepository
+	sgcRepo     repository.ServerGameConfigRepository
+}
+
+func NewPlayerHandler(
+	playerRepo repository.PlayerSessionRepository,
+	sessionRepo repository.SessionRepository,
+	sgcRepo repository.ServerGameConfigRepository,
+) *PlayerHandler {
+	return &PlayerHandler{
+		playerRepo:  playerRepo,
+		sessionRepo: sessionRepo,
+		sgcRepo:     sgcRepo,
+	}
+}
+
+func (h *PlayerHandler) ListSessionPlayers(ctx context.Context, req *pb.ListSessionPlayersRequest) (*pb.ListSessionPlayersResponse, error) {
+	if _, err := h.sessionRepo.Get(ctx, req.SessionId); err != nil {
+		return nil, status.Errorf(codes.NotFound, "session not found: %v", err)
+	}
+
+	players, err := h.playerRepo.ListBySession(ctx, req.SessionId, req.OnlineOnly)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list session players: %v", err)
+	}
+	pbPlayers := make([]*pb.PlayerSession, len(players))
+	for i, p := range players {
+		pbPlayers[i] = playerSessionToProto(p)
+	}
+	return &pb.ListSessionPlayersResponse{Players: pbPlayers}, nil
+}
+
+func (h *PlayerHandler) GetPlayerStats(ctx context.Context, req *pb.GetPlayerStatsRequest) (*pb.GetPlayerStatsResponse, error) {
+	if _, err := h.sgcRepo.Get(ctx, req.ServerGameConfigId); err != nil {
+		return nil, status.Errorf(codes.NotFound, "server game config not found: %v", err)
+	}
+
+	until := time.Now().UTC()
+	if req.Until > 0 {
+		until = time.Unix(req.Until, 0).UTC()
+	}
+	since := until.Add(-defaultPlayerStatsWindow)
+	if req.Since > 0 {
+		since = time.Unix(req.Since, 0).UTC()
+	}
+	if !until.After(since) {
+		return nil, status.Error(codes.InvalidArgument, "until must be after since")
+	}
+
+	bucket := manman.DefaultPlayerStatsBucket(until.Sub(since))
+	if req.BucketSeconds > 0 {
+		bucket = time.Duration(req.BucketSeconds) * time.Second
+	}
+	if until.Sub(since)/bucket > maxConcurrencyBuckets {
+		return nil, status.Errorf(codes.InvalidArgument, "window needs more than %d buckets of %s", maxConcurrencyBuckets, bucket)
+	}
+
+	top := int(req.TopPlayers)
+	if top <= 0 {
+		top = defaultTopPlayers
+	}
+
+	stays, err := h.playerRepo.ListBySGC(ctx, req.ServerGameConfigId, since, until)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list players: %v", err)
+	}
+	stats := manman.ComputePlayerStats(req.ServerGameConfigId, stays, since, until, bucket, top)
+	return &pb.GetPlayerStatsResponse{Stats: playerStatsToProto(stats)}, nil
+}
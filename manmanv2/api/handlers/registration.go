@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -57,6 +58,11 @@ func (h *RegistrationHandler) RegisterServer(ctx context.Context, req *pb.Regist
 		server.Environment = &req.Environment
 	}
 
+	// The host's own labels win over ones set through UpdateServer
+	if len(req.Labels) > 0 {
+		server.Labels = req.Labels
+	}
+
 	if err := h.serverRepo.Update(ctx, server); err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update server status: %v", err)
 	}
@@ -70,6 +76,7 @@ func (h *RegistrationHandler) RegisterServer(ctx context.Context, req *pb.Regist
 			CPUCores:               req.Capabilities.CpuCores,
 			AvailableCPUMillicores: req.Capabilities.AvailableCpuMillicores,
 			DockerVersion:          req.Capabilities.DockerVersion,
+			PortRanges:             portRangesFromProto(req.Capabilities.AvailablePorts),
 		}
 
 		if err := h.capabilityRepo.Insert(ctx, capability); err != nil {
@@ -106,6 +113,7 @@ func (h *RegistrationHandler) Heartbeat(ctx context.Context, req *pb.HeartbeatRe
 			CPUCores:               req.Capabilities.CpuCores,
 			AvailableCPUMillicores: req.Capabilities.AvailableCpuMillicores,
 			DockerVersion:          req.Capabilities.DockerVersion,
+			PortRanges:             portRangesFromProto(req.Capabilities.AvailablePorts),
 		}
 
 		if err := h.capabilityRepo.Insert(ctx, capability); err != nil {
@@ -118,3 +126,19 @@ func (h *RegistrationHandler) Heartbeat(ctx context.Context, req *pb.HeartbeatRe
 		NextHeartbeatSeconds: 30, // 30 second interval
 	}, nil
 }
+
+// portRangesFromProto keeps the well-formed ranges a host reported
+func portRangesFromProto(ranges []*pb.PortRange) []manman.PortRange {
+	var out []manman.PortRange
+	for _, r := range ranges {
+		if r.Start <= 0 || r.End < r.Start || r.End > 65535 {
+			continue
+		}
+		protocol := strings.ToUpper(r.Protocol)
+		if protocol != manman.ProtocolTCP && protocol != manman.ProtocolUDP {
+			continue
+		}
+		out = append(out, manman.PortRange{Start: r.Start, End: r.End, Protocol: protocol})
+	}
+	return out
+}
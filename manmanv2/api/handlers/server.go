@@ -106,6 +106,9 @@ func (h *ServerHandler) UpdateServer(ctx context.Context, req *pb.UpdateServerRe
 		if req.IsDefault {
 			server.IsDefault = req.IsDefault
 		}
+		if req.Labels != nil {
+			server.Labels = req.Labels
+		}
 	} else {
 		// Update only specified fields
 		for _, path := range req.UpdatePaths {
@@ -116,6 +119,8 @@ func (h *ServerHandler) UpdateServer(ctx context.Context, req *pb.UpdateServerRe
 				server.Status = req.Status
 			case "is_default":
 				server.IsDefault = req.IsDefault
+			case "labels":
+				server.Labels = req.Labels
 			}
 		}
 	}
@@ -143,6 +148,7 @@ func serverToProto(s *manman.Server) *pb.Server {
 		Name:      s.Name,
 		Status:    s.Status,
 		IsDefault: s.IsDefault,
+		Labels:    s.Labels,
 	}
 
 	if s.LastSeen != nil {
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/whale-net/everything/manmanv2/models"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	pb "github.com/whale-net/everything/manmanv2/protos"
@@ -12,14 +14,18 @@ import (
 
 // ServerGameConfigHandler handles ServerGameConfig-related RPCs
 type ServerGameConfigHandler struct {
-	repo     repository.ServerGameConfigRepository
-	portRepo repository.ServerPortRepository
+	repo      repository.ServerGameConfigRepository
+	portRepo  repository.ServerPortRepository
+	imageRepo repository.SGCImageStatusRepository
+	placer    *Placer
 }
 
-func NewServerGameConfigHandler(repo repository.ServerGameConfigRepository, portRepo repository.ServerPortRepository) *ServerGameConfigHandler {
+func NewServerGameConfigHandler(repo repository.ServerGameConfigRepository, portRepo repository.ServerPortRepository, imageRepo repository.SGCImageStatusRepository, placer *Placer) *ServerGameConfigHandler {
 	return &ServerGameConfigHandler{
-		repo:     repo,
-		portRepo: portRepo,
+		repo:      repo,
+		portRepo:  portRepo,
+		imageRepo: imageRepo,
+		placer:    placer,
 	}
 }
 
@@ -58,8 +64,25 @@ func (h *ServerGameConfigHandler) ListServerGameConfigs(ctx context.Context, req
 	}
 
 	pbConfigs := make([]*pb.ServerGameConfig, len(configs))
+	sgcIDs := make([]int64, len(configs))
 	for i, c := range configs {
 		pbConfigs[i] = serverGameConfigToProto(c)
+		sgcIDs[i] = c.SGCID
+	}
+	if len(sgcIDs) > 0 {
+		images, err := h.imageRepo.ListBySGCs(ctx, sgcIDs)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "failed to list image statuses: %v", err)
+		}
+		byID := make(map[int64]*manman.SGCImageStatus, len(images))
+		for _, img := range images {
+			byID[img.SGCID] = img
+		}
+		for _, c := range pbConfigs {
+			if img, ok := byID[c.ServerGameConfigId]; ok {
+				c.ImageStatus = imageStatusToProto(img)
+			}
+		}
 	}
 
 	return &pb.ListServerGameConfigsResponse{
@@ -74,8 +97,15 @@ func (h *ServerGameConfigHandler) GetServerGameConfig(ctx context.Context, req *
 		return nil, status.Errorf(codes.NotFound, "server game config not found: %v", err)
 	}
 
+	pbConfig := serverGameConfigToProto(config)
+	if img, err := h.imageRepo.Get(ctx, config.SGCID); err == nil {
+		pbConfig.ImageStatus = imageStatusToProto(img)
+	} else if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, status.Errorf(codes.Internal, "failed to get image status: %v", err)
+	}
+
 	return &pb.GetServerGameConfigResponse{
-		Config: serverGameConfigToProto(config),
+		Config: pbConfig,
 	}, nil
 }
 
@@ -84,15 +114,47 @@ func (h *ServerGameConfigHandler) DeployGameConfig(ctx context.Context, req *pb.
 	// This allows multiple SGCs to define the same ports, with actual allocation
 	// and conflict detection happening only when sessions start.
 
+	if err := validateResourceLimits(req.ResourceLimits); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid resource_limits: %v", err)
+	}
+	restartPolicy, err := restartPolicyFromProto(req.RestartPolicy)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid restart_policy: %v", err)
+	}
+	idleShutdown, err := idleShutdownFromProto(req.IdleShutdown)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid idle_shutdown: %v", err)
+	}
+	if req.AddonUpdatePolicy != "" && !manman.IsValidAddonUpdatePolicy(req.AddonUpdatePolicy) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid addon_update_policy %q: must be off, notify or reinstall", req.AddonUpdatePolicy)
+	}
+
 	// Create the ServerGameConfig
 	sgc := &manman.ServerGameConfig{
-		ServerID:     req.ServerId,
-		GameConfigID: req.GameConfigId,
-		Status:       manman.SGCStatusInactive,
-		PortBindings: portBindingsToJSONB(req.PortBindings),
+		ServerID:      req.ServerId,
+		GameConfigID:  req.GameConfigId,
+		Status:        manman.SGCStatusInactive,
+		PortBindings:  portBindingsToJSONB(req.PortBindings),
+		RestartPolicy: restartPolicy.ToJSONB(),
+		IdleShutdown:  idleShutdown.ToJSONB(),
+
+		AddonUpdatePolicy: req.AddonUpdatePolicy,
 	}
+	sgc.CPUMillicores, sgc.MemoryMB, sgc.PidsLimit, sgc.Ulimits = resourceLimitOverridesToColumns(req.ResourceLimits)
 
-	sgc, err := h.repo.Create(ctx, sgc)
+	// Without a server, pick one; placement also fills in host ports left as 0
+	if req.ServerId == 0 {
+		sgc, chosen, err := h.placer.Deploy(ctx, sgc, req.PortBindings, req.Placement)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.DeployGameConfigResponse{
+			Config:    serverGameConfigToProto(sgc),
+			Placement: chosen,
+		}, nil
+	}
+
+	sgc, err = h.repo.Create(ctx, sgc)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to deploy game config: %v", err)
 	}
@@ -107,6 +169,21 @@ func (h *ServerGameConfigHandler) DeployGameConfig(ctx context.Context, req *pb.
 }
 
 func (h *ServerGameConfigHandler) UpdateServerGameConfig(ctx context.Context, req *pb.UpdateServerGameConfigRequest) (*pb.UpdateServerGameConfigResponse, error) {
+	if err := validateResourceLimits(req.ResourceLimits); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid resource_limits: %v", err)
+	}
+	restartPolicy, err := restartPolicyFromProto(req.RestartPolicy)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid restart_policy: %v", err)
+	}
+	idleShutdown, err := idleShutdownFromProto(req.IdleShutdown)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid idle_shutdown: %v", err)
+	}
+	if req.AddonUpdatePolicy != "" && !manman.IsValidAddonUpdatePolicy(req.AddonUpdatePolicy) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid addon_update_policy %q: must be off, notify or reinstall", req.AddonUpdatePolicy)
+	}
+
 	sgc, err := h.repo.Get(ctx, req.ServerGameConfigId)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "server game config not found: %v", err)
@@ -121,6 +198,18 @@ func (h *ServerGameConfigHandler) UpdateServerGameConfig(ctx context.Context, re
 		if req.Status != "" {
 			sgc.Status = req.Status
 		}
+		if req.ResourceLimits != nil {
+			sgc.CPUMillicores, sgc.MemoryMB, sgc.PidsLimit, sgc.Ulimits = resourceLimitOverridesToColumns(req.ResourceLimits)
+		}
+		if req.RestartPolicy != nil {
+			sgc.RestartPolicy = restartPolicy.ToJSONB()
+		}
+		if req.IdleShutdown != nil {
+			sgc.IdleShutdown = idleShutdown.ToJSONB()
+		}
+		if req.AddonUpdatePolicy != "" {
+			sgc.AddonUpdatePolicy = req.AddonUpdatePolicy
+		}
 	} else {
 		// Update only specified fields
 		for _, path := range req.UpdatePaths {
@@ -129,6 +218,17 @@ func (h *ServerGameConfigHandler) UpdateServerGameConfig(ctx context.Context, re
 				sgc.PortBindings = portBindingsToJSONB(req.PortBindings)
 			case "status":
 				sgc.Status = req.Status
+			case "resource_limits":
+				sgc.CPUMillicores, sgc.MemoryMB, sgc.PidsLimit, sgc.Ulimits = resourceLimitOverridesToColumns(req.ResourceLimits)
+			case "restart_policy":
+				sgc.RestartPolicy = restartPolicy.ToJSONB()
+			case "idle_shutdown":
+				sgc.IdleShutdown = idleShutdown.ToJSONB()
+			case "addon_update_policy":
+				if req.AddonUpdatePolicy == "" {
+					return nil, status.Error(codes.InvalidArgument, "addon_update_policy cannot be cleared")
+				}
+				sgc.AddonUpdatePolicy = req.AddonUpdatePolicy
 			}
 		}
 	}
@@ -160,5 +260,27 @@ func serverGameConfigToProto(sgc *manman.ServerGameConfig) *pb.ServerGameConfig
 		GameConfigId:       sgc.GameConfigID,
 		PortBindings:       jsonbToPortBindings(sgc.PortBindings),
 		Status:             sgc.Status,
+		ResourceLimits:     resourceLimitsFromColumns(sgc.CPUMillicores, sgc.MemoryMB, sgc.PidsLimit, sgc.Ulimits),
+		RestartPolicy:      restartPolicyToProto(sgc.RestartPolicy),
+		IdleShutdown:       idleShutdownToProto(sgc.IdleShutdown),
+		AddonUpdatePolicy:  sgc.AddonUpdatePolicy,
+	}
+}
+
+func imageStatusToProto(s *manman.SGCImageStatus) *pb.ImageStatus {
+	out := &pb.ImageStatus{
+		Image:           s.Image,
+		UpdateAvailable: s.UpdateAvailable(),
+		CheckedAt:       s.CheckedAt.Unix(),
+	}
+	if s.LocalDigest != nil {
+		out.LocalDigest = *s.LocalDigest
+	}
+	if s.RemoteDigest != nil {
+		out.RemoteDigest = *s.RemoteDigest
+	}
+	if s.ErrorMessage != nil {
+		out.Error = *s.ErrorMessage
 	}
+	return out
 }
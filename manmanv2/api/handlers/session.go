@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"log/slog"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -24,6 +27,7 @@ type SessionHandler struct {
 	gcRepo          repository.GameConfigRepository
 	publisher       *CommandPublisher
 	workshopManager workshop.WorkshopManagerInterface
+	placer          *Placer
 }
 
 func NewSessionHandler(repo *repository.Repository, publisher *CommandPublisher, workshopManager workshop.WorkshopManagerInterface) *SessionHandler {
@@ -34,6 +38,7 @@ func NewSessionHandler(repo *repository.Repository, publisher *CommandPublisher,
 		gcRepo:          repo.GameConfigs,
 		publisher:       publisher,
 		workshopManager: workshopManager,
+		placer:          NewPlacer(repo),
 	}
 }
 
@@ -114,19 +119,102 @@ func (h *SessionHandler) GetSession(ctx context.Context, req *pb.GetSessionReque
 	}, nil
 }
 
+// deployedSGC returns the SGC sessions of gameConfigID start on, preferring an inactive
+// one, or nil if the game config isn't deployed. SGCs being or already migrated away are
+// skipped.
+func (h *SessionHandler) deployedSGC(ctx context.Context, gameConfigID int64) (*manman.ServerGameConfig, error) {
+	sgcs, err := h.sgcRepo.List(ctx, nil, 1000, 0)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list server game configs: %v", err)
+	}
+	var found *manman.ServerGameConfig
+	for _, sgc := range sgcs {
+		if sgc.GameConfigID != gameConfigID || sgc.Status == manman.SGCStatusMigrating || sgc.Status == manman.SGCStatusMigrated {
+			continue
+		}
+		switch {
+		case found == nil:
+			found = sgc
+		case (sgc.Status == manman.SGCStatusInactive) != (found.Status == manman.SGCStatusInactive):
+			if sgc.Status == manman.SGCStatusInactive {
+				found = sgc
+			}
+		case sgc.SGCID < found.SGCID:
+			found = sgc
+		}
+	}
+	return found, nil
+}
+
 func (h *SessionHandler) StartSession(ctx context.Context, req *pb.StartSessionRequest) (*pb.StartSessionResponse, error) {
+	sgcID := req.ServerGameConfigId
+
+	overrides, err := sessionOverridesFromRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Starting a game config rather than an SGC uses its existing SGC, deploying it to the
+	// best server first if it has none
+	var placement *pb.PlacementCandidate
+	if sgcID == 0 {
+		if req.GameConfigId == 0 {
+			return nil, status.Error(codes.InvalidArgument, "server_game_config_id or game_config_id is required")
+		}
+		existing, err := h.deployedSGC(ctx, req.GameConfigId)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			sgcID = existing.SGCID
+		} else {
+			sgc, chosen, err := h.placer.Deploy(ctx, &manman.ServerGameConfig{GameConfigID: req.GameConfigId, Status: manman.SGCStatusInactive}, req.PortBindings, req.Placement)
+			if err != nil {
+				return nil, err
+			}
+			log.Printf("placed game config %d on server %d as SGC %d", req.GameConfigId, sgc.ServerID, sgc.SGCID)
+			sgcID = sgc.SGCID
+			placement = chosen
+		}
+	}
+
+	// An automatic restart links the new session to the crashed one
+	var previous *manman.Session
+	if req.PreviousSessionId > 0 {
+		previous, err = h.sessionRepo.Get(ctx, req.PreviousSessionId)
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "previous session not found: %v", err)
+		}
+		if previous.SGCID != sgcID {
+			return nil, status.Errorf(codes.InvalidArgument, "previous session %d belongs to server game config %d", previous.SessionID, previous.SGCID)
+		}
+		// A session that may still have a container can't be replaced this way: it would
+		// bypass the active-session check below and have its ports freed while in use
+		if previous.Status != manman.SessionStatusCrashed && previous.Status != manman.SessionStatusLost {
+			return nil, status.Errorf(codes.FailedPrecondition, "previous session %d is %s; only a crashed or lost session can be restarted", previous.SessionID, previous.Status)
+		}
+		// Restarts come from the host, which doesn't know what the session was started with
+		if overrides.empty() {
+			overrides, err = previousSessionOverrides(ctx, h.repo.ConfigurationPatches, previous)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// Check for existing active sessions
 	allActiveStatuses := []string{
 		manman.SessionStatusPending,
 		manman.SessionStatusStarting,
 		manman.SessionStatusRunning,
+		manman.SessionStatusReady,
 		manman.SessionStatusStopping,
 		manman.SessionStatusCrashed,
 		manman.SessionStatusLost,
 	}
 
 	filters := &repository.SessionFilters{
-		SGCID:        &req.ServerGameConfigId,
+		SGCID:        &sgcID,
 		StatusFilter: allActiveStatuses,
 	}
 
@@ -156,41 +244,115 @@ func (h *SessionHandler) StartSession(ctx context.Context, req *pb.StartSessionR
 
 	if internalForce {
 		// User requested force start: mark other sessions as stopped and deallocate ports
-		log.Printf("Force start requested by user for SGC %d, will invalidate %d active sessions", req.ServerGameConfigId, len(activeSessions))
+		log.Printf("Force start requested by user for SGC %d, will invalidate %d active sessions", sgcID, len(activeSessions))
 	}
 
-	// Create session in database
-	session := &manman.Session{
-		SGCID:  req.ServerGameConfigId,
-		Status: manman.SessionStatusPending,
+	newSession := &manman.Session{SGCID: sgcID}
+	if previous != nil {
+		newSession.PreviousSessionID = &previous.SessionID
+		newSession.RestartAttempt = req.RestartAttempt
+		if newSession.RestartAttempt <= 0 {
+			newSession.RestartAttempt = previous.RestartAttempt + 1
+		}
+		log.Printf("[session %d] automatic restart attempt %d for SGC %d", previous.SessionID, newSession.RestartAttempt, sgcID)
+
+		// The crashed container is gone; free its ports in case the crash hasn't been processed yet
+		if err := h.repo.ServerPorts.DeallocatePortsBySessionID(ctx, previous.SessionID); err != nil {
+			log.Printf("Warning: Failed to deallocate ports for session %d: %v", previous.SessionID, err)
+		}
 	}
 
-	session, err = h.sessionRepo.Create(ctx, session)
+	session, sgc, cmd, err := h.createSession(ctx, newSession, internalForce, overrides)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to create session: %v", err)
+		return nil, err
 	}
 
-	if internalForce {
-		// Mark other sessions as stopped in DB immediately
-		if err := h.sessionRepo.StopOtherSessionsForSGC(ctx, session.SessionID, req.ServerGameConfigId); err != nil {
-			log.Printf("Warning: Failed to invalidate other sessions for SGC %d: %v", req.ServerGameConfigId, err)
+	// Publish start session command to RabbitMQ
+	if h.publisher != nil {
+		// Short timeout: host manager replies immediately on receipt (work runs async).
+		if err := h.publisher.PublishStartSession(ctx, sgc.ServerID, cmd, 30*time.Second); err != nil {
+			log.Printf("Warning: Failed to publish start session command: %v", err)
+			// Don't fail the request - the session is created, operator can manually trigger
 		}
 	}
 
+	return &pb.StartSessionResponse{
+		Session:   sessionToProto(session),
+		Placement: placement,
+	}, nil
+}
+
+// createSession saves session as a pending session of its SGC, allocates its ports and
+// builds the start command for the host manager. Callers are responsible for
+// publishing the command. Lineage fields set on session (restored_from_backup_id,
+// previous_session_id, restart_attempt) are recorded as given, and overrides (which may
+// be nil) are validated against the game's strategies and saved with the session.
+func (h *SessionHandler) createSession(ctx context.Context, session *manman.Session, force bool, overrides *sessionOverrides) (*manman.Session, *manman.ServerGameConfig, map[string]interface{}, error) {
+	sgcID := session.SGCID
+
 	// Fetch ServerGameConfig to get server ID and deployment details
-	sgc, err := h.sgcRepo.Get(ctx, req.ServerGameConfigId)
+	sgc, err := h.sgcRepo.Get(ctx, sgcID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to fetch server game config: %v", err)
+		return nil, nil, nil, status.Errorf(codes.Internal, "failed to fetch server game config: %v", err)
+	}
+
+	switch sgc.Status {
+	case manman.SGCStatusMigrating:
+		return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "server game config %d is being migrated to another server", sgcID)
+	case manman.SGCStatusMigrated:
+		return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "server game config %d has been migrated to another server", sgcID)
 	}
 
 	// Fetch GameConfig to get game details
 	gc, err := h.gcRepo.Get(ctx, sgc.GameConfigID)
 	if err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to fetch game config: %v", err)
+		return nil, nil, nil, status.Errorf(codes.Internal, "failed to fetch game config: %v", err)
+	}
+
+	var patches []*manman.ConfigurationPatch
+	if !overrides.empty() {
+		patches = overrides.patches
+		if len(patches) > 0 {
+			strategies, err := h.repo.ConfigurationStrategies.ListByGame(ctx, gc.GameID)
+			if err != nil {
+				return nil, nil, nil, status.Errorf(codes.Internal, "failed to fetch configuration strategies: %v", err)
+			}
+			if err := checkSessionPatches(patches, strategies); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		session.ImageTag = stringPtr(overrides.imageTag)
+		session.EnvOverrides = mapToJSONB(overrides.env)
+	}
+
+	// Create the session only if the server can fit the container. Sessions of this SGC
+	// aren't counted: they are either blocking the start or being replaced. The host fetches
+	// the session's configuration when it starts the container, so its patches are saved
+	// with it.
+	session.Status = manman.SessionStatusPending
+	session, err = h.sessionRepo.CreateWithinCapacity(ctx, session, sgc.ServerID, manman.ResolveResourceLimits(gc, sgc), patches)
+	if errors.Is(err, repository.ErrInsufficientCapacity) {
+		return nil, nil, nil, status.Errorf(codes.FailedPrecondition, "server %d: %v", sgc.ServerID, err)
+	}
+	if err != nil {
+		return nil, nil, nil, status.Errorf(codes.Internal, "failed to create session: %v", err)
+	}
+
+	if len(patches) > 0 {
+		log.Printf("[session %d] saved %d session patches", session.SessionID, len(patches))
+	}
+
+	if force {
+		// The host stops the SGC's other session before it starts this one and reports it
+		// stopped, as for StopSession; until then it is stopping. Once this session is running
+		// the processor marks any that are left stopped.
+		if err := h.stopOtherLiveSessions(ctx, session.SessionID, sgcID); err != nil {
+			log.Printf("Warning: Failed to mark other sessions of SGC %d stopping: %v", sgcID, err)
+		}
 	}
 
 	// If force=true, deallocate ports held by crashed/stopped sessions for this SGC
-	if internalForce {
+	if force {
 		// Find all terminal sessions (crashed, stopped, lost) for this SGC
 		filters := &repository.SessionFilters{
 			SGCID:        &sgc.SGCID,
@@ -228,8 +390,10 @@ func (h *SessionHandler) StartSession(ctx context.Context, req *pb.StartSessionR
 		if err := h.repo.ServerPorts.AllocateMultiplePorts(ctx, sgc.ServerID, portBindings, session.SessionID); err != nil {
 			// Rollback: mark session as failed
 			session.Status = manman.SessionStatusCrashed
-			h.sessionRepo.Update(ctx, session)
-			return nil, status.Errorf(codes.ResourceExhausted, "failed to allocate ports (ports may be in use by another session): %v", err)
+			if updateErr := h.sessionRepo.Update(ctx, session); updateErr != nil {
+				log.Printf("Warning: Failed to mark session %d crashed: %v", session.SessionID, updateErr)
+			}
+			return nil, nil, nil, status.Errorf(codes.ResourceExhausted, "failed to allocate ports (ports may be in use by another session): %v", err)
 		}
 		log.Printf("[session %d] allocated %d ports on server %d", session.SessionID, len(portBindings), sgc.ServerID)
 	}
@@ -243,20 +407,7 @@ func (h *SessionHandler) StartSession(ctx context.Context, req *pb.StartSessionR
 
 	// Addon downloads are handled blocking by the host manager during session start.
 	// No pre-flight needed here.
-
-	// Publish start session command to RabbitMQ
-	if h.publisher != nil {
-		cmd := buildStartSessionCommand(session, sgc, gc, internalForce, volumes)
-		// Short timeout: host manager replies immediately on receipt (work runs async).
-		if err := h.publisher.PublishStartSession(ctx, sgc.ServerID, cmd, 30*time.Second); err != nil {
-			log.Printf("Warning: Failed to publish start session command: %v", err)
-			// Don't fail the request - the session is created, operator can manually trigger
-		}
-	}
-
-	return &pb.StartSessionResponse{
-		Session: sessionToProto(session),
-	}, nil
+	return session, sgc, buildStartSessionCommand(session, sgc, gc, force, volumes), nil
 }
 
 func (h *SessionHandler) StopSession(ctx context.Context, req *pb.StopSessionRequest) (*pb.StopSessionResponse, error) {
@@ -282,10 +433,21 @@ func (h *SessionHandler) StopSession(ctx context.Context, req *pb.StopSessionReq
 		}
 	}
 
-	// Update session status
+	if err := h.markStopping(ctx, session); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update session: %v", err)
+	}
+
+	return &pb.StopSessionResponse{
+		Session: sessionToProto(session),
+	}, nil
+}
+
+// markStopping records that the host has been asked to stop session and frees its ports
+// for other sessions. The host reports the session stopped once its container is gone.
+func (h *SessionHandler) markStopping(ctx context.Context, session *manman.Session) error {
 	session.Status = manman.SessionStatusStopping
 	if err := h.sessionRepo.Update(ctx, session); err != nil {
-		return nil, status.Errorf(codes.Internal, "failed to update session: %v", err)
+		return err
 	}
 
 	// Deallocate ports for this session to allow other sessions to use them
@@ -295,10 +457,33 @@ func (h *SessionHandler) StopSession(ctx context.Context, req *pb.StopSessionReq
 	} else {
 		log.Printf("[session %d] deallocated ports", session.SessionID)
 	}
+	return nil
+}
 
-	return &pb.StopSessionResponse{
-		Session: sessionToProto(session),
-	}, nil
+// stopOtherLiveSessions marks the SGC's live sessions other than keepSessionID stopping,
+// for when the host is about to stop them itself
+func (h *SessionHandler) stopOtherLiveSessions(ctx context.Context, keepSessionID, sgcID int64) error {
+	live, err := h.sessionRepo.ListWithFilters(ctx, &repository.SessionFilters{
+		SGCID: &sgcID,
+		StatusFilter: []string{
+			manman.SessionStatusPending,
+			manman.SessionStatusStarting,
+			manman.SessionStatusRunning,
+			manman.SessionStatusReady,
+		},
+	}, 100, 0)
+	if err != nil {
+		return err
+	}
+	for _, s := range live {
+		if s.SessionID == keepSessionID {
+			continue
+		}
+		if err := h.markStopping(ctx, s); err != nil {
+			return fmt.Errorf("session %d: %w", s.SessionID, err)
+		}
+	}
+	return nil
 }
 
 func (h *SessionHandler) SendInput(ctx context.Context, req *pb.SendInputRequest) (*pb.SendInputResponse, error) {
@@ -308,7 +493,7 @@ func (h *SessionHandler) SendInput(ctx context.Context, req *pb.SendInputRequest
 	}
 
 	// Only allow sending input to running sessions
-	if session.Status != manman.SessionStatusRunning {
+	if !session.IsLive() {
 		return nil, status.Errorf(codes.FailedPrecondition, "session is not running (status: %s)", session.Status)
 	}
 
@@ -344,14 +529,38 @@ func buildStartSessionCommand(session *manman.Session, sgc *manman.ServerGameCon
 		"command_from_db", gc.Command,
 		"command_array", commandArray)
 
+	// Session overrides win over the game config's env and image tag
+	image := gc.Image
+	if session.ImageTag != nil {
+		image = withImageTag(image, *session.ImageTag)
+	}
+	env := jsonbToMap(gc.EnvTemplate)
+	if overrides := jsonbToMap(session.EnvOverrides); len(overrides) > 0 {
+		if env == nil {
+			env = make(map[string]string, len(overrides))
+		}
+		for k, v := range overrides {
+			env[k] = v
+		}
+	}
+
 	gameConfig := map[string]interface{}{
 		"config_id":     gc.ConfigID,
-		"image":         gc.Image,
+		"image":         image,
 		"args_template": gc.ArgsTemplate,
-		"env_template":  jsonbToMap(gc.EnvTemplate),
+		"env_template":  env,
 		"entrypoint":    jsonbToStringArray(gc.Entrypoint),
 		"command":       commandArray,
 	}
+	if probe := manman.ReadinessProbeFromJSONB(gc.ReadinessProbe); probe != nil {
+		gameConfig["readiness_probe"] = probe
+	}
+	if transport := manman.InputTransportFromJSONB(gc.InputTransport); transport != nil {
+		gameConfig["input_transport"] = transport
+	}
+	if query := manman.StatusQueryFromJSONB(gc.StatusQuery); query != nil {
+		gameConfig["status_query"] = query
+	}
 
 	// Add volume mounts from game_config_volumes
 	var volumeMsgs []map[string]interface{}
@@ -379,13 +588,22 @@ func buildStartSessionCommand(session *manman.Session, sgc *manman.ServerGameCon
 		"port_bindings": convertPortBindingsToMessage(sgc.PortBindings),
 	}
 
-	return map[string]interface{}{
+	cmd := map[string]interface{}{
 		"session_id":         session.SessionID,
 		"sgc_id":             sgc.SGCID,
 		"game_config":        gameConfig,
 		"server_game_config": serverGameConfig,
 		"force":              force,
+		"resource_limits":    manman.ResolveResourceLimits(gc, sgc),
+		"restart_attempt":    session.RestartAttempt,
 	}
+	if policy := manman.RestartPolicyFromJSONB(sgc.RestartPolicy); policy != nil {
+		cmd["restart_policy"] = policy
+	}
+	if rule := manman.IdleShutdownFromJSONB(sgc.IdleShutdown); rule != nil {
+		cmd["player_count_probe"] = rule.PlayerCount
+	}
+	return cmd
 }
 
 // convertPortBindingsToMessage converts JSONB port bindings to RabbitMQ message format
@@ -443,5 +661,36 @@ func sessionToProto(s *manman.Session) *pb.Session {
 		pbSession.ExitCode = int32(*s.ExitCode)
 	}
 
+	if s.RestoredFromBackupID != nil {
+		pbSession.RestoredFromBackupId = *s.RestoredFromBackupID
+	}
+
+	if s.PreviousSessionID != nil {
+		pbSession.PreviousSessionId = *s.PreviousSessionID
+	}
+	pbSession.RestartAttempt = s.RestartAttempt
+
+	if s.RestartAbandonedReason != nil {
+		pbSession.RestartAbandonedReason = *s.RestartAbandonedReason
+	}
+
+	pbSession.PlayerCount = s.PlayerCount
+	if s.IdleSince != nil {
+		pbSession.IdleSince = s.IdleSince.Unix()
+	}
+	if s.MapName != nil {
+		pbSession.MapName = *s.MapName
+	}
+	if s.MaxPlayers != nil {
+		pbSession.MaxPlayers = *s.MaxPlayers
+	}
+	if s.ImageTag != nil {
+		pbSession.ImageTag = *s.ImageTag
+	}
+	for k := range s.EnvOverrides {
+		pbSession.EnvOverrideKeys = append(pbSession.EnvOverrideKeys, k)
+	}
+	sort.Strings(pbSession.EnvOverrideKeys)
+
 	return pbSession
 }
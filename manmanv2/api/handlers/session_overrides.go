@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	// imageTagPattern is the tag grammar of the OCI distribution spec
+	imageTagPattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+)
+
+// sessionOverrides are what a StartSession request changes for that session only: patches
+// saved at the session level, env vars merged over the game config's env_template and an
+// image tag replacing the game config's.
+type sessionOverrides struct {
+	patches  []*manman.ConfigurationPatch
+	env      map[string]string
+	imageTag string
+}
+
+func (o *sessionOverrides) empty() bool {
+	return o == nil || (len(o.patches) == 0 && len(o.env) == 0 && o.imageTag == "")
+}
+
+// sessionOverridesFromRequest checks the parts of a request's overrides that don't depend on
+// the game. Patches are checked against the game's strategies by checkSessionPatches.
+func sessionOverridesFromRequest(req *pb.StartSessionRequest) (*sessionOverrides, error) {
+	o := &sessionOverrides{env: req.Env, imageTag: strings.TrimSpace(req.ImageTag)}
+	if o.imageTag != "" && !imageTagPattern.MatchString(o.imageTag) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid image tag %q", o.imageTag)
+	}
+	for name := range o.env {
+		if !envVarNamePattern.MatchString(name) {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid env var name %q", name)
+		}
+	}
+	patches, err := sessionPatchesFromOverrides(req.Patches)
+	if err != nil {
+		return nil, err
+	}
+	o.patches = patches
+	return o, nil
+}
+
+// sessionPatchesFromOverrides turns requested overrides into unsaved session-level patches
+func sessionPatchesFromOverrides(overrides []*pb.SessionPatchOverride) ([]*manman.ConfigurationPatch, error) {
+	var patches []*manman.ConfigurationPatch
+	for i, p := range overrides {
+		if p.StrategyId == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "patch %d: strategy_id is required", i)
+		}
+		if strings.TrimSpace(p.PatchContent) == "" {
+			return nil, status.Errorf(codes.InvalidArgument, "patch %d: patch_content is required", i)
+		}
+		if p.PatchFormat != "" && !isValidPatchFormat(p.PatchFormat) {
+			return nil, status.Errorf(codes.InvalidArgument, "patch %d: unknown patch_format %q", i, p.PatchFormat)
+		}
+		patches = append(patches, &manman.ConfigurationPatch{
+			StrategyID:   p.StrategyId,
+			PatchLevel:   manman.PatchLevelSession,
+			PatchContent: stringPtr(p.PatchContent),
+			PatchFormat:  p.PatchFormat,
+			PatchOrder:   int(p.PatchOrder),
+		})
+	}
+	return patches, nil
+}
+
+// previousSessionOverrides returns the overrides a crashed session was started with, so an
+// automatic restart brings back the same server rather than the SGC's defaults.
+func previousSessionOverrides(ctx context.Context, patchRepo repository.ConfigurationPatchRepository, previous *manman.Session) (*sessionOverrides, error) {
+	o := &sessionOverrides{env: jsonbToMap(previous.EnvOverrides)}
+	if previous.ImageTag != nil {
+		o.imageTag = *previous.ImageTag
+	}
+	level := manman.PatchLevelSession
+	patches, err := patchRepo.List(ctx, nil, &level, &previous.SessionID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list patches of session %d: %v", previous.SessionID, err)
+	}
+	for _, p := range patches {
+		o.patches = append(o.patches, &manman.ConfigurationPatch{
+			StrategyID:   p.StrategyID,
+			PatchLevel:   manman.PatchLevelSession,
+			PatchContent: p.PatchContent,
+			PatchFormat:  p.PatchFormat,
+			VolumeID:     p.VolumeID,
+			PathOverride: p.PathOverride,
+			PatchOrder:   p.PatchOrder,
+		})
+	}
+	return o, nil
+}
+
+// checkSessionPatches rejects patches for strategies the game doesn't have, for volume
+// strategies, or that can't be applied to their strategy's base template. Patches that fail
+// to apply are otherwise only skipped when the host renders the configuration, which would
+// start the session without the override the caller asked for.
+func checkSessionPatches(patches []*manman.ConfigurationPatch, strategies []*manman.ConfigurationStrategy) error {
+	byID := make(map[int64]*manman.ConfigurationStrategy, len(strategies))
+	for _, s := range strategies {
+		byID[s.StrategyID] = s
+	}
+	for _, p := range patches {
+		strategy, ok := byID[p.StrategyID]
+		if !ok {
+			return status.Errorf(codes.InvalidArgument, "strategy %d is not a configuration strategy of this game", p.StrategyID)
+		}
+		if strategy.StrategyType == manman.StrategyTypeVolume {
+			return status.Errorf(codes.InvalidArgument, "strategy %q is a volume and can't be patched", strategy.Name)
+		}
+		base := ""
+		if strategy.BaseTemplate != nil {
+			base = *strategy.BaseTemplate
+		}
+		_, layers := layerConfigurationPatches(strategy.StrategyType, base, []*manman.ConfigurationPatch{p})
+		for _, layer := range layers {
+			if layer.Error != "" {
+				return status.Errorf(codes.InvalidArgument, "patch for strategy %q can't be applied: %s", strategy.Name, layer.Error)
+			}
+		}
+	}
+	return nil
+}
+
+// withImageTag replaces the tag or digest of image with tag.
+func withImageTag(image, tag string) string {
+	if tag == "" {
+		return image
+	}
+	name := image
+	if i := strings.Index(name, "@"); i >= 0 {
+		name = name[:i]
+	}
+	// A colon before the last slash is a registry port, not a tag
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name = name[:i]
+	}
+	return name + ":" + tag
+}
+
+// isValidPatchFormat reports whether f is a patch format the layering engine knows.
+func isValidPatchFormat(f string) bool {
+	switch f {
+	case manman.PatchFormatTemplate, manman.PatchFormatJSONMergePatch, manman.PatchFormatJSONPatch,
+		manman.PatchFormatYAMLMerge, manman.PatchFormatProperties:
+		return true
+	}
+	return false
+}
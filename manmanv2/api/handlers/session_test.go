@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/whale-net/everything/manmanv2/models"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	pb "github.com/whale-net/everything/manmanv2/protos"
@@ -16,6 +19,11 @@ type MockSessionRepo struct {
 	repository.SessionRepository
 	sessions []*manman.Session
 	created  []*manman.Session
+
+	overcommit string                // returned by CreateWithinCapacity when set
+	limits     manman.ResourceLimits // last limits checked by CreateWithinCapacity
+	patchRepo  *MockPatchRepo        // receives the patches CreateWithinCapacity saves
+	patchErr   error                 // returned by CreateWithinCapacity, saving nothing, when set
 }
 
 func (m *MockSessionRepo) ListWithFilters(ctx context.Context, filters *repository.SessionFilters, limit, offset int) ([]*manman.Session, error) {
@@ -48,6 +56,35 @@ func (m *MockSessionRepo) Create(ctx context.Context, s *manman.Session) (*manma
 	return s, nil
 }
 
+func (m *MockSessionRepo) CreateWithinCapacity(ctx context.Context, s *manman.Session, serverID int64, limits manman.ResourceLimits, patches []*manman.ConfigurationPatch) (*manman.Session, error) {
+	m.limits = limits
+	if m.overcommit != "" {
+		return nil, fmt.Errorf("%w: %s", repository.ErrInsufficientCapacity, m.overcommit)
+	}
+	if len(patches) > 0 && m.patchErr != nil {
+		return nil, m.patchErr
+	}
+	s, _ = m.Create(ctx, s)
+	for _, p := range patches {
+		p.EntityID = s.SessionID
+		m.patchRepo.Create(ctx, p)
+	}
+	return s, nil
+}
+
+func (m *MockSessionRepo) Get(ctx context.Context, id int64) (*manman.Session, error) {
+	for _, s := range m.sessions {
+		if s.SessionID == id {
+			return s, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *MockSessionRepo) Update(ctx context.Context, s *manman.Session) error {
+	return nil
+}
+
 func (m *MockSessionRepo) StopOtherSessionsForSGC(ctx context.Context, sessionID int64, sgcID int64) error {
 	for _, s := range m.sessions {
 		if s.SGCID == sgcID && s.SessionID != sessionID {
@@ -57,13 +94,75 @@ func (m *MockSessionRepo) StopOtherSessionsForSGC(ctx context.Context, sessionID
 	return nil
 }
 
-// MockSGCRepo
+// MockServerRepo serves a fixed set of servers
+type MockServerRepo struct {
+	repository.ServerRepository
+	servers []*manman.Server
+}
+
+func (m *MockServerRepo) Get(ctx context.Context, id int64) (*manman.Server, error) {
+	for _, s := range m.servers {
+		if s.ServerID == id {
+			return s, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *MockServerRepo) List(ctx context.Context, limit, offset int) ([]*manman.Server, error) {
+	return m.servers, nil
+}
+
+// MockSGCRepo holds SGCs by ID and records creates. Without any SGCs, Get returns one of
+// game config 1 on server 1 for every ID. portsTaken makes that many CreateReservingPorts
+// calls fail as if a concurrent deployment took the ports.
 type MockSGCRepo struct {
 	repository.ServerGameConfigRepository
+	sgcs       map[int64]*manman.ServerGameConfig
+	created    []*manman.ServerGameConfig
+	portsTaken int
 }
 
 func (m *MockSGCRepo) Get(ctx context.Context, id int64) (*manman.ServerGameConfig, error) {
-	return &manman.ServerGameConfig{SGCID: id, ServerID: 1, GameConfigID: 1}, nil
+	if m.sgcs == nil {
+		return &manman.ServerGameConfig{SGCID: id, ServerID: 1, GameConfigID: 1}, nil
+	}
+	if sgc, ok := m.sgcs[id]; ok {
+		return sgc, nil
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *MockSGCRepo) List(ctx context.Context, serverID *int64, limit, offset int) ([]*manman.ServerGameConfig, error) {
+	var result []*manman.ServerGameConfig
+	for _, sgc := range m.sgcs {
+		if serverID == nil || sgc.ServerID == *serverID {
+			result = append(result, sgc)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockSGCRepo) Create(ctx context.Context, sgc *manman.ServerGameConfig) (*manman.ServerGameConfig, error) {
+	sgc.SGCID = int64(1000 + len(m.created))
+	m.created = append(m.created, sgc)
+	if m.sgcs != nil {
+		m.sgcs[sgc.SGCID] = sgc
+	}
+	return sgc, nil
+}
+
+func (m *MockSGCRepo) CreateReservingPorts(ctx context.Context, sgc *manman.ServerGameConfig, reserve []*manman.PortBinding) (*manman.ServerGameConfig, error) {
+	if m.portsTaken > 0 {
+		m.portsTaken--
+		return nil, repository.ErrPortsTaken
+	}
+	return m.Create(ctx, sgc)
+}
+
+func (m *MockSGCRepo) Update(ctx context.Context, sgc *manman.ServerGameConfig) error {
+	m.sgcs[sgc.SGCID] = sgc
+	return nil
 }
 
 // MockGCRepo
@@ -78,15 +177,71 @@ func (m *MockGCRepo) Get(ctx context.Context, id int64) (*manman.GameConfig, err
 // MockStrategyRepo
 type MockStrategyRepo struct {
 	repository.ConfigurationStrategyRepository
+	strategies []*manman.ConfigurationStrategy
 }
 
 func (m *MockStrategyRepo) ListByGame(ctx context.Context, gameID int64) ([]*manman.ConfigurationStrategy, error) {
-	return []*manman.ConfigurationStrategy{}, nil
+	return m.strategies, nil
 }
 
-// MockServerPortRepo
+// MockPatchRepo keeps created patches in memory
+type MockPatchRepo struct {
+	repository.ConfigurationPatchRepository
+	patches []*manman.ConfigurationPatch
+}
+
+func (m *MockPatchRepo) Create(ctx context.Context, p *manman.ConfigurationPatch) (*manman.ConfigurationPatch, error) {
+	p.PatchID = int64(len(m.patches) + 1)
+	m.patches = append(m.patches, p)
+	return p, nil
+}
+
+func (m *MockPatchRepo) ListByStrategyAndEntity(ctx context.Context, strategyID int64, patchLevel string, entityID int64) ([]*manman.ConfigurationPatch, error) {
+	return m.List(ctx, &strategyID, &patchLevel, &entityID)
+}
+
+func (m *MockPatchRepo) List(ctx context.Context, strategyID *int64, patchLevel *string, entityID *int64) ([]*manman.ConfigurationPatch, error) {
+	var result []*manman.ConfigurationPatch
+	for _, p := range m.patches {
+		if (strategyID == nil || p.StrategyID == *strategyID) &&
+			(patchLevel == nil || p.PatchLevel == *patchLevel) &&
+			(entityID == nil || p.EntityID == *entityID) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// MockServerPortRepo treats allocated as taken and everything else as free
 type MockServerPortRepo struct {
 	repository.ServerPortRepository
+	allocated []*manman.ServerPort
+}
+
+func (m *MockServerPortRepo) ListAllocatedPorts(ctx context.Context, serverID int64) ([]*manman.ServerPort, error) {
+	var result []*manman.ServerPort
+	for _, p := range m.allocated {
+		if p.ServerID == serverID {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func (m *MockServerPortRepo) GetAvailablePortsInRange(ctx context.Context, serverID int64, protocol string, startPort, endPort, limit int) ([]int, error) {
+	used := make(map[int]bool)
+	for _, p := range m.allocated {
+		if p.ServerID == serverID && p.Protocol == protocol {
+			used[p.Port] = true
+		}
+	}
+	var free []int
+	for port := startPort; port <= endPort && len(free) < limit; port++ {
+		if !used[port] {
+			free = append(free, port)
+		}
+	}
+	return free, nil
 }
 
 func (m *MockServerPortRepo) DeallocatePortsBySessionID(ctx context.Context, sessionID int64) error {
@@ -102,6 +257,24 @@ func (m *MockGameConfigVolumeRepo) ListByGameConfig(ctx context.Context, configI
 	return []*manman.GameConfigVolume{}, nil
 }
 
+// MockServerCapabilityRepo returns the server's entry in caps, else cap, or
+// pgx.ErrNoRows when neither is set
+type MockServerCapabilityRepo struct {
+	repository.ServerCapabilityRepository
+	cap  *manman.ServerCapability
+	caps map[int64]*manman.ServerCapability
+}
+
+func (m *MockServerCapabilityRepo) Get(ctx context.Context, serverID int64) (*manman.ServerCapability, error) {
+	if c, ok := m.caps[serverID]; ok {
+		return c, nil
+	}
+	if m.cap == nil {
+		return nil, pgx.ErrNoRows
+	}
+	return m.cap, nil
+}
+
 func TestStartSessionLifecycle(t *testing.T) {
 	sessionRepo := &MockSessionRepo{}
 	sgcRepo := &MockSGCRepo{}
@@ -109,14 +282,18 @@ func TestStartSessionLifecycle(t *testing.T) {
 	strategyRepo := &MockStrategyRepo{}
 	serverPortRepo := &MockServerPortRepo{}
 	volumeRepo := &MockGameConfigVolumeRepo{}
+	patchRepo := &MockPatchRepo{}
+	sessionRepo.patchRepo = patchRepo
 
 	repo := &repository.Repository{
 		Sessions:                sessionRepo,
 		ServerGameConfigs:       sgcRepo,
 		GameConfigs:             gcRepo,
 		ConfigurationStrategies: strategyRepo,
+		ConfigurationPatches:    patchRepo,
 		ServerPorts:             serverPortRepo,
 		GameConfigVolumes:       volumeRepo,
+		ServerCapabilities:      &MockServerCapabilityRepo{},
 	}
 
 	h := &SessionHandler{
@@ -175,6 +352,10 @@ func TestStartSessionLifecycle(t *testing.T) {
 		if len(sessionRepo.created) != 1 {
 			t.Fatal("Expected new session to be created")
 		}
+		// The host reports the old session stopped once it has stopped it
+		if got := sessionRepo.sessions[0].Status; got != manman.SessionStatusStopping {
+			t.Errorf("Expected old session to be stopping, got %s", got)
+		}
 	})
 
 	t.Run("Happy path: crashed session exists, force=false", func(t *testing.T) {
@@ -193,4 +374,209 @@ func TestStartSessionLifecycle(t *testing.T) {
 			t.Errorf("Expected status pending, got %s", resp.Session.Status)
 		}
 	})
+	t.Run("Automatic restart: links the crashed session", func(t *testing.T) {
+		previousID := int64(10)
+		sessionRepo.sessions = []*manman.Session{
+			{SessionID: previousID, SGCID: sgcID, Status: manman.SessionStatusCrashed, RestartAttempt: 2},
+		}
+		sessionRepo.created = nil
+
+		req := &pb.StartSessionRequest{ServerGameConfigId: sgcID, PreviousSessionId: previousID}
+		resp, err := h.StartSession(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.Session.PreviousSessionId != previousID || resp.Session.RestartAttempt != 3 {
+			t.Errorf("Expected restart of session %d attempt 3, got %d attempt %d",
+				previousID, resp.Session.PreviousSessionId, resp.Session.RestartAttempt)
+		}
+	})
+
+	t.Run("Automatic restart: previous session still running", func(t *testing.T) {
+		for _, st := range []string{manman.SessionStatusRunning, manman.SessionStatusReady} {
+			sessionRepo.sessions = []*manman.Session{
+				{SessionID: 10, SGCID: sgcID, Status: st},
+			}
+			sessionRepo.created = nil
+
+			req := &pb.StartSessionRequest{ServerGameConfigId: sgcID, PreviousSessionId: 10}
+			_, err := h.StartSession(context.Background(), req)
+			if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+				t.Errorf("Expected FailedPrecondition error, got %v", err)
+			}
+			if len(sessionRepo.created) != 0 {
+				t.Errorf("Expected no session to be created while session 10 is %s", st)
+			}
+		}
+	})
+
+	t.Run("Automatic restart: superseded by another active session", func(t *testing.T) {
+		sessionRepo.sessions = []*manman.Session{
+			{SessionID: 10, SGCID: sgcID, Status: manman.SessionStatusCrashed},
+			{SessionID: 11, SGCID: sgcID, Status: manman.SessionStatusRunning},
+		}
+
+		req := &pb.StartSessionRequest{ServerGameConfigId: sgcID, PreviousSessionId: 10}
+		_, err := h.StartSession(context.Background(), req)
+		if st, ok := status.FromError(err); !ok || st.Code() != codes.FailedPrecondition {
+			t.Errorf("Expected FailedPrecondition error, got %v", err)
+		}
+	})
+
+	t.Run("Automatic restart: previous session of another SGC", func(t *testing.T) {
+		sessionRepo.sessions = []*manman.Session{
+			{SessionID: 10, SGCID: sgcID + 1, Status: manman.SessionStatusCrashed},
+		}
+
+		req := &pb.StartSessionRequest{ServerGameConfigId: sgcID, PreviousSessionId: 10}
+		_, err := h.StartSession(context.Background(), req)
+		if st, ok := status.FromError(err); !ok || st.Code() != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument error, got %v", err)
+		}
+	})
+
+	t.Run("Overrides: saved with the session", func(t *testing.T) {
+		strategyRepo.strategies = []*manman.ConfigurationStrategy{
+			{StrategyID: 7, Name: "Server Properties", StrategyType: manman.StrategyTypeFileProperties},
+		}
+		sessionRepo.sessions = nil
+		patchRepo.patches = nil
+
+		req := &pb.StartSessionRequest{
+			ServerGameConfigId: sgcID,
+			Patches:            []*pb.SessionPatchOverride{{StrategyId: 7, PatchContent: "level-name=test"}},
+			Env:                map[string]string{"DIFFICULTY": "peaceful"},
+			ImageTag:           "java17",
+		}
+		resp, err := h.StartSession(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if resp.Session.ImageTag != "java17" || len(resp.Session.EnvOverrideKeys) != 1 || resp.Session.EnvOverrideKeys[0] != "DIFFICULTY" {
+			t.Errorf("Expected overrides on the session, got image tag %q env keys %v", resp.Session.ImageTag, resp.Session.EnvOverrideKeys)
+		}
+		if saved := sessionRepo.created[len(sessionRepo.created)-1]; saved.EnvOverrides["DIFFICULTY"] != "peaceful" {
+			t.Errorf("Expected env override values saved with the session, got %v", saved.EnvOverrides)
+		}
+		if len(patchRepo.patches) != 1 {
+			t.Fatalf("Expected 1 saved patch, got %d", len(patchRepo.patches))
+		}
+		if p := patchRepo.patches[0]; p.PatchLevel != manman.PatchLevelSession || p.EntityID != resp.Session.SessionId {
+			t.Errorf("Expected a session patch of session %d, got %s patch of %d", resp.Session.SessionId, p.PatchLevel, p.EntityID)
+		}
+	})
+
+	t.Run("Overrides: nothing saved when a patch can't be", func(t *testing.T) {
+		strategyRepo.strategies = []*manman.ConfigurationStrategy{
+			{StrategyID: 7, Name: "Server Properties", StrategyType: manman.StrategyTypeFileProperties},
+		}
+		sessionRepo.sessions = nil
+		sessionRepo.created = nil
+		patchRepo.patches = nil
+		sessionRepo.patchErr = errors.New("connection reset")
+		defer func() { sessionRepo.patchErr = nil }()
+
+		req := &pb.StartSessionRequest{
+			ServerGameConfigId: sgcID,
+			Patches:            []*pb.SessionPatchOverride{{StrategyId: 7, PatchContent: "level-name=test"}},
+		}
+		_, err := h.StartSession(context.Background(), req)
+		if st, ok := status.FromError(err); !ok || st.Code() != codes.Internal {
+			t.Errorf("Expected Internal error, got %v", err)
+		}
+		if len(sessionRepo.created) != 0 || len(patchRepo.patches) != 0 {
+			t.Errorf("Expected nothing to be saved, got %d sessions and %d patches", len(sessionRepo.created), len(patchRepo.patches))
+		}
+	})
+
+	t.Run("Overrides: invalid overrides rejected", func(t *testing.T) {
+		strategyRepo.strategies = []*manman.ConfigurationStrategy{
+			{StrategyID: 7, Name: "Server Properties", StrategyType: manman.StrategyTypeFileProperties},
+			{StrategyID: 8, Name: "Data", StrategyType: manman.StrategyTypeVolume},
+			{StrategyID: 9, Name: "Settings", StrategyType: manman.StrategyTypeFileJSON},
+		}
+		tests := map[string]*pb.StartSessionRequest{
+			"unknown strategy": {Patches: []*pb.SessionPatchOverride{{StrategyId: 99, PatchContent: "a=b"}}},
+			"volume strategy":  {Patches: []*pb.SessionPatchOverride{{StrategyId: 8, PatchContent: "a=b"}}},
+			"bad patch":        {Patches: []*pb.SessionPatchOverride{{StrategyId: 9, PatchContent: "{not json"}}},
+			"empty patch":      {Patches: []*pb.SessionPatchOverride{{StrategyId: 7}}},
+			"bad format":       {Patches: []*pb.SessionPatchOverride{{StrategyId: 7, PatchContent: "a=b", PatchFormat: "xml"}}},
+			"bad env name":     {Env: map[string]string{"NOT-VALID": "1"}},
+			"bad image tag":    {ImageTag: "latest:v2"},
+		}
+		for name, req := range tests {
+			sessionRepo.sessions = nil
+			sessionRepo.created = nil
+			patchRepo.patches = nil
+			req.ServerGameConfigId = sgcID
+
+			_, err := h.StartSession(context.Background(), req)
+			if st, ok := status.FromError(err); !ok || st.Code() != codes.InvalidArgument {
+				t.Errorf("%s: expected InvalidArgument error, got %v", name, err)
+			}
+			if len(sessionRepo.created) != 0 || len(patchRepo.patches) != 0 {
+				t.Errorf("%s: expected nothing to be saved", name)
+			}
+		}
+	})
+
+	t.Run("Automatic restart: keeps the crashed session's overrides", func(t *testing.T) {
+		strategyRepo.strategies = []*manman.ConfigurationStrategy{
+			{StrategyID: 7, Name: "Server Properties", StrategyType: manman.StrategyTypeFileProperties},
+		}
+		tag := "java17"
+		sessionRepo.sessions = []*manman.Session{
+			{SessionID: 10, SGCID: sgcID, Status: manman.SessionStatusCrashed, ImageTag: &tag, EnvOverrides: manman.JSONB{"DIFFICULTY": "peaceful"}},
+		}
+		content := "level-name=test"
+		patchRepo.patches = []*manman.ConfigurationPatch{
+			{PatchID: 1, StrategyID: 7, PatchLevel: manman.PatchLevelSession, EntityID: 10, PatchContent: &content},
+		}
+
+		req := &pb.StartSessionRequest{ServerGameConfigId: sgcID, PreviousSessionId: 10}
+		resp, err := h.StartSession(context.Background(), req)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if saved := sessionRepo.created[len(sessionRepo.created)-1]; resp.Session.ImageTag != tag || saved.EnvOverrides["DIFFICULTY"] != "peaceful" {
+			t.Errorf("Expected the previous session's overrides, got image tag %q env %v", resp.Session.ImageTag, saved.EnvOverrides)
+		}
+		if len(patchRepo.patches) != 2 || patchRepo.patches[1].EntityID != resp.Session.SessionId {
+			t.Errorf("Expected the previous session's patch copied to session %d, got %d patches", resp.Session.SessionId, len(patchRepo.patches))
+		}
+	})
+}
+
+func TestBuildStartSessionCommandOverrides(t *testing.T) {
+	tag := "java17"
+	session := &manman.Session{SessionID: 1, ImageTag: &tag, EnvOverrides: manman.JSONB{"DIFFICULTY": "peaceful"}}
+	gc := &manman.GameConfig{ConfigID: 1, Image: "itzg/minecraft-server:latest", EnvTemplate: manman.JSONB{"EULA": "TRUE", "DIFFICULTY": "hard"}}
+
+	cmd := buildStartSessionCommand(session, &manman.ServerGameConfig{SGCID: 1}, gc, false, nil)
+	gameConfig := cmd["game_config"].(map[string]interface{})
+	if gameConfig["image"] != "itzg/minecraft-server:java17" {
+		t.Errorf("Expected image with the session's tag, got %v", gameConfig["image"])
+	}
+	env := gameConfig["env_template"].(map[string]string)
+	if env["EULA"] != "TRUE" || env["DIFFICULTY"] != "peaceful" {
+		t.Errorf("Expected session env merged over the game config's, got %v", env)
+	}
+}
+
+func TestWithImageTag(t *testing.T) {
+	tests := []struct {
+		image, tag, want string
+	}{
+		{"itzg/minecraft-server", "java17", "itzg/minecraft-server:java17"},
+		{"itzg/minecraft-server:latest", "java17", "itzg/minecraft-server:java17"},
+		{"registry.local:5000/game", "v2", "registry.local:5000/game:v2"},
+		{"registry.local:5000/game:v1", "v2", "registry.local:5000/game:v2"},
+		{"game@sha256:abcd", "v2", "game:v2"},
+		{"game:v1", "", "game:v1"},
+	}
+	for _, tt := range tests {
+		if got := withImageTag(tt.image, tt.tag); got != tt.want {
+			t.Errorf("withImageTag(%q, %q) = %q, want %q", tt.image, tt.tag, got, tt.want)
+		}
+	}
 }
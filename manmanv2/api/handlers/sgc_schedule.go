@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// SGCScheduleHandler handles SGCSchedule CRUD. The schedules are run by the processor.
+type SGCScheduleHandler struct {
+	scheduleRepo     repository.SGCScheduleRepository
+	sgcRepo          repository.ServerGameConfigRepository
+	backupConfigRepo repository.BackupConfigRepository
+}
+
+func NewSGCScheduleHandler(
+	scheduleRepo repository.SGCScheduleRepository,
+	sgcRepo repository.ServerGameConfigRepository,
+	backupConfigRepo repository.BackupConfigRepository,
+) *SGCScheduleHandler {
+	return &SGCScheduleHandler{
+		scheduleRepo:     scheduleRepo,
+		sgcRepo:          sgcRepo,
+		backupConfigRepo: backupConfigRepo,
+	}
+}
+
+func (h *SGCScheduleHandler) CreateSGCSchedule(ctx context.Context, req *pb.CreateSGCScheduleRequest) (*pb.CreateSGCScheduleResponse, error) {
+	if _, err := h.sgcRepo.Get(ctx, req.ServerGameConfigId); err != nil {
+		return nil, status.Errorf(codes.NotFound, "server game config not found: %v", err)
+	}
+
+	schedule := &manman.SGCSchedule{
+		SGCID:   req.ServerGameConfigId,
+		Enabled: req.Enabled,
+	}
+	if err := h.applySchedule(ctx, schedule, req.StartCron, req.StopCron, req.Timezone, req.BackupConfigId); err != nil {
+		return nil, err
+	}
+
+	schedule, err := h.scheduleRepo.Create(ctx, schedule)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create schedule: %v", err)
+	}
+	return &pb.CreateSGCScheduleResponse{Schedule: sgcScheduleToProto(schedule)}, nil
+}
+
+func (h *SGCScheduleHandler) ListSGCSchedules(ctx context.Context, req *pb.ListSGCSchedulesRequest) (*pb.ListSGCSchedulesResponse, error) {
+	schedules, err := h.scheduleRepo.List(ctx, req.ServerGameConfigId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list schedules: %v", err)
+	}
+	pbSchedules := make([]*pb.SGCSchedule, len(schedules))
+	for i, s := range schedules {
+		pbSchedules[i] = sgcScheduleToProto(s)
+	}
+	return &pb.ListSGCSchedulesResponse{Schedules: pbSchedules}, nil
+}
+
+func (h *SGCScheduleHandler) UpdateSGCSchedule(ctx context.Context, req *pb.UpdateSGCScheduleRequest) (*pb.UpdateSGCScheduleResponse, error) {
+	schedule, err := h.scheduleRepo.Get(ctx, req.ScheduleId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "schedule not found: %v", err)
+	}
+
+	schedule.Enabled = req.Enabled
+	if err := h.applySchedule(ctx, schedule, req.StartCron, req.StopCron, req.Timezone, req.BackupConfigId); err != nil {
+		return nil, err
+	}
+
+	if err := h.scheduleRepo.Update(ctx, schedule); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update schedule: %v", err)
+	}
+	return &pb.UpdateSGCScheduleResponse{Schedule: sgcScheduleToProto(schedule)}, nil
+}
+
+func (h *SGCScheduleHandler) DeleteSGCSchedule(ctx context.Context, req *pb.DeleteSGCScheduleRequest) (*pb.DeleteSGCScheduleResponse, error) {
+	if err := h.scheduleRepo.Delete(ctx, req.ScheduleId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete schedule: %v", err)
+	}
+	return &pb.DeleteSGCScheduleResponse{}, nil
+}
+
+// applySchedule validates and copies the request's crons, timezone and pre-stop backup onto schedule
+func (h *SGCScheduleHandler) applySchedule(ctx context.Context, schedule *manman.SGCSchedule, startCron, stopCron, timezone string, backupConfigID int64) error {
+	schedule.StartCron = stringPtr(startCron)
+	schedule.StopCron = stringPtr(stopCron)
+	schedule.Timezone = timezone
+	if schedule.Timezone == "" {
+		schedule.Timezone = "UTC"
+	}
+	if err := schedule.Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid schedule: %v", err)
+	}
+
+	schedule.BackupConfigID = nil
+	if backupConfigID != 0 {
+		if schedule.StopCron == nil {
+			return status.Error(codes.InvalidArgument, "backup_config_id requires a stop_cron")
+		}
+		if _, err := h.backupConfigRepo.Get(ctx, backupConfigID); err != nil {
+			return status.Errorf(codes.InvalidArgument, "backup config %d not found: %v", backupConfigID, err)
+		}
+		schedule.BackupConfigID = &backupConfigID
+	}
+	return nil
+}
+
+func sgcScheduleToProto(s *manman.SGCSchedule) *pb.SGCSchedule {
+	pbSchedule := &pb.SGCSchedule{
+		ScheduleId:         s.ScheduleID,
+		ServerGameConfigId: s.SGCID,
+		Timezone:           s.Timezone,
+		Enabled:            s.Enabled,
+		CreatedAt:          s.CreatedAt.Unix(),
+		UpdatedAt:          s.UpdatedAt.Unix(),
+	}
+	if s.StartCron != nil {
+		pbSchedule.StartCron = *s.StartCron
+	}
+	if s.StopCron != nil {
+		pbSchedule.StopCron = *s.StopCron
+	}
+	if s.BackupConfigID != nil {
+		pbSchedule.BackupConfigId = *s.BackupConfigID
+	}
+	if s.LastStartAt != nil {
+		pbSchedule.LastStartAt = s.LastStartAt.Unix()
+	}
+	if s.LastStopAt != nil {
+		pbSchedule.LastStopAt = s.LastStopAt.Unix()
+	}
+	if s.Enabled {
+		if action, at := s.NextAction(time.Now()); action != "" {
+			pbSchedule.NextAction = action
+			pbSchedule.NextActionAt = at.Unix()
+		}
+	}
+	return pbSchedule
+}
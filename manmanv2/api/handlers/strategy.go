@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"context"
+	"log"
 
 	"github.com/whale-net/everything/manmanv2/models"
 	"github.com/whale-net/everything/manmanv2/api/repository"
@@ -125,8 +126,14 @@ func (h *ConfigurationStrategyHandler) DeleteConfigurationStrategy(ctx context.C
 }
 
 func (h *ConfigurationStrategyHandler) GetSessionConfiguration(ctx context.Context, req *pb.GetSessionConfigurationRequest, fullRepo *repository.Repository) (*pb.GetSessionConfigurationResponse, error) {
+	return h.renderSessionConfiguration(ctx, req.SessionId, nil, fullRepo)
+}
+
+// renderSessionConfiguration renders every strategy of a session's game, layering extra
+// after the session's own patches
+func (h *ConfigurationStrategyHandler) renderSessionConfiguration(ctx context.Context, sessionID int64, extra []*manman.ConfigurationPatch, fullRepo *repository.Repository) (*pb.GetSessionConfigurationResponse, error) {
 	// Get session to find game/config IDs
-	session, err := fullRepo.Sessions.Get(ctx, req.SessionId)
+	session, err := fullRepo.Sessions.Get(ctx, sessionID)
 	if err != nil {
 		return nil, status.Errorf(codes.NotFound, "session not found: %v", err)
 	}
@@ -148,6 +155,9 @@ func (h *ConfigurationStrategyHandler) GetSessionConfiguration(ctx context.Conte
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to fetch strategies: %v", err)
 	}
+	if err := checkSessionPatches(extra, strategies); err != nil {
+		return nil, err
+	}
 
 	// Render each strategy
 	var renderedConfigs []*pb.RenderedConfiguration
@@ -158,6 +168,7 @@ func (h *ConfigurationStrategyHandler) GetSessionConfiguration(ctx context.Conte
 		}
 
 		rendered := &pb.RenderedConfiguration{
+			StrategyId:      strategy.StrategyID,
 			StrategyName:    strategy.Name,
 			StrategyType:    strategy.StrategyType,
 			RenderedContent: "",
@@ -173,27 +184,39 @@ func (h *ConfigurationStrategyHandler) GetSessionConfiguration(ctx context.Conte
 			rendered.BaseContent = *strategy.BaseTemplate
 		}
 
-		// Cascade patches: GameConfig → ServerGameConfig
+		// Cascade patches: GameConfig → ServerGameConfig → Session
 		// 1. Get all game_config level patches (ordered by patch_order ASC, patch_id ASC)
-		gcPatches, err := fullRepo.ConfigurationPatches.ListByStrategyAndEntity(ctx, strategy.StrategyID, "game_config", gc.ConfigID)
+		gcPatches, err := fullRepo.ConfigurationPatches.ListByStrategyAndEntity(ctx, strategy.StrategyID, manman.PatchLevelGameConfig, gc.ConfigID)
 		if err != nil {
 			gcPatches = nil
 		}
 
 		// 2. Get all server_game_config level patches (override game_config)
-		sgcPatches, err := fullRepo.ConfigurationPatches.ListByStrategyAndEntity(ctx, strategy.StrategyID, "server_game_config", sgc.SGCID)
+		sgcPatches, err := fullRepo.ConfigurationPatches.ListByStrategyAndEntity(ctx, strategy.StrategyID, manman.PatchLevelServerGameConfig, sgc.SGCID)
 		if err != nil {
 			sgcPatches = nil
 		}
 
-		// Concatenate all patches in cascade order: GC patches first, then SGC patches
-		// Each group is already ordered by patch_order; SGC patches override GC patches
-		allPatches := append(gcPatches, sgcPatches...)
-		patchContent := joinPatchContents(allPatches)
+		// 3. Get all session level patches (override everything else)
+		sessionPatches, err := fullRepo.ConfigurationPatches.ListByStrategyAndEntity(ctx, strategy.StrategyID, manman.PatchLevelSession, session.SessionID)
+		if err != nil {
+			sessionPatches = nil
+		}
 
-		// Set rendered content to the cascaded patches
-		// Host-manager will merge this with existing file if base is empty (merge mode)
-		rendered.RenderedContent = patchContent
+		// Layer patches in cascade order, each applied with its own patch_format.
+		// Host-manager will merge the result with the existing file if base is empty (merge mode)
+		allPatches := append(append(gcPatches, sgcPatches...), sessionPatches...)
+		for _, p := range extra {
+			if p.StrategyID == strategy.StrategyID {
+				allPatches = append(allPatches, p)
+			}
+		}
+		rendered.RenderedContent, rendered.Patches = layerConfigurationPatches(strategy.StrategyType, rendered.BaseContent, allPatches)
+		for _, layer := range rendered.Patches {
+			if layer.Error != "" {
+				log.Printf("Warning: skipped patch %d (%s) for strategy %q: %s", layer.PatchId, layer.Level, strategy.Name, layer.Error)
+			}
+		}
 
 		renderedConfigs = append(renderedConfigs, rendered)
 	}
@@ -207,15 +230,14 @@ func (h *ConfigurationStrategyHandler) GetSessionConfiguration(ctx context.Conte
 }
 
 func (h *ConfigurationStrategyHandler) PreviewConfiguration(ctx context.Context, req *pb.PreviewConfigurationRequest, fullRepo *repository.Repository) (*pb.PreviewConfigurationResponse, error) {
-	// Get session configuration (same as GetSessionConfiguration for now)
-	sessionResp, err := h.GetSessionConfiguration(ctx, &pb.GetSessionConfigurationRequest{
-		SessionId: req.SessionId,
-	}, fullRepo)
+	overrides, err := sessionPatchesFromOverrides(req.ParameterOverrides)
+	if err != nil {
+		return nil, err
+	}
+	sessionResp, err := h.renderSessionConfiguration(ctx, req.SessionId, overrides, fullRepo)
 	if err != nil {
 		return nil, err
 	}
-
-	// TODO: Apply parameter overrides from req.ParameterOverrides
 
 	return &pb.PreviewConfigurationResponse{
 		Configurations: sessionResp.Configurations,
@@ -244,21 +266,3 @@ func strategyToProto(s *manman.ConfigurationStrategy) *pb.ConfigurationStrategy
 
 	return proto
 }
-
-// joinPatchContents concatenates non-nil patch contents with newline separators.
-func joinPatchContents(patches []*manman.ConfigurationPatch) string {
-	var parts []string
-	for _, p := range patches {
-		if p.PatchContent != nil && *p.PatchContent != "" {
-			parts = append(parts, *p.PatchContent)
-		}
-	}
-	result := ""
-	for i, part := range parts {
-		if i > 0 {
-			result += "\n"
-		}
-		result += part
-	}
-	return result
-}
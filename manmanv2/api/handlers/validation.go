@@ -2,87 +2,174 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
 	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ValidationHandler struct {
+	repo           *repository.Repository
 	serverRepo     repository.ServerRepository
 	gameConfigRepo repository.GameConfigRepository
-	// portRepo and capabilityRepo will be added in Phase 5
+	placer         *Placer
 }
 
-func NewValidationHandler(
-	serverRepo repository.ServerRepository,
-	gameConfigRepo repository.GameConfigRepository,
-) *ValidationHandler {
+func NewValidationHandler(repo *repository.Repository) *ValidationHandler {
 	return &ValidationHandler{
-		serverRepo:     serverRepo,
-		gameConfigRepo: gameConfigRepo,
+		repo:           repo,
+		serverRepo:     repo.Servers,
+		gameConfigRepo: repo.GameConfigs,
+		placer:         NewPlacer(repo),
 	}
 }
 
 func (h *ValidationHandler) ValidateDeployment(ctx context.Context, req *pb.ValidateDeploymentRequest) (*pb.ValidateDeploymentResponse, error) {
 	issues := []*pb.ValidationIssue{}
 
-	// 1. Check server exists and is online
-	server, err := h.serverRepo.Get(ctx, req.ServerId)
+	// 1. Check server exists and is online; without one the deployment is placed automatically
+	if req.ServerId != 0 {
+		server, err := h.serverRepo.Get(ctx, req.ServerId)
+		if err != nil {
+			return &pb.ValidateDeploymentResponse{
+				Valid: false,
+				Issues: []*pb.ValidationIssue{{
+					Severity: pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
+					Field:    "server_id",
+					Message:  "Server not found",
+				}},
+			}, nil
+		}
+
+		if server.Status != "online" {
+			issues = append(issues, &pb.ValidationIssue{
+				Severity:   pb.ValidationSeverity_VALIDATION_SEVERITY_WARNING,
+				Field:      "server_id",
+				Message:    "Server is offline",
+				Suggestion: "Wait for server to come online or choose different server",
+			})
+		}
+	}
+
+	// 2. Check game config exists
+	gc, err := h.gameConfigRepo.Get(ctx, req.GameConfigId)
 	if err != nil {
 		return &pb.ValidateDeploymentResponse{
 			Valid: false,
 			Issues: []*pb.ValidationIssue{{
 				Severity: pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
-				Field:    "server_id",
-				Message:  "Server not found",
+				Field:    "game_config_id",
+				Message:  "Game config not found",
 			}},
 		}, nil
 	}
 
-	if server.Status != "online" {
+	if err := validateResourceLimits(req.ResourceLimits); err != nil {
 		issues = append(issues, &pb.ValidationIssue{
-			Severity:   pb.ValidationSeverity_VALIDATION_SEVERITY_WARNING,
-			Field:      "server_id",
-			Message:    "Server is offline",
-			Suggestion: "Wait for server to come online or choose different server",
+			Severity: pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
+			Field:    "resource_limits",
+			Message:  err.Error(),
 		})
 	}
+	sgc := &manman.ServerGameConfig{ServerID: req.ServerId, GameConfigID: gc.ConfigID}
+	sgc.CPUMillicores, sgc.MemoryMB, sgc.PidsLimit, sgc.Ulimits = resourceLimitOverridesToColumns(req.ResourceLimits)
+	limits := manman.ResolveResourceLimits(gc, sgc)
 
-	// 2. Check game config exists
-	_, err = h.gameConfigRepo.Get(ctx, req.GameConfigId)
+	// 3. Rank the servers, or just the requested one, to explain where this would run
+	candidates, err := h.placer.Rank(ctx, placementRequest{
+		gc:          gc,
+		limits:      limits,
+		ports:       req.PortBindings,
+		constraints: req.Placement,
+		serverID:    req.ServerId,
+	})
 	if err != nil {
-		return &pb.ValidateDeploymentResponse{
-			Valid: false,
-			Issues: []*pb.ValidationIssue{{
-				Severity: pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
-				Field:    "game_config_id",
-				Message:  "Game config not found",
-			}},
-		}, nil
+		return nil, status.Errorf(codes.Internal, "failed to evaluate placement: %v", err)
+	}
+	var chosen *pb.PlacementCandidate
+	if len(candidates) > 0 && candidates[0].Eligible {
+		chosen = candidates[0]
 	}
 
-	// 3. Validate port availability (TODO: requires port repository)
-	// for _, binding := range req.PortBindings {
-	//     inUse, err := h.portRepo.IsPortInUse(ctx, req.ServerId, binding.HostPort, binding.Protocol)
-	//     if err != nil {
-	//         continue
-	//     }
-	//     if inUse {
-	//         issues = append(issues, &pb.ValidationIssue{
-	//             Severity:   pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
-	//             Field:      "port_bindings",
-	//             Message:    fmt.Sprintf("Port %d/%s already in use", binding.HostPort, binding.Protocol),
-	//             Suggestion: "Choose a different port or stop conflicting service",
-	//         })
-	//     }
-	// }
+	serverID := req.ServerId
+	resolvedPorts := req.PortBindings
+	if req.ServerId == 0 {
+		if chosen == nil {
+			issues = append(issues, &pb.ValidationIssue{
+				Severity:   pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
+				Field:      "placement",
+				Message:    "No server can run this deployment: " + summarizeRejections(candidates),
+				Suggestion: "Relax the placement constraints, lower the resource limits or bring another server online",
+			})
+		} else {
+			serverID = chosen.ServerId
+			resolvedPorts = chosen.PortBindings
+		}
+	} else {
+		// 4. Validate port availability on the requested server, within the ranges it reported
+		capability, err := h.repo.ServerCapabilities.Get(ctx, req.ServerId)
+		if errors.Is(err, pgx.ErrNoRows) {
+			capability, err = nil, nil
+		}
+		var ports []*pb.PortBinding
+		var conflict string
+		if err == nil {
+			ports, conflict, err = pickHostPorts(ctx, h.repo, req.ServerId, portRanges(capability), req.PortBindings)
+		}
+		if err != nil {
+			issues = append(issues, &pb.ValidationIssue{
+				Severity: pb.ValidationSeverity_VALIDATION_SEVERITY_WARNING,
+				Field:    "port_bindings",
+				Message:  fmt.Sprintf("Could not check port availability: %v", err),
+			})
+		} else if conflict != "" {
+			issues = append(issues, &pb.ValidationIssue{
+				Severity:   pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
+				Field:      "port_bindings",
+				Message:    conflict,
+				Suggestion: "Choose a different port, leave host_port as 0 to have one picked, or stop the conflicting session",
+			})
+		} else {
+			resolvedPorts = ports
+		}
+	}
 
-	// 4. Estimate resources
+	// 5. Check the server can fit the container's resource limits
+	if serverID != 0 {
+		overcommit, err := checkServerCapacity(ctx, h.repo, serverID, 0, limits)
+		if err != nil {
+			issues = append(issues, &pb.ValidationIssue{
+				Severity: pb.ValidationSeverity_VALIDATION_SEVERITY_WARNING,
+				Field:    "resource_limits",
+				Message:  fmt.Sprintf("Could not check server capacity: %v", err),
+			})
+		} else if overcommit != "" {
+			issues = append(issues, &pb.ValidationIssue{
+				Severity:   pb.ValidationSeverity_VALIDATION_SEVERITY_ERROR,
+				Field:      "resource_limits",
+				Message:    fmt.Sprintf("Server does not have capacity: %s", overcommit),
+				Suggestion: "Lower the resource limits, stop other sessions or choose a different server",
+			})
+		}
+	}
+
+	// 6. Estimate resources
 	estimate := &pb.DeploymentEstimate{
 		EstimatedMemoryMb:      1024, // Default estimate
 		EstimatedCpuMillicores: 500,  // Default estimate
 	}
-	for _, binding := range req.PortBindings {
+	if limits.MemoryMB > 0 {
+		estimate.EstimatedMemoryMb = limits.MemoryMB
+	}
+	if limits.CPUMillicores > 0 {
+		estimate.EstimatedCpuMillicores = limits.CPUMillicores
+	}
+	for _, binding := range resolvedPorts {
 		estimate.AllocatedPorts = append(estimate.AllocatedPorts, binding.HostPort)
 	}
 
@@ -95,10 +182,14 @@ func (h *ValidationHandler) ValidateDeployment(ctx context.Context, req *pb.Vali
 		}
 	}
 
-	return &pb.ValidateDeploymentResponse{
-		Valid:    valid,
-		Issues:   issues,
-		Estimate: estimate,
-	}, nil
+	resp := &pb.ValidateDeploymentResponse{
+		Valid:      valid,
+		Issues:     issues,
+		Estimate:   estimate,
+		Candidates: candidates,
+	}
+	if valid && chosen != nil {
+		resp.ChosenServerId = chosen.ServerId
+	}
+	return resp, nil
 }
-
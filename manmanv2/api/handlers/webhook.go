@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// WebhookHandler manages outgoing webhooks and their delivery history. Deliveries are
+// queued and sent by the notifier service, not the API.
+type WebhookHandler struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+}
+
+func NewWebhookHandler(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+	}
+}
+
+func (h *WebhookHandler) ListWebhooks(ctx context.Context, req *pb.ListWebhooksRequest) (*pb.ListWebhooksResponse, error) {
+	webhooks, err := h.webhookRepo.List(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list webhooks: %v", err)
+	}
+	pbWebhooks := make([]*pb.Webhook, len(webhooks))
+	for i, w := range webhooks {
+		pbWebhooks[i] = webhookToProto(w)
+	}
+	return &pb.ListWebhooksResponse{Webhooks: pbWebhooks}, nil
+}
+
+func (h *WebhookHandler) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.CreateWebhookResponse, error) {
+	webhook := &manman.Webhook{
+		Name:          req.Name,
+		URL:           req.Url,
+		Format:        req.Format,
+		Secret:        req.Secret,
+		EventPatterns: req.EventPatterns,
+		SGCIDs:        req.ServerGameConfigIds,
+		Enabled:       req.Enabled,
+	}
+	if webhook.Format == "" {
+		webhook.Format = manman.WebhookFormatJSON
+	}
+	if err := webhook.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid webhook: %v", err)
+	}
+
+	webhook, err := h.webhookRepo.Create(ctx, webhook)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to create webhook: %v", err)
+	}
+	return &pb.CreateWebhookResponse{Webhook: webhookToProto(webhook)}, nil
+}
+
+func (h *WebhookHandler) UpdateWebhook(ctx context.Context, req *pb.UpdateWebhookRequest) (*pb.UpdateWebhookResponse, error) {
+	webhook, err := h.webhookRepo.Get(ctx, req.WebhookId)
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound, "webhook not found: %v", err)
+	}
+
+	// Apply field paths
+	if len(req.UpdatePaths) == 0 {
+		// Update all provided fields
+		if req.Name != "" {
+			webhook.Name = req.Name
+		}
+		if req.Url != "" {
+			webhook.URL = req.Url
+		}
+		if req.Format != "" {
+			webhook.Format = req.Format
+		}
+		if req.Secret != "" {
+			webhook.Secret = req.Secret
+		}
+		if len(req.EventPatterns) > 0 {
+			webhook.EventPatterns = req.EventPatterns
+		}
+		if len(req.ServerGameConfigIds) > 0 {
+			webhook.SGCIDs = req.ServerGameConfigIds
+		}
+		// Only update enabled if explicitly provided
+		if req.Enabled {
+			webhook.Enabled = req.Enabled
+		}
+	} else {
+		// Update only specified fields
+		for _, path := range req.UpdatePaths {
+			switch path {
+			case "name":
+				webhook.Name = req.Name
+			case "url":
+				webhook.URL = req.Url
+			case "format":
+				webhook.Format = req.Format
+			case "secret":
+				webhook.Secret = req.Secret
+			case "event_patterns":
+				webhook.EventPatterns = req.EventPatterns
+			case "server_game_config_ids":
+				webhook.SGCIDs = req.ServerGameConfigIds
+			case "enabled":
+				webhook.Enabled = req.Enabled
+			}
+		}
+	}
+
+	if err := webhook.Validate(); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid webhook: %v", err)
+	}
+	if err := h.webhookRepo.Update(ctx, webhook); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to update webhook: %v", err)
+	}
+	return &pb.UpdateWebhookResponse{Webhook: webhookToProto(webhook)}, nil
+}
+
+func (h *WebhookHandler) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	if err := h.webhookRepo.Delete(ctx, req.WebhookId); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to delete webhook: %v", err)
+	}
+	return &pb.DeleteWebhookResponse{}, nil
+}
+
+func (h *WebhookHandler) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	pageSize := int(req.PageSize)
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	offset := 0
+	if req.PageToken != "" {
+		var err error
+		offset, err = decodePageToken(req.PageToken)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid page token: %v", err)
+		}
+	}
+
+	deliveries, err := h.deliveryRepo.List(ctx, req.WebhookId, req.Status, pageSize+1, offset)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list deliveries: %v", err)
+	}
+
+	var nextPageToken string
+	if len(deliveries) > pageSize {
+		deliveries = deliveries[:pageSize]
+		nextPageToken = encodePageToken(offset + pageSize)
+	}
+
+	pbDeliveries := make([]*pb.WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		pbDeliveries[i] = webhookDeliveryToProto(d)
+	}
+
+	return &pb.ListWebhookDeliveriesResponse{
+		Deliveries:    pbDeliveries,
+		NextPageToken: nextPageToken,
+	}, nil
+}
+
+func (h *WebhookHandler) RedeliverWebhookDelivery(ctx context.Context, req *pb.RedeliverWebhookDeliveryRequest) (*pb.RedeliverWebhookDeliveryResponse, error) {
+	if err := h.deliveryRepo.Requeue(ctx, req.DeliveryId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, status.Errorf(codes.FailedPrecondition, "delivery %d doesn't exist or isn't dead", req.DeliveryId)
+		}
+		return nil, status.Errorf(codes.Internal, "failed to requeue delivery: %v", err)
+	}
+	delivery, err := h.deliveryRepo.Get(ctx, req.DeliveryId)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to get delivery: %v", err)
+	}
+	return &pb.RedeliverWebhookDeliveryResponse{Delivery: webhookDeliveryToProto(delivery)}, nil
+}
+
+func webhookToProto(w *manman.Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		WebhookId:           w.WebhookID,
+		Name:                w.Name,
+		Url:                 w.URL,
+		Format:              w.Format,
+		HasSecret:           w.Secret != "",
+		EventPatterns:       w.EventPatterns,
+		ServerGameConfigIds: w.SGCIDs,
+		Enabled:             w.Enabled,
+		CreatedAt:           w.CreatedAt.Unix(),
+		UpdatedAt:           w.UpdatedAt.Unix(),
+	}
+}
+
+func webhookDeliveryToProto(d *manman.WebhookDelivery) *pb.WebhookDelivery {
+	pbDelivery := &pb.WebhookDelivery{
+		DeliveryId:    d.DeliveryID,
+		WebhookId:     d.WebhookID,
+		RoutingKey:    d.RoutingKey,
+		Status:        d.Status,
+		Attempts:      int32(d.Attempts),
+		NextAttemptAt: d.NextAttemptAt.Unix(),
+		CreatedAt:     d.CreatedAt.Unix(),
+	}
+	if raw, err := json.Marshal(d.Payload); err == nil {
+		pbDelivery.PayloadJson = string(raw)
+	}
+	if d.LastStatusCode != nil {
+		pbDelivery.LastStatusCode = int32(*d.LastStatusCode)
+	}
+	if d.LastError != nil {
+		pbDelivery.LastError = *d.LastError
+	}
+	if d.DeliveredAt != nil {
+		pbDelivery.DeliveredAt = d.DeliveredAt.Unix()
+	}
+	return pbDelivery
+}
@@ -2,7 +2,11 @@ package workshop
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 
+	"github.com/whale-net/everything/manmanv2/api/addonsource"
+	"github.com/whale-net/everything/manmanv2/api/workshop"
 	"github.com/whale-net/everything/manmanv2/models"
 	pb "github.com/whale-net/everything/manmanv2/protos"
 	"google.golang.org/grpc/codes"
@@ -25,6 +29,9 @@ func (h *WorkshopServiceHandler) CreateAddon(ctx context.Context, req *pb.Create
 	if platformType == "" {
 		platformType = manman.PlatformTypeSteamWorkshop
 	}
+	if !manman.IsValidPlatformType(platformType) {
+		return nil, status.Errorf(codes.InvalidArgument, "unsupported platform_type: %s", platformType)
+	}
 
 	if req.PresetId == 0 && req.InstallationPath == "" {
 		return nil, status.Error(codes.InvalidArgument,
@@ -52,8 +59,17 @@ func (h *WorkshopServiceHandler) CreateAddon(ctx context.Context, req *pb.Create
 	}
 	if req.Metadata != "" {
 		metadata := make(map[string]interface{})
+		if err := json.Unmarshal([]byte(req.Metadata), &metadata); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "metadata must be a JSON object: %v", err)
+		}
 		addon.Metadata = metadata
 	}
+	// The host verifies URL downloads against the checksum FetchAddonMetadata records
+	if platformType == manman.PlatformTypeURL {
+		if err := addonsource.CheckURLAddon(addon.WorkshopID, addon.Metadata); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
 
 	addon, err := h.addonRepo.Create(ctx, addon)
 	if err != nil {
@@ -144,6 +160,9 @@ func (h *WorkshopServiceHandler) UpdateAddon(ctx context.Context, req *pb.Update
 		}
 		if req.Metadata != "" {
 			metadata := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(req.Metadata), &metadata); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "metadata must be a JSON object: %v", err)
+			}
 			addon.Metadata = metadata
 		}
 		addon.IsDeprecated = req.IsDeprecated
@@ -168,6 +187,11 @@ func (h *WorkshopServiceHandler) UpdateAddon(ctx context.Context, req *pb.Update
 				addon.IsDeprecated = req.IsDeprecated
 			case "metadata":
 				metadata := make(map[string]interface{})
+				if req.Metadata != "" {
+					if err := json.Unmarshal([]byte(req.Metadata), &metadata); err != nil {
+						return nil, status.Errorf(codes.InvalidArgument, "metadata must be a JSON object: %v", err)
+					}
+				}
 				addon.Metadata = metadata
 			}
 		}
@@ -181,6 +205,11 @@ func (h *WorkshopServiceHandler) UpdateAddon(ctx context.Context, req *pb.Update
 			"addon must have either preset_id or installation_path set after update. "+
 				"Cannot clear both values as the addon would not be installable.")
 	}
+	if addon.PlatformType == manman.PlatformTypeURL {
+		if err := addonsource.CheckURLAddon(addon.WorkshopID, addon.Metadata); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
+	}
 
 	if err := h.addonRepo.Update(ctx, addon); err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to update addon: %v", err)
@@ -204,7 +233,7 @@ func (h *WorkshopServiceHandler) DeleteAddon(ctx context.Context, req *pb.Delete
 	return &pb.DeleteAddonResponse{}, nil
 }
 
-// FetchAddonMetadata fetches metadata from Steam Workshop API without creating a database record
+// FetchAddonMetadata fetches metadata from the addon's platform without creating a database record
 func (h *WorkshopServiceHandler) FetchAddonMetadata(ctx context.Context, req *pb.FetchAddonMetadataRequest) (*pb.FetchAddonMetadataResponse, error) {
 	if req.GameId == 0 {
 		return nil, status.Error(codes.InvalidArgument, "game_id is required")
@@ -218,13 +247,16 @@ func (h *WorkshopServiceHandler) FetchAddonMetadata(ctx context.Context, req *pb
 		platformType = manman.PlatformTypeSteamWorkshop
 	}
 
-	if platformType != manman.PlatformTypeSteamWorkshop {
+	if !manman.IsValidPlatformType(platformType) {
 		return nil, status.Errorf(codes.InvalidArgument, "unsupported platform_type: %s", platformType)
 	}
 
-	metadata, err := h.workshopManager.FetchMetadata(ctx, req.GameId, req.WorkshopId)
+	metadata, err := h.workshopManager.FetchMetadata(ctx, req.GameId, platformType, req.WorkshopId)
+	if errors.Is(err, workshop.ErrUnsupportedPlatform) {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s addons are not enabled on this server", platformType)
+	}
 	if err != nil {
-		return nil, status.Errorf(codes.Unavailable, "failed to fetch addon metadata from Steam Workshop: %v", err)
+		return nil, status.Errorf(codes.Unavailable, "failed to fetch addon metadata: %v", err)
 	}
 
 	return &pb.FetchAddonMetadataResponse{
@@ -264,6 +296,13 @@ func addonToProto(addon *manman.WorkshopAddon) *pb.WorkshopAddon {
 		if appID, ok := addon.Metadata["steam_app_id"].(string); ok {
 			pbAddon.SteamAppId = appID
 		}
+		// Addon sources keep what they need to resolve downloads here, so it has to make
+		// the round trip from FetchAddonMetadata to CreateAddon
+		if addon.PlatformType != manman.PlatformTypeSteamWorkshop && len(addon.Metadata) > 0 {
+			if b, err := json.Marshal(addon.Metadata); err == nil {
+				pbAddon.Metadata = string(b)
+			}
+		}
 	}
 
 	return pbAddon
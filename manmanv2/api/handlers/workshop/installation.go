@@ -23,9 +23,33 @@ func (h *WorkshopServiceHandler) InstallAddon(ctx context.Context, req *pb.Insta
 		return nil, status.Errorf(codes.Internal, "failed to install addon: %v", err)
 	}
 
-	return &pb.InstallAddonResponse{
+	resp := &pb.InstallAddonResponse{
 		Installation: installationToProto(installation),
-	}, nil
+	}
+
+	// The caller downloads the addon itself, so it needs to know where from
+	if req.SkipDispatch {
+		addon, err := h.addonRepo.Get(ctx, req.AddonId)
+		if err != nil {
+			return nil, status.Errorf(codes.NotFound, "addon not found: %v", err)
+		}
+		download, err := h.workshopManager.ResolveDownload(ctx, addon)
+		if err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to resolve addon download: %v", err)
+		}
+		if download != nil {
+			resp.Download = &pb.AddonDownload{
+				Url:               download.URL,
+				FileName:          download.FileName,
+				Version:           download.Version,
+				ChecksumAlgorithm: download.ChecksumAlgorithm,
+				Checksum:          download.Checksum,
+				Extract:           download.Extract,
+			}
+		}
+	}
+
+	return resp, nil
 }
 
 // GetInstallation retrieves a workshop installation by ID
@@ -137,6 +161,7 @@ func installationToProto(installation *manman.WorkshopInstallation) *pb.Workshop
 		ProgressPercent:  int32(installation.ProgressPercent),
 		CreatedAt:        installation.CreatedAt.Unix(),
 		UpdatedAt:        installation.UpdatedAt.Unix(),
+		UpdateAvailable:  installation.UpdateAvailable,
 	}
 
 	if installation.ErrorMessage != nil {
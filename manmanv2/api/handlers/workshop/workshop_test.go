@@ -2,10 +2,13 @@ package workshop
 
 import (
 	"context"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/whale-net/everything/manmanv2/api/addonsource"
+	"github.com/whale-net/everything/manmanv2/api/workshop"
 	"github.com/whale-net/everything/manmanv2/models"
 	pb "github.com/whale-net/everything/manmanv2/protos"
 	"google.golang.org/grpc/codes"
@@ -41,6 +44,14 @@ func (m *MockWorkshopAddonRepository) List(ctx context.Context, gameID *int64, i
 	return args.Get(0).([]*manman.WorkshopAddon), args.Error(1)
 }
 
+func (m *MockWorkshopAddonRepository) ListInActiveLibraries(ctx context.Context) ([]*manman.WorkshopAddon, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*manman.WorkshopAddon), args.Error(1)
+}
+
 func (m *MockWorkshopAddonRepository) Update(ctx context.Context, addon *manman.WorkshopAddon) error {
 	args := m.Called(ctx, addon)
 	return args.Error(0)
@@ -122,6 +133,22 @@ func (m *MockWorkshopInstallationRepository) UpdateProgress(ctx context.Context,
 	return args.Error(0)
 }
 
+func (m *MockWorkshopInstallationRepository) MarkUpdateAvailable(ctx context.Context, addonID int64) ([]*manman.WorkshopInstallation, error) {
+	args := m.Called(ctx, addonID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*manman.WorkshopInstallation), args.Error(1)
+}
+
+func (m *MockWorkshopInstallationRepository) ListUpdateAvailable(ctx context.Context) ([]*manman.WorkshopInstallation, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*manman.WorkshopInstallation), args.Error(1)
+}
+
 func (m *MockWorkshopInstallationRepository) Delete(ctx context.Context, installationID int64) error {
 	args := m.Called(ctx, installationID)
 	return args.Error(0)
@@ -202,6 +229,14 @@ func (m *MockWorkshopLibraryRepository) ListReferences(ctx context.Context, libr
 	return args.Get(0).([]*manman.WorkshopLibrary), args.Error(1)
 }
 
+func (m *MockWorkshopLibraryRepository) ListByAddon(ctx context.Context, addonID int64) ([]*manman.WorkshopLibrary, error) {
+	args := m.Called(ctx, addonID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*manman.WorkshopLibrary), args.Error(1)
+}
+
 func (m *MockWorkshopLibraryRepository) DetectCircularReference(ctx context.Context, parentLibraryID, childLibraryID int64) (bool, error) {
 	args := m.Called(ctx, parentLibraryID, childLibraryID)
 	return args.Bool(0), args.Error(1)
@@ -233,14 +268,22 @@ func (m *MockWorkshopManager) ResetInstallation(ctx context.Context, installatio
 	return args.Get(0).(*manman.WorkshopInstallation), args.Error(1)
 }
 
-func (m *MockWorkshopManager) FetchMetadata(ctx context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error) {
-	args := m.Called(ctx, gameID, workshopID)
+func (m *MockWorkshopManager) FetchMetadata(ctx context.Context, gameID int64, platformType, workshopID string) (*manman.WorkshopAddon, error) {
+	args := m.Called(ctx, gameID, platformType, workshopID)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*manman.WorkshopAddon), args.Error(1)
 }
 
+func (m *MockWorkshopManager) ResolveDownload(ctx context.Context, addon *manman.WorkshopAddon) (*addonsource.Download, error) {
+	args := m.Called(ctx, addon)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*addonsource.Download), args.Error(1)
+}
+
 func (m *MockWorkshopManager) EnsureLibraryAddonsInstalled(ctx context.Context, sgcID int64) error {
 	args := m.Called(ctx, sgcID)
 	return args.Error(0)
@@ -317,7 +360,58 @@ func TestInstallAddon(t *testing.T) {
 	}
 }
 
+// TestInstallAddon_SkipDispatchReturnsDownload checks that callers downloading addons
+// themselves are told where to fetch addon source files from
+func TestInstallAddon_SkipDispatchReturnsDownload(t *testing.T) {
+	addon := &manman.WorkshopAddon{AddonID: 100, WorkshopID: "sodium", PlatformType: manman.PlatformTypeModrinth}
+	mockRepo := new(MockWorkshopAddonRepository)
+	mockRepo.On("Get", mock.Anything, int64(100)).Return(addon, nil)
+	mockManager := new(MockWorkshopManager)
+	mockManager.On("InstallAddon", mock.Anything, int64(1), int64(100), false, true, "", int64(0), int64(0)).
+		Return(&manman.WorkshopInstallation{InstallationID: 5, SGCID: 1, AddonID: 100}, nil)
+	mockManager.On("ResolveDownload", mock.Anything, addon).Return(&addonsource.Download{
+		URL:               "https://cdn.example/sodium.jar",
+		FileName:          "sodium.jar",
+		Version:           "abc",
+		ChecksumAlgorithm: addonsource.ChecksumSHA512,
+		Checksum:          "cc",
+	}, nil)
+
+	handler := &WorkshopServiceHandler{addonRepo: mockRepo, workshopManager: mockManager}
+	resp, err := handler.InstallAddon(context.Background(), &pb.InstallAddonRequest{SgcId: 1, AddonId: 100, SkipDispatch: true})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), resp.Installation.InstallationId)
+	if assert.NotNil(t, resp.Download) {
+		assert.Equal(t, "https://cdn.example/sodium.jar", resp.Download.Url)
+		assert.Equal(t, "sodium.jar", resp.Download.FileName)
+		assert.Equal(t, "sha512", resp.Download.ChecksumAlgorithm)
+		assert.Equal(t, "cc", resp.Download.Checksum)
+	}
+	mockManager.AssertExpectations(t)
+}
+
 // TestGetInstallation tests the GetInstallation RPC
+func TestCreateAddon_URLRequiresChecksum(t *testing.T) {
+	mockRepo := new(MockWorkshopAddonRepository)
+	handler := &WorkshopServiceHandler{addonRepo: mockRepo}
+	req := &pb.CreateAddonRequest{
+		GameId:           1,
+		WorkshopId:       "https://example.com/mods/pack.zip",
+		PlatformType:     manman.PlatformTypeURL,
+		Name:             "pack.zip",
+		InstallationPath: "mods",
+	}
+
+	_, err := handler.CreateAddon(context.Background(), req)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	req.Metadata = `{"sha256": "` + strings.Repeat("ab", 32) + `"}`
+	mockRepo.On("Create", mock.Anything, mock.Anything).Return(&manman.WorkshopAddon{AddonID: 1, Name: "pack.zip"}, nil)
+	_, err = handler.CreateAddon(context.Background(), req)
+	assert.NoError(t, err)
+}
+
 func TestGetInstallation(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -955,7 +1049,7 @@ func TestFetchAddonMetadata(t *testing.T) {
 			mockSetup: func(m *MockWorkshopManager) {
 				description := "Test addon description"
 				fileSize := int64(1024000)
-				m.On("FetchMetadata", mock.Anything, int64(1), "123456").
+				m.On("FetchMetadata", mock.Anything, int64(1), manman.PlatformTypeSteamWorkshop, "123456").
 					Return(&manman.WorkshopAddon{
 						GameID:        1,
 						WorkshopID:    "123456",
@@ -987,7 +1081,7 @@ func TestFetchAddonMetadata(t *testing.T) {
 			mockSetup: func(m *MockWorkshopManager) {
 				description := "Test collection"
 				fileSize := int64(2048000)
-				m.On("FetchMetadata", mock.Anything, int64(1), "789012").
+				m.On("FetchMetadata", mock.Anything, int64(1), manman.PlatformTypeSteamWorkshop, "789012").
 					Return(&manman.WorkshopAddon{
 						GameID:        1,
 						WorkshopID:    "789012",
@@ -1038,6 +1132,19 @@ func TestFetchAddonMetadata(t *testing.T) {
 			mockSetup:     func(m *MockWorkshopManager) {},
 			expectedError: codes.InvalidArgument,
 		},
+		{
+			name: "platform without a registered source",
+			request: &pb.FetchAddonMetadataRequest{
+				GameId:       1,
+				WorkshopId:   "238222",
+				PlatformType: manman.PlatformTypeCurseForge,
+			},
+			mockSetup: func(m *MockWorkshopManager) {
+				m.On("FetchMetadata", mock.Anything, int64(1), manman.PlatformTypeCurseForge, "238222").
+					Return(nil, workshop.ErrUnsupportedPlatform)
+			},
+			expectedError: codes.FailedPrecondition,
+		},
 		{
 			name: "steam API failure",
 			request: &pb.FetchAddonMetadataRequest{
@@ -1045,7 +1152,7 @@ func TestFetchAddonMetadata(t *testing.T) {
 				WorkshopId: "999999",
 			},
 			mockSetup: func(m *MockWorkshopManager) {
-				m.On("FetchMetadata", mock.Anything, int64(1), "999999").
+				m.On("FetchMetadata", mock.Anything, int64(1), manman.PlatformTypeSteamWorkshop, "999999").
 					Return(nil, assert.AnError)
 			},
 			expectedError: codes.Unavailable,
@@ -12,15 +12,20 @@ import (
 
 	"github.com/whale-net/everything/libs/go/db"
 	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/libs/go/grpcclient"
 	"github.com/whale-net/everything/libs/go/logging"
 	rmqlib "github.com/whale-net/everything/libs/go/rmq"
 	"github.com/whale-net/everything/libs/go/s3"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"github.com/whale-net/everything/manmanv2/api/addonsource"
+	"github.com/whale-net/everything/manmanv2/api/audit"
+	"github.com/whale-net/everything/manmanv2/api/auth"
 	"github.com/whale-net/everything/manmanv2/api/handlers"
 	workshophandler "github.com/whale-net/everything/manmanv2/api/handlers/workshop"
 	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
 	"github.com/whale-net/everything/manmanv2/api/steam"
 	"github.com/whale-net/everything/manmanv2/api/workshop"
+	"github.com/whale-net/everything/manmanv2/models"
 	pb "github.com/whale-net/everything/manmanv2/protos"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
@@ -57,6 +62,7 @@ func run() error {
 	grpcAuthMode := getEnv("GRPC_AUTH_MODE", "none")
 	grpcOIDCIssuer := getEnv("GRPC_OIDC_ISSUER", "")
 	grpcOIDCClientID := getEnv("GRPC_OIDC_CLIENT_ID", "")
+	logProcessorAddress := getEnv("LOG_PROCESSOR_ADDRESS", "") // Optional: enables AttachSession
 
 	// Initialize database pool (reads PG_DATABASE_URL)
 	log.Println("Connecting to database...")
@@ -102,18 +108,23 @@ func run() error {
 		Mode:      grpcauth.AuthMode(grpcAuthMode),
 		IssuerURL: grpcOIDCIssuer,
 		ClientID:  grpcOIDCClientID,
+		DevRoles:  []string{auth.RoleAdmin},
 	})
 	if err != nil {
 		return fmt.Errorf("failed to create auth interceptors: %w", err)
 	}
+	// Role checks run after authentication has put the caller's claims in the context
+	authorizer := auth.NewAuthorizer(repo.SGCGrants, repo.Sessions)
+	// The audit recorder sits between them so denied calls are recorded too
+	auditor := audit.NewRecorder(repo.AuditEvents, repo.Sessions)
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer(
 		grpc.MaxRecvMsgSize(10 * 1024 * 1024), // 10 MB
 		grpc.MaxSendMsgSize(10 * 1024 * 1024), // 10 MB
 		grpc.StatsHandler(otelgrpc.NewServerHandler()),
-		grpc.ChainUnaryInterceptor(unaryInt),
-		grpc.ChainStreamInterceptor(streamInt),
+		grpc.ChainUnaryInterceptor(unaryInt, auditor.UnaryInterceptor(), authorizer.UnaryInterceptor()),
+		grpc.ChainStreamInterceptor(streamInt, authorizer.StreamInterceptor()),
 	)
 
 	// Register Workshop service early so workshopManager is available for APIServer
@@ -138,11 +149,30 @@ func run() error {
 		steamClient,
 		rmqPublisher,
 	)
+	workshopManager.RegisterSource(manman.PlatformTypeModrinth,
+		addonsource.NewModrinthSource(getEnv("MODRINTH_API_URL", addonsource.DefaultModrinthURL), 30*time.Second))
+	if curseForgeAPIKey := getEnv("CURSEFORGE_API_KEY", ""); curseForgeAPIKey != "" {
+		workshopManager.RegisterSource(manman.PlatformTypeCurseForge,
+			addonsource.NewCurseForgeSource(getEnv("CURSEFORGE_API_URL", addonsource.DefaultCurseForgeURL), curseForgeAPIKey, 30*time.Second))
+	}
+	// Fetching URL metadata downloads the whole file to checksum it
+	workshopManager.RegisterSource(manman.PlatformTypeURL, addonsource.NewURLSource(10*time.Minute))
 
 	// Register API server
 	apiServer := handlers.NewAPIServer(repo, s3Client, rmqConn, workshopManager)
 	pb.RegisterManManAPIServer(grpcServer, apiServer)
 
+	// The session console streams output from the log-processor as the attached user
+	if logProcessorAddress != "" {
+		logProcessorConn, err := grpcclient.NewClient(ctx, logProcessorAddress, grpcauth.NewUserTokenDialOption(grpcauth.AuthMode(grpcAuthMode)))
+		if err != nil {
+			return fmt.Errorf("failed to connect to log-processor: %w", err)
+		}
+		defer logProcessorConn.Close()
+		apiServer.EnableConsole(pb.NewLogProcessorClient(logProcessorConn.GetConnection()), authorizer.Authorize, auditor.Record)
+		log.Printf("Session console enabled (log-processor at %s)", logProcessorAddress)
+	}
+
 	// Register Workshop service
 	workshopHandler := workshophandler.NewWorkshopServiceHandler(
 		repo.WorkshopAddons,
@@ -153,6 +183,7 @@ func run() error {
 		workshopManager,
 	)
 	pb.RegisterWorkshopServiceServer(grpcServer, workshopHandler)
+	auditor.SetReaders(apiServer, workshopHandler)
 
 	// Initialize workshop status handler for installation status updates
 	log.Println("Setting up workshop status handler...")
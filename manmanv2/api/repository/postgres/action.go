@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ActionRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewActionRepository(db *pgxpool.Pool) *ActionRepository {
+func NewActionRepository(db DB) *ActionRepository {
 	return &ActionRepository{db: db}
 }
 
@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	manman "github.com/whale-net/everything/manmanv2/models"
 )
 
 type AddonPathPresetRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewAddonPathPresetRepository(db *pgxpool.Pool) *AddonPathPresetRepository {
+func NewAddonPathPresetRepository(db DB) *AddonPathPresetRepository {
 	return &AddonPathPresetRepository{db: db}
 }
 
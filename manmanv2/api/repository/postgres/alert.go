@@ -0,0 +1,174 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+const alertRuleColumns = `rule_id, game_id, sgc_id, name, pattern, threshold, window_seconds, cooldown_seconds, action_id, backup_config_id, enabled, created_at, updated_at`
+
+// AlertRuleRepository implements repository.AlertRuleRepository
+type AlertRuleRepository struct {
+	db DB
+}
+
+func NewAlertRuleRepository(db DB) *AlertRuleRepository {
+	return &AlertRuleRepository{db: db}
+}
+
+func (r *AlertRuleRepository) Create(ctx context.Context, rule *manman.AlertRule) (*manman.AlertRule, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO alert_rules (game_id, sgc_id, name, pattern, threshold, window_seconds, cooldown_seconds, action_id, backup_config_id, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+		RETURNING rule_id, created_at, updated_at
+	`, rule.GameID, rule.SGCID, rule.Name, rule.Pattern, rule.Threshold, rule.WindowSeconds, rule.CooldownSeconds,
+		rule.ActionID, rule.BackupConfigID, rule.Enabled).Scan(&rule.RuleID, &rule.CreatedAt, &rule.UpdatedAt)
+	return rule, err
+}
+
+func (r *AlertRuleRepository) Get(ctx context.Context, ruleID int64) (*manman.AlertRule, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+alertRuleColumns+` FROM alert_rules WHERE rule_id = $1`, ruleID)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := scanAlertRules(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return rules[0], nil
+}
+
+func (r *AlertRuleRepository) List(ctx context.Context, gameID, sgcID *int64) ([]*manman.AlertRule, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE ($1::bigint IS NULL OR game_id = $1) AND ($2::bigint IS NULL OR sgc_id = $2)
+		ORDER BY name, rule_id
+	`, gameID, sgcID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlertRules(rows)
+}
+
+func (r *AlertRuleRepository) ListApplicable(ctx context.Context, sgcID int64) ([]*manman.AlertRule, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+alertRuleColumns+`
+		FROM alert_rules
+		WHERE sgc_id = $1
+		   OR game_id = (
+			SELECT gc.game_id
+			FROM server_game_configs sgc
+			JOIN game_configs gc ON gc.config_id = sgc.game_config_id
+			WHERE sgc.sgc_id = $1
+		)
+		ORDER BY name, rule_id
+	`, sgcID)
+	if err != nil {
+		return nil, err
+	}
+	return scanAlertRules(rows)
+}
+
+func (r *AlertRuleRepository) Update(ctx context.Context, rule *manman.AlertRule) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE alert_rules
+		SET name = $2, pattern = $3, threshold = $4, window_seconds = $5, cooldown_seconds = $6,
+		    action_id = $7, backup_config_id = $8, enabled = $9, updated_at = NOW()
+		WHERE rule_id = $1
+	`, rule.RuleID, rule.Name, rule.Pattern, rule.Threshold, rule.WindowSeconds, rule.CooldownSeconds,
+		rule.ActionID, rule.BackupConfigID, rule.Enabled)
+	return err
+}
+
+func (r *AlertRuleRepository) Delete(ctx context.Context, ruleID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM alert_rules WHERE rule_id = $1`, ruleID)
+	return err
+}
+
+func scanAlertRules(rows pgx.Rows) ([]*manman.AlertRule, error) {
+	defer rows.Close()
+	var rules []*manman.AlertRule
+	for rows.Next() {
+		rule := &manman.AlertRule{}
+		if err := rows.Scan(&rule.RuleID, &rule.GameID, &rule.SGCID, &rule.Name, &rule.Pattern, &rule.Threshold, &rule.WindowSeconds,
+			&rule.CooldownSeconds, &rule.ActionID, &rule.BackupConfigID, &rule.Enabled, &rule.CreatedAt, &rule.UpdatedAt); err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, rows.Err()
+}
+
+// AlertEventRepository implements repository.AlertEventRepository
+type AlertEventRepository struct {
+	db DB
+}
+
+func NewAlertEventRepository(db DB) *AlertEventRepository {
+	return &AlertEventRepository{db: db}
+}
+
+func (r *AlertEventRepository) Create(ctx context.Context, e *manman.AlertEvent) (*manman.AlertEvent, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO alert_events (rule_id, session_id, sgc_id, match_count, line, first_match_at, fired_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING alert_id
+	`, e.RuleID, e.SessionID, e.SGCID, e.MatchCount, e.Line, e.FirstMatchAt, e.FiredAt).Scan(&e.AlertID)
+	return e, err
+}
+
+func (r *AlertEventRepository) UpdateResponse(ctx context.Context, alertID int64, actionExecutionID, backupID *int64, responseError *string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE alert_events
+		SET action_execution_id = $2, backup_id = $3, response_error = $4
+		WHERE alert_id = $1
+	`, alertID, actionExecutionID, backupID, responseError)
+	return err
+}
+
+func (r *AlertEventRepository) LastFired(ctx context.Context, ruleID, sgcID int64) (*time.Time, error) {
+	var firedAt *time.Time
+	err := r.db.QueryRow(ctx, `
+		SELECT MAX(fired_at) FROM alert_events WHERE rule_id = $1 AND sgc_id = $2
+	`, ruleID, sgcID).Scan(&firedAt)
+	return firedAt, err
+}
+
+func (r *AlertEventRepository) List(ctx context.Context, filters *repository.AlertEventFilters, limit, offset int) ([]*manman.AlertEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if filters == nil {
+		filters = &repository.AlertEventFilters{}
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT alert_id, rule_id, session_id, sgc_id, match_count, line, first_match_at, fired_at, action_execution_id, backup_id, response_error
+		FROM alert_events
+		WHERE ($1 = 0 OR rule_id = $1) AND ($2 = 0 OR sgc_id = $2) AND ($3 = 0 OR session_id = $3)
+		ORDER BY alert_id DESC
+		LIMIT $4 OFFSET $5
+	`, filters.RuleID, filters.SGCID, filters.SessionID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*manman.AlertEvent
+	for rows.Next() {
+		e := &manman.AlertEvent{}
+		if err := rows.Scan(&e.AlertID, &e.RuleID, &e.SessionID, &e.SGCID, &e.MatchCount, &e.Line, &e.FirstMatchAt, &e.FiredAt,
+			&e.ActionExecutionID, &e.BackupID, &e.ResponseError); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// AuditEventRepository implements repository.AuditEventRepository
+type AuditEventRepository struct {
+	db DB
+}
+
+func NewAuditEventRepository(db DB) *AuditEventRepository {
+	return &AuditEventRepository{db: db}
+}
+
+func (r *AuditEventRepository) Create(ctx context.Context, e *manman.AuditEvent) (*manman.AuditEvent, error) {
+	entities := e.Entities
+	if entities == nil {
+		entities = map[string]int64{}
+	}
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO audit_events (occurred_at, subject, username, method, entities, request, changes, status_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING audit_event_id
+	`, e.OccurredAt, e.Subject, e.Username, e.Method, entities, e.Request, e.Changes, e.StatusCode, e.Error, e.DurationMs).Scan(&e.AuditEventID)
+	return e, err
+}
+
+func (r *AuditEventRepository) List(ctx context.Context, filters *repository.AuditEventFilters, limit, offset int) ([]*manman.AuditEvent, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if filters == nil {
+		filters = &repository.AuditEventFilters{}
+	}
+
+	whereClauses := []string{}
+	args := []interface{}{}
+	add := func(clause string, arg interface{}) {
+		args = append(args, arg)
+		whereClauses = append(whereClauses, fmt.Sprintf(clause, len(args)))
+	}
+
+	if filters.Subject != "" {
+		add("(subject = $%[1]d OR username = $%[1]d)", filters.Subject)
+	}
+	if filters.Method != "" {
+		add("method = $%d", filters.Method)
+	}
+	if filters.EntityKey != "" && filters.EntityID != 0 {
+		// Containment uses the GIN index on entities
+		add("entities @> $%d::jsonb", map[string]int64{filters.EntityKey: filters.EntityID})
+	}
+	if filters.Since != nil {
+		add("occurred_at >= $%d", *filters.Since)
+	}
+	if filters.Until != nil {
+		add("occurred_at < $%d", *filters.Until)
+	}
+
+	query := `
+		SELECT audit_event_id, occurred_at, subject, username, method, entities, request, changes, status_code, error, duration_ms
+		FROM audit_events
+	`
+	if len(whereClauses) > 0 {
+		query += " WHERE " + strings.Join(whereClauses, " AND ")
+	}
+	args = append(args, limit, offset)
+	query += fmt.Sprintf(" ORDER BY audit_event_id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*manman.AuditEvent
+	for rows.Next() {
+		e := &manman.AuditEvent{}
+		if err := rows.Scan(&e.AuditEventID, &e.OccurredAt, &e.Subject, &e.Username, &e.Method, &e.Entities, &e.Request, &e.Changes, &e.StatusCode, &e.Error, &e.DurationMs); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -4,15 +4,14 @@ import (
 	"context"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type BackupRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewBackupRepository(db *pgxpool.Pool) *BackupRepository {
+func NewBackupRepository(db DB) *BackupRepository {
 	return &BackupRepository{db: db}
 }
 
@@ -42,13 +41,13 @@ func (r *BackupRepository) Create(ctx context.Context, backup *manman.Backup) (*
 func (r *BackupRepository) Get(ctx context.Context, backupID int64) (*manman.Backup, error) {
 	query := `
 		SELECT backup_id, session_id, server_game_config_id, backup_config_id, volume_id,
-		       s3_url, size_bytes, status, error_message, description, created_at
+		       s3_url, size_bytes, checksum, status, error_message, description, pruned_reason, created_at
 		FROM backups WHERE backup_id = $1 AND deleted_at IS NULL
 	`
 	b := &manman.Backup{}
 	err := r.db.QueryRow(ctx, query, backupID).Scan(
 		&b.BackupID, &b.SessionID, &b.ServerGameConfigID, &b.BackupConfigID, &b.VolumeID,
-		&b.S3URL, &b.SizeBytes, &b.Status, &b.ErrorMessage, &b.Description, &b.CreatedAt,
+		&b.S3URL, &b.SizeBytes, &b.Checksum, &b.Status, &b.ErrorMessage, &b.Description, &b.PrunedReason, &b.CreatedAt,
 	)
 	if err != nil {
 		return nil, err
@@ -56,18 +55,18 @@ func (r *BackupRepository) Get(ctx context.Context, backupID int64) (*manman.Bac
 	return b, nil
 }
 
-func (r *BackupRepository) List(ctx context.Context, sgcID *int64, sessionID *int64, limit int, offset int) ([]*manman.Backup, error) {
+func (r *BackupRepository) List(ctx context.Context, sgcID *int64, sessionID *int64, includePruned bool, limit int, offset int) ([]*manman.Backup, error) {
 	query := `
 		SELECT backup_id, session_id, server_game_config_id, backup_config_id, volume_id,
-		       s3_url, size_bytes, status, error_message, description, created_at
+		       s3_url, size_bytes, checksum, status, error_message, description, pruned_reason, created_at
 		FROM backups
 		WHERE ($1::bigint IS NULL OR server_game_config_id = $1)
 		  AND ($2::bigint IS NULL OR session_id = $2)
-		  AND deleted_at IS NULL
+		  AND (deleted_at IS NULL OR ($5 AND pruned_reason IS NOT NULL))
 		ORDER BY created_at DESC
 		LIMIT $3 OFFSET $4
 	`
-	rows, err := r.db.Query(ctx, query, sgcID, sessionID, limit, offset)
+	rows, err := r.db.Query(ctx, query, sgcID, sessionID, limit, offset, includePruned)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +77,7 @@ func (r *BackupRepository) List(ctx context.Context, sgcID *int64, sessionID *in
 		b := &manman.Backup{}
 		if err := rows.Scan(
 			&b.BackupID, &b.SessionID, &b.ServerGameConfigID, &b.BackupConfigID, &b.VolumeID,
-			&b.S3URL, &b.SizeBytes, &b.Status, &b.ErrorMessage, &b.Description, &b.CreatedAt,
+			&b.S3URL, &b.SizeBytes, &b.Checksum, &b.Status, &b.ErrorMessage, &b.Description, &b.PrunedReason, &b.CreatedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -92,6 +91,41 @@ func (r *BackupRepository) Delete(ctx context.Context, backupID int64) error {
 	return err
 }
 
+func (r *BackupRepository) ListCompletedByConfig(ctx context.Context, backupConfigID int64) ([]*manman.Backup, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT backup_id, session_id, server_game_config_id, backup_config_id, volume_id,
+		       s3_url, size_bytes, checksum, status, error_message, description, pruned_reason, created_at
+		FROM backups
+		WHERE backup_config_id = $1 AND status = $2 AND deleted_at IS NULL
+		ORDER BY created_at DESC, backup_id DESC
+	`, backupConfigID, manman.BackupStatusCompleted)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backups []*manman.Backup
+	for rows.Next() {
+		b := &manman.Backup{}
+		if err := rows.Scan(
+			&b.BackupID, &b.SessionID, &b.ServerGameConfigID, &b.BackupConfigID, &b.VolumeID,
+			&b.S3URL, &b.SizeBytes, &b.Checksum, &b.Status, &b.ErrorMessage, &b.Description, &b.PrunedReason, &b.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	return backups, rows.Err()
+}
+
+func (r *BackupRepository) Prune(ctx context.Context, backupID int64, reason string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE backups SET deleted_at = NOW(), pruned_reason = $2
+		WHERE backup_id = $1 AND deleted_at IS NULL
+	`, backupID, reason)
+	return err
+}
+
 func (r *BackupRepository) UpdateStatus(ctx context.Context, backupID int64, status string, s3URL *string, sizeBytes *int64, errMsg *string) error {
 	_, err := r.db.Exec(ctx, `
 		UPDATE backups SET status = $2, s3_url = $3, size_bytes = $4, error_message = $5
@@ -100,21 +134,33 @@ func (r *BackupRepository) UpdateStatus(ctx context.Context, backupID int64, sta
 	return err
 }
 
+func (r *BackupRepository) SetChecksum(ctx context.Context, backupID int64, checksum string) error {
+	_, err := r.db.Exec(ctx, `UPDATE backups SET checksum = $2 WHERE backup_id = $1`, backupID, checksum)
+	return err
+}
+
 // BackupConfigRepository implements repository.BackupConfigRepository
 type BackupConfigRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewBackupConfigRepository(db *pgxpool.Pool) *BackupConfigRepository {
+func NewBackupConfigRepository(db DB) *BackupConfigRepository {
 	return &BackupConfigRepository{db: db}
 }
 
 func (r *BackupConfigRepository) Create(ctx context.Context, cfg *manman.BackupConfig) (*manman.BackupConfig, error) {
 	err := r.db.QueryRow(ctx, `
-		INSERT INTO backup_configs (volume_id, cadence_minutes, backup_path, enabled, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		INSERT INTO backup_configs (
+			volume_id, cadence_minutes, backup_path, enabled,
+			retention_keep_last, retention_daily_days, retention_weekly_weeks,
+			retention_monthly_months, retention_max_total_bytes,
+			created_at, updated_at
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
 		RETURNING backup_config_id, created_at, updated_at
 	`, cfg.VolumeID, cfg.CadenceMinutes, cfg.BackupPath, cfg.Enabled,
+		cfg.RetentionKeepLast, cfg.RetentionDailyDays, cfg.RetentionWeeklyWeeks,
+		cfg.RetentionMonthlyMonths, cfg.RetentionMaxTotalBytes,
 	).Scan(&cfg.BackupConfigID, &cfg.CreatedAt, &cfg.UpdatedAt)
 	return cfg, err
 }
@@ -122,11 +168,14 @@ func (r *BackupConfigRepository) Create(ctx context.Context, cfg *manman.BackupC
 func (r *BackupConfigRepository) Get(ctx context.Context, id int64) (*manman.BackupConfig, error) {
 	cfg := &manman.BackupConfig{}
 	err := r.db.QueryRow(ctx, `
-		SELECT backup_config_id, volume_id, cadence_minutes, backup_path, enabled, last_backup_at, created_at, updated_at
+		SELECT backup_config_id, volume_id, cadence_minutes, backup_path, enabled, last_backup_at, created_at, updated_at,
+		       retention_keep_last, retention_daily_days, retention_weekly_weeks, retention_monthly_months, retention_max_total_bytes
 		FROM backup_configs WHERE backup_config_id = $1 AND deleted_at IS NULL
 	`, id).Scan(
 		&cfg.BackupConfigID, &cfg.VolumeID, &cfg.CadenceMinutes, &cfg.BackupPath,
 		&cfg.Enabled, &cfg.LastBackupAt, &cfg.CreatedAt, &cfg.UpdatedAt,
+		&cfg.RetentionKeepLast, &cfg.RetentionDailyDays, &cfg.RetentionWeeklyWeeks,
+		&cfg.RetentionMonthlyMonths, &cfg.RetentionMaxTotalBytes,
 	)
 	if err != nil {
 		return nil, err
@@ -136,7 +185,8 @@ func (r *BackupConfigRepository) Get(ctx context.Context, id int64) (*manman.Bac
 
 func (r *BackupConfigRepository) List(ctx context.Context, volumeID int64) ([]*manman.BackupConfig, error) {
 	rows, err := r.db.Query(ctx, `
-		SELECT backup_config_id, volume_id, cadence_minutes, backup_path, enabled, last_backup_at, created_at, updated_at
+		SELECT backup_config_id, volume_id, cadence_minutes, backup_path, enabled, last_backup_at, created_at, updated_at,
+		       retention_keep_last, retention_daily_days, retention_weekly_weeks, retention_monthly_months, retention_max_total_bytes
 		FROM backup_configs WHERE volume_id = $1 AND deleted_at IS NULL ORDER BY backup_config_id
 	`, volumeID)
 	if err != nil {
@@ -150,6 +200,8 @@ func (r *BackupConfigRepository) List(ctx context.Context, volumeID int64) ([]*m
 		if err := rows.Scan(
 			&cfg.BackupConfigID, &cfg.VolumeID, &cfg.CadenceMinutes, &cfg.BackupPath,
 			&cfg.Enabled, &cfg.LastBackupAt, &cfg.CreatedAt, &cfg.UpdatedAt,
+			&cfg.RetentionKeepLast, &cfg.RetentionDailyDays, &cfg.RetentionWeeklyWeeks,
+			&cfg.RetentionMonthlyMonths, &cfg.RetentionMaxTotalBytes,
 		); err != nil {
 			return nil, err
 		}
@@ -161,9 +213,14 @@ func (r *BackupConfigRepository) List(ctx context.Context, volumeID int64) ([]*m
 func (r *BackupConfigRepository) Update(ctx context.Context, cfg *manman.BackupConfig) error {
 	_, err := r.db.Exec(ctx, `
 		UPDATE backup_configs
-		SET cadence_minutes = $2, backup_path = $3, enabled = $4, updated_at = NOW()
+		SET cadence_minutes = $2, backup_path = $3, enabled = $4,
+		    retention_keep_last = $5, retention_daily_days = $6, retention_weekly_weeks = $7,
+		    retention_monthly_months = $8, retention_max_total_bytes = $9,
+		    updated_at = NOW()
 		WHERE backup_config_id = $1
-	`, cfg.BackupConfigID, cfg.CadenceMinutes, cfg.BackupPath, cfg.Enabled)
+	`, cfg.BackupConfigID, cfg.CadenceMinutes, cfg.BackupPath, cfg.Enabled,
+		cfg.RetentionKeepLast, cfg.RetentionDailyDays, cfg.RetentionWeeklyWeeks,
+		cfg.RetentionMonthlyMonths, cfg.RetentionMaxTotalBytes)
 	return err
 }
 
@@ -177,7 +234,9 @@ func (r *BackupConfigRepository) Delete(ctx context.Context, id int64) error {
 func (r *BackupConfigRepository) ListDue(ctx context.Context, now time.Time) ([]*manman.BackupConfig, error) {
 	rows, err := r.db.Query(ctx, `
 		SELECT DISTINCT bc.backup_config_id, bc.volume_id, bc.cadence_minutes, bc.backup_path,
-		                bc.enabled, bc.last_backup_at, bc.created_at, bc.updated_at
+		                bc.enabled, bc.last_backup_at, bc.created_at, bc.updated_at,
+		                bc.retention_keep_last, bc.retention_daily_days, bc.retention_weekly_weeks,
+		                bc.retention_monthly_months, bc.retention_max_total_bytes
 		FROM backup_configs bc
 		JOIN game_config_volumes gcv ON gcv.volume_id = bc.volume_id
 		JOIN game_configs gc ON gc.config_id = gcv.config_id
@@ -208,6 +267,8 @@ func (r *BackupConfigRepository) ListDue(ctx context.Context, now time.Time) ([]
 		if err := rows.Scan(
 			&cfg.BackupConfigID, &cfg.VolumeID, &cfg.CadenceMinutes, &cfg.BackupPath,
 			&cfg.Enabled, &cfg.LastBackupAt, &cfg.CreatedAt, &cfg.UpdatedAt,
+			&cfg.RetentionKeepLast, &cfg.RetentionDailyDays, &cfg.RetentionWeeklyWeeks,
+			&cfg.RetentionMonthlyMonths, &cfg.RetentionMaxTotalBytes,
 		); err != nil {
 			return nil, err
 		}
@@ -223,6 +284,37 @@ func (r *BackupConfigRepository) UpdateLastBackupAt(ctx context.Context, id int6
 	return err
 }
 
+func (r *BackupConfigRepository) ListWithRetention(ctx context.Context) ([]*manman.BackupConfig, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT backup_config_id, volume_id, cadence_minutes, backup_path, enabled, last_backup_at, created_at, updated_at,
+		       retention_keep_last, retention_daily_days, retention_weekly_weeks, retention_monthly_months, retention_max_total_bytes
+		FROM backup_configs
+		WHERE deleted_at IS NULL
+		  AND (retention_keep_last > 0 OR retention_daily_days > 0 OR retention_weekly_weeks > 0
+		       OR retention_monthly_months > 0 OR retention_max_total_bytes > 0)
+		ORDER BY backup_config_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cfgs []*manman.BackupConfig
+	for rows.Next() {
+		cfg := &manman.BackupConfig{}
+		if err := rows.Scan(
+			&cfg.BackupConfigID, &cfg.VolumeID, &cfg.CadenceMinutes, &cfg.BackupPath,
+			&cfg.Enabled, &cfg.LastBackupAt, &cfg.CreatedAt, &cfg.UpdatedAt,
+			&cfg.RetentionKeepLast, &cfg.RetentionDailyDays, &cfg.RetentionWeeklyWeeks,
+			&cfg.RetentionMonthlyMonths, &cfg.RetentionMaxTotalBytes,
+		); err != nil {
+			return nil, err
+		}
+		cfgs = append(cfgs, cfg)
+	}
+	return cfgs, rows.Err()
+}
+
 func (r *BackupConfigRepository) AddAction(ctx context.Context, backupConfigID, actionID int64, displayOrder int) error {
 	_, err := r.db.Exec(ctx, `
 		INSERT INTO backup_config_actions (backup_config_id, action_id, display_order)
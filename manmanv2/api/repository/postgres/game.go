@@ -3,26 +3,25 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type GameRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewGameRepository(db *pgxpool.Pool) *GameRepository {
+func NewGameRepository(db DB) *GameRepository {
 	return &GameRepository{db: db}
 }
 
 func (r *GameRepository) Create(ctx context.Context, game *manman.Game) (*manman.Game, error) {
 	query := `
-		INSERT INTO games (name, steam_app_id, metadata)
-		VALUES ($1, $2, $3)
+		INSERT INTO games (name, steam_app_id, metadata, player_events)
+		VALUES ($1, $2, $3, $4)
 		RETURNING game_id
 	`
 
-	err := r.db.QueryRow(ctx, query, game.Name, game.SteamAppID, game.Metadata).Scan(&game.GameID)
+	err := r.db.QueryRow(ctx, query, game.Name, game.SteamAppID, game.Metadata, game.PlayerEvents).Scan(&game.GameID)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +33,7 @@ func (r *GameRepository) Get(ctx context.Context, gameID int64) (*manman.Game, e
 	game := &manman.Game{}
 
 	query := `
-		SELECT game_id, name, steam_app_id, metadata
+		SELECT game_id, name, steam_app_id, metadata, player_events
 		FROM games
 		WHERE game_id = $1
 	`
@@ -44,6 +43,30 @@ func (r *GameRepository) Get(ctx context.Context, gameID int64) (*manman.Game, e
 		&game.Name,
 		&game.SteamAppID,
 		&game.Metadata,
+		&game.PlayerEvents,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return game, nil
+}
+
+func (r *GameRepository) GetByName(ctx context.Context, name string) (*manman.Game, error) {
+	game := &manman.Game{}
+
+	query := `
+		SELECT game_id, name, steam_app_id, metadata, player_events
+		FROM games
+		WHERE name = $1
+	`
+
+	err := r.db.QueryRow(ctx, query, name).Scan(
+		&game.GameID,
+		&game.Name,
+		&game.SteamAppID,
+		&game.Metadata,
+		&game.PlayerEvents,
 	)
 	if err != nil {
 		return nil, err
@@ -58,7 +81,7 @@ func (r *GameRepository) List(ctx context.Context, limit, offset int) ([]*manman
 	}
 
 	query := `
-		SELECT game_id, name, steam_app_id, metadata
+		SELECT game_id, name, steam_app_id, metadata, player_events
 		FROM games
 		ORDER BY game_id
 		LIMIT $1 OFFSET $2
@@ -78,6 +101,7 @@ func (r *GameRepository) List(ctx context.Context, limit, offset int) ([]*manman
 			&game.Name,
 			&game.SteamAppID,
 			&game.Metadata,
+			&game.PlayerEvents,
 		)
 		if err != nil {
 			return nil, err
@@ -91,11 +115,11 @@ func (r *GameRepository) List(ctx context.Context, limit, offset int) ([]*manman
 func (r *GameRepository) Update(ctx context.Context, game *manman.Game) error {
 	query := `
 		UPDATE games
-		SET name = $2, steam_app_id = $3, metadata = $4
+		SET name = $2, steam_app_id = $3, metadata = $4, player_events = $5
 		WHERE game_id = $1
 	`
 
-	_, err := r.db.Exec(ctx, query, game.GameID, game.Name, game.SteamAppID, game.Metadata)
+	_, err := r.db.Exec(ctx, query, game.GameID, game.Name, game.SteamAppID, game.Metadata, game.PlayerEvents)
 	return err
 }
 
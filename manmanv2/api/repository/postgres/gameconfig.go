@@ -3,22 +3,23 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type GameConfigRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewGameConfigRepository(db *pgxpool.Pool) *GameConfigRepository {
+func NewGameConfigRepository(db DB) *GameConfigRepository {
 	return &GameConfigRepository{db: db}
 }
 
 func (r *GameConfigRepository) Create(ctx context.Context, config *manman.GameConfig) (*manman.GameConfig, error) {
 	query := `
-		INSERT INTO game_configs (game_id, name, image, args_template, env_template, entrypoint, command)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO game_configs (game_id, name, image, args_template, env_template, entrypoint, command,
+		                          cpu_millicores, memory_mb, pids_limit, ulimits, readiness_probe,
+		                          input_transport, status_query)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING config_id
 	`
 
@@ -30,6 +31,13 @@ func (r *GameConfigRepository) Create(ctx context.Context, config *manman.GameCo
 		config.EnvTemplate,
 		config.Entrypoint,
 		config.Command,
+		config.CPUMillicores,
+		config.MemoryMB,
+		config.PidsLimit,
+		config.Ulimits,
+		config.ReadinessProbe,
+		config.InputTransport,
+		config.StatusQuery,
 	).Scan(&config.ConfigID)
 	if err != nil {
 		return nil, err
@@ -42,7 +50,8 @@ func (r *GameConfigRepository) Get(ctx context.Context, configID int64) (*manman
 	config := &manman.GameConfig{}
 
 	query := `
-		SELECT config_id, game_id, name, image, args_template, env_template, entrypoint, command
+		SELECT config_id, game_id, name, image, args_template, env_template, entrypoint, command,
+		       cpu_millicores, memory_mb, pids_limit, ulimits, readiness_probe, input_transport, status_query
 		FROM game_configs
 		WHERE config_id = $1
 	`
@@ -56,6 +65,13 @@ func (r *GameConfigRepository) Get(ctx context.Context, configID int64) (*manman
 		&config.EnvTemplate,
 		&config.Entrypoint,
 		&config.Command,
+		&config.CPUMillicores,
+		&config.MemoryMB,
+		&config.PidsLimit,
+		&config.Ulimits,
+		&config.ReadinessProbe,
+		&config.InputTransport,
+		&config.StatusQuery,
 	)
 	if err != nil {
 		return nil, err
@@ -74,7 +90,8 @@ func (r *GameConfigRepository) List(ctx context.Context, gameID *int64, limit, o
 
 	if gameID != nil {
 		query = `
-			SELECT config_id, game_id, name, image, args_template, env_template, entrypoint, command
+			SELECT config_id, game_id, name, image, args_template, env_template, entrypoint, command,
+			       cpu_millicores, memory_mb, pids_limit, ulimits, readiness_probe, input_transport, status_query
 			FROM game_configs
 			WHERE game_id = $1
 			ORDER BY config_id
@@ -83,7 +100,8 @@ func (r *GameConfigRepository) List(ctx context.Context, gameID *int64, limit, o
 		args = []interface{}{*gameID, limit, offset}
 	} else {
 		query = `
-			SELECT config_id, game_id, name, image, args_template, env_template, entrypoint, command
+			SELECT config_id, game_id, name, image, args_template, env_template, entrypoint, command,
+			       cpu_millicores, memory_mb, pids_limit, ulimits, readiness_probe, input_transport, status_query
 			FROM game_configs
 			ORDER BY config_id
 			LIMIT $1 OFFSET $2
@@ -109,6 +127,13 @@ func (r *GameConfigRepository) List(ctx context.Context, gameID *int64, limit, o
 			&config.EnvTemplate,
 			&config.Entrypoint,
 			&config.Command,
+			&config.CPUMillicores,
+			&config.MemoryMB,
+			&config.PidsLimit,
+			&config.Ulimits,
+			&config.ReadinessProbe,
+			&config.InputTransport,
+			&config.StatusQuery,
 		)
 		if err != nil {
 			return nil, err
@@ -122,7 +147,9 @@ func (r *GameConfigRepository) List(ctx context.Context, gameID *int64, limit, o
 func (r *GameConfigRepository) Update(ctx context.Context, config *manman.GameConfig) error {
 	query := `
 		UPDATE game_configs
-		SET name = $2, image = $3, args_template = $4, env_template = $5, entrypoint = $6, command = $7
+		SET name = $2, image = $3, args_template = $4, env_template = $5, entrypoint = $6, command = $7,
+		    cpu_millicores = $8, memory_mb = $9, pids_limit = $10, ulimits = $11, readiness_probe = $12,
+		    input_transport = $13, status_query = $14
 		WHERE config_id = $1
 	`
 
@@ -134,6 +161,13 @@ func (r *GameConfigRepository) Update(ctx context.Context, config *manman.GameCo
 		config.EnvTemplate,
 		config.Entrypoint,
 		config.Command,
+		config.CPUMillicores,
+		config.MemoryMB,
+		config.PidsLimit,
+		config.Ulimits,
+		config.ReadinessProbe,
+		config.InputTransport,
+		config.StatusQuery,
 	)
 	return err
 }
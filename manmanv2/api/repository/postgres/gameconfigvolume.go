@@ -3,15 +3,14 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type GameConfigVolumeRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewGameConfigVolumeRepository(db *pgxpool.Pool) *GameConfigVolumeRepository {
+func NewGameConfigVolumeRepository(db DB) *GameConfigVolumeRepository {
 	return &GameConfigVolumeRepository{db: db}
 }
 
@@ -5,15 +5,15 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
+	"github.com/whale-net/everything/manmanv2/api/repository"
 )
 
 type LogReferenceRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewLogReferenceRepository(db *pgxpool.Pool) *LogReferenceRepository {
+func NewLogReferenceRepository(db DB) *LogReferenceRepository {
 	return &LogReferenceRepository{db: db}
 }
 
@@ -304,3 +304,71 @@ func (r *LogReferenceRepository) GetHistogramBySession(ctx context.Context, sess
 
 	return histogram, rows.Err()
 }
+
+func (r *LogReferenceRepository) SetSearchIndex(ctx context.Context, logID int64, sources, terms []string) error {
+	query := `
+		UPDATE log_references
+		SET sources = $2, terms = $3
+		WHERE log_id = $1
+	`
+
+	_, err := r.db.Exec(ctx, query, logID, sources, terms)
+	return err
+}
+
+func (r *LogReferenceRepository) SearchWindows(ctx context.Context, filters *repository.LogSearchFilters, limit int) ([]*manman.ArchivedLogWindow, error) {
+	// A window matches when any of its references does: each holds the index of the
+	// lines it uploaded, and a line is only ever in one of them
+	query := `
+		SELECT lr.session_id, lr.sgc_id, sgc.server_id, MIN(lr.file_path), lr.minute_timestamp
+		FROM log_references lr
+		JOIN server_game_configs sgc ON sgc.sgc_id = lr.sgc_id
+		WHERE lr.state = 'complete'
+		  AND lr.minute_timestamp >= $1 AND lr.minute_timestamp <= $2
+		  AND (cardinality($3::bigint[]) = 0 OR lr.session_id = ANY($3))
+		  AND (cardinality($4::bigint[]) = 0 OR lr.sgc_id = ANY($4))
+		  AND (cardinality($5::bigint[]) = 0 OR sgc.server_id = ANY($5))
+		  AND (cardinality($6::text[]) = 0 OR lr.sources IS NULL OR lr.sources && $6)
+		  AND (cardinality($7::text[]) = 0 OR lr.terms IS NULL OR lr.terms @> $7)
+		  AND ($8::timestamp IS NULL OR (lr.minute_timestamp, lr.session_id) >= ($8, $9::bigint))
+		GROUP BY lr.session_id, lr.sgc_id, sgc.server_id, lr.minute_timestamp
+		ORDER BY lr.minute_timestamp, lr.session_id
+		LIMIT $10
+	`
+
+	var afterMinute *time.Time
+	var afterSession int64
+	if filters.After != nil {
+		afterMinute = &filters.After.MinuteTimestamp
+		afterSession = filters.After.SessionID
+	}
+
+	// Windows are keyed by the minute they start, so the one holding Start begins before it
+	rows, err := r.db.Query(ctx, query,
+		filters.Start.Truncate(time.Minute),
+		filters.End,
+		nonNilInt64s(filters.SessionIDs),
+		nonNilInt64s(filters.SGCIDs),
+		nonNilInt64s(filters.ServerIDs),
+		nonNilStrings(filters.Sources),
+		nonNilStrings(filters.Terms),
+		afterMinute,
+		afterSession,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var windows []*manman.ArchivedLogWindow
+	for rows.Next() {
+		w := &manman.ArchivedLogWindow{}
+		if err := rows.Scan(&w.SessionID, &w.SGCID, &w.ServerID, &w.FilePath, &w.MinuteTimestamp); err != nil {
+			return nil, err
+		}
+		windows = append(windows, w)
+	}
+
+	return windows, rows.Err()
+}
@@ -0,0 +1,181 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+const sessionMetricsColumns = `session_id, resolution_seconds, bucket_start, server_id, sgc_id, samples, cpu_percent_sum, cpu_percent_max,
+	memory_bytes_sum, memory_bytes_max, memory_limit_bytes, network_rx_bytes_per_sec_sum, network_tx_bytes_per_sec_sum,
+	block_read_bytes_per_sec_sum, block_write_bytes_per_sec_sum`
+
+const hostMetricsColumns = `server_id, resolution_seconds, bucket_start, samples, disk_total_bytes, disk_used_bytes_max`
+
+// MetricsRepository implements repository.MetricsRepository
+type MetricsRepository struct {
+	db DB
+}
+
+func NewMetricsRepository(db DB) *MetricsRepository {
+	return &MetricsRepository{db: db}
+}
+
+func (r *MetricsRepository) Record(ctx context.Context, sample *manman.MetricsSample) error {
+	bucket := sample.SampledAt.UTC().Truncate(time.Minute)
+
+	batch := &pgx.Batch{}
+	if sample.DiskTotalBytes > 0 {
+		batch.Queue(`
+			INSERT INTO host_metrics (`+hostMetricsColumns+`)
+			VALUES ($1, $2, $3, 1, $4, $5)
+			ON CONFLICT (server_id, resolution_seconds, bucket_start) DO UPDATE SET
+				samples = host_metrics.samples + 1,
+				disk_total_bytes = EXCLUDED.disk_total_bytes,
+				disk_used_bytes_max = GREATEST(host_metrics.disk_used_bytes_max, EXCLUDED.disk_used_bytes_max)
+		`, sample.ServerID, manman.MetricsResolutionMinute, bucket, sample.DiskTotalBytes, sample.DiskUsedBytes)
+	}
+	for _, s := range sample.Sessions {
+		batch.Queue(`
+			INSERT INTO session_metrics (`+sessionMetricsColumns+`)
+			VALUES ($1, $2, $3, $4, $5, 1, $6, $6, $7, $8, $9, $10, $11, $12, $13)
+			ON CONFLICT (session_id, resolution_seconds, bucket_start) DO UPDATE SET
+				samples = session_metrics.samples + 1,
+				cpu_percent_sum = session_metrics.cpu_percent_sum + EXCLUDED.cpu_percent_sum,
+				cpu_percent_max = GREATEST(session_metrics.cpu_percent_max, EXCLUDED.cpu_percent_max),
+				memory_bytes_sum = session_metrics.memory_bytes_sum + EXCLUDED.memory_bytes_sum,
+				memory_bytes_max = GREATEST(session_metrics.memory_bytes_max, EXCLUDED.memory_bytes_max),
+				memory_limit_bytes = EXCLUDED.memory_limit_bytes,
+				network_rx_bytes_per_sec_sum = session_metrics.network_rx_bytes_per_sec_sum + EXCLUDED.network_rx_bytes_per_sec_sum,
+				network_tx_bytes_per_sec_sum = session_metrics.network_tx_bytes_per_sec_sum + EXCLUDED.network_tx_bytes_per_sec_sum,
+				block_read_bytes_per_sec_sum = session_metrics.block_read_bytes_per_sec_sum + EXCLUDED.block_read_bytes_per_sec_sum,
+				block_write_bytes_per_sec_sum = session_metrics.block_write_bytes_per_sec_sum + EXCLUDED.block_write_bytes_per_sec_sum
+		`, s.SessionID, manman.MetricsResolutionMinute, bucket, sample.ServerID, s.SGCID, s.CPUPercent, float64(s.MemoryBytes),
+			s.MemoryBytes, s.MemoryLimitBytes, s.NetworkRxBytesPerSec, s.NetworkTxBytesPerSec, s.BlockReadBytesPerSec, s.BlockWriteBytesPerSec)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	return r.db.SendBatch(ctx, batch).Close()
+}
+
+func (r *MetricsRepository) ListHost(ctx context.Context, serverID int64, start, end time.Time) ([]*manman.HostMetricsBucket, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+hostMetricsColumns+`
+		FROM host_metrics
+		WHERE server_id = $1 AND bucket_start >= $2 AND bucket_start < $3
+		ORDER BY bucket_start, resolution_seconds
+	`, serverID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []*manman.HostMetricsBucket
+	for rows.Next() {
+		b := &manman.HostMetricsBucket{}
+		if err := rows.Scan(&b.ServerID, &b.ResolutionSeconds, &b.BucketStart, &b.Samples, &b.DiskTotalBytes, &b.DiskUsedBytesMax); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+func (r *MetricsRepository) ListSessions(ctx context.Context, serverID int64, start, end time.Time) ([]*manman.SessionMetricsBucket, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+sessionMetricsColumns+`
+		FROM session_metrics
+		WHERE server_id = $1 AND bucket_start >= $2 AND bucket_start < $3
+		ORDER BY session_id, bucket_start, resolution_seconds
+	`, serverID, start.UTC(), end.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var buckets []*manman.SessionMetricsBucket
+	for rows.Next() {
+		b := &manman.SessionMetricsBucket{}
+		if err := rows.Scan(&b.SessionID, &b.ResolutionSeconds, &b.BucketStart, &b.ServerID, &b.SGCID, &b.Samples,
+			&b.CPUPercentSum, &b.CPUPercentMax, &b.MemoryBytesSum, &b.MemoryBytesMax, &b.MemoryLimitBytes,
+			&b.NetworkRxBytesPerSecSum, &b.NetworkTxBytesPerSecSum, &b.BlockReadBytesPerSecSum, &b.BlockWriteBytesPerSecSum); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+func (r *MetricsRepository) Rollup(ctx context.Context, olderThan time.Time) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	// An hour that is still partly within the minute retention is rolled up in pieces;
+	// the upserts add each piece to the same hour bucket
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO session_metrics (`+sessionMetricsColumns+`)
+		SELECT session_id, $2, date_trunc('hour', bucket_start), MAX(server_id), MAX(sgc_id), SUM(samples),
+			SUM(cpu_percent_sum), MAX(cpu_percent_max), SUM(memory_bytes_sum), MAX(memory_bytes_max), MAX(memory_limit_bytes),
+			SUM(network_rx_bytes_per_sec_sum), SUM(network_tx_bytes_per_sec_sum),
+			SUM(block_read_bytes_per_sec_sum), SUM(block_write_bytes_per_sec_sum)
+		FROM session_metrics
+		WHERE resolution_seconds = $1 AND bucket_start < $3
+		GROUP BY session_id, date_trunc('hour', bucket_start)
+		ON CONFLICT (session_id, resolution_seconds, bucket_start) DO UPDATE SET
+			samples = session_metrics.samples + EXCLUDED.samples,
+			cpu_percent_sum = session_metrics.cpu_percent_sum + EXCLUDED.cpu_percent_sum,
+			cpu_percent_max = GREATEST(session_metrics.cpu_percent_max, EXCLUDED.cpu_percent_max),
+			memory_bytes_sum = session_metrics.memory_bytes_sum + EXCLUDED.memory_bytes_sum,
+			memory_bytes_max = GREATEST(session_metrics.memory_bytes_max, EXCLUDED.memory_bytes_max),
+			memory_limit_bytes = EXCLUDED.memory_limit_bytes,
+			network_rx_bytes_per_sec_sum = session_metrics.network_rx_bytes_per_sec_sum + EXCLUDED.network_rx_bytes_per_sec_sum,
+			network_tx_bytes_per_sec_sum = session_metrics.network_tx_bytes_per_sec_sum + EXCLUDED.network_tx_bytes_per_sec_sum,
+			block_read_bytes_per_sec_sum = session_metrics.block_read_bytes_per_sec_sum + EXCLUDED.block_read_bytes_per_sec_sum,
+			block_write_bytes_per_sec_sum = session_metrics.block_write_bytes_per_sec_sum + EXCLUDED.block_write_bytes_per_sec_sum
+	`, manman.MetricsResolutionMinute, manman.MetricsResolutionHour, olderThan.UTC()); err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO host_metrics (`+hostMetricsColumns+`)
+		SELECT server_id, $2, date_trunc('hour', bucket_start), SUM(samples), MAX(disk_total_bytes), MAX(disk_used_bytes_max)
+		FROM host_metrics
+		WHERE resolution_seconds = $1 AND bucket_start < $3
+		GROUP BY server_id, date_trunc('hour', bucket_start)
+		ON CONFLICT (server_id, resolution_seconds, bucket_start) DO UPDATE SET
+			samples = host_metrics.samples + EXCLUDED.samples,
+			disk_total_bytes = EXCLUDED.disk_total_bytes,
+			disk_used_bytes_max = GREATEST(host_metrics.disk_used_bytes_max, EXCLUDED.disk_used_bytes_max)
+	`, manman.MetricsResolutionMinute, manman.MetricsResolutionHour, olderThan.UTC()); err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, `DELETE FROM session_metrics WHERE resolution_seconds = $1 AND bucket_start < $2`,
+		manman.MetricsResolutionMinute, olderThan.UTC())
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM host_metrics WHERE resolution_seconds = $1 AND bucket_start < $2`,
+		manman.MetricsResolutionMinute, olderThan.UTC()); err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), tx.Commit(ctx)
+}
+
+func (r *MetricsRepository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.db.Exec(ctx, `DELETE FROM session_metrics WHERE bucket_start < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	hostTag, err := r.db.Exec(ctx, `DELETE FROM host_metrics WHERE bucket_start < $1`, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected() + hostTag.RowsAffected(), nil
+}
@@ -3,15 +3,14 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ConfigurationPatchRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewConfigurationPatchRepository(db *pgxpool.Pool) *ConfigurationPatchRepository {
+func NewConfigurationPatchRepository(db DB) *ConfigurationPatchRepository {
 	return &ConfigurationPatchRepository{db: db}
 }
 
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// PlayerSessionRepository implements repository.PlayerSessionRepository
+type PlayerSessionRepository struct {
+	db DB
+}
+
+func NewPlayerSessionRepository(db DB) *PlayerSessionRepository {
+	return &PlayerSessionRepository{db: db}
+}
+
+func (r *PlayerSessionRepository) Join(ctx context.Context, sessionID, sgcID int64, playerName string, playerID *string, at time.Time) error {
+	// idx_player_sessions_open allows one open stay per player per session
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO player_sessions (session_id, sgc_id, player_name, player_id, joined_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT (session_id, player_name) WHERE left_at IS NULL DO NOTHING
+	`, sessionID, sgcID, playerName, playerID, at)
+	return err
+}
+
+func (r *PlayerSessionRepository) Leave(ctx context.Context, sessionID int64, playerName string, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE player_sessions
+		SET left_at = GREATEST($3, joined_at), last_seen_at = GREATEST($3, last_seen_at)
+		WHERE session_id = $1 AND player_name = $2 AND left_at IS NULL
+	`, sessionID, playerName, at)
+	return err
+}
+
+func (r *PlayerSessionRepository) RecordChat(ctx context.Context, sessionID int64, playerName string, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE player_sessions
+		SET chat_messages = chat_messages + 1, last_seen_at = GREATEST($3, last_seen_at)
+		WHERE session_id = $1 AND player_name = $2 AND left_at IS NULL
+	`, sessionID, playerName, at)
+	return err
+}
+
+func (r *PlayerSessionRepository) EndSession(ctx context.Context, sessionID int64, at time.Time) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE player_sessions
+		SET left_at = GREATEST($2, last_seen_at)
+		WHERE session_id = $1 AND left_at IS NULL
+	`, sessionID, at)
+	return err
+}
+
+func (r *PlayerSessionRepository) ListBySession(ctx context.Context, sessionID int64, onlineOnly bool) ([]*manman.PlayerSession, error) {
+	return r.list(ctx, `
+		SELECT player_session_id, session_id, sgc_id, player_name, player_id, joined_at, left_at,
+		       chat_messages, last_seen_at
+		FROM player_sessions
+		WHERE session_id = $1 AND (NOT $2 OR left_at IS NULL)
+		ORDER BY joined_at, player_session_id
+	`, sessionID, onlineOnly)
+}
+
+func (r *PlayerSessionRepository) ListBySGC(ctx context.Context, sgcID int64, since, until time.Time) ([]*manman.PlayerSession, error) {
+	return r.list(ctx, `
+		SELECT player_session_id, session_id, sgc_id, player_name, player_id, joined_at, left_at,
+		       chat_messages, last_seen_at
+		FROM player_sessions
+		WHERE sgc_id = $1 AND joined_at < $3 AND (left_at IS NULL OR left_at > $2)
+		ORDER BY joined_at, player_session_id
+	`, sgcID, since, until)
+}
+
+func (r *PlayerSessionRepository) list(ctx context.Context, query string, args ...interface{}) ([]*manman.PlayerSession, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var stays []*manman.PlayerSession
+	for rows.Next() {
+		p := &manman.PlayerSession{}
+		if err := rows.Scan(
+			&p.PlayerSessionID, &p.SessionID, &p.SGCID, &p.PlayerName, &p.PlayerID, &p.JoinedAt, &p.LeftAt,
+			&p.ChatMessages, &p.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		stays = append(stays, p)
+	}
+	return stays, rows.Err()
+}
@@ -1,13 +1,32 @@
 package postgres
 
 import (
-	"github.com/jackc/pgx/v5/pgxpool"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 )
 
-// NewRepository creates a new repository from an existing connection pool.
-func NewRepository(pool *pgxpool.Pool) *repository.Repository {
+// DB is what the repositories query: a connection pool, or a transaction when a caller
+// needs several repositories' writes to land together. Begin on a transaction opens a
+// savepoint, so repository methods that use their own transaction still work inside one.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	SendBatch(ctx context.Context, b *pgx.Batch) pgx.BatchResults
+}
+
+// NewRepository creates a new repository from an existing connection pool or transaction.
+func NewRepository(pool DB) *repository.Repository {
 	return &repository.Repository{
+		InTx: func(ctx context.Context, fn func(tx *repository.Repository) error) error {
+			return pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+				return fn(NewRepository(tx))
+			})
+		},
 		Servers:                 NewServerRepository(pool),
 		Games:                   NewGameRepository(pool),
 		GameConfigs:             NewGameConfigRepository(pool),
@@ -15,8 +34,19 @@ func NewRepository(pool *pgxpool.Pool) *repository.Repository {
 		Sessions:                NewSessionRepository(pool),
 		ServerCapabilities:      NewServerCapabilityRepository(pool),
 		LogReferences:           NewLogReferenceRepository(pool),
+		PlayerSessions:          NewPlayerSessionRepository(pool),
 		Backups:                 NewBackupRepository(pool),
 		BackupConfigs:           NewBackupConfigRepository(pool),
+		SGCSchedules:            NewSGCScheduleRepository(pool),
+		SGCGrants:               NewSGCGrantRepository(pool),
+		SGCMigrations:           NewSGCMigrationRepository(pool),
+		SGCImageStatuses:        NewSGCImageStatusRepository(pool),
+		AuditEvents:             NewAuditEventRepository(pool),
+		Webhooks:                NewWebhookRepository(pool),
+		WebhookDeliveries:       NewWebhookDeliveryRepository(pool),
+		AlertRules:              NewAlertRuleRepository(pool),
+		AlertEvents:             NewAlertEventRepository(pool),
+		Metrics:                 NewMetricsRepository(pool),
 		ServerPorts:             NewServerPortRepository(pool),
 		ConfigurationStrategies: NewConfigurationStrategyRepository(pool),
 		ConfigurationPatches:    NewConfigurationPatchRepository(pool),
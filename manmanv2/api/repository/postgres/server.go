@@ -4,15 +4,14 @@ import (
 	"context"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ServerRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewServerRepository(db *pgxpool.Pool) *ServerRepository {
+func NewServerRepository(db DB) *ServerRepository {
 	return &ServerRepository{db: db}
 }
 
@@ -21,6 +20,7 @@ func (r *ServerRepository) Create(ctx context.Context, name string) (*manman.Ser
 		Name:      name,
 		Status:    manman.ServerStatusOffline,
 		IsDefault: false, // Will be set after checking if first server
+		Labels:    map[string]string{},
 	}
 
 	// Check if this will be the first server
@@ -58,7 +58,7 @@ func (r *ServerRepository) Get(ctx context.Context, serverID int64) (*manman.Ser
 	server := &manman.Server{}
 
 	query := `
-		SELECT server_id, name, status, last_seen, is_default
+		SELECT server_id, name, status, last_seen, is_default, labels
 		FROM servers
 		WHERE server_id = $1
 	`
@@ -69,6 +69,7 @@ func (r *ServerRepository) Get(ctx context.Context, serverID int64) (*manman.Ser
 		&server.Status,
 		&server.LastSeen,
 		&server.IsDefault,
+		&server.Labels,
 	)
 	if err != nil {
 		return nil, err
@@ -81,7 +82,7 @@ func (r *ServerRepository) GetByName(ctx context.Context, name string) (*manman.
 	server := &manman.Server{}
 
 	query := `
-		SELECT server_id, name, status, last_seen, is_default
+		SELECT server_id, name, status, last_seen, is_default, labels
 		FROM servers
 		WHERE name = $1
 	`
@@ -92,6 +93,7 @@ func (r *ServerRepository) GetByName(ctx context.Context, name string) (*manman.
 		&server.Status,
 		&server.LastSeen,
 		&server.IsDefault,
+		&server.Labels,
 	)
 	if err != nil {
 		return nil, err
@@ -106,7 +108,7 @@ func (r *ServerRepository) List(ctx context.Context, limit, offset int) ([]*manm
 	}
 
 	query := `
-		SELECT server_id, name, status, last_seen, is_default
+		SELECT server_id, name, status, last_seen, is_default, labels
 		FROM servers
 		ORDER BY server_id
 		LIMIT $1 OFFSET $2
@@ -127,6 +129,7 @@ func (r *ServerRepository) List(ctx context.Context, limit, offset int) ([]*manm
 			&server.Status,
 			&server.LastSeen,
 			&server.IsDefault,
+			&server.Labels,
 		)
 		if err != nil {
 			return nil, err
@@ -140,11 +143,15 @@ func (r *ServerRepository) List(ctx context.Context, limit, offset int) ([]*manm
 func (r *ServerRepository) Update(ctx context.Context, server *manman.Server) error {
 	query := `
 		UPDATE servers
-		SET name = $2, status = $3, is_default = $4
+		SET name = $2, status = $3, is_default = $4, labels = $5
 		WHERE server_id = $1
 	`
 
-	_, err := r.db.Exec(ctx, query, server.ServerID, server.Name, server.Status, server.IsDefault)
+	labels := server.Labels
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	_, err := r.db.Exec(ctx, query, server.ServerID, server.Name, server.Status, server.IsDefault, labels)
 	return err
 }
 
@@ -182,7 +189,7 @@ func (r *ServerRepository) UpdateLastSeen(ctx context.Context, serverID int64, l
 
 func (r *ServerRepository) ListStaleServers(ctx context.Context, thresholdSeconds int) ([]*manman.Server, error) {
 	query := `
-		SELECT server_id, name, status, last_seen, is_default
+		SELECT server_id, name, status, last_seen, is_default, labels
 		FROM servers
 		WHERE status = $1
 		  AND last_seen < NOW() - INTERVAL '1 second' * $2
@@ -204,6 +211,7 @@ func (r *ServerRepository) ListStaleServers(ctx context.Context, thresholdSecond
 			&server.Status,
 			&server.LastSeen,
 			&server.IsDefault,
+			&server.Labels,
 		)
 		if err != nil {
 			return nil, err
@@ -234,7 +242,7 @@ func (r *ServerRepository) GetDefaultServer(ctx context.Context) (*manman.Server
 	server := &manman.Server{}
 
 	query := `
-		SELECT server_id, name, status, last_seen, is_default
+		SELECT server_id, name, status, last_seen, is_default, labels
 		FROM servers
 		WHERE is_default = TRUE
 		LIMIT 1
@@ -246,6 +254,7 @@ func (r *ServerRepository) GetDefaultServer(ctx context.Context) (*manman.Server
 		&server.Status,
 		&server.LastSeen,
 		&server.IsDefault,
+		&server.Labels,
 	)
 	if err != nil {
 		return nil, err
@@ -4,15 +4,14 @@ import (
 	"context"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ServerCapabilityRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewServerCapabilityRepository(db *pgxpool.Pool) *ServerCapabilityRepository {
+func NewServerCapabilityRepository(db DB) *ServerCapabilityRepository {
 	return &ServerCapabilityRepository{db: db}
 }
 
@@ -20,12 +19,17 @@ func (r *ServerCapabilityRepository) Insert(ctx context.Context, cap *manman.Ser
 	query := `
 		INSERT INTO server_capabilities (
 			server_id, total_memory_mb, available_memory_mb,
-			cpu_cores, available_cpu_millicores, docker_version, recorded_at
+			cpu_cores, available_cpu_millicores, docker_version, port_ranges, recorded_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING capability_id, recorded_at
 	`
 
+	portRanges := cap.PortRanges
+	if portRanges == nil {
+		portRanges = []manman.PortRange{}
+	}
+
 	now := time.Now()
 	err := r.db.QueryRow(ctx, query,
 		cap.ServerID,
@@ -34,6 +38,7 @@ func (r *ServerCapabilityRepository) Insert(ctx context.Context, cap *manman.Ser
 		cap.CPUCores,
 		cap.AvailableCPUMillicores,
 		cap.DockerVersion,
+		portRanges,
 		now,
 	).Scan(&cap.CapabilityID, &cap.RecordedAt)
 	return err
@@ -44,7 +49,7 @@ func (r *ServerCapabilityRepository) Get(ctx context.Context, serverID int64) (*
 
 	query := `
 		SELECT capability_id, server_id, total_memory_mb, available_memory_mb,
-		       cpu_cores, available_cpu_millicores, docker_version, recorded_at
+		       cpu_cores, available_cpu_millicores, docker_version, port_ranges, recorded_at
 		FROM server_capabilities
 		WHERE server_id = $1
 		ORDER BY recorded_at DESC
@@ -59,6 +64,7 @@ func (r *ServerCapabilityRepository) Get(ctx context.Context, serverID int64) (*
 		&cap.CPUCores,
 		&cap.AvailableCPUMillicores,
 		&cap.DockerVersion,
+		&cap.PortRanges,
 		&cap.RecordedAt,
 	)
 	if err != nil {
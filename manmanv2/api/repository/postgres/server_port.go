@@ -6,15 +6,14 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ServerPortRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewServerPortRepository(db *pgxpool.Pool) *ServerPortRepository {
+func NewServerPortRepository(db DB) *ServerPortRepository {
 	return &ServerPortRepository{db: db}
 }
 
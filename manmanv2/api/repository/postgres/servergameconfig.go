@@ -2,32 +2,91 @@ package postgres
 
 import (
 	"context"
+	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ServerGameConfigRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewServerGameConfigRepository(db *pgxpool.Pool) *ServerGameConfigRepository {
+func NewServerGameConfigRepository(db DB) *ServerGameConfigRepository {
 	return &ServerGameConfigRepository{db: db}
 }
 
 func (r *ServerGameConfigRepository) Create(ctx context.Context, sgc *manman.ServerGameConfig) (*manman.ServerGameConfig, error) {
+	return createServerGameConfig(ctx, r.db, sgc)
+}
+
+func (r *ServerGameConfigRepository) CreateReservingPorts(ctx context.Context, sgc *manman.ServerGameConfig, reserve []*manman.PortBinding) (*manman.ServerGameConfig, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent placements on the server wait here until this one is saved
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM servers WHERE server_id = $1 FOR UPDATE`, sgc.ServerID); err != nil {
+		return nil, err
+	}
+	for _, b := range reserve {
+		var taken bool
+		err := tx.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM server_game_configs sgc, jsonb_each_text(sgc.port_bindings) b
+				WHERE sgc.server_id = $1 AND split_part(b.key, '/', 2) = $3 AND b.value::numeric = $2
+			) OR EXISTS (
+				SELECT 1 FROM server_ports WHERE server_id = $1 AND port = $2 AND protocol = $3
+			)
+		`, sgc.ServerID, b.HostPort, b.Protocol).Scan(&taken)
+		if err != nil {
+			return nil, err
+		}
+		if taken {
+			return nil, fmt.Errorf("%w: %d/%s on server %d", repository.ErrPortsTaken, b.HostPort, b.Protocol, sgc.ServerID)
+		}
+	}
+
+	if _, err := createServerGameConfig(ctx, tx, sgc); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return sgc, nil
+}
+
+// rowQuerier is a pool or a transaction
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// createServerGameConfig inserts sgc, filling in its ID and defaulted columns
+func createServerGameConfig(ctx context.Context, db rowQuerier, sgc *manman.ServerGameConfig) (*manman.ServerGameConfig, error) {
 	query := `
-		INSERT INTO server_game_configs (server_id, game_config_id, port_bindings, status)
-		VALUES ($1, $2, $3, $4)
-		RETURNING sgc_id
+		INSERT INTO server_game_configs (server_id, game_config_id, port_bindings, status,
+		                                 cpu_millicores, memory_mb, pids_limit, ulimits, restart_policy, idle_shutdown,
+		                                 addon_update_policy)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE(NULLIF($11, ''), 'notify'))
+		RETURNING sgc_id, addon_update_policy
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	err := db.QueryRow(ctx, query,
 		sgc.ServerID,
 		sgc.GameConfigID,
 		sgc.PortBindings,
 		sgc.Status,
-	).Scan(&sgc.SGCID)
+		sgc.CPUMillicores,
+		sgc.MemoryMB,
+		sgc.PidsLimit,
+		sgc.Ulimits,
+		sgc.RestartPolicy,
+		sgc.IdleShutdown,
+		sgc.AddonUpdatePolicy,
+	).Scan(&sgc.SGCID, &sgc.AddonUpdatePolicy)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +98,9 @@ func (r *ServerGameConfigRepository) Get(ctx context.Context, sgcID int64) (*man
 	sgc := &manman.ServerGameConfig{}
 
 	query := `
-		SELECT sgc_id, server_id, game_config_id, port_bindings, status
+		SELECT sgc_id, server_id, game_config_id, port_bindings, status,
+		       cpu_millicores, memory_mb, pids_limit, ulimits, restart_policy, idle_shutdown,
+		       addon_update_policy
 		FROM server_game_configs
 		WHERE sgc_id = $1
 	`
@@ -50,6 +111,13 @@ func (r *ServerGameConfigRepository) Get(ctx context.Context, sgcID int64) (*man
 		&sgc.GameConfigID,
 		&sgc.PortBindings,
 		&sgc.Status,
+		&sgc.CPUMillicores,
+		&sgc.MemoryMB,
+		&sgc.PidsLimit,
+		&sgc.Ulimits,
+		&sgc.RestartPolicy,
+		&sgc.IdleShutdown,
+		&sgc.AddonUpdatePolicy,
 	)
 	if err != nil {
 		return nil, err
@@ -68,7 +136,9 @@ func (r *ServerGameConfigRepository) List(ctx context.Context, serverID *int64,
 
 	if serverID != nil {
 		query = `
-			SELECT sgc_id, server_id, game_config_id, port_bindings, status
+			SELECT sgc_id, server_id, game_config_id, port_bindings, status,
+			       cpu_millicores, memory_mb, pids_limit, ulimits, restart_policy, idle_shutdown,
+			       addon_update_policy
 			FROM server_game_configs
 			WHERE server_id = $1
 			ORDER BY sgc_id
@@ -77,7 +147,9 @@ func (r *ServerGameConfigRepository) List(ctx context.Context, serverID *int64,
 		args = []interface{}{*serverID, limit, offset}
 	} else {
 		query = `
-			SELECT sgc_id, server_id, game_config_id, port_bindings, status
+			SELECT sgc_id, server_id, game_config_id, port_bindings, status,
+			       cpu_millicores, memory_mb, pids_limit, ulimits, restart_policy, idle_shutdown,
+			       addon_update_policy
 			FROM server_game_configs
 			ORDER BY sgc_id
 			LIMIT $1 OFFSET $2
@@ -100,6 +172,13 @@ func (r *ServerGameConfigRepository) List(ctx context.Context, serverID *int64,
 			&sgc.GameConfigID,
 			&sgc.PortBindings,
 			&sgc.Status,
+			&sgc.CPUMillicores,
+			&sgc.MemoryMB,
+			&sgc.PidsLimit,
+			&sgc.Ulimits,
+			&sgc.RestartPolicy,
+			&sgc.IdleShutdown,
+			&sgc.AddonUpdatePolicy,
 		)
 		if err != nil {
 			return nil, err
@@ -113,7 +192,10 @@ func (r *ServerGameConfigRepository) List(ctx context.Context, serverID *int64,
 func (r *ServerGameConfigRepository) Update(ctx context.Context, sgc *manman.ServerGameConfig) error {
 	query := `
 		UPDATE server_game_configs
-		SET port_bindings = $2, status = $3
+		SET port_bindings = $2, status = $3,
+		    cpu_millicores = $4, memory_mb = $5, pids_limit = $6, ulimits = $7,
+		    restart_policy = $8, idle_shutdown = $9,
+		    addon_update_policy = COALESCE(NULLIF($10, ''), addon_update_policy)
 		WHERE sgc_id = $1
 	`
 
@@ -121,14 +203,37 @@ func (r *ServerGameConfigRepository) Update(ctx context.Context, sgc *manman.Ser
 		sgc.SGCID,
 		sgc.PortBindings,
 		sgc.Status,
+		sgc.CPUMillicores,
+		sgc.MemoryMB,
+		sgc.PidsLimit,
+		sgc.Ulimits,
+		sgc.RestartPolicy,
+		sgc.IdleShutdown,
+		sgc.AddonUpdatePolicy,
 	)
 	return err
 }
 
+// Delete also removes the SGC's patches and action definitions, which reference it by
+// level and entity rather than by foreign key
 func (r *ServerGameConfigRepository) Delete(ctx context.Context, sgcID int64) error {
-	query := `DELETE FROM server_game_configs WHERE sgc_id = $1`
-	_, err := r.db.Exec(ctx, query, sgcID)
-	return err
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	queries := []string{
+		`DELETE FROM configuration_patches WHERE patch_level = 'server_game_config' AND entity_id = $1`,
+		`DELETE FROM action_definitions WHERE definition_level = 'server_game_config' AND entity_id = $1`,
+		`DELETE FROM server_game_configs WHERE sgc_id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.Exec(ctx, query, sgcID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *ServerGameConfigRepository) AddLibrary(ctx context.Context, sgcID, libraryID int64, presetID, volumeID *int64, installationPathOverride *string) error {
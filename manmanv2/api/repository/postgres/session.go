@@ -2,33 +2,107 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5"
 	"github.com/whale-net/everything/manmanv2/models"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 )
 
 type SessionRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewSessionRepository(db *pgxpool.Pool) *SessionRepository {
+func NewSessionRepository(db DB) *SessionRepository {
 	return &SessionRepository{db: db}
 }
 
 func (r *SessionRepository) Create(ctx context.Context, session *manman.Session) (*manman.Session, error) {
+	return createSession(ctx, r.db, session)
+}
+
+func (r *SessionRepository) CreateWithinCapacity(ctx context.Context, session *manman.Session, serverID int64, limits manman.ResourceLimits, patches []*manman.ConfigurationPatch) (*manman.Session, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Concurrent starts on the server wait here until this one is saved, so each sees
+	// the others' sessions
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM servers WHERE server_id = $1 FOR UPDATE`, serverID); err != nil {
+		return nil, err
+	}
+
+	if limits.CPUMillicores > 0 || limits.MemoryMB > 0 {
+		capability := &manman.ServerCapability{ServerID: serverID}
+		err := tx.QueryRow(ctx, `
+			SELECT total_memory_mb, cpu_cores
+			FROM server_capabilities
+			WHERE server_id = $1
+			ORDER BY recorded_at DESC
+			LIMIT 1
+		`, serverID).Scan(&capability.TotalMemoryMB, &capability.CPUCores)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+
+		// Servers that have not reported capabilities are not checked
+		if err == nil {
+			var cpu, mem int64
+			err := tx.QueryRow(ctx, `
+				SELECT COALESCE(SUM(COALESCE(sgc.cpu_millicores, gc.cpu_millicores, 0)), 0),
+				       COALESCE(SUM(COALESCE(sgc.memory_mb, gc.memory_mb, 0)), 0)
+				FROM sessions s
+				JOIN server_game_configs sgc ON sgc.sgc_id = s.sgc_id
+				JOIN game_configs gc ON gc.config_id = sgc.game_config_id
+				WHERE sgc.server_id = $1 AND s.sgc_id <> $2 AND s.status = ANY($3)
+			`, serverID, session.SGCID, manman.CapacityReservingStatuses).Scan(&cpu, &mem)
+			if err != nil {
+				return nil, err
+			}
+			committed := manman.ResourceLimits{CPUMillicores: int32(cpu), MemoryMB: int32(mem)}
+			if overcommit := capability.Overcommit(committed, limits); overcommit != "" {
+				return nil, fmt.Errorf("%w: %s", repository.ErrInsufficientCapacity, overcommit)
+			}
+		}
+	}
+
+	if _, err := createSession(ctx, tx, session); err != nil {
+		return nil, err
+	}
+	patchRepo := NewConfigurationPatchRepository(tx)
+	for _, patch := range patches {
+		patch.EntityID = session.SessionID
+		if _, err := patchRepo.Create(ctx, patch); err != nil {
+			return nil, fmt.Errorf("failed to save session patch: %w", err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// createSession inserts session, filling in its ID
+func createSession(ctx context.Context, db rowQuerier, session *manman.Session) (*manman.Session, error) {
 	query := `
-		INSERT INTO sessions (sgc_id, status)
-		VALUES ($1, $2)
+		INSERT INTO sessions (sgc_id, status, restored_from_backup_id, previous_session_id, restart_attempt, image_tag, env_overrides)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING session_id
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	err := db.QueryRow(ctx, query,
 		session.SGCID,
 		session.Status,
+		session.RestoredFromBackupID,
+		session.PreviousSessionID,
+		session.RestartAttempt,
+		session.ImageTag,
+		session.EnvOverrides,
 	).Scan(&session.SessionID)
 	if err != nil {
 		return nil, err
@@ -41,7 +115,7 @@ func (r *SessionRepository) Get(ctx context.Context, sessionID int64) (*manman.S
 	session := &manman.Session{}
 
 	query := `
-		SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, created_at, updated_at
+		SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, restored_from_backup_id, previous_session_id, restart_attempt, restart_abandoned_reason, player_count, idle_since, map_name, max_players, image_tag, env_overrides, created_at, updated_at
 		FROM sessions
 		WHERE session_id = $1
 	`
@@ -53,6 +127,16 @@ func (r *SessionRepository) Get(ctx context.Context, sessionID int64) (*manman.S
 		&session.EndedAt,
 		&session.ExitCode,
 		&session.Status,
+		&session.RestoredFromBackupID,
+		&session.PreviousSessionID,
+		&session.RestartAttempt,
+		&session.RestartAbandonedReason,
+		&session.PlayerCount,
+		&session.IdleSince,
+		&session.MapName,
+		&session.MaxPlayers,
+		&session.ImageTag,
+		&session.EnvOverrides,
 		&session.CreatedAt,
 		&session.UpdatedAt,
 	)
@@ -73,7 +157,7 @@ func (r *SessionRepository) List(ctx context.Context, sgcID *int64, limit, offse
 
 	if sgcID != nil {
 		query = `
-			SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, created_at, updated_at
+			SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, restored_from_backup_id, previous_session_id, restart_attempt, restart_abandoned_reason, player_count, idle_since, map_name, max_players, image_tag, env_overrides, created_at, updated_at
 			FROM sessions
 			WHERE sgc_id = $1
 			ORDER BY session_id DESC
@@ -82,7 +166,7 @@ func (r *SessionRepository) List(ctx context.Context, sgcID *int64, limit, offse
 		args = []interface{}{*sgcID, limit, offset}
 	} else {
 		query = `
-			SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, created_at, updated_at
+			SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, restored_from_backup_id, previous_session_id, restart_attempt, restart_abandoned_reason, player_count, idle_since, map_name, max_players, image_tag, env_overrides, created_at, updated_at
 			FROM sessions
 			ORDER BY session_id DESC
 			LIMIT $1 OFFSET $2
@@ -106,6 +190,16 @@ func (r *SessionRepository) List(ctx context.Context, sgcID *int64, limit, offse
 			&session.EndedAt,
 			&session.ExitCode,
 			&session.Status,
+			&session.RestoredFromBackupID,
+			&session.PreviousSessionID,
+			&session.RestartAttempt,
+			&session.RestartAbandonedReason,
+			&session.PlayerCount,
+			&session.IdleSince,
+			&session.MapName,
+			&session.MaxPlayers,
+			&session.ImageTag,
+			&session.EnvOverrides,
 			&session.CreatedAt,
 			&session.UpdatedAt,
 		)
@@ -122,7 +216,7 @@ func (r *SessionRepository) StopOtherSessionsForSGC(ctx context.Context, session
 	query := `
 		UPDATE sessions
 		SET status = 'stopped', ended_at = $3
-		WHERE sgc_id = $1 AND session_id != $2 AND status IN ('pending', 'starting', 'running', 'stopping', 'crashed', 'lost')
+		WHERE sgc_id = $1 AND session_id != $2 AND status IN ('pending', 'starting', 'running', 'ready', 'stopping', 'crashed', 'lost')
 	`
 	_, err := r.db.Exec(ctx, query, sgcID, sessionID, time.Now())
 	return err
@@ -150,7 +244,7 @@ func (r *SessionRepository) ListWithFilters(ctx context.Context, filters *reposi
 	}
 
 	baseQuery := `
-		SELECT s.session_id, s.sgc_id, s.started_at, s.ended_at, s.exit_code, s.status, s.created_at, s.updated_at
+		SELECT s.session_id, s.sgc_id, s.started_at, s.ended_at, s.exit_code, s.status, s.restored_from_backup_id, s.previous_session_id, s.restart_attempt, s.restart_abandoned_reason, s.player_count, s.idle_since, s.map_name, s.max_players, s.image_tag, s.env_overrides, s.created_at, s.updated_at
 		FROM sessions s
 	`
 
@@ -168,7 +262,7 @@ func (r *SessionRepository) ListWithFilters(ctx context.Context, filters *reposi
 	// Filter by ServerID (requires join)
 	if filters.ServerID != nil {
 		baseQuery = `
-			SELECT s.session_id, s.sgc_id, s.started_at, s.ended_at, s.exit_code, s.status, s.created_at, s.updated_at
+			SELECT s.session_id, s.sgc_id, s.started_at, s.ended_at, s.exit_code, s.status, s.restored_from_backup_id, s.previous_session_id, s.restart_attempt, s.restart_abandoned_reason, s.player_count, s.idle_since, s.map_name, s.max_players, s.image_tag, s.env_overrides, s.created_at, s.updated_at
 			FROM sessions s
 			JOIN server_game_configs sgc ON s.sgc_id = sgc.sgc_id
 		`
@@ -204,7 +298,7 @@ func (r *SessionRepository) ListWithFilters(ctx context.Context, filters *reposi
 
 	// Filter for live_only
 	if filters.LiveOnly {
-		whereClauses = append(whereClauses, "s.status IN ('pending', 'starting', 'running', 'stopping')")
+		whereClauses = append(whereClauses, "s.status IN ('pending', 'starting', 'running', 'ready', 'stopping')")
 	}
 
 	// Build WHERE clause
@@ -232,6 +326,16 @@ func (r *SessionRepository) ListWithFilters(ctx context.Context, filters *reposi
 			&session.EndedAt,
 			&session.ExitCode,
 			&session.Status,
+			&session.RestoredFromBackupID,
+			&session.PreviousSessionID,
+			&session.RestartAttempt,
+			&session.RestartAbandonedReason,
+			&session.PlayerCount,
+			&session.IdleSince,
+			&session.MapName,
+			&session.MaxPlayers,
+			&session.ImageTag,
+			&session.EnvOverrides,
 			&session.CreatedAt,
 			&session.UpdatedAt,
 		)
@@ -283,9 +387,98 @@ func (r *SessionRepository) UpdateSessionEnd(ctx context.Context, sessionID int6
 	return err
 }
 
+func (r *SessionRepository) SetRestartAbandoned(ctx context.Context, sessionID int64, reason string) error {
+	query := `
+		UPDATE sessions
+		SET restart_abandoned_reason = $2
+		WHERE session_id = $1
+		RETURNING session_id
+	`
+
+	var returnedID int64
+	err := r.db.QueryRow(ctx, query, sessionID, reason).Scan(&returnedID)
+	return err
+}
+
+func (r *SessionRepository) UpdatePlayerCount(ctx context.Context, sessionID int64, count int32, at time.Time) error {
+	query := `
+		UPDATE sessions
+		SET player_count = $2,
+		    idle_since = CASE WHEN $2 = 0 THEN COALESCE(idle_since, $3) ELSE NULL END
+		WHERE session_id = $1
+		RETURNING session_id
+	`
+
+	var returnedID int64
+	err := r.db.QueryRow(ctx, query, sessionID, count, at).Scan(&returnedID)
+	return err
+}
+
+func (r *SessionRepository) UpdateServerInfo(ctx context.Context, sessionID int64, mapName string, maxPlayers int32) error {
+	query := `
+		UPDATE sessions
+		SET map_name = NULLIF($2, ''), max_players = NULLIF($3, 0)
+		WHERE session_id = $1
+		RETURNING session_id
+	`
+
+	var returnedID int64
+	err := r.db.QueryRow(ctx, query, sessionID, mapName, maxPlayers).Scan(&returnedID)
+	return err
+}
+
+func (r *SessionRepository) ListIdle(ctx context.Context, now time.Time) ([]*manman.Session, error) {
+	query := `
+		SELECT s.session_id, s.sgc_id, s.started_at, s.ended_at, s.exit_code, s.status, s.restored_from_backup_id, s.previous_session_id, s.restart_attempt, s.restart_abandoned_reason, s.player_count, s.idle_since, s.map_name, s.max_players, s.image_tag, s.env_overrides, s.created_at, s.updated_at
+		FROM sessions s
+		JOIN server_game_configs sgc ON s.sgc_id = sgc.sgc_id
+		WHERE s.status IN ('running', 'ready')
+		AND s.idle_since IS NOT NULL
+		AND (sgc.idle_shutdown->>'idle_minutes')::int > 0
+		AND s.idle_since <= $1 - make_interval(mins => (sgc.idle_shutdown->>'idle_minutes')::int)
+	`
+
+	rows, err := r.db.Query(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*manman.Session
+	for rows.Next() {
+		session := &manman.Session{}
+		err := rows.Scan(
+			&session.SessionID,
+			&session.SGCID,
+			&session.StartedAt,
+			&session.EndedAt,
+			&session.ExitCode,
+			&session.Status,
+			&session.RestoredFromBackupID,
+			&session.PreviousSessionID,
+			&session.RestartAttempt,
+			&session.RestartAbandonedReason,
+			&session.PlayerCount,
+			&session.IdleSince,
+			&session.MapName,
+			&session.MaxPlayers,
+			&session.ImageTag,
+			&session.EnvOverrides,
+			&session.CreatedAt,
+			&session.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	return sessions, rows.Err()
+}
+
 func (r *SessionRepository) GetStaleSessions(ctx context.Context, threshold time.Duration) ([]*manman.Session, error) {
 	query := `
-		SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, created_at, updated_at
+		SELECT session_id, sgc_id, started_at, ended_at, exit_code, status, restored_from_backup_id, previous_session_id, restart_attempt, restart_abandoned_reason, player_count, idle_since, map_name, max_players, image_tag, env_overrides, created_at, updated_at
 		FROM sessions
 		WHERE status IN ('pending', 'starting', 'stopping')
 		AND updated_at < $1
@@ -308,6 +501,16 @@ func (r *SessionRepository) GetStaleSessions(ctx context.Context, threshold time
 			&session.EndedAt,
 			&session.ExitCode,
 			&session.Status,
+			&session.RestoredFromBackupID,
+			&session.PreviousSessionID,
+			&session.RestartAttempt,
+			&session.RestartAbandonedReason,
+			&session.PlayerCount,
+			&session.IdleSince,
+			&session.MapName,
+			&session.MaxPlayers,
+			&session.ImageTag,
+			&session.EnvOverrides,
 			&session.CreatedAt,
 			&session.UpdatedAt,
 		)
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// SGCGrantRepository implements repository.SGCGrantRepository
+type SGCGrantRepository struct {
+	db DB
+}
+
+func NewSGCGrantRepository(db DB) *SGCGrantRepository {
+	return &SGCGrantRepository{db: db}
+}
+
+func (r *SGCGrantRepository) Create(ctx context.Context, g *manman.SGCGrant) (*manman.SGCGrant, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO sgc_grants (sgc_id, subject, note, created_by, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING grant_id, created_at
+	`, g.SGCID, g.Subject, g.Note, g.CreatedBy).Scan(&g.GrantID, &g.CreatedAt)
+	return g, err
+}
+
+func (r *SGCGrantRepository) Delete(ctx context.Context, grantID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM sgc_grants WHERE grant_id = $1`, grantID)
+	return err
+}
+
+func (r *SGCGrantRepository) List(ctx context.Context, sgcID int64) ([]*manman.SGCGrant, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT grant_id, sgc_id, subject, note, created_by, created_at
+		FROM sgc_grants WHERE $1 = 0 OR sgc_id = $1 ORDER BY sgc_id, subject
+	`, sgcID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var grants []*manman.SGCGrant
+	for rows.Next() {
+		g := &manman.SGCGrant{}
+		if err := rows.Scan(&g.GrantID, &g.SGCID, &g.Subject, &g.Note, &g.CreatedBy, &g.CreatedAt); err != nil {
+			return nil, err
+		}
+		grants = append(grants, g)
+	}
+	return grants, rows.Err()
+}
+
+func (r *SGCGrantRepository) ListSGCIDs(ctx context.Context, subject string) ([]int64, error) {
+	rows, err := r.db.Query(ctx, `SELECT sgc_id FROM sgc_grants WHERE subject = $1 ORDER BY sgc_id`, subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sgcIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		sgcIDs = append(sgcIDs, id)
+	}
+	return sgcIDs, rows.Err()
+}
+
+func (r *SGCGrantRepository) Exists(ctx context.Context, sgcID int64, subject string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM sgc_grants WHERE sgc_id = $1 AND subject = $2)
+	`, sgcID, subject).Scan(&exists)
+	return exists, err
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+const imageStatusColumns = `sgc_id, image, local_digest, remote_digest, error_message, checked_at`
+
+// SGCImageStatusRepository implements repository.SGCImageStatusRepository
+type SGCImageStatusRepository struct {
+	db DB
+}
+
+func NewSGCImageStatusRepository(db DB) *SGCImageStatusRepository {
+	return &SGCImageStatusRepository{db: db}
+}
+
+func (r *SGCImageStatusRepository) Upsert(ctx context.Context, s *manman.SGCImageStatus) error {
+	_, err := r.db.Exec(ctx, `
+		INSERT INTO sgc_image_status (sgc_id, image, local_digest, remote_digest, error_message, checked_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (sgc_id) DO UPDATE
+		SET image = EXCLUDED.image, local_digest = EXCLUDED.local_digest, remote_digest = EXCLUDED.remote_digest,
+		    error_message = EXCLUDED.error_message, checked_at = EXCLUDED.checked_at
+	`, s.SGCID, s.Image, s.LocalDigest, s.RemoteDigest, s.ErrorMessage, s.CheckedAt)
+	return err
+}
+
+func (r *SGCImageStatusRepository) Get(ctx context.Context, sgcID int64) (*manman.SGCImageStatus, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+imageStatusColumns+` FROM sgc_image_status WHERE sgc_id = $1`, sgcID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, err := scanImageStatuses(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(statuses) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return statuses[0], nil
+}
+
+func (r *SGCImageStatusRepository) ListBySGCs(ctx context.Context, sgcIDs []int64) ([]*manman.SGCImageStatus, error) {
+	if len(sgcIDs) == 0 {
+		return nil, nil
+	}
+	rows, err := r.db.Query(ctx, `SELECT `+imageStatusColumns+` FROM sgc_image_status WHERE sgc_id = ANY($1)`, sgcIDs)
+	if err != nil {
+		return nil, err
+	}
+	return scanImageStatuses(rows)
+}
+
+func scanImageStatuses(rows pgx.Rows) ([]*manman.SGCImageStatus, error) {
+	defer rows.Close()
+	var statuses []*manman.SGCImageStatus
+	for rows.Next() {
+		s := &manman.SGCImageStatus{}
+		if err := rows.Scan(&s.SGCID, &s.Image, &s.LocalDigest, &s.RemoteDigest, &s.ErrorMessage, &s.CheckedAt); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, rows.Err()
+}
@@ -0,0 +1,275 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+const migrationColumns = `migration_id, source_sgc_id, source_server_id, source_status, target_server_id, target_sgc_id,
+	port_bindings, volumes, start_session, status, error_message, created_at, updated_at, completed_at`
+
+// SGCMigrationRepository implements repository.SGCMigrationRepository
+type SGCMigrationRepository struct {
+	db DB
+}
+
+func NewSGCMigrationRepository(db DB) *SGCMigrationRepository {
+	return &SGCMigrationRepository{db: db}
+}
+
+func (r *SGCMigrationRepository) Create(ctx context.Context, m *manman.SGCMigration) (*manman.SGCMigration, error) {
+	if m.Status == "" {
+		m.Status = manman.MigrationStatusPending
+	}
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	// Conditional on the status the caller checked, so of two concurrent requests only one
+	// takes the SGC; sessions can't be started on it from here on
+	tag, err := tx.Exec(ctx, `
+		UPDATE server_game_configs SET status = $3 WHERE sgc_id = $1 AND status = $2
+	`, m.SourceSGCID, m.SourceStatus, manman.SGCStatusMigrating)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, repository.ErrStatusChanged
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO sgc_migrations (source_sgc_id, source_server_id, source_status, target_server_id,
+		                            port_bindings, volumes, start_session, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW(), NOW())
+		RETURNING migration_id, created_at, updated_at
+	`, m.SourceSGCID, m.SourceServerID, m.SourceStatus, m.TargetServerID,
+		nonNilPortBindings(m.PortBindings), nonNilMigrationVolumes(m.Volumes), m.StartSession, m.Status,
+	).Scan(&m.MigrationID, &m.CreatedAt, &m.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return m, tx.Commit(ctx)
+}
+
+func (r *SGCMigrationRepository) Get(ctx context.Context, migrationID int64) (*manman.SGCMigration, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+migrationColumns+` FROM sgc_migrations WHERE migration_id = $1`, migrationID)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := scanMigrations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(migrations) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return migrations[0], nil
+}
+
+func (r *SGCMigrationRepository) List(ctx context.Context, sgcID int64, limit, offset int) ([]*manman.SGCMigration, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+migrationColumns+` FROM sgc_migrations
+		WHERE ($1::bigint = 0 OR source_sgc_id = $1 OR target_sgc_id = $1)
+		ORDER BY migration_id DESC
+		LIMIT $2 OFFSET $3
+	`, sgcID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanMigrations(rows)
+}
+
+func (r *SGCMigrationRepository) ListInFlight(ctx context.Context) ([]*manman.SGCMigration, error) {
+	rows, err := r.db.Query(ctx, `
+		SELECT `+migrationColumns+` FROM sgc_migrations
+		WHERE status NOT IN ($1, $2)
+		ORDER BY migration_id
+	`, manman.MigrationStatusCompleted, manman.MigrationStatusFailed)
+	if err != nil {
+		return nil, err
+	}
+	return scanMigrations(rows)
+}
+
+// Update leaves volumes alone: the target host writes restore results into them
+// concurrently, so they only change through SetVolumeBackup and RecordRestore.
+func (r *SGCMigrationRepository) Update(ctx context.Context, m *manman.SGCMigration) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE sgc_migrations
+		SET status = $2, target_sgc_id = $3, error_message = $4, updated_at = NOW(),
+		    completed_at = CASE WHEN $2 IN ($5, $6) THEN COALESCE(completed_at, NOW()) END
+		WHERE migration_id = $1
+	`, m.MigrationID, m.Status, m.TargetSGCID, m.ErrorMessage,
+		manman.MigrationStatusCompleted, manman.MigrationStatusFailed)
+	return err
+}
+
+// SetVolumeBackup records the backup taken of one volume in its element of the volumes array
+func (r *SGCMigrationRepository) SetVolumeBackup(ctx context.Context, migrationID, volumeID, backupID int64) error {
+	patch, err := json.Marshal(map[string]interface{}{"backup_id": backupID})
+	if err != nil {
+		return err
+	}
+	return r.patchVolume(ctx, migrationID, "volume_id", volumeID, patch)
+}
+
+// RecordRestore merges the result into the matching element of the volumes array in
+// one statement, so restores of several volumes reporting at once don't overwrite
+// each other.
+func (r *SGCMigrationRepository) RecordRestore(ctx context.Context, migrationID, backupID int64, checksum, errMsg string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"restored":          errMsg == "",
+		"restored_checksum": checksum,
+		"error":             errMsg,
+	})
+	if err != nil {
+		return err
+	}
+	return r.patchVolume(ctx, migrationID, "backup_id", backupID, patch)
+}
+
+// patchVolume merges patch into the volumes element whose key equals id, in one statement
+func (r *SGCMigrationRepository) patchVolume(ctx context.Context, migrationID int64, key string, id int64, patch []byte) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE sgc_migrations
+		SET volumes = (
+		        SELECT COALESCE(jsonb_agg(CASE WHEN (v->>$2::text)::bigint = $3 THEN v || $4::jsonb ELSE v END ORDER BY ord), '[]')
+		        FROM jsonb_array_elements(volumes) WITH ORDINALITY AS t(v, ord)
+		    ),
+		    updated_at = NOW()
+		WHERE migration_id = $1
+	`, migrationID, key, id, string(patch))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// CopySGCSettings clears the target first, so a retry after a failure doesn't duplicate
+// anything. Actions, their input fields and options are matched up by name, which is unique
+// within an action's level and entity; an alert rule that runs one of the source's actions
+// runs its copy. Backup configs belong to the game config's volumes, which the target shares.
+func (r *SGCMigrationRepository) CopySGCSettings(ctx context.Context, sourceSGCID, targetSGCID int64) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	deletes := []string{
+		`DELETE FROM configuration_patches WHERE patch_level = 'server_game_config' AND entity_id = $1`,
+		`DELETE FROM action_definitions WHERE definition_level = 'server_game_config' AND entity_id = $1`,
+		`DELETE FROM sgc_schedules WHERE sgc_id = $1`,
+		`DELETE FROM sgc_grants WHERE sgc_id = $1`,
+		`DELETE FROM alert_rules WHERE sgc_id = $1`,
+	}
+	for _, query := range deletes {
+		if _, err := tx.Exec(ctx, query, targetSGCID); err != nil {
+			return err
+		}
+	}
+
+	copies := []string{
+		`INSERT INTO configuration_patches (strategy_id, patch_level, entity_id, patch_content, patch_format, volume_id, path_override, patch_order)
+		 SELECT strategy_id, patch_level, $2, patch_content, patch_format, volume_id, path_override, patch_order
+		 FROM configuration_patches
+		 WHERE patch_level = 'server_game_config' AND entity_id = $1`,
+
+		`INSERT INTO action_definitions (definition_level, entity_id, name, label, description, command_template,
+		                                 display_order, group_name, button_style, icon, requires_confirmation,
+		                                 confirmation_message, enabled)
+		 SELECT definition_level, $2, name, label, description, command_template,
+		        display_order, group_name, button_style, icon, requires_confirmation,
+		        confirmation_message, enabled
+		 FROM action_definitions
+		 WHERE definition_level = 'server_game_config' AND entity_id = $1`,
+
+		`INSERT INTO action_input_fields (action_id, name, label, field_type, required, placeholder, help_text,
+		                                  default_value, display_order, pattern, min_value, max_value, min_length, max_length)
+		 SELECT dst.action_id, f.name, f.label, f.field_type, f.required, f.placeholder, f.help_text,
+		        f.default_value, f.display_order, f.pattern, f.min_value, f.max_value, f.min_length, f.max_length
+		 FROM action_input_fields f
+		 JOIN action_definitions src ON src.action_id = f.action_id
+		 JOIN action_definitions dst ON dst.definition_level = src.definition_level AND dst.entity_id = $2 AND dst.name = src.name
+		 WHERE src.definition_level = 'server_game_config' AND src.entity_id = $1`,
+
+		`INSERT INTO action_input_options (field_id, value, label, display_order, is_default)
+		 SELECT dst_field.field_id, o.value, o.label, o.display_order, o.is_default
+		 FROM action_input_options o
+		 JOIN action_input_fields src_field ON src_field.field_id = o.field_id
+		 JOIN action_definitions src ON src.action_id = src_field.action_id
+		 JOIN action_definitions dst ON dst.definition_level = src.definition_level AND dst.entity_id = $2 AND dst.name = src.name
+		 JOIN action_input_fields dst_field ON dst_field.action_id = dst.action_id AND dst_field.name = src_field.name
+		 WHERE src.definition_level = 'server_game_config' AND src.entity_id = $1`,
+
+		// last_evaluated_at carries over so occurrences the source already acted on don't fire again
+		`INSERT INTO sgc_schedules (sgc_id, start_cron, stop_cron, timezone, backup_config_id, enabled,
+		                            last_evaluated_at, last_start_at, last_stop_at)
+		 SELECT $2, start_cron, stop_cron, timezone, backup_config_id, enabled,
+		        last_evaluated_at, last_start_at, last_stop_at
+		 FROM sgc_schedules
+		 WHERE sgc_id = $1`,
+
+		`INSERT INTO sgc_grants (sgc_id, subject, note, created_by, created_at)
+		 SELECT $2, subject, note, created_by, created_at
+		 FROM sgc_grants
+		 WHERE sgc_id = $1`,
+
+		`INSERT INTO alert_rules (sgc_id, name, pattern, threshold, window_seconds, cooldown_seconds,
+		                          action_id, backup_config_id, enabled)
+		 SELECT $2, r.name, r.pattern, r.threshold, r.window_seconds, r.cooldown_seconds,
+		        COALESCE(dst.action_id, r.action_id), r.backup_config_id, r.enabled
+		 FROM alert_rules r
+		 LEFT JOIN action_definitions src ON src.action_id = r.action_id
+		      AND src.definition_level = 'server_game_config' AND src.entity_id = $1
+		 LEFT JOIN action_definitions dst ON dst.definition_level = 'server_game_config' AND dst.entity_id = $2
+		      AND dst.name = src.name
+		 WHERE r.sgc_id = $1`,
+	}
+	for _, query := range copies {
+		if _, err := tx.Exec(ctx, query, sourceSGCID, targetSGCID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func scanMigrations(rows pgx.Rows) ([]*manman.SGCMigration, error) {
+	defer rows.Close()
+	var migrations []*manman.SGCMigration
+	for rows.Next() {
+		m := &manman.SGCMigration{}
+		if err := rows.Scan(
+			&m.MigrationID, &m.SourceSGCID, &m.SourceServerID, &m.SourceStatus, &m.TargetServerID, &m.TargetSGCID,
+			&m.PortBindings, &m.Volumes, &m.StartSession, &m.Status, &m.ErrorMessage, &m.CreatedAt, &m.UpdatedAt, &m.CompletedAt,
+		); err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	return migrations, rows.Err()
+}
+
+// The JSONB columns are NOT NULL; a nil slice would encode as null
+func nonNilPortBindings(s []manman.PortBinding) []manman.PortBinding {
+	if s == nil {
+		return []manman.PortBinding{}
+	}
+	return s
+}
+
+func nonNilMigrationVolumes(s []manman.MigrationVolume) []manman.MigrationVolume {
+	if s == nil {
+		return []manman.MigrationVolume{}
+	}
+	return s
+}
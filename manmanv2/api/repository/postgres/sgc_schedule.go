@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// SGCScheduleRepository implements repository.SGCScheduleRepository
+type SGCScheduleRepository struct {
+	db DB
+}
+
+func NewSGCScheduleRepository(db DB) *SGCScheduleRepository {
+	return &SGCScheduleRepository{db: db}
+}
+
+func (r *SGCScheduleRepository) Create(ctx context.Context, s *manman.SGCSchedule) (*manman.SGCSchedule, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO sgc_schedules (sgc_id, start_cron, stop_cron, timezone, backup_config_id, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
+		RETURNING schedule_id, created_at, updated_at
+	`, s.SGCID, s.StartCron, s.StopCron, s.Timezone, s.BackupConfigID, s.Enabled,
+	).Scan(&s.ScheduleID, &s.CreatedAt, &s.UpdatedAt)
+	return s, err
+}
+
+func (r *SGCScheduleRepository) Get(ctx context.Context, scheduleID int64) (*manman.SGCSchedule, error) {
+	s := &manman.SGCSchedule{}
+	err := r.db.QueryRow(ctx, `
+		SELECT schedule_id, sgc_id, start_cron, stop_cron, timezone, backup_config_id, enabled,
+		       last_evaluated_at, last_start_at, last_stop_at, created_at, updated_at
+		FROM sgc_schedules WHERE schedule_id = $1
+	`, scheduleID).Scan(
+		&s.ScheduleID, &s.SGCID, &s.StartCron, &s.StopCron, &s.Timezone, &s.BackupConfigID, &s.Enabled,
+		&s.LastEvaluatedAt, &s.LastStartAt, &s.LastStopAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *SGCScheduleRepository) List(ctx context.Context, sgcID int64) ([]*manman.SGCSchedule, error) {
+	return r.list(ctx, `
+		SELECT schedule_id, sgc_id, start_cron, stop_cron, timezone, backup_config_id, enabled,
+		       last_evaluated_at, last_start_at, last_stop_at, created_at, updated_at
+		FROM sgc_schedules WHERE sgc_id = $1 ORDER BY schedule_id
+	`, sgcID)
+}
+
+func (r *SGCScheduleRepository) ListEnabled(ctx context.Context) ([]*manman.SGCSchedule, error) {
+	return r.list(ctx, `
+		SELECT schedule_id, sgc_id, start_cron, stop_cron, timezone, backup_config_id, enabled,
+		       last_evaluated_at, last_start_at, last_stop_at, created_at, updated_at
+		FROM sgc_schedules WHERE enabled = true ORDER BY schedule_id
+	`)
+}
+
+func (r *SGCScheduleRepository) list(ctx context.Context, query string, args ...interface{}) ([]*manman.SGCSchedule, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*manman.SGCSchedule
+	for rows.Next() {
+		s := &manman.SGCSchedule{}
+		if err := rows.Scan(
+			&s.ScheduleID, &s.SGCID, &s.StartCron, &s.StopCron, &s.Timezone, &s.BackupConfigID, &s.Enabled,
+			&s.LastEvaluatedAt, &s.LastStartAt, &s.LastStopAt, &s.CreatedAt, &s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+func (r *SGCScheduleRepository) Update(ctx context.Context, s *manman.SGCSchedule) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE sgc_schedules
+		SET start_cron = $2, stop_cron = $3, timezone = $4, backup_config_id = $5, enabled = $6,
+		    updated_at = NOW()
+		WHERE schedule_id = $1
+	`, s.ScheduleID, s.StartCron, s.StopCron, s.Timezone, s.BackupConfigID, s.Enabled)
+	return err
+}
+
+func (r *SGCScheduleRepository) Delete(ctx context.Context, scheduleID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM sgc_schedules WHERE schedule_id = $1`, scheduleID)
+	return err
+}
+
+// MarkEvaluated records that occurrences up to at have been handled. action is the
+// ScheduleAction taken, or "" when nothing was due.
+func (r *SGCScheduleRepository) MarkEvaluated(ctx context.Context, scheduleID int64, at time.Time, action string) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE sgc_schedules
+		SET last_evaluated_at = $2,
+		    last_start_at = CASE WHEN $3 = 'start' THEN $2 ELSE last_start_at END,
+		    last_stop_at = CASE WHEN $3 = 'stop' THEN $2 ELSE last_stop_at END
+		WHERE schedule_id = $1
+	`, scheduleID, at, action)
+	return err
+}
@@ -3,15 +3,14 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type ConfigurationStrategyRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewConfigurationStrategyRepository(db *pgxpool.Pool) *ConfigurationStrategyRepository {
+func NewConfigurationStrategyRepository(db DB) *ConfigurationStrategyRepository {
 	return &ConfigurationStrategyRepository{db: db}
 }
 
@@ -0,0 +1,228 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+const webhookColumns = `webhook_id, name, url, format, secret, event_patterns, sgc_ids, enabled, created_at, updated_at`
+
+// WebhookRepository implements repository.WebhookRepository
+type WebhookRepository struct {
+	db DB
+}
+
+func NewWebhookRepository(db DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, w *manman.Webhook) (*manman.Webhook, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO webhooks (name, url, format, secret, event_patterns, sgc_ids, enabled, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())
+		RETURNING webhook_id, created_at, updated_at
+	`, w.Name, w.URL, w.Format, w.Secret, nonNilStrings(w.EventPatterns), nonNilInt64s(w.SGCIDs), w.Enabled).Scan(&w.WebhookID, &w.CreatedAt, &w.UpdatedAt)
+	return w, err
+}
+
+func (r *WebhookRepository) Get(ctx context.Context, webhookID int64) (*manman.Webhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE webhook_id = $1`, webhookID)
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := scanWebhooks(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(webhooks) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return webhooks[0], nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]*manman.Webhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY name, webhook_id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhooks(rows)
+}
+
+func (r *WebhookRepository) ListEnabled(ctx context.Context) ([]*manman.Webhook, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE enabled ORDER BY webhook_id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanWebhooks(rows)
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, w *manman.Webhook) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhooks
+		SET name = $2, url = $3, format = $4, secret = $5, event_patterns = $6, sgc_ids = $7, enabled = $8, updated_at = NOW()
+		WHERE webhook_id = $1
+	`, w.WebhookID, w.Name, w.URL, w.Format, w.Secret, nonNilStrings(w.EventPatterns), nonNilInt64s(w.SGCIDs), w.Enabled)
+	return err
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, webhookID int64) error {
+	_, err := r.db.Exec(ctx, `DELETE FROM webhooks WHERE webhook_id = $1`, webhookID)
+	return err
+}
+
+func scanWebhooks(rows pgx.Rows) ([]*manman.Webhook, error) {
+	defer rows.Close()
+	var webhooks []*manman.Webhook
+	for rows.Next() {
+		w := &manman.Webhook{}
+		if err := rows.Scan(&w.WebhookID, &w.Name, &w.URL, &w.Format, &w.Secret, &w.EventPatterns, &w.SGCIDs, &w.Enabled, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// The array columns are NOT NULL; pgx encodes a nil slice as NULL
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+func nonNilInt64s(s []int64) []int64 {
+	if s == nil {
+		return []int64{}
+	}
+	return s
+}
+
+const deliveryColumns = `delivery_id, webhook_id, routing_key, payload, status, attempts, last_status_code, last_error, next_attempt_at, created_at, delivered_at`
+
+// WebhookDeliveryRepository implements repository.WebhookDeliveryRepository
+type WebhookDeliveryRepository struct {
+	db DB
+}
+
+func NewWebhookDeliveryRepository(db DB) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db}
+}
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, d *manman.WebhookDelivery) (*manman.WebhookDelivery, error) {
+	err := r.db.QueryRow(ctx, `
+		INSERT INTO webhook_deliveries (webhook_id, routing_key, payload, status, next_attempt_at, created_at)
+		VALUES ($1, $2, $3, 'pending', NOW(), NOW())
+		RETURNING delivery_id, status, next_attempt_at, created_at
+	`, d.WebhookID, d.RoutingKey, d.Payload).Scan(&d.DeliveryID, &d.Status, &d.NextAttemptAt, &d.CreatedAt)
+	return d, err
+}
+
+func (r *WebhookDeliveryRepository) Get(ctx context.Context, deliveryID int64) (*manman.WebhookDelivery, error) {
+	rows, err := r.db.Query(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE delivery_id = $1`, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(deliveries) == 0 {
+		return nil, pgx.ErrNoRows
+	}
+	return deliveries[0], nil
+}
+
+func (r *WebhookDeliveryRepository) List(ctx context.Context, webhookID int64, status string, limit, offset int) ([]*manman.WebhookDelivery, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := r.db.Query(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE webhook_id = $1 AND ($2 = '' OR status = $2)
+		ORDER BY delivery_id DESC
+		LIMIT $3 OFFSET $4
+	`, webhookID, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, limit int, staleAfter time.Duration) ([]*manman.WebhookDelivery, error) {
+	// SKIP LOCKED lets several notifier replicas claim disjoint batches
+	rows, err := r.db.Query(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'sending', next_attempt_at = NOW()
+		WHERE delivery_id IN (
+			SELECT delivery_id FROM webhook_deliveries
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+			   OR (status = 'sending' AND next_attempt_at <= NOW() - make_interval(secs => $2))
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+deliveryColumns+`
+	`, limit, staleAfter.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+func (r *WebhookDeliveryRepository) MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error {
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'delivered', attempts = attempts + 1, last_status_code = $2, last_error = NULL, delivered_at = NOW()
+		WHERE delivery_id = $1
+	`, deliveryID, statusCode)
+	return err
+}
+
+func (r *WebhookDeliveryRepository) MarkFailed(ctx context.Context, deliveryID int64, statusCode *int, errMsg string, nextAttemptAt *time.Time) error {
+	status := manman.DeliveryStatusPending
+	next := time.Now()
+	if nextAttemptAt == nil {
+		status = manman.DeliveryStatusDead
+	} else {
+		next = *nextAttemptAt
+	}
+	_, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = attempts + 1, last_status_code = $3, last_error = $4, next_attempt_at = $5
+		WHERE delivery_id = $1
+	`, deliveryID, status, statusCode, errMsg, next)
+	return err
+}
+
+func (r *WebhookDeliveryRepository) Requeue(ctx context.Context, deliveryID int64) error {
+	tag, err := r.db.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		WHERE delivery_id = $1 AND status = 'dead'
+	`, deliveryID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func scanDeliveries(rows pgx.Rows) ([]*manman.WebhookDelivery, error) {
+	defer rows.Close()
+	var deliveries []*manman.WebhookDelivery
+	for rows.Next() {
+		d := &manman.WebhookDelivery{}
+		if err := rows.Scan(&d.DeliveryID, &d.WebhookID, &d.RoutingKey, &d.Payload, &d.Status, &d.Attempts, &d.LastStatusCode, &d.LastError, &d.NextAttemptAt, &d.CreatedAt, &d.DeliveredAt); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
@@ -3,15 +3,14 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type WorkshopAddonRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewWorkshopAddonRepository(db *pgxpool.Pool) *WorkshopAddonRepository {
+func NewWorkshopAddonRepository(db DB) *WorkshopAddonRepository {
 	return &WorkshopAddonRepository{db: db}
 }
 
@@ -170,6 +169,64 @@ func (r *WorkshopAddonRepository) List(ctx context.Context, gameID *int64, inclu
 	return addons, rows.Err()
 }
 
+// ListInActiveLibraries lists the addons in libraries attached to an SGC, directly or
+// through library references
+func (r *WorkshopAddonRepository) ListInActiveLibraries(ctx context.Context) ([]*manman.WorkshopAddon, error) {
+	query := `
+		WITH RECURSIVE active_libraries AS (
+			SELECT library_id FROM sgc_workshop_libraries
+			UNION
+			SELECT wlr.child_library_id
+			FROM workshop_library_references wlr
+			INNER JOIN active_libraries al ON wlr.parent_library_id = al.library_id
+		)
+		SELECT addon_id, game_id, workshop_id, platform_type, name, description,
+			   file_size_bytes, installation_path, preset_id,
+			   is_collection, is_deprecated, metadata, last_updated, created_at, updated_at
+		FROM workshop_addons
+		WHERE addon_id IN (
+			SELECT wla.addon_id
+			FROM workshop_library_addons wla
+			INNER JOIN active_libraries al ON wla.library_id = al.library_id
+		)
+		ORDER BY addon_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var addons []*manman.WorkshopAddon
+	for rows.Next() {
+		addon := &manman.WorkshopAddon{}
+		err := rows.Scan(
+			&addon.AddonID,
+			&addon.GameID,
+			&addon.WorkshopID,
+			&addon.PlatformType,
+			&addon.Name,
+			&addon.Description,
+			&addon.FileSizeBytes,
+			&addon.InstallationPath,
+			&addon.PresetID,
+			&addon.IsCollection,
+			&addon.IsDeprecated,
+			&addon.Metadata,
+			&addon.LastUpdated,
+			&addon.CreatedAt,
+			&addon.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		addons = append(addons, addon)
+	}
+
+	return addons, rows.Err()
+}
+
 func (r *WorkshopAddonRepository) Update(ctx context.Context, addon *manman.WorkshopAddon) error {
 	query := `
 		UPDATE workshop_addons
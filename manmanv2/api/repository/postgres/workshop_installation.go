@@ -3,15 +3,14 @@ package postgres
 import (
 	"context"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type WorkshopInstallationRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewWorkshopInstallationRepository(db *pgxpool.Pool) *WorkshopInstallationRepository {
+func NewWorkshopInstallationRepository(db DB) *WorkshopInstallationRepository {
 	return &WorkshopInstallationRepository{db: db}
 }
 
@@ -50,7 +49,7 @@ func (r *WorkshopInstallationRepository) Get(ctx context.Context, installationID
 	query := `
 		SELECT installation_id, sgc_id, addon_id, status, installation_path,
 			   progress_percent, error_message, download_started_at,
-			   download_completed_at, created_at, updated_at
+			   download_completed_at, update_available, created_at, updated_at
 		FROM workshop_installations
 		WHERE installation_id = $1
 	`
@@ -65,6 +64,7 @@ func (r *WorkshopInstallationRepository) Get(ctx context.Context, installationID
 		&installation.ErrorMessage,
 		&installation.DownloadStartedAt,
 		&installation.DownloadCompletedAt,
+		&installation.UpdateAvailable,
 		&installation.CreatedAt,
 		&installation.UpdatedAt,
 	)
@@ -81,7 +81,7 @@ func (r *WorkshopInstallationRepository) GetBySGCAndAddon(ctx context.Context, s
 	query := `
 		SELECT installation_id, sgc_id, addon_id, status, installation_path,
 			   progress_percent, error_message, download_started_at,
-			   download_completed_at, created_at, updated_at
+			   download_completed_at, update_available, created_at, updated_at
 		FROM workshop_installations
 		WHERE sgc_id = $1 AND addon_id = $2
 	`
@@ -96,6 +96,7 @@ func (r *WorkshopInstallationRepository) GetBySGCAndAddon(ctx context.Context, s
 		&installation.ErrorMessage,
 		&installation.DownloadStartedAt,
 		&installation.DownloadCompletedAt,
+		&installation.UpdateAvailable,
 		&installation.CreatedAt,
 		&installation.UpdatedAt,
 	)
@@ -114,7 +115,7 @@ func (r *WorkshopInstallationRepository) ListBySGC(ctx context.Context, sgcID in
 	query := `
 		SELECT installation_id, sgc_id, addon_id, status, installation_path,
 			   progress_percent, error_message, download_started_at,
-			   download_completed_at, created_at, updated_at
+			   download_completed_at, update_available, created_at, updated_at
 		FROM workshop_installations
 		WHERE sgc_id = $1
 		ORDER BY installation_id
@@ -140,6 +141,7 @@ func (r *WorkshopInstallationRepository) ListBySGC(ctx context.Context, sgcID in
 			&installation.ErrorMessage,
 			&installation.DownloadStartedAt,
 			&installation.DownloadCompletedAt,
+			&installation.UpdateAvailable,
 			&installation.CreatedAt,
 			&installation.UpdatedAt,
 		)
@@ -160,7 +162,7 @@ func (r *WorkshopInstallationRepository) List(ctx context.Context, limit, offset
 	query := `
 		SELECT installation_id, sgc_id, addon_id, status, installation_path,
 			   progress_percent, error_message, download_started_at,
-			   download_completed_at, created_at, updated_at
+			   download_completed_at, update_available, created_at, updated_at
 		FROM workshop_installations
 		ORDER BY installation_id
 		LIMIT $1 OFFSET $2
@@ -185,6 +187,7 @@ func (r *WorkshopInstallationRepository) List(ctx context.Context, limit, offset
 			&installation.ErrorMessage,
 			&installation.DownloadStartedAt,
 			&installation.DownloadCompletedAt,
+			&installation.UpdateAvailable,
 			&installation.CreatedAt,
 			&installation.UpdatedAt,
 		)
@@ -205,7 +208,7 @@ func (r *WorkshopInstallationRepository) ListByAddon(ctx context.Context, addonI
 	query := `
 		SELECT installation_id, sgc_id, addon_id, status, installation_path,
 			   progress_percent, error_message, download_started_at,
-			   download_completed_at, created_at, updated_at
+			   download_completed_at, update_available, created_at, updated_at
 		FROM workshop_installations
 		WHERE addon_id = $1
 		ORDER BY installation_id
@@ -231,6 +234,7 @@ func (r *WorkshopInstallationRepository) ListByAddon(ctx context.Context, addonI
 			&installation.ErrorMessage,
 			&installation.DownloadStartedAt,
 			&installation.DownloadCompletedAt,
+			&installation.UpdateAvailable,
 			&installation.CreatedAt,
 			&installation.UpdatedAt,
 		)
@@ -243,10 +247,12 @@ func (r *WorkshopInstallationRepository) ListByAddon(ctx context.Context, addonI
 	return installations, rows.Err()
 }
 
+// UpdateStatus sets an installation's status. Reaching installed clears update_available.
 func (r *WorkshopInstallationRepository) UpdateStatus(ctx context.Context, installationID int64, status string, errorMsg *string) error {
 	query := `
 		UPDATE workshop_installations
-		SET status = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP
+		SET status = $2, error_message = $3, updated_at = CURRENT_TIMESTAMP,
+		    update_available = update_available AND $2 <> 'installed'
 		WHERE installation_id = $1
 	`
 
@@ -254,6 +260,92 @@ func (r *WorkshopInstallationRepository) UpdateStatus(ctx context.Context, insta
 	return err
 }
 
+// MarkUpdateAvailable flags every installed copy of an addon as out of date and returns them
+func (r *WorkshopInstallationRepository) MarkUpdateAvailable(ctx context.Context, addonID int64) ([]*manman.WorkshopInstallation, error) {
+	query := `
+		UPDATE workshop_installations
+		SET update_available = true, updated_at = CURRENT_TIMESTAMP
+		WHERE addon_id = $1 AND status = 'installed'
+		RETURNING installation_id, sgc_id, addon_id, status, installation_path,
+			   progress_percent, error_message, download_started_at,
+			   download_completed_at, update_available, created_at, updated_at
+	`
+
+	rows, err := r.db.Query(ctx, query, addonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var installations []*manman.WorkshopInstallation
+	for rows.Next() {
+		installation := &manman.WorkshopInstallation{}
+		err := rows.Scan(
+			&installation.InstallationID,
+			&installation.SGCID,
+			&installation.AddonID,
+			&installation.Status,
+			&installation.InstallationPath,
+			&installation.ProgressPercent,
+			&installation.ErrorMessage,
+			&installation.DownloadStartedAt,
+			&installation.DownloadCompletedAt,
+			&installation.UpdateAvailable,
+			&installation.CreatedAt,
+			&installation.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, installation)
+	}
+
+	return installations, rows.Err()
+}
+
+// ListUpdateAvailable returns installed addons flagged as out of date, oldest flag first
+func (r *WorkshopInstallationRepository) ListUpdateAvailable(ctx context.Context) ([]*manman.WorkshopInstallation, error) {
+	query := `
+		SELECT installation_id, sgc_id, addon_id, status, installation_path,
+			   progress_percent, error_message, download_started_at,
+			   download_completed_at, update_available, created_at, updated_at
+		FROM workshop_installations
+		WHERE update_available AND status = 'installed'
+		ORDER BY updated_at, installation_id
+	`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var installations []*manman.WorkshopInstallation
+	for rows.Next() {
+		installation := &manman.WorkshopInstallation{}
+		err := rows.Scan(
+			&installation.InstallationID,
+			&installation.SGCID,
+			&installation.AddonID,
+			&installation.Status,
+			&installation.InstallationPath,
+			&installation.ProgressPercent,
+			&installation.ErrorMessage,
+			&installation.DownloadStartedAt,
+			&installation.DownloadCompletedAt,
+			&installation.UpdateAvailable,
+			&installation.CreatedAt,
+			&installation.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		installations = append(installations, installation)
+	}
+
+	return installations, rows.Err()
+}
+
 func (r *WorkshopInstallationRepository) UpdateProgress(ctx context.Context, installationID int64, percent int) error {
 	query := `
 		UPDATE workshop_installations
@@ -4,15 +4,14 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
 type WorkshopLibraryRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
-func NewWorkshopLibraryRepository(db *pgxpool.Pool) *WorkshopLibraryRepository {
+func NewWorkshopLibraryRepository(db DB) *WorkshopLibraryRepository {
 	return &WorkshopLibraryRepository{db: db}
 }
 
@@ -274,6 +273,43 @@ func (r *WorkshopLibraryRepository) ListReferences(ctx context.Context, libraryI
 	return libraries, rows.Err()
 }
 
+// ListByAddon lists the libraries that contain an addon directly
+func (r *WorkshopLibraryRepository) ListByAddon(ctx context.Context, addonID int64) ([]*manman.WorkshopLibrary, error) {
+	query := `
+		SELECT wl.library_id, wl.game_id, wl.name, wl.description, wl.preset_id, wl.created_at, wl.updated_at
+		FROM workshop_libraries wl
+		INNER JOIN workshop_library_addons wla ON wl.library_id = wla.library_id
+		WHERE wla.addon_id = $1
+		ORDER BY wl.library_id
+	`
+
+	rows, err := r.db.Query(ctx, query, addonID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var libraries []*manman.WorkshopLibrary
+	for rows.Next() {
+		library := &manman.WorkshopLibrary{}
+		err := rows.Scan(
+			&library.LibraryID,
+			&library.GameID,
+			&library.Name,
+			&library.Description,
+			&library.PresetID,
+			&library.CreatedAt,
+			&library.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		libraries = append(libraries, library)
+	}
+
+	return libraries, rows.Err()
+}
+
 // DetectCircularReference checks if adding a reference would create a circular dependency
 // It uses a recursive CTE to traverse the reference graph
 func (r *WorkshopLibraryRepository) DetectCircularReference(ctx context.Context, parentLibraryID, childLibraryID int64) (bool, error) {
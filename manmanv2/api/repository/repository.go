@@ -2,11 +2,24 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
+// ErrStatusChanged is returned when a row is no longer in the status a change expected,
+// because another request changed it first
+var ErrStatusChanged = errors.New("status changed concurrently")
+
+// ErrPortsTaken is returned when a host port picked for an SGC was taken by another SGC
+// or a session before the SGC could be saved
+var ErrPortsTaken = errors.New("port taken concurrently")
+
+// ErrInsufficientCapacity is returned when a session's resource limits don't fit in what
+// its server has free
+var ErrInsufficientCapacity = errors.New("not enough capacity")
+
 // ServerRepository defines operations for Server entities
 type ServerRepository interface {
 	Create(ctx context.Context, name string) (*manman.Server, error)
@@ -25,6 +38,7 @@ type ServerRepository interface {
 type GameRepository interface {
 	Create(ctx context.Context, game *manman.Game) (*manman.Game, error)
 	Get(ctx context.Context, gameID int64) (*manman.Game, error)
+	GetByName(ctx context.Context, name string) (*manman.Game, error)
 	List(ctx context.Context, limit, offset int) ([]*manman.Game, error)
 	Update(ctx context.Context, game *manman.Game) error
 	Delete(ctx context.Context, gameID int64) error
@@ -42,6 +56,9 @@ type GameConfigRepository interface {
 // ServerGameConfigRepository defines operations for ServerGameConfig entities
 type ServerGameConfigRepository interface {
 	Create(ctx context.Context, sgc *manman.ServerGameConfig) (*manman.ServerGameConfig, error)
+	// CreateReservingPorts creates sgc, holding its server's row lock while it checks that
+	// no other SGC binds and no session holds any of reserve. Returns ErrPortsTaken if one does.
+	CreateReservingPorts(ctx context.Context, sgc *manman.ServerGameConfig, reserve []*manman.PortBinding) (*manman.ServerGameConfig, error)
 	Get(ctx context.Context, sgcID int64) (*manman.ServerGameConfig, error)
 	List(ctx context.Context, serverID *int64, limit, offset int) ([]*manman.ServerGameConfig, error)
 	Update(ctx context.Context, sgc *manman.ServerGameConfig) error
@@ -66,6 +83,11 @@ type SessionFilters struct {
 // SessionRepository defines operations for Session entities
 type SessionRepository interface {
 	Create(ctx context.Context, session *manman.Session) (*manman.Session, error)
+	// CreateWithinCapacity creates session, holding serverID's row lock while it checks that
+	// limits fit in what the server's capacity leaves after the limits of other SGCs' active
+	// sessions there. Returns an error wrapping ErrInsufficientCapacity if they don't.
+	// patches are saved as the session's session-level patches in the same transaction.
+	CreateWithinCapacity(ctx context.Context, session *manman.Session, serverID int64, limits manman.ResourceLimits, patches []*manman.ConfigurationPatch) (*manman.Session, error)
 	Get(ctx context.Context, sessionID int64) (*manman.Session, error)
 	List(ctx context.Context, sgcID *int64, limit, offset int) ([]*manman.Session, error)
 	ListWithFilters(ctx context.Context, filters *SessionFilters, limit, offset int) ([]*manman.Session, error)
@@ -75,6 +97,13 @@ type SessionRepository interface {
 	UpdateSessionEnd(ctx context.Context, sessionID int64, status string, endedAt time.Time, exitCode *int) error
 	GetStaleSessions(ctx context.Context, threshold time.Duration) ([]*manman.Session, error)
 	StopOtherSessionsForSGC(ctx context.Context, sessionID int64, sgcID int64) error
+	SetRestartAbandoned(ctx context.Context, sessionID int64, reason string) error
+	// UpdatePlayerCount records a reported player count; idle_since is set when it drops to zero and cleared otherwise
+	UpdatePlayerCount(ctx context.Context, sessionID int64, count int32, at time.Time) error
+	// UpdateServerInfo records the map and player cap reported by the game config's status query
+	UpdateServerInfo(ctx context.Context, sessionID int64, mapName string, maxPlayers int32) error
+	// ListIdle returns live sessions whose SGC idle_shutdown timeout has elapsed since the player count reached zero
+	ListIdle(ctx context.Context, now time.Time) ([]*manman.Session, error)
 }
 
 // ServerCapabilityRepository defines operations for ServerCapability entities
@@ -94,15 +123,58 @@ type LogReferenceRepository interface {
 	GetMinMaxTimes(ctx context.Context, sgcID int64) (minTime, maxTime *time.Time, err error)
 	GetMinMaxTimesBySession(ctx context.Context, sessionID int64) (minTime, maxTime *time.Time, err error)
 	GetHistogramBySession(ctx context.Context, sessionID int64, bucketSeconds int64, startTime, endTime *int64) (map[int64]map[string]int32, error)
+	// SetSearchIndex records the sources and distinct words of an archived window's lines;
+	// nil terms leave it unindexed so every search scans it
+	SetSearchIndex(ctx context.Context, logID int64, sources, terms []string) error
+	// SearchWindows returns the complete archived windows that may hold lines matching
+	// filters, ordered by minute then session
+	SearchWindows(ctx context.Context, filters *LogSearchFilters, limit int) ([]*manman.ArchivedLogWindow, error)
+}
+
+// LogSearchFilters narrows a log search; empty slices match everything
+type LogSearchFilters struct {
+	Start      time.Time
+	End        time.Time
+	SessionIDs []int64
+	SGCIDs     []int64
+	ServerIDs  []int64
+	Sources    []string
+	Terms      []string // every word must be in a window's index, unless it has none
+	// After resumes a search: windows before (After.MinuteTimestamp, After.SessionID) are skipped
+	After *manman.ArchivedLogWindow
+}
+
+// PlayerSessionRepository defines operations for PlayerSession entities, which the
+// log-processor records from join, leave and chat log lines
+type PlayerSessionRepository interface {
+	// Join opens a stay for the player; a no-op when they already have one open on the session
+	Join(ctx context.Context, sessionID, sgcID int64, playerName string, playerID *string, at time.Time) error
+	// Leave closes the player's open stay on the session, if any
+	Leave(ctx context.Context, sessionID int64, playerName string, at time.Time) error
+	// RecordChat counts a chat message on the player's open stay, if any
+	RecordChat(ctx context.Context, sessionID int64, playerName string, at time.Time) error
+	// EndSession closes every stay still open on the session
+	EndSession(ctx context.Context, sessionID int64, at time.Time) error
+	ListBySession(ctx context.Context, sessionID int64, onlineOnly bool) ([]*manman.PlayerSession, error)
+	// ListBySGC returns stays on the SGC's sessions that overlap [since, until)
+	ListBySGC(ctx context.Context, sgcID int64, since, until time.Time) ([]*manman.PlayerSession, error)
 }
 
 // BackupRepository defines operations for Backup entities
 type BackupRepository interface {
 	Create(ctx context.Context, backup *manman.Backup) (*manman.Backup, error)
 	Get(ctx context.Context, backupID int64) (*manman.Backup, error)
-	List(ctx context.Context, sgcID *int64, sessionID *int64, limit int, offset int) ([]*manman.Backup, error)
+	// List returns non-deleted backups, newest first; includePruned adds the ones the retention
+	// job removed, with their pruned_reason set
+	List(ctx context.Context, sgcID *int64, sessionID *int64, includePruned bool, limit int, offset int) ([]*manman.Backup, error)
 	Delete(ctx context.Context, backupID int64) error
 	UpdateStatus(ctx context.Context, backupID int64, status string, s3URL *string, sizeBytes *int64, errMsg *string) error
+	// SetChecksum records the sha256 of the uploaded archive
+	SetChecksum(ctx context.Context, backupID int64, checksum string) error
+	// ListCompletedByConfig returns completed, non-deleted backups for a config, newest first
+	ListCompletedByConfig(ctx context.Context, backupConfigID int64) ([]*manman.Backup, error)
+	// Prune soft-deletes a backup and records why the retention job removed it
+	Prune(ctx context.Context, backupID int64, reason string) error
 }
 
 // BackupConfigRepository defines operations for BackupConfig entities
@@ -115,12 +187,158 @@ type BackupConfigRepository interface {
 	// ListDue returns enabled configs whose cadence has elapsed and whose SGC had an active session since last_backup_at
 	ListDue(ctx context.Context, now time.Time) ([]*manman.BackupConfig, error)
 	UpdateLastBackupAt(ctx context.Context, backupConfigID int64, t time.Time) error
+	// ListWithRetention returns configs that have at least one retention rule enabled
+	ListWithRetention(ctx context.Context) ([]*manman.BackupConfig, error)
 	// Actions
 	AddAction(ctx context.Context, backupConfigID, actionID int64, displayOrder int) error
 	RemoveAction(ctx context.Context, backupConfigID, actionID int64) error
 	ListActions(ctx context.Context, backupConfigID int64) ([]*manman.BackupConfigAction, error)
 }
 
+// SGCScheduleRepository defines operations for SGCSchedule entities
+type SGCScheduleRepository interface {
+	Create(ctx context.Context, schedule *manman.SGCSchedule) (*manman.SGCSchedule, error)
+	Get(ctx context.Context, scheduleID int64) (*manman.SGCSchedule, error)
+	List(ctx context.Context, sgcID int64) ([]*manman.SGCSchedule, error)
+	ListEnabled(ctx context.Context) ([]*manman.SGCSchedule, error)
+	Update(ctx context.Context, schedule *manman.SGCSchedule) error
+	Delete(ctx context.Context, scheduleID int64) error
+	// MarkEvaluated records that occurrences up to at have been handled, and the action taken if any
+	MarkEvaluated(ctx context.Context, scheduleID int64, at time.Time, action string) error
+}
+
+// SGCImageStatusRepository defines operations for the image state hosts report per SGC
+type SGCImageStatusRepository interface {
+	Upsert(ctx context.Context, status *manman.SGCImageStatus) error
+	Get(ctx context.Context, sgcID int64) (*manman.SGCImageStatus, error)
+	// ListBySGCs returns the statuses of those of sgcIDs that have one
+	ListBySGCs(ctx context.Context, sgcIDs []int64) ([]*manman.SGCImageStatus, error)
+}
+
+// SGCGrantRepository defines operations for per-SGC operator grants
+type SGCGrantRepository interface {
+	Create(ctx context.Context, grant *manman.SGCGrant) (*manman.SGCGrant, error)
+	Delete(ctx context.Context, grantID int64) error
+	// List returns sgcID's grants, or every grant when sgcID is 0
+	List(ctx context.Context, sgcID int64) ([]*manman.SGCGrant, error)
+	// ListSGCIDs returns the SGCs subject has been granted
+	ListSGCIDs(ctx context.Context, subject string) ([]int64, error)
+	Exists(ctx context.Context, sgcID int64, subject string) (bool, error)
+}
+
+// AuditEventFilters defines filters for audit event queries; zero values match everything
+type AuditEventFilters struct {
+	Subject   string
+	Method    string
+	EntityKey string // e.g. "session_id"; with EntityID, matches events that targeted that entity
+	EntityID  int64
+	Since     *time.Time
+	Until     *time.Time
+}
+
+// AuditEventRepository defines operations for the append-only audit log
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *manman.AuditEvent) (*manman.AuditEvent, error)
+	// List returns matching events, newest first
+	List(ctx context.Context, filters *AuditEventFilters, limit, offset int) ([]*manman.AuditEvent, error)
+}
+
+// SGCMigrationRepository defines operations for SGC migrations
+type SGCMigrationRepository interface {
+	// Create marks the source SGC migrating and records the migration together. It returns
+	// ErrStatusChanged if the SGC has left SourceStatus, and fails on the in-flight index if
+	// the source SGC is already migrating.
+	Create(ctx context.Context, migration *manman.SGCMigration) (*manman.SGCMigration, error)
+	Get(ctx context.Context, migrationID int64) (*manman.SGCMigration, error)
+	// List returns migrations with sgcID as source or target, or every migration when sgcID is 0, newest first
+	List(ctx context.Context, sgcID int64, limit, offset int) ([]*manman.SGCMigration, error)
+	ListInFlight(ctx context.Context) ([]*manman.SGCMigration, error)
+	// Update saves the status, target SGC and error; completing or failing sets completed_at
+	Update(ctx context.Context, migration *manman.SGCMigration) error
+	// SetVolumeBackup records the backup taken of the volume with volumeID
+	SetVolumeBackup(ctx context.Context, migrationID, volumeID, backupID int64) error
+	// RecordRestore merges a restore result into the volume with backupID
+	RecordRestore(ctx context.Context, migrationID, backupID int64, checksum, errMsg string) error
+	// CopySGCSettings replaces targetSGCID's patches, action definitions, schedules, grants
+	// and alert rules with copies of sourceSGCID's, in one transaction
+	CopySGCSettings(ctx context.Context, sourceSGCID, targetSGCID int64) error
+}
+
+// WebhookRepository defines operations for outgoing event webhooks
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *manman.Webhook) (*manman.Webhook, error)
+	Get(ctx context.Context, webhookID int64) (*manman.Webhook, error)
+	List(ctx context.Context) ([]*manman.Webhook, error)
+	ListEnabled(ctx context.Context) ([]*manman.Webhook, error)
+	Update(ctx context.Context, webhook *manman.Webhook) error
+	Delete(ctx context.Context, webhookID int64) error
+}
+
+// WebhookDeliveryRepository defines operations for the webhook delivery queue and history
+type WebhookDeliveryRepository interface {
+	Create(ctx context.Context, delivery *manman.WebhookDelivery) (*manman.WebhookDelivery, error)
+	Get(ctx context.Context, deliveryID int64) (*manman.WebhookDelivery, error)
+	// List returns webhookID's deliveries, newest first; status filters when non-empty
+	List(ctx context.Context, webhookID int64, status string, limit, offset int) ([]*manman.WebhookDelivery, error)
+	// ClaimDue marks up to limit pending deliveries whose next attempt is due as sending
+	// and returns them. Deliveries left sending for longer than staleAfter (the notifier
+	// died mid-send) are claimed again.
+	ClaimDue(ctx context.Context, limit int, staleAfter time.Duration) ([]*manman.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, deliveryID int64, statusCode int) error
+	// MarkFailed records a failed attempt; the delivery is retried at nextAttemptAt, or
+	// dead-lettered when nextAttemptAt is nil
+	MarkFailed(ctx context.Context, deliveryID int64, statusCode *int, errMsg string, nextAttemptAt *time.Time) error
+	// Requeue makes a dead delivery pending again with a fresh attempt count
+	Requeue(ctx context.Context, deliveryID int64) error
+}
+
+// AlertRuleRepository defines operations for log alert rules
+type AlertRuleRepository interface {
+	Create(ctx context.Context, rule *manman.AlertRule) (*manman.AlertRule, error)
+	Get(ctx context.Context, ruleID int64) (*manman.AlertRule, error)
+	// List returns the rules scoped to gameID or sgcID, whichever is set, or every rule when neither is
+	List(ctx context.Context, gameID, sgcID *int64) ([]*manman.AlertRule, error)
+	// ListApplicable returns the rules that watch sgcID: its own and its game's
+	ListApplicable(ctx context.Context, sgcID int64) ([]*manman.AlertRule, error)
+	// Update saves everything but the scope
+	Update(ctx context.Context, rule *manman.AlertRule) error
+	Delete(ctx context.Context, ruleID int64) error
+}
+
+// AlertEventFilters defines filters for alert event queries; zero values match everything
+type AlertEventFilters struct {
+	RuleID    int64
+	SGCID     int64
+	SessionID int64
+}
+
+// AlertEventRepository defines operations for the history of fired alerts
+type AlertEventRepository interface {
+	Create(ctx context.Context, event *manman.AlertEvent) (*manman.AlertEvent, error)
+	// UpdateResponse records the action execution and backup an alert started, or why they failed
+	UpdateResponse(ctx context.Context, alertID int64, actionExecutionID, backupID *int64, responseError *string) error
+	// LastFired returns when ruleID last fired on sgcID, or nil if it never has
+	LastFired(ctx context.Context, ruleID, sgcID int64) (*time.Time, error)
+	// List returns matching events, newest first
+	List(ctx context.Context, filters *AlertEventFilters, limit, offset int) ([]*manman.AlertEvent, error)
+}
+
+// MetricsRepository stores the resource usage hosts sample, in minute and hour buckets
+type MetricsRepository interface {
+	// Record adds a sample to the minute buckets of its host and sessions
+	Record(ctx context.Context, sample *manman.MetricsSample) error
+	// ListHost returns a server's disk usage buckets starting in [start, end), oldest first
+	ListHost(ctx context.Context, serverID int64, start, end time.Time) ([]*manman.HostMetricsBucket, error)
+	// ListSessions returns the buckets of every session on a server starting in
+	// [start, end), by session and then oldest first
+	ListSessions(ctx context.Context, serverID int64, start, end time.Time) ([]*manman.SessionMetricsBucket, error)
+	// Rollup folds minute buckets starting before olderThan into hour buckets and
+	// deletes them, returning how many minute buckets it folded
+	Rollup(ctx context.Context, olderThan time.Time) (int64, error)
+	// DeleteBefore deletes buckets of every resolution starting before before
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
 // ServerPortRepository defines operations for port allocation management
 type ServerPortRepository interface {
 	AllocatePort(ctx context.Context, serverID int64, port int, protocol string, sessionID int64) error
@@ -169,6 +387,8 @@ type WorkshopAddonRepository interface {
 	Get(ctx context.Context, addonID int64) (*manman.WorkshopAddon, error)
 	GetByWorkshopID(ctx context.Context, gameID int64, workshopID string, platformType string) (*manman.WorkshopAddon, error)
 	List(ctx context.Context, gameID *int64, includeDeprecated bool, limit, offset int) ([]*manman.WorkshopAddon, error)
+	// ListInActiveLibraries lists addons in libraries attached to an SGC, directly or through references
+	ListInActiveLibraries(ctx context.Context) ([]*manman.WorkshopAddon, error)
 	Update(ctx context.Context, addon *manman.WorkshopAddon) error
 	Delete(ctx context.Context, addonID int64) error
 }
@@ -183,6 +403,10 @@ type WorkshopInstallationRepository interface {
 	ListByAddon(ctx context.Context, addonID int64, limit, offset int) ([]*manman.WorkshopInstallation, error)
 	UpdateStatus(ctx context.Context, installationID int64, status string, errorMsg *string) error
 	UpdateProgress(ctx context.Context, installationID int64, percent int) error
+	// MarkUpdateAvailable flags the installed copies of an addon as out of date and returns them
+	MarkUpdateAvailable(ctx context.Context, addonID int64) ([]*manman.WorkshopInstallation, error)
+	// ListUpdateAvailable returns installed addons flagged as out of date
+	ListUpdateAvailable(ctx context.Context) ([]*manman.WorkshopInstallation, error)
 	Delete(ctx context.Context, installationID int64) error
 }
 
@@ -201,6 +425,7 @@ type WorkshopLibraryRepository interface {
 	AddReference(ctx context.Context, parentLibraryID, childLibraryID int64) error
 	RemoveReference(ctx context.Context, parentLibraryID, childLibraryID int64) error
 	ListReferences(ctx context.Context, libraryID int64) ([]*manman.WorkshopLibrary, error)
+	ListByAddon(ctx context.Context, addonID int64) ([]*manman.WorkshopLibrary, error)
 	DetectCircularReference(ctx context.Context, parentLibraryID, childLibraryID int64) (bool, error)
 }
 
@@ -222,8 +447,19 @@ type Repository struct {
 	Sessions               SessionRepository
 	ServerCapabilities     ServerCapabilityRepository
 	LogReferences          LogReferenceRepository
+	PlayerSessions         PlayerSessionRepository
 	Backups                BackupRepository
 	BackupConfigs          BackupConfigRepository
+	SGCSchedules           SGCScheduleRepository
+	SGCGrants              SGCGrantRepository
+	SGCMigrations          SGCMigrationRepository
+	SGCImageStatuses       SGCImageStatusRepository
+	AuditEvents            AuditEventRepository
+	Webhooks               WebhookRepository
+	WebhookDeliveries      WebhookDeliveryRepository
+	AlertRules             AlertRuleRepository
+	AlertEvents            AlertEventRepository
+	Metrics                MetricsRepository
 	ServerPorts            ServerPortRepository
 	ConfigurationStrategies ConfigurationStrategyRepository
 	ConfigurationPatches   ConfigurationPatchRepository
@@ -233,4 +469,8 @@ type Repository struct {
 	WorkshopLibraries      WorkshopLibraryRepository
 	AddonPathPresets       AddonPathPresetRepository
 	Actions                interface{} // ActionRepository from postgres package
+
+	// InTx runs fn with repositories whose work all goes through one transaction,
+	// committed when fn returns nil and rolled back otherwise
+	InTx func(ctx context.Context, fn func(tx *Repository) error) error
 }
@@ -2,12 +2,14 @@ package workshop
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"path/filepath"
 	"time"
 
 	"github.com/whale-net/everything/manmanv2/models"
+	"github.com/whale-net/everything/manmanv2/api/addonsource"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	"github.com/whale-net/everything/manmanv2/api/steam"
 )
@@ -23,14 +25,24 @@ type SteamClient interface {
 	GetCollectionDetails(ctx context.Context, collectionID string) ([]steam.CollectionItem, error)
 }
 
-// DownloadAddonCommand represents a command to download a workshop addon
+// ErrUnsupportedPlatform is returned for addons on a platform without a registered source
+var ErrUnsupportedPlatform = errors.New("unsupported platform_type")
+
+// DownloadAddonCommand represents a command to download a workshop addon.
+// Addons from an addon source carry a DownloadURL; the rest are fetched with SteamCMD.
 type DownloadAddonCommand struct {
-	InstallationID int64  `json:"installation_id"`
-	SGCID          int64  `json:"sgc_id"`
-	AddonID        int64  `json:"addon_id"`
-	WorkshopID     string `json:"workshop_id"`
-	SteamAppID     string `json:"steam_app_id"`
-	InstallPath    string `json:"install_path"`
+	InstallationID    int64  `json:"installation_id"`
+	SGCID             int64  `json:"sgc_id"`
+	AddonID           int64  `json:"addon_id"`
+	WorkshopID        string `json:"workshop_id"`
+	SteamAppID        string `json:"steam_app_id"`
+	InstallPath       string `json:"install_path"`
+	PlatformType      string `json:"platform_type,omitempty"`
+	DownloadURL       string `json:"download_url,omitempty"`
+	FileName          string `json:"file_name,omitempty"`
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
+	Checksum          string `json:"checksum,omitempty"`
+	Extract           bool   `json:"extract,omitempty"`
 }
 
 // RemoveAddonCommand represents a command to remove a workshop addon
@@ -46,7 +58,8 @@ type WorkshopManagerInterface interface {
 	InstallAddon(ctx context.Context, sgcID, addonID int64, forceReinstall, skipDispatch bool, installationPathOverride string, presetIDOverride int64, volumeIDOverride int64) (*manman.WorkshopInstallation, error)
 	RemoveInstallation(ctx context.Context, installationID int64) error
 	ResetInstallation(ctx context.Context, installationID int64) (*manman.WorkshopInstallation, error)
-	FetchMetadata(ctx context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error)
+	FetchMetadata(ctx context.Context, gameID int64, platformType, workshopID string) (*manman.WorkshopAddon, error)
+	ResolveDownload(ctx context.Context, addon *manman.WorkshopAddon) (*addonsource.Download, error)
 	EnsureLibraryAddonsInstalled(ctx context.Context, sgcID int64) error
 }
 
@@ -62,6 +75,7 @@ type WorkshopManager struct {
 	presetRepo       repository.AddonPathPresetRepository
 	sessionRepo      repository.SessionRepository
 	steamClient      SteamClient
+	sources          map[string]addonsource.Source
 	rmqPublisher     RMQPublisher
 }
 
@@ -90,10 +104,16 @@ func NewWorkshopManager(
 		presetRepo:       presetRepo,
 		sessionRepo:      sessionRepo,
 		steamClient:      steamClient,
+		sources:          make(map[string]addonsource.Source),
 		rmqPublisher:     rmqPublisher,
 	}
 }
 
+// RegisterSource makes addons of platformType available through source
+func (wm *WorkshopManager) RegisterSource(platformType string, source addonsource.Source) {
+	wm.sources[platformType] = source
+}
+
 // InstallAddon creates/updates the installation record and, unless skipDispatch is true,
 // publishes a download command to RabbitMQ for the host manager to execute.
 // installationPathOverride, if non-empty, overrides the addon's configured installation path.
@@ -166,6 +186,18 @@ func (wm *WorkshopManager) InstallAddon(ctx context.Context, sgcID, addonID int6
 			WorkshopID:     addon.WorkshopID,
 			SteamAppID:     steamAppID,
 			InstallPath:    installPath,
+			PlatformType:   addon.PlatformType,
+		}
+		download, err := wm.ResolveDownload(ctx, addon)
+		if err != nil {
+			return nil, err
+		}
+		if download != nil {
+			downloadCmd.DownloadURL = download.URL
+			downloadCmd.FileName = download.FileName
+			downloadCmd.ChecksumAlgorithm = download.ChecksumAlgorithm
+			downloadCmd.Checksum = download.Checksum
+			downloadCmd.Extract = download.Extract
 		}
 
 		routingKey := fmt.Sprintf("command.host.%d.workshop.download", sgc.ServerID)
@@ -235,8 +267,12 @@ func (wm *WorkshopManager) resolveInstallationPath(ctx context.Context, sgc *man
 	return filepath.Join(volume.ContainerPath, relativePath), nil
 }
 
-// FetchMetadata fetches metadata from Steam Workshop without creating a database record
-func (wm *WorkshopManager) FetchMetadata(ctx context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error) {
+// FetchMetadata fetches metadata from the addon's platform without creating a database record
+func (wm *WorkshopManager) FetchMetadata(ctx context.Context, gameID int64, platformType, workshopID string) (*manman.WorkshopAddon, error) {
+	if platformType != manman.PlatformTypeSteamWorkshop {
+		return wm.fetchSourceMetadata(ctx, gameID, platformType, workshopID)
+	}
+
 	// Fetch metadata from Steam Workshop API
 	metadata, err := wm.steamClient.GetWorkshopItemDetails(ctx, workshopID)
 	if err != nil {
@@ -278,6 +314,58 @@ func (wm *WorkshopManager) FetchMetadata(ctx context.Context, gameID int64, work
 	return addon, nil
 }
 
+// fetchSourceMetadata fetches metadata from a registered addon source. What the source
+// needs to resolve downloads later is kept in the addon's metadata.
+func (wm *WorkshopManager) fetchSourceMetadata(ctx context.Context, gameID int64, platformType, id string) (*manman.WorkshopAddon, error) {
+	source, ok := wm.sources[platformType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPlatform, platformType)
+	}
+	metadata, err := source.FetchMetadata(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s metadata: %w", platformType, err)
+	}
+
+	addon := &manman.WorkshopAddon{
+		GameID:       gameID,
+		WorkshopID:   id,
+		PlatformType: platformType,
+		Name:         metadata.Title,
+		Metadata:     metadata.Extra,
+	}
+	if metadata.Description != "" {
+		addon.Description = &metadata.Description
+	}
+	if metadata.FileSize > 0 {
+		addon.FileSizeBytes = &metadata.FileSize
+	}
+	if !metadata.TimeUpdated.IsZero() {
+		addon.LastUpdated = &metadata.TimeUpdated
+	}
+	return addon, nil
+}
+
+// ResolveDownload finds the file the host manager should download for an addon. Steam
+// Workshop addons are downloaded by SteamCMD, so they resolve to nil.
+func (wm *WorkshopManager) ResolveDownload(ctx context.Context, addon *manman.WorkshopAddon) (*addonsource.Download, error) {
+	if addon.PlatformType == "" || addon.PlatformType == manman.PlatformTypeSteamWorkshop {
+		return nil, nil
+	}
+	source, ok := wm.sources[addon.PlatformType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPlatform, addon.PlatformType)
+	}
+	download, err := source.ResolveDownload(ctx, addon.WorkshopID, addon.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve download for addon %d: %w", addon.AddonID, err)
+	}
+	// The host refuses files it can't verify
+	if download.Checksum == "" {
+		return nil, fmt.Errorf("%s has no checksum for addon %d's file %s", addon.PlatformType, addon.AddonID, download.FileName)
+	}
+	return download, nil
+}
+
 // EnsureLibraryAddonsInstalled pre-installs all addons from libraries attached to an SGC.
 // It collects all unique addon IDs (recursively via library references), triggers installs
 // for any not yet installed, and polls until they complete or timeout.
@@ -385,7 +473,7 @@ func (wm *WorkshopManager) EnsureLibraryAddonsInstalled(ctx context.Context, sgc
 // FetchAndCreateAddon fetches metadata from Steam Workshop and creates addon
 func (wm *WorkshopManager) FetchAndCreateAddon(ctx context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error) {
 	// Fetch metadata using FetchMetadata
-	addon, err := wm.FetchMetadata(ctx, gameID, workshopID)
+	addon, err := wm.FetchMetadata(ctx, gameID, manman.PlatformTypeSteamWorkshop, workshopID)
 	if err != nil {
 		return nil, err
 	}
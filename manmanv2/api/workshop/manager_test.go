@@ -2,11 +2,14 @@ package workshop
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/whale-net/everything/manmanv2/models"
+	"github.com/whale-net/everything/manmanv2/api/addonsource"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	"github.com/whale-net/everything/manmanv2/api/steam"
 )
@@ -37,6 +40,10 @@ func (m *mockAddonRepo) List(ctx context.Context, gameID *int64, includeDeprecat
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (m *mockAddonRepo) ListInActiveLibraries(ctx context.Context) ([]*manman.WorkshopAddon, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockAddonRepo) Update(ctx context.Context, addon *manman.WorkshopAddon) error {
 	return fmt.Errorf("not implemented")
 }
@@ -103,6 +110,14 @@ func (m *mockInstallationRepo) List(ctx context.Context, limit, offset int) ([]*
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (m *mockInstallationRepo) MarkUpdateAvailable(ctx context.Context, addonID int64) ([]*manman.WorkshopInstallation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (m *mockInstallationRepo) ListUpdateAvailable(ctx context.Context) ([]*manman.WorkshopInstallation, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockInstallationRepo) Delete(ctx context.Context, installationID int64) error {
 	return fmt.Errorf("not implemented")
 }
@@ -123,6 +138,10 @@ func (m *mockSGCRepo) Create(ctx context.Context, sgc *manman.ServerGameConfig)
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (m *mockSGCRepo) CreateReservingPorts(ctx context.Context, sgc *manman.ServerGameConfig, reserve []*manman.PortBinding) (*manman.ServerGameConfig, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockSGCRepo) List(ctx context.Context, serverID *int64, limit, offset int) ([]*manman.ServerGameConfig, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -256,6 +275,10 @@ func (m *mockGameRepo) Get(ctx context.Context, gameID int64) (*manman.Game, err
 	return game, nil
 }
 
+func (m *mockGameRepo) GetByName(ctx context.Context, name string) (*manman.Game, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockGameRepo) List(ctx context.Context, limit, offset int) ([]*manman.Game, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -291,6 +314,10 @@ func (m *mockSessionRepo) Create(ctx context.Context, session *manman.Session) (
 	return nil, fmt.Errorf("not implemented")
 }
 
+func (m *mockSessionRepo) CreateWithinCapacity(ctx context.Context, session *manman.Session, serverID int64, limits manman.ResourceLimits, patches []*manman.ConfigurationPatch) (*manman.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 func (m *mockSessionRepo) Get(ctx context.Context, sessionID int64) (*manman.Session, error) {
 	return nil, fmt.Errorf("not implemented")
 }
@@ -334,6 +361,22 @@ func (m *mockSessionRepo) StopOtherSessionsForSGC(ctx context.Context, sessionID
 	return fmt.Errorf("not implemented")
 }
 
+func (m *mockSessionRepo) SetRestartAbandoned(ctx context.Context, sessionID int64, reason string) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockSessionRepo) UpdatePlayerCount(ctx context.Context, sessionID int64, count int32, at time.Time) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockSessionRepo) UpdateServerInfo(ctx context.Context, sessionID int64, mapName string, maxPlayers int32) error {
+	return fmt.Errorf("not implemented")
+}
+
+func (m *mockSessionRepo) ListIdle(ctx context.Context, now time.Time) ([]*manman.Session, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
 // Helper function to create a test WorkshopManager with mocks
 func createTestManager() (*WorkshopManager, *mockAddonRepo, *mockInstallationRepo, *mockSGCRepo, *mockGameConfigRepo, *mockVolumeRepo, *mockSessionRepo, *mockRMQPublisher) {
 	addonRepo := &mockAddonRepo{addons: make(map[int64]*manman.WorkshopAddon)}
@@ -1401,7 +1444,7 @@ func TestFetchMetadata(t *testing.T) {
 				steamClient: steamClient,
 			}
 
-			addon, err := manager.FetchMetadata(ctx, tt.gameID, tt.workshopID)
+			addon, err := manager.FetchMetadata(ctx, tt.gameID, manman.PlatformTypeSteamWorkshop, tt.workshopID)
 
 			if tt.expectError {
 				if err == nil {
@@ -1421,3 +1464,109 @@ func TestFetchMetadata(t *testing.T) {
 		})
 	}
 }
+
+// fakeAddonSource resolves every addon to the same download
+type fakeAddonSource struct {
+	metadata *addonsource.Metadata
+	download *addonsource.Download
+	gotOpts  map[string]interface{}
+}
+
+func (f *fakeAddonSource) FetchMetadata(ctx context.Context, id string) (*addonsource.Metadata, error) {
+	return f.metadata, nil
+}
+
+func (f *fakeAddonSource) ResolveDownload(ctx context.Context, id string, opts map[string]interface{}) (*addonsource.Download, error) {
+	f.gotOpts = opts
+	return f.download, nil
+}
+
+func TestInstallAddon_DispatchesSourceDownload(t *testing.T) {
+	ctx := context.Background()
+	manager, addonRepo, _, sgcRepo, _, volumeRepo, _, rmqPublisher := createTestManager()
+	manager.gameRepo.(*mockGameRepo).games[1] = &manman.Game{GameID: 1, Name: "Minecraft"}
+	sgcRepo.sgcs[1] = &manman.ServerGameConfig{SGCID: 1, GameConfigID: 1, ServerID: 7}
+	volumeRepo.volumes[1] = []*manman.GameConfigVolume{{ContainerPath: "/data"}}
+
+	modsPath := "mods"
+	addonRepo.addons[1] = &manman.WorkshopAddon{
+		AddonID:          1,
+		GameID:           1,
+		WorkshopID:       "sodium",
+		PlatformType:     manman.PlatformTypeModrinth,
+		InstallationPath: &modsPath,
+		Metadata:         map[string]interface{}{"loader": "fabric"},
+	}
+	source := &fakeAddonSource{download: &addonsource.Download{
+		URL:               "https://cdn.example/sodium.jar",
+		FileName:          "sodium.jar",
+		ChecksumAlgorithm: addonsource.ChecksumSHA512,
+		Checksum:          "cc",
+	}}
+	manager.RegisterSource(manman.PlatformTypeModrinth, source)
+
+	if _, err := manager.InstallAddon(ctx, 1, 1, false, false, "", 0, 0); err != nil {
+		t.Fatalf("InstallAddon failed: %v", err)
+	}
+	if len(rmqPublisher.publishedCommands) != 1 {
+		t.Fatalf("expected 1 download command, got %d", len(rmqPublisher.publishedCommands))
+	}
+	cmd := rmqPublisher.publishedCommands[0]
+	if cmd.PlatformType != manman.PlatformTypeModrinth || cmd.DownloadURL != "https://cdn.example/sodium.jar" ||
+		cmd.FileName != "sodium.jar" || cmd.ChecksumAlgorithm != "sha512" || cmd.Checksum != "cc" {
+		t.Errorf("unexpected download command: %+v", cmd)
+	}
+	if cmd.InstallPath != "/data/mods" {
+		t.Errorf("expected install path /data/mods, got %s", cmd.InstallPath)
+	}
+	if source.gotOpts["loader"] != "fabric" {
+		t.Errorf("expected the addon's metadata as options, got %v", source.gotOpts)
+	}
+
+	// Addons on a platform without a source can't be installed
+	addonRepo.addons[2] = &manman.WorkshopAddon{AddonID: 2, GameID: 1, WorkshopID: "238222",
+		PlatformType: manman.PlatformTypeCurseForge, InstallationPath: &modsPath}
+	if _, err := manager.InstallAddon(ctx, 1, 2, false, false, "", 0, 0); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("expected ErrUnsupportedPlatform, got %v", err)
+	}
+
+	// Nor can files the host has no checksum to verify
+	source.download = &addonsource.Download{URL: "https://cdn.example/sodium.jar", FileName: "sodium.jar"}
+	if _, err := manager.ResolveDownload(ctx, addonRepo.addons[1]); err == nil || !strings.Contains(err.Error(), "no checksum") {
+		t.Errorf("expected a missing checksum to be refused, got %v", err)
+	}
+}
+
+func TestFetchMetadata_Source(t *testing.T) {
+	ctx := context.Background()
+	manager, _, _, _, _, _, _, _ := createTestManager()
+	updated := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	manager.RegisterSource(manman.PlatformTypeURL, &fakeAddonSource{metadata: &addonsource.Metadata{
+		ID:          "https://example.com/mod.zip",
+		Title:       "mod.zip",
+		FileSize:    2048,
+		TimeUpdated: updated,
+		Extra:       map[string]interface{}{"sha256": "abc"},
+	}})
+
+	addon, err := manager.FetchMetadata(ctx, 3, manman.PlatformTypeURL, "https://example.com/mod.zip")
+	if err != nil {
+		t.Fatalf("FetchMetadata failed: %v", err)
+	}
+	if addon.GameID != 3 || addon.PlatformType != manman.PlatformTypeURL || addon.Name != "mod.zip" {
+		t.Errorf("unexpected addon: %+v", addon)
+	}
+	if addon.FileSizeBytes == nil || *addon.FileSizeBytes != 2048 || addon.LastUpdated == nil || !addon.LastUpdated.Equal(updated) {
+		t.Errorf("expected size and update time from the source, got %+v", addon)
+	}
+	if addon.Description != nil {
+		t.Errorf("expected no description, got %q", *addon.Description)
+	}
+	if addon.Metadata["sha256"] != "abc" {
+		t.Errorf("expected source metadata to be kept, got %v", addon.Metadata)
+	}
+
+	if _, err := manager.FetchMetadata(ctx, 3, manman.PlatformTypeModrinth, "sodium"); !errors.Is(err, ErrUnsupportedPlatform) {
+		t.Errorf("expected ErrUnsupportedPlatform, got %v", err)
+	}
+}
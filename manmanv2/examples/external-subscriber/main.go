@@ -124,6 +124,8 @@ func (s *ExternalEventSubscriber) handleEvent(ctx context.Context, msg rmq.Messa
 	switch {
 	case matchesPattern(msg.RoutingKey, "manman.host.*"):
 		return s.handleHostEvent(msg.RoutingKey, msg.Body)
+	case msg.RoutingKey == "manman.session.restart_abandoned":
+		return s.handleRestartAbandoned(msg.Body)
 	case matchesPattern(msg.RoutingKey, "manman.session.*"):
 		return s.handleSessionEvent(msg.RoutingKey, msg.Body)
 	default:
@@ -183,6 +185,8 @@ func (s *ExternalEventSubscriber) handleSessionEvent(routingKey string, body []b
 	case "running":
 		s.logger.Info("🎮 Session started", "session_id", event.SessionID)
 		// sendSlackNotification(fmt.Sprintf("Game session %d started", event.SessionID))
+	case "ready":
+		s.logger.Info("✅ Session ready for players", "session_id", event.SessionID)
 	case "stopped":
 		s.logger.Info("🛑 Session stopped", "session_id", event.SessionID, "exit_code", event.ExitCode)
 		// sendSlackNotification(fmt.Sprintf("Session %d stopped (exit: %v)", event.SessionID, event.ExitCode))
@@ -197,6 +201,25 @@ func (s *ExternalEventSubscriber) handleSessionEvent(routingKey string, body []b
 	return nil
 }
 
+// handleRestartAbandoned processes a host giving up automatically restarting a session
+func (s *ExternalEventSubscriber) handleRestartAbandoned(body []byte) error {
+	var event hostrmq.RestartAbandonedUpdate
+	if err := json.Unmarshal(body, &event); err != nil {
+		s.logger.Error("failed to unmarshal restart event", "error", err)
+		return nil
+	}
+
+	s.logger.Warn("🚫 Gave up restarting session",
+		"session_id", event.SessionID,
+		"sgc_id", event.SGCID,
+		"attempt", event.Attempt,
+		"reason", event.Reason,
+	)
+	// sendSlackAlert(fmt.Sprintf("Stopped restarting SGC %d: %s", event.SGCID, event.Reason))
+
+	return nil
+}
+
 // matchesPattern checks if a routing key matches a pattern (simple implementation)
 func matchesPattern(routingKey, pattern string) bool {
 	if pattern == "#" {
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log/slog"
@@ -83,7 +85,8 @@ func (h *CommandHandlerImpl) HandleBackup(ctx context.Context, cmd *hostrmq.Back
 	defer os.Remove(tmpFile.Name())
 	defer tmpFile.Close()
 
-	if _, err := io.Copy(tmpFile, tarReader); err != nil {
+	hasher := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmpFile, hasher), tarReader); err != nil {
 		_ = tarCmd.Process.Kill()
 		return fail(fmt.Errorf("failed to buffer tar output: %w", err))
 	}
@@ -125,12 +128,15 @@ func (h *CommandHandlerImpl) HandleBackup(ctx context.Context, cmd *hostrmq.Back
 	}
 
 	s3URL := fmt.Sprintf("s3://%s", cmd.S3Key)
-	slog.Info("backup completed", "backup_id", cmd.BackupID, "s3_url", s3URL)
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+	slog.Info("backup completed", "backup_id", cmd.BackupID, "s3_url", s3URL, "sha256", checksum)
 
 	return h.publisher.PublishBackupStatus(ctx, &hostrmq.BackupStatusUpdate{
-		BackupID: cmd.BackupID,
-		S3URL:    &s3URL,
-		Status:   manman.BackupStatusCompleted,
+		BackupID:  cmd.BackupID,
+		S3URL:     &s3URL,
+		SizeBytes: &size,
+		Checksum:  &checksum,
+		Status:    manman.BackupStatusCompleted,
 	})
 }
 
@@ -141,15 +147,10 @@ func (h *CommandHandlerImpl) HandleBackup(ctx context.Context, cmd *hostrmq.Back
 func (h *CommandHandlerImpl) resolveBackupSourceDir(ctx context.Context, cmd *hostrmq.BackupCommand) (tarPath string, cleanup func(), err error) {
 	if cmd.VolumeType != "named" {
 		// Bind-mount: derive the internal path from host_subpath.
-		subPath := strings.TrimPrefix(cmd.VolumeHostPath, "/")
-		if subPath == "" {
+		if strings.TrimPrefix(cmd.VolumeHostPath, "/") == "" {
 			return "", nil, fmt.Errorf("volume_host_path is empty for bind-mount backup %d", cmd.BackupID)
 		}
-		dirName := fmt.Sprintf("sgc-%d", cmd.SGCID)
-		if h.environment != "" {
-			dirName = fmt.Sprintf("sgc-%s-%d", h.environment, cmd.SGCID)
-		}
-		return filepath.Join(h.internalDataDir, dirName, subPath), nil, nil
+		return h.bindVolumeDir(cmd.SGCID, cmd.VolumeHostPath), nil, nil
 	}
 
 	// Named volume: Docker manages the storage location, so direct host filesystem access is
@@ -188,6 +189,16 @@ func (h *CommandHandlerImpl) resolveBackupSourceDir(ctx context.Context, cmd *ho
 	return stagingInternal, cleanup, nil
 }
 
+// bindVolumeDir returns the internal path of a bind-mount volume, i.e. the directory this
+// process sees for internalDataDir/sgc-[env-]id/<host_subpath>.
+func (h *CommandHandlerImpl) bindVolumeDir(sgcID int64, volumeHostPath string) string {
+	dirName := fmt.Sprintf("sgc-%d", sgcID)
+	if h.environment != "" {
+		dirName = fmt.Sprintf("sgc-%s-%d", h.environment, sgcID)
+	}
+	return filepath.Join(h.internalDataDir, dirName, strings.TrimPrefix(volumeHostPath, "/"))
+}
+
 // getNamedVolumeName mirrors the naming convention in the session manager and workshop orchestrator.
 func (h *CommandHandlerImpl) getNamedVolumeName(sgcID int64, volumeName string) string {
 	if h.environment != "" {
@@ -1,13 +1,16 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 
 	pb "github.com/whale-net/everything/manmanv2/protos"
+	"gopkg.in/yaml.v3"
 )
 
 // Renderer handles configuration strategy rendering
@@ -41,50 +44,82 @@ type RenderedFile struct {
 	HostPath string // Absolute path on host where file should be written
 }
 
-// RenderConfigurations renders all configuration strategies from API response
+// RenderResult holds everything rendered for a session. Files are written to disk;
+// Env and Args are merged into the game container spec.
+type RenderResult struct {
+	Files []*RenderedFile
+	Env   map[string]string // from env_vars strategies; later strategies win
+	Args  []string          // from cli_args strategies, in strategy order
+}
+
+// RenderConfigurations renders all configuration strategies from API response and
+// returns the files to write. Use Render to also get env vars and CLI args.
 func (r *Renderer) RenderConfigurations(configurations []*pb.RenderedConfiguration, baseDataDir string) ([]*RenderedFile, error) {
+	result, err := r.Render(configurations, baseDataDir)
+	if err != nil {
+		return nil, err
+	}
+	return result.Files, nil
+}
+
+// Render renders all configuration strategies from API response
+func (r *Renderer) Render(configurations []*pb.RenderedConfiguration, baseDataDir string) (*RenderResult, error) {
 	r.logger.Info("starting configuration rendering", "count", len(configurations))
 
+	result := &RenderResult{Env: make(map[string]string)}
 	if len(configurations) == 0 {
 		r.logger.Debug("no configurations to render")
-		return nil, nil
+		return result, nil
 	}
 
-	var renderedFiles []*RenderedFile
-
 	for _, config := range configurations {
 		r.logger.Info("processing configuration", "strategy_name", config.StrategyName, "strategy_type", config.StrategyType)
 
 		// Render based on strategy type
+		var file *RenderedFile
+		var err error
 		switch config.StrategyType {
 		case "file_properties":
-			file, err := r.renderPropertiesFileFromConfig(config, baseDataDir)
+			file, err = r.renderPropertiesFileFromConfig(config, baseDataDir)
 			if err != nil {
 				return nil, fmt.Errorf("failed to render properties file for %s: %w", config.StrategyName, err)
 			}
-			if file != nil {
-				renderedFiles = append(renderedFiles, file)
-			}
 
 		case "env_vars":
-			r.logger.Warn("env vars rendering not yet implemented", "strategy_name", config.StrategyName)
+			env := renderEnvVars(config)
+			for key, value := range env {
+				result.Env[key] = value
+			}
+			r.logger.Debug("rendered env vars", "strategy_name", config.StrategyName, "count", len(env))
 
 		case "cli_args":
-			r.logger.Warn("CLI args rendering not yet implemented", "strategy_name", config.StrategyName)
+			args := renderCLIArgs(config)
+			result.Args = append(result.Args, args...)
+			r.logger.Debug("rendered CLI args", "strategy_name", config.StrategyName, "count", len(args))
 
 		case "file_json":
-			r.logger.Warn("JSON file rendering not yet implemented", "strategy_name", config.StrategyName)
+			file, err = r.renderJSONFileFromConfig(config, baseDataDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render JSON file for %s: %w", config.StrategyName, err)
+			}
 
 		case "file_yaml":
-			r.logger.Warn("YAML file rendering not yet implemented", "strategy_name", config.StrategyName)
+			file, err = r.renderYAMLFileFromConfig(config, baseDataDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to render YAML file for %s: %w", config.StrategyName, err)
+			}
 
 		default:
 			r.logger.Warn("unknown strategy type", "strategy_type", config.StrategyType, "strategy_name", config.StrategyName)
 		}
+
+		if file != nil {
+			result.Files = append(result.Files, file)
+		}
 	}
 
-	r.logger.Info("rendered configuration files", "count", len(renderedFiles))
-	return renderedFiles, nil
+	r.logger.Info("rendered configurations", "files", len(result.Files), "env_vars", len(result.Env), "cli_args", len(result.Args))
+	return result, nil
 }
 
 // WriteRenderedFiles writes all rendered files to disk
@@ -111,38 +146,19 @@ func (r *Renderer) WriteRenderedFiles(files []*RenderedFile) error {
 
 // renderPropertiesFileFromConfig renders a Java properties file from API configuration
 func (r *Renderer) renderPropertiesFileFromConfig(config *pb.RenderedConfiguration, baseDataDir string) (*RenderedFile, error) {
-	// Determine host path
-	if config.TargetPath == "" {
-		return nil, fmt.Errorf("no target path specified for configuration %s", config.StrategyName)
+	hostPath, err := targetHostPath(config, baseDataDir)
+	if err != nil {
+		return nil, err
 	}
 
-	// Map container path to host path: /data/foo -> {BaseDataDir}/data/foo
-	relativePath := strings.TrimPrefix(config.TargetPath, "/")
-	hostPath := filepath.Join(baseDataDir, relativePath)
-
-	var properties map[string]string
-
 	// Two modes:
 	// 1. base_template provided (BaseContent non-empty): Use it as starting point
 	// 2. base_template empty: Read existing file and merge (for auto-generating games)
-	if config.BaseContent != "" {
-		r.logger.Debug("using base template", "strategy_name", config.StrategyName)
-		properties = parsePropertiesFile(config.BaseContent)
-	} else {
-		r.logger.Debug("base template empty, checking for existing file", "path", hostPath)
-		existingContent, err := os.ReadFile(hostPath)
-		if err != nil {
-			if os.IsNotExist(err) {
-				r.logger.Debug("no existing file found, starting with empty properties")
-				properties = make(map[string]string)
-			} else {
-				return nil, fmt.Errorf("failed to read existing file %s: %w", hostPath, err)
-			}
-		} else {
-			r.logger.Debug("read existing file, merging changes", "bytes", len(existingContent))
-			properties = parsePropertiesFile(string(existingContent))
-		}
+	baseContent, err := r.readBaseContent(config, hostPath)
+	if err != nil {
+		return nil, err
 	}
+	properties := parsePropertiesFile(baseContent)
 
 	if config.RenderedContent != "" && config.RenderedContent != config.BaseContent {
 		r.logger.Debug("applying overrides from rendered content")
@@ -162,6 +178,37 @@ func (r *Renderer) renderPropertiesFileFromConfig(config *pb.RenderedConfigurati
 	}, nil
 }
 
+// targetHostPath maps a strategy's container target path to the host path:
+// /data/foo -> {BaseDataDir}/data/foo
+func targetHostPath(config *pb.RenderedConfiguration, baseDataDir string) (string, error) {
+	if config.TargetPath == "" {
+		return "", fmt.Errorf("no target path specified for configuration %s", config.StrategyName)
+	}
+	relativePath := strings.TrimPrefix(config.TargetPath, "/")
+	return filepath.Join(baseDataDir, relativePath), nil
+}
+
+// readBaseContent returns the content a structured file is rendered on top of: the
+// strategy's base template when set, otherwise the existing file on disk (merge mode).
+// An empty string means there is nothing to start from.
+func (r *Renderer) readBaseContent(config *pb.RenderedConfiguration, hostPath string) (string, error) {
+	if config.BaseContent != "" {
+		r.logger.Debug("using base template", "strategy_name", config.StrategyName)
+		return config.BaseContent, nil
+	}
+	r.logger.Debug("base template empty, checking for existing file", "path", hostPath)
+	existingContent, err := os.ReadFile(hostPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			r.logger.Debug("no existing file found, starting empty")
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read existing file %s: %w", hostPath, err)
+	}
+	r.logger.Debug("read existing file, merging changes", "bytes", len(existingContent))
+	return string(existingContent), nil
+}
+
 // parsePropertiesFile parses a Java properties file into a map
 func parsePropertiesFile(content string) map[string]string {
 	properties := make(map[string]string)
@@ -215,3 +262,258 @@ func renderPropertiesMap(properties map[string]string) string {
 
 	return strings.Join(lines, "\n")
 }
+
+// renderJSONFileFromConfig renders a JSON file from API configuration. Overrides are
+// deep-merged into the base document (a null override removes the key) and the result
+// is written with keys sorted so output is stable between renders.
+func (r *Renderer) renderJSONFileFromConfig(config *pb.RenderedConfiguration, baseDataDir string) (*RenderedFile, error) {
+	hostPath, err := targetHostPath(config, baseDataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	baseContent, err := r.readBaseContent(config, hostPath)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := parseJSONDocuments(baseContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base JSON: %w", err)
+	}
+	doc := mergeDocuments(nil, docs)
+
+	if config.RenderedContent != "" && config.RenderedContent != config.BaseContent {
+		r.logger.Debug("applying overrides from rendered content")
+		overrides, err := parseJSONDocuments(config.RenderedContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JSON overrides: %w", err)
+		}
+		doc = mergeDocuments(doc, overrides)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	// encoding/json sorts map keys
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return &RenderedFile{
+		Path:     config.TargetPath,
+		Content:  string(out) + "\n",
+		HostPath: hostPath,
+	}, nil
+}
+
+// renderYAMLFileFromConfig renders a YAML file from API configuration using the same
+// merge rules as renderJSONFileFromConfig.
+func (r *Renderer) renderYAMLFileFromConfig(config *pb.RenderedConfiguration, baseDataDir string) (*RenderedFile, error) {
+	hostPath, err := targetHostPath(config, baseDataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	baseContent, err := r.readBaseContent(config, hostPath)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := parseYAMLDocuments(baseContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse base YAML: %w", err)
+	}
+	doc := mergeDocuments(nil, docs)
+
+	if config.RenderedContent != "" && config.RenderedContent != config.BaseContent {
+		r.logger.Debug("applying overrides from rendered content")
+		overrides, err := parseYAMLDocuments(config.RenderedContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse YAML overrides: %w", err)
+		}
+		doc = mergeDocuments(doc, overrides)
+	}
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	// yaml.v3 sorts map keys
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode YAML: %w", err)
+	}
+
+	return &RenderedFile{
+		Path:     config.TargetPath,
+		Content:  string(out),
+		HostPath: hostPath,
+	}, nil
+}
+
+// parseJSONDocuments decodes a stream of JSON documents. Rendered content is built by
+// concatenating one patch per line, so several documents are expected. Numbers are kept
+// as json.Number so large integers survive the round trip unchanged.
+func parseJSONDocuments(content string) ([]interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var docs []interface{}
+	for {
+		var doc interface{}
+		if err := dec.Decode(&doc); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// parseYAMLDocuments decodes a stream of YAML documents.
+func parseYAMLDocuments(content string) ([]interface{}, error) {
+	dec := yaml.NewDecoder(strings.NewReader(content))
+	var docs []interface{}
+	for {
+		var doc interface{}
+		if err := dec.Decode(&doc); err == io.EOF {
+			return docs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if doc != nil {
+			docs = append(docs, doc)
+		}
+	}
+}
+
+// mergeDocuments applies each document on top of base in order.
+func mergeDocuments(base interface{}, docs []interface{}) interface{} {
+	for _, doc := range docs {
+		base = mergeValue(base, doc)
+	}
+	return base
+}
+
+// mergeValue merges override into base following JSON merge patch semantics: objects
+// merge key by key, a null value deletes the key, and anything else replaces base.
+func mergeValue(base, override interface{}) interface{} {
+	overrideMap, ok := override.(map[string]interface{})
+	if !ok {
+		return override
+	}
+	baseMap, ok := base.(map[string]interface{})
+	if !ok {
+		baseMap = make(map[string]interface{}, len(overrideMap))
+	}
+	for key, value := range overrideMap {
+		if value == nil {
+			delete(baseMap, key)
+			continue
+		}
+		baseMap[key] = mergeValue(baseMap[key], value)
+	}
+	return baseMap
+}
+
+// renderEnvVars parses KEY=VALUE lines from the base and rendered content, with rendered
+// values overriding base values. Blank lines, comments, an optional "export " prefix and
+// surrounding quotes are handled the way a .env file would be.
+func renderEnvVars(config *pb.RenderedConfiguration) map[string]string {
+	env := parseEnvFile(config.BaseContent)
+	if config.RenderedContent != config.BaseContent {
+		for key, value := range parseEnvFile(config.RenderedContent) {
+			env[key] = value
+		}
+	}
+	return env
+}
+
+// parseEnvFile parses .env style content into a map
+func parseEnvFile(content string) map[string]string {
+	env := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+
+		key, value, found := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[key] = value
+	}
+	return env
+}
+
+// renderCLIArgs parses one argument per line from the base and rendered content. Each line
+// is a flag optionally followed by its value ("-maxplayers 16"). Flags such as +exec can be
+// repeated, so every line is kept in order; a flag the rendered content sets has all of its
+// base lines replaced by the rendered ones, where it first appeared, and new flags are
+// appended.
+func renderCLIArgs(config *pb.RenderedConfiguration) []string {
+	base := parseCLIArgLines(config.BaseContent)
+	var overrides [][]string
+	if config.RenderedContent != config.BaseContent {
+		overrides = parseCLIArgLines(config.RenderedContent)
+	}
+
+	var order []string
+	byFlag := make(map[string][][]string)
+	for _, arg := range overrides {
+		if _, seen := byFlag[arg[0]]; !seen {
+			order = append(order, arg[0])
+		}
+		byFlag[arg[0]] = append(byFlag[arg[0]], arg)
+	}
+
+	var args []string
+	placed := make(map[string]bool)
+	for _, arg := range base {
+		override, ok := byFlag[arg[0]]
+		if !ok {
+			args = append(args, arg...)
+			continue
+		}
+		if !placed[arg[0]] {
+			placed[arg[0]] = true
+			for _, o := range override {
+				args = append(args, o...)
+			}
+		}
+	}
+	for _, flag := range order {
+		if placed[flag] {
+			continue
+		}
+		for _, o := range byFlag[flag] {
+			args = append(args, o...)
+		}
+	}
+	return args
+}
+
+// parseCLIArgLines splits cli_args content into one argument per non-comment line: the flag,
+// then its value if it has one.
+func parseCLIArgLines(content string) [][]string {
+	var lines [][]string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		flag, value, _ := strings.Cut(line, " ")
+		arg := []string{flag}
+		if value = strings.TrimSpace(value); value != "" {
+			arg = append(arg, value)
+		}
+		lines = append(lines, arg)
+	}
+	return lines
+}
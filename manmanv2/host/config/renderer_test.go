@@ -200,14 +200,19 @@ func TestRenderConfigurationsMultipleFiles(t *testing.T) {
 		t.Fatalf("Failed to render configurations: %v", err)
 	}
 
-	// Should only render the properties file (JSON not implemented yet)
-	if len(files) != 1 {
-		t.Fatalf("Expected 1 file (only properties), got %d", len(files))
+	if len(files) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(files))
 	}
 
 	if files[0].HostPath != filepath.Join(baseDataDir, "data/server.properties") {
 		t.Errorf("Incorrect host path for server.properties")
 	}
+	if files[1].HostPath != filepath.Join(baseDataDir, "data/whitelist.json") {
+		t.Errorf("Incorrect host path for whitelist.json")
+	}
+	if files[1].Content != "[]\n" {
+		t.Errorf("Expected empty JSON array for whitelist.json, got %q", files[1].Content)
+	}
 }
 
 func TestMergeModeEmptyBaseTemplate(t *testing.T) {
@@ -567,3 +572,205 @@ motd=Custom Server`
 	t.Logf("   Final: max-players=%s (patch_order=1 wins), difficulty=%s (patch_order=0 survives)",
 		properties["max-players"], properties["difficulty"])
 }
+
+func TestRenderEnvVars(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	configs := []*pb.RenderedConfiguration{
+		{
+			StrategyName:    "Base Env",
+			StrategyType:    "env_vars",
+			BaseContent:     "# defaults\nMAX_PLAYERS=10\nexport SERVER_NAME=\"My Server\"\nEULA=TRUE",
+			RenderedContent: "# defaults\nMAX_PLAYERS=10\nexport SERVER_NAME=\"My Server\"\nEULA=TRUE\nMAX_PLAYERS=16",
+		},
+		{
+			StrategyName:    "SGC Env",
+			StrategyType:    "env_vars",
+			RenderedContent: "SERVER_NAME='Whale Net'\nnot a var",
+		},
+	}
+
+	result, err := renderer.Render(configs, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to render configurations: %v", err)
+	}
+	if len(result.Files) != 0 {
+		t.Errorf("Expected env_vars to produce no files, got %d", len(result.Files))
+	}
+
+	expected := map[string]string{
+		"MAX_PLAYERS": "16",
+		"SERVER_NAME": "Whale Net",
+		"EULA":        "TRUE",
+	}
+	if len(result.Env) != len(expected) {
+		t.Errorf("Expected %d env vars, got %d: %v", len(expected), len(result.Env), result.Env)
+	}
+	for key, want := range expected {
+		if got := result.Env[key]; got != want {
+			t.Errorf("Env %s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestRenderCLIArgs(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	configs := []*pb.RenderedConfiguration{
+		{
+			StrategyName:    "Launch Args",
+			StrategyType:    "cli_args",
+			BaseContent:     "-dedicated\n+map de_dust2\n+exec server.cfg\n+exec gamemode.cfg\n-maxplayers 10\n+exec autoexec.cfg",
+			RenderedContent: "+map de_inferno\n-maxplayers 16\n+sv_password hunter 2",
+		},
+	}
+
+	result, err := renderer.Render(configs, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to render configurations: %v", err)
+	}
+
+	// Overridden flags keep their base position, repeated base flags are all kept and new
+	// flags are appended
+	expected := []string{"-dedicated", "+map", "de_inferno", "+exec", "server.cfg", "+exec", "gamemode.cfg", "-maxplayers", "16", "+exec", "autoexec.cfg", "+sv_password", "hunter 2"}
+	if strings.Join(result.Args, "|") != strings.Join(expected, "|") {
+		t.Errorf("Args = %q, want %q", result.Args, expected)
+	}
+}
+
+func TestRenderCLIArgsRepeatedOverride(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	configs := []*pb.RenderedConfiguration{
+		{
+			StrategyName:    "Launch Args",
+			StrategyType:    "cli_args",
+			BaseContent:     "-dedicated\n+exec server.cfg\n-maxplayers 10\n+exec autoexec.cfg",
+			RenderedContent: "+exec practice.cfg\n+exec bots.cfg",
+		},
+	}
+
+	result, err := renderer.Render(configs, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to render configurations: %v", err)
+	}
+
+	// Every base +exec is replaced by the rendered ones, where +exec first appeared
+	expected := []string{"-dedicated", "+exec", "practice.cfg", "+exec", "bots.cfg", "-maxplayers", "10"}
+	if strings.Join(result.Args, "|") != strings.Join(expected, "|") {
+		t.Errorf("Args = %q, want %q", result.Args, expected)
+	}
+}
+
+func TestRenderJSONFile(t *testing.T) {
+	renderer := NewRenderer(nil)
+	baseDataDir := t.TempDir()
+
+	config := &pb.RenderedConfiguration{
+		StrategyName: "Server JSON",
+		StrategyType: "file_json",
+		TargetPath:   "/config/server.json",
+		BaseContent:  `{"name":"base","settings":{"pvp":true,"difficulty":"normal"},"motd":"hello","seed":12345678901234567890}`,
+		// One patch per line, applied in order; null removes a key
+		RenderedContent: "{\"settings\":{\"difficulty\":\"hard\"}}\n{\"motd\":null,\"admins\":[\"alice\"]}",
+	}
+
+	files, err := renderer.RenderConfigurations([]*pb.RenderedConfiguration{config}, baseDataDir)
+	if err != nil {
+		t.Fatalf("Failed to render configurations: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+
+	expected := `{
+  "admins": [
+    "alice"
+  ],
+  "name": "base",
+  "seed": 12345678901234567890,
+  "settings": {
+    "difficulty": "hard",
+    "pvp": true
+  }
+}
+`
+	if files[0].Content != expected {
+		t.Errorf("Rendered JSON mismatch.\nGot:\n%s\nWant:\n%s", files[0].Content, expected)
+	}
+	if want := filepath.Join(baseDataDir, "config/server.json"); files[0].HostPath != want {
+		t.Errorf("HostPath = %s, want %s", files[0].HostPath, want)
+	}
+}
+
+func TestRenderJSONFileMergesExistingFile(t *testing.T) {
+	renderer := NewRenderer(nil)
+	baseDataDir := t.TempDir()
+
+	hostPath := filepath.Join(baseDataDir, "data", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(hostPath), 0755); err != nil {
+		t.Fatalf("Failed to create dir: %v", err)
+	}
+	if err := os.WriteFile(hostPath, []byte(`{"generated":"by game","port":7777}`), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	config := &pb.RenderedConfiguration{
+		StrategyName:    "Settings",
+		StrategyType:    "file_json",
+		TargetPath:      "/data/settings.json",
+		RenderedContent: `{"port":7778}`,
+	}
+
+	files, err := renderer.RenderConfigurations([]*pb.RenderedConfiguration{config}, baseDataDir)
+	if err != nil {
+		t.Fatalf("Failed to render configurations: %v", err)
+	}
+
+	expected := "{\n  \"generated\": \"by game\",\n  \"port\": 7778\n}\n"
+	if files[0].Content != expected {
+		t.Errorf("Rendered JSON mismatch.\nGot:\n%s\nWant:\n%s", files[0].Content, expected)
+	}
+}
+
+func TestRenderJSONFileInvalidContent(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	config := &pb.RenderedConfiguration{
+		StrategyName:    "Broken",
+		StrategyType:    "file_json",
+		TargetPath:      "/data/broken.json",
+		BaseContent:     `{}`,
+		RenderedContent: `{"unterminated":`,
+	}
+
+	if _, err := renderer.RenderConfigurations([]*pb.RenderedConfiguration{config}, t.TempDir()); err == nil {
+		t.Error("Expected error for invalid JSON overrides")
+	}
+}
+
+func TestRenderYAMLFile(t *testing.T) {
+	renderer := NewRenderer(nil)
+
+	config := &pb.RenderedConfiguration{
+		StrategyName:    "Server YAML",
+		StrategyType:    "file_yaml",
+		TargetPath:      "/config/server.yaml",
+		BaseContent:     "server:\n  port: 25565\n  name: base\nworld: overworld\nplugins:\n  - essentials\n",
+		RenderedContent: "server:\n  name: Whale Net\n---\nworld: null\nplugins:\n  - worldedit\n",
+	}
+
+	files, err := renderer.RenderConfigurations([]*pb.RenderedConfiguration{config}, t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to render configurations: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(files))
+	}
+
+	// Keys are sorted and lists are replaced, not appended
+	expected := "plugins:\n    - worldedit\nserver:\n    name: Whale Net\n    port: 25565\n"
+	if files[0].Content != expected {
+		t.Errorf("Rendered YAML mismatch.\nGot:\n%s\nWant:\n%s", files[0].Content, expected)
+	}
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/docker"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+// imageManager keeps the images of this host's active SGCs pulled ahead of time, so a
+// session start's pull has little left to fetch and its cached fallback is recent when the
+// registry is down. Each
+// refresh reports the digest every SGC runs next to the registry's current digest, then
+// prunes game images no game config references any more.
+type imageManager struct {
+	dockerClient *docker.Client
+	apiClient    pb.ManManAPIClient
+	publisher    *rmq.Publisher
+	serverID     int64
+	environment  string
+	logger       *slog.Logger
+}
+
+// run refreshes immediately and then every interval until ctx is done
+func (m *imageManager) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := m.refresh(ctx); err != nil {
+			m.logger.Warn("image refresh failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *imageManager) refresh(ctx context.Context) error {
+	gameConfigs, err := m.listGameConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	sgcs, err := m.listSGCs(ctx)
+	if err != nil {
+		return err
+	}
+
+	running, err := m.runningImages(ctx)
+	if err != nil {
+		return err
+	}
+
+	report := &rmq.ImageStatusReport{CheckedAt: time.Now()}
+	pulled := make(map[string]bool)
+	for _, sgc := range sgcs {
+		if sgc.Status != manman.SGCStatusActive {
+			continue
+		}
+		gc, ok := gameConfigs[sgc.GameConfigId]
+		if !ok || gc.Image == "" {
+			continue
+		}
+		status := rmq.SGCImageStatus{SGCID: sgc.ServerGameConfigId, Image: gc.Image}
+
+		remote, err := m.dockerClient.RemoteImageDigest(ctx, gc.Image)
+		if err != nil {
+			status.Error = err.Error()
+		}
+		status.RemoteDigest = remote
+
+		cachedDigest, err := m.dockerClient.LocalImageDigest(ctx, gc.Image)
+		if err != nil {
+			status.Error = err.Error()
+		}
+		// Pull once per image per refresh, and only when the registry has something new
+		if !pulled[gc.Image] && (cachedDigest == "" || (remote != "" && remote != cachedDigest)) {
+			pulled[gc.Image] = true
+			m.logger.Info("pre-pulling image", "image", gc.Image, "sgc_id", sgc.ServerGameConfigId)
+			if _, err := m.dockerClient.EnsureImage(ctx, gc.Image, docker.PullAlways, 3); err != nil {
+				status.Error = err.Error()
+			} else if cachedDigest, err = m.dockerClient.LocalImageDigest(ctx, gc.Image); err != nil {
+				status.Error = err.Error()
+			}
+		}
+
+		// What the SGC runs is its container's image; the cached copy is what it will run next
+		status.LocalDigest = cachedDigest
+		if imageID, ok := running[sgc.ServerGameConfigId]; ok {
+			if digest, err := m.dockerClient.ImageIDDigest(ctx, imageID, gc.Image); err == nil && digest != "" {
+				status.LocalDigest = digest
+			}
+		}
+		report.Images = append(report.Images, status)
+	}
+
+	if err := m.publisher.PublishImageStatus(ctx, report); err != nil {
+		m.logger.Warn("failed to publish image status", "error", err)
+	}
+
+	return m.prune(ctx, gameConfigs)
+}
+
+// prune removes local images of game config repositories that are no longer what any
+// game config's image points at. Images a container still uses, stopped or not, are
+// kept, as is anything outside those repositories.
+func (m *imageManager) prune(ctx context.Context, gameConfigs map[int64]*pb.GameConfig) error {
+	repositories := make(map[string]bool)
+	keep := make(map[string]bool)
+	for _, gc := range gameConfigs {
+		if gc.Image == "" {
+			continue
+		}
+		if repo := docker.ImageRepository(gc.Image); repo != "" {
+			repositories[repo] = true
+		}
+		id, err := m.dockerClient.ImageID(ctx, gc.Image)
+		if err != nil {
+			return err
+		}
+		if id != "" {
+			keep[id] = true
+		}
+	}
+
+	containers, err := m.dockerClient.ListContainers(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, c := range containers {
+		keep[c.ImageID] = true
+	}
+
+	images, err := m.dockerClient.ListImages(ctx)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		if keep[img.ID] || !inRepositories(img, repositories) {
+			continue
+		}
+		m.logger.Info("pruning unreferenced image", "image_id", img.ID, "tags", img.RepoTags)
+		if err := m.dockerClient.RemoveImage(ctx, img.ID); err != nil {
+			m.logger.Warn("failed to prune image", "image_id", img.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// runningImages maps the SGC of each of this host's game containers to its image ID
+func (m *imageManager) runningImages(ctx context.Context) (map[int64]string, error) {
+	filters := map[string]string{
+		"manman.type":      "game",
+		"manman.server_id": fmt.Sprintf("%d", m.serverID),
+	}
+	if m.environment != "" {
+		filters["manman.environment"] = m.environment
+	}
+	containers, err := m.dockerClient.ListContainers(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	images := make(map[int64]string, len(containers))
+	for _, c := range containers {
+		sgcID, err := strconv.ParseInt(c.Labels["manman.sgc_id"], 10, 64)
+		if err != nil {
+			continue
+		}
+		if _, seen := images[sgcID]; !seen || c.Running {
+			images[sgcID] = c.ImageID
+		}
+	}
+	return images, nil
+}
+
+func (m *imageManager) listSGCs(ctx context.Context) ([]*pb.ServerGameConfig, error) {
+	var sgcs []*pb.ServerGameConfig
+	pageToken := ""
+	for {
+		resp, err := m.apiClient.ListServerGameConfigs(ctx, &pb.ListServerGameConfigsRequest{
+			ServerId:  m.serverID,
+			PageSize:  100,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list server game configs: %w", err)
+		}
+		sgcs = append(sgcs, resp.Configs...)
+		if resp.NextPageToken == "" {
+			return sgcs, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+func (m *imageManager) listGameConfigs(ctx context.Context) (map[int64]*pb.GameConfig, error) {
+	configs := make(map[int64]*pb.GameConfig)
+	pageToken := ""
+	for {
+		resp, err := m.apiClient.ListGameConfigs(ctx, &pb.ListGameConfigsRequest{
+			PageSize:  100,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list game configs: %w", err)
+		}
+		for _, gc := range resp.Configs {
+			configs[gc.ConfigId] = gc
+		}
+		if resp.NextPageToken == "" {
+			return configs, nil
+		}
+		pageToken = resp.NextPageToken
+	}
+}
+
+func inRepositories(img docker.ImageInfo, repositories map[string]bool) bool {
+	for _, ref := range append(append([]string{}, img.RepoTags...), img.RepoDigests...) {
+		if repositories[docker.ImageRepository(ref)] {
+			return true
+		}
+	}
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -19,6 +20,7 @@ import (
 	"github.com/whale-net/everything/manmanv2/host/rmq"
 	"github.com/whale-net/everything/manmanv2/host/session"
 	"github.com/whale-net/everything/manmanv2/host/workshop"
+	"github.com/whale-net/everything/manmanv2/models"
 	pb "github.com/whale-net/everything/manmanv2/protos"
 	"google.golang.org/grpc"
 )
@@ -53,6 +55,30 @@ func run() error {
 	serverName := getEnv("SERVER_NAME", "")
 	environment := getEnv("ENVIRONMENT", "")
 
+	// Placement inputs: labels deployments can require or prefer, and the host ports
+	// automatic placement may hand out
+	labels, err := parseLabels(getEnv("SERVER_LABELS", ""))
+	if err != nil {
+		return fmt.Errorf("invalid SERVER_LABELS: %w", err)
+	}
+	portRanges, err := parsePortRanges(getEnv("HOST_PORT_RANGE", ""))
+	if err != nil {
+		return fmt.Errorf("invalid HOST_PORT_RANGE: %w", err)
+	}
+
+	// How often images of this host's SGCs are pre-pulled and checked for updates; 0 disables it
+	imageRefreshInterval, err := time.ParseDuration(getEnv("IMAGE_REFRESH_INTERVAL", "30m"))
+	if err != nil {
+		return fmt.Errorf("invalid IMAGE_REFRESH_INTERVAL: %w", err)
+	}
+
+	// How often game container resource usage and data directory disk usage are sampled
+	// and published with the health heartbeat; 0 disables it
+	metricsInterval, err := time.ParseDuration(getEnv("METRICS_INTERVAL", "30s"))
+	if err != nil {
+		return fmt.Errorf("invalid METRICS_INTERVAL: %w", err)
+	}
+
 	// HOST_DATA_DIR is the path on the host where session data is stored
 	// This container must have that path mounted at /var/lib/manman/sessions:
 	//   -v ${HOST_DATA_DIR}:/var/lib/manman/sessions
@@ -89,7 +115,7 @@ func run() error {
 
 	// Self-registration mode: Register with API and get server_id
 	logger.Info("starting host manager (self-registration mode)")
-	serverID, err := selfRegister(ctx, apiAddress, serverName, environment, dockerSocket, authOpt)
+	serverID, err := selfRegister(ctx, apiAddress, serverName, environment, labels, portRanges, dockerSocket, authOpt)
 	if err != nil {
 		return fmt.Errorf("failed to self-register: %w", err)
 	}
@@ -140,6 +166,19 @@ func run() error {
 	// Initialize session manager with gRPC client for configuration fetching and RMQ publisher for logs
 	sessionManager := session.NewSessionManager(dockerClient, environment, hostDataDir, grpcClient, downloadOrchestrator, rmqPublisher)
 
+	// Keep game images warm so starts only pull what changed since the last refresh
+	if imageRefreshInterval > 0 {
+		images := &imageManager{
+			dockerClient: dockerClient,
+			apiClient:    grpcClient,
+			publisher:    rmqPublisher,
+			serverID:     serverID,
+			environment:  environment,
+			logger:       logging.Get("images"),
+		}
+		go images.run(ctx, imageRefreshInterval)
+	}
+
 	// Recover orphaned sessions on startup
 	logger.Info("recovering orphaned sessions")
 	if err := sessionManager.RecoverOrphanedSessions(ctx, serverID); err != nil {
@@ -153,7 +192,7 @@ func run() error {
 
 	// Publish initial health with session stats
 	stats := sessionManager.GetSessionStats()
-	if err := rmqPublisher.PublishHealth(ctx, convertSessionStats(&stats)); err != nil {
+	if err := rmqPublisher.PublishHealth(ctx, convertSessionStats(&stats), nil); err != nil {
 		logger.Warn("failed to publish initial health", "error", err)
 	}
 
@@ -195,13 +234,26 @@ func run() error {
 			case <-healthTicker.C:
 				// Get current session statistics
 				stats := sessionManager.GetSessionStats()
-				if err := rmqPublisher.PublishHealth(ctx, convertSessionStats(&stats)); err != nil {
+				if err := rmqPublisher.PublishHealth(ctx, convertSessionStats(&stats), nil); err != nil {
 					logger.Warn("failed to publish health", "error", err)
 				}
 			}
 		}
 	}()
 
+	if metricsInterval > 0 {
+		sampler := &metricsSampler{
+			dockerClient:   dockerClient,
+			sessionManager: sessionManager,
+			publisher:      rmqPublisher,
+			serverID:       serverID,
+			environment:    environment,
+			dataDir:        session.InternalDataDir,
+			logger:         logging.Get("metrics"),
+		}
+		go sampler.run(ctx, metricsInterval)
+	}
+
 	// Start periodic orphan cleanup (every 5 minutes)
 	orphanCleanupTicker := time.NewTicker(5 * time.Minute)
 	defer orphanCleanupTicker.Stop()
@@ -298,15 +350,22 @@ func (h *CommandHandlerImpl) HandleStartSession(ctx context.Context, cmd *rmq.St
 	}
 
 	sessionCmd := &session.StartSessionCommand{
-		SessionID:    cmd.SessionID,
-		SGCID:        cmd.SGCID,
-		ServerID:     h.serverID,
-		Image:        cmd.GameConfig.Image,
-		Command:      command,
-		Env:          env,
-		PortBindings: ports,
-		Volumes:      volumes,
-		Force:        cmd.Force,
+		SessionID:      cmd.SessionID,
+		SGCID:          cmd.SGCID,
+		ServerID:       h.serverID,
+		Image:          cmd.GameConfig.Image,
+		Command:        command,
+		Env:            env,
+		PortBindings:   ports,
+		Volumes:        volumes,
+		Force:          cmd.Force,
+		Resources:      containerResources(cmd.ResourceLimits),
+		Readiness:      readinessProbe(cmd.GameConfig.ReadinessProbe),
+		Restart:        restartPolicy(cmd.RestartPolicy),
+		RestartAttempt: cmd.RestartAttempt,
+		PlayerCount:    playerCountProbe(cmd.PlayerCountProbe),
+		InputTransport: inputTransport(cmd.GameConfig.InputTransport),
+		StatusQuery:    statusQuery(cmd.GameConfig.StatusQuery),
 	}
 
 	// Publish starting status before attempting container creation
@@ -328,9 +387,19 @@ func (h *CommandHandlerImpl) HandleStartSession(ctx context.Context, cmd *rmq.St
 	}
 
 	slog.Info("publishing session status", "session_id", cmd.SessionID, "status", "running")
-	return h.publisher.PublishSessionStatus(ctx, &rmq.SessionStatusUpdate{
+	if err := h.publisher.PublishSessionStatus(ctx, &rmq.SessionStatusUpdate{
 		SessionID: cmd.SessionID, SGCID: cmd.SGCID, Status: "running",
-	})
+	}); err != nil {
+		return err
+	}
+
+	// ready is published once the game config's readiness probe passes
+	h.sessionManager.StartReadinessProbe(cmd.SessionID)
+	// player counts feed the SGC's idle shutdown rule
+	h.sessionManager.StartPlayerCount(cmd.SessionID)
+	// live player count and map from the game's status protocol
+	h.sessionManager.StartStatusQuery(cmd.SessionID)
+	return nil
 }
 
 // HandleStopSession handles a stop session command
@@ -415,16 +484,23 @@ func (h *CommandHandlerImpl) HandleDownloadAddon(ctx context.Context, cmd *rmq.D
 		"sgc_id", cmd.SGCID,
 		"addon_id", cmd.AddonID,
 		"workshop_id", cmd.WorkshopID,
-		"steam_app_id", cmd.SteamAppID)
+		"steam_app_id", cmd.SteamAppID,
+		"platform_type", cmd.PlatformType)
 
 	// Convert rmq.DownloadAddonCommand to workshop.DownloadAddonCommand
 	workshopCmd := &workshop.DownloadAddonCommand{
-		InstallationID: cmd.InstallationID,
-		SGCID:          cmd.SGCID,
-		AddonID:        cmd.AddonID,
-		WorkshopID:     cmd.WorkshopID,
-		SteamAppID:     cmd.SteamAppID,
-		InstallPath:    cmd.InstallPath,
+		InstallationID:    cmd.InstallationID,
+		SGCID:             cmd.SGCID,
+		AddonID:           cmd.AddonID,
+		WorkshopID:        cmd.WorkshopID,
+		SteamAppID:        cmd.SteamAppID,
+		InstallPath:       cmd.InstallPath,
+		PlatformType:      cmd.PlatformType,
+		DownloadURL:       cmd.DownloadURL,
+		FileName:          cmd.FileName,
+		ChecksumAlgorithm: cmd.ChecksumAlgorithm,
+		Checksum:          cmd.Checksum,
+		Extract:           cmd.Extract,
 	}
 
 	// Call download orchestrator in a goroutine to avoid blocking RabbitMQ consumer
@@ -490,7 +566,7 @@ func initializeGRPCClient(ctx context.Context, apiAddress string, authOpt grpc.D
 	return pb.NewManManAPIClient(conn), pb.NewWorkshopServiceClient(conn), nil
 }
 
-func selfRegister(ctx context.Context, apiAddress, serverName, environment, dockerSocket string, authOpt grpc.DialOption) (int64, error) {
+func selfRegister(ctx context.Context, apiAddress, serverName, environment string, labels map[string]string, portRanges []*pb.PortRange, dockerSocket string, authOpt grpc.DialOption) (int64, error) {
 	// Generate server name if not provided
 	if serverName == "" {
 		hostname, err := os.Hostname()
@@ -534,6 +610,7 @@ func selfRegister(ctx context.Context, apiAddress, serverName, environment, dock
 		CpuCores:               int32(info.NCPU),
 		AvailableCpuMillicores: int32(info.NCPU * 1000), // Assume all available initially
 		DockerVersion:          info.ServerVersion,
+		AvailablePorts:         portRanges,
 	}
 
 	// Call RegisterServer
@@ -541,6 +618,7 @@ func selfRegister(ctx context.Context, apiAddress, serverName, environment, dock
 		Name:         serverName,
 		Capabilities: capabilities,
 		Environment:  environment,
+		Labels:       labels,
 	}
 
 	resp, err := grpcClient.RegisterServer(ctx, req)
@@ -552,6 +630,52 @@ func selfRegister(ctx context.Context, apiAddress, serverName, environment, dock
 	return resp.ServerId, nil
 }
 
+// parseLabels parses "key=value,key2=value2"; a bare key gets an empty value
+func parseLabels(s string) (map[string]string, error) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if key == "" {
+			return nil, fmt.Errorf("label %q has no key", pair)
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// parsePortRanges parses "start-end" into the same range for TCP and UDP; empty means
+// no ranges are reported and placement falls back to the API's default
+func parsePortRanges(s string) ([]*pb.PortRange, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	startStr, endStr, ok := strings.Cut(s, "-")
+	if !ok {
+		return nil, fmt.Errorf("expected start-end, got %q", s)
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(startStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid start port: %w", err)
+	}
+	end, err := strconv.Atoi(strings.TrimSpace(endStr))
+	if err != nil {
+		return nil, fmt.Errorf("invalid end port: %w", err)
+	}
+	if start < 1 || end > 65535 || end < start {
+		return nil, fmt.Errorf("range %d-%d is not within 1-65535", start, end)
+	}
+	return []*pb.PortRange{
+		{Start: int32(start), End: int32(end), Protocol: "TCP"},
+		{Start: int32(start), End: int32(end), Protocol: "UDP"},
+	}, nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -565,6 +689,87 @@ func shouldUseAPITLS(address string) bool {
 	return strings.HasPrefix(lower, "https://") || strings.Contains(lower, ":443")
 }
 
+// containerResources converts the API's resource limits into Docker units
+func containerResources(limits rmq.ResourceLimitsMessage) docker.ContainerResources {
+	resources := docker.ContainerResources{
+		NanoCPUs:    int64(limits.CPUMillicores) * 1_000_000,
+		MemoryBytes: int64(limits.MemoryMB) * 1024 * 1024,
+		PidsLimit:   int64(limits.PidsLimit),
+	}
+	for _, u := range limits.Ulimits {
+		resources.Ulimits = append(resources.Ulimits, docker.Ulimit{Name: u.Name, Soft: u.Soft, Hard: u.Hard})
+	}
+	return resources
+}
+
+// readinessProbe converts the API's readiness probe; nil when the game config has none
+func readinessProbe(probe *rmq.ReadinessProbeMessage) *manman.ReadinessProbe {
+	if probe == nil || probe.Type == "" {
+		return nil
+	}
+	return &manman.ReadinessProbe{
+		Type:            probe.Type,
+		LogPattern:      probe.LogPattern,
+		Port:            probe.Port,
+		Command:         probe.Command,
+		TimeoutSeconds:  probe.TimeoutSeconds,
+		IntervalSeconds: probe.IntervalSeconds,
+	}
+}
+
+func restartPolicy(policy *rmq.RestartPolicyMessage) *manman.RestartPolicy {
+	if policy == nil || policy.Mode == "" {
+		return nil
+	}
+	return &manman.RestartPolicy{
+		Mode:               policy.Mode,
+		MaxAttempts:        policy.MaxAttempts,
+		BackoffSeconds:     policy.BackoffSeconds,
+		MaxBackoffSeconds:  policy.MaxBackoffSeconds,
+		CrashWindowSeconds: policy.CrashWindowSeconds,
+		MaxCrashesInWindow: policy.MaxCrashesInWindow,
+	}
+}
+
+func playerCountProbe(probe *rmq.PlayerCountProbeMessage) *manman.PlayerCountProbe {
+	if probe == nil || probe.Type == "" {
+		return nil
+	}
+	return &manman.PlayerCountProbe{
+		Type:            probe.Type,
+		JoinPattern:     probe.JoinPattern,
+		LeavePattern:    probe.LeavePattern,
+		CountPattern:    probe.CountPattern,
+		Command:         probe.Command,
+		IntervalSeconds: probe.IntervalSeconds,
+	}
+}
+
+func inputTransport(transport *rmq.InputTransportMessage) *manman.InputTransport {
+	if transport == nil || transport.Type == "" {
+		return nil
+	}
+	return &manman.InputTransport{
+		Type:         transport.Type,
+		Port:         transport.Port,
+		PasswordEnv:  transport.PasswordEnv,
+		PasswordArg:  transport.PasswordArg,
+		PasswordFile: transport.PasswordFile,
+		PasswordKey:  transport.PasswordKey,
+	}
+}
+
+func statusQuery(q *rmq.StatusQueryMessage) *manman.StatusQuery {
+	if q == nil || q.Type == "" {
+		return nil
+	}
+	return &manman.StatusQuery{
+		Type:            q.Type,
+		Port:            q.Port,
+		IntervalSeconds: q.IntervalSeconds,
+	}
+}
+
 // convertSessionStats converts session.SessionStats to rmq.SessionStats
 func convertSessionStats(stats *session.SessionStats) *rmq.SessionStats {
 	if stats == nil {
@@ -575,6 +780,7 @@ func convertSessionStats(stats *session.SessionStats) *rmq.SessionStats {
 		Pending:  stats.Pending,
 		Starting: stats.Starting,
 		Running:  stats.Running,
+		Ready:    stats.Ready,
 		Stopping: stats.Stopping,
 		Stopped:  stats.Stopped,
 		Crashed:  stats.Crashed,
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/docker"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/host/session"
+)
+
+// metricsSampler samples Docker stats for each running game container and the disk usage
+// of the session data directory, and publishes them with the health heartbeat. Docker's
+// counters are cumulative, so CPU and I/O rates come from the previous sample of the
+// same container.
+type metricsSampler struct {
+	dockerClient   *docker.Client
+	sessionManager *session.SessionManager
+	publisher      *rmq.Publisher
+	serverID       int64
+	environment    string
+	dataDir        string
+	logger         *slog.Logger
+
+	previous map[string]*docker.ContainerStats // by container ID
+}
+
+// run samples immediately and then every interval until ctx is done
+func (m *metricsSampler) run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		metrics, err := m.sample(ctx)
+		if err != nil {
+			m.logger.Warn("metrics sample failed", "error", err)
+		} else {
+			stats := m.sessionManager.GetSessionStats()
+			if err := m.publisher.PublishHealth(ctx, convertSessionStats(&stats), metrics); err != nil {
+				m.logger.Warn("failed to publish metrics", "error", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *metricsSampler) sample(ctx context.Context) (*rmq.HostMetrics, error) {
+	metrics := &rmq.HostMetrics{SampledAt: time.Now()}
+
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(m.dataDir, &fs); err != nil {
+		m.logger.Warn("failed to stat data directory", "path", m.dataDir, "error", err)
+	} else {
+		metrics.DiskTotalBytes = fs.Blocks * uint64(fs.Bsize)
+		metrics.DiskUsedBytes = (fs.Blocks - fs.Bfree) * uint64(fs.Bsize)
+	}
+
+	filters := map[string]string{
+		"manman.type":      "game",
+		"manman.server_id": fmt.Sprintf("%d", m.serverID),
+	}
+	if m.environment != "" {
+		filters["manman.environment"] = m.environment
+	}
+	containers, err := m.dockerClient.ListContainers(ctx, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list game containers: %w", err)
+	}
+
+	current := make(map[string]*docker.ContainerStats, len(containers))
+	for _, c := range containers {
+		if !c.Running {
+			continue
+		}
+		sessionID, err := strconv.ParseInt(c.Labels["manman.session_id"], 10, 64)
+		if err != nil {
+			continue
+		}
+		sgcID, _ := strconv.ParseInt(c.Labels["manman.sgc_id"], 10, 64)
+
+		stats, err := m.dockerClient.ContainerStats(ctx, c.ContainerID)
+		if err != nil {
+			m.logger.Warn("failed to get container stats", "session_id", sessionID, "error", err)
+			continue
+		}
+		current[c.ContainerID] = stats
+		// A container's first sample has nothing to take rates from; reporting it would
+		// record zero CPU and I/O into the session's averages
+		prev, ok := m.previous[c.ContainerID]
+		if !ok {
+			continue
+		}
+		metrics.Sessions = append(metrics.Sessions, sessionMetrics(sessionID, sgcID, prev, stats))
+	}
+	// Containers that are gone drop out, so a replacement starts from a fresh sample
+	m.previous = current
+	return metrics, nil
+}
+
+// sessionMetrics turns a container's stats into a session's usage since its previous sample
+func sessionMetrics(sessionID, sgcID int64, prev, cur *docker.ContainerStats) rmq.SessionMetrics {
+	sm := rmq.SessionMetrics{
+		SessionID:        sessionID,
+		SGCID:            sgcID,
+		MemoryBytes:      cur.MemoryBytes,
+		MemoryLimitBytes: cur.MemoryLimitBytes,
+	}
+	elapsed := cur.Read.Sub(prev.Read)
+	sm.CPUPercent = docker.CPUPercent(prev, cur)
+	sm.NetworkRxBytesPerSec = docker.ByteRate(prev.NetworkRxBytes, cur.NetworkRxBytes, elapsed)
+	sm.NetworkTxBytesPerSec = docker.ByteRate(prev.NetworkTxBytes, cur.NetworkTxBytes, elapsed)
+	sm.BlockReadBytesPerSec = docker.ByteRate(prev.BlockReadBytes, cur.BlockReadBytes, elapsed)
+	sm.BlockWriteBytesPerSec = docker.ByteRate(prev.BlockWriteBytes, cur.BlockWriteBytes, elapsed)
+	return sm
+}
@@ -0,0 +1,117 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// A2S packet headers
+const (
+	a2sInfoRequest  = 'T'
+	a2sInfoResponse = 'I'
+	a2sChallenge    = 'A'
+)
+
+var a2sPrefix = []byte{0xFF, 0xFF, 0xFF, 0xFF}
+
+// A2SInfo queries a Source engine server (CS2, TF2, Rust, ARK, Valheim, ...) with A2S_INFO
+func A2SInfo(ctx context.Context, address string) (*Status, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	request := append(append([]byte{}, a2sPrefix...), a2sInfoRequest)
+	request = append(request, "Source Engine Query\x00"...)
+
+	buf := make([]byte, 1400)
+	challenge := []byte(nil)
+	// Servers may answer with a challenge first; the request is then repeated with it appended
+	for attempt := 0; attempt < 2; attempt++ {
+		if _, err := conn.Write(append(request, challenge...)); err != nil {
+			return nil, err
+		}
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		resp := buf[:n]
+		if len(resp) < 5 || !bytes.Equal(resp[:4], a2sPrefix) {
+			return nil, errors.New("a2s: unexpected response")
+		}
+		switch resp[4] {
+		case a2sChallenge:
+			if len(resp) < 9 {
+				return nil, errors.New("a2s: short challenge")
+			}
+			challenge = append([]byte{}, resp[5:9]...)
+		case a2sInfoResponse:
+			return parseA2SInfo(resp[5:])
+		default:
+			return nil, fmt.Errorf("a2s: unexpected response type 0x%02x", resp[4])
+		}
+	}
+	return nil, errors.New("a2s: server kept answering with challenges")
+}
+
+// parseA2SInfo reads an A2S_INFO payload: protocol, name, map, folder, game, app id,
+// players, max players, ...
+func parseA2SInfo(b []byte) (*Status, error) {
+	r := bytes.NewReader(b)
+	if _, err := r.ReadByte(); err != nil { // protocol
+		return nil, err
+	}
+	var strs [4]string
+	for i := range strs {
+		s, err := readCString(r)
+		if err != nil {
+			return nil, fmt.Errorf("a2s: %w", err)
+		}
+		strs[i] = s
+	}
+	var appID uint16
+	if err := binary.Read(r, binary.LittleEndian, &appID); err != nil {
+		return nil, fmt.Errorf("a2s: %w", err)
+	}
+	players, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("a2s: %w", err)
+	}
+	maxPlayers, err := r.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("a2s: %w", err)
+	}
+	return &Status{
+		Name:       strs[0],
+		Map:        strs[1],
+		Players:    int32(players),
+		MaxPlayers: int32(maxPlayers),
+	}, nil
+}
+
+func readCString(r *bytes.Reader) (string, error) {
+	var out []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", errors.New("unterminated string")
+		}
+		if c == 0 {
+			return string(out), nil
+		}
+		out = append(out, c)
+	}
+}
@@ -0,0 +1,143 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// maxStatusSize bounds the status JSON a server may send (it can embed a favicon)
+const maxStatusSize = 1 << 20
+
+// minecraftStatus is the part of the server list ping response we use
+type minecraftStatus struct {
+	Players struct {
+		Max    int32 `json:"max"`
+		Online int32 `json:"online"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"` // a string or a chat component
+}
+
+// MinecraftStatus queries a Minecraft Java server with the server list ping
+func MinecraftStatus(ctx context.Context, address string) (*Status, error) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	_ = conn.SetDeadline(deadline)
+
+	// Handshake (id 0): protocol version -1, address, port, next state 1 (status)
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0)
+	writeVarInt(&handshake, -1)
+	writeVarInt(&handshake, int32(len(host)))
+	handshake.WriteString(host)
+	_ = binary.Write(&handshake, binary.BigEndian, uint16(port))
+	writeVarInt(&handshake, 1)
+
+	var out bytes.Buffer
+	writeVarInt(&out, int32(handshake.Len()))
+	out.Write(handshake.Bytes())
+	// Status request: length 1, id 0
+	out.Write([]byte{1, 0})
+	if _, err := conn.Write(out.Bytes()); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	if _, err := readVarInt(r); err != nil { // packet length
+		return nil, err
+	}
+	id, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if id != 0 {
+		return nil, fmt.Errorf("minecraft: unexpected packet id %d", id)
+	}
+	size, err := readVarInt(r)
+	if err != nil {
+		return nil, err
+	}
+	if size < 0 || size > maxStatusSize {
+		return nil, fmt.Errorf("minecraft: invalid status size %d", size)
+	}
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	var status minecraftStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("minecraft: invalid status: %w", err)
+	}
+	return &Status{
+		Name:       motd(status.Description),
+		Players:    status.Players.Online,
+		MaxPlayers: status.Players.Max,
+	}, nil
+}
+
+// motd reads the description, which is either a plain string or a chat component with text
+func motd(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var component struct {
+		Text string `json:"text"`
+	}
+	_ = json.Unmarshal(raw, &component)
+	return component.Text
+}
+
+func writeVarInt(buf *bytes.Buffer, v int32) {
+	u := uint32(v)
+	for {
+		if u&^0x7F == 0 {
+			buf.WriteByte(byte(u))
+			return
+		}
+		buf.WriteByte(byte(u&0x7F | 0x80))
+		u >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var result uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(result), nil
+		}
+	}
+	return 0, errors.New("minecraft: varint too long")
+}
@@ -0,0 +1,16 @@
+// Package query asks running game servers for their player count and map over the games'
+// own status protocols.
+package query
+
+import "time"
+
+// Status is what a game server reports about itself
+type Status struct {
+	Name       string
+	Map        string // empty for games without maps (Minecraft)
+	Players    int32
+	MaxPlayers int32
+}
+
+// defaultTimeout applies when the caller's context has no deadline
+const defaultTimeout = 5 * time.Second
@@ -0,0 +1,151 @@
+package query
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func a2sInfoPayload(name, mapName string, players, maxPlayers byte) []byte {
+	var b bytes.Buffer
+	b.Write(a2sPrefix)
+	b.WriteByte(a2sInfoResponse)
+	b.WriteByte(17) // protocol
+	for _, s := range []string{name, mapName, "cstrike", "Counter-Strike"} {
+		b.WriteString(s)
+		b.WriteByte(0)
+	}
+	_ = binary.Write(&b, binary.LittleEndian, uint16(240))
+	b.WriteByte(players)
+	b.WriteByte(maxPlayers)
+	return b.Bytes()
+}
+
+// fakeA2SServer answers the first request with a challenge and the challenged request
+// with an info payload
+func fakeA2SServer(t *testing.T) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	challenge := []byte{1, 2, 3, 4}
+	go func() {
+		buf := make([]byte, 1400)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req := buf[:n]
+			if bytes.HasSuffix(req, challenge) {
+				_, _ = conn.WriteTo(a2sInfoPayload("My Server", "de_dust2", 3, 20), addr)
+				continue
+			}
+			resp := append(append([]byte{}, a2sPrefix...), a2sChallenge)
+			_, _ = conn.WriteTo(append(resp, challenge...), addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestA2SInfo(t *testing.T) {
+	addr := fakeA2SServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	status, err := A2SInfo(ctx, addr)
+	if err != nil {
+		t.Fatalf("A2SInfo: %v", err)
+	}
+	if status.Name != "My Server" || status.Map != "de_dust2" || status.Players != 3 || status.MaxPlayers != 20 {
+		t.Errorf("unexpected status %+v", status)
+	}
+}
+
+func TestParseA2SInfoTruncated(t *testing.T) {
+	payload := a2sInfoPayload("name", "map", 1, 2)
+	if _, err := parseA2SInfo(payload[5 : len(payload)-3]); err == nil {
+		t.Error("expected an error for a truncated payload")
+	}
+}
+
+// fakeMinecraftServer reads the handshake and status request and answers with status
+func fakeMinecraftServer(t *testing.T, status string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ { // handshake, status request
+			n, err := readVarInt(r)
+			if err != nil {
+				return
+			}
+			if _, err := r.Discard(int(n)); err != nil {
+				return
+			}
+		}
+		var body bytes.Buffer
+		writeVarInt(&body, 0)
+		writeVarInt(&body, int32(len(status)))
+		body.WriteString(status)
+		var out bytes.Buffer
+		writeVarInt(&out, int32(body.Len()))
+		out.Write(body.Bytes())
+		_, _ = conn.Write(out.Bytes())
+	}()
+	return ln.Addr().String()
+}
+
+func TestMinecraftStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		motd     string
+	}{
+		{"plain description", `{"players":{"max":20,"online":5},"description":"A Minecraft Server"}`, "A Minecraft Server"},
+		{"chat component", `{"players":{"max":20,"online":5},"description":{"text":"Hello"}}`, "Hello"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr := fakeMinecraftServer(t, tt.response)
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+
+			status, err := MinecraftStatus(ctx, addr)
+			if err != nil {
+				t.Fatalf("MinecraftStatus: %v", err)
+			}
+			if status.Players != 5 || status.MaxPlayers != 20 || status.Name != tt.motd {
+				t.Errorf("unexpected status %+v", status)
+			}
+		})
+	}
+}
+
+func TestVarIntRoundTrip(t *testing.T) {
+	for _, v := range []int32{0, 1, 127, 128, 255, 25565, 2147483647, -1} {
+		var b bytes.Buffer
+		writeVarInt(&b, v)
+		got, err := readVarInt(&b)
+		if err != nil || got != v {
+			t.Errorf("varint %d: got %d, %v", v, got, err)
+		}
+	}
+}
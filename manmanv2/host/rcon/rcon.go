@@ -0,0 +1,153 @@
+// Package rcon sends console commands to game servers that read them over the network
+// instead of stdin: Source RCON (TCP) and WebRCON (JSON over a websocket).
+package rcon
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Source RCON packet types
+const (
+	typeResponseValue = 0
+	typeExecCommand   = 2
+	typeAuthResponse  = 2
+	typeAuth          = 3
+)
+
+// maxPacketSize is the largest packet the Source RCON protocol allows
+const maxPacketSize = 4096 + 10
+
+// ErrAuthFailed is returned when the server rejects the RCON password
+var ErrAuthFailed = errors.New("rcon authentication failed")
+
+// Conn is an authenticated Source RCON connection (Source engine games, Minecraft, Arma Reforger's
+// RCON mod, ...)
+type Conn struct {
+	conn   net.Conn
+	nextID int32
+}
+
+// Dial connects to address and authenticates with password
+func Dial(ctx context.Context, address, password string) (*Conn, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{conn: conn, nextID: 1}
+	if err := c.auth(ctx, password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Conn) auth(ctx context.Context, password string) error {
+	c.setDeadline(ctx)
+	id := c.id()
+	if err := c.write(id, typeAuth, password); err != nil {
+		return err
+	}
+	// Source servers send an empty response value before the auth response; Minecraft doesn't
+	for {
+		respID, respType, _, err := c.read()
+		if err != nil {
+			return err
+		}
+		if respType != typeAuthResponse {
+			continue
+		}
+		if respID == -1 || respID != id {
+			return ErrAuthFailed
+		}
+		return nil
+	}
+}
+
+// Exec runs command and returns the server's response
+func (c *Conn) Exec(ctx context.Context, command string) (string, error) {
+	c.setDeadline(ctx)
+	id := c.id()
+	if err := c.write(id, typeExecCommand, command); err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	received := false
+	for {
+		respID, respType, body, err := c.read()
+		if err != nil {
+			// Long responses are split across packets with no end marker; once one has
+			// arrived, a short silence ends the response.
+			var netErr net.Error
+			if received && errors.As(err, &netErr) && netErr.Timeout() {
+				return out.String(), nil
+			}
+			return out.String(), err
+		}
+		if respID != id || respType != typeResponseValue {
+			continue
+		}
+		out.WriteString(body)
+		received = true
+		_ = c.conn.SetReadDeadline(time.Now().Add(250 * time.Millisecond))
+	}
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) id() int32 {
+	id := c.nextID
+	c.nextID++
+	return id
+}
+
+func (c *Conn) setDeadline(ctx context.Context) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	_ = c.conn.SetDeadline(deadline)
+}
+
+func (c *Conn) write(id, packetType int32, body string) error {
+	if len(body)+10 > maxPacketSize {
+		return fmt.Errorf("rcon command too long (%d bytes)", len(body))
+	}
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.LittleEndian, int32(len(body)+10))
+	_ = binary.Write(&buf, binary.LittleEndian, id)
+	_ = binary.Write(&buf, binary.LittleEndian, packetType)
+	buf.WriteString(body)
+	buf.Write([]byte{0, 0})
+	_, err := c.conn.Write(buf.Bytes())
+	return err
+}
+
+func (c *Conn) read() (id, packetType int32, body string, err error) {
+	var size int32
+	if err := binary.Read(c.conn, binary.LittleEndian, &size); err != nil {
+		return 0, 0, "", err
+	}
+	if size < 10 || size > maxPacketSize {
+		return 0, 0, "", fmt.Errorf("invalid rcon packet size %d", size)
+	}
+	packet := make([]byte, size)
+	if _, err := io.ReadFull(c.conn, packet); err != nil {
+		return 0, 0, "", err
+	}
+	id = int32(binary.LittleEndian.Uint32(packet[0:4]))
+	packetType = int32(binary.LittleEndian.Uint32(packet[4:8]))
+	return id, packetType, string(bytes.TrimRight(packet[8:], "\x00")), nil
+}
@@ -0,0 +1,177 @@
+package rcon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRCONServer answers auth with password and echoes commands back as "ran: <command>"
+func fakeRCONServer(t *testing.T, password string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		c := &Conn{conn: conn}
+		for {
+			id, packetType, body, err := c.read()
+			if err != nil {
+				return
+			}
+			switch packetType {
+			case typeAuth:
+				_ = c.write(id, typeResponseValue, "")
+				if body != password {
+					id = -1
+				}
+				_ = c.write(id, typeAuthResponse, "")
+			case typeExecCommand:
+				_ = c.write(id, typeResponseValue, "ran: "+body)
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestRCONExec(t *testing.T) {
+	addr := fakeRCONServer(t, "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := Dial(ctx, addr, "secret")
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	out, err := conn.Exec(ctx, "say hello")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if out != "ran: say hello" {
+		t.Errorf("Unexpected response %q", out)
+	}
+}
+
+func TestRCONBadPassword(t *testing.T) {
+	addr := fakeRCONServer(t, "secret")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if _, err := Dial(ctx, addr, "wrong"); !errors.Is(err, ErrAuthFailed) {
+		t.Errorf("Expected ErrAuthFailed, got %v", err)
+	}
+}
+
+// fakeWebRCONServer upgrades /secret to a websocket and replies to each command, after
+// first broadcasting an unrelated console line
+func fakeWebRCONServer(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		req, err := http.ReadRequest(r)
+		if err != nil {
+			return
+		}
+		if req.URL.Path != "/secret" {
+			io.WriteString(conn, "HTTP/1.1 401 Unauthorized\r\n\r\n")
+			return
+		}
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: "+
+			websocketAccept(req.Header.Get("Sec-WebSocket-Key"))+"\r\n\r\n")
+
+		ws := &wsConn{conn: conn, r: r}
+		for {
+			data, err := ws.readText()
+			if err != nil {
+				return
+			}
+			var msg webRCONMessage
+			_ = json.Unmarshal(data, &msg)
+			broadcast, _ := json.Marshal(webRCONMessage{Identifier: 0, Message: "player joined"})
+			reply, _ := json.Marshal(webRCONMessage{Identifier: msg.Identifier, Message: "ran: " + msg.Message})
+			// Servers don't mask frames
+			for _, payload := range [][]byte{broadcast, reply} {
+				frame := append([]byte{0x80 | opText, byte(len(payload))}, payload...)
+				conn.Write(frame)
+			}
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestWebRCONExec(t *testing.T) {
+	addr := fakeWebRCONServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := DialWebSocket(ctx, addr, "secret")
+	if err != nil {
+		t.Fatalf("DialWebSocket failed: %v", err)
+	}
+	defer conn.Close()
+
+	out, err := conn.Exec(ctx, "status")
+	if err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	if out != "ran: status" {
+		t.Errorf("Unexpected response %q", out)
+	}
+}
+
+func TestWebRCONBadPassword(t *testing.T) {
+	addr := fakeWebRCONServer(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := DialWebSocket(ctx, addr, "wrong")
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected handshake to fail with 401, got %v", err)
+	}
+}
+
+func TestWebSocketFrameLengths(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	payload := []byte(strings.Repeat("x", 70000)) // needs the 64-bit length form
+	go (&wsConn{conn: client}).writeText(payload)
+
+	ws := &wsConn{conn: server, r: bufio.NewReader(server)}
+	got, err := ws.readText()
+	if err != nil {
+		t.Fatalf("readText failed: %v", err)
+	}
+	if len(got) != len(payload) {
+		t.Errorf("Expected %d bytes, got %d", len(payload), len(got))
+	}
+}
@@ -0,0 +1,71 @@
+package rcon
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"time"
+)
+
+// webRCONMessage is the JSON envelope WebRCON uses in both directions
+type webRCONMessage struct {
+	Identifier int    `json:"Identifier"`
+	Message    string `json:"Message"`
+	Name       string `json:"Name,omitempty"`
+	Type       string `json:"Type,omitempty"`
+}
+
+// WebConn is a WebRCON connection, as used by Rust. The password is part of the URL, so a
+// connection that opens is already authenticated.
+type WebConn struct {
+	ws     *wsConn
+	nextID int
+}
+
+// DialWebSocket connects to ws://address/password
+func DialWebSocket(ctx context.Context, address, password string) (*WebConn, error) {
+	ws, err := dialWebSocket(ctx, address, "/"+url.PathEscape(password))
+	if err != nil {
+		return nil, err
+	}
+	return &WebConn{ws: ws, nextID: 1}, nil
+}
+
+// Exec runs command and returns the server's reply to it. Console output the server
+// broadcasts in the meantime (identifier 0) is skipped.
+func (c *WebConn) Exec(ctx context.Context, command string) (string, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(10 * time.Second)
+	}
+	_ = c.ws.conn.SetDeadline(deadline)
+
+	id := c.nextID
+	c.nextID++
+	req, err := json.Marshal(webRCONMessage{Identifier: id, Message: command, Name: "manman"})
+	if err != nil {
+		return "", err
+	}
+	if err := c.ws.writeText(req); err != nil {
+		return "", err
+	}
+
+	for {
+		data, err := c.ws.readText()
+		if err != nil {
+			return "", err
+		}
+		var resp webRCONMessage
+		if err := json.Unmarshal(data, &resp); err != nil {
+			continue
+		}
+		if resp.Identifier == id {
+			return resp.Message, nil
+		}
+	}
+}
+
+// Close closes the connection
+func (c *WebConn) Close() error {
+	return c.ws.close()
+}
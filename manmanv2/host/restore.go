@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/whale-net/everything/libs/go/docker"
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+)
+
+// HandleRestoreBackup downloads a backup archive via pre-signed URL and unpacks it into the
+// volume it was taken from. Any running session for the SGC is stopped first, and if the
+// command carries a start session command it is run once the files are in place.
+func (h *CommandHandlerImpl) HandleRestoreBackup(ctx context.Context, cmd *hostrmq.RestoreBackupCommand) error {
+	if cmd.PresignedURL == "" {
+		return fmt.Errorf("presigned_url is empty for restore of backup %d", cmd.BackupID)
+	}
+
+	slog.Info("processing restore command", "backup_id", cmd.BackupID, "sgc_id", cmd.SGCID, "s3_key", cmd.S3Key, "volume_type", cmd.VolumeType)
+
+	var startCmd *hostrmq.StartSessionCommand
+	if len(cmd.StartSession) > 0 {
+		startCmd = &hostrmq.StartSessionCommand{}
+		if err := json.Unmarshal(cmd.StartSession, startCmd); err != nil {
+			return fmt.Errorf("failed to decode start session command for restore of backup %d: %w", cmd.BackupID, err)
+		}
+	}
+
+	// The API already created the follow-up session as pending; don't leave it hanging
+	// if the restore never gets as far as starting it.
+	fail := func(err error) error {
+		msg := err.Error()
+		_ = h.publisher.PublishRestoreStatus(ctx, &hostrmq.RestoreStatusUpdate{
+			BackupID:     cmd.BackupID,
+			SGCID:        cmd.SGCID,
+			MigrationID:  cmd.MigrationID,
+			Status:       "failed",
+			ErrorMessage: &msg,
+		})
+		if startCmd != nil {
+			_ = h.publisher.PublishSessionStatus(ctx, &hostrmq.SessionStatusUpdate{
+				SessionID: startCmd.SessionID, SGCID: startCmd.SGCID, Status: "crashed",
+			})
+		}
+		return err
+	}
+
+	// 1. Stop the running session so the game can't write over the restored files.
+	if state, exists := h.sessionManager.GetSessionStateBySGCID(cmd.SGCID); exists {
+		slog.Info("stopping session before restore", "session_id", state.SessionID, "sgc_id", cmd.SGCID)
+		if err := h.HandleStopSession(ctx, &hostrmq.StopSessionCommand{SessionID: state.SessionID}); err != nil {
+			return fail(fmt.Errorf("failed to stop session %d before restore: %w", state.SessionID, err))
+		}
+	}
+
+	// 2. Download the archive via pre-signed GET URL
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cmd.PresignedURL, nil)
+	if err != nil {
+		return fail(fmt.Errorf("failed to create download request: %w", err))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fail(fmt.Errorf("failed to download from S3: %w", err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fail(fmt.Errorf("S3 download returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody))))
+	}
+
+	// 3. Replace the volume's contents with the archive's.
+	// The archive is hashed on the way through so it can be checked against the backup.
+	hasher := sha256.New()
+	body := io.TeeReader(resp.Body, hasher)
+	if err := h.unpackRestoreArchive(ctx, cmd, body); err != nil {
+		return fail(err)
+	}
+	// tar can stop before the end of the stream; hash whatever it left
+	if _, err := io.Copy(io.Discard, body); err != nil {
+		return fail(fmt.Errorf("failed to finish reading archive: %w", err))
+	}
+	checksum := hex.EncodeToString(hasher.Sum(nil))
+
+	slog.Info("restore completed", "backup_id", cmd.BackupID, "sgc_id", cmd.SGCID, "sha256", checksum)
+	if err := h.publisher.PublishRestoreStatus(ctx, &hostrmq.RestoreStatusUpdate{
+		BackupID:    cmd.BackupID,
+		SGCID:       cmd.SGCID,
+		MigrationID: cmd.MigrationID,
+		Checksum:    &checksum,
+		Status:      "completed",
+	}); err != nil {
+		slog.Warn("failed to publish restore status", "backup_id", cmd.BackupID, "error", err)
+	}
+
+	// 4. Optionally bring the server back up on the restored data
+	if startCmd != nil {
+		return h.HandleStartSession(ctx, startCmd)
+	}
+	return nil
+}
+
+// unpackRestoreArchive replaces the contents of the command's volume with a gzipped tar
+// stream. The archive is extracted into a staging directory first, so a corrupt or truncated
+// archive leaves the volume as it was. Only once it has been extracted in full is the volume
+// emptied and the staged files moved in; files that are not in the archive don't survive the
+// restore. Named volumes are emptied and filled by a busybox helper container, mirroring
+// resolveBackupSourceDir.
+func (h *CommandHandlerImpl) unpackRestoreArchive(ctx context.Context, cmd *hostrmq.RestoreBackupCommand, archive io.Reader) error {
+	var targetDir, dockerVolumeName string
+	if cmd.VolumeType != "named" {
+		if strings.TrimPrefix(cmd.VolumeHostPath, "/") == "" {
+			return fmt.Errorf("volume_host_path is empty for bind-mount restore of backup %d", cmd.BackupID)
+		}
+		targetDir = h.bindVolumeDir(cmd.SGCID, cmd.VolumeHostPath)
+	} else {
+		if cmd.VolumeName == "" {
+			return fmt.Errorf("volume_name is empty for named-volume restore of backup %d", cmd.BackupID)
+		}
+		dockerVolumeName = h.getNamedVolumeName(cmd.SGCID, cmd.VolumeName)
+	}
+
+	// Staged under internalDataDir, the same filesystem as bind-mount volumes, so the staged
+	// files can be renamed into place
+	stagingInternal, err := os.MkdirTemp(h.internalDataDir, fmt.Sprintf("restore-%d-*", cmd.BackupID))
+	if err != nil {
+		return fmt.Errorf("failed to create restore staging dir: %w", err)
+	}
+	defer os.RemoveAll(stagingInternal)
+
+	if err := extractTarGz(ctx, archive, stagingInternal); err != nil {
+		return err
+	}
+
+	if targetDir != "" {
+		return replaceDirContents(targetDir, stagingInternal)
+	}
+
+	stagingHost := strings.Replace(stagingInternal, h.internalDataDir, h.hostDataDir, 1)
+
+	helperConfig := docker.ContainerConfig{
+		Name:    fmt.Sprintf("backup-restore-%d-%d", cmd.SGCID, cmd.BackupID),
+		Image:   "busybox:latest",
+		Command: []string{"sh", "-c", "find /vol -mindepth 1 -delete && cp -a /staging/. /vol/"},
+		Volumes: []string{
+			fmt.Sprintf("%s:/vol", dockerVolumeName),
+			fmt.Sprintf("%s:/staging", stagingHost),
+		},
+	}
+
+	slog.Info("restoring named volume via helper container", "volume", dockerVolumeName, "staging", stagingHost)
+	if err := h.runBackupHelperContainer(ctx, helperConfig); err != nil {
+		return fmt.Errorf("failed to restore named volume %s: %w", dockerVolumeName, err)
+	}
+	return nil
+}
+
+// replaceDirContents empties targetDir, creating it if needed, and moves every entry of
+// stagingDir into it.
+func replaceDirContents(targetDir, stagingDir string) error {
+	if err := os.MkdirAll(targetDir, 0755); err != nil {
+		return fmt.Errorf("failed to create restore target dir: %w", err)
+	}
+	existing, err := os.ReadDir(targetDir)
+	if err != nil {
+		return fmt.Errorf("failed to read restore target dir: %w", err)
+	}
+	for _, e := range existing {
+		if err := os.RemoveAll(filepath.Join(targetDir, e.Name())); err != nil {
+			return fmt.Errorf("failed to clear restore target dir: %w", err)
+		}
+	}
+	staged, err := os.ReadDir(stagingDir)
+	if err != nil {
+		return fmt.Errorf("failed to read restore staging dir: %w", err)
+	}
+	for _, e := range staged {
+		if err := os.Rename(filepath.Join(stagingDir, e.Name()), filepath.Join(targetDir, e.Name())); err != nil {
+			return fmt.Errorf("failed to move %s into restore target dir: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
+
+// extractTarGz pipes a gzipped tar stream into `tar -xzf - -C dir`.
+func extractTarGz(ctx context.Context, archive io.Reader, dir string) error {
+	tarCmd := exec.CommandContext(ctx, "tar", "-xzf", "-", "-C", dir)
+	tarCmd.Stdin = archive
+	if out, err := tarCmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("tar extract failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
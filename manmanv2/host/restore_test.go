@@ -0,0 +1,110 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+)
+
+// tarGz builds a gzipped tar archive holding files, keyed by relative path.
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatalf("write header: %v", err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("close tar: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("close gzip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func TestUnpackRestoreArchive_BindReplacesContents(t *testing.T) {
+	h := &CommandHandlerImpl{internalDataDir: t.TempDir()}
+	cmd := &hostrmq.RestoreBackupCommand{BackupID: 1, SGCID: 5, VolumeHostPath: "/data"}
+	target := h.bindVolumeDir(cmd.SGCID, cmd.VolumeHostPath)
+
+	writeFile(t, filepath.Join(target, "world/level.dat"), "new world")
+	writeFile(t, filepath.Join(target, "world/region/r.0.0.mca"), "new region")
+	writeFile(t, filepath.Join(target, "server.properties"), "old properties")
+
+	archive := tarGz(t, map[string]string{
+		"world/level.dat":   "old world",
+		"server.properties": "restored properties",
+	})
+	if err := h.unpackRestoreArchive(context.Background(), cmd, bytes.NewReader(archive)); err != nil {
+		t.Fatalf("unpackRestoreArchive: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"world/level.dat":   "old world",
+		"server.properties": "restored properties",
+	} {
+		got, err := os.ReadFile(filepath.Join(target, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(target, "world/region")); !os.IsNotExist(err) {
+		t.Errorf("world/region is not in the archive and should be gone after restore, stat err = %v", err)
+	}
+
+	// Only the volume itself should be left under the data dir
+	entries, err := os.ReadDir(h.internalDataDir)
+	if err != nil {
+		t.Fatalf("read data dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("data dir has %d entries, want only the sgc dir; staging was not cleaned up", len(entries))
+	}
+}
+
+func TestUnpackRestoreArchive_BindCorruptArchiveKeepsContents(t *testing.T) {
+	h := &CommandHandlerImpl{internalDataDir: t.TempDir()}
+	cmd := &hostrmq.RestoreBackupCommand{BackupID: 1, SGCID: 5, VolumeHostPath: "/data"}
+	target := h.bindVolumeDir(cmd.SGCID, cmd.VolumeHostPath)
+	writeFile(t, filepath.Join(target, "world/level.dat"), "current world")
+
+	archive := tarGz(t, map[string]string{"world/level.dat": "old world"})
+	truncated := archive[:len(archive)/2]
+	if err := h.unpackRestoreArchive(context.Background(), cmd, bytes.NewReader(truncated)); err == nil {
+		t.Fatal("expected an error for a truncated archive")
+	}
+
+	got, err := os.ReadFile(filepath.Join(target, "world/level.dat"))
+	if err != nil {
+		t.Fatalf("read level.dat: %v", err)
+	}
+	if string(got) != "current world" {
+		t.Errorf("level.dat = %q, want the volume left as it was", got)
+	}
+}
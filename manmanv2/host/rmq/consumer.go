@@ -19,6 +19,7 @@ type CommandHandler interface {
 	HandleDownloadAddon(ctx context.Context, cmd *DownloadAddonCommand) error
 	HandleRemoveAddon(ctx context.Context, cmd *RemoveAddonCommand) error
 	HandleBackup(ctx context.Context, cmd *BackupCommand) error
+	HandleRestoreBackup(ctx context.Context, cmd *RestoreBackupCommand) error
 }
 
 // Consumer consumes commands from RabbitMQ
@@ -47,6 +48,7 @@ func NewConsumer(conn *rmq.Connection, serverID int64, handler CommandHandler) (
 		fmt.Sprintf("command.host.%d.workshop.download", serverID),
 		fmt.Sprintf("command.host.%d.workshop.remove", serverID),
 		fmt.Sprintf("command.host.%d.backup", serverID),
+		fmt.Sprintf("command.host.%d.backup.restore", serverID),
 	}
 
 	if err := consumer.BindExchange(exchange, routingKeys); err != nil {
@@ -69,6 +71,7 @@ func NewConsumer(conn *rmq.Connection, serverID int64, handler CommandHandler) (
 	downloadAddonKey := fmt.Sprintf("command.host.%d.workshop.download", serverID)
 	removeAddonKey := fmt.Sprintf("command.host.%d.workshop.remove", serverID)
 	backupKey := fmt.Sprintf("command.host.%d.backup", serverID)
+	restoreBackupKey := fmt.Sprintf("command.host.%d.backup.restore", serverID)
 
 	consumer.RegisterHandler(startKey, c.handleStartSession)
 	consumer.RegisterHandler(stopKey, c.handleStopSession)
@@ -77,6 +80,7 @@ func NewConsumer(conn *rmq.Connection, serverID int64, handler CommandHandler) (
 	consumer.RegisterHandler(downloadAddonKey, c.handleDownloadAddon)
 	consumer.RegisterHandler(removeAddonKey, c.handleRemoveAddon)
 	consumer.RegisterHandler(backupKey, c.handleBackup)
+	consumer.RegisterHandler(restoreBackupKey, c.handleRestoreBackup)
 
 	return c, nil
 }
@@ -202,3 +206,25 @@ func (c *Consumer) handleBackup(ctx context.Context, msg rmq.Message) error {
 	}()
 	return nil
 }
+
+func (c *Consumer) handleRestoreBackup(ctx context.Context, msg rmq.Message) error {
+	var cmd RestoreBackupCommand
+	if err := json.Unmarshal(msg.Body, &cmd); err != nil {
+		return fmt.Errorf("failed to unmarshal restore backup command: %w", err)
+	}
+	slog.Info("received command", "command", "restore_backup", "backup_id", cmd.BackupID, "sgc_id", cmd.SGCID, "routing_key", msg.RoutingKey)
+
+	if !cmd.CreatedAt.IsZero() && time.Since(cmd.CreatedAt) > backupCommandMaxAge {
+		slog.Warn("discarding expired restore command", "backup_id", cmd.BackupID, "sgc_id", cmd.SGCID, "age", time.Since(cmd.CreatedAt).Round(time.Second))
+		return nil
+	}
+
+	go func() {
+		if err := c.handler.HandleRestoreBackup(context.Background(), &cmd); err != nil {
+			slog.Error("restore failed", "backup_id", cmd.BackupID, "error", err)
+		} else {
+			slog.Info("command completed", "command", "restore_backup", "backup_id", cmd.BackupID)
+		}
+	}()
+	return nil
+}
@@ -1,6 +1,9 @@
 package rmq
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // PortBindingMessage represents a container-to-host port mapping
 type PortBindingMessage struct {
@@ -20,13 +23,43 @@ type VolumeMountMessage struct {
 
 // GameConfigMessage represents game configuration details
 type GameConfigMessage struct {
-	ConfigID      int64                  `json:"config_id"`
-	Image         string                 `json:"image"`
-	ArgsTemplate  string                 `json:"args_template"`
-	EnvTemplate  map[string]string    `json:"env_template"`
-	Entrypoint   []string             `json:"entrypoint"`
-	Command       []string               `json:"command"`
-	Volumes       []VolumeMountMessage   `json:"volumes"`
+	ConfigID       int64                  `json:"config_id"`
+	Image          string                 `json:"image"`
+	ArgsTemplate   string                 `json:"args_template"`
+	EnvTemplate    map[string]string      `json:"env_template"`
+	Entrypoint     []string               `json:"entrypoint"`
+	Command        []string               `json:"command"`
+	Volumes        []VolumeMountMessage   `json:"volumes"`
+	ReadinessProbe *ReadinessProbeMessage `json:"readiness_probe,omitempty"`
+	InputTransport *InputTransportMessage `json:"input_transport,omitempty"`
+	StatusQuery    *StatusQueryMessage    `json:"status_query,omitempty"`
+}
+
+// ReadinessProbeMessage describes how the host decides a started session is ready for players
+type ReadinessProbeMessage struct {
+	Type            string   `json:"type"` // "log" | "tcp" | "udp" | "exec"
+	LogPattern      string   `json:"log_pattern,omitempty"`
+	Port            int32    `json:"port,omitempty"`
+	Command         []string `json:"command,omitempty"`
+	TimeoutSeconds  int32    `json:"timeout_seconds,omitempty"`
+	IntervalSeconds int32    `json:"interval_seconds,omitempty"`
+}
+
+// InputTransportMessage is how the host delivers SendInput commands to the game server
+type InputTransportMessage struct {
+	Type         string `json:"type"` // "stdin" | "rcon" | "websocket-rcon"
+	Port         int32  `json:"port,omitempty"`
+	PasswordEnv  string `json:"password_env,omitempty"`
+	PasswordArg  string `json:"password_arg,omitempty"`
+	PasswordFile string `json:"password_file,omitempty"`
+	PasswordKey  string `json:"password_key,omitempty"`
+}
+
+// StatusQueryMessage is the query protocol the host polls for live player count and map
+type StatusQueryMessage struct {
+	Type            string `json:"type"` // "a2s" | "minecraft"
+	Port            int32  `json:"port"`
+	IntervalSeconds int32  `json:"interval_seconds,omitempty"`
 }
 
 // ServerGameConfigMessage represents server-specific game configuration
@@ -37,11 +70,50 @@ type ServerGameConfigMessage struct {
 
 // StartSessionCommand represents a command to start a session
 type StartSessionCommand struct {
-	SessionID        int64                   `json:"session_id"`
-	SGCID            int64                   `json:"sgc_id"`
-	GameConfig       GameConfigMessage       `json:"game_config"`
-	ServerGameConfig ServerGameConfigMessage `json:"server_game_config"`
-	Force            bool                   `json:"force"`
+	SessionID        int64                    `json:"session_id"`
+	SGCID            int64                    `json:"sgc_id"`
+	GameConfig       GameConfigMessage        `json:"game_config"`
+	ServerGameConfig ServerGameConfigMessage  `json:"server_game_config"`
+	Force            bool                     `json:"force"`
+	ResourceLimits   ResourceLimitsMessage    `json:"resource_limits"`
+	RestartPolicy    *RestartPolicyMessage    `json:"restart_policy,omitempty"`
+	RestartAttempt   int32                    `json:"restart_attempt"` // 0 unless this session is an automatic restart
+	PlayerCountProbe *PlayerCountProbeMessage `json:"player_count_probe,omitempty"`
+}
+
+// PlayerCountProbeMessage describes how the host counts a session's players for idle shutdown
+type PlayerCountProbeMessage struct {
+	Type            string   `json:"type"` // "log" | "exec" | "query"
+	JoinPattern     string   `json:"join_pattern,omitempty"`
+	LeavePattern    string   `json:"leave_pattern,omitempty"`
+	CountPattern    string   `json:"count_pattern,omitempty"`
+	Command         []string `json:"command,omitempty"`
+	IntervalSeconds int32    `json:"interval_seconds,omitempty"`
+}
+
+// RestartPolicyMessage is the SGC's crash restart policy
+type RestartPolicyMessage struct {
+	Mode               string `json:"mode"` // "never" | "on-failure" | "always"
+	MaxAttempts        int32  `json:"max_attempts,omitempty"`
+	BackoffSeconds     int32  `json:"backoff_seconds,omitempty"`
+	MaxBackoffSeconds  int32  `json:"max_backoff_seconds,omitempty"`
+	CrashWindowSeconds int32  `json:"crash_window_seconds,omitempty"`
+	MaxCrashesInWindow int32  `json:"max_crashes_in_window,omitempty"`
+}
+
+// ResourceLimitsMessage carries the effective container limits for a session. Zero means unlimited.
+type ResourceLimitsMessage struct {
+	CPUMillicores int32           `json:"cpu_millicores,omitempty"`
+	MemoryMB      int32           `json:"memory_mb,omitempty"`
+	PidsLimit     int32           `json:"pids_limit,omitempty"`
+	Ulimits       []UlimitMessage `json:"ulimits,omitempty"`
+}
+
+// UlimitMessage is a named process limit, e.g. nofile
+type UlimitMessage struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
 }
 
 // StopSessionCommand represents a command to stop a session
@@ -71,14 +143,54 @@ type HostStatusUpdate struct {
 type SessionStatusUpdate struct {
 	SessionID int64  `json:"session_id"`
 	SGCID     int64  `json:"sgc_id"`
-	Status    string `json:"status"` // "pending" | "starting" | "running" | "stopping" | "stopped" | "crashed"
+	Status    string `json:"status"` // "pending" | "starting" | "running" | "ready" | "stopping" | "stopped" | "crashed"
 	ExitCode  *int   `json:"exit_code,omitempty"`
 }
 
+// RestartAbandonedUpdate reports that the host stopped restarting an SGC after one of its
+// sessions crashed
+type RestartAbandonedUpdate struct {
+	SessionID int64  `json:"session_id"` // the crashed session that was not restarted
+	SGCID     int64  `json:"sgc_id"`
+	Attempt   int32  `json:"attempt"` // the restart attempt that was abandoned
+	Reason    string `json:"reason"`
+}
+
+// PlayerCountUpdate reports a session's player count whenever it changes. MapName and
+// MaxPlayers are set when the count comes from the game config's status query.
+type PlayerCountUpdate struct {
+	SessionID   int64     `json:"session_id"`
+	SGCID       int64     `json:"sgc_id"`
+	PlayerCount int32     `json:"player_count"`
+	MapName     string    `json:"map_name,omitempty"`
+	MaxPlayers  int32     `json:"max_players,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// ImageStatusReport is published by a host after each background image refresh with the
+// image state of every SGC on the server
+type ImageStatusReport struct {
+	ServerID  int64            `json:"server_id"`
+	Images    []SGCImageStatus `json:"images"`
+	CheckedAt time.Time        `json:"checked_at"`
+}
+
+// SGCImageStatus is one SGC's image on a host. LocalDigest is the digest its running session
+// uses, or of the host's cached copy when nothing is running; RemoteDigest is what the tag
+// points at in the registry. Either is empty when unknown, with Error saying why.
+type SGCImageStatus struct {
+	SGCID        int64  `json:"sgc_id"`
+	Image        string `json:"image"`
+	LocalDigest  string `json:"local_digest,omitempty"`
+	RemoteDigest string `json:"remote_digest,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
 // HealthUpdate represents a health/keepalive message with session metrics
 type HealthUpdate struct {
-	ServerID        int64           `json:"server_id"`
-	SessionStats    *SessionStats   `json:"session_stats,omitempty"`
+	ServerID     int64         `json:"server_id"`
+	SessionStats *SessionStats `json:"session_stats,omitempty"`
+	Metrics      *HostMetrics  `json:"metrics,omitempty"` // only on the periodic resource sample
 }
 
 // SessionStats represents aggregated session statistics
@@ -87,18 +199,50 @@ type SessionStats struct {
 	Pending  int `json:"pending"`
 	Starting int `json:"starting"`
 	Running  int `json:"running"`
+	Ready    int `json:"ready"`
 	Stopping int `json:"stopping"`
 	Stopped  int `json:"stopped"`
 	Crashed  int `json:"crashed"`
 }
-// DownloadAddonCommand represents a command to download a workshop addon
+
+// HostMetrics is a resource usage sample of the host's data directory and each of its
+// game containers
+type HostMetrics struct {
+	SampledAt      time.Time        `json:"sampled_at"`
+	DiskTotalBytes uint64           `json:"disk_total_bytes"`
+	DiskUsedBytes  uint64           `json:"disk_used_bytes"`
+	Sessions       []SessionMetrics `json:"sessions,omitempty"`
+}
+
+// SessionMetrics is one game container's usage. CPU and the rates cover the time since
+// the previous sample, so a container isn't reported until its second sample.
+type SessionMetrics struct {
+	SessionID             int64   `json:"session_id"`
+	SGCID                 int64   `json:"sgc_id"`
+	CPUPercent            float64 `json:"cpu_percent"` // 100 is one full core
+	MemoryBytes           uint64  `json:"memory_bytes"`
+	MemoryLimitBytes      uint64  `json:"memory_limit_bytes"`
+	NetworkRxBytesPerSec  float64 `json:"network_rx_bytes_per_sec"`
+	NetworkTxBytesPerSec  float64 `json:"network_tx_bytes_per_sec"`
+	BlockReadBytesPerSec  float64 `json:"block_read_bytes_per_sec"`
+	BlockWriteBytesPerSec float64 `json:"block_write_bytes_per_sec"`
+}
+
+// DownloadAddonCommand represents a command to download a workshop addon.
+// Addons from an addon source carry a DownloadURL; the rest are fetched with SteamCMD.
 type DownloadAddonCommand struct {
-	InstallationID int64  `json:"installation_id"`
-	SGCID          int64  `json:"sgc_id"`
-	AddonID        int64  `json:"addon_id"`
-	WorkshopID     string `json:"workshop_id"`
-	SteamAppID     string `json:"steam_app_id"`
-	InstallPath    string `json:"install_path"`
+	InstallationID    int64  `json:"installation_id"`
+	SGCID             int64  `json:"sgc_id"`
+	AddonID           int64  `json:"addon_id"`
+	WorkshopID        string `json:"workshop_id"`
+	SteamAppID        string `json:"steam_app_id"`
+	InstallPath       string `json:"install_path"`
+	PlatformType      string `json:"platform_type,omitempty"`
+	DownloadURL       string `json:"download_url,omitempty"`
+	FileName          string `json:"file_name,omitempty"`
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
+	Checksum          string `json:"checksum,omitempty"`
+	Extract           bool   `json:"extract,omitempty"`
 }
 
 // InstallationStatusUpdate represents a status update for a workshop addon installation
@@ -131,11 +275,72 @@ type BackupCommand struct {
 	CreatedAt         time.Time `json:"created_at"`          // used to discard commands that queued too long
 }
 
+// RestoreBackupCommand instructs the host-manager to download a backup archive and unpack it
+// into the volume it was taken from. Any running session for the SGC is stopped first.
+type RestoreBackupCommand struct {
+	BackupID       int64           `json:"backup_id"`
+	SGCID          int64           `json:"sgc_id"`
+	VolumeType     string          `json:"volume_type"`             // "bind" or "named"
+	VolumeHostPath string          `json:"volume_host_path"`        // host path to volume root (bind volumes only)
+	VolumeName     string          `json:"volume_name"`             // logical volume name (used to derive Docker named volume)
+	S3Key          string          `json:"s3_key"`                  // key of the archive being restored
+	PresignedURL   string          `json:"presigned_url"`           // pre-signed GET URL for direct download
+	StartSession   json.RawMessage `json:"start_session,omitempty"` // optional StartSessionCommand to run once restored
+	MigrationID    int64           `json:"migration_id,omitempty"`  // set when restoring onto the target of an SGC migration
+	CreatedAt      time.Time       `json:"created_at"`              // used to discard commands that queued too long
+}
+
 // BackupStatusUpdate reports the result of a backup operation back to the processor
 type BackupStatusUpdate struct {
 	BackupID     int64   `json:"backup_id"`
 	S3URL        *string `json:"s3_url,omitempty"`
 	SizeBytes    *int64  `json:"size_bytes,omitempty"`
+	Checksum     *string `json:"checksum,omitempty"` // sha256 of the uploaded archive, hex
+	Status       string  `json:"status"`             // "completed" | "failed"
+	ErrorMessage *string `json:"error_message,omitempty"`
+}
+
+// RestoreStatusUpdate reports the result of a restore back to the processor
+type RestoreStatusUpdate struct {
+	BackupID     int64   `json:"backup_id"`
+	SGCID        int64   `json:"sgc_id"`
+	MigrationID  int64   `json:"migration_id,omitempty"`
+	Checksum     *string `json:"checksum,omitempty"` // sha256 of the downloaded archive, hex
+	Status       string  `json:"status"`             // "completed" | "failed"
+	ErrorMessage *string `json:"error_message,omitempty"`
+}
+
+// BackupEvent is published by the processor to the external exchange as
+// manman.backup.<status> once a backup finishes
+type BackupEvent struct {
+	BackupID     int64   `json:"backup_id"`
+	SGCID        int64   `json:"sgc_id"`
+	SessionID    int64   `json:"session_id"`
 	Status       string  `json:"status"` // "completed" | "failed"
+	SizeBytes    *int64  `json:"size_bytes,omitempty"`
 	ErrorMessage *string `json:"error_message,omitempty"`
 }
+
+// MigrationEvent is published by the processor to the external exchange as
+// manman.migration.<status> each time an SGC migration moves to a new step
+type MigrationEvent struct {
+	MigrationID    int64   `json:"migration_id"`
+	SGCID          int64   `json:"sgc_id"` // the source SGC
+	SourceServerID int64   `json:"source_server_id"`
+	TargetServerID int64   `json:"target_server_id"`
+	TargetSGCID    int64   `json:"target_sgc_id,omitempty"`
+	Status         string  `json:"status"`
+	ErrorMessage   *string `json:"error_message,omitempty"`
+}
+
+// AddonUpdateEvent is published by the processor to the external exchange as
+// manman.sgc.addon_update_available when an addon installed on an SGC changes upstream
+type AddonUpdateEvent struct {
+	SGCID          int64     `json:"sgc_id"`
+	InstallationID int64     `json:"installation_id"`
+	AddonID        int64     `json:"addon_id"`
+	WorkshopID     string    `json:"workshop_id"`
+	Name           string    `json:"name"`
+	TimeUpdated    time.Time `json:"time_updated"`
+	Policy         string    `json:"policy"` // notify, or reinstall when it will be re-downloaded after the session stops
+}
@@ -175,3 +175,94 @@ func TestHostStatusUpdate_MarshalUnmarshal(t *testing.T) {
 		t.Errorf("Expected Status %s, got %s", update.Status, unmarshaled.Status)
 	}
 }
+
+func TestRestoreBackupCommand_EmbeddedStartSession(t *testing.T) {
+	start := rmq.StartSessionCommand{SessionID: 12, SGCID: 34, Force: true}
+	startBody, err := json.Marshal(start)
+	if err != nil {
+		t.Fatalf("Failed to marshal start command: %v", err)
+	}
+
+	cmd := rmq.RestoreBackupCommand{
+		BackupID:     7,
+		SGCID:        34,
+		VolumeType:   "named",
+		VolumeName:   "data",
+		S3Key:        "backups/34/1/7.tar.gz",
+		PresignedURL: "https://s3.example/backups/34/1/7.tar.gz",
+		StartSession: startBody,
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("Failed to marshal command: %v", err)
+	}
+
+	var unmarshaled rmq.RestoreBackupCommand
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal command: %v", err)
+	}
+	if unmarshaled.BackupID != cmd.BackupID {
+		t.Errorf("Expected BackupID %d, got %d", cmd.BackupID, unmarshaled.BackupID)
+	}
+
+	var embedded rmq.StartSessionCommand
+	if err := json.Unmarshal(unmarshaled.StartSession, &embedded); err != nil {
+		t.Fatalf("Failed to unmarshal embedded start command: %v", err)
+	}
+	if embedded.SessionID != start.SessionID || !embedded.Force {
+		t.Errorf("Expected embedded session %d with force, got %+v", start.SessionID, embedded)
+	}
+}
+
+func TestRestoreBackupCommand_OmitsEmptyStartSession(t *testing.T) {
+	data, err := json.Marshal(rmq.RestoreBackupCommand{BackupID: 1})
+	if err != nil {
+		t.Fatalf("Failed to marshal command: %v", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal command: %v", err)
+	}
+	if _, ok := raw["start_session"]; ok {
+		t.Error("Expected start_session to be omitted when empty")
+	}
+}
+
+func TestHealthUpdate_MetricsOptional(t *testing.T) {
+	data, err := json.Marshal(rmq.HealthUpdate{ServerID: 1, SessionStats: &rmq.SessionStats{Total: 1}})
+	if err != nil {
+		t.Fatalf("Failed to marshal update: %v", err)
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Failed to unmarshal update: %v", err)
+	}
+	if _, ok := raw["metrics"]; ok {
+		t.Error("Expected metrics to be omitted from a plain heartbeat")
+	}
+
+	update := rmq.HealthUpdate{
+		ServerID: 1,
+		Metrics: &rmq.HostMetrics{
+			DiskTotalBytes: 100 << 30,
+			DiskUsedBytes:  40 << 30,
+			Sessions:       []rmq.SessionMetrics{{SessionID: 12, SGCID: 34, CPUPercent: 150, MemoryBytes: 1 << 30}},
+		},
+	}
+	data, err = json.Marshal(update)
+	if err != nil {
+		t.Fatalf("Failed to marshal update: %v", err)
+	}
+	var unmarshaled rmq.HealthUpdate
+	if err := json.Unmarshal(data, &unmarshaled); err != nil {
+		t.Fatalf("Failed to unmarshal update: %v", err)
+	}
+	if unmarshaled.Metrics == nil || len(unmarshaled.Metrics.Sessions) != 1 {
+		t.Fatalf("Expected metrics with one session, got %+v", unmarshaled.Metrics)
+	}
+	if got := unmarshaled.Metrics.Sessions[0]; got != update.Metrics.Sessions[0] {
+		t.Errorf("Expected session metrics %+v, got %+v", update.Metrics.Sessions[0], got)
+	}
+}
@@ -47,14 +47,34 @@ func (p *Publisher) PublishSessionStatus(ctx context.Context, update *SessionSta
 	return p.publisher.Publish(ctx, "manman", routingKey, update)
 }
 
-// PublishHealth publishes a health/keepalive message with optional session stats
-func (p *Publisher) PublishHealth(ctx context.Context, stats *SessionStats) error {
+// PublishRestartAbandoned publishes that the host gave up restarting a crashed session
+func (p *Publisher) PublishRestartAbandoned(ctx context.Context, update *RestartAbandonedUpdate) error {
+	routingKey := fmt.Sprintf("status.restart.%d", update.SessionID)
+	slog.Info("publishing restart abandoned event",
+		"session_id", update.SessionID, "sgc_id", update.SGCID,
+		"attempt", update.Attempt, "reason", update.Reason, "routing_key", routingKey)
+	return p.publisher.Publish(ctx, "manman", routingKey, update)
+}
+
+// PublishPlayerCount publishes a session's player count for idle shutdown
+func (p *Publisher) PublishPlayerCount(ctx context.Context, update *PlayerCountUpdate) error {
+	routingKey := fmt.Sprintf("status.players.%d", update.SessionID)
+	slog.Debug("publishing player count",
+		"session_id", update.SessionID, "sgc_id", update.SGCID,
+		"player_count", update.PlayerCount, "routing_key", routingKey)
+	return p.publisher.Publish(ctx, "manman", routingKey, update)
+}
+
+// PublishHealth publishes a health/keepalive message with optional session stats and
+// resource metrics
+func (p *Publisher) PublishHealth(ctx context.Context, stats *SessionStats, metrics *HostMetrics) error {
 	update := HealthUpdate{
 		ServerID:     p.serverID,
 		SessionStats: stats,
+		Metrics:      metrics,
 	}
 	routingKey := fmt.Sprintf("health.host.%d", p.serverID)
-	slog.Debug("publishing health heartbeat", "server_id", p.serverID, "routing_key", routingKey)
+	slog.Debug("publishing health heartbeat", "server_id", p.serverID, "routing_key", routingKey, "has_metrics", metrics != nil)
 	return p.publisher.Publish(ctx, "manman", routingKey, update)
 }
 
@@ -83,3 +103,25 @@ func (p *Publisher) PublishBackupStatus(ctx context.Context, update *BackupStatu
 		"routing_key", routingKey)
 	return p.publisher.Publish(ctx, "manman", routingKey, update)
 }
+
+// PublishImageStatus publishes the result of a background image refresh
+func (p *Publisher) PublishImageStatus(ctx context.Context, report *ImageStatusReport) error {
+	routingKey := fmt.Sprintf("status.image.%d", p.serverID)
+	slog.Info("publishing image status event",
+		"server_id", p.serverID,
+		"images", len(report.Images),
+		"routing_key", routingKey)
+	report.ServerID = p.serverID
+	return p.publisher.Publish(ctx, "manman", routingKey, report)
+}
+
+// PublishRestoreStatus publishes a restore completion/failure status update
+func (p *Publisher) PublishRestoreStatus(ctx context.Context, update *RestoreStatusUpdate) error {
+	routingKey := fmt.Sprintf("status.restore.%d", update.BackupID)
+	slog.Info("publishing restore status event",
+		"backup_id", update.BackupID,
+		"sgc_id", update.SGCID,
+		"status", update.Status,
+		"routing_key", routingKey)
+	return p.publisher.Publish(ctx, "manman", routingKey, update)
+}
@@ -0,0 +1,212 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/host/rcon"
+	"github.com/whale-net/everything/manmanv2/models"
+	"gopkg.in/yaml.v3"
+)
+
+// rconTimeout bounds dialing, authenticating and running one RCON command
+const rconTimeout = 10 * time.Second
+
+// inputTransport is a game config's RCON transport with its password resolved from the
+// session's final command, env and config files. err is set on a recovered session whose
+// password can no longer be resolved; SendInput returns it instead of trying stdin.
+type inputTransport struct {
+	manman.InputTransport
+	password string
+	err      error
+}
+
+// resolveInputTransport resolves an RCON transport's password. Returns nil for stdin (or no
+// transport), which SendInput treats as attaching to the container's stdin. Config files
+// are read from dataDir, the SGC's data directory, where the renderer writes them.
+func resolveInputTransport(transport *manman.InputTransport, command, env []string, dataDir string) (*inputTransport, error) {
+	if transport == nil || transport.Type == "" || transport.Type == manman.InputTransportStdin {
+		return nil, nil
+	}
+	resolved := &inputTransport{InputTransport: *transport}
+	var tried []string
+	if transport.PasswordEnv != "" {
+		if password, ok := envValue(env, transport.PasswordEnv); ok {
+			resolved.password = password
+			return resolved, nil
+		}
+		tried = append(tried, fmt.Sprintf("env %q", transport.PasswordEnv))
+	}
+	if transport.PasswordArg != "" {
+		if password, ok := argValue(command, transport.PasswordArg); ok {
+			resolved.password = password
+			return resolved, nil
+		}
+		tried = append(tried, fmt.Sprintf("arg %q", transport.PasswordArg))
+	}
+	if transport.PasswordFile != "" {
+		path := filepath.Join(dataDir, strings.TrimPrefix(transport.PasswordFile, "/"))
+		password, ok, err := fileValue(path, transport.PasswordKey)
+		if err != nil {
+			return resolved, fmt.Errorf("failed to read rcon password file %s: %w", transport.PasswordFile, err)
+		}
+		if ok {
+			resolved.password = password
+			return resolved, nil
+		}
+		tried = append(tried, fmt.Sprintf("key %q of %s", transport.PasswordKey, transport.PasswordFile))
+	}
+	return resolved, fmt.Errorf("rcon password not found in %s", strings.Join(tried, ", "))
+}
+
+// fileValue looks key up in a config file. JSON and YAML files take a dotted path to a
+// scalar; other files are read a line at a time as "key=value", "key: value" or, as in
+// Source's server.cfg, "key value", with surrounding quotes dropped.
+func fileValue(path, key string) (string, bool, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, err
+	}
+	var doc interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return "", false, err
+		}
+		return documentValue(doc, key)
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return "", false, err
+		}
+		return documentValue(doc, key)
+	}
+	value, ok := lineValue(string(content), key)
+	return value, ok, nil
+}
+
+// documentValue follows a dotted path through decoded JSON or YAML. A key that itself
+// contains dots is matched whole before being split.
+func documentValue(doc interface{}, path string) (string, bool, error) {
+	obj, ok := doc.(map[string]interface{})
+	if !ok {
+		return "", false, nil
+	}
+	if value, ok := obj[path]; ok {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			return "", false, fmt.Errorf("%s is not a scalar", path)
+		case nil:
+			return "", false, nil
+		}
+		return fmt.Sprint(value), true, nil
+	}
+	head, rest, found := strings.Cut(path, ".")
+	if !found {
+		return "", false, nil
+	}
+	return documentValue(obj[head], rest)
+}
+
+// lineValue finds key at the start of a line followed by '=', ':' or whitespace
+func lineValue(content, key string) (string, bool) {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		rest, ok := strings.CutPrefix(line, key)
+		if !ok || rest == "" {
+			continue
+		}
+		trimmed := strings.TrimLeft(rest, " \t")
+		switch {
+		case trimmed != "" && (trimmed[0] == '=' || trimmed[0] == ':'):
+			trimmed = strings.TrimSpace(trimmed[1:])
+		case len(trimmed) == len(rest):
+			continue // key is only a prefix of another key
+		}
+		if len(trimmed) >= 2 && (trimmed[0] == '"' || trimmed[0] == '\'') && trimmed[len(trimmed)-1] == trimmed[0] {
+			trimmed = trimmed[1 : len(trimmed)-1]
+		}
+		return trimmed, true
+	}
+	return "", false
+}
+
+// envValue looks key up in KEY=VALUE env
+func envValue(env []string, key string) (string, bool) {
+	for _, kv := range env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+// argValue returns the value following flag in command ("flag value" or "flag=value").
+// Shell-wrapped commands (/bin/sh -c "...") are split on whitespace so the script's flags
+// are found too.
+func argValue(command []string, flag string) (string, bool) {
+	var args []string
+	for _, part := range command {
+		args = append(args, strings.Fields(part)...)
+	}
+	for i, arg := range args {
+		arg = strings.Trim(arg, `'"`)
+		if arg == flag && i+1 < len(args) {
+			return strings.Trim(args[i+1], `'"`), true
+		}
+		if value, ok := strings.CutPrefix(arg, flag+"="); ok {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// sendRCON runs input as a command over the session's RCON transport and publishes the
+// server's response as a log line.
+func (sm *SessionManager) sendRCON(ctx context.Context, state *State, transport *inputTransport, input []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, rconTimeout)
+	defer cancel()
+
+	address, err := sm.containerAddress(ctx, state, transport.Port)
+	if err != nil {
+		return fmt.Errorf("failed to resolve rcon address: %w", err)
+	}
+	command := strings.TrimRight(string(input), "\r\n")
+
+	var response string
+	switch transport.Type {
+	case manman.InputTransportWebSocketRCON:
+		conn, err := rcon.DialWebSocket(ctx, address, transport.password)
+		if err != nil {
+			return fmt.Errorf("failed to connect to websocket rcon: %w", err)
+		}
+		defer conn.Close()
+		response, err = conn.Exec(ctx, command)
+		if err != nil {
+			return fmt.Errorf("websocket rcon command failed: %w", err)
+		}
+	default:
+		conn, err := rcon.Dial(ctx, address, transport.password)
+		if err != nil {
+			return fmt.Errorf("failed to connect to rcon: %w", err)
+		}
+		defer conn.Close()
+		response, err = conn.Exec(ctx, command)
+		if err != nil {
+			return fmt.Errorf("rcon command failed: %w", err)
+		}
+	}
+	slog.Debug("sent rcon command", "session_id", state.SessionID, "transport", transport.Type)
+
+	if response != "" && sm.rmqPublisher != nil {
+		if err := sm.rmqPublisher.PublishLog(ctx, state.SessionID, "rcon", response); err != nil {
+			slog.Warn("failed to publish rcon response", "session_id", state.SessionID, "error", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+func TestResolveInputTransport(t *testing.T) {
+	tests := []struct {
+		name      string
+		transport *manman.InputTransport
+		command   []string
+		env       []string
+		files     map[string]string // container path -> content
+		want      string
+		wantNil   bool
+		wantErr   bool
+	}{
+		{
+			name:    "no transport is stdin",
+			wantNil: true,
+		},
+		{
+			name:      "explicit stdin",
+			transport: &manman.InputTransport{Type: manman.InputTransportStdin},
+			wantNil:   true,
+		},
+		{
+			name:      "password from env",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 27015, PasswordEnv: "RCON_PASSWORD"},
+			env:       []string{"OTHER=x", "RCON_PASSWORD=secret"},
+			want:      "secret",
+		},
+		{
+			name:      "password from arg",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 27015, PasswordArg: "+rcon_password"},
+			command:   []string{"./srcds_run", "-game", "csgo", "+rcon_password", "secret"},
+			want:      "secret",
+		},
+		{
+			name:      "password from flag=value arg",
+			transport: &manman.InputTransport{Type: manman.InputTransportWebSocketRCON, Port: 28016, PasswordArg: "-rcon.password"},
+			command:   []string{"./RustDedicated", "-rcon.password=secret"},
+			want:      "secret",
+		},
+		{
+			name:      "password from shell-wrapped args_template",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 27015, PasswordArg: "+rcon_password"},
+			command:   []string{"/bin/sh", "-c", "./srcds_run -game cs2 '+rcon_password' 'secret'"},
+			want:      "secret",
+		},
+		{
+			name:      "env falls back to arg",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 27015, PasswordEnv: "RCON_PASSWORD", PasswordArg: "+rcon_password"},
+			command:   []string{"./srcds_run", "+rcon_password", "fromarg"},
+			want:      "fromarg",
+		},
+		{
+			name:      "password from server.properties",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 25575, PasswordFile: "/data/server.properties", PasswordKey: "rcon.password"},
+			files:     map[string]string{"/data/server.properties": "# Minecraft\nenable-rcon=true\nrcon.password=secret\nrcon.port=25575\n"},
+			want:      "secret",
+		},
+		{
+			name:      "password from server.cfg",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 27015, PasswordFile: "/data/cfg/server.cfg", PasswordKey: "rcon_password"},
+			files:     map[string]string{"/data/cfg/server.cfg": "// server\nhostname \"test\"\nrcon_password_min 1\nrcon_password \"secret\"\n"},
+			want:      "secret",
+		},
+		{
+			name:      "password from nested config.json",
+			transport: &manman.InputTransport{Type: manman.InputTransportWebSocketRCON, Port: 28016, PasswordFile: "/data/config.json", PasswordKey: "rcon.password"},
+			files:     map[string]string{"/data/config.json": `{"rcon": {"port": 28016, "password": "secret"}}`},
+			want:      "secret",
+		},
+		{
+			name:      "password from yaml",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 25575, PasswordFile: "/data/config.yml", PasswordKey: "rcon.password"},
+			files:     map[string]string{"/data/config.yml": "rcon:\n  password: secret\n"},
+			want:      "secret",
+		},
+		{
+			name:      "key missing from file",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 25575, PasswordFile: "/data/server.properties", PasswordKey: "rcon.password"},
+			files:     map[string]string{"/data/server.properties": "enable-rcon=true\n"},
+			wantErr:   true,
+		},
+		{
+			name:      "file not rendered",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 25575, PasswordFile: "/data/server.properties", PasswordKey: "rcon.password"},
+			wantErr:   true,
+		},
+		{
+			name:      "password missing",
+			transport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: 27015, PasswordEnv: "RCON_PASSWORD"},
+			env:       []string{"OTHER=x"},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataDir := t.TempDir()
+			for path, content := range tt.files {
+				hostPath := filepath.Join(dataDir, path)
+				if err := os.MkdirAll(filepath.Dir(hostPath), 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(hostPath, []byte(content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := resolveInputTransport(tt.transport, tt.command, tt.env, dataDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("expected nil transport, got %+v", got)
+				}
+				return
+			}
+			if got == nil {
+				t.Fatal("expected a transport")
+			}
+			if !tt.wantErr && got.password != tt.want {
+				t.Errorf("password = %q, want %q", got.password, tt.want)
+			}
+		})
+	}
+}
@@ -4,11 +4,13 @@ import (
 	"bufio"
 	"context"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -38,9 +40,12 @@ type SessionManager struct {
 	grpcClient           pb.ManManAPIClient
 	renderer             *config.Renderer
 	workshopOrchestrator WorkshopOrchestrator
+	crashes              *crashHistory // recent crash times per SGC, for restart crash loop detection
 	rmqPublisher         interface {
 		PublishLog(ctx context.Context, sessionID int64, source string, message string) error
 		PublishSessionStatus(ctx context.Context, update *hostrmq.SessionStatusUpdate) error
+		PublishRestartAbandoned(ctx context.Context, update *hostrmq.RestartAbandonedUpdate) error
+		PublishPlayerCount(ctx context.Context, update *hostrmq.PlayerCountUpdate) error
 	}
 }
 
@@ -59,6 +64,8 @@ func NewSessionManager(
 	rmqPublisher interface {
 		PublishLog(ctx context.Context, sessionID int64, source string, message string) error
 		PublishSessionStatus(ctx context.Context, update *hostrmq.SessionStatusUpdate) error
+		PublishRestartAbandoned(ctx context.Context, update *hostrmq.RestartAbandonedUpdate) error
+		PublishPlayerCount(ctx context.Context, update *hostrmq.PlayerCountUpdate) error
 	},
 ) *SessionManager {
 	return &SessionManager{
@@ -69,21 +76,29 @@ func NewSessionManager(
 		grpcClient:           grpcClient,
 		workshopOrchestrator: workshopOrchestrator,
 		renderer:             config.NewRenderer(nil),
+		crashes:              newCrashHistory(),
 		rmqPublisher:         rmqPublisher,
 	}
 }
 
 // StartSessionCommand represents a command to start a session
 type StartSessionCommand struct {
-	SessionID     int64
-	SGCID         int64
-	ServerID      int64
-	Image         string
-	Command       []string
-	Env          []string
-	PortBindings map[string]string // containerPort -> hostPort
-	Volumes      []VolumeMount     // many volumes
-	Force        bool
+	SessionID      int64
+	SGCID          int64
+	ServerID       int64
+	Image          string
+	Command        []string
+	Env            []string
+	PortBindings   map[string]string // containerPort -> hostPort
+	Volumes        []VolumeMount     // many volumes
+	Force          bool
+	Resources      docker.ContainerResources
+	Readiness      *manman.ReadinessProbe   // nil = ready as soon as the container starts
+	Restart        *manman.RestartPolicy    // nil = never restart
+	RestartAttempt int32                    // 0 unless this session is an automatic restart
+	PlayerCount    *manman.PlayerCountProbe // nil = players aren't counted (no idle shutdown)
+	InputTransport *manman.InputTransport   // nil = stdin
+	StatusQuery    *manman.StatusQuery      // nil = no live player count or map
 }
 
 type VolumeMount struct {
@@ -169,11 +184,25 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 		}
 	}
 
+	readiness, err := newReadinessWatch(cmd.Readiness)
+	if err != nil {
+		return &rmq.PermanentError{Err: err}
+	}
+	players, err := newPlayerCounter(cmd.PlayerCount)
+	if err != nil {
+		return &rmq.PermanentError{Err: err}
+	}
+
 	// Create session state
 	state := &State{
-		SessionID: sessionID,
-		SGCID:     sgcID,
-		Status:    manman.SessionStatusPending,
+		SessionID:      sessionID,
+		SGCID:          sgcID,
+		Status:         manman.SessionStatusPending,
+		readiness:      readiness,
+		players:        players,
+		restart:        cmd.Restart,
+		restartAttempt: cmd.RestartAttempt,
+		statusQuery:    cmd.StatusQuery,
 	}
 	sm.stateManager.AddSession(state)
 	slog.Debug("session added to state manager", "session_id", sessionID)
@@ -185,6 +214,7 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 	state.NetworkName = ""
 
 	// 2. Fetch and render configurations
+	var rendered *config.RenderResult
 	slog.Info("fetching configuration strategies", "session_id", sessionID)
 	configResp, err := sm.grpcClient.GetSessionConfiguration(ctx, &pb.GetSessionConfigurationRequest{
 		SessionId: sessionID,
@@ -199,7 +229,7 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 		// Render configurations
 		if len(configResp.Configurations) > 0 {
 			sgcInternalDir := sm.getSGCInternalDir(cmd.SGCID)
-			renderedFiles, err := sm.renderer.RenderConfigurations(configResp.Configurations, sgcInternalDir)
+			rendered, err = sm.renderer.Render(configResp.Configurations, sgcInternalDir)
 			if err != nil {
 				slog.Error("failed to render configurations", "session_id", sessionID, "error", err)
 				sm.cleanupSession(ctx, state)
@@ -209,9 +239,9 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 			}
 
 			// Write rendered files to disk
-			if len(renderedFiles) > 0 {
-				slog.Info("writing configuration files", "session_id", sessionID, "count", len(renderedFiles))
-				if err := sm.renderer.WriteRenderedFiles(renderedFiles); err != nil {
+			if len(rendered.Files) > 0 {
+				slog.Info("writing configuration files", "session_id", sessionID, "count", len(rendered.Files))
+				if err := sm.renderer.WriteRenderedFiles(rendered.Files); err != nil {
 					slog.Error("failed to write configuration files", "session_id", sessionID, "error", err)
 					sm.cleanupSession(ctx, state)
 					state.UpdateStatus(manman.SessionStatusCrashed)
@@ -223,6 +253,19 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 		}
 	}
 
+	// Resolve the input transport now so a game config whose RCON password can't be found
+	// fails to start instead of running without a way to send it commands.
+	command, env := containerCommandEnv(cmd, rendered)
+	input, err := resolveInputTransport(cmd.InputTransport, command, env, sm.getSGCInternalDir(cmd.SGCID))
+	if err != nil {
+		slog.Error("failed to set up input transport", "session_id", sessionID, "error", err)
+		sm.cleanupSession(ctx, state)
+		state.UpdateStatus(manman.SessionStatusCrashed)
+		sm.stateManager.RemoveSession(sessionID)
+		return &rmq.PermanentError{Err: fmt.Errorf("failed to set up input transport: %w", err)}
+	}
+	state.input = input
+
 	// 3. Download workshop addons from libraries (blocking)
 	if sm.workshopOrchestrator != nil {
 		slog.Info("downloading workshop addons from libraries", "session_id", sessionID, "sgc_id", cmd.SGCID)
@@ -250,21 +293,11 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 		slog.Info("workshop addons downloaded successfully", "session_id", sessionID)
 	}
 
-	// 4. Pull game image (always pull to ensure latest version is used)
-	// TODO: add cache fallback
-	slog.Info("pulling image", "session_id", sessionID, "image", cmd.Image)
-	const maxPullAttempts = 3
-	var pullErr error
-	for attempt := 1; attempt <= maxPullAttempts; attempt++ {
-		pullErr = sm.dockerClient.PullImage(ctx, cmd.Image)
-		if pullErr == nil {
-			break
-		}
-		slog.Warn("failed to pull image, retrying", "session_id", sessionID, "image", cmd.Image, "attempt", attempt, "error", pullErr)
-		if attempt < maxPullAttempts {
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-	}
+	// 4. Pull game image, falling back to the local copy if the registry can't be reached.
+	// Always pull: a session-level image tag override isn't pre-pulled, and a moved tag
+	// should be picked up on start.
+	slog.Info("ensuring image", "session_id", sessionID, "image", cmd.Image)
+	cached, pullErr := sm.dockerClient.EnsureImage(ctx, cmd.Image, docker.PullAlways, 3)
 	if pullErr != nil {
 		slog.Error("failed to pull image after retries", "session_id", sessionID, "image", cmd.Image, "error", pullErr)
 		sm.cleanupSession(ctx, state)
@@ -272,10 +305,13 @@ func (sm *SessionManager) StartSession(ctx context.Context, cmd *StartSessionCom
 		sm.stateManager.RemoveSession(sessionID)
 		return &rmq.PermanentError{Err: fmt.Errorf("failed to pull image %s: %w", cmd.Image, pullErr)}
 	}
+	if cached {
+		slog.Info("using local image", "session_id", sessionID, "image", cmd.Image)
+	}
 
 	// 5. Create game container
 	slog.Info("creating container", "session_id", sessionID, "image", cmd.Image)
-	containerID, err := sm.createGameContainer(ctx, state, cmd)
+	containerID, err := sm.createGameContainer(ctx, state, cmd, rendered)
 	if err != nil {
 		if isNameConflictError(err) {
 			if !cmd.Force {
@@ -417,6 +453,13 @@ func (sm *SessionManager) SendInput(ctx context.Context, sessionID int64, input
 	state.mu.Lock()
 	defer state.mu.Unlock()
 
+	if state.input != nil {
+		if state.input.err != nil {
+			return fmt.Errorf("input transport unavailable: %w", state.input.err)
+		}
+		return sm.sendRCON(ctx, state, state.input, input)
+	}
+
 	// Lazy attach: attach only when sending command
 	if state.AttachResp == nil {
 		slog.Debug("attaching to container for command", "session_id", sessionID)
@@ -445,8 +488,9 @@ func (sm *SessionManager) SendInput(ctx context.Context, sessionID int64, input
 	return nil
 }
 
-// createGameContainer creates the game container directly
-func (sm *SessionManager) createGameContainer(ctx context.Context, state *State, cmd *StartSessionCommand) (string, error) {
+// createGameContainer creates the game container directly.
+// rendered may be nil when the session has no configuration strategies.
+func (sm *SessionManager) createGameContainer(ctx context.Context, state *State, cmd *StartSessionCommand, rendered *config.RenderResult) (string, error) {
 	// Get paths for this SGC
 	sgcInternalDir := sm.getSGCInternalDir(state.SGCID) // Where to create dirs (inside this container)
 	sgcHostDir := sm.getSGCHostDir(state.SGCID)         // What to tell Docker (host path)
@@ -500,11 +544,18 @@ func (sm *SessionManager) createGameContainer(ctx context.Context, state *State,
 		volumes = append(volumes, mountStr)
 	}
 
-	config := docker.ContainerConfig{
+	command, env := containerCommandEnv(cmd, rendered)
+
+	settings, err := json.Marshal(settingsFromCommand(cmd))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session settings: %w", err)
+	}
+
+	containerConfig := docker.ContainerConfig{
 		Image:     cmd.Image,
 		Name:      sm.getContainerName(cmd.ServerID, cmd.SGCID),
-		Command:   cmd.Command,
-		Env:       cmd.Env,
+		Command:   command,
+		Env:       env,
 		NetworkID: state.NetworkID,
 		Volumes:   volumes,
 		Ports:     cmd.PortBindings,
@@ -515,12 +566,73 @@ func (sm *SessionManager) createGameContainer(ctx context.Context, state *State,
 			"manman.server_id":   fmt.Sprintf("%d", cmd.ServerID),
 			"manman.environment": sm.environment,
 			"manman.created_at":  time.Now().Format(time.RFC3339),
+			sessionSettingsLabel: string(settings),
 		},
 		OpenStdin:  true,
 		AutoRemove: false,
+		Resources:  cmd.Resources,
 	}
 
-	return sm.dockerClient.CreateContainer(ctx, config)
+	return sm.dockerClient.CreateContainer(ctx, containerConfig)
+}
+
+// mergeRenderedEnv merges env vars rendered from configuration strategies into the game
+// config's KEY=VALUE env. Rendered values win; keys are emitted in sorted order so the
+// container spec is stable between starts.
+func mergeRenderedEnv(env []string, rendered map[string]string) []string {
+	if len(rendered) == 0 {
+		return env
+	}
+
+	merged := make([]string, 0, len(env)+len(rendered))
+	for _, kv := range env {
+		key, _, _ := strings.Cut(kv, "=")
+		if _, overridden := rendered[key]; !overridden {
+			merged = append(merged, kv)
+		}
+	}
+
+	keys := make([]string, 0, len(rendered))
+	for key := range rendered {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		merged = append(merged, fmt.Sprintf("%s=%s", key, rendered[key]))
+	}
+	return merged
+}
+
+// containerCommandEnv returns the game container's final command and env, with the args
+// and env rendered from configuration strategies applied
+func containerCommandEnv(cmd *StartSessionCommand, rendered *config.RenderResult) ([]string, []string) {
+	command, env := cmd.Command, cmd.Env
+	if rendered != nil {
+		command = appendRenderedArgs(command, rendered.Args)
+		env = mergeRenderedEnv(env, rendered.Env)
+	}
+	return command, env
+}
+
+// appendRenderedArgs appends CLI args rendered from configuration strategies to the game
+// command. When the command is an args_template wrapped in a shell (/bin/sh -c "..."),
+// the args are quoted and appended to the script instead.
+func appendRenderedArgs(command []string, args []string) []string {
+	if len(args) == 0 {
+		return command
+	}
+
+	if len(command) == 3 && command[1] == "-c" && strings.HasSuffix(command[0], "sh") {
+		quoted := make([]string, len(args))
+		for i, arg := range args {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		return []string{command[0], command[1], command[2] + " " + strings.Join(quoted, " ")}
+	}
+
+	merged := make([]string, 0, len(command)+len(args))
+	merged = append(merged, command...)
+	return append(merged, args...)
 }
 
 func (sm *SessionManager) getNamedVolumeName(sgcID int64, volumeName string) string {
@@ -638,6 +750,8 @@ func (sm *SessionManager) startStreamReaderWithFormat(state *State, reader io.Re
 		}
 
 		addMessage := func(message, source string) {
+			state.readiness.observeLine(message)
+			state.players.observeLine(message)
 			mu.Lock()
 			logBuffer = append(logBuffer, message)
 			sourceBuffer = append(sourceBuffer, source)
@@ -707,7 +821,7 @@ func (sm *SessionManager) startStreamReaderWithFormat(state *State, reader io.Re
 
 // handleContainerExit is called when the output reader detects the stream has closed
 func (sm *SessionManager) handleContainerExit(state *State) {
-	if state.GetStatus() != "running" {
+	if status := state.GetStatus(); status != manman.SessionStatusRunning && status != manman.SessionStatusReady {
 		return // already stopping/stopped — not a crash
 	}
 	ctx := context.Background()
@@ -740,6 +854,8 @@ func (sm *SessionManager) handleContainerExit(state *State) {
 	// Remove session from state manager to allow new sessions for this SGC
 	sm.stateManager.RemoveSession(state.SessionID)
 	slog.Info("removed session from state manager after crash", "session_id", state.SessionID)
+
+	sm.scheduleRestart(state, exitCode != 0)
 }
 
 // CleanupOrphans performs a single pass of orphan game container cleanup
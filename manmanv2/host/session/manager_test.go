@@ -244,3 +244,60 @@ func TestVolumeTypePassedToDocker(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeRenderedEnv(t *testing.T) {
+	env := []string{"EULA=TRUE", "MAX_PLAYERS=10"}
+	rendered := map[string]string{"MAX_PLAYERS": "16", "SERVER_NAME": "Whale Net"}
+
+	got := mergeRenderedEnv(env, rendered)
+	want := []string{"EULA=TRUE", "MAX_PLAYERS=16", "SERVER_NAME=Whale Net"}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("env[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestAppendRenderedArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  []string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "no args leaves command alone",
+			command:  []string{"./server"},
+			expected: []string{"./server"},
+		},
+		{
+			name:     "args appended to command array",
+			command:  []string{"-dedicated"},
+			args:     []string{"-maxplayers", "16"},
+			expected: []string{"-dedicated", "-maxplayers", "16"},
+		},
+		{
+			name:     "args quoted into shell script",
+			command:  []string{"/bin/sh", "-c", "./start.sh"},
+			args:     []string{"+hostname", "Bob's Server"},
+			expected: []string{"/bin/sh", "-c", `./start.sh '+hostname' 'Bob'\''s Server'`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := appendRenderedArgs(tt.command, tt.args)
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Expected %q, got %q", tt.expected, got)
+			}
+			for i := range tt.expected {
+				if got[i] != tt.expected[i] {
+					t.Errorf("command[%d] = %q, want %q", i, got[i], tt.expected[i])
+				}
+			}
+		})
+	}
+}
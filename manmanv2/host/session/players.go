@@ -0,0 +1,194 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// playerCounter tracks a session's player count for idle shutdown. Log probes are fed each
+// log line by the stream reader; exec probes are polled by StartPlayerCount; query probes
+// are set by StartStatusQuery.
+type playerCounter struct {
+	probe   *manman.PlayerCountProbe
+	join    *regexp.Regexp
+	leave   *regexp.Regexp
+	count   *regexp.Regexp
+	changed chan struct{} // signalled (without blocking) whenever the count changes
+	mu      sync.Mutex
+	players int32
+}
+
+func newPlayerCounter(probe *manman.PlayerCountProbe) (*playerCounter, error) {
+	if probe == nil {
+		return nil, nil
+	}
+	if err := probe.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid player count probe: %w", err)
+	}
+	c := &playerCounter{probe: probe, changed: make(chan struct{}, 1)}
+	if probe.JoinPattern != "" {
+		c.join = regexp.MustCompile(probe.JoinPattern)
+	}
+	if probe.LeavePattern != "" {
+		c.leave = regexp.MustCompile(probe.LeavePattern)
+	}
+	if pattern := probe.Pattern(); pattern != "" {
+		c.count = regexp.MustCompile(pattern)
+	}
+	return c, nil
+}
+
+// observeLine updates a log probe's count from a log line. Safe on a nil counter.
+func (c *playerCounter) observeLine(line string) {
+	if c == nil || c.probe.Type != manman.PlayerCountProbeLog {
+		return
+	}
+	if n, ok := c.parseCount(line); ok {
+		c.set(n)
+		return
+	}
+	switch {
+	case c.join != nil && c.join.MatchString(line):
+		c.add(1)
+	case c.leave != nil && c.leave.MatchString(line):
+		c.add(-1)
+	}
+}
+
+// parseCount reads the count pattern's first capture group from text
+func (c *playerCounter) parseCount(text string) (int32, bool) {
+	if c.count == nil {
+		return 0, false
+	}
+	m := c.count.FindStringSubmatch(text)
+	if len(m) < 2 {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(m[1], 10, 32)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return int32(n), true
+}
+
+// add adjusts the count by delta, never going below zero (a leave can be logged for a
+// player who joined before the counter started).
+func (c *playerCounter) add(delta int32) {
+	c.mu.Lock()
+	n := c.players + delta
+	if n < 0 {
+		n = 0
+	}
+	c.mu.Unlock()
+	c.set(n)
+}
+
+func (c *playerCounter) set(n int32) {
+	c.mu.Lock()
+	changed := c.players != n
+	c.players = n
+	c.mu.Unlock()
+	if changed {
+		select {
+		case c.changed <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (c *playerCounter) get() int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.players
+}
+
+// StartPlayerCount reports a session's player count in the background for as long as it is
+// running: once at start (zero players), then on every change. Sessions without a player
+// count probe are skipped, as are query probes, which StartStatusQuery reports.
+func (sm *SessionManager) StartPlayerCount(sessionID int64) {
+	state, ok := sm.stateManager.GetSession(sessionID)
+	if !ok || state.players == nil || state.players.probe.Type == manman.PlayerCountProbeQuery {
+		return
+	}
+
+	go func() {
+		c := state.players
+		sm.publishPlayerCount(state, c.get())
+
+		// Log probes only need a periodic check that the session is still live
+		interval := c.probe.Interval()
+		if c.probe.Type == manman.PlayerCountProbeLog {
+			interval = 5 * time.Second
+		}
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-c.changed:
+				if !sessionLive(state) {
+					return
+				}
+				sm.publishPlayerCount(state, c.get())
+			case <-ticker.C:
+				if !sessionLive(state) {
+					return
+				}
+				if c.probe.Type == manman.PlayerCountProbeExec {
+					sm.execPlayerCount(state, c)
+				}
+			}
+		}
+	}()
+}
+
+// execPlayerCount runs an exec probe's command once and records the count it prints
+func (sm *SessionManager) execPlayerCount(state *State, c *playerCounter) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	exitCode, output, err := sm.dockerClient.ExecInContainer(ctx, state.GameContainerID, c.probe.Command)
+	if err != nil || exitCode != 0 {
+		slog.Debug("player count command failed", "session_id", state.SessionID, "exit_code", exitCode, "error", err)
+		return
+	}
+	n, ok := c.parseCount(output)
+	if !ok {
+		slog.Debug("player count not found in command output", "session_id", state.SessionID, "output", output)
+		return
+	}
+	c.set(n)
+}
+
+func (sm *SessionManager) publishPlayerCount(state *State, count int32) {
+	if sm.rmqPublisher == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	mapName, maxPlayers := state.server.get()
+	if err := sm.rmqPublisher.PublishPlayerCount(ctx, &hostrmq.PlayerCountUpdate{
+		SessionID:   state.SessionID,
+		SGCID:       state.SGCID,
+		PlayerCount: count,
+		MapName:     mapName,
+		MaxPlayers:  maxPlayers,
+		Timestamp:   time.Now(),
+	}); err != nil {
+		slog.Warn("failed to publish player count", "session_id", state.SessionID, "error", err)
+	}
+}
+
+// sessionLive reports whether the session is still running (ready or not)
+func sessionLive(state *State) bool {
+	status := state.GetStatus()
+	return status == manman.SessionStatusRunning || status == manman.SessionStatusReady
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+func TestPlayerCounterJoinLeave(t *testing.T) {
+	c, err := newPlayerCounter(&manman.PlayerCountProbe{
+		Type:         manman.PlayerCountProbeLog,
+		JoinPattern:  `joined the game`,
+		LeavePattern: `left the game`,
+	})
+	if err != nil {
+		t.Fatalf("newPlayerCounter failed: %v", err)
+	}
+
+	c.observeLine("alice left the game") // left before the counter started; must not go negative
+	c.observeLine("alice joined the game")
+	c.observeLine("bob joined the game")
+	c.observeLine("unrelated line")
+	c.observeLine("alice left the game")
+
+	if got := c.get(); got != 1 {
+		t.Errorf("Expected 1 player, got %d", got)
+	}
+}
+
+func TestPlayerCounterCountPatternOverrides(t *testing.T) {
+	c, _ := newPlayerCounter(&manman.PlayerCountProbe{
+		Type:         manman.PlayerCountProbeLog,
+		JoinPattern:  `joined`,
+		LeavePattern: `left`,
+		CountPattern: `There are (\d+) of a max`,
+	})
+
+	c.observeLine("steve joined")
+	c.observeLine("There are 4 of a max of 20 players online")
+	if got := c.get(); got != 4 {
+		t.Errorf("Expected count line to set 4 players, got %d", got)
+	}
+}
+
+func TestPlayerCounterSignalsChanges(t *testing.T) {
+	c, _ := newPlayerCounter(&manman.PlayerCountProbe{Type: manman.PlayerCountProbeLog, CountPattern: `players: (\d+)`})
+
+	c.observeLine("players: 0") // unchanged from the initial count
+	select {
+	case <-c.changed:
+		t.Fatal("Expected no change signal for an unchanged count")
+	default:
+	}
+
+	c.observeLine("players: 2")
+	c.observeLine("players: 3")
+	select {
+	case <-c.changed:
+	default:
+		t.Fatal("Expected a change signal")
+	}
+}
+
+func TestPlayerCounterExecOutput(t *testing.T) {
+	c, err := newPlayerCounter(&manman.PlayerCountProbe{Type: manman.PlayerCountProbeExec, Command: []string{"rcon", "list"}})
+	if err != nil {
+		t.Fatalf("newPlayerCounter failed: %v", err)
+	}
+	if n, ok := c.parseCount("Players online: 7/16"); !ok || n != 7 {
+		t.Errorf("Expected default pattern to read 7, got %d, %v", n, ok)
+	}
+	c.observeLine("players: 9") // exec probes ignore log lines
+	if got := c.get(); got != 0 {
+		t.Errorf("Expected exec counter to ignore logs, got %d", got)
+	}
+}
+
+func TestPlayerCounterNil(t *testing.T) {
+	c, err := newPlayerCounter(nil)
+	if err != nil || c != nil {
+		t.Fatalf("Expected nil counter without a probe, got %v, %v", c, err)
+	}
+	c.observeLine("nil counter ignores lines")
+
+	if _, err := newPlayerCounter(&manman.PlayerCountProbe{Type: manman.PlayerCountProbeLog}); err == nil {
+		t.Error("Expected a log probe without patterns to be rejected")
+	}
+}
@@ -0,0 +1,242 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"regexp"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// errNotRunning stops a probe when the session leaves running (stopped or crashed) before it passed.
+var errNotRunning = errors.New("session is no longer running")
+
+// readinessWatch tracks a session's readiness probe. Log probes are fed each log line by
+// the stream reader; the other probe types are polled by waitForReady.
+type readinessWatch struct {
+	probe   *manman.ReadinessProbe
+	pattern *regexp.Regexp
+	matched chan struct{}
+	once    sync.Once
+}
+
+func newReadinessWatch(probe *manman.ReadinessProbe) (*readinessWatch, error) {
+	if probe == nil {
+		return nil, nil
+	}
+	if err := probe.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid readiness probe: %w", err)
+	}
+	w := &readinessWatch{probe: probe, matched: make(chan struct{})}
+	if probe.Type == manman.ReadinessProbeLog {
+		w.pattern = regexp.MustCompile(probe.LogPattern)
+	}
+	return w, nil
+}
+
+// observeLine checks a log line against a log probe's pattern. Safe on a nil watch.
+func (w *readinessWatch) observeLine(line string) {
+	if w == nil || w.pattern == nil {
+		return
+	}
+	if w.pattern.MatchString(line) {
+		w.once.Do(func() { close(w.matched) })
+	}
+}
+
+// StartReadinessProbe waits in the background for a running session's readiness probe to pass
+// and then marks it ready. If the probe doesn't pass within its timeout the session is crashed.
+// Sessions without a probe are marked ready straight away. Call after publishing running so
+// ready can't overtake it.
+func (sm *SessionManager) StartReadinessProbe(sessionID int64) {
+	state, ok := sm.stateManager.GetSession(sessionID)
+	if !ok {
+		return
+	}
+
+	go func() {
+		err := sm.waitForReady(state)
+		if errors.Is(err, errNotRunning) {
+			slog.Info("session left running before readiness probe passed", "session_id", state.SessionID, "status", state.GetStatus())
+			return
+		}
+		if err != nil {
+			sm.failReadiness(state, err)
+			return
+		}
+
+		state.UpdateStatus(manman.SessionStatusReady)
+		slog.Info("session ready", "session_id", state.SessionID)
+		if sm.rmqPublisher != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := sm.rmqPublisher.PublishSessionStatus(ctx, &hostrmq.SessionStatusUpdate{
+				SessionID: state.SessionID, SGCID: state.SGCID, Status: manman.SessionStatusReady,
+			}); err != nil {
+				slog.Error("failed to publish ready status", "session_id", state.SessionID, "error", err)
+			}
+		}
+	}()
+}
+
+// waitForReady blocks until the session's probe passes, the probe times out, or the session
+// stops running.
+func (sm *SessionManager) waitForReady(state *State) error {
+	w := state.readiness
+	if w == nil {
+		return nil
+	}
+	probe := w.probe
+
+	ctx, cancel := context.WithTimeout(context.Background(), probe.Timeout())
+	defer cancel()
+
+	slog.Info("waiting for readiness probe", "session_id", state.SessionID, "type", probe.Type, "timeout", probe.Timeout())
+
+	// Log probes are checked every second so a stopped session is noticed quickly;
+	// the others are attempted once per interval.
+	interval := probe.Interval()
+	if probe.Type == manman.ReadinessProbeLog {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastErr error
+	for {
+		if state.GetStatus() != manman.SessionStatusRunning {
+			return errNotRunning
+		}
+		if probe.Type != manman.ReadinessProbeLog {
+			if lastErr = sm.probeOnce(ctx, state, probe); lastErr == nil {
+				return nil
+			}
+			slog.Debug("readiness probe not passing yet", "session_id", state.SessionID, "error", lastErr)
+		}
+
+		select {
+		case <-w.matched:
+			return nil
+		case <-ctx.Done():
+			if lastErr != nil {
+				return fmt.Errorf("readiness probe did not pass within %s: %w", probe.Timeout(), lastErr)
+			}
+			return fmt.Errorf("readiness probe did not pass within %s", probe.Timeout())
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeOnce makes a single tcp, udp or exec probe attempt
+func (sm *SessionManager) probeOnce(ctx context.Context, state *State, probe *manman.ReadinessProbe) error {
+	if probe.Type == manman.ReadinessProbeExec {
+		exitCode, output, err := sm.dockerClient.ExecInContainer(ctx, state.GameContainerID, probe.Command)
+		if err != nil {
+			return err
+		}
+		if exitCode != 0 {
+			return fmt.Errorf("command exited %d: %s", exitCode, output)
+		}
+		return nil
+	}
+
+	address, err := sm.containerAddress(ctx, state, probe.Port)
+	if err != nil {
+		return err
+	}
+	if probe.Type == manman.ReadinessProbeUDP {
+		return probeUDP(address, 2*time.Second)
+	}
+	return probeTCP(address, 2*time.Second)
+}
+
+// containerAddress returns host:port for a port on the session's container. Ports are
+// reached on the container's own address, so a host port binding isn't needed.
+func (sm *SessionManager) containerAddress(ctx context.Context, state *State, port int32) (string, error) {
+	status, err := sm.dockerClient.GetContainerStatus(ctx, state.GameContainerID)
+	if err != nil {
+		return "", err
+	}
+	if status.IPAddress == "" {
+		return "", fmt.Errorf("container has no IP address")
+	}
+	return net.JoinHostPort(status.IPAddress, strconv.Itoa(int(port))), nil
+}
+
+// probeTCP passes if the address accepts a connection
+func probeTCP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeUDP sends an empty datagram and passes unless the port is refused (ICMP port
+// unreachable). UDP has no handshake, so silence is treated as a listening server;
+// games that need a real query are better served by a log or exec probe.
+func probeUDP(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("udp", address, timeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte{0}); err != nil {
+		return err
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(timeout))
+	buf := make([]byte, 1)
+	if _, err := conn.Read(buf); err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			return nil
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return fmt.Errorf("port refused: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// failReadiness crashes a session whose probe didn't pass, mirroring handleContainerExit
+func (sm *SessionManager) failReadiness(state *State, probeErr error) {
+	if state.GetStatus() != manman.SessionStatusRunning {
+		return
+	}
+	state.UpdateStatus(manman.SessionStatusCrashed)
+	slog.Warn("readiness probe failed, marked crashed", "session_id", state.SessionID, "error", probeErr)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if sm.rmqPublisher != nil {
+		if err := sm.rmqPublisher.PublishSessionStatus(ctx, &hostrmq.SessionStatusUpdate{
+			SessionID: state.SessionID, SGCID: state.SGCID, Status: manman.SessionStatusCrashed,
+		}); err != nil {
+			slog.Error("failed to publish crashed status", "session_id", state.SessionID, "error", err)
+		}
+	}
+
+	if state.GameContainerID != "" {
+		timeout := 10 * time.Second
+		if err := sm.dockerClient.StopContainer(ctx, state.GameContainerID, &timeout); err != nil {
+			slog.Warn("failed to stop container after readiness failure", "session_id", state.SessionID, "error", err)
+		}
+		if err := sm.dockerClient.RemoveContainer(ctx, state.GameContainerID, true); err != nil {
+			slog.Warn("failed to remove container after readiness failure", "session_id", state.SessionID, "error", err)
+		}
+	}
+
+	sm.stateManager.RemoveSession(state.SessionID)
+	sm.scheduleRestart(state, true)
+}
@@ -0,0 +1,88 @@
+package session
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+func TestReadinessWatchLogProbe(t *testing.T) {
+	w, err := newReadinessWatch(&manman.ReadinessProbe{Type: manman.ReadinessProbeLog, LogPattern: `Server started on :\d+`, TimeoutSeconds: 5})
+	if err != nil {
+		t.Fatalf("newReadinessWatch failed: %v", err)
+	}
+	state := &State{SessionID: 1, Status: manman.SessionStatusRunning, readiness: w}
+	sm := &SessionManager{}
+
+	go func() {
+		w.observeLine("Loading world...")
+		w.observeLine("Server started on :7777")
+		w.observeLine("Server started on :7777") // matching twice must not panic
+	}()
+
+	if err := sm.waitForReady(state); err != nil {
+		t.Errorf("Expected probe to pass, got %v", err)
+	}
+}
+
+func TestReadinessWatchStopsWhenSessionStops(t *testing.T) {
+	w, _ := newReadinessWatch(&manman.ReadinessProbe{Type: manman.ReadinessProbeLog, LogPattern: "never"})
+	state := &State{SessionID: 1, Status: manman.SessionStatusStopping, readiness: w}
+
+	if err := (&SessionManager{}).waitForReady(state); !errors.Is(err, errNotRunning) {
+		t.Errorf("Expected errNotRunning, got %v", err)
+	}
+}
+
+func TestReadinessWatchNoProbe(t *testing.T) {
+	w, err := newReadinessWatch(nil)
+	if err != nil || w != nil {
+		t.Fatalf("Expected nil watch without a probe, got %v, %v", w, err)
+	}
+	w.observeLine("nil watch ignores lines")
+
+	if err := (&SessionManager{}).waitForReady(&State{Status: manman.SessionStatusRunning}); err != nil {
+		t.Errorf("Expected immediate readiness, got %v", err)
+	}
+}
+
+func TestNewReadinessWatchRejectsInvalidProbe(t *testing.T) {
+	if _, err := newReadinessWatch(&manman.ReadinessProbe{Type: manman.ReadinessProbeLog, LogPattern: "("}); err == nil {
+		t.Error("Expected invalid pattern to be rejected")
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := ln.Addr().String()
+
+	if err := probeTCP(addr, time.Second); err != nil {
+		t.Errorf("Expected listening port to pass, got %v", err)
+	}
+	ln.Close()
+	if err := probeTCP(addr, time.Second); err == nil {
+		t.Error("Expected closed port to fail")
+	}
+}
+
+func TestProbeUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	addr := conn.LocalAddr().String()
+
+	if err := probeUDP(addr, 200*time.Millisecond); err != nil {
+		t.Errorf("Expected listening port to pass, got %v", err)
+	}
+	conn.Close()
+	if err := probeUDP(addr, 200*time.Millisecond); err == nil {
+		t.Error("Expected closed port to be refused")
+	}
+}
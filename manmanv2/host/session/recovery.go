@@ -2,13 +2,87 @@ package session
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"strconv"
 
+	"github.com/whale-net/everything/libs/go/docker"
 	"github.com/whale-net/everything/manmanv2/models"
 )
 
+// sessionSettingsLabel holds a game container's sessionSettings as JSON
+const sessionSettingsLabel = "manman.session_settings"
+
+// sessionSettings are the parts of a StartSessionCommand the host keeps using while the
+// session runs. They are stored on the game container so a session recovered after a host
+// manager restart keeps its probes, restart policy and input transport. Passwords aren't
+// stored; the input transport's is resolved again from the container's command and env.
+type sessionSettings struct {
+	Readiness      *manman.ReadinessProbe   `json:"readiness,omitempty"`
+	Restart        *manman.RestartPolicy    `json:"restart,omitempty"`
+	RestartAttempt int32                    `json:"restart_attempt,omitempty"`
+	PlayerCount    *manman.PlayerCountProbe `json:"player_count,omitempty"`
+	InputTransport *manman.InputTransport   `json:"input_transport,omitempty"`
+	StatusQuery    *manman.StatusQuery      `json:"status_query,omitempty"`
+}
+
+func settingsFromCommand(cmd *StartSessionCommand) sessionSettings {
+	return sessionSettings{
+		Readiness:      cmd.Readiness,
+		Restart:        cmd.Restart,
+		RestartAttempt: cmd.RestartAttempt,
+		PlayerCount:    cmd.PlayerCount,
+		InputTransport: cmd.InputTransport,
+		StatusQuery:    cmd.StatusQuery,
+	}
+}
+
+// recoveredState rebuilds the state of a session from its running game container, as
+// running until its readiness probe passes again. A
+// container without settings (created before they were stored) recovers without probes,
+// restart policy or input transport, as it did before. dataDir is the SGC's data directory,
+// where an RCON password file is read from.
+func recoveredState(sessionID, sgcID int64, container *docker.ContainerStatus, dataDir string) *State {
+	state := &State{
+		SessionID:       sessionID,
+		SGCID:           sgcID,
+		GameContainerID: container.ContainerID,
+		AttachStrategy:  "persistent",
+		IsTTY:           true, // Always use TTY mode
+		Status:          manman.SessionStatusRunning,
+	}
+
+	raw, ok := container.Labels[sessionSettingsLabel]
+	if !ok {
+		slog.Warn("recovered container has no session settings", "session_id", sessionID)
+		return state
+	}
+	var settings sessionSettings
+	if err := json.Unmarshal([]byte(raw), &settings); err != nil {
+		slog.Warn("failed to decode recovered session settings", "session_id", sessionID, "error", err)
+		return state
+	}
+
+	var err error
+	if state.readiness, err = newReadinessWatch(settings.Readiness); err != nil {
+		slog.Warn("dropping invalid readiness probe of recovered session", "session_id", sessionID, "error", err)
+	}
+	if state.players, err = newPlayerCounter(settings.PlayerCount); err != nil {
+		slog.Warn("dropping invalid player count probe of recovered session", "session_id", sessionID, "error", err)
+	}
+	state.restart = settings.Restart
+	state.restartAttempt = settings.RestartAttempt
+	state.statusQuery = settings.StatusQuery
+	// The container is already running, so keep it; SendInput reports the error rather than
+	// falling back to stdin.
+	if state.input, err = resolveInputTransport(settings.InputTransport, container.Command, container.Env, dataDir); err != nil {
+		slog.Warn("input transport of recovered session unavailable", "session_id", sessionID, "error", err)
+		state.input.err = err
+	}
+	return state
+}
+
 // RecoverOrphanedSessions recovers sessions from existing game containers.
 // It looks for game containers directly — no wrapper involved.
 func (sm *SessionManager) RecoverOrphanedSessions(ctx context.Context, serverID int64) error {
@@ -76,21 +150,17 @@ func (sm *SessionManager) RecoverOrphanedSessions(ctx context.Context, serverID
 				continue
 			}
 
-			networkName := sm.getNetworkName(sessionID)
-			state := &State{
-				SessionID:       sessionID,
-				SGCID:           sgcID,
-				GameContainerID: game.ID,
-				LogReader:       logReader,
-				AttachResp:      nil, // Attached on first SendInput
-				AttachStrategy:  "persistent",
-				IsTTY:           true, // Always use TTY mode
-				NetworkName:     networkName,
-				Status:          manman.SessionStatusRunning,
-			}
+			state := recoveredState(sessionID, sgcID, status, sm.getSGCInternalDir(sgcID))
+			state.LogReader = logReader
+			state.NetworkName = sm.getNetworkName(sessionID)
 
 			sm.stateManager.AddSession(state)
 			sm.startLogReader(state)
+			// The game may have stopped serving while the host was down, so a recovered session
+			// is only ready again once its probe passes; log probes see the replayed logs.
+			sm.StartReadinessProbe(sessionID)
+			sm.StartPlayerCount(sessionID)
+			sm.StartStatusQuery(sessionID)
 			slog.Info("session recovered", "session_id", sessionID, "sgc_id", sgcID)
 		} else {
 			// Not running — nothing to recover, remove it
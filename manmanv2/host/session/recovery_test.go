@@ -0,0 +1,187 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/docker"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// fakeDockerDaemon serves the parts of the Docker API recovery and RCON input use, for one
+// running game container, on a unix socket. Returns the socket path.
+func fakeDockerDaemon(t *testing.T, containerID string, labels map[string]string, cmd, env []string) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+
+	version := regexp.MustCompile(`^/v[0-9.]+`)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := version.ReplaceAllString(r.URL.Path, "")
+		w.Header().Set("Api-Version", "1.43")
+		switch {
+		case path == "/_ping":
+			_, _ = io.WriteString(w, "OK")
+		case path == "/containers/json":
+			_ = json.NewEncoder(w).Encode([]map[string]interface{}{
+				{"Id": containerID, "Names": []string{"/game-1-5"}, "Labels": labels, "State": "running"},
+			})
+		case path == "/containers/"+containerID+"/json":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"Id":    containerID,
+				"Name":  "/game-1-5",
+				"State": map[string]interface{}{"Status": "running", "Running": true},
+				"Config": map[string]interface{}{
+					"Labels": labels, "Cmd": cmd, "Env": env,
+				},
+				"NetworkSettings": map[string]interface{}{
+					"Networks": map[string]interface{}{"bridge": map[string]interface{}{"IPAddress": "127.0.0.1"}},
+				},
+			})
+		case path == "/containers/"+containerID+"/logs":
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	})}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { srv.Close() })
+	return socket
+}
+
+// fakeRCONServer accepts password and records each command it runs
+func fakeRCONServer(t *testing.T, password string, commands chan<- string) int32 {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	write := func(conn net.Conn, id, packetType int32, body string) {
+		var buf bytes.Buffer
+		_ = binary.Write(&buf, binary.LittleEndian, int32(len(body)+10))
+		_ = binary.Write(&buf, binary.LittleEndian, id)
+		_ = binary.Write(&buf, binary.LittleEndian, packetType)
+		buf.WriteString(body)
+		buf.Write([]byte{0, 0})
+		_, _ = conn.Write(buf.Bytes())
+	}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var size int32
+			if err := binary.Read(conn, binary.LittleEndian, &size); err != nil {
+				return
+			}
+			packet := make([]byte, size)
+			if _, err := io.ReadFull(conn, packet); err != nil {
+				return
+			}
+			id := int32(binary.LittleEndian.Uint32(packet[0:4]))
+			body := string(bytes.TrimRight(packet[8:], "\x00"))
+			switch binary.LittleEndian.Uint32(packet[4:8]) {
+			case 3: // auth
+				if body != password {
+					id = -1
+				}
+				write(conn, id, 2, "")
+			case 2: // exec
+				commands <- body
+				write(conn, id, 0, "ok")
+			}
+		}
+	}()
+	return int32(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func TestRecoveredSessionKeepsSettings(t *testing.T) {
+	commands := make(chan string, 1)
+	port := fakeRCONServer(t, "secret", commands)
+	game, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer game.Close()
+	gamePort := int32(game.Addr().(*net.TCPAddr).Port)
+
+	cmd := &StartSessionCommand{
+		SessionID:      42,
+		SGCID:          5,
+		Readiness:      &manman.ReadinessProbe{Type: manman.ReadinessProbeTCP, Port: gamePort},
+		Restart:        &manman.RestartPolicy{Mode: manman.RestartPolicyOnFailure, MaxAttempts: 3},
+		RestartAttempt: 2,
+		InputTransport: &manman.InputTransport{Type: manman.InputTransportRCON, Port: port, PasswordArg: "+rcon_password"},
+		StatusQuery:    &manman.StatusQuery{Type: manman.StatusQueryA2S, Port: 27015},
+	}
+	settings, err := json.Marshal(settingsFromCommand(cmd))
+	if err != nil {
+		t.Fatalf("encode settings: %v", err)
+	}
+	labels := map[string]string{
+		"manman.type":        "game",
+		"manman.session_id":  "42",
+		"manman.sgc_id":      "5",
+		"manman.server_id":   "1",
+		sessionSettingsLabel: string(settings),
+	}
+	socket := fakeDockerDaemon(t, "abc123", labels, []string{"./srcds_run", "+rcon_password", "secret"}, nil)
+
+	dockerClient, err := docker.NewClient(socket)
+	if err != nil {
+		t.Fatalf("docker client: %v", err)
+	}
+	defer dockerClient.Close()
+	sm := NewSessionManager(dockerClient, "", t.TempDir(), nil, nil, &recordingPublisher{})
+
+	if err := sm.RecoverOrphanedSessions(context.Background(), 1); err != nil {
+		t.Fatalf("RecoverOrphanedSessions: %v", err)
+	}
+	state, ok := sm.GetSessionState(42)
+	if !ok {
+		t.Fatal("session was not recovered")
+	}
+	if state.readiness == nil || state.statusQuery == nil || state.restart == nil || state.restartAttempt != 2 {
+		t.Errorf("recovered session lost its settings: readiness %v, status query %v, restart %v, attempt %d",
+			state.readiness, state.statusQuery, state.restart, state.restartAttempt)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for state.GetStatus() != manman.SessionStatusReady {
+		select {
+		case <-ctx.Done():
+			t.Fatalf("recovered session status = %s, want ready once its probe passes", state.GetStatus())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	if err := sm.SendInput(ctx, 42, []byte("status\n")); err != nil {
+		t.Fatalf("SendInput: %v", err)
+	}
+	select {
+	case got := <-commands:
+		if strings.TrimSpace(got) != "status" {
+			t.Errorf("rcon command = %q, want %q", got, "status")
+		}
+	case <-ctx.Done():
+		t.Fatal("SendInput did not use rcon")
+	}
+}
@@ -0,0 +1,150 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// A restart the API refuses is retried restartRefusalRetries times, restartRefusalDelay apart
+const (
+	restartRefusalRetries = 3
+	restartRefusalDelay   = 5 * time.Second
+)
+
+// crashHistory remembers recent crash times per SGC, so a crash loop is detected across
+// the new sessions each restart creates.
+type crashHistory struct {
+	mu      sync.Mutex
+	crashes map[int64][]time.Time
+}
+
+func newCrashHistory() *crashHistory {
+	return &crashHistory{crashes: make(map[int64][]time.Time)}
+}
+
+// record adds a crash for the SGC and returns how many crashes it has had within window
+func (h *crashHistory) record(sgcID int64, at time.Time, window time.Duration) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	recent := h.crashes[sgcID][:0]
+	for _, t := range h.crashes[sgcID] {
+		if at.Sub(t) < window {
+			recent = append(recent, t)
+		}
+	}
+	recent = append(recent, at)
+	h.crashes[sgcID] = recent
+	return len(recent)
+}
+
+// nextRestartAttempt numbers the restart of a crashed session. A session that stayed up
+// longer than the crash window starts a new run of attempts.
+func nextRestartAttempt(policy *manman.RestartPolicy, current int32, startedAt *time.Time, crashedAt time.Time) int32 {
+	if startedAt != nil && crashedAt.Sub(*startedAt) >= policy.CrashWindow() {
+		return 1
+	}
+	return current + 1
+}
+
+// restartGiveUpReason returns why restart attempt should not happen, or "" if it should
+func restartGiveUpReason(policy *manman.RestartPolicy, attempt int32, crashesInWindow int) string {
+	if crashesInWindow >= policy.MaxCrashes() {
+		return fmt.Sprintf("crash loop: %d crashes within %s", crashesInWindow, policy.CrashWindow())
+	}
+	if limit := policy.Attempts(); limit > 0 && attempt > limit {
+		return fmt.Sprintf("reached max_attempts (%d)", limit)
+	}
+	return ""
+}
+
+// scheduleRestart applies the session's restart policy after its container exited
+// unexpectedly. The restart is requested from the API after the policy's backoff so it is
+// recorded as a new session linked to this one; the API sends the start command back to
+// this host. Call after the session has been removed from the state manager.
+func (sm *SessionManager) scheduleRestart(state *State, failed bool) {
+	policy := state.restart
+	if !policy.RestartsAfter(failed) {
+		return
+	}
+
+	now := time.Now()
+	crashes := sm.crashes.record(state.SGCID, now, policy.CrashWindow())
+	attempt := nextRestartAttempt(policy, state.restartAttempt, state.StartedAt, now)
+	if reason := restartGiveUpReason(policy, attempt, crashes); reason != "" {
+		sm.abandonRestart(state, attempt, reason)
+		return
+	}
+
+	delay := policy.Backoff(attempt)
+	slog.Info("scheduling session restart", "session_id", state.SessionID, "sgc_id", state.SGCID,
+		"attempt", attempt, "delay", delay, "mode", policy.Mode)
+
+	go func() {
+		time.Sleep(delay)
+
+		// A manual start during the backoff replaces the restart
+		if existing, ok := sm.stateManager.GetSessionBySGCID(state.SGCID); ok {
+			slog.Info("skipping restart, SGC already has an active session",
+				"session_id", state.SessionID, "active_session_id", existing.SessionID)
+			return
+		}
+
+		// The API only restarts a session it has recorded as crashed, and the crashed status
+		// published on exit may still be in flight, so a refusal is retried a few times
+		var resp *pb.StartSessionResponse
+		var err error
+		for try := 0; ; try++ {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			resp, err = sm.grpcClient.StartSession(ctx, &pb.StartSessionRequest{
+				ServerGameConfigId: state.SGCID,
+				PreviousSessionId:  state.SessionID,
+				RestartAttempt:     attempt,
+			})
+			cancel()
+			if status.Code(err) != codes.FailedPrecondition || try >= restartRefusalRetries {
+				break
+			}
+			time.Sleep(restartRefusalDelay)
+		}
+		if err != nil {
+			if status.Code(err) == codes.FailedPrecondition {
+				slog.Info("skipping restart, SGC was started elsewhere", "session_id", state.SessionID, "error", err)
+				return
+			}
+			sm.abandonRestart(state, attempt, fmt.Sprintf("restart request failed: %v", err))
+			return
+		}
+		slog.Info("requested session restart", "session_id", state.SessionID,
+			"new_session_id", resp.Session.GetSessionId(), "attempt", attempt)
+	}()
+}
+
+// abandonRestart publishes that the host gave up restarting a crashed session
+func (sm *SessionManager) abandonRestart(state *State, attempt int32, reason string) {
+	slog.Warn("giving up restarting session", "session_id", state.SessionID, "sgc_id", state.SGCID,
+		"attempt", attempt, "reason", reason)
+	if sm.rmqPublisher == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := sm.rmqPublisher.PublishRestartAbandoned(ctx, &hostrmq.RestartAbandonedUpdate{
+		SessionID: state.SessionID,
+		SGCID:     state.SGCID,
+		Attempt:   attempt,
+		Reason:    reason,
+	}); err != nil {
+		slog.Error("failed to publish restart abandoned", "session_id", state.SessionID, "error", err)
+	}
+}
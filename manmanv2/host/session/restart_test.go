@@ -0,0 +1,130 @@
+package session
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+type recordingPublisher struct {
+	abandoned    []*hostrmq.RestartAbandonedUpdate
+	playerCounts []*hostrmq.PlayerCountUpdate
+}
+
+func (p *recordingPublisher) PublishLog(ctx context.Context, sessionID int64, source string, message string) error {
+	return nil
+}
+
+func (p *recordingPublisher) PublishSessionStatus(ctx context.Context, update *hostrmq.SessionStatusUpdate) error {
+	return nil
+}
+
+func (p *recordingPublisher) PublishRestartAbandoned(ctx context.Context, update *hostrmq.RestartAbandonedUpdate) error {
+	p.abandoned = append(p.abandoned, update)
+	return nil
+}
+
+func (p *recordingPublisher) PublishPlayerCount(ctx context.Context, update *hostrmq.PlayerCountUpdate) error {
+	p.playerCounts = append(p.playerCounts, update)
+	return nil
+}
+
+func TestCrashHistoryCountsWithinWindow(t *testing.T) {
+	h := newCrashHistory()
+	start := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	window := 10 * time.Minute
+
+	h.record(1, start, window)
+	h.record(1, start.Add(4*time.Minute), window)
+	h.record(2, start.Add(5*time.Minute), window)
+
+	if got := h.record(1, start.Add(8*time.Minute), window); got != 3 {
+		t.Errorf("Expected 3 crashes within the window, got %d", got)
+	}
+	// The first crash falls out of the window
+	if got := h.record(1, start.Add(12*time.Minute), window); got != 3 {
+		t.Errorf("Expected 3 crashes after the oldest expired, got %d", got)
+	}
+}
+
+func TestNextRestartAttempt(t *testing.T) {
+	policy := &manman.RestartPolicy{Mode: manman.RestartPolicyOnFailure, CrashWindowSeconds: 600}
+	crashedAt := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	shortRun := crashedAt.Add(-time.Minute)
+	if got := nextRestartAttempt(policy, 2, &shortRun, crashedAt); got != 3 {
+		t.Errorf("Expected attempt 3 after a short run, got %d", got)
+	}
+	longRun := crashedAt.Add(-time.Hour)
+	if got := nextRestartAttempt(policy, 2, &longRun, crashedAt); got != 1 {
+		t.Errorf("Expected attempts to reset after a long run, got %d", got)
+	}
+	if got := nextRestartAttempt(policy, 0, nil, crashedAt); got != 1 {
+		t.Errorf("Expected attempt 1 for a session that never started, got %d", got)
+	}
+}
+
+func TestRestartGiveUpReason(t *testing.T) {
+	onFailure := &manman.RestartPolicy{Mode: manman.RestartPolicyOnFailure, MaxAttempts: 3, MaxCrashesInWindow: 5}
+	always := &manman.RestartPolicy{Mode: manman.RestartPolicyAlways, MaxCrashesInWindow: 5}
+
+	tests := []struct {
+		name    string
+		policy  *manman.RestartPolicy
+		attempt int32
+		crashes int
+		want    string
+	}{
+		{"within limits", onFailure, 3, 1, ""},
+		{"max attempts", onFailure, 4, 1, "max_attempts"},
+		{"crash loop", onFailure, 1, 5, "crash loop"},
+		{"always has no attempt limit", always, 50, 1, ""},
+		{"always still detects crash loops", always, 2, 5, "crash loop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := restartGiveUpReason(tt.policy, tt.attempt, tt.crashes)
+			if tt.want == "" && got != "" {
+				t.Errorf("Expected restart, got give up: %s", got)
+			}
+			if tt.want != "" && !strings.Contains(got, tt.want) {
+				t.Errorf("Expected give up reason containing %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestScheduleRestartPublishesGiveUp(t *testing.T) {
+	publisher := &recordingPublisher{}
+	sm := &SessionManager{stateManager: NewManager(), crashes: newCrashHistory(), rmqPublisher: publisher}
+	policy := &manman.RestartPolicy{Mode: manman.RestartPolicyOnFailure, MaxAttempts: 2}
+
+	sm.scheduleRestart(&State{SessionID: 7, SGCID: 3, restart: policy, restartAttempt: 2}, true)
+
+	if len(publisher.abandoned) != 1 {
+		t.Fatalf("Expected one restart abandoned event, got %d", len(publisher.abandoned))
+	}
+	if got := publisher.abandoned[0]; got.SessionID != 7 || got.SGCID != 3 || got.Attempt != 3 {
+		t.Errorf("Unexpected restart abandoned event: %+v", got)
+	}
+}
+
+func TestScheduleRestartIgnoresCleanExitOnFailure(t *testing.T) {
+	publisher := &recordingPublisher{}
+	sm := &SessionManager{stateManager: NewManager(), crashes: newCrashHistory(), rmqPublisher: publisher}
+	policy := &manman.RestartPolicy{Mode: manman.RestartPolicyOnFailure}
+
+	sm.scheduleRestart(&State{SessionID: 7, SGCID: 3, restart: policy}, false)
+	sm.scheduleRestart(&State{SessionID: 8, SGCID: 3}, true)
+
+	if len(publisher.abandoned) != 0 {
+		t.Errorf("Expected no restart events, got %d", len(publisher.abandoned))
+	}
+	if got := sm.crashes.record(3, time.Now(), time.Minute); got != 1 {
+		t.Errorf("Expected sessions that aren't restarted not to count as crashes, got %d", got)
+	}
+}
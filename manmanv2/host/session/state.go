@@ -6,23 +6,31 @@ import (
 	"time"
 
 	"github.com/docker/docker/api/types"
+	"github.com/whale-net/everything/manmanv2/models"
 )
 
 // State represents the state of a session
 type State struct {
 	SessionID       int64
 	SGCID           int64
-	Status          string // "pending" | "starting" | "running" | "stopping" | "stopped" | "crashed"
+	Status          string // "pending" | "starting" | "running" | "ready" | "stopping" | "stopped" | "crashed"
 	NetworkID       string
 	NetworkName     string
 	GameContainerID string
-	LogReader       io.ReadCloser               // Docker logs API stream for stdout/stderr
-	AttachResp      *types.HijackedResponse     // stdin attach; nil until command is sent
-	AttachStrategy  string                      // "lazy" | "persistent"
-	IsTTY           bool                        // Whether container uses TTY mode
+	LogReader       io.ReadCloser           // Docker logs API stream for stdout/stderr
+	AttachResp      *types.HijackedResponse // stdin attach; nil until command is sent
+	AttachStrategy  string                  // "lazy" | "persistent"
+	IsTTY           bool                    // Whether container uses TTY mode
 	StartedAt       *time.Time
 	StoppedAt       *time.Time
 	ExitCode        *int
+	readiness       *readinessWatch       // nil when the game config has no readiness probe
+	players         *playerCounter        // nil when the SGC has no idle shutdown rule
+	restart         *manman.RestartPolicy // nil when the SGC has no restart policy
+	restartAttempt  int32                 // 0 unless this session is an automatic restart
+	input           *inputTransport       // nil = commands go to the container's stdin
+	statusQuery     *manman.StatusQuery   // nil when the game config has no status query
+	server          serverInfo            // last status query result
 	mu              sync.RWMutex
 }
 
@@ -131,6 +139,7 @@ type SessionStats struct {
 	Pending  int
 	Starting int
 	Running  int
+	Ready    int
 	Stopping int
 	Stopped  int
 	Crashed  int
@@ -153,6 +162,8 @@ func (m *Manager) GetSessionStats() SessionStats {
 			stats.Starting++
 		case "running":
 			stats.Running++
+		case "ready":
+			stats.Ready++
 		case "stopping":
 			stats.Stopping++
 		case "stopped":
@@ -0,0 +1,100 @@
+package session
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/host/query"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// serverInfo is what the session's status query last reported besides the player count
+type serverInfo struct {
+	mu         sync.Mutex
+	mapName    string
+	maxPlayers int32
+}
+
+// set records a status query result and reports whether it differs from the last one
+func (s *serverInfo) set(mapName string, maxPlayers int32) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.mapName != mapName || s.maxPlayers != maxPlayers
+	s.mapName, s.maxPlayers = mapName, maxPlayers
+	return changed
+}
+
+func (s *serverInfo) get() (string, int32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.mapName, s.maxPlayers
+}
+
+// StartStatusQuery polls a session's status query in the background for as long as it is
+// running, publishing the player count, map and max players whenever they change. The
+// queried count is used unless the SGC counts players from logs or exec output. Sessions
+// without a status query are skipped.
+func (sm *SessionManager) StartStatusQuery(sessionID int64) {
+	state, ok := sm.stateManager.GetSession(sessionID)
+	if !ok {
+		return
+	}
+	if state.statusQuery == nil {
+		if state.players != nil && state.players.probe.Type == manman.PlayerCountProbeQuery {
+			slog.Warn("player count probe uses the status query but the game config has none", "session_id", sessionID)
+		}
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(state.statusQuery.Interval())
+		defer ticker.Stop()
+
+		lastCount := int32(-1)
+		for {
+			if !sessionLive(state) {
+				return
+			}
+			if status, ok := sm.queryStatus(state); ok {
+				infoChanged := state.server.set(status.Map, status.MaxPlayers)
+				count := status.Players
+				if c := state.players; c != nil && c.probe.Type != manman.PlayerCountProbeQuery {
+					count = c.get()
+				} else if c != nil {
+					c.set(count)
+				}
+				if infoChanged || count != lastCount {
+					lastCount = count
+					sm.publishPlayerCount(state, count)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// queryStatus runs the session's status query once
+func (sm *SessionManager) queryStatus(state *State) (*query.Status, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	address, err := sm.containerAddress(ctx, state, state.statusQuery.Port)
+	if err != nil {
+		slog.Debug("status query address unavailable", "session_id", state.SessionID, "error", err)
+		return nil, false
+	}
+	var status *query.Status
+	switch state.statusQuery.Type {
+	case manman.StatusQueryMinecraft:
+		status, err = query.MinecraftStatus(ctx, address)
+	default:
+		status, err = query.A2SInfo(ctx, address)
+	}
+	if err != nil {
+		slog.Debug("status query failed", "session_id", state.SessionID, "type", state.statusQuery.Type, "error", err)
+		return nil, false
+	}
+	return status, true
+}
@@ -0,0 +1,241 @@
+package workshop
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fetchHTTP downloads an addon source's file into tempDownloadDir, verifies its checksum,
+// and unpacks or moves it into stagingDir
+func (do *DownloadOrchestrator) fetchHTTP(ctx context.Context, logger *slog.Logger, cmd *DownloadAddonCommand, tempDownloadDir, stagingDir string) error {
+	fileName := filepath.Base(cmd.FileName)
+	if cmd.FileName == "" || fileName == "." || fileName == ".." || fileName == string(filepath.Separator) {
+		return fmt.Errorf("invalid download file name %q", cmd.FileName)
+	}
+	hasher, err := newChecksumHash(cmd.ChecksumAlgorithm)
+	if err != nil {
+		return err
+	}
+	if hasher == nil || cmd.Checksum == "" {
+		return fmt.Errorf("download of %s has no checksum to verify it against", cmd.DownloadURL)
+	}
+
+	downloadDir := filepath.Join(tempDownloadDir, "download")
+	if err := os.MkdirAll(downloadDir, 0777); err != nil {
+		return fmt.Errorf("failed to create download directory: %w", err)
+	}
+	downloadPath := filepath.Join(downloadDir, fileName)
+
+	logger.Info("downloading addon", "url", cmd.DownloadURL, "file", fileName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cmd.DownloadURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create download request: %w", err)
+	}
+	resp, err := do.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", cmd.DownloadURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download of %s returned status %d", cmd.DownloadURL, resp.StatusCode)
+	}
+
+	f, err := os.Create(downloadPath)
+	if err != nil {
+		return fmt.Errorf("failed to create download file: %w", err)
+	}
+	progress := &progressWriter{total: resp.ContentLength, report: func(percent int) {
+		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusDownloading, percent, nil)
+	}}
+	_, copyErr := io.Copy(io.MultiWriter(f, progress, hasher), resp.Body)
+	closeErr := f.Close()
+	if copyErr != nil {
+		return fmt.Errorf("failed to download %s: %w", cmd.DownloadURL, copyErr)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write download file: %w", closeErr)
+	}
+
+	if got := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(got, cmd.Checksum) {
+		logger.Error("checksum mismatch", "algorithm", cmd.ChecksumAlgorithm, "expected", cmd.Checksum, "got", got)
+		return fmt.Errorf("%s checksum mismatch for %s: expected %s, got %s", cmd.ChecksumAlgorithm, fileName, cmd.Checksum, got)
+	}
+
+	if cmd.Extract {
+		logger.Info("extracting archive", "file", fileName, "to", stagingDir)
+		if err := extractArchive(downloadPath, stagingDir); err != nil {
+			return fmt.Errorf("failed to extract %s: %w", fileName, err)
+		}
+		return nil
+	}
+
+	dstFile := filepath.Join(stagingDir, fileName)
+	if err := os.Rename(downloadPath, dstFile); err != nil {
+		if err := copyFile(downloadPath, dstFile); err != nil {
+			return fmt.Errorf("failed to stage %s: %w", fileName, err)
+		}
+	}
+	return nil
+}
+
+// newChecksumHash returns the hash for a checksum algorithm, or nil when there is none
+func newChecksumHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case "":
+		return nil, nil
+	case "sha1":
+		return sha1.New(), nil
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	}
+	return nil, fmt.Errorf("unsupported checksum algorithm %q", algorithm)
+}
+
+// progressWriter reports download progress in steps of 10 percent
+type progressWriter struct {
+	total   int64 // -1 or 0 when the server didn't say
+	written int64
+	last    int
+	report  func(percent int)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.written += int64(len(b))
+	if p.total > 0 {
+		if percent := int(p.written * 100 / p.total); percent >= p.last+10 && percent < 100 {
+			p.last = percent - percent%10
+			p.report(p.last)
+		}
+	}
+	return len(b), nil
+}
+
+// extractArchive unpacks a zip or (gzipped) tar archive into dst. Entries that would land
+// outside dst are rejected, and links are skipped.
+func extractArchive(path, dst string) error {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return extractZip(path, dst)
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		return extractTar(gz, dst)
+	case strings.HasSuffix(name, ".tar"):
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return extractTar(f, dst)
+	}
+	return fmt.Errorf("unsupported archive type: %s", filepath.Base(path))
+}
+
+func extractZip(path, dst string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	for _, zf := range r.File {
+		target, err := archiveEntryPath(dst, zf.Name)
+		if err != nil {
+			return err
+		}
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(target, 0777); err != nil {
+				return err
+			}
+		case mode.IsRegular():
+			rc, err := zf.Open()
+			if err != nil {
+				return err
+			}
+			err = writeArchiveFile(target, rc, mode.Perm())
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func extractTar(r io.Reader, dst string) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target, err := archiveEntryPath(dst, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0777); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := writeArchiveFile(target, tr, os.FileMode(header.Mode).Perm()); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// archiveEntryPath joins an archive entry's name onto dst, refusing names that escape it
+func archiveEntryPath(dst, name string) (string, error) {
+	target := filepath.Join(dst, name)
+	if target != filepath.Clean(dst) && !strings.HasPrefix(target, filepath.Clean(dst)+string(filepath.Separator)) {
+		return "", fmt.Errorf("archive entry %q escapes the install directory", name)
+	}
+	return target, nil
+}
+
+func writeArchiveFile(target string, r io.Reader, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0777); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
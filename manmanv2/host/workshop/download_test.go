@@ -0,0 +1,168 @@
+package workshop
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func tarGzArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// serveFiles stubs an addon source's file host
+func serveFiles(t *testing.T, files map[string][]byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFetchHTTP(t *testing.T) {
+	jar := []byte("fabric mod jar")
+	modZip := zipArchive(t, map[string]string{"QualityOfLife/mod.jar": "necesse mod", "QualityOfLife/info.txt": "info"})
+	srv := serveFiles(t, map[string][]byte{
+		"/sodium.jar": jar,
+		"/qol.zip":    modZip,
+	})
+
+	publisher := &MockInstallationStatusPublisher{}
+	orchestrator := NewDownloadOrchestrator(nil, nil, nil, 1, "test", "/tmp/test", "/var/lib/test", 1, publisher)
+	ctx := context.Background()
+
+	t.Run("file with checksum", func(t *testing.T) {
+		tempDir, stagingDir := t.TempDir(), t.TempDir()
+		cmd := &DownloadAddonCommand{
+			InstallationID:    1,
+			DownloadURL:       srv.URL + "/sodium.jar",
+			FileName:          "sodium.jar",
+			ChecksumAlgorithm: "sha256",
+			Checksum:          sha256Hex(jar),
+		}
+		require.NoError(t, orchestrator.fetchHTTP(ctx, slog.Default(), cmd, tempDir, stagingDir))
+		got, err := os.ReadFile(filepath.Join(stagingDir, "sodium.jar"))
+		require.NoError(t, err)
+		assert.Equal(t, jar, got)
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		tempDir, stagingDir := t.TempDir(), t.TempDir()
+		cmd := &DownloadAddonCommand{
+			DownloadURL:       srv.URL + "/sodium.jar",
+			FileName:          "sodium.jar",
+			ChecksumAlgorithm: "sha256",
+			Checksum:          sha256Hex([]byte("something else")),
+		}
+		err := orchestrator.fetchHTTP(ctx, slog.Default(), cmd, tempDir, stagingDir)
+		assert.ErrorContains(t, err, "checksum mismatch")
+		entries, _ := os.ReadDir(stagingDir)
+		assert.Empty(t, entries, "nothing is staged when the checksum fails")
+	})
+
+	t.Run("archive is extracted", func(t *testing.T) {
+		tempDir, stagingDir := t.TempDir(), t.TempDir()
+		cmd := &DownloadAddonCommand{
+			DownloadURL:       srv.URL + "/qol.zip",
+			FileName:          "qol.zip",
+			ChecksumAlgorithm: "sha256",
+			Checksum:          sha256Hex(modZip),
+			Extract:           true,
+		}
+		require.NoError(t, orchestrator.fetchHTTP(ctx, slog.Default(), cmd, tempDir, stagingDir))
+		got, err := os.ReadFile(filepath.Join(stagingDir, "QualityOfLife", "mod.jar"))
+		require.NoError(t, err)
+		assert.Equal(t, "necesse mod", string(got))
+	})
+
+	t.Run("no checksum", func(t *testing.T) {
+		stagingDir := t.TempDir()
+		cmd := &DownloadAddonCommand{DownloadURL: srv.URL + "/sodium.jar", FileName: "sodium.jar"}
+		err := orchestrator.fetchHTTP(ctx, slog.Default(), cmd, t.TempDir(), stagingDir)
+		assert.ErrorContains(t, err, "no checksum")
+		entries, _ := os.ReadDir(stagingDir)
+		assert.Empty(t, entries, "nothing is staged without a checksum")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		cmd := &DownloadAddonCommand{DownloadURL: srv.URL + "/gone.jar", FileName: "gone.jar", ChecksumAlgorithm: "sha256", Checksum: sha256Hex(jar)}
+		err := orchestrator.fetchHTTP(ctx, slog.Default(), cmd, t.TempDir(), t.TempDir())
+		assert.ErrorContains(t, err, "status 404")
+	})
+
+	t.Run("unknown checksum algorithm", func(t *testing.T) {
+		cmd := &DownloadAddonCommand{DownloadURL: srv.URL + "/sodium.jar", FileName: "sodium.jar", ChecksumAlgorithm: "md5", Checksum: "x"}
+		err := orchestrator.fetchHTTP(ctx, slog.Default(), cmd, t.TempDir(), t.TempDir())
+		assert.ErrorContains(t, err, "unsupported checksum algorithm")
+	})
+}
+
+func TestExtractArchive(t *testing.T) {
+	dir := t.TempDir()
+
+	tgz := filepath.Join(dir, "maps.tar.gz")
+	require.NoError(t, os.WriteFile(tgz, tarGzArchive(t, map[string]string{"maps/one.bsp": "map"}), 0644))
+	dst := filepath.Join(dir, "tgz")
+	require.NoError(t, extractArchive(tgz, dst))
+	got, err := os.ReadFile(filepath.Join(dst, "maps", "one.bsp"))
+	require.NoError(t, err)
+	assert.Equal(t, "map", string(got))
+
+	// Entries may not climb out of the install directory
+	evil := filepath.Join(dir, "evil.zip")
+	require.NoError(t, os.WriteFile(evil, zipArchive(t, map[string]string{"../../escaped.txt": "x"}), 0644))
+	assert.ErrorContains(t, extractArchive(evil, filepath.Join(dir, "zip")), "escapes the install directory")
+	_, err = os.Stat(filepath.Join(dir, "..", "escaped.txt"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.ErrorContains(t, extractArchive(filepath.Join(dir, "mod.rar"), dst), "unsupported archive type")
+}
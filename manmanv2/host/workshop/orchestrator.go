@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"net/http"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -32,6 +33,7 @@ type DownloadOrchestrator struct {
 	maxConcurrent   int
 	semaphore       chan struct{}
 	rmqPublisher    InstallationStatusPublisher
+	httpClient      *http.Client // downloads addon source files
 
 	// In-progress download tracking to prevent duplicates
 	inProgressMutex     sync.RWMutex
@@ -43,14 +45,21 @@ type InstallationStatusPublisher interface {
 	PublishInstallationStatus(ctx context.Context, update *rmq.InstallationStatusUpdate) error
 }
 
-// DownloadAddonCommand is received via RabbitMQ from control plane
+// DownloadAddonCommand is received via RabbitMQ from control plane.
+// Addons from an addon source carry a DownloadURL; the rest are fetched with SteamCMD.
 type DownloadAddonCommand struct {
-	InstallationID int64  `json:"installation_id"`
-	SGCID          int64  `json:"sgc_id"`
-	AddonID        int64  `json:"addon_id"`
-	WorkshopID     string `json:"workshop_id"`
-	SteamAppID     string `json:"steam_app_id"`
-	InstallPath    string `json:"install_path"`
+	InstallationID    int64  `json:"installation_id"`
+	SGCID             int64  `json:"sgc_id"`
+	AddonID           int64  `json:"addon_id"`
+	WorkshopID        string `json:"workshop_id"`
+	SteamAppID        string `json:"steam_app_id"`
+	InstallPath       string `json:"install_path"`
+	PlatformType      string `json:"platform_type,omitempty"`
+	DownloadURL       string `json:"download_url,omitempty"`
+	FileName          string `json:"file_name,omitempty"`
+	ChecksumAlgorithm string `json:"checksum_algorithm,omitempty"`
+	Checksum          string `json:"checksum,omitempty"`
+	Extract           bool   `json:"extract,omitempty"`
 }
 
 // Installation status constants
@@ -85,6 +94,7 @@ func NewDownloadOrchestrator(
 		maxConcurrent:       maxConcurrent,
 		semaphore:           make(chan struct{}, maxConcurrent),
 		rmqPublisher:        rmqPublisher,
+		httpClient:          &http.Client{Timeout: 30 * time.Minute},
 		inProgressDownloads: make(map[int64]bool),
 	}
 }
@@ -111,11 +121,110 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 	do.semaphore <- struct{}{}
 	defer func() { <-do.semaphore }()
 
-	logger.Info("starting workshop addon download")
+	logger.Info("starting workshop addon download", "platform_type", cmd.PlatformType)
 
 	// Update status to downloading
 	do.publishStatus(ctx, cmd.InstallationID, InstallationStatusDownloading, 0, nil)
 
+	// Create temporary download directory.
+	// Downloads land here first; their files are gathered into a staging subdir
+	// before installing to the target volume.
+	tempSuffix := fmt.Sprintf("%d-%d", cmd.AddonID, time.Now().Unix())
+	tempDownloadDir := filepath.Join(do.getSGCInternalDir(cmd.SGCID), ".workshop-temp", tempSuffix)
+	if err := os.MkdirAll(tempDownloadDir, 0777); err != nil {
+		logger.Error("failed to create temp download directory", "error", err)
+		do.handleDownloadError(ctx, cmd.InstallationID, err)
+		return err
+	}
+	defer os.RemoveAll(tempDownloadDir) // Clean up temp dir after download
+
+	// tempHostDir and tempDownloadDir point to the same location from Docker's and
+	// the host-manager's perspectives respectively.
+	tempHostDir := filepath.Join(do.getSGCHostDir(cmd.SGCID), ".workshop-temp", tempSuffix)
+
+	// Stage downloaded files into a subdirectory of the temp dir so they are
+	// accessible via a bind mount regardless of target volume type.
+	stagingDir := filepath.Join(tempDownloadDir, "staging")
+	if err := os.MkdirAll(stagingDir, 0777); err != nil {
+		errMsg := fmt.Sprintf("failed to create staging directory: %v", err)
+		logger.Error("extraction failed", "error", err)
+		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
+		return fmt.Errorf("%s", errMsg)
+	}
+
+	var err error
+	if cmd.DownloadURL != "" {
+		err = do.fetchHTTP(ctx, logger, cmd, tempDownloadDir, stagingDir)
+	} else {
+		err = do.fetchSteamWorkshop(ctx, logger, cmd, tempDownloadDir, tempHostDir, stagingDir)
+	}
+	if err != nil {
+		do.handleDownloadError(ctx, cmd.InstallationID, err)
+		return err
+	}
+
+	// Resolve where this install path lives (bind-mount or named volume)
+	target, err := do.resolveInstallTarget(ctx, cmd.SGCID, cmd.InstallPath)
+	if err != nil {
+		errMsg := fmt.Sprintf("failed to resolve install path: %v", err)
+		logger.Error("extraction failed", "error", err, "container_path", cmd.InstallPath)
+		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
+		return fmt.Errorf("%s", errMsg)
+	}
+
+	// Install staged files into the target volume.
+	if target.isNamed {
+		// Named volume: use a busybox helper container so Docker manages the copy natively.
+		// The staging dir is bind-mounted and the named volume is mounted at its container path.
+		stagingHostDir := filepath.Join(tempHostDir, "staging")
+		destPath := target.ContainerPath
+		if target.RelPath != "" {
+			destPath = filepath.Join(destPath, target.RelPath)
+		}
+		copyCmd := fmt.Sprintf("mkdir -p %s && cp -r /tmp/workshop-staging/. %s/", destPath, destPath)
+		helperConfig := docker.ContainerConfig{
+			Name:  fmt.Sprintf("workshop-install-%s-%d-%d", do.environment, cmd.SGCID, cmd.AddonID),
+			Image: "busybox:latest",
+			Command: []string{"sh", "-c", copyCmd},
+			Volumes: []string{
+				fmt.Sprintf("%s:/tmp/workshop-staging", stagingHostDir),
+				fmt.Sprintf("%s:%s", target.VolumeName, target.ContainerPath),
+			},
+		}
+		logger.Info("copying to named volume via helper container", "volume", target.VolumeName, "dest", destPath)
+		if err := do.runHelperContainer(ctx, helperConfig); err != nil {
+			errMsg := fmt.Sprintf("failed to copy files into named volume: %v", err)
+			logger.Error("extraction failed", "error", err)
+			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
+			return fmt.Errorf("%s", errMsg)
+		}
+	} else {
+		// Bind-mount volume: merge files into the resolved host path.
+		// Use copyDirectory rather than moveDirectory so that previously installed
+		// addons sharing the same install path are not wiped out.
+		installPath := target.BindPath
+		if err := os.MkdirAll(installPath, 0777); err != nil {
+			errMsg := fmt.Sprintf("failed to create install directory: %v", err)
+			logger.Error("extraction failed", "error", err)
+			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
+			return fmt.Errorf("%s", errMsg)
+		}
+		if err := do.copyDirectory(stagingDir, installPath); err != nil {
+			errMsg := fmt.Sprintf("failed to copy files to install path: %v", err)
+			logger.Error("extraction failed", "error", err)
+			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
+			return fmt.Errorf("%s", errMsg)
+		}
+	}
+
+	logger.Info("download completed successfully")
+	do.publishStatus(ctx, cmd.InstallationID, InstallationStatusInstalled, 100, nil)
+	return nil
+}
+
+// fetchSteamWorkshop downloads a Steam Workshop item with a SteamCMD container into
+// tempDownloadDir and moves its content into stagingDir
+func (do *DownloadOrchestrator) fetchSteamWorkshop(ctx context.Context, logger *slog.Logger, cmd *DownloadAddonCommand, tempDownloadDir, tempHostDir, stagingDir string) error {
 	// Build download container configuration with environment-aware naming
 	containerName := do.getDownloadContainerName(cmd.SGCID, cmd.AddonID)
 
@@ -130,27 +239,12 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 	volumeMounts, err := do.resolveVolumeMounts(ctx, cmd.SGCID)
 	if err != nil {
 		logger.Error("failed to resolve volume mounts", "error", err)
-		do.handleDownloadError(ctx, cmd.InstallationID, err)
-		return err
-	}
-
-	// Create temporary download directory for SteamCMD.
-	// SteamCMD creates steamapps/workshop/content/<appid>/<workshopid>/ structure;
-	// we extract from there to a staging subdir before installing to the target volume.
-	tempSuffix := fmt.Sprintf("%d-%d", cmd.AddonID, time.Now().Unix())
-	tempDownloadDir := filepath.Join(do.getSGCInternalDir(cmd.SGCID), ".workshop-temp", tempSuffix)
-	if err := os.MkdirAll(tempDownloadDir, 0777); err != nil {
-		logger.Error("failed to create temp download directory", "error", err)
-		do.handleDownloadError(ctx, cmd.InstallationID, err)
 		return err
 	}
-	defer os.RemoveAll(tempDownloadDir) // Clean up temp dir after download
 
 	// Mount temp directory into container at /tmp/workshop-download.
-	// tempHostDir and tempDownloadDir point to the same location from Docker's and
-	// the host-manager's perspectives respectively.
+	// SteamCMD creates steamapps/workshop/content/<appid>/<workshopid>/ structure in it.
 	containerTempDir := "/tmp/workshop-download"
-	tempHostDir := filepath.Join(do.getSGCHostDir(cmd.SGCID), ".workshop-temp", tempSuffix)
 	volumeMounts = append(volumeMounts, fmt.Sprintf("%s:%s", tempHostDir, containerTempDir))
 
 	// Build SteamCMD command with container temp directory
@@ -164,32 +258,21 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 		Env:     []string{},
 	}
 
-	// Always pull steamcmd image to ensure latest version is used
-	// TODO: add cache fallback
+	// Pull steamcmd to stay current, but a cached copy will do if the registry is down
 	logger.Info("pulling steamcmd image", "image", steamCMDImage)
-	const maxPullAttempts = 3
-	var pullErr error
-	for attempt := 1; attempt <= maxPullAttempts; attempt++ {
-		pullErr = do.dockerClient.PullImage(ctx, steamCMDImage)
-		if pullErr == nil {
-			break
-		}
-		logger.Warn("failed to pull steamcmd image, retrying", "image", steamCMDImage, "attempt", attempt, "error", pullErr)
-		if attempt < maxPullAttempts {
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-	}
+	cached, pullErr := do.dockerClient.EnsureImage(ctx, steamCMDImage, docker.PullAlways, 3)
 	if pullErr != nil {
 		logger.Error("failed to pull steamcmd image after retries", "error", pullErr)
-		do.handleDownloadError(ctx, cmd.InstallationID, pullErr)
 		return pullErr
 	}
+	if cached {
+		logger.Warn("using cached steamcmd image", "image", steamCMDImage)
+	}
 
 	// Create container (image already pulled above)
 	containerID, err := do.dockerClient.CreateContainer(ctx, containerConfig)
 	if err != nil {
 		logger.Error("failed to create download container", "error", err)
-		do.handleDownloadError(ctx, cmd.InstallationID, err)
 		return err
 	}
 
@@ -197,7 +280,6 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 	err = do.dockerClient.StartContainer(ctx, containerID)
 	if err != nil {
 		logger.Error("failed to start download container", "error", err)
-		do.handleDownloadError(ctx, cmd.InstallationID, err)
 		return err
 	}
 
@@ -207,7 +289,6 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 	logReader, err := do.dockerClient.GetContainerLogs(ctx, containerID, true, "all")
 	if err != nil {
 		logger.Error("failed to get container logs", "error", err)
-		do.handleDownloadError(ctx, cmd.InstallationID, err)
 		return err
 	}
 	defer logReader.Close()
@@ -245,48 +326,24 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 	// Clean up container
 	_ = do.dockerClient.RemoveContainer(ctx, containerID, true)
 
-	// Update installation status
 	if exitCode != 0 {
-		errMsg := fmt.Sprintf("download failed with exit code %d", exitCode)
 		logger.Error("download failed", "exit_code", exitCode)
-		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-		return fmt.Errorf("%s", errMsg)
+		return fmt.Errorf("download failed with exit code %d", exitCode)
 	}
 
-	// Extract files from SteamCMD's nested structure to final install path
+	// Extract files from SteamCMD's nested structure to the staging dir
 	steamContentDir := filepath.Join(tempDownloadDir, "steamapps", "workshop", "content", cmd.SteamAppID, cmd.WorkshopID)
-	
+
 	// Check if directory exists and has content
 	entries, err := os.ReadDir(steamContentDir)
 	if err != nil {
 		errMsg := fmt.Sprintf("downloaded content not found at expected path: %s (error: %v)", steamContentDir, err)
 		logger.Error("extraction failed", "error", errMsg, "temp_dir", tempDownloadDir)
-		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
 		return fmt.Errorf("%s", errMsg)
 	}
 	if len(entries) == 0 {
 		errMsg := fmt.Sprintf("downloaded content directory is empty: %s", steamContentDir)
 		logger.Error("extraction failed", "error", errMsg, "temp_dir", tempDownloadDir)
-		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-		return fmt.Errorf("%s", errMsg)
-	}
-
-	// Resolve where this install path lives (bind-mount or named volume)
-	target, err := do.resolveInstallTarget(ctx, cmd.SGCID, cmd.InstallPath)
-	if err != nil {
-		errMsg := fmt.Sprintf("failed to resolve install path: %v", err)
-		logger.Error("extraction failed", "error", err, "container_path", cmd.InstallPath)
-		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-		return fmt.Errorf("%s", errMsg)
-	}
-
-	// Stage extracted files into a subdirectory of the temp dir so they are
-	// accessible via the existing bind mount regardless of target volume type.
-	stagingDir := filepath.Join(tempDownloadDir, "staging")
-	if err := os.MkdirAll(stagingDir, 0777); err != nil {
-		errMsg := fmt.Sprintf("failed to create staging directory: %v", err)
-		logger.Error("extraction failed", "error", err)
-		do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
 		return fmt.Errorf("%s", errMsg)
 	}
 
@@ -309,69 +366,17 @@ func (do *DownloadOrchestrator) HandleDownloadCommand(ctx context.Context, cmd *
 
 		if err := os.Rename(srcFile, dstFile); err != nil {
 			if err := copyFile(srcFile, dstFile); err != nil {
-				errMsg := fmt.Sprintf("failed to copy workshop file: %v", err)
 				logger.Error("extraction failed", "error", err)
-				do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-				return fmt.Errorf("%s", errMsg)
+				return fmt.Errorf("failed to copy workshop file: %v", err)
 			}
 			os.Remove(srcFile)
 		}
 	} else {
 		if err := do.moveDirectory(steamContentDir, stagingDir); err != nil {
-			errMsg := fmt.Sprintf("failed to extract workshop content: %v", err)
-			logger.Error("extraction failed", "error", err)
-			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-			return fmt.Errorf("%s", errMsg)
-		}
-	}
-
-	// Install staged files into the target volume.
-	if target.isNamed {
-		// Named volume: use a busybox helper container so Docker manages the copy natively.
-		// The staging dir is bind-mounted and the named volume is mounted at its container path.
-		stagingHostDir := filepath.Join(tempHostDir, "staging")
-		destPath := target.ContainerPath
-		if target.RelPath != "" {
-			destPath = filepath.Join(destPath, target.RelPath)
-		}
-		copyCmd := fmt.Sprintf("mkdir -p %s && cp -r /tmp/workshop-staging/. %s/", destPath, destPath)
-		helperConfig := docker.ContainerConfig{
-			Name:  fmt.Sprintf("workshop-install-%s-%d-%d", do.environment, cmd.SGCID, cmd.AddonID),
-			Image: "busybox:latest",
-			Command: []string{"sh", "-c", copyCmd},
-			Volumes: []string{
-				fmt.Sprintf("%s:/tmp/workshop-staging", stagingHostDir),
-				fmt.Sprintf("%s:%s", target.VolumeName, target.ContainerPath),
-			},
-		}
-		logger.Info("copying to named volume via helper container", "volume", target.VolumeName, "dest", destPath)
-		if err := do.runHelperContainer(ctx, helperConfig); err != nil {
-			errMsg := fmt.Sprintf("failed to copy files into named volume: %v", err)
-			logger.Error("extraction failed", "error", err)
-			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-			return fmt.Errorf("%s", errMsg)
-		}
-	} else {
-		// Bind-mount volume: merge files into the resolved host path.
-		// Use copyDirectory rather than moveDirectory so that previously installed
-		// addons sharing the same install path are not wiped out.
-		installPath := target.BindPath
-		if err := os.MkdirAll(installPath, 0777); err != nil {
-			errMsg := fmt.Sprintf("failed to create install directory: %v", err)
 			logger.Error("extraction failed", "error", err)
-			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-			return fmt.Errorf("%s", errMsg)
-		}
-		if err := do.copyDirectory(stagingDir, installPath); err != nil {
-			errMsg := fmt.Sprintf("failed to copy files to install path: %v", err)
-			logger.Error("extraction failed", "error", err)
-			do.publishStatus(ctx, cmd.InstallationID, InstallationStatusFailed, 0, &errMsg)
-			return fmt.Errorf("%s", errMsg)
+			return fmt.Errorf("failed to extract workshop content: %v", err)
 		}
 	}
-
-	logger.Info("download completed successfully")
-	do.publishStatus(ctx, cmd.InstallationID, InstallationStatusInstalled, 100, nil)
 	return nil
 }
 
@@ -634,21 +639,9 @@ func (do *DownloadOrchestrator) runHelperContainer(ctx context.Context, config d
 		_ = do.dockerClient.RemoveContainer(ctx, existing.ContainerID, true)
 	}
 
-	// Always pull helper image to ensure latest version is used
-	// TODO: add cache fallback
-	const maxHelperPullAttempts = 3
-	var helperPullErr error
-	for attempt := 1; attempt <= maxHelperPullAttempts; attempt++ {
-		helperPullErr = do.dockerClient.PullImage(ctx, config.Image)
-		if helperPullErr == nil {
-			break
-		}
-		if attempt < maxHelperPullAttempts {
-			time.Sleep(time.Duration(attempt) * time.Second)
-		}
-	}
-	if helperPullErr != nil {
-		return fmt.Errorf("failed to pull helper image %s: %w", config.Image, helperPullErr)
+	// Pull the helper image to stay current, but a cached copy will do if the registry is down
+	if _, err := do.dockerClient.EnsureImage(ctx, config.Image, docker.PullAlways, 3); err != nil {
+		return fmt.Errorf("failed to pull helper image %s: %w", config.Image, err)
 	}
 
 	containerID, err := do.dockerClient.CreateContainer(ctx, config)
@@ -983,6 +976,14 @@ func (do *DownloadOrchestrator) EnsureLibraryAddonsInstalled(ctx context.Context
 			WorkshopID:     addon.WorkshopId,
 			SteamAppID:     addon.SteamAppId,
 			InstallPath:    installResp.Installation.InstallationPath,
+			PlatformType:   addon.PlatformType,
+		}
+		if dl := installResp.Download; dl != nil {
+			cmd.DownloadURL = dl.Url
+			cmd.FileName = dl.FileName
+			cmd.ChecksumAlgorithm = dl.ChecksumAlgorithm
+			cmd.Checksum = dl.Checksum
+			cmd.Extract = dl.Extract
 		}
 
 		// Download addon (blocking)
@@ -0,0 +1,171 @@
+// Package alerts evaluates a session's alert rules against its live log lines and
+// publishes the rules that fire for the processor to record and act on.
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/rmq"
+)
+
+// Rule is a compiled alert rule
+type Rule struct {
+	ID        int64
+	re        *regexp.Regexp
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+}
+
+// NewRule compiles pattern. The API validates rules on save, so an error here means the
+// rule was stored some other way.
+func NewRule(id int64, pattern string, threshold, windowSeconds, cooldownSeconds int32) (*Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for rule %d: %w", id, err)
+	}
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Rule{
+		ID:        id,
+		re:        re,
+		threshold: int(threshold),
+		window:    time.Duration(windowSeconds) * time.Second,
+		cooldown:  time.Duration(cooldownSeconds) * time.Second,
+	}, nil
+}
+
+// same reports whether other is the same rule with the same definition
+func (r *Rule) same(other *Rule) bool {
+	return r.ID == other.ID && r.re.String() == other.re.String() && r.threshold == other.threshold &&
+		r.window == other.window && r.cooldown == other.cooldown
+}
+
+// Firing is a rule reaching its threshold on a session. It is the body of the
+// status.alert.<session_id> message.
+type Firing struct {
+	RuleID       int64     `json:"rule_id"`
+	SessionID    int64     `json:"session_id"`
+	SGCID        int64     `json:"sgc_id"`
+	MatchCount   int       `json:"match_count"`
+	FirstMatchAt time.Time `json:"first_match_at"`
+	Line         string    `json:"line"` // the line that reached the threshold
+	FiredAt      time.Time `json:"fired_at"`
+}
+
+type ruleState struct {
+	rule      *Rule
+	matches   []time.Time // within the window, oldest first
+	lastFired time.Time
+}
+
+// Evaluator tracks one session's rules. Lines are observed in log order, so the
+// window slides on log timestamps rather than the wall clock.
+type Evaluator struct {
+	sessionID int64
+	sgcID     int64
+
+	mu    sync.Mutex
+	rules map[int64]*ruleState
+}
+
+func NewEvaluator(sessionID, sgcID int64) *Evaluator {
+	return &Evaluator{sessionID: sessionID, sgcID: sgcID, rules: make(map[int64]*ruleState)}
+}
+
+// SetRules replaces the rules. Unchanged rules keep their matches and cooldown, so a
+// periodic refresh doesn't reset them.
+func (e *Evaluator) SetRules(rules []*Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	next := make(map[int64]*ruleState, len(rules))
+	for _, rule := range rules {
+		if state, ok := e.rules[rule.ID]; ok && state.rule.same(rule) {
+			next[rule.ID] = state
+			continue
+		}
+		next[rule.ID] = &ruleState{rule: rule}
+	}
+	e.rules = next
+}
+
+// Len returns the number of rules
+func (e *Evaluator) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.rules)
+}
+
+// Observe matches a line logged at at against every rule and returns those that fire.
+// A rule fires when threshold lines match within its window and its cooldown has
+// passed; its window then starts over.
+func (e *Evaluator) Observe(at time.Time, line string) []Firing {
+	line = strings.TrimRight(line, "\r\n")
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var firings []Firing
+	for _, state := range e.rules {
+		if !state.rule.re.MatchString(line) {
+			continue
+		}
+
+		// Drop matches that have slid out of the window
+		cutoff := at.Add(-state.rule.window)
+		kept := state.matches[:0]
+		for _, t := range state.matches {
+			if t.After(cutoff) {
+				kept = append(kept, t)
+			}
+		}
+		state.matches = append(kept, at)
+
+		if len(state.matches) < state.rule.threshold {
+			continue
+		}
+		if !state.lastFired.IsZero() && at.Sub(state.lastFired) < state.rule.cooldown {
+			continue
+		}
+		firings = append(firings, Firing{
+			RuleID:       state.rule.ID,
+			SessionID:    e.sessionID,
+			SGCID:        e.sgcID,
+			MatchCount:   len(state.matches),
+			FirstMatchAt: state.matches[0],
+			Line:         line,
+			FiredAt:      at,
+		})
+		state.lastFired = at
+		state.matches = nil
+	}
+	return firings
+}
+
+// Publisher sends firings to the processor over the manman exchange
+type Publisher struct {
+	publisher *rmq.Publisher
+}
+
+func NewPublisher(conn *rmq.Connection) (*Publisher, error) {
+	publisher, err := rmq.NewPublisher(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create alert publisher: %w", err)
+	}
+	return &Publisher{publisher: publisher}, nil
+}
+
+func (p *Publisher) Publish(ctx context.Context, firing Firing) error {
+	return p.publisher.Publish(ctx, "manman", fmt.Sprintf("status.alert.%d", firing.SessionID), firing)
+}
+
+func (p *Publisher) Close() error {
+	return p.publisher.Close()
+}
@@ -0,0 +1,92 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func mustRule(t *testing.T, id int64, pattern string, threshold, window, cooldown int32) *Rule {
+	t.Helper()
+	r, err := NewRule(id, pattern, threshold, window, cooldown)
+	if err != nil {
+		t.Fatalf("NewRule failed: %v", err)
+	}
+	return r
+}
+
+func TestEvaluatorThresholdWindowCooldown(t *testing.T) {
+	e := NewEvaluator(7, 3)
+	e.SetRules([]*Rule{mustRule(t, 1, `(?i)out of memory`, 3, 60, 300)})
+
+	observe := func(offset time.Duration, line string) []Firing {
+		return e.Observe(t0.Add(offset), line)
+	}
+
+	// Two matches, then a third after the first has left the window
+	observe(0, "Out of memory")
+	observe(30*time.Second, "unrelated")
+	observe(40*time.Second, "out of memory")
+	if f := observe(70*time.Second, "OUT OF MEMORY"); len(f) != 0 {
+		t.Fatalf("fired with the first match outside the window: %+v", f)
+	}
+
+	f := observe(80*time.Second, "java.lang.OutOfMemoryError: out of memory\r\n")
+	if len(f) != 1 {
+		t.Fatalf("got %d firings, want 1", len(f))
+	}
+	want := Firing{
+		RuleID:       1,
+		SessionID:    7,
+		SGCID:        3,
+		MatchCount:   3,
+		FirstMatchAt: t0.Add(40 * time.Second),
+		Line:         "java.lang.OutOfMemoryError: out of memory",
+		FiredAt:      t0.Add(80 * time.Second),
+	}
+	if f[0] != want {
+		t.Errorf("firing = %+v, want %+v", f[0], want)
+	}
+
+	// The window starts over and the cooldown holds back the next burst
+	for i := 0; i < 3; i++ {
+		if f := observe(time.Duration(90+i)*time.Second, "out of memory"); len(f) != 0 {
+			t.Fatalf("fired during cooldown: %+v", f)
+		}
+	}
+	observe(400*time.Second, "out of memory")
+	observe(401*time.Second, "out of memory")
+	if f := observe(402*time.Second, "out of memory"); len(f) != 1 {
+		t.Errorf("got %d firings after the cooldown, want 1", len(f))
+	}
+}
+
+func TestEvaluatorSetRulesKeepsState(t *testing.T) {
+	e := NewEvaluator(7, 3)
+	e.SetRules([]*Rule{mustRule(t, 1, `crash`, 2, 60, 0), mustRule(t, 2, `corrupt`, 1, 60, 0)})
+	e.Observe(t0, "crash")
+
+	// A refresh with the same definition keeps the first match; rule 2 is gone
+	e.SetRules([]*Rule{mustRule(t, 1, `crash`, 2, 60, 0)})
+	if e.Len() != 1 {
+		t.Fatalf("Len = %d, want 1", e.Len())
+	}
+	if f := e.Observe(t0.Add(time.Second), "crash corrupt"); len(f) != 1 || f[0].RuleID != 1 {
+		t.Fatalf("got %+v, want rule 1 only", f)
+	}
+
+	// A changed definition starts over
+	e.Observe(t0.Add(2*time.Second), "crash")
+	e.SetRules([]*Rule{mustRule(t, 1, `crash`, 3, 60, 0)})
+	e.Observe(t0.Add(3*time.Second), "crash")
+	if f := e.Observe(t0.Add(4*time.Second), "crash"); len(f) != 0 {
+		t.Fatalf("changed rule kept its old matches: %+v", f)
+	}
+}
+
+func TestNewRuleInvalidPattern(t *testing.T) {
+	if _, err := NewRule(1, "(", 1, 60, 0); err == nil {
+		t.Error("NewRule accepted an invalid pattern")
+	}
+}
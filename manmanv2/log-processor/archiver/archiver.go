@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
 	"sync"
 	"time"
 
@@ -24,6 +25,11 @@ type MinuteWindow struct {
 	FirstLogTime    time.Time
 	LastLogTime     time.Time
 	mu              sync.Mutex
+
+	// Search index of the window's lines; terms is dropped once it outgrows MaxLogWindowTerms
+	sources   map[string]bool
+	terms     map[string]bool
+	unindexed bool
 }
 
 // AddLog adds a log line to the window
@@ -39,9 +45,45 @@ func (w *MinuteWindow) AddLog(timestamp time.Time, source, message string) {
 		w.LastLogTime = timestamp
 	}
 
-	// Format: [timestamp] [source] message
-	fmt.Fprintf(&w.Buffer, "[%s] [%s] %s\n", timestamp.Format(time.RFC3339), source, message)
+	w.Buffer.WriteString(manman.FormatArchivedLogLine(timestamp, source, message))
 	w.LineCount++
+	w.index(source, message)
+}
+
+func (w *MinuteWindow) index(source, message string) {
+	if w.sources == nil {
+		w.sources = make(map[string]bool)
+		w.terms = make(map[string]bool)
+	}
+	w.sources[source] = true
+	if w.unindexed {
+		return
+	}
+	for _, term := range manman.LogTerms(message) {
+		w.terms[term] = true
+	}
+	if len(w.terms) > manman.MaxLogWindowTerms {
+		w.terms = nil
+		w.unindexed = true
+	}
+}
+
+// SearchIndex returns the sources and distinct words of the window's lines, sorted.
+// terms is nil when the window had too many words to index.
+func (w *MinuteWindow) SearchIndex() (sources, terms []string) {
+	for source := range w.sources {
+		sources = append(sources, source)
+	}
+	sort.Strings(sources)
+	if w.unindexed {
+		return sources, nil
+	}
+	terms = make([]string, 0, len(w.terms))
+	for term := range w.terms {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+	return sources, terms
 }
 
 // GetKey returns the MinuteWindow's unique key
@@ -320,6 +362,12 @@ func (a *Archiver) uploadWindow(ctx context.Context, window *MinuteWindow) error
 			len(logData), len(compressedData), 100.0*(1-float64(len(compressedData))/float64(len(logData))))
 	}
 
+	// Index the lines this upload added; a window without an index is still searched, just never skipped
+	sources, terms := window.SearchIndex()
+	if err := a.logRepo.SetSearchIndex(ctx, logRef.LogID, sources, terms); err != nil {
+		log.Printf("Failed to index window %s for search: %v", window.GetKey(), err)
+	}
+
 	// Mark as complete
 	if err := a.logRepo.UpdateState(ctx, logRef.LogID, manman.LogStateComplete); err != nil {
 		return fmt.Errorf("failed to update log state: %w", err)
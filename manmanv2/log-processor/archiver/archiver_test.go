@@ -498,3 +498,27 @@ func TestFlushSessionMultipleWindows(t *testing.T) {
 	// 3. Then all minute windows for that session are uploaded to S3
 	// 4. And each minute gets its own S3 object
 }
+
+// TestWindowSearchIndex tests that a window indexes the sources and words of its lines
+func TestWindowSearchIndex(t *testing.T) {
+	minute := time.Date(2026, 2, 10, 15, 30, 0, 0, time.UTC)
+	window := &MinuteWindow{MinuteTimestamp: minute}
+	window.AddLog(minute.Add(5*time.Second), "stdout", "Player Alice joined")
+	window.AddLog(minute.Add(6*time.Second), "stderr", "ERROR: alice timed out")
+
+	sources, terms := window.SearchIndex()
+	if strings.Join(sources, ",") != "stderr,stdout" {
+		t.Errorf("sources = %v, want [stderr stdout]", sources)
+	}
+	if got, want := strings.Join(terms, ","), "alice,error,joined,out,player,timed"; got != want {
+		t.Errorf("terms = %s, want %s", got, want)
+	}
+
+	// A window with too many distinct words isn't indexed, so searches always scan it
+	for i := 0; i <= manman.MaxLogWindowTerms; i++ {
+		window.AddLog(minute.Add(7*time.Second), "stdout", fmt.Sprintf("word%d", i))
+	}
+	if sources, terms := window.SearchIndex(); terms != nil || len(sources) != 2 {
+		t.Errorf("after overflow got sources %v and %d terms, want 2 sources and no terms", sources, len(terms))
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/whale-net/everything/libs/go/rmq"
+	"github.com/whale-net/everything/manmanv2/log-processor/alerts"
+	"github.com/whale-net/everything/manmanv2/log-processor/players"
+	"github.com/whale-net/everything/manmanv2/models"
 	manmanpb "github.com/whale-net/everything/manmanv2/protos"
 )
 
@@ -33,6 +36,13 @@ type SessionConsumer struct {
 	done          chan struct{}
 	logsProcessed *int64 // Pointer to manager's counter for atomic increment
 
+	// players extracts join/leave/chat events from log lines; nil when the game has no
+	// player event patterns or no recorder is configured
+	players *players.Extractor
+
+	// alerts evaluates the SGC's alert rules; nil when no alert publisher is configured
+	alerts *alerts.Evaluator
+
 	// idleSince is set when subscriber count drops to zero.
 	// Zero value means the consumer has active subscribers.
 	idleSince time.Time
@@ -66,6 +76,8 @@ type Manager struct {
 	config        *ConsumerConfig
 	grpcClient    manmanpb.ManManAPIClient
 	archiver      Archiver
+	players       PlayerRecorder
+	alerts        AlertPublisher
 	logsProcessed int64 // Total logs processed (atomic)
 	statsCtx      context.Context
 	statsCancel   context.CancelFunc
@@ -81,6 +93,17 @@ type Archiver interface {
 	FlushSession(ctx context.Context, sessionID int64) error
 }
 
+// PlayerRecorder is the interface for recording player events extracted from logs
+type PlayerRecorder interface {
+	Record(ctx context.Context, sessionID, sgcID int64, event players.Event) error
+	EndSession(ctx context.Context, sessionID int64, at time.Time) error
+}
+
+// AlertPublisher is the interface for publishing fired alert rules
+type AlertPublisher interface {
+	Publish(ctx context.Context, firing alerts.Firing) error
+}
+
 // ConsumerConfig holds configuration for consumers
 type ConsumerConfig struct {
 	LogBufferTTL     int
@@ -88,8 +111,8 @@ type ConsumerConfig struct {
 	DebugLogOutput   bool
 }
 
-// NewManager creates a new consumer manager
-func NewManager(conn *rmq.Connection, config *ConsumerConfig, grpcClient manmanpb.ManManAPIClient, archiver Archiver) *Manager {
+// NewManager creates a new consumer manager. archiver, playerRecorder and alertPublisher may be nil.
+func NewManager(conn *rmq.Connection, config *ConsumerConfig, grpcClient manmanpb.ManManAPIClient, archiver Archiver, playerRecorder PlayerRecorder, alertPublisher AlertPublisher) *Manager {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	m := &Manager{
@@ -98,6 +121,8 @@ func NewManager(conn *rmq.Connection, config *ConsumerConfig, grpcClient manmanp
 		config:        config,
 		grpcClient:    grpcClient,
 		archiver:      archiver,
+		players:       playerRecorder,
+		alerts:        alertPublisher,
 		logsProcessed: 0,
 		statsCtx:      ctx,
 		statsCancel:   cancel,
@@ -195,7 +220,7 @@ func (m *Manager) Subscribe(ctx context.Context, sessionID int64, afterSequence
 // chance to set the flag on the running transition.
 func isLiveSessionStatus(status string) bool {
 	switch status {
-	case "pending", "starting", "running", "stopping":
+	case "pending", "starting", "running", "ready", "stopping":
 		return true
 	default:
 		return false
@@ -251,6 +276,23 @@ func (m *Manager) createConsumer(ctx context.Context, sessionID int64) (*Session
 		bufferFull:       false,
 	}
 
+	if m.players != nil {
+		extractor, err := m.playerExtractor(consumerCtx, sc.sgcID)
+		if err != nil {
+			// Logs still stream and archive; only the roster is missing
+			log.Printf("[consumer-manager] player events disabled for session %d: %v", sessionID, err)
+		}
+		sc.players = extractor
+	}
+
+	if m.alerts != nil {
+		sc.alerts = alerts.NewEvaluator(sessionID, sc.sgcID)
+		if err := m.loadAlertRules(consumerCtx, sc); err != nil {
+			// Retried on the next refresh
+			log.Printf("[consumer-manager] failed to load alert rules for session %d: %v", sessionID, err)
+		}
+	}
+
 	// Seed with any logs retained from a previous consumer reap so reconnecting
 	// clients get recent context without waiting for RabbitMQ to re-deliver.
 	if retained, ok := m.retainedLogs[sessionID]; ok {
@@ -259,11 +301,91 @@ func (m *Manager) createConsumer(ctx context.Context, sessionID int64) (*Session
 	}
 
 	// Start consuming in background using the detached context
-	go sc.consumeLoop(consumerCtx, m.config.DebugLogOutput, m.archiver)
+	go sc.consumeLoop(consumerCtx, m.config.DebugLogOutput, m.archiver, m.players, m.alerts)
 
 	return sc, nil
 }
 
+// playerExtractor builds an extractor from the player event patterns of the SGC's game.
+// Returns nil when the game has none.
+func (m *Manager) playerExtractor(ctx context.Context, sgcID int64) (*players.Extractor, error) {
+	sgcResp, err := m.grpcClient.GetServerGameConfig(ctx, &manmanpb.GetServerGameConfigRequest{
+		ServerGameConfigId: sgcID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get server game config: %w", err)
+	}
+	configResp, err := m.grpcClient.GetGameConfig(ctx, &manmanpb.GetGameConfigRequest{
+		ConfigId: sgcResp.Config.GameConfigId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game config: %w", err)
+	}
+	gameResp, err := m.grpcClient.GetGame(ctx, &manmanpb.GetGameRequest{
+		GameId: configResp.Config.GameId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game: %w", err)
+	}
+
+	patterns := gameResp.Game.PlayerEvents
+	if patterns == nil || patterns.JoinPattern == "" {
+		return nil, nil
+	}
+	return players.NewExtractor(&manman.PlayerEventPatterns{
+		JoinPattern:  patterns.JoinPattern,
+		LeavePattern: patterns.LeavePattern,
+		ChatPattern:  patterns.ChatPattern,
+	})
+}
+
+// loadAlertRules fetches the enabled rules that watch the consumer's SGC, its own and
+// its game's, into its evaluator
+func (m *Manager) loadAlertRules(ctx context.Context, sc *SessionConsumer) error {
+	resp, err := m.grpcClient.ListAlertRules(ctx, &manmanpb.ListAlertRulesRequest{
+		ServerGameConfigId: sc.sgcID,
+	})
+	if err != nil {
+		return err
+	}
+	var rules []*alerts.Rule
+	for _, r := range resp.Rules {
+		if !r.Enabled {
+			continue
+		}
+		rule, err := alerts.NewRule(r.RuleId, r.Pattern, r.Threshold, r.WindowSeconds, r.CooldownSeconds)
+		if err != nil {
+			log.Printf("[consumer-manager] skipping alert rule for session %d: %v", sc.sessionID, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	sc.alerts.SetRules(rules)
+	return nil
+}
+
+// refreshAlertRules reloads every consumer's alert rules so edits take effect on
+// running sessions
+func (m *Manager) refreshAlertRules() {
+	if m.alerts == nil {
+		return
+	}
+	m.mu.RLock()
+	consumers := make([]*SessionConsumer, 0, len(m.consumers))
+	for _, c := range m.consumers {
+		consumers = append(consumers, c)
+	}
+	m.mu.RUnlock()
+
+	for _, c := range consumers {
+		ctx, cancel := context.WithTimeout(m.statsCtx, 10*time.Second)
+		if err := m.loadAlertRules(ctx, c); err != nil {
+			log.Printf("[consumer-manager] failed to refresh alert rules for session %d: %v", c.sessionID, err)
+		}
+		cancel()
+	}
+}
+
 // CreateConsumerForSession creates a consumer for a session (called by lifecycle handler)
 func (m *Manager) CreateConsumerForSession(ctx context.Context, sessionID int64) error {
 	m.mu.Lock()
@@ -291,6 +413,13 @@ func (m *Manager) DeleteConsumerForSession(sessionID int64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	// The session has ended, so anyone still online has left
+	if m.players != nil {
+		if err := m.players.EndSession(context.Background(), sessionID, time.Now().UTC()); err != nil {
+			log.Printf("[consumer-manager] failed to close player sessions for session %d: %v", sessionID, err)
+		}
+	}
+
 	consumer, exists := m.consumers[sessionID]
 	if !exists {
 		log.Printf("[consumer-manager] no consumer exists for session %d", sessionID)
@@ -440,7 +569,7 @@ func (sc *SessionConsumer) close() {
 }
 
 // consumeLoop consumes messages from RabbitMQ and broadcasts to subscribers
-func (sc *SessionConsumer) consumeLoop(ctx context.Context, debugOutput bool, archiver Archiver) {
+func (sc *SessionConsumer) consumeLoop(ctx context.Context, debugOutput bool, archiver Archiver, playerRecorder PlayerRecorder, alertPublisher AlertPublisher) {
 	defer close(sc.done)
 
 	// Register message handler
@@ -470,6 +599,25 @@ func (sc *SessionConsumer) consumeLoop(ctx context.Context, debugOutput bool, ar
 			archiver.AddLog(sc.sgcID, logMsg.SessionID, timestamp, logMsg.Source, logMsg.Message)
 		}
 
+		// Record player joins, leaves and chat for the roster
+		if sc.players != nil && playerRecorder != nil {
+			if event, ok := sc.players.Extract(logMsg.Message); ok {
+				event.At = timestamp
+				if err := playerRecorder.Record(ctx, sc.sessionID, sc.sgcID, event); err != nil {
+					log.Printf("[log-processor] failed to record player %s for session %d: %v", event.Type, sc.sessionID, err)
+				}
+			}
+		}
+
+		// Publish alert rules this line fires for the processor to record and act on
+		if sc.alerts != nil && alertPublisher != nil {
+			for _, firing := range sc.alerts.Observe(timestamp, logMsg.Message) {
+				if err := alertPublisher.Publish(ctx, firing); err != nil {
+					log.Printf("[log-processor] failed to publish alert %d for session %d: %v", firing.RuleID, sc.sessionID, err)
+				}
+			}
+		}
+
 		// Convert to protobuf message
 		pbMsg := &manmanpb.LogMessage{
 			SessionId: logMsg.SessionID,
@@ -523,7 +671,8 @@ func (sc *SessionConsumer) consumeLoop(ctx context.Context, debugOutput bool, ar
 	<-ctx.Done()
 }
 
-// logStats logs processing statistics every 30 seconds
+// logStats logs processing statistics every 30 seconds, reaping idle consumers and
+// refreshing alert rules on the same tick
 func (m *Manager) logStats() {
 	defer m.statsWg.Done()
 
@@ -536,6 +685,7 @@ func (m *Manager) logStats() {
 			return
 		case <-ticker.C:
 			m.reapIdleConsumers()
+			m.refreshAlertRules()
 
 			m.mu.RLock()
 			activeSources := len(m.consumers)
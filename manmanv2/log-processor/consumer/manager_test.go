@@ -250,10 +250,10 @@ func TestLifecycleManagedConsumerNotReaped(t *testing.T) {
 
 // TestIsLiveSessionStatus verifies the status set treated as "still expected to
 // produce logs" matches what recoverActiveSessions' LiveOnly filter trusts as
-// live (pending/starting/running/stopping), since createConsumer only gets one
+// live (pending/starting/running/ready/stopping), since createConsumer only gets one
 // chance to set lifecycleManaged and CreateConsumerForSession is idempotent.
 func TestIsLiveSessionStatus(t *testing.T) {
-	live := []string{"pending", "starting", "running", "stopping"}
+	live := []string{"pending", "starting", "running", "ready", "stopping"}
 	for _, s := range live {
 		if !isLiveSessionStatus(s) {
 			t.Errorf("status %q should be treated as live", s)
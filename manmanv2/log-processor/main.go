@@ -10,6 +10,7 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/jackc/pgx/v5/pgxpool"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
@@ -20,9 +21,11 @@ import (
 	"github.com/whale-net/everything/libs/go/rmq"
 	"github.com/whale-net/everything/libs/go/s3"
 	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/log-processor/alerts"
 	"github.com/whale-net/everything/manmanv2/log-processor/archiver"
 	"github.com/whale-net/everything/manmanv2/log-processor/consumer"
 	"github.com/whale-net/everything/manmanv2/log-processor/lifecycle"
+	"github.com/whale-net/everything/manmanv2/log-processor/players"
 	"github.com/whale-net/everything/manmanv2/log-processor/server"
 	manmanpb "github.com/whale-net/everything/manmanv2/protos"
 )
@@ -50,9 +53,23 @@ func main() {
 		"buffer_max_msgs", config.LogBufferMaxMsgs,
 	)
 
+	// Connect to database (if configured); it backs both log archival and player tracking
+	var dbPool *pgxpool.Pool
+	if config.DatabaseURL != "" {
+		slog.Info("connecting to database")
+		var err error
+		dbPool, err = db.NewPool(ctx, config.DatabaseURL)
+		if err != nil {
+			slog.Error("failed to connect to database", "error", err)
+			os.Exit(1)
+		}
+		defer dbPool.Close()
+		slog.Info("connected to database")
+	}
+
 	// Initialize S3 client (if configured)
 	var logArchiver *archiver.Archiver
-	if config.S3Bucket != "" && config.DatabaseURL != "" {
+	if config.S3Bucket != "" && dbPool != nil {
 		slog.Info("initializing S3 client for log archival")
 		s3Client, err := s3.NewClient(ctx, s3.Config{
 			Bucket:    config.S3Bucket,
@@ -67,16 +84,6 @@ func main() {
 		}
 		slog.Info("S3 client initialized", "bucket", config.S3Bucket, "region", config.S3Region)
 
-		// Connect to database (URL already loaded from PG_DATABASE_URL into config)
-		slog.Info("connecting to database")
-		dbPool, err := db.NewPool(ctx, config.DatabaseURL)
-		if err != nil {
-			slog.Error("failed to connect to database", "error", err)
-			os.Exit(1)
-		}
-		defer dbPool.Close()
-		slog.Info("connected to database")
-
 		// Create log reference repository
 		logRepo := postgres.NewLogReferenceRepository(dbPool)
 
@@ -87,6 +94,15 @@ func main() {
 		slog.Info("S3 archival not configured (missing S3_BUCKET or DATABASE_URL)")
 	}
 
+	// Player join/leave/chat tracking needs only the database
+	var playerRecorder consumer.PlayerRecorder
+	if dbPool != nil {
+		playerRecorder = players.NewRecorder(postgres.NewPlayerSessionRepository(dbPool))
+		slog.Info("player event recording enabled")
+	} else {
+		slog.Info("player event recording not configured (missing DATABASE_URL)")
+	}
+
 	// Build service account dial option for outgoing API calls
 	authOpt, err := grpcauth.NewServiceAccountDialOption(grpcauth.ClientConfig{
 		Mode:                     grpcauth.AuthMode(config.GRPCAuthMode),
@@ -121,13 +137,21 @@ func main() {
 	defer rmqConn.Close()
 	slog.Info("connected to RabbitMQ")
 
+	// Alert rules are evaluated here and recorded by the processor
+	alertPublisher, err := alerts.NewPublisher(rmqConn)
+	if err != nil {
+		slog.Error("failed to create alert publisher", "error", err)
+		os.Exit(1)
+	}
+	defer alertPublisher.Close()
+
 	// Create consumer manager
 	consumerConfig := &consumer.ConsumerConfig{
 		LogBufferTTL:     config.LogBufferTTL,
 		LogBufferMaxMsgs: config.LogBufferMaxMsgs,
 		DebugLogOutput:   config.DebugLogOutput,
 	}
-	consumerManager := consumer.NewManager(rmqConn, consumerConfig, apiClient, logArchiver)
+	consumerManager := consumer.NewManager(rmqConn, consumerConfig, apiClient, logArchiver, playerRecorder, alertPublisher)
 	defer consumerManager.Close()
 
 	// On startup, recreate consumers for all sessions that are already running.
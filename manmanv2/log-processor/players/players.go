@@ -0,0 +1,118 @@
+// Package players extracts player join, leave and chat events from session log lines using
+// a game's player event patterns, and records them as player_session rows.
+package players
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// Event is a player event found in a log line
+type Event struct {
+	Type       string // manman.PlayerEventJoin | Leave | Chat
+	PlayerName string
+	PlayerID   string // empty unless the pattern captures an id group
+	Message    string // chat text, when the chat pattern captures a message group
+	At         time.Time
+}
+
+// Extractor matches log lines against a game's compiled player event patterns
+type Extractor struct {
+	join  *regexp.Regexp
+	leave *regexp.Regexp
+	chat  *regexp.Regexp
+}
+
+// NewExtractor compiles patterns. Returns nil for nil patterns.
+func NewExtractor(patterns *manman.PlayerEventPatterns) (*Extractor, error) {
+	if patterns == nil {
+		return nil, nil
+	}
+	if err := patterns.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid player event patterns: %w", err)
+	}
+	e := &Extractor{join: regexp.MustCompile(patterns.JoinPattern)}
+	if patterns.LeavePattern != "" {
+		e.leave = regexp.MustCompile(patterns.LeavePattern)
+	}
+	if patterns.ChatPattern != "" {
+		e.chat = regexp.MustCompile(patterns.ChatPattern)
+	}
+	return e, nil
+}
+
+// Extract returns the player event in line, if any. Chat is matched first so a player
+// can't fake a join or leave by typing one into chat.
+func (e *Extractor) Extract(line string) (Event, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	for _, m := range []struct {
+		eventType string
+		re        *regexp.Regexp
+	}{
+		{manman.PlayerEventChat, e.chat},
+		{manman.PlayerEventJoin, e.join},
+		{manman.PlayerEventLeave, e.leave},
+	} {
+		if m.re == nil {
+			continue
+		}
+		match := m.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		event := Event{Type: m.eventType}
+		if i := m.re.SubexpIndex(manman.PlayerGroupName); i >= 0 {
+			event.PlayerName = strings.TrimSpace(match[i])
+		}
+		if i := m.re.SubexpIndex(manman.PlayerGroupID); i >= 0 {
+			event.PlayerID = strings.TrimSpace(match[i])
+		}
+		if i := m.re.SubexpIndex(manman.PlayerGroupMessage); i >= 0 {
+			event.Message = match[i]
+		}
+		if event.PlayerName == "" {
+			return Event{}, false
+		}
+		return event, true
+	}
+	return Event{}, false
+}
+
+// Recorder persists extracted events as player_session rows
+type Recorder struct {
+	repo repository.PlayerSessionRepository
+}
+
+// NewRecorder creates a recorder backed by repo
+func NewRecorder(repo repository.PlayerSessionRepository) *Recorder {
+	return &Recorder{repo: repo}
+}
+
+// Record applies one event to the session's player stays
+func (r *Recorder) Record(ctx context.Context, sessionID, sgcID int64, event Event) error {
+	switch event.Type {
+	case manman.PlayerEventJoin:
+		var playerID *string
+		if event.PlayerID != "" {
+			playerID = &event.PlayerID
+		}
+		return r.repo.Join(ctx, sessionID, sgcID, event.PlayerName, playerID, event.At)
+	case manman.PlayerEventLeave:
+		return r.repo.Leave(ctx, sessionID, event.PlayerName, event.At)
+	case manman.PlayerEventChat:
+		return r.repo.RecordChat(ctx, sessionID, event.PlayerName, event.At)
+	default:
+		return fmt.Errorf("unknown player event type %q", event.Type)
+	}
+}
+
+// EndSession closes the stays of players still online when the session ended
+func (r *Recorder) EndSession(ctx context.Context, sessionID int64, at time.Time) error {
+	return r.repo.EndSession(ctx, sessionID, at)
+}
@@ -0,0 +1,79 @@
+package players
+
+import (
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+func TestExtractorMinecraft(t *testing.T) {
+	e, err := NewExtractor(&manman.PlayerEventPatterns{
+		JoinPattern:  `\]: (?P<player>\w+) joined the game`,
+		LeavePattern: `\]: (?P<player>\w+) left the game`,
+		ChatPattern:  `\]: <(?P<player>\w+)> (?P<message>.*)`,
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor failed: %v", err)
+	}
+
+	tests := []struct {
+		line   string
+		want   Event
+		wantOK bool
+	}{
+		{
+			line:   "[12:00:00] [Server thread/INFO]: alice joined the game",
+			want:   Event{Type: manman.PlayerEventJoin, PlayerName: "alice"},
+			wantOK: true,
+		},
+		{
+			line:   "[12:10:00] [Server thread/INFO]: alice left the game\r\n",
+			want:   Event{Type: manman.PlayerEventLeave, PlayerName: "alice"},
+			wantOK: true,
+		},
+		{
+			line:   "[12:05:00] [Server thread/INFO]: <bob> hello there",
+			want:   Event{Type: manman.PlayerEventChat, PlayerName: "bob", Message: "hello there"},
+			wantOK: true,
+		},
+		{
+			// typed into chat; must not register as a join
+			line:   "[12:06:00] [Server thread/INFO]: <bob> ]: mallory joined the game",
+			want:   Event{Type: manman.PlayerEventChat, PlayerName: "bob", Message: "]: mallory joined the game"},
+			wantOK: true,
+		},
+		{
+			line: "[12:07:00] [Server thread/INFO]: Saving the game",
+		},
+	}
+	for _, tt := range tests {
+		got, ok := e.Extract(tt.line)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("Extract(%q) = %+v, %v; want %+v, %v", tt.line, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestExtractorPlayerID(t *testing.T) {
+	e, err := NewExtractor(&manman.PlayerEventPatterns{
+		JoinPattern: `"(?P<player>[^"<]+)<\d+><(?P<id>\[U:\d:\d+\])><>" entered the game`,
+	})
+	if err != nil {
+		t.Fatalf("NewExtractor failed: %v", err)
+	}
+
+	got, ok := e.Extract(`L 01/01/2024 - 12:00:00: "carol<2><[U:1:12345]><>" entered the game`)
+	if !ok || got.PlayerName != "carol" || got.PlayerID != "[U:1:12345]" {
+		t.Errorf("Expected carol with an ID, got %+v, %v", got, ok)
+	}
+}
+
+func TestNewExtractorNil(t *testing.T) {
+	e, err := NewExtractor(nil)
+	if e != nil || err != nil {
+		t.Errorf("Expected nil extractor for nil patterns, got %v, %v", e, err)
+	}
+	if _, err := NewExtractor(&manman.PlayerEventPatterns{JoinPattern: `(\w+) joined`}); err == nil {
+		t.Error("Expected an error for a join pattern without a player group")
+	}
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newActionsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "actions",
+		Short: "List and run a session's game actions",
+	}
+	c.AddCommand(newActionsListCmd(), newActionsRunCmd())
+	return c
+}
+
+func newActionsListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list <session-id>",
+		Short: "List the actions available on a session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetSessionActions(ctx, &pb.GetSessionActionsRequest{SessionId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table {
+					t := newTable("ID", "NAME", "LABEL", "GROUP", "INPUTS", "CONFIRM")
+					for _, a := range resp.Actions {
+						inputs := make([]string, 0, len(a.InputFields))
+						for _, f := range a.InputFields {
+							name := f.Name
+							if f.Required {
+								name += "*"
+							}
+							inputs = append(inputs, name)
+						}
+						t.add(fmtID(a.ActionId), a.Name, a.Label, orDash(a.GroupName), orDash(strings.Join(inputs, ",")), fmtBool(a.RequiresConfirmation))
+					}
+					return t
+				})
+			})
+		},
+	}
+}
+
+func newActionsRunCmd() *cobra.Command {
+	var inputs []string
+	var yes bool
+	c := &cobra.Command{
+		Use:   "run <session-id> <action>",
+		Short: "Run an action on a session by name or ID",
+		Long:  "Run an action on a session. <action> is the action's name or ID as shown by `actions list`; inputs are given as --input name=value. Actions that ask for confirmation in the UI need --yes.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			sessionID, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			values, err := parseInputs(inputs)
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				available, err := c.API.GetSessionActions(ctx, &pb.GetSessionActionsRequest{SessionId: sessionID})
+				if err != nil {
+					return err
+				}
+				action, err := findAction(available.Actions, args[1])
+				if err != nil {
+					return err
+				}
+				if action.RequiresConfirmation && !yes {
+					msg := action.ConfirmationMessage
+					if msg == "" {
+						msg = fmt.Sprintf("%s asks for confirmation", action.Label)
+					}
+					return fmt.Errorf("%s; pass --yes to run it", msg)
+				}
+
+				resp, err := c.API.ExecuteAction(ctx, &pb.ExecuteActionRequest{
+					SessionId:   sessionID,
+					ActionId:    action.ActionId,
+					InputValues: values,
+				})
+				if err != nil {
+					return err
+				}
+				if err := printResult(cmd, resp, func() *table {
+					t := newTable("EXECUTION", "SUCCESS", "COMMAND", "ERROR")
+					t.add(fmtID(resp.ExecutionId), fmtBool(resp.Success), resp.RenderedCommand, orDash(resp.ErrorMessage))
+					return t
+				}); err != nil {
+					return err
+				}
+				if !resp.Success {
+					return fmt.Errorf("action %s failed: %s", action.Name, resp.ErrorMessage)
+				}
+				return nil
+			})
+		},
+	}
+	c.Flags().StringArrayVarP(&inputs, "input", "i", nil, "input value as name=value, repeatable")
+	c.Flags().BoolVarP(&yes, "yes", "y", false, "run actions that ask for confirmation")
+	return c
+}
+
+// findAction picks an action by ID or name.
+func findAction(actions []*pb.ActionDefinition, ref string) (*pb.ActionDefinition, error) {
+	id, _ := strconv.ParseInt(ref, 10, 64)
+	for _, a := range actions {
+		if a.Name == ref || (id != 0 && a.ActionId == id) {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("no action %q on this session", ref)
+}
+
+func parseInputs(inputs []string) (map[string]string, error) {
+	return parseKeyValues("input", inputs)
+}
+
+// parseKeyValues parses the name=value arguments of a repeatable flag.
+func parseKeyValues(flag string, args []string) (map[string]string, error) {
+	values := make(map[string]string, len(args))
+	for _, in := range args {
+		name, value, ok := strings.Cut(in, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --%s %q, want name=value", flag, in)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
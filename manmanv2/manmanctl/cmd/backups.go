@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newBackupsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "backups",
+		Short: "List, take and restore volume backups",
+	}
+	c.AddCommand(
+		newBackupsListCmd(),
+		newBackupsGetCmd(),
+		newBackupsCreateCmd(),
+		newBackupsTriggerCmd(),
+		newBackupsRestoreCmd(),
+	)
+	return c
+}
+
+func newBackupsListCmd() *cobra.Command {
+	var sgcID, sessionID int64
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List backups, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListBackupsResponse{}
+				err := allPages(func(token string) (string, error) {
+					resp, err := c.API.ListBackups(ctx, &pb.ListBackupsRequest{ServerGameConfigId: sgcID, SessionId: sessionID, PageSize: pageSize, PageToken: token})
+					if err != nil {
+						return "", err
+					}
+					all.Backups = append(all.Backups, resp.Backups...)
+					return resp.NextPageToken, nil
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, all, func() *table { return backupsTable(all.Backups...) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&sgcID, "sgc", 0, "only backups of this ServerGameConfig ID")
+	c.Flags().Int64Var(&sessionID, "session", 0, "only backups taken during this session ID")
+	return c
+}
+
+func newBackupsGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <backup-id>",
+		Short: "Show one backup",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "backup")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetBackup(ctx, &pb.GetBackupRequest{BackupId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return backupsTable(resp.Backup) })
+			})
+		},
+	}
+}
+
+func newBackupsCreateCmd() *cobra.Command {
+	var description string
+	c := &cobra.Command{
+		Use:   "create <session-id>",
+		Short: "Back up a session's volumes now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.CreateBackup(ctx, &pb.CreateBackupRequest{SessionId: id, Description: description})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return backupsTable(resp.Backup) })
+			})
+		},
+	}
+	c.Flags().StringVar(&description, "description", "", "note stored with the backup")
+	return c
+}
+
+func newBackupsTriggerCmd() *cobra.Command {
+	var backupConfigID int64
+	c := &cobra.Command{
+		Use:   "trigger <sgc-id> --backup-config <id>",
+		Short: "Run one of an SGC's scheduled backup configs now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "server game config")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.TriggerBackup(ctx, &pb.TriggerBackupRequest{ServerGameConfigId: id, BackupConfigId: backupConfigID})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table {
+					t := newTable("BACKUP")
+					t.add(fmtID(resp.BackupId))
+					return t
+				})
+			})
+		},
+	}
+	c.Flags().Int64Var(&backupConfigID, "backup-config", 0, "backup config ID to run")
+	_ = c.MarkFlagRequired("backup-config")
+	return c
+}
+
+func newBackupsRestoreCmd() *cobra.Command {
+	var start bool
+	c := &cobra.Command{
+		Use:   "restore <backup-id>",
+		Short: "Restore a backup onto its volume",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "backup")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.RestoreBackup(ctx, &pb.RestoreBackupRequest{BackupId: id, StartSession: start})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table {
+					t := newTable("BACKUP", "SESSION")
+					t.add(fmtID(id), fmtID(resp.GetSession().GetSessionId()))
+					return t
+				})
+			})
+		},
+	}
+	c.Flags().BoolVar(&start, "start", false, "start a new session once the volume is restored")
+	return c
+}
+
+func backupsTable(backups ...*pb.Backup) *table {
+	t := newTable("ID", "SGC", "SESSION", "STATUS", "SIZE", "CREATED", "DESCRIPTION")
+	for _, b := range backups {
+		t.add(fmtID(b.BackupId), fmtID(b.ServerGameConfigId), fmtID(b.SessionId), b.Status, fmtBytes(b.SizeBytes), fmtTime(b.CreatedAt), orDash(b.Description))
+	}
+	return t
+}
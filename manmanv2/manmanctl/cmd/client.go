@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/libs/go/grpcclient"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc"
+)
+
+// apiClient wraps a connection to the control API and its two services. One
+// dial per invocation — this is a CLI, not a long-lived process.
+type apiClient struct {
+	conn     *grpcclient.Client
+	API      pb.ManManAPIClient
+	Workshop pb.WorkshopServiceClient
+}
+
+func (c *apiClient) Close() error {
+	return c.conn.Close()
+}
+
+// authDialOption picks the credentials for a dial. With --token (or
+// MANMAN_TOKEN) calls are made as that user, exactly as the UI forwards a
+// signed-in user's token; the returned context carries the token for
+// grpcauth to read. Otherwise the GRPC_AUTH_* service account is used, the
+// same client credentials the host manager and log-processor use.
+func authDialOption(ctx context.Context) (context.Context, grpc.DialOption, error) {
+	mode := grpcauth.AuthMode(getEnv("GRPC_AUTH_MODE", string(grpcauth.AuthModeNone)))
+	if userToken != "" {
+		return grpcauth.WithUserToken(ctx, userToken), grpcauth.NewUserTokenDialOption(mode), nil
+	}
+	opt, err := grpcauth.NewServiceAccountDialOption(grpcauth.ClientConfig{
+		Mode:         mode,
+		TokenURL:     os.Getenv("GRPC_AUTH_TOKEN_URL"),
+		ClientID:     os.Getenv("GRPC_AUTH_CLIENT_ID"),
+		ClientSecret: os.Getenv("GRPC_AUTH_CLIENT_SECRET"),
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create auth dial option: %w", err)
+	}
+	return ctx, opt, nil
+}
+
+// withClient dials the control API, runs fn with the (possibly
+// token-carrying) context, and always closes the connection.
+func withClient(cmd *cobra.Command, fn func(ctx context.Context, c *apiClient) error) error {
+	ctx, authOpt, err := authDialOption(cmd.Context())
+	if err != nil {
+		return err
+	}
+	conn, err := grpcclient.NewClient(ctx, apiAddr, authOpt)
+	if err != nil {
+		return fmt.Errorf("failed to connect to control API at %s: %w", apiAddr, err)
+	}
+	c := &apiClient{
+		conn:     conn,
+		API:      pb.NewManManAPIClient(conn.GetConnection()),
+		Workshop: pb.NewWorkshopServiceClient(conn.GetConnection()),
+	}
+	defer c.Close() //nolint:errcheck
+	return fn(ctx, c)
+}
+
+// withLogProcessor dials the log-processor directly for log tailing, with
+// the same credentials withClient would use.
+func withLogProcessor(cmd *cobra.Command, fn func(ctx context.Context, c pb.LogProcessorClient) error) error {
+	ctx, authOpt, err := authDialOption(cmd.Context())
+	if err != nil {
+		return err
+	}
+	conn, err := grpcclient.NewClient(ctx, logProcessorAddr, authOpt)
+	if err != nil {
+		return fmt.Errorf("failed to connect to log-processor at %s: %w", logProcessorAddr, err)
+	}
+	defer conn.Close() //nolint:errcheck
+	return fn(ctx, pb.NewLogProcessorClient(conn.GetConnection()))
+}
+
+// getEnv reads an environment variable, falling back to defaultValue when
+// unset or empty.
+func getEnv(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
+
+// pageSize is what list commands ask for per page; they follow next_page_token
+// until the server has nothing more, so scripts always see the full list.
+const pageSize = 100
+
+// allPages calls fetch with successive page tokens until it returns an empty
+// next token.
+func allPages(fetch func(pageToken string) (string, error)) error {
+	token := ""
+	for {
+		next, err := fetch(token)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
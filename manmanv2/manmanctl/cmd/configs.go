@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newConfigsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "configs",
+		Short: "List and inspect game configs",
+	}
+	c.AddCommand(newConfigsListCmd(), newConfigsGetCmd(), newConfigsVolumesCmd())
+	return c
+}
+
+func newConfigsListCmd() *cobra.Command {
+	var gameID int64
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List game configs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListGameConfigsResponse{}
+				err := allPages(func(token string) (string, error) {
+					resp, err := c.API.ListGameConfigs(ctx, &pb.ListGameConfigsRequest{GameId: gameID, PageSize: pageSize, PageToken: token})
+					if err != nil {
+						return "", err
+					}
+					all.Configs = append(all.Configs, resp.Configs...)
+					return resp.NextPageToken, nil
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, all, func() *table { return configsTable(all.Configs...) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&gameID, "game", 0, "only configs of this game ID")
+	return c
+}
+
+func newConfigsGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <config-id>",
+		Short: "Show one game config",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "config")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetGameConfig(ctx, &pb.GetGameConfigRequest{ConfigId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return configsTable(resp.Config) })
+			})
+		},
+	}
+}
+
+func newConfigsVolumesCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "volumes <config-id>",
+		Short: "List a game config's volumes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "config")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.ListGameConfigVolumes(ctx, &pb.ListGameConfigVolumesRequest{ConfigId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table {
+					t := newTable("ID", "NAME", "CONTAINER PATH", "HOST SUBPATH", "TYPE", "READ ONLY")
+					for _, v := range resp.Volumes {
+						t.add(fmtID(v.VolumeId), v.Name, v.ContainerPath, orDash(v.HostSubpath), v.VolumeType, fmtBool(v.ReadOnly))
+					}
+					return t
+				})
+			})
+		},
+	}
+}
+
+func configsTable(configs ...*pb.GameConfig) *table {
+	t := newTable("ID", "GAME", "NAME", "IMAGE")
+	for _, gc := range configs {
+		t.add(fmtID(gc.ConfigId), fmtID(gc.GameId), gc.Name, gc.Image)
+	}
+	return t
+}
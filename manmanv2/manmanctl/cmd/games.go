@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newGamesCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "games",
+		Short: "List games and move them between control planes as bundles",
+	}
+	c.AddCommand(newGamesListCmd(), newGamesGetCmd(), newGamesExportCmd(), newGamesImportCmd())
+	return c
+}
+
+func newGamesListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List games",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListGamesResponse{}
+				err := allPages(func(token string) (string, error) {
+					resp, err := c.API.ListGames(ctx, &pb.ListGamesRequest{PageSize: pageSize, PageToken: token})
+					if err != nil {
+						return "", err
+					}
+					all.Games = append(all.Games, resp.Games...)
+					return resp.NextPageToken, nil
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, all, func() *table { return gamesTable(all.Games...) })
+			})
+		},
+	}
+}
+
+func newGamesGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <game-id>",
+		Short: "Show one game",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "game")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetGame(ctx, &pb.GetGameRequest{GameId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return gamesTable(resp.Game) })
+			})
+		},
+	}
+}
+
+func gamesTable(games ...*pb.Game) *table {
+	t := newTable("ID", "NAME", "STEAM APP", "GENRE")
+	for _, g := range games {
+		t.add(fmtID(g.GameId), g.Name, orDash(g.SteamAppId), orDash(g.GetMetadata().GetGenre()))
+	}
+	return t
+}
+
+func newGamesExportCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "export <game-id>",
+		Short: "Print a game and everything defined under it as a bundle",
+		Long:  "Print a game bundle: the game, its configs, volumes, backups, strategies, patches, actions and addon path presets. The bundle is YAML unless --output json is given.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "game")
+			if err != nil {
+				return err
+			}
+			format := outputYAML
+			if output == outputJSON {
+				format = outputJSON
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.ExportGameBundle(ctx, &pb.ExportGameBundleRequest{GameId: id, Format: format})
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(cmd.OutOrStdout(), resp.Bundle)
+				return err
+			})
+		},
+	}
+}
+
+func newGamesImportCmd() *cobra.Command {
+	var file string
+	var dryRun bool
+	c := &cobra.Command{
+		Use:   "import -f <bundle>",
+		Short: "Create or update a game from a bundle",
+		Long:  "Create or update a game from a YAML or JSON bundle (- reads stdin). Existing objects are matched by name; --dry-run reports what would change without writing.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := readInput(cmd, file)
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.ImportGameBundle(ctx, &pb.ImportGameBundleRequest{Bundle: string(data), DryRun: dryRun})
+				if err != nil {
+					return err
+				}
+				if dryRun {
+					fmt.Fprintln(cmd.ErrOrStderr(), "dry run: no changes written")
+				}
+				return printResult(cmd, resp, func() *table {
+					t := newTable("KIND", "KEY", "OP", "FIELDS")
+					for _, ch := range resp.Changes {
+						t.add(ch.Kind, ch.Key, ch.Op, orDash(strings.Join(ch.Fields, ",")))
+					}
+					return t
+				})
+			})
+		},
+	}
+	c.Flags().StringVarP(&file, "file", "f", "", "bundle file, or - for stdin")
+	c.Flags().BoolVar(&dryRun, "dry-run", false, "report changes without writing them")
+	_ = c.MarkFlagRequired("file")
+	return c
+}
+
+// readInput reads a file argument, with - meaning stdin.
+func readInput(cmd *cobra.Command, file string) ([]byte, error) {
+	if file == "-" {
+		return io.ReadAll(cmd.InOrStdin())
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+	return data, nil
+}
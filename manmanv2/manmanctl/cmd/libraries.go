@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newLibrariesCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "libraries",
+		Short: "List workshop libraries and attach them to ServerGameConfigs",
+	}
+	c.AddCommand(
+		newLibrariesListCmd(),
+		newLibrariesGetCmd(),
+		newLibrariesAddonsCmd(),
+		newLibrariesSGCCmd(),
+		newLibrariesAttachCmd(),
+		newLibrariesDetachCmd(),
+	)
+	return c
+}
+
+func newLibrariesListCmd() *cobra.Command {
+	var gameID int64
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List workshop libraries",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListLibrariesResponse{}
+				for {
+					resp, err := c.Workshop.ListLibraries(ctx, &pb.ListLibrariesRequest{GameId: gameID, Limit: pageSize, Offset: int32(len(all.Libraries))})
+					if err != nil {
+						return err
+					}
+					all.Libraries = append(all.Libraries, resp.Libraries...)
+					all.TotalCount = resp.TotalCount
+					if len(resp.Libraries) == 0 || int32(len(all.Libraries)) >= resp.TotalCount {
+						break
+					}
+				}
+				return printResult(cmd, all, func() *table { return librariesTable(all.Libraries...) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&gameID, "game", 0, "only libraries of this game ID")
+	return c
+}
+
+func newLibrariesGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <library-id>",
+		Short: "Show one library",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "library")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.Workshop.GetLibrary(ctx, &pb.GetLibraryRequest{LibraryId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return librariesTable(resp.Library) })
+			})
+		},
+	}
+}
+
+func newLibrariesAddonsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "addons <library-id>",
+		Short: "List the addons in a library",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "library")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.Workshop.GetLibraryAddons(ctx, &pb.GetLibraryAddonsRequest{LibraryId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table {
+					t := newTable("ID", "WORKSHOP ID", "PLATFORM", "NAME", "SIZE", "UPDATED")
+					for _, a := range resp.Addons {
+						t.add(fmtID(a.AddonId), a.WorkshopId, a.PlatformType, a.Name, fmtBytes(a.FileSizeBytes), fmtTime(a.LastUpdated))
+					}
+					return t
+				})
+			})
+		},
+	}
+}
+
+func newLibrariesSGCCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "sgc <sgc-id>",
+		Short: "List the libraries attached to a ServerGameConfig",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "server game config")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.Workshop.ListSGCLibraries(ctx, &pb.ListSGCLibrariesRequest{SgcId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return librariesTable(resp.Libraries...) })
+			})
+		},
+	}
+}
+
+func newLibrariesAttachCmd() *cobra.Command {
+	var sgcID, presetID, volumeID int64
+	var path string
+	c := &cobra.Command{
+		Use:   "attach <library-id> --sgc <sgc-id>",
+		Short: "Attach a library to a ServerGameConfig",
+		Long:  "Attach a library to a ServerGameConfig so its addons are installed there. The library's default path preset is used unless --preset, --volume or --path say otherwise.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "library")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.Workshop.AddLibraryToSGC(ctx, &pb.AddLibraryToSGCRequest{
+					SgcId:                    sgcID,
+					LibraryId:                id,
+					PresetId:                 presetID,
+					VolumeId:                 volumeID,
+					InstallationPathOverride: path,
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return attachmentTable(id, sgcID) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&sgcID, "sgc", 0, "ServerGameConfig ID to attach to")
+	c.Flags().Int64Var(&presetID, "preset", 0, "addon path preset ID instead of the library's default")
+	c.Flags().Int64Var(&volumeID, "volume", 0, "volume ID to install into")
+	c.Flags().StringVar(&path, "path", "", "custom installation path")
+	_ = c.MarkFlagRequired("sgc")
+	return c
+}
+
+func newLibrariesDetachCmd() *cobra.Command {
+	var sgcID int64
+	c := &cobra.Command{
+		Use:   "detach <library-id> --sgc <sgc-id>",
+		Short: "Detach a library from a ServerGameConfig",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "library")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.Workshop.RemoveLibraryFromSGC(ctx, &pb.RemoveLibraryFromSGCRequest{SgcId: sgcID, LibraryId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return attachmentTable(id, sgcID) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&sgcID, "sgc", 0, "ServerGameConfig ID to detach from")
+	_ = c.MarkFlagRequired("sgc")
+	return c
+}
+
+func attachmentTable(libraryID, sgcID int64) *table {
+	t := newTable("LIBRARY", "SGC")
+	t.add(fmtID(libraryID), fmtID(sgcID))
+	return t
+}
+
+func librariesTable(libraries ...*pb.WorkshopLibrary) *table {
+	t := newTable("ID", "GAME", "NAME", "PRESET", "DESCRIPTION")
+	for _, l := range libraries {
+		t.add(fmtID(l.LibraryId), fmtID(l.GameId), l.Name, fmtID(l.PresetId), orDash(l.Description))
+	}
+	return t
+}
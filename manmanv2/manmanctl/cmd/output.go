@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+func validateOutput(o string) error {
+	switch o {
+	case outputTable, outputJSON, outputYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid --output %q, want table, json or yaml", o)
+}
+
+// table is the human-readable view of a response: a header row and one row
+// per item, aligned with a tabwriter.
+type table struct {
+	headers []string
+	rows    [][]string
+}
+
+func newTable(headers ...string) *table {
+	return &table{headers: headers}
+}
+
+func (t *table) add(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(t.headers, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// printResult writes a response in the --output format. json and yaml print
+// the whole message with proto field names, so scripts see every field;
+// table prints the columns tbl picks.
+func printResult(cmd *cobra.Command, msg proto.Message, tbl func() *table) error {
+	w := cmd.OutOrStdout()
+	switch output {
+	case outputJSON:
+		b, err := protojson.MarshalOptions{Multiline: true, Indent: "  ", UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to format response: %w", err)
+		}
+		_, err = fmt.Fprintln(w, string(b))
+		return err
+	case outputYAML:
+		b, err := marshalYAML(msg)
+		if err != nil {
+			return fmt.Errorf("failed to format response: %w", err)
+		}
+		_, err = w.Write(b)
+		return err
+	default:
+		return tbl().write(w)
+	}
+}
+
+// printEvent writes one message of a stream as it arrives: one JSON object
+// per line for json, one document per message for yaml, and line for table.
+func printEvent(cmd *cobra.Command, msg proto.Message, line string) error {
+	w := cmd.OutOrStdout()
+	switch output {
+	case outputJSON:
+		b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+		if err != nil {
+			return fmt.Errorf("failed to format event: %w", err)
+		}
+		// protojson doesn't promise stable whitespace; compact keeps one event per line
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, b); err != nil {
+			return fmt.Errorf("failed to format event: %w", err)
+		}
+		_, err = fmt.Fprintln(w, buf.String())
+		return err
+	case outputYAML:
+		b, err := marshalYAML(msg)
+		if err != nil {
+			return fmt.Errorf("failed to format event: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "---\n%s", b)
+		return err
+	default:
+		_, err := fmt.Fprintln(w, line)
+		return err
+	}
+}
+
+// marshalYAML converts the message's JSON form to YAML. Going through a node
+// keeps the field order; clearing the JSON styles lets the encoder pick plain
+// scalars and block collections while still quoting strings that would
+// otherwise read as numbers or booleans.
+func marshalYAML(msg proto.Message) ([]byte, error) {
+	b, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+	clearStyle(&node)
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+func fmtID(id int64) string {
+	if id == 0 {
+		return "-"
+	}
+	return strconv.FormatInt(id, 10)
+}
+
+// fmtTime renders a Unix timestamp in seconds in local time, or "-" for unset.
+func fmtTime(unix int64) string {
+	if unix == 0 {
+		return "-"
+	}
+	return time.Unix(unix, 0).Local().Format("2006-01-02 15:04:05")
+}
+
+func fmtBool(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+func fmtBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
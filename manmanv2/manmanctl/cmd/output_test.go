@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func TestTable(t *testing.T) {
+	players := int32(3)
+	tbl := sessionsTable(
+		&pb.Session{SessionId: 12, ServerGameConfigId: 4, Status: "running", PlayerCount: &players, MaxPlayers: 10, MapName: "de_dust2"},
+		&pb.Session{SessionId: 9, ServerGameConfigId: 4, Status: "stopped"},
+	)
+	var buf bytes.Buffer
+	require.NoError(t, tbl.write(&buf))
+	assert.Equal(t, ""+
+		"ID  SGC  STATUS   STARTED  ENDED  PLAYERS  MAP\n"+
+		"12  4    running  -        -      3/10     de_dust2\n"+
+		"9   4    stopped  -        -      -        -\n", buf.String())
+}
+
+func TestOutputFlag(t *testing.T) {
+	root := NewRootCmd()
+	root.SetArgs([]string{"servers", "list", "-o", "xml"})
+	root.SetOut(&bytes.Buffer{})
+	err := root.Execute()
+	assert.EqualError(t, err, `invalid --output "xml", want table, json or yaml`)
+}
+
+func TestFormatHelpers(t *testing.T) {
+	assert.Equal(t, "-", fmtID(0))
+	assert.Equal(t, "42", fmtID(42))
+	assert.Equal(t, "-", fmtTime(0))
+	assert.Equal(t, "512 B", fmtBytes(512))
+	assert.Equal(t, "1.5 KiB", fmtBytes(1536))
+	assert.Equal(t, "2.0 GiB", fmtBytes(2<<30))
+	assert.Equal(t, "region=eu,tier=gold", fmtLabels(map[string]string{"tier": "gold", "region": "eu"}))
+	assert.Equal(t, "27015:27015/udp,8080:80/tcp", fmtPorts([]*pb.PortBinding{
+		{HostPort: 27015, ContainerPort: 27015, Protocol: "UDP"},
+		{HostPort: 8080, ContainerPort: 80, Protocol: "TCP"},
+	}))
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func TestParsePortBinding(t *testing.T) {
+	b, err := parsePortBinding("27015:27015/udp")
+	require.NoError(t, err)
+	assert.Equal(t, &pb.PortBinding{HostPort: 27015, ContainerPort: 27015, Protocol: "UDP"}, b)
+
+	b, err = parsePortBinding("0:25565")
+	require.NoError(t, err)
+	assert.Equal(t, &pb.PortBinding{HostPort: 0, ContainerPort: 25565, Protocol: "TCP"}, b)
+
+	for _, bad := range []string{"25565", "a:1", "1:0", "1:70000", "1:1/sctp"} {
+		_, err := parsePortBinding(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestParseInputs(t *testing.T) {
+	values, err := parseInputs([]string{"map=de_dust2", "message=a=b", "empty="})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"map": "de_dust2", "message": "a=b", "empty": ""}, values)
+
+	_, err = parseInputs([]string{"map"})
+	assert.EqualError(t, err, `invalid --input "map", want name=value`)
+	_, err = parseInputs([]string{"=x"})
+	assert.Error(t, err)
+}
+
+func TestFindAction(t *testing.T) {
+	actions := []*pb.ActionDefinition{
+		{ActionId: 7, Name: "save_game"},
+		{ActionId: 8, Name: "change_map"},
+	}
+	a, err := findAction(actions, "change_map")
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), a.ActionId)
+
+	a, err = findAction(actions, "7")
+	require.NoError(t, err)
+	assert.Equal(t, "save_game", a.Name)
+
+	_, err = findAction(actions, "restart")
+	assert.EqualError(t, err, `no action "restart" on this session`)
+}
+
+func TestParseID(t *testing.T) {
+	id, err := parseID("15", "session")
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), id)
+
+	_, err = parseID("abc", "session")
+	assert.EqualError(t, err, `invalid session id "abc"`)
+	_, err = parseID("0", "session")
+	assert.Error(t, err)
+}
+
+func TestParsePatches(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "patch.json")
+	require.NoError(t, os.WriteFile(file, []byte(`{"map":"test"}`), 0o644))
+
+	patches, err := parsePatches(&cobra.Command{}, []string{"7=level-name=test", "9=@" + file})
+	require.NoError(t, err)
+	assert.Equal(t, []*pb.SessionPatchOverride{
+		{StrategyId: 7, PatchContent: "level-name=test"},
+		{StrategyId: 9, PatchContent: `{"map":"test"}`, PatchOrder: 1},
+	}, patches)
+
+	for _, bad := range []string{"7", "x=a", "9=@/does/not/exist"} {
+		_, err := parsePatches(&cobra.Command{}, []string{bad})
+		assert.Error(t, err, bad)
+	}
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	apiAddr          string
+	logProcessorAddr string
+	userToken        string
+	output           string
+)
+
+// Execute runs the CLI. Called from main.go. Interrupts cancel the command's
+// context so streaming commands (attach, logs) close their streams cleanly.
+func Execute() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := NewRootCmd().ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// NewRootCmd builds a complete, independent command tree so tests can run
+// several command lines in one process without sharing parsed flag values.
+func NewRootCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:           "manmanctl",
+		Short:         "Command-line client for ManManV2",
+		Long:          "manmanctl drives a ManManV2 control plane: servers, games, configs, deployments, sessions, actions, backups and workshop libraries.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return validateOutput(output)
+		},
+	}
+	c.PersistentFlags().StringVar(&apiAddr, "address", getEnv("MANMAN_API_ADDRESS", "localhost:50051"), "control API address (host:port)")
+	c.PersistentFlags().StringVar(&logProcessorAddr, "log-processor-address", getEnv("LOG_PROCESSOR_ADDRESS", "localhost:50053"), "log-processor address (host:port), used by `sessions logs`")
+	c.PersistentFlags().StringVar(&userToken, "token", os.Getenv("MANMAN_TOKEN"), "user access token to call as; without one the GRPC_AUTH_* service account is used")
+	c.PersistentFlags().StringVarP(&output, "output", "o", outputTable, "Output format: table, json or yaml")
+
+	c.AddCommand(
+		newServersCmd(),
+		newGamesCmd(),
+		newConfigsCmd(),
+		newSGCsCmd(),
+		newSessionsCmd(),
+		newActionsCmd(),
+		newBackupsCmd(),
+		newLibrariesCmd(),
+	)
+	return c
+}
+
+// parseID parses a positional ID argument, naming what it identifies in the error.
+func parseID(arg, what string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s id %q", what, arg)
+	}
+	return id, nil
+}
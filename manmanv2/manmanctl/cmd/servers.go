@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newServersCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "servers",
+		Short: "List and inspect host servers",
+	}
+	c.AddCommand(newServersListCmd(), newServersGetCmd())
+	return c
+}
+
+func newServersListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List servers",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListServersResponse{}
+				err := allPages(func(token string) (string, error) {
+					resp, err := c.API.ListServers(ctx, &pb.ListServersRequest{PageSize: pageSize, PageToken: token})
+					if err != nil {
+						return "", err
+					}
+					all.Servers = append(all.Servers, resp.Servers...)
+					return resp.NextPageToken, nil
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, all, func() *table { return serversTable(all.Servers...) })
+			})
+		},
+	}
+}
+
+func newServersGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <server-id>",
+		Short: "Show one server",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "server")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetServer(ctx, &pb.GetServerRequest{ServerId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return serversTable(resp.Server) })
+			})
+		},
+	}
+}
+
+func serversTable(servers ...*pb.Server) *table {
+	t := newTable("ID", "NAME", "STATUS", "ENVIRONMENT", "DEFAULT", "LAST SEEN", "LABELS")
+	for _, s := range servers {
+		t.add(fmtID(s.ServerId), s.Name, s.Status, orDash(s.Environment), fmtBool(s.IsDefault), fmtTime(s.LastSeen), fmtLabels(s.Labels))
+	}
+	return t
+}
+
+func fmtLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return "-"
+	}
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newSessionsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "sessions",
+		Short: "Start, stop and watch game server sessions",
+	}
+	c.AddCommand(
+		newSessionsListCmd(),
+		newSessionsGetCmd(),
+		newSessionsStartCmd(),
+		newSessionsStopCmd(),
+		newSessionsSendCmd(),
+		newSessionsAttachCmd(),
+		newSessionsLogsCmd(),
+	)
+	return c
+}
+
+func newSessionsListCmd() *cobra.Command {
+	var sgcID, serverID int64
+	var statuses []string
+	var live bool
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List sessions, newest first",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListSessionsResponse{}
+				err := allPages(func(token string) (string, error) {
+					resp, err := c.API.ListSessions(ctx, &pb.ListSessionsRequest{
+						ServerGameConfigId: sgcID,
+						ServerId:           serverID,
+						StatusFilter:       statuses,
+						LiveOnly:           live,
+						PageSize:           pageSize,
+						PageToken:          token,
+					})
+					if err != nil {
+						return "", err
+					}
+					all.Sessions = append(all.Sessions, resp.Sessions...)
+					return resp.NextPageToken, nil
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, all, func() *table { return sessionsTable(all.Sessions...) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&sgcID, "sgc", 0, "only sessions of this ServerGameConfig ID")
+	c.Flags().Int64Var(&serverID, "server", 0, "only sessions on this server ID")
+	c.Flags().StringSliceVar(&statuses, "status", nil, "only sessions in these statuses, e.g. running,crashed")
+	c.Flags().BoolVar(&live, "live", false, "only sessions that haven't ended")
+	return c
+}
+
+func newSessionsGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <session-id>",
+		Short: "Show one session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetSession(ctx, &pb.GetSessionRequest{SessionId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return sessionsTable(resp.Session) })
+			})
+		},
+	}
+}
+
+func newSessionsStartCmd() *cobra.Command {
+	var force bool
+	var imageTag string
+	var env, patches []string
+	c := &cobra.Command{
+		Use:   "start <sgc-id>",
+		Short: "Start a session for a ServerGameConfig",
+		Long:  "Start a session for a ServerGameConfig. --image-tag, --env and --patch override the SGC's configuration for this session only; a patch is given as <strategy-id>=<content>, or <strategy-id>=@<file> to read it from a file, in the strategy's own format.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "server game config")
+			if err != nil {
+				return err
+			}
+			req := &pb.StartSessionRequest{ServerGameConfigId: id, Force: force, ImageTag: imageTag}
+			if len(env) > 0 {
+				if req.Env, err = parseKeyValues("env", env); err != nil {
+					return err
+				}
+			}
+			if req.Patches, err = parsePatches(cmd, patches); err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.StartSession(ctx, req)
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return sessionsTable(resp.Session) })
+			})
+		},
+	}
+	c.Flags().BoolVar(&force, "force", false, "start even if the SGC already has an active session, replacing it")
+	c.Flags().StringVar(&imageTag, "image-tag", "", "run this tag of the game config's image")
+	c.Flags().StringArrayVarP(&env, "env", "e", nil, "env var as NAME=value over the game config's, repeatable")
+	c.Flags().StringArrayVar(&patches, "patch", nil, "session patch as <strategy-id>=<content> or <strategy-id>=@<file>, repeatable")
+	return c
+}
+
+// parsePatches parses --patch arguments, reading @file contents (- for stdin).
+func parsePatches(cmd *cobra.Command, args []string) ([]*pb.SessionPatchOverride, error) {
+	var patches []*pb.SessionPatchOverride
+	for _, arg := range args {
+		ref, content, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --patch %q, want <strategy-id>=<content>", arg)
+		}
+		id, err := parseID(ref, "strategy")
+		if err != nil {
+			return nil, err
+		}
+		if file, ok := strings.CutPrefix(content, "@"); ok {
+			data, err := readInput(cmd, file)
+			if err != nil {
+				return nil, err
+			}
+			content = string(data)
+		}
+		patches = append(patches, &pb.SessionPatchOverride{StrategyId: id, PatchContent: content, PatchOrder: int32(len(patches))})
+	}
+	return patches, nil
+}
+
+func newSessionsStopCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "stop <session-id>",
+		Short: "Stop a session",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.StopSession(ctx, &pb.StopSessionRequest{SessionId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return sessionsTable(resp.Session) })
+			})
+		},
+	}
+}
+
+func newSessionsSendCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "send <session-id> <line>",
+		Short: "Send one line to a session's console",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				_, err := c.API.SendInput(ctx, &pb.SendInputRequest{SessionId: id, Input: []byte(args[1] + "\n")})
+				return err
+			})
+		},
+	}
+}
+
+func newSessionsAttachCmd() *cobra.Command {
+	var after int64
+	var readOnly bool
+	c := &cobra.Command{
+		Use:   "attach <session-id>",
+		Short: "Attach to a session's console",
+		Long:  "Attach to a session's console: print its output and everyone's input, and send each line read from stdin. The console is left when stdin closes or on interrupt; with --read-only stdin is ignored.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				stream, err := c.API.AttachSession(ctx)
+				if err != nil {
+					return err
+				}
+				if err := stream.Send(&pb.AttachSessionRequest{SessionId: id, AfterSequenceNumber: after}); err != nil {
+					return err
+				}
+				if !readOnly {
+					go func() {
+						scanner := bufio.NewScanner(cmd.InOrStdin())
+						for scanner.Scan() {
+							if err := stream.Send(&pb.AttachSessionRequest{Input: []byte(scanner.Text())}); err != nil {
+								return
+							}
+						}
+						_ = stream.CloseSend()
+					}()
+				}
+				for {
+					ev, err := stream.Recv()
+					if err != nil {
+						return streamEnded(ctx, err)
+					}
+					if err := printEvent(cmd, ev, consoleLine(ev)); err != nil {
+						return err
+					}
+				}
+			})
+		},
+	}
+	c.Flags().Int64Var(&after, "after", 0, "only output after this log sequence number (0 prints the backlog)")
+	c.Flags().BoolVar(&readOnly, "read-only", false, "watch without sending stdin")
+	return c
+}
+
+func newSessionsLogsCmd() *cobra.Command {
+	var after int64
+	var follow time.Duration
+	c := &cobra.Command{
+		Use:   "logs <session-id>",
+		Short: "Tail a session's logs from the log-processor",
+		Long:  "Print a session's log backlog, then follow new lines from the log-processor until interrupted (or for --for).",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "session")
+			if err != nil {
+				return err
+			}
+			return withLogProcessor(cmd, func(ctx context.Context, c pb.LogProcessorClient) error {
+				if follow > 0 {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithTimeout(ctx, follow)
+					defer cancel()
+				}
+				stream, err := c.StreamSessionLogs(ctx, &pb.StreamSessionLogsRequest{SessionId: id, AfterSequenceNumber: after})
+				if err != nil {
+					return err
+				}
+				for {
+					msg, err := stream.Recv()
+					if err != nil {
+						return streamEnded(ctx, err)
+					}
+					if err := printEvent(cmd, msg, logLine(msg)); err != nil {
+						return err
+					}
+				}
+			})
+		},
+	}
+	c.Flags().Int64Var(&after, "after", 0, "only lines after this sequence number (0 prints the backlog)")
+	c.Flags().DurationVar(&follow, "for", 0, "stop following after this long, e.g. 30s (0 follows until interrupted)")
+	return c
+}
+
+// streamEnded turns the error that ended a stream into the command's result:
+// the server closing it, an interrupt or --for running out are all a normal
+// end.
+func streamEnded(ctx context.Context, err error) error {
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if ctx.Err() != nil {
+		if code := status.Code(err); code == codes.Canceled || code == codes.DeadlineExceeded {
+			return nil
+		}
+	}
+	return err
+}
+
+func logLine(msg *pb.LogMessage) string {
+	ts := time.UnixMilli(msg.Timestamp).Local().Format("15:04:05")
+	return fmt.Sprintf("%s [%s] %s", ts, msg.Source, strings.TrimRight(msg.Message, "\n"))
+}
+
+// consoleLine renders an attach event the way the UI's console does: output
+// as log lines, input prefixed with who sent it.
+func consoleLine(ev *pb.AttachSessionEvent) string {
+	switch {
+	case ev.Output != nil:
+		return logLine(ev.Output)
+	case ev.Input != nil:
+		ts := time.UnixMilli(ev.Input.Timestamp).Local().Format("15:04:05")
+		if ev.Input.Error != "" {
+			return fmt.Sprintf("%s %s> %s (not delivered: %s)", ts, ev.Input.Username, ev.Input.Input, ev.Input.Error)
+		}
+		return fmt.Sprintf("%s %s> %s", ts, ev.Input.Username, ev.Input.Input)
+	case ev.Attendees != nil:
+		return "* attached: " + strings.Join(ev.Attendees.Usernames, ", ")
+	}
+	return ""
+}
+
+func sessionsTable(sessions ...*pb.Session) *table {
+	t := newTable("ID", "SGC", "STATUS", "STARTED", "ENDED", "PLAYERS", "MAP")
+	for _, s := range sessions {
+		players := "-"
+		if s.PlayerCount != nil {
+			players = fmt.Sprint(s.GetPlayerCount())
+			if s.MaxPlayers > 0 {
+				players += fmt.Sprintf("/%d", s.MaxPlayers)
+			}
+		}
+		t.add(fmtID(s.SessionId), fmtID(s.ServerGameConfigId), s.Status, fmtTime(s.StartedAt), fmtTime(s.EndedAt), players, orDash(s.MapName))
+	}
+	return t
+}
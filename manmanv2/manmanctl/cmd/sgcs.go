@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+func newSGCsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "sgcs",
+		Short: "List, inspect and create deployments of game configs to servers (ServerGameConfigs)",
+	}
+	c.AddCommand(newSGCsListCmd(), newSGCsGetCmd(), newSGCsDeployCmd())
+	return c
+}
+
+func newSGCsListCmd() *cobra.Command {
+	var serverID int64
+	c := &cobra.Command{
+		Use:   "list",
+		Short: "List ServerGameConfigs",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				all := &pb.ListServerGameConfigsResponse{}
+				err := allPages(func(token string) (string, error) {
+					resp, err := c.API.ListServerGameConfigs(ctx, &pb.ListServerGameConfigsRequest{ServerId: serverID, PageSize: pageSize, PageToken: token})
+					if err != nil {
+						return "", err
+					}
+					all.Configs = append(all.Configs, resp.Configs...)
+					return resp.NextPageToken, nil
+				})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, all, func() *table { return sgcsTable(all.Configs...) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&serverID, "server", 0, "only deployments on this server ID")
+	return c
+}
+
+func newSGCsGetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <sgc-id>",
+		Short: "Show one ServerGameConfig",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseID(args[0], "server game config")
+			if err != nil {
+				return err
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.GetServerGameConfig(ctx, &pb.GetServerGameConfigRequest{ServerGameConfigId: id})
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return sgcsTable(resp.Config) })
+			})
+		},
+	}
+}
+
+func newSGCsDeployCmd() *cobra.Command {
+	var configID, serverID int64
+	var ports []string
+	var addonUpdatePolicy string
+	c := &cobra.Command{
+		Use:   "deploy --config <config-id>",
+		Short: "Deploy a game config to a server",
+		Long:  "Deploy a game config to a server. Without --server the best online server is picked and any host port of 0 is assigned during placement.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := &pb.DeployGameConfigRequest{
+				ServerId:          serverID,
+				GameConfigId:      configID,
+				AddonUpdatePolicy: addonUpdatePolicy,
+			}
+			for _, p := range ports {
+				binding, err := parsePortBinding(p)
+				if err != nil {
+					return err
+				}
+				req.PortBindings = append(req.PortBindings, binding)
+			}
+			return withClient(cmd, func(ctx context.Context, c *apiClient) error {
+				resp, err := c.API.DeployGameConfig(ctx, req)
+				if err != nil {
+					return err
+				}
+				return printResult(cmd, resp, func() *table { return sgcsTable(resp.Config) })
+			})
+		},
+	}
+	c.Flags().Int64Var(&configID, "config", 0, "game config ID to deploy")
+	c.Flags().Int64Var(&serverID, "server", 0, "server ID to deploy to; omit to place automatically")
+	c.Flags().StringArrayVarP(&ports, "port", "p", nil, "port binding host:container[/tcp|udp], repeatable")
+	c.Flags().StringVar(&addonUpdatePolicy, "addon-update-policy", "", "off, notify or reinstall (default notify)")
+	_ = c.MarkFlagRequired("config")
+	return c
+}
+
+// parsePortBinding reads a docker-style host:container[/protocol] binding.
+func parsePortBinding(s string) (*pb.PortBinding, error) {
+	spec, protocol, _ := strings.Cut(s, "/")
+	protocol = strings.ToUpper(protocol)
+	switch protocol {
+	case "":
+		protocol = "TCP"
+	case "TCP", "UDP":
+	default:
+		return nil, fmt.Errorf("invalid port binding %q: protocol must be tcp or udp", s)
+	}
+	hostPart, containerPart, ok := strings.Cut(spec, ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid port binding %q: want host:container[/protocol]", s)
+	}
+	host, err := strconv.ParseInt(hostPart, 10, 32)
+	if err != nil || host < 0 || host > 65535 {
+		return nil, fmt.Errorf("invalid port binding %q: bad host port", s)
+	}
+	container, err := strconv.ParseInt(containerPart, 10, 32)
+	if err != nil || container < 1 || container > 65535 {
+		return nil, fmt.Errorf("invalid port binding %q: bad container port", s)
+	}
+	return &pb.PortBinding{HostPort: int32(host), ContainerPort: int32(container), Protocol: protocol}, nil
+}
+
+func sgcsTable(sgcs ...*pb.ServerGameConfig) *table {
+	t := newTable("ID", "SERVER", "CONFIG", "STATUS", "PORTS", "IMAGE UPDATE")
+	for _, sgc := range sgcs {
+		t.add(fmtID(sgc.ServerGameConfigId), fmtID(sgc.ServerId), fmtID(sgc.GameConfigId), sgc.Status, fmtPorts(sgc.PortBindings), fmtBool(sgc.GetImageStatus().GetUpdateAvailable()))
+	}
+	return t
+}
+
+func fmtPorts(bindings []*pb.PortBinding) string {
+	if len(bindings) == 0 {
+		return "-"
+	}
+	parts := make([]string, 0, len(bindings))
+	for _, b := range bindings {
+		parts = append(parts, fmt.Sprintf("%d:%d/%s", b.HostPort, b.ContainerPort, strings.ToLower(b.Protocol)))
+	}
+	return strings.Join(parts, ",")
+}
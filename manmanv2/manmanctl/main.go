@@ -0,0 +1,10 @@
+// Command manmanctl is the command-line client for ManManV2. Every command
+// calls the control API (or the log-processor, for `sessions logs`) and
+// formats the response — see README.md.
+package main
+
+import "github.com/whale-net/everything/manmanv2/manmanctl/cmd"
+
+func main() {
+	cmd.Execute()
+}
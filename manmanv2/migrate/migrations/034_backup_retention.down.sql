@@ -0,0 +1,8 @@
+ALTER TABLE backups DROP COLUMN IF EXISTS pruned_reason;
+
+ALTER TABLE backup_configs
+    DROP COLUMN IF EXISTS retention_keep_last,
+    DROP COLUMN IF EXISTS retention_daily_days,
+    DROP COLUMN IF EXISTS retention_weekly_weeks,
+    DROP COLUMN IF EXISTS retention_monthly_months,
+    DROP COLUMN IF EXISTS retention_max_total_bytes;
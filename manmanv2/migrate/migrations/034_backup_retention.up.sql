@@ -0,0 +1,11 @@
+-- Retention rules for scheduled backups. Rules are evaluated per SGC; 0 disables a rule
+-- and a config with every rule disabled never prunes.
+ALTER TABLE backup_configs
+    ADD COLUMN IF NOT EXISTS retention_keep_last        INT    NOT NULL DEFAULT 0 CHECK (retention_keep_last >= 0),
+    ADD COLUMN IF NOT EXISTS retention_daily_days       INT    NOT NULL DEFAULT 0 CHECK (retention_daily_days >= 0),
+    ADD COLUMN IF NOT EXISTS retention_weekly_weeks     INT    NOT NULL DEFAULT 0 CHECK (retention_weekly_weeks >= 0),
+    ADD COLUMN IF NOT EXISTS retention_monthly_months   INT    NOT NULL DEFAULT 0 CHECK (retention_monthly_months >= 0),
+    ADD COLUMN IF NOT EXISTS retention_max_total_bytes  BIGINT NOT NULL DEFAULT 0 CHECK (retention_max_total_bytes >= 0);
+
+-- Why the retention job soft-deleted a backup (NULL for manual deletes)
+ALTER TABLE backups ADD COLUMN IF NOT EXISTS pruned_reason TEXT;
@@ -0,0 +1,11 @@
+ALTER TABLE server_game_configs
+    DROP COLUMN IF EXISTS cpu_millicores,
+    DROP COLUMN IF EXISTS memory_mb,
+    DROP COLUMN IF EXISTS pids_limit,
+    DROP COLUMN IF EXISTS ulimits;
+
+ALTER TABLE game_configs
+    DROP COLUMN IF EXISTS cpu_millicores,
+    DROP COLUMN IF EXISTS memory_mb,
+    DROP COLUMN IF EXISTS pids_limit,
+    DROP COLUMN IF EXISTS ulimits;
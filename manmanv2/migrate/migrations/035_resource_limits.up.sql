@@ -0,0 +1,13 @@
+-- Container resource limits. On game_configs NULL means unlimited; on server_game_configs
+-- NULL inherits the game config's value. ulimits maps a ulimit name to {"soft": n, "hard": n}.
+ALTER TABLE game_configs
+    ADD COLUMN IF NOT EXISTS cpu_millicores INT CHECK (cpu_millicores > 0),
+    ADD COLUMN IF NOT EXISTS memory_mb      INT CHECK (memory_mb > 0),
+    ADD COLUMN IF NOT EXISTS pids_limit     INT CHECK (pids_limit > 0),
+    ADD COLUMN IF NOT EXISTS ulimits        JSONB;
+
+ALTER TABLE server_game_configs
+    ADD COLUMN IF NOT EXISTS cpu_millicores INT CHECK (cpu_millicores > 0),
+    ADD COLUMN IF NOT EXISTS memory_mb      INT CHECK (memory_mb > 0),
+    ADD COLUMN IF NOT EXISTS pids_limit     INT CHECK (pids_limit > 0),
+    ADD COLUMN IF NOT EXISTS ulimits        JSONB;
@@ -0,0 +1,3 @@
+UPDATE sessions SET status = 'running' WHERE status = 'ready';
+
+ALTER TABLE game_configs DROP COLUMN IF EXISTS readiness_probe;
@@ -0,0 +1,5 @@
+-- Readiness probe for a game config's containers. A session moves from running to ready
+-- once the probe passes, or is marked crashed if it doesn't pass within the timeout.
+-- Shape: {"type": "log"|"tcp"|"udp"|"exec", "log_pattern": "...", "port": 27015,
+--         "command": [...], "timeout_seconds": 300, "interval_seconds": 5}
+ALTER TABLE game_configs ADD COLUMN IF NOT EXISTS readiness_probe JSONB;
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_sessions_previous_session_id;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS restart_abandoned_reason,
+    DROP COLUMN IF EXISTS restart_attempt,
+    DROP COLUMN IF EXISTS previous_session_id;
+
+ALTER TABLE server_game_configs DROP COLUMN IF EXISTS restart_policy;
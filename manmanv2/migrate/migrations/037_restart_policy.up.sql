@@ -0,0 +1,15 @@
+-- Crash restart policy for a server game config. The host restarts a crashed session
+-- by starting a new session linked to the one that crashed.
+-- Shape: {"mode": "never"|"on-failure"|"always", "max_attempts": 5, "backoff_seconds": 10,
+--         "max_backoff_seconds": 300, "crash_window_seconds": 600, "max_crashes_in_window": 5}
+ALTER TABLE server_game_configs ADD COLUMN IF NOT EXISTS restart_policy JSONB;
+
+-- previous_session_id links an automatic restart to the session that crashed.
+-- restart_attempt counts consecutive automatic restarts (0 for a manual start).
+-- restart_abandoned_reason is set on a crashed session when the host gave up restarting it.
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS previous_session_id      BIGINT REFERENCES sessions(session_id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS restart_attempt          INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS restart_abandoned_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_session_id ON sessions(previous_session_id);
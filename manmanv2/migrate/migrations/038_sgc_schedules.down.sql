@@ -0,0 +1,8 @@
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS idle_since,
+    DROP COLUMN IF EXISTS player_count;
+
+ALTER TABLE server_game_configs DROP COLUMN IF EXISTS idle_shutdown;
+
+DROP INDEX IF EXISTS idx_sgc_schedules_sgc_id;
+DROP TABLE IF EXISTS sgc_schedules;
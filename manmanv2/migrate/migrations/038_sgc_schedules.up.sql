@@ -0,0 +1,31 @@
+-- Cron schedules that start and stop an SGC's session. Crons are standard 5-field expressions
+-- evaluated in timezone; a schedule may have only a start or only a stop cron.
+CREATE TABLE IF NOT EXISTS sgc_schedules (
+    schedule_id       BIGSERIAL PRIMARY KEY,
+    sgc_id            BIGINT  NOT NULL REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    start_cron        TEXT,
+    stop_cron         TEXT,
+    timezone          TEXT    NOT NULL DEFAULT 'UTC',
+    backup_config_id  BIGINT  REFERENCES backup_configs(backup_config_id) ON DELETE SET NULL, -- backed up before a scheduled stop
+    enabled           BOOLEAN NOT NULL DEFAULT true,
+    last_evaluated_at TIMESTAMP, -- occurrences up to here have been acted on
+    last_start_at     TIMESTAMP,
+    last_stop_at      TIMESTAMP,
+    created_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at        TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (start_cron IS NOT NULL OR stop_cron IS NOT NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sgc_schedules_sgc_id ON sgc_schedules(sgc_id);
+
+-- Idle shutdown rule for a server game config: stop the session after idle_minutes with
+-- zero players. The host counts players with the player_count probe.
+-- Shape: {"idle_minutes": 30, "player_count": {"type": "log"|"exec", "join_pattern": "...",
+--         "leave_pattern": "...", "count_pattern": "...", "command": [...], "interval_seconds": 60}}
+ALTER TABLE server_game_configs ADD COLUMN IF NOT EXISTS idle_shutdown JSONB;
+
+-- player_count is the last count the host reported; idle_since is when it last dropped to zero
+-- (NULL while players are online or when the SGC has no player count probe).
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS player_count INT,
+    ADD COLUMN IF NOT EXISTS idle_since   TIMESTAMP;
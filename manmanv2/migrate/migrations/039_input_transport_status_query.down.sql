@@ -0,0 +1,5 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS max_players;
+ALTER TABLE sessions DROP COLUMN IF EXISTS map_name;
+
+ALTER TABLE game_configs DROP COLUMN IF EXISTS status_query;
+ALTER TABLE game_configs DROP COLUMN IF EXISTS input_transport;
@@ -0,0 +1,13 @@
+-- How SendInput and actions reach a game config's containers.
+-- Shape: {"type": "stdin"|"rcon"|"websocket-rcon", "port": 25575,
+--         "password_env": "RCON_PASSWORD", "password_arg": "+rcon_password"}
+-- NULL means stdin.
+ALTER TABLE game_configs ADD COLUMN IF NOT EXISTS input_transport JSONB;
+
+-- Game query protocol the host polls for live player count and map.
+-- Shape: {"type": "a2s"|"minecraft", "port": 27015, "interval_seconds": 30}
+ALTER TABLE game_configs ADD COLUMN IF NOT EXISTS status_query JSONB;
+
+-- Last map and player cap reported by the status query
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS map_name TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS max_players INT;
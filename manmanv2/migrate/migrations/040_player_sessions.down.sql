@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_player_sessions_open;
+DROP INDEX IF EXISTS idx_player_sessions_sgc_joined;
+DROP INDEX IF EXISTS idx_player_sessions_session_id;
+DROP TABLE IF EXISTS player_sessions;
+
+ALTER TABLE games DROP COLUMN IF EXISTS player_events;
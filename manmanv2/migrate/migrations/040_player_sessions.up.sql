@@ -0,0 +1,25 @@
+-- Regexes the log-processor matches against a game's log lines to track players.
+-- Shape: {"join_pattern": "...", "leave_pattern": "...", "chat_pattern": "..."}
+-- Each captures the player's name in (?P<player>...), optionally a stable ID in (?P<id>...).
+-- NULL means the game's logs aren't scanned for players.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS player_events JSONB;
+
+-- One row per player stay on a session: opened by a join line, closed by the matching leave
+-- line or by the session ending.
+CREATE TABLE IF NOT EXISTS player_sessions (
+    player_session_id BIGSERIAL PRIMARY KEY,
+    session_id        BIGINT    NOT NULL REFERENCES sessions(session_id) ON DELETE CASCADE,
+    sgc_id            BIGINT    NOT NULL REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    player_name       TEXT      NOT NULL,
+    player_id         TEXT,
+    joined_at         TIMESTAMP NOT NULL,
+    left_at           TIMESTAMP,
+    chat_messages     INT       NOT NULL DEFAULT 0,
+    last_seen_at      TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_player_sessions_session_id ON player_sessions(session_id);
+CREATE INDEX IF NOT EXISTS idx_player_sessions_sgc_joined ON player_sessions(sgc_id, joined_at);
+-- At most one open stay per player per session
+CREATE UNIQUE INDEX IF NOT EXISTS idx_player_sessions_open
+    ON player_sessions(session_id, player_name) WHERE left_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_sgc_grants_subject;
+DROP TABLE IF EXISTS sgc_grants;
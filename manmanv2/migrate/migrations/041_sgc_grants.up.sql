@@ -0,0 +1,13 @@
+-- Per-SGC operator grants: subject may start, stop and send input to this SGC's sessions
+-- without holding the global manman-operator role. subject is the token's "sub" claim.
+CREATE TABLE IF NOT EXISTS sgc_grants (
+    grant_id   BIGSERIAL PRIMARY KEY,
+    sgc_id     BIGINT    NOT NULL REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    subject    TEXT      NOT NULL,
+    note       TEXT      NOT NULL DEFAULT '',
+    created_by TEXT      NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (sgc_id, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_sgc_grants_subject ON sgc_grants(subject);
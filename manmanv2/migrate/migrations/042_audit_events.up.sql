@@ -0,0 +1,30 @@
+-- Append-only record of every mutating ManManAPI / WorkshopService call, written by the
+-- API's audit interceptor.
+CREATE TABLE IF NOT EXISTS audit_events (
+    audit_event_id BIGSERIAL PRIMARY KEY,
+    occurred_at    TIMESTAMP NOT NULL DEFAULT NOW(),
+    subject        TEXT      NOT NULL DEFAULT '',  -- the caller's "sub" claim
+    username       TEXT      NOT NULL DEFAULT '',  -- the caller's preferred_username, if known
+    method         TEXT      NOT NULL,             -- RPC name, e.g. "StartSession"
+    entities       JSONB     NOT NULL DEFAULT '{}', -- target IDs, e.g. {"session_id": 12}
+    request        JSONB,                          -- the request with secrets redacted
+    status_code    TEXT      NOT NULL,             -- gRPC code name, "OK" on success
+    error          TEXT,
+    duration_ms    BIGINT    NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events(occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_subject ON audit_events(subject, occurred_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_entities ON audit_events USING GIN (entities);
+
+-- Rows are never changed or removed once written
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_events_append_only ON audit_events;
+CREATE TRIGGER audit_events_append_only
+    BEFORE UPDATE OR DELETE ON audit_events
+    FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,37 @@
+-- Outgoing webhooks for external lifecycle events, delivered by the notifier service.
+-- event_patterns are AMQP topic patterns matched against the external exchange's
+-- routing keys ("manman.session.crashed", "manman.backup.*", "manman.#").
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id     BIGSERIAL PRIMARY KEY,
+    name           TEXT      NOT NULL,
+    url            TEXT      NOT NULL,
+    format         TEXT      NOT NULL DEFAULT 'json', -- 'json' | 'discord' | 'slack'
+    secret         TEXT      NOT NULL DEFAULT '',     -- HMAC key for X-ManMan-Signature; empty sends unsigned
+    event_patterns TEXT[]    NOT NULL DEFAULT '{}',
+    sgc_ids        BIGINT[]  NOT NULL DEFAULT '{}',   -- only events for these SGCs; empty for all
+    enabled        BOOLEAN   NOT NULL DEFAULT TRUE,
+    created_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT webhooks_format_check CHECK (format IN ('json', 'discord', 'slack'))
+);
+
+-- One row per (event, webhook). Rows are retried with backoff until delivered or,
+-- after the last attempt or a permanent rejection, left as 'dead' for inspection and
+-- manual redelivery.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id      BIGSERIAL PRIMARY KEY,
+    webhook_id       BIGINT    NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    routing_key      TEXT      NOT NULL,
+    payload          JSONB     NOT NULL, -- the event as published
+    status           TEXT      NOT NULL DEFAULT 'pending', -- 'pending' | 'sending' | 'delivered' | 'dead'
+    attempts         INT       NOT NULL DEFAULT 0,
+    last_status_code INT,
+    last_error       TEXT,
+    next_attempt_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    created_at       TIMESTAMP NOT NULL DEFAULT NOW(),
+    delivered_at     TIMESTAMP,
+    CONSTRAINT webhook_deliveries_status_check CHECK (status IN ('pending', 'sending', 'delivered', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, delivery_id DESC);
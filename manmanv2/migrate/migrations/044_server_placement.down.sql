@@ -0,0 +1,3 @@
+ALTER TABLE server_capabilities DROP COLUMN IF EXISTS port_ranges;
+DROP INDEX IF EXISTS idx_servers_labels;
+ALTER TABLE servers DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,12 @@
+-- Placement inputs: labels a host reports (region, hardware, ...) that deployments can
+-- require or prefer, and the host port ranges automatic port picking may use
+
+ALTER TABLE servers ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX idx_servers_labels ON servers USING GIN (labels);
+
+COMMENT ON COLUMN servers.labels IS 'Key/value labels for placement constraints, e.g. {"region": "eu"}';
+
+ALTER TABLE server_capabilities ADD COLUMN port_ranges JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN server_capabilities.port_ranges IS 'Host port ranges open for game servers: [{"start", "end", "protocol"}]';
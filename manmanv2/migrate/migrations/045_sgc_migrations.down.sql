@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_sgc_migrations_in_flight;
+DROP INDEX IF EXISTS idx_sgc_migrations_source;
+DROP TABLE IF EXISTS sgc_migrations;
+
+ALTER TABLE backups DROP COLUMN IF EXISTS checksum;
@@ -0,0 +1,32 @@
+-- Checksum of a backup archive (sha256, hex) as uploaded by the host. Restores report the
+-- checksum of what they downloaded so the two can be compared.
+ALTER TABLE backups ADD COLUMN IF NOT EXISTS checksum TEXT;
+
+-- Moves of an SGC and its volume data to another server, driven step by step by the
+-- processor. port_bindings are the host ports picked on the target when the migration was
+-- requested; volumes tracks each volume's backup on the source and restore on the target.
+CREATE TABLE IF NOT EXISTS sgc_migrations (
+    migration_id     BIGSERIAL   PRIMARY KEY,
+    source_sgc_id    BIGINT      NOT NULL REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    source_server_id BIGINT      NOT NULL REFERENCES servers(server_id) ON DELETE CASCADE,
+    source_status    VARCHAR(50) NOT NULL,  -- the source SGC's status before the migration, restored on failure
+    target_server_id BIGINT      NOT NULL REFERENCES servers(server_id) ON DELETE CASCADE,
+    target_sgc_id    BIGINT      REFERENCES server_game_configs(sgc_id) ON DELETE SET NULL,
+    port_bindings    JSONB       NOT NULL DEFAULT '[]',
+    volumes          JSONB       NOT NULL DEFAULT '[]',
+    start_session    BOOLEAN     NOT NULL DEFAULT FALSE,
+    status           VARCHAR(50) NOT NULL DEFAULT 'pending',
+    error_message    TEXT,
+    created_at       TIMESTAMP   NOT NULL DEFAULT NOW(),
+    updated_at       TIMESTAMP   NOT NULL DEFAULT NOW(),
+    completed_at     TIMESTAMP,
+    CONSTRAINT sgc_migrations_status_check CHECK (status IN (
+        'pending', 'stopping', 'backing_up', 'creating_target', 'restoring', 'verifying', 'completed', 'failed'
+    ))
+);
+
+CREATE INDEX IF NOT EXISTS idx_sgc_migrations_source ON sgc_migrations(source_sgc_id, migration_id DESC);
+
+-- An SGC can only be moving one way at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sgc_migrations_in_flight ON sgc_migrations(source_sgc_id)
+    WHERE status NOT IN ('completed', 'failed');
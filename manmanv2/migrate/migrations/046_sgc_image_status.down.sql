@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sgc_image_status;
@@ -0,0 +1,11 @@
+-- Image state of each SGC as last reported by its host's background image refresh.
+-- local_digest is what the SGC runs (or the host has cached), remote_digest what its tag
+-- points at in the registry; an update is available when both are known and differ.
+CREATE TABLE IF NOT EXISTS sgc_image_status (
+    sgc_id        BIGINT    PRIMARY KEY REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    image         TEXT      NOT NULL,
+    local_digest  TEXT,
+    remote_digest TEXT,
+    error_message TEXT,
+    checked_at    TIMESTAMP NOT NULL DEFAULT NOW()
+);
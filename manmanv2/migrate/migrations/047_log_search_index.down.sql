@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_log_refs_minute;
+DROP INDEX IF EXISTS idx_log_refs_terms;
+ALTER TABLE log_references DROP COLUMN IF EXISTS terms;
+ALTER TABLE log_references DROP COLUMN IF EXISTS sources;
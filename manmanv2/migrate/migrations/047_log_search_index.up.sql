@@ -0,0 +1,11 @@
+-- Search index of each archived minute window, written by the archiver as it uploads.
+-- sources holds the log sources present (stdout | stderr | host) and terms the distinct
+-- lowercased words. NULL means the window isn't indexed: it was archived before the
+-- index existed, or had more distinct words than the archiver indexes. Searches always
+-- scan unindexed windows.
+ALTER TABLE log_references ADD COLUMN sources TEXT[];
+ALTER TABLE log_references ADD COLUMN terms TEXT[];
+
+CREATE INDEX idx_log_refs_terms ON log_references USING GIN (terms);
+-- Searches that aren't scoped to one SGC range over every window
+CREATE INDEX idx_log_refs_minute ON log_references(minute_timestamp);
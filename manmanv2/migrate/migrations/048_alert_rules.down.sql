@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_alert_events_session;
+DROP INDEX IF EXISTS idx_alert_events_sgc;
+DROP INDEX IF EXISTS idx_alert_events_rule_sgc;
+DROP TABLE IF EXISTS alert_events;
+
+DROP INDEX IF EXISTS idx_alert_rules_sgc_id;
+DROP INDEX IF EXISTS idx_alert_rules_game_id;
+DROP TABLE IF EXISTS alert_rules;
@@ -0,0 +1,41 @@
+-- Log alert rules, on every SGC of a game or on one SGC. The log-processor matches pattern
+-- against each live log line; a rule fires when threshold lines match within window_seconds
+-- and stays quiet for cooldown_seconds afterwards.
+CREATE TABLE IF NOT EXISTS alert_rules (
+    rule_id          BIGSERIAL PRIMARY KEY,
+    game_id          BIGINT  REFERENCES games(game_id) ON DELETE CASCADE,
+    sgc_id           BIGINT  REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    name             TEXT    NOT NULL,
+    pattern          TEXT    NOT NULL,
+    threshold        INT     NOT NULL DEFAULT 1 CHECK (threshold > 0),
+    window_seconds   INT     NOT NULL DEFAULT 60 CHECK (window_seconds > 0),
+    cooldown_seconds INT     NOT NULL DEFAULT 900 CHECK (cooldown_seconds >= 0),
+    action_id        BIGINT  REFERENCES action_definitions(action_id) ON DELETE SET NULL, -- run on the session when fired
+    backup_config_id BIGINT  REFERENCES backup_configs(backup_config_id) ON DELETE SET NULL, -- taken when fired
+    enabled          BOOLEAN NOT NULL DEFAULT true,
+    created_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at       TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((game_id IS NULL) <> (sgc_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_rules_game_id ON alert_rules(game_id);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_sgc_id ON alert_rules(sgc_id);
+
+-- Each firing of a rule, recorded by the processor, with what its action and backup did
+CREATE TABLE IF NOT EXISTS alert_events (
+    alert_id            BIGSERIAL PRIMARY KEY,
+    rule_id             BIGINT    NOT NULL REFERENCES alert_rules(rule_id) ON DELETE CASCADE,
+    session_id          BIGINT    NOT NULL REFERENCES sessions(session_id) ON DELETE CASCADE,
+    sgc_id              BIGINT    NOT NULL REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    match_count         INT       NOT NULL,
+    line                TEXT      NOT NULL, -- the line that reached the threshold
+    first_match_at      TIMESTAMP NOT NULL,
+    fired_at            TIMESTAMP NOT NULL,
+    action_execution_id BIGINT,
+    backup_id           BIGINT,
+    response_error      TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_events_rule_sgc ON alert_events(rule_id, sgc_id, fired_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_events_sgc ON alert_events(sgc_id, fired_at DESC);
+CREATE INDEX IF NOT EXISTS idx_alert_events_session ON alert_events(session_id);
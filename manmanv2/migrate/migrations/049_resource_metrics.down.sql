@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS host_metrics;
+
+DROP INDEX IF EXISTS idx_session_metrics_server;
+DROP TABLE IF EXISTS session_metrics;
@@ -0,0 +1,35 @@
+-- Resource usage the host managers sample, bucketed by the processor. Samples land in
+-- 60-second buckets; a periodic job rolls buckets older than a day into 3600-second ones
+-- and drops hourly buckets once they pass the retention period. Averages are the sums
+-- divided by samples.
+CREATE TABLE IF NOT EXISTS session_metrics (
+    session_id                      BIGINT    NOT NULL REFERENCES sessions(session_id) ON DELETE CASCADE,
+    resolution_seconds              INT       NOT NULL,
+    bucket_start                    TIMESTAMP NOT NULL,
+    server_id                       BIGINT    NOT NULL REFERENCES servers(server_id) ON DELETE CASCADE,
+    sgc_id                          BIGINT    NOT NULL REFERENCES server_game_configs(sgc_id) ON DELETE CASCADE,
+    samples                         INT       NOT NULL,
+    cpu_percent_sum                 DOUBLE PRECISION NOT NULL, -- 100 is one full core
+    cpu_percent_max                 DOUBLE PRECISION NOT NULL,
+    memory_bytes_sum                DOUBLE PRECISION NOT NULL,
+    memory_bytes_max                BIGINT    NOT NULL,
+    memory_limit_bytes              BIGINT    NOT NULL,
+    network_rx_bytes_per_sec_sum    DOUBLE PRECISION NOT NULL,
+    network_tx_bytes_per_sec_sum    DOUBLE PRECISION NOT NULL,
+    block_read_bytes_per_sec_sum    DOUBLE PRECISION NOT NULL,
+    block_write_bytes_per_sec_sum   DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (session_id, resolution_seconds, bucket_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_session_metrics_server ON session_metrics(server_id, bucket_start);
+
+-- Disk usage of each host's session data directory
+CREATE TABLE IF NOT EXISTS host_metrics (
+    server_id           BIGINT    NOT NULL REFERENCES servers(server_id) ON DELETE CASCADE,
+    resolution_seconds  INT       NOT NULL,
+    bucket_start        TIMESTAMP NOT NULL,
+    samples             INT       NOT NULL,
+    disk_total_bytes    BIGINT    NOT NULL,
+    disk_used_bytes_max BIGINT    NOT NULL,
+    PRIMARY KEY (server_id, resolution_seconds, bucket_start)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_workshop_installations_update_available;
+ALTER TABLE workshop_installations DROP COLUMN IF EXISTS update_available;
+
+ALTER TABLE server_game_configs DROP COLUMN IF EXISTS addon_update_policy;
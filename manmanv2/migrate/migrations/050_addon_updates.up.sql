@@ -0,0 +1,11 @@
+-- What the processor does when a workshop addon installed on an SGC gets an update beyond
+-- flagging the installation: 'off' nothing, 'notify' publishes manman.sgc.addon_update_available
+-- and 'reinstall' also re-downloads the addon once the SGC has no active session.
+ALTER TABLE server_game_configs ADD COLUMN IF NOT EXISTS addon_update_policy VARCHAR(20) NOT NULL DEFAULT 'notify'
+    CHECK (addon_update_policy IN ('off', 'notify', 'reinstall'));
+
+-- Set when the addon changed upstream after it was installed; cleared once it is installed again
+ALTER TABLE workshop_installations ADD COLUMN IF NOT EXISTS update_available BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_workshop_installations_update_available
+    ON workshop_installations(sgc_id) WHERE update_available;
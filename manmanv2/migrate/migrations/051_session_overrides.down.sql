@@ -0,0 +1,2 @@
+ALTER TABLE sessions DROP COLUMN IF EXISTS env_overrides;
+ALTER TABLE sessions DROP COLUMN IF EXISTS image_tag;
@@ -0,0 +1,5 @@
+-- Overrides given when a session was started: an alternate tag for the game config's image
+-- and env vars merged over its env_template. Session-level configuration patches live in
+-- configuration_patches with patch_level 'session'.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS image_tag TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS env_overrides JSONB;
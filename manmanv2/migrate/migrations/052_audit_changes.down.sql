@@ -0,0 +1 @@
+ALTER TABLE audit_events DROP COLUMN IF EXISTS changes;
@@ -0,0 +1,3 @@
+-- What an update changed: {"field": {"before": ..., "after": ...}} for each field whose
+-- value differs, read from the entity before and after the call
+ALTER TABLE audit_events ADD COLUMN IF NOT EXISTS changes JSONB;
@@ -0,0 +1,12 @@
+-- Unlimited overrides can't be represented; they fall back to inheriting
+UPDATE server_game_configs SET cpu_millicores = NULL WHERE cpu_millicores = 0;
+UPDATE server_game_configs SET memory_mb = NULL WHERE memory_mb = 0;
+UPDATE server_game_configs SET pids_limit = NULL WHERE pids_limit = 0;
+
+ALTER TABLE server_game_configs
+    DROP CONSTRAINT IF EXISTS server_game_configs_cpu_millicores_check,
+    DROP CONSTRAINT IF EXISTS server_game_configs_memory_mb_check,
+    DROP CONSTRAINT IF EXISTS server_game_configs_pids_limit_check,
+    ADD CONSTRAINT server_game_configs_cpu_millicores_check CHECK (cpu_millicores > 0),
+    ADD CONSTRAINT server_game_configs_memory_mb_check CHECK (memory_mb > 0),
+    ADD CONSTRAINT server_game_configs_pids_limit_check CHECK (pids_limit > 0);
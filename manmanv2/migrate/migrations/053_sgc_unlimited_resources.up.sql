@@ -0,0 +1,9 @@
+-- A server game config can override its game config's limit with 0, meaning unlimited;
+-- NULL still inherits it
+ALTER TABLE server_game_configs
+    DROP CONSTRAINT IF EXISTS server_game_configs_cpu_millicores_check,
+    DROP CONSTRAINT IF EXISTS server_game_configs_memory_mb_check,
+    DROP CONSTRAINT IF EXISTS server_game_configs_pids_limit_check,
+    ADD CONSTRAINT server_game_configs_cpu_millicores_check CHECK (cpu_millicores >= 0),
+    ADD CONSTRAINT server_game_configs_memory_mb_check CHECK (memory_mb >= 0),
+    ADD CONSTRAINT server_game_configs_pids_limit_check CHECK (pids_limit >= 0);
@@ -0,0 +1,171 @@
+package manman
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the @ shorthands accepted in place of five fields
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// CronSchedule is a parsed five-field cron expression: minute hour day-of-month month day-of-week.
+// Fields accept *, numbers, ranges (a-b), steps (*/n, a-b/n) and comma lists. Day-of-week
+// is 0-7 with 0 and 7 both Sunday. As in standard cron, when both day fields are restricted
+// a time matches if either does.
+type CronSchedule struct {
+	minutes    [60]bool
+	hours      [24]bool
+	days       [32]bool
+	months     [13]bool
+	weekdays   [7]bool
+	anyDay     bool // day-of-month is *
+	anyWeekday bool // day-of-week is *
+	loc        *time.Location
+}
+
+// ParseCron parses a cron expression evaluated in loc (UTC when nil)
+func ParseCron(expr string, loc *time.Location) (*CronSchedule, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	c := &CronSchedule{loc: loc}
+	if err := parseCronField(fields[0], 0, 59, c.minutes[:]); err != nil {
+		return nil, fmt.Errorf("minute: %w", err)
+	}
+	if err := parseCronField(fields[1], 0, 23, c.hours[:]); err != nil {
+		return nil, fmt.Errorf("hour: %w", err)
+	}
+	if err := parseCronField(fields[2], 1, 31, c.days[:]); err != nil {
+		return nil, fmt.Errorf("day of month: %w", err)
+	}
+	if err := parseCronField(fields[3], 1, 12, c.months[:]); err != nil {
+		return nil, fmt.Errorf("month: %w", err)
+	}
+	var weekdays [8]bool
+	if err := parseCronField(fields[4], 0, 7, weekdays[:]); err != nil {
+		return nil, fmt.Errorf("day of week: %w", err)
+	}
+	copy(c.weekdays[:], weekdays[:7])
+	c.weekdays[0] = c.weekdays[0] || weekdays[7]
+	c.anyDay = fields[2] == "*"
+	c.anyWeekday = fields[4] == "*"
+	return c, nil
+}
+
+// parseCronField sets set[v] for every value the field selects within [first, last]
+func parseCronField(field string, first, last int, set []bool) error {
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			rangePart = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+		}
+
+		lo, hi := first, last
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return fmt.Errorf("invalid range %q", part)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return fmt.Errorf("invalid range %q", part)
+			}
+		default:
+			n, err := strconv.Atoi(rangePart)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = last
+			}
+		}
+		if lo < first || hi > last || lo > hi {
+			return fmt.Errorf("%q is outside %d-%d", part, first, last)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+// dayMatches applies the day-of-month / day-of-week rules to t's date
+func (c *CronSchedule) dayMatches(t time.Time) bool {
+	dom, dow := c.days[t.Day()], c.weekdays[t.Weekday()]
+	switch {
+	case c.anyDay && c.anyWeekday:
+		return true
+	case c.anyDay:
+		return dow
+	case c.anyWeekday:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// Next returns the first time strictly after t that the schedule fires, or the zero time
+// if it never fires within five years (e.g. 30 February).
+func (c *CronSchedule) Next(t time.Time) time.Time {
+	t = t.In(c.loc)
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, c.loc)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !c.months[t.Month()] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.hours[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, c.loc)
+			continue
+		}
+		if !c.minutes[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// Last returns the latest time in (after, until] that the schedule fires, if any
+func (c *CronSchedule) Last(after, until time.Time) (time.Time, bool) {
+	var last time.Time
+	found := false
+	for next := c.Next(after); !next.IsZero() && !next.After(until); next = c.Next(next) {
+		last, found = next, true
+	}
+	return last, found
+}
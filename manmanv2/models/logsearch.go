@@ -0,0 +1,69 @@
+package manman
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// MaxLogTermLength caps indexed words; longer ones are cut to this many runes, on both
+// the index and the query side
+const MaxLogTermLength = 64
+
+// MaxLogWindowTerms is the most distinct words a minute window's search index holds. A
+// noisier window is left unindexed and every search scans it.
+const MaxLogWindowTerms = 4096
+
+// ArchivedLogLine is one line of an archived minute window
+type ArchivedLogLine struct {
+	Timestamp time.Time
+	Source    string // stdout | stderr | host
+	Message   string
+}
+
+// FormatArchivedLogLine renders a line the way the archiver stores it:
+// "[timestamp] [source] message"
+func FormatArchivedLogLine(timestamp time.Time, source, message string) string {
+	return fmt.Sprintf("[%s] [%s] %s\n", timestamp.Format(time.RFC3339), source, message)
+}
+
+// ParseArchivedLogLine reverses FormatArchivedLogLine. Lines that don't follow the
+// format, like the separator written when a window is appended to, give false.
+func ParseArchivedLogLine(line string) (ArchivedLogLine, bool) {
+	rest, ok := strings.CutPrefix(strings.TrimRight(line, "\r\n"), "[")
+	if !ok {
+		return ArchivedLogLine{}, false
+	}
+	ts, rest, ok := strings.Cut(rest, "] [")
+	if !ok {
+		return ArchivedLogLine{}, false
+	}
+	source, message, ok := strings.Cut(rest, "] ")
+	if !ok {
+		// An empty message leaves no space after the source
+		source, ok = strings.CutSuffix(rest, "]")
+		if !ok {
+			return ArchivedLogLine{}, false
+		}
+	}
+	timestamp, err := time.Parse(time.RFC3339, ts)
+	if err != nil {
+		return ArchivedLogLine{}, false
+	}
+	return ArchivedLogLine{Timestamp: timestamp, Source: source, Message: message}, true
+}
+
+// LogTerms splits a log message into the lowercased words the search index holds:
+// runs of letters and digits, cut to MaxLogTermLength. Duplicates are kept.
+func LogTerms(message string) []string {
+	words := strings.FieldsFunc(strings.ToLower(message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, w := range words {
+		if r := []rune(w); len(r) > MaxLogTermLength {
+			words[i] = string(r[:MaxLogTermLength])
+		}
+	}
+	return words
+}
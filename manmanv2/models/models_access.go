@@ -0,0 +1,14 @@
+package manman
+
+import "time"
+
+// SGCGrant lets a subject operate one SGC (start, stop and send input to its sessions)
+// without holding the global operator role
+type SGCGrant struct {
+	GrantID   int64     `db:"grant_id"`
+	SGCID     int64     `db:"sgc_id"`
+	Subject   string    `db:"subject"` // the token's "sub" claim
+	Note      string    `db:"note"`
+	CreatedBy string    `db:"created_by"`
+	CreatedAt time.Time `db:"created_at"`
+}
@@ -0,0 +1,67 @@
+package manman
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// AlertRule watches the live logs of a game's SGCs, or of one SGC, for a pattern. It fires
+// when Threshold lines match within WindowSeconds, then stays quiet for CooldownSeconds.
+type AlertRule struct {
+	RuleID          int64     `db:"rule_id"`
+	GameID          *int64    `db:"game_id"` // set for a rule on every SGC of a game
+	SGCID           *int64    `db:"sgc_id"`  // set for a rule on one SGC
+	Name            string    `db:"name"`
+	Pattern         string    `db:"pattern"` // RE2, matched against each log line
+	Threshold       int32     `db:"threshold"`
+	WindowSeconds   int32     `db:"window_seconds"`
+	CooldownSeconds int32     `db:"cooldown_seconds"`
+	ActionID        *int64    `db:"action_id"`        // run on the session when fired
+	BackupConfigID  *int64    `db:"backup_config_id"` // taken when fired
+	Enabled         bool      `db:"enabled"`
+	CreatedAt       time.Time `db:"created_at"`
+	UpdatedAt       time.Time `db:"updated_at"`
+}
+
+// Validate checks the rule has exactly one scope, a pattern that compiles and a usable
+// threshold, window and cooldown
+func (r *AlertRule) Validate() error {
+	if (r.GameID == nil) == (r.SGCID == nil) {
+		return fmt.Errorf("a rule applies to either a game or a server game config")
+	}
+	if r.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if r.Pattern == "" {
+		return fmt.Errorf("pattern is required")
+	}
+	if _, err := regexp.Compile(r.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if r.Threshold < 1 {
+		return fmt.Errorf("threshold must be at least 1")
+	}
+	if r.WindowSeconds < 1 {
+		return fmt.Errorf("window_seconds must be at least 1")
+	}
+	if r.CooldownSeconds < 0 {
+		return fmt.Errorf("cooldown_seconds can't be negative")
+	}
+	return nil
+}
+
+// AlertEvent is one firing of an alert rule on a session
+type AlertEvent struct {
+	AlertID           int64     `db:"alert_id"`
+	RuleID            int64     `db:"rule_id"`
+	SessionID         int64     `db:"session_id"`
+	SGCID             int64     `db:"sgc_id"`
+	MatchCount        int32     `db:"match_count"`
+	Line              string    `db:"line"` // the line that reached the threshold
+	FirstMatchAt      time.Time `db:"first_match_at"`
+	FiredAt           time.Time `db:"fired_at"`
+	ActionExecutionID *int64    `db:"action_execution_id"`
+	BackupID          *int64    `db:"backup_id"`
+	ResponseError     *string   `db:"response_error"` // why the action or backup failed to start
+}
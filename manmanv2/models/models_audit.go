@@ -0,0 +1,18 @@
+package manman
+
+import "time"
+
+// AuditEvent records one mutating API call. Rows are append-only.
+type AuditEvent struct {
+	AuditEventID int64            `db:"audit_event_id"`
+	OccurredAt   time.Time        `db:"occurred_at"`
+	Subject      string           `db:"subject"`  // the caller's "sub" claim
+	Username     string           `db:"username"` // preferred_username, empty if unknown
+	Method       string           `db:"method"`   // RPC name, e.g. "StartSession"
+	Entities     map[string]int64 `db:"entities"` // target IDs keyed by field, e.g. {"session_id": 12}
+	Request      JSONB            `db:"request"`  // the request with secrets redacted
+	Changes      JSONB            `db:"changes"`  // for updates, {"field": {"before": ..., "after": ...}}
+	StatusCode   string           `db:"status_code"`
+	Error        *string          `db:"error"`
+	DurationMs   int64            `db:"duration_ms"`
+}
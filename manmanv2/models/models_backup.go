@@ -9,11 +9,13 @@ type Backup struct {
 	ServerGameConfigID int64     `db:"server_game_config_id"`
 	BackupConfigID     *int64    `db:"backup_config_id"` // nil for manual backups
 	VolumeID           *int64    `db:"volume_id"`
-	S3URL              *string   `db:"s3_url"`         // set on completion
-	SizeBytes          *int64    `db:"size_bytes"`     // set on completion
-	Status             string    `db:"status"`         // pending/running/completed/failed
+	S3URL              *string   `db:"s3_url"`     // set on completion
+	SizeBytes          *int64    `db:"size_bytes"` // set on completion
+	Checksum           *string   `db:"checksum"`   // sha256 of the archive, set on completion
+	Status             string    `db:"status"`     // pending/running/completed/failed
 	ErrorMessage       *string   `db:"error_message"`
 	Description        *string   `db:"description"`
+	PrunedReason       *string   `db:"pruned_reason"` // set when removed by the retention job
 	CreatedAt          time.Time `db:"created_at"`
 }
 
@@ -27,6 +29,19 @@ type BackupConfig struct {
 	LastBackupAt   *time.Time `db:"last_backup_at"`
 	CreatedAt      time.Time  `db:"created_at"`
 	UpdatedAt      time.Time  `db:"updated_at"`
+
+	// Retention rules, evaluated per SGC. 0 disables a rule.
+	RetentionKeepLast      int   `db:"retention_keep_last"`       // always keep the N newest
+	RetentionDailyDays     int   `db:"retention_daily_days"`      // newest per day, for N days
+	RetentionWeeklyWeeks   int   `db:"retention_weekly_weeks"`    // newest per ISO week, for N weeks
+	RetentionMonthlyMonths int   `db:"retention_monthly_months"`  // newest per month, for N months
+	RetentionMaxTotalBytes int64 `db:"retention_max_total_bytes"` // cap on the retained total size
+}
+
+// HasRetention returns true if any retention rule is enabled
+func (c BackupConfig) HasRetention() bool {
+	return c.RetentionKeepLast > 0 || c.RetentionDailyDays > 0 || c.RetentionWeeklyWeeks > 0 ||
+		c.RetentionMonthlyMonths > 0 || c.RetentionMaxTotalBytes > 0
 }
 
 // BackupConfigAction is an ordered pre-backup action for a BackupConfig
@@ -1,6 +1,12 @@
 package manman
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
 
 // Game represents a game definition (e.g., Minecraft, Valheim)
 type Game struct {
@@ -8,6 +14,8 @@ type Game struct {
 	Name       string  `db:"name"`
 	SteamAppID *string `db:"steam_app_id"`
 	Metadata   JSONB   `db:"metadata"`
+
+	PlayerEvents JSONB `db:"player_events"` // see PlayerEventPatterns; nil = logs aren't scanned for players
 }
 
 // GameConfig represents a preset/template for running a game
@@ -20,6 +28,16 @@ type GameConfig struct {
 	EnvTemplate  JSONB   `db:"env_template"`
 	Entrypoint   JSONB   `db:"entrypoint"` // []string stored as JSONB
 	Command      JSONB   `db:"command"`    // []string stored as JSONB
+
+	// Container resource limits; nil means unlimited
+	CPUMillicores *int32 `db:"cpu_millicores"`
+	MemoryMB      *int32 `db:"memory_mb"`
+	PidsLimit     *int32 `db:"pids_limit"`
+	Ulimits       JSONB  `db:"ulimits"` // name -> {"soft": n, "hard": n}
+
+	ReadinessProbe JSONB `db:"readiness_probe"` // see ReadinessProbe; nil = ready once started
+	InputTransport JSONB `db:"input_transport"` // see InputTransport; nil = stdin
+	StatusQuery    JSONB `db:"status_query"`    // see StatusQuery; nil = no live player count or map
 }
 
 // GameConfigVolume represents a volume mount configuration specific to a GameConfig
@@ -42,4 +60,550 @@ type ServerGameConfig struct {
 	GameConfigID int64  `db:"game_config_id"`
 	PortBindings JSONB  `db:"port_bindings"`
 	Status       string `db:"status"`
+
+	// Container resource limit overrides; nil inherits from the GameConfig
+	CPUMillicores *int32 `db:"cpu_millicores"`
+	MemoryMB      *int32 `db:"memory_mb"`
+	PidsLimit     *int32 `db:"pids_limit"`
+	Ulimits       JSONB  `db:"ulimits"` // merged over the GameConfig's ulimits by name
+
+	RestartPolicy JSONB `db:"restart_policy"` // nil means never restart
+	IdleShutdown  JSONB `db:"idle_shutdown"`  // see IdleShutdown; nil means never stopped for being idle
+
+	AddonUpdatePolicy string `db:"addon_update_policy"` // AddonUpdatePolicyOff | Notify | Reinstall
+}
+
+// SGCImageStatus is an SGC's image as last reported by its host
+type SGCImageStatus struct {
+	SGCID        int64     `db:"sgc_id"`
+	Image        string    `db:"image"`
+	LocalDigest  *string   `db:"local_digest"`  // what the SGC runs, or the host's cached copy
+	RemoteDigest *string   `db:"remote_digest"` // what the tag points at in the registry
+	ErrorMessage *string   `db:"error_message"`
+	CheckedAt    time.Time `db:"checked_at"`
+}
+
+// UpdateAvailable reports whether the registry has a different image than the SGC's
+func (s *SGCImageStatus) UpdateAvailable() bool {
+	return s.LocalDigest != nil && s.RemoteDigest != nil && *s.LocalDigest != *s.RemoteDigest
+}
+
+// ResourceLimits are the effective container limits for a session. Zero means unlimited.
+type ResourceLimits struct {
+	CPUMillicores int32    `json:"cpu_millicores,omitempty"`
+	MemoryMB      int32    `json:"memory_mb,omitempty"`
+	PidsLimit     int32    `json:"pids_limit,omitempty"`
+	Ulimits       []Ulimit `json:"ulimits,omitempty"`
+}
+
+// Ulimit is a named process limit, e.g. nofile
+type Ulimit struct {
+	Name string `json:"name"`
+	Soft int64  `json:"soft"`
+	Hard int64  `json:"hard"`
+}
+
+// IsZero returns true if no limit is set
+func (l ResourceLimits) IsZero() bool {
+	return l.CPUMillicores == 0 && l.MemoryMB == 0 && l.PidsLimit == 0 && len(l.Ulimits) == 0
+}
+
+// ResolveResourceLimits returns the effective limits for an SGC: each SGC value that is set
+// overrides the GameConfig's, so an SGC's 0 lifts the GameConfig's limit, and ulimits are
+// merged by name. sgc may be nil.
+func ResolveResourceLimits(gc *GameConfig, sgc *ServerGameConfig) ResourceLimits {
+	var limits ResourceLimits
+	ulimits := make(map[string]Ulimit)
+
+	apply := func(cpu, mem, pids *int32, raw JSONB) {
+		if cpu != nil {
+			limits.CPUMillicores = *cpu
+		}
+		if mem != nil {
+			limits.MemoryMB = *mem
+		}
+		if pids != nil {
+			limits.PidsLimit = *pids
+		}
+		for name, u := range UlimitsFromJSONB(raw) {
+			ulimits[name] = u
+		}
+	}
+	if gc != nil {
+		apply(gc.CPUMillicores, gc.MemoryMB, gc.PidsLimit, gc.Ulimits)
+	}
+	if sgc != nil {
+		apply(sgc.CPUMillicores, sgc.MemoryMB, sgc.PidsLimit, sgc.Ulimits)
+	}
+
+	names := make([]string, 0, len(ulimits))
+	for name := range ulimits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		limits.Ulimits = append(limits.Ulimits, ulimits[name])
+	}
+	return limits
+}
+
+// UlimitsFromJSONB decodes a ulimits column ({"nofile": {"soft": 1024, "hard": 4096}}).
+// Malformed entries are skipped.
+func UlimitsFromJSONB(raw JSONB) map[string]Ulimit {
+	ulimits := make(map[string]Ulimit, len(raw))
+	for name, v := range raw {
+		data, err := json.Marshal(v)
+		if err != nil {
+			continue
+		}
+		var u Ulimit
+		if err := json.Unmarshal(data, &u); err != nil {
+			continue
+		}
+		u.Name = name
+		ulimits[name] = u
+	}
+	return ulimits
+}
+
+// UlimitsToJSONB encodes ulimits for storage; nil when empty.
+func UlimitsToJSONB(ulimits []Ulimit) JSONB {
+	if len(ulimits) == 0 {
+		return nil
+	}
+	raw := make(JSONB, len(ulimits))
+	for _, u := range ulimits {
+		raw[u.Name] = map[string]interface{}{"soft": u.Soft, "hard": u.Hard}
+	}
+	return raw
+}
+
+// Readiness probe defaults
+const (
+	DefaultReadinessTimeout  = 5 * time.Minute
+	DefaultReadinessInterval = 5 * time.Second
+)
+
+// ReadinessProbe decides when a started game server is accepting players
+type ReadinessProbe struct {
+	Type            string   `json:"type"`                  // ReadinessProbeLog | TCP | UDP | Exec
+	LogPattern      string   `json:"log_pattern,omitempty"` // regex matched against each log line
+	Port            int32    `json:"port,omitempty"`        // container port for tcp/udp
+	Command         []string `json:"command,omitempty"`     // exec command
+	TimeoutSeconds  int32    `json:"timeout_seconds,omitempty"`
+	IntervalSeconds int32    `json:"interval_seconds,omitempty"`
+}
+
+// ReadinessProbeFromJSONB decodes a readiness_probe column. Returns nil when unset or malformed.
+func ReadinessProbeFromJSONB(raw JSONB) *ReadinessProbe {
+	var probe ReadinessProbe
+	if !decodeJSONB(raw, &probe) || probe.Type == "" {
+		return nil
+	}
+	return &probe
+}
+
+// ToJSONB encodes the probe for storage; nil for a nil probe.
+func (p *ReadinessProbe) ToJSONB() JSONB {
+	if p == nil {
+		return nil
+	}
+	return encodeJSONB(p)
+}
+
+// Validate checks the probe has what its type needs
+func (p *ReadinessProbe) Validate() error {
+	if p.TimeoutSeconds < 0 || p.IntervalSeconds < 0 {
+		return fmt.Errorf("timeout_seconds and interval_seconds must not be negative")
+	}
+	switch p.Type {
+	case ReadinessProbeLog:
+		if p.LogPattern == "" {
+			return fmt.Errorf("log_pattern is required for log probes")
+		}
+		if _, err := regexp.Compile(p.LogPattern); err != nil {
+			return fmt.Errorf("invalid log_pattern: %w", err)
+		}
+	case ReadinessProbeTCP, ReadinessProbeUDP:
+		if p.Port <= 0 || p.Port > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535 for %s probes", p.Type)
+		}
+	case ReadinessProbeExec:
+		if len(p.Command) == 0 {
+			return fmt.Errorf("command is required for exec probes")
+		}
+	default:
+		return fmt.Errorf("unknown readiness probe type %q", p.Type)
+	}
+	return nil
+}
+
+// Timeout returns how long the probe may take to pass before the session is marked crashed
+func (p *ReadinessProbe) Timeout() time.Duration {
+	if p.TimeoutSeconds <= 0 {
+		return DefaultReadinessTimeout
+	}
+	return time.Duration(p.TimeoutSeconds) * time.Second
+}
+
+// Interval returns the delay between tcp/udp/exec probe attempts
+func (p *ReadinessProbe) Interval() time.Duration {
+	if p.IntervalSeconds <= 0 {
+		return DefaultReadinessInterval
+	}
+	return time.Duration(p.IntervalSeconds) * time.Second
+}
+
+// DefaultStatusQueryInterval is the delay between status queries
+const DefaultStatusQueryInterval = 30 * time.Second
+
+// InputTransport is how SendInput and actions deliver commands to a game server. rcon and
+// websocket-rcon connect to a container port with a password taken from the session's
+// rendered config: an env var, the value following a CLI flag, or a key in a config file.
+type InputTransport struct {
+	Type         string `json:"type"`                    // InputTransportStdin | RCON | WebSocketRCON
+	Port         int32  `json:"port,omitempty"`          // container port RCON listens on
+	PasswordEnv  string `json:"password_env,omitempty"`  // env var holding the password, e.g. RCON_PASSWORD
+	PasswordArg  string `json:"password_arg,omitempty"`  // CLI flag followed by the password, e.g. +rcon_password
+	PasswordFile string `json:"password_file,omitempty"` // container path of a config file, e.g. /data/server.properties
+	PasswordKey  string `json:"password_key,omitempty"`  // key in password_file; dotted for JSON and YAML, e.g. rcon.password
+}
+
+// InputTransportFromJSONB decodes an input_transport column. Returns nil when unset or malformed.
+func InputTransportFromJSONB(raw JSONB) *InputTransport {
+	var transport InputTransport
+	if !decodeJSONB(raw, &transport) || transport.Type == "" {
+		return nil
+	}
+	return &transport
+}
+
+// ToJSONB encodes the transport for storage; nil for a nil transport.
+func (t *InputTransport) ToJSONB() JSONB {
+	if t == nil {
+		return nil
+	}
+	return encodeJSONB(t)
+}
+
+// Validate checks an RCON transport has a port and somewhere to find its password
+func (t *InputTransport) Validate() error {
+	switch t.Type {
+	case InputTransportStdin:
+		return nil
+	case InputTransportRCON, InputTransportWebSocketRCON:
+		if t.Port <= 0 || t.Port > 65535 {
+			return fmt.Errorf("port must be between 1 and 65535 for %s", t.Type)
+		}
+		if t.PasswordEnv == "" && t.PasswordArg == "" && t.PasswordFile == "" {
+			return fmt.Errorf("password_env, password_arg or password_file is required for %s", t.Type)
+		}
+		if (t.PasswordFile == "") != (t.PasswordKey == "") {
+			return fmt.Errorf("password_file and password_key must be set together")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown input transport %q", t.Type)
+	}
+}
+
+// StatusQuery asks a running game server for its player count and map over the game's own
+// query protocol
+type StatusQuery struct {
+	Type            string `json:"type"` // StatusQueryA2S | StatusQueryMinecraft
+	Port            int32  `json:"port"` // container port the query protocol listens on
+	IntervalSeconds int32  `json:"interval_seconds,omitempty"`
+}
+
+// StatusQueryFromJSONB decodes a status_query column. Returns nil when unset or malformed.
+func StatusQueryFromJSONB(raw JSONB) *StatusQuery {
+	var query StatusQuery
+	if !decodeJSONB(raw, &query) || query.Type == "" {
+		return nil
+	}
+	return &query
+}
+
+// ToJSONB encodes the query for storage; nil for a nil query.
+func (q *StatusQuery) ToJSONB() JSONB {
+	if q == nil {
+		return nil
+	}
+	return encodeJSONB(q)
+}
+
+// Validate checks the query has a known protocol and a port
+func (q *StatusQuery) Validate() error {
+	if q.Type != StatusQueryA2S && q.Type != StatusQueryMinecraft {
+		return fmt.Errorf("unknown status query type %q", q.Type)
+	}
+	if q.Port <= 0 || q.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535")
+	}
+	if q.IntervalSeconds < 0 {
+		return fmt.Errorf("interval_seconds must not be negative")
+	}
+	return nil
+}
+
+// Interval returns the delay between queries
+func (q *StatusQuery) Interval() time.Duration {
+	if q.IntervalSeconds <= 0 {
+		return DefaultStatusQueryInterval
+	}
+	return time.Duration(q.IntervalSeconds) * time.Second
+}
+
+// Restart policy defaults
+const (
+	DefaultRestartMaxAttempts = 5
+	DefaultRestartBackoff     = 10 * time.Second
+	DefaultRestartMaxBackoff  = 5 * time.Minute
+	DefaultRestartCrashWindow = 10 * time.Minute
+	DefaultRestartMaxCrashes  = 5
+)
+
+// RestartPolicy decides whether the host restarts a session whose container exited
+// unexpectedly. Restarts back off exponentially, and the host gives up once the SGC
+// crashes max_crashes_in_window times within crash_window_seconds.
+type RestartPolicy struct {
+	Mode               string `json:"mode"`                            // RestartPolicyNever | OnFailure | Always
+	MaxAttempts        int32  `json:"max_attempts,omitempty"`          // on-failure only
+	BackoffSeconds     int32  `json:"backoff_seconds,omitempty"`       // delay before the first restart, doubled per attempt
+	MaxBackoffSeconds  int32  `json:"max_backoff_seconds,omitempty"`   // cap on the doubled delay
+	CrashWindowSeconds int32  `json:"crash_window_seconds,omitempty"`  // crash loop detection window
+	MaxCrashesInWindow int32  `json:"max_crashes_in_window,omitempty"` // crashes within the window before giving up
+}
+
+// RestartPolicyFromJSONB decodes a restart_policy column. Returns nil when unset or malformed.
+func RestartPolicyFromJSONB(raw JSONB) *RestartPolicy {
+	var policy RestartPolicy
+	if !decodeJSONB(raw, &policy) || policy.Mode == "" {
+		return nil
+	}
+	return &policy
+}
+
+// ToJSONB encodes the policy for storage; nil for a nil policy.
+func (p *RestartPolicy) ToJSONB() JSONB {
+	if p == nil {
+		return nil
+	}
+	return encodeJSONB(p)
+}
+
+// Validate checks the mode is known and the limits aren't negative
+func (p *RestartPolicy) Validate() error {
+	switch p.Mode {
+	case RestartPolicyNever, RestartPolicyOnFailure, RestartPolicyAlways:
+	default:
+		return fmt.Errorf("unknown restart policy mode %q", p.Mode)
+	}
+	if p.MaxAttempts < 0 || p.BackoffSeconds < 0 || p.MaxBackoffSeconds < 0 ||
+		p.CrashWindowSeconds < 0 || p.MaxCrashesInWindow < 0 {
+		return fmt.Errorf("restart policy limits must not be negative")
+	}
+	if p.BackoffSeconds > 0 && p.MaxBackoffSeconds > 0 && p.MaxBackoffSeconds < p.BackoffSeconds {
+		return fmt.Errorf("max_backoff_seconds must be at least backoff_seconds")
+	}
+	return nil
+}
+
+// RestartsAfter reports whether the policy restarts a session after an unexpected exit.
+// failed is true for a non-zero exit code or a readiness probe that didn't pass.
+func (p *RestartPolicy) RestartsAfter(failed bool) bool {
+	if p == nil {
+		return false
+	}
+	switch p.Mode {
+	case RestartPolicyAlways:
+		return true
+	case RestartPolicyOnFailure:
+		return failed
+	default:
+		return false
+	}
+}
+
+// Attempts returns how many restarts in a row on-failure allows; 0 means unlimited (always)
+func (p *RestartPolicy) Attempts() int32 {
+	if p.Mode != RestartPolicyOnFailure {
+		return 0
+	}
+	if p.MaxAttempts <= 0 {
+		return DefaultRestartMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// Backoff returns the delay before restart attempt n (1-based): backoff_seconds doubled
+// for each earlier attempt, capped at max_backoff_seconds
+func (p *RestartPolicy) Backoff(attempt int32) time.Duration {
+	delay := DefaultRestartBackoff
+	if p.BackoffSeconds > 0 {
+		delay = time.Duration(p.BackoffSeconds) * time.Second
+	}
+	limit := DefaultRestartMaxBackoff
+	if p.MaxBackoffSeconds > 0 {
+		limit = time.Duration(p.MaxBackoffSeconds) * time.Second
+	}
+	if limit < delay {
+		limit = delay
+	}
+	for i := int32(1); i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	return delay
+}
+
+// CrashWindow returns the window crashes are counted over for crash loop detection
+func (p *RestartPolicy) CrashWindow() time.Duration {
+	if p.CrashWindowSeconds <= 0 {
+		return DefaultRestartCrashWindow
+	}
+	return time.Duration(p.CrashWindowSeconds) * time.Second
+}
+
+// MaxCrashes returns how many crashes within the window stop further restarts
+func (p *RestartPolicy) MaxCrashes() int {
+	if p.MaxCrashesInWindow <= 0 {
+		return DefaultRestartMaxCrashes
+	}
+	return int(p.MaxCrashesInWindow)
+}
+
+// Idle shutdown defaults
+const (
+	DefaultPlayerCountInterval = time.Minute
+	DefaultPlayerCountPattern  = `(\d+)`
+)
+
+// IdleShutdown stops a session once it has had zero players for idle_minutes
+type IdleShutdown struct {
+	IdleMinutes int32            `json:"idle_minutes"`
+	PlayerCount PlayerCountProbe `json:"player_count"`
+}
+
+// PlayerCountProbe tells the host how to count a session's players. Log probes track
+// join_pattern/leave_pattern matches, or read the count from a line matching count_pattern.
+// Exec probes run command periodically and read the count from its output. Query probes use
+// the count reported by the game config's status query.
+type PlayerCountProbe struct {
+	Type            string   `json:"type"`                    // PlayerCountProbeLog | Exec | Query
+	JoinPattern     string   `json:"join_pattern,omitempty"`  // log: a player joined
+	LeavePattern    string   `json:"leave_pattern,omitempty"` // log: a player left
+	CountPattern    string   `json:"count_pattern,omitempty"` // first capture group is the player count
+	Command         []string `json:"command,omitempty"`       // exec command
+	IntervalSeconds int32    `json:"interval_seconds,omitempty"`
+}
+
+// IdleShutdownFromJSONB decodes an idle_shutdown column. Returns nil when unset or malformed.
+func IdleShutdownFromJSONB(raw JSONB) *IdleShutdown {
+	var rule IdleShutdown
+	if !decodeJSONB(raw, &rule) || rule.IdleMinutes <= 0 {
+		return nil
+	}
+	return &rule
+}
+
+// ToJSONB encodes the rule for storage; nil for a nil rule.
+func (r *IdleShutdown) ToJSONB() JSONB {
+	if r == nil {
+		return nil
+	}
+	return encodeJSONB(r)
+}
+
+// Validate checks the idle time is positive and the probe can count players
+func (r *IdleShutdown) Validate() error {
+	if r.IdleMinutes <= 0 {
+		return fmt.Errorf("idle_minutes must be > 0")
+	}
+	return r.PlayerCount.Validate()
+}
+
+// IdleTimeout returns how long a session may have zero players before it is stopped
+func (r *IdleShutdown) IdleTimeout() time.Duration {
+	return time.Duration(r.IdleMinutes) * time.Minute
+}
+
+// Validate checks the probe has what its type needs and its patterns compile
+func (p *PlayerCountProbe) Validate() error {
+	if p.IntervalSeconds < 0 {
+		return fmt.Errorf("interval_seconds must not be negative")
+	}
+	for name, pattern := range map[string]string{
+		"join_pattern":  p.JoinPattern,
+		"leave_pattern": p.LeavePattern,
+		"count_pattern": p.CountPattern,
+	} {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if name == "count_pattern" && re.NumSubexp() < 1 {
+			return fmt.Errorf("count_pattern must have a capture group for the player count")
+		}
+	}
+	switch p.Type {
+	case PlayerCountProbeLog:
+		if p.CountPattern == "" && (p.JoinPattern == "" || p.LeavePattern == "") {
+			return fmt.Errorf("log player count probes need count_pattern or both join_pattern and leave_pattern")
+		}
+	case PlayerCountProbeExec:
+		if len(p.Command) == 0 {
+			return fmt.Errorf("command is required for exec player count probes")
+		}
+	case PlayerCountProbeQuery:
+	default:
+		return fmt.Errorf("unknown player count probe type %q", p.Type)
+	}
+	return nil
+}
+
+// Pattern returns the count pattern, defaulting to the first number for exec probes
+func (p *PlayerCountProbe) Pattern() string {
+	if p.CountPattern == "" && p.Type == PlayerCountProbeExec {
+		return DefaultPlayerCountPattern
+	}
+	return p.CountPattern
+}
+
+// Interval returns the delay between exec probe runs
+func (p *PlayerCountProbe) Interval() time.Duration {
+	if p.IntervalSeconds <= 0 {
+		return DefaultPlayerCountInterval
+	}
+	return time.Duration(p.IntervalSeconds) * time.Second
+}
+
+// decodeJSONB round-trips a JSONB column into v. Returns false when raw is nil or doesn't fit v.
+func decodeJSONB(raw JSONB, v interface{}) bool {
+	if raw == nil {
+		return false
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return false
+	}
+	return json.Unmarshal(data, v) == nil
+}
+
+// encodeJSONB round-trips v into a JSONB column value; nil if v can't be encoded.
+func encodeJSONB(v interface{}) JSONB {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var raw JSONB
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	return raw
 }
@@ -0,0 +1,75 @@
+package manman
+
+import "time"
+
+// Resolutions of stored resource metrics. Samples are recorded per minute and rolled up
+// per hour once they are older than MetricsMinuteRetention.
+const (
+	MetricsResolutionMinute int32 = 60
+	MetricsResolutionHour   int32 = 3600
+
+	MetricsMinuteRetention = 24 * time.Hour
+	MetricsHourRetention   = 30 * 24 * time.Hour
+)
+
+// MetricsSample is one resource sample from a host: its data directory's disk usage and
+// the usage of each of its game containers
+type MetricsSample struct {
+	ServerID       int64
+	SampledAt      time.Time
+	DiskTotalBytes int64
+	DiskUsedBytes  int64
+	Sessions       []SessionMetricsSample
+}
+
+// SessionMetricsSample is one game container's usage in a MetricsSample
+type SessionMetricsSample struct {
+	SessionID             int64
+	SGCID                 int64
+	CPUPercent            float64 // 100 is one full core
+	MemoryBytes           int64
+	MemoryLimitBytes      int64
+	NetworkRxBytesPerSec  float64
+	NetworkTxBytesPerSec  float64
+	BlockReadBytesPerSec  float64
+	BlockWriteBytesPerSec float64
+}
+
+// SessionMetricsBucket aggregates a session's samples over ResolutionSeconds from
+// BucketStart
+type SessionMetricsBucket struct {
+	SessionID                int64     `db:"session_id"`
+	ResolutionSeconds        int32     `db:"resolution_seconds"`
+	BucketStart              time.Time `db:"bucket_start"`
+	ServerID                 int64     `db:"server_id"`
+	SGCID                    int64     `db:"sgc_id"`
+	Samples                  int32     `db:"samples"`
+	CPUPercentSum            float64   `db:"cpu_percent_sum"`
+	CPUPercentMax            float64   `db:"cpu_percent_max"`
+	MemoryBytesSum           float64   `db:"memory_bytes_sum"`
+	MemoryBytesMax           int64     `db:"memory_bytes_max"`
+	MemoryLimitBytes         int64     `db:"memory_limit_bytes"`
+	NetworkRxBytesPerSecSum  float64   `db:"network_rx_bytes_per_sec_sum"`
+	NetworkTxBytesPerSecSum  float64   `db:"network_tx_bytes_per_sec_sum"`
+	BlockReadBytesPerSecSum  float64   `db:"block_read_bytes_per_sec_sum"`
+	BlockWriteBytesPerSecSum float64   `db:"block_write_bytes_per_sec_sum"`
+}
+
+// Avg divides one of the bucket's sums by its sample count
+func (b *SessionMetricsBucket) Avg(sum float64) float64 {
+	if b.Samples == 0 {
+		return 0
+	}
+	return sum / float64(b.Samples)
+}
+
+// HostMetricsBucket is a host's peak data directory disk usage over ResolutionSeconds
+// from BucketStart
+type HostMetricsBucket struct {
+	ServerID          int64     `db:"server_id"`
+	ResolutionSeconds int32     `db:"resolution_seconds"`
+	BucketStart       time.Time `db:"bucket_start"`
+	Samples           int32     `db:"samples"`
+	DiskTotalBytes    int64     `db:"disk_total_bytes"`
+	DiskUsedBytesMax  int64     `db:"disk_used_bytes_max"`
+}
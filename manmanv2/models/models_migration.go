@@ -0,0 +1,48 @@
+package manman
+
+import "time"
+
+// SGC migration statuses, in the order the processor moves through them
+const (
+	MigrationStatusPending        = "pending"
+	MigrationStatusStopping       = "stopping"        // waiting for the source session to stop
+	MigrationStatusBackingUp      = "backing_up"      // archiving each volume on the source server
+	MigrationStatusCreatingTarget = "creating_target" // recreating the SGC on the target server
+	MigrationStatusRestoring      = "restoring"       // unpacking each archive on the target server
+	MigrationStatusVerifying      = "verifying"       // comparing restored and backed up checksums
+	MigrationStatusCompleted      = "completed"
+	MigrationStatusFailed         = "failed"
+)
+
+// SGCMigration moves an SGC and its volume data to another server
+type SGCMigration struct {
+	MigrationID    int64             `db:"migration_id"`
+	SourceSGCID    int64             `db:"source_sgc_id"`
+	SourceServerID int64             `db:"source_server_id"`
+	SourceStatus   string            `db:"source_status"` // restored on the source SGC if the migration fails
+	TargetServerID int64             `db:"target_server_id"`
+	TargetSGCID    *int64            `db:"target_sgc_id"` // set once the target SGC is created
+	PortBindings   []PortBinding     `db:"port_bindings"` // host ports picked on the target
+	Volumes        []MigrationVolume `db:"volumes"`
+	StartSession   bool              `db:"start_session"` // start the target SGC once the migration completes
+	Status         string            `db:"status"`
+	ErrorMessage   *string           `db:"error_message"`
+	CreatedAt      time.Time         `db:"created_at"`
+	UpdatedAt      time.Time         `db:"updated_at"`
+	CompletedAt    *time.Time        `db:"completed_at"`
+}
+
+// Done reports whether the migration has finished, successfully or not
+func (m *SGCMigration) Done() bool {
+	return m.Status == MigrationStatusCompleted || m.Status == MigrationStatusFailed
+}
+
+// MigrationVolume is one GameConfigVolume's progress through a migration
+type MigrationVolume struct {
+	VolumeID         int64  `json:"volume_id"`
+	Name             string `json:"name"`
+	BackupID         int64  `json:"backup_id"`
+	Restored         bool   `json:"restored"`
+	RestoredChecksum string `json:"restored_checksum,omitempty"` // sha256 of the archive the target downloaded
+	Error            string `json:"error,omitempty"`
+}
@@ -0,0 +1,123 @@
+package manman
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Webhook formats
+const (
+	WebhookFormatJSON    = "json"    // the event envelope as-is
+	WebhookFormatDiscord = "discord" // a Discord message with one embed
+	WebhookFormatSlack   = "slack"   // a Slack incoming-webhook message; Discord also accepts these on /slack
+)
+
+// Webhook delivery statuses
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusSending   = "sending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusDead      = "dead" // gave up; kept for inspection and manual redelivery
+)
+
+// Webhook receives external lifecycle events matching its patterns
+type Webhook struct {
+	WebhookID     int64     `db:"webhook_id"`
+	Name          string    `db:"name"`
+	URL           string    `db:"url"`
+	Format        string    `db:"format"`
+	Secret        string    `db:"secret"`         // HMAC key for the signature header; empty sends unsigned
+	EventPatterns []string  `db:"event_patterns"` // AMQP topic patterns, e.g. "manman.session.*"
+	SGCIDs        []int64   `db:"sgc_ids"`        // only events for these SGCs; empty for all
+	Enabled       bool      `db:"enabled"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+// Validate checks the webhook has an http(s) URL, a known format and at least one pattern
+func (w *Webhook) Validate() error {
+	if strings.TrimSpace(w.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http(s) URL")
+	}
+	switch w.Format {
+	case WebhookFormatJSON, WebhookFormatDiscord, WebhookFormatSlack:
+	default:
+		return fmt.Errorf("unknown format %q", w.Format)
+	}
+	if len(w.EventPatterns) == 0 {
+		return fmt.Errorf("at least one event pattern is required")
+	}
+	for _, p := range w.EventPatterns {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("event patterns must not be empty")
+		}
+	}
+	return nil
+}
+
+// Matches reports whether an event with routingKey, about sgcID (0 when it concerns
+// no SGC, e.g. host events), should be delivered to the webhook
+func (w *Webhook) Matches(routingKey string, sgcID int64) bool {
+	if !w.Enabled {
+		return false
+	}
+	if len(w.SGCIDs) > 0 {
+		found := false
+		for _, id := range w.SGCIDs {
+			if id == sgcID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for _, p := range w.EventPatterns {
+		if matchTopic(strings.Split(p, "."), strings.Split(routingKey, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchTopic matches AMQP topic words: * is exactly one word, # zero or more
+func matchTopic(pattern, key []string) bool {
+	if len(pattern) == 0 {
+		return len(key) == 0
+	}
+	if pattern[0] == "#" {
+		if matchTopic(pattern[1:], key) {
+			return true
+		}
+		return len(key) > 0 && matchTopic(pattern, key[1:])
+	}
+	if len(key) == 0 {
+		return false
+	}
+	if pattern[0] == "*" || pattern[0] == key[0] {
+		return matchTopic(pattern[1:], key[1:])
+	}
+	return false
+}
+
+// WebhookDelivery is one event queued for, or delivered to, one webhook
+type WebhookDelivery struct {
+	DeliveryID     int64      `db:"delivery_id"`
+	WebhookID      int64      `db:"webhook_id"`
+	RoutingKey     string     `db:"routing_key"`
+	Payload        JSONB      `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	LastStatusCode *int       `db:"last_status_code"`
+	LastError      *string    `db:"last_error"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+}
@@ -0,0 +1,217 @@
+package manman
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// Regex groups player event patterns capture
+const (
+	PlayerGroupName    = "player"
+	PlayerGroupID      = "id"
+	PlayerGroupMessage = "message"
+)
+
+// PlayerEventPatterns are a game's regexes for join, leave and chat log lines. Each
+// captures the player's name in a (?P<player>...) group and optionally a stable ID
+// (e.g. a SteamID) in (?P<id>...); chat may capture its text in (?P<message>...).
+type PlayerEventPatterns struct {
+	JoinPattern  string `json:"join_pattern"`
+	LeavePattern string `json:"leave_pattern,omitempty"`
+	ChatPattern  string `json:"chat_pattern,omitempty"`
+}
+
+// PlayerEventPatternsFromJSONB decodes a player_events column. Returns nil when unset or malformed.
+func PlayerEventPatternsFromJSONB(raw JSONB) *PlayerEventPatterns {
+	var patterns PlayerEventPatterns
+	if !decodeJSONB(raw, &patterns) || patterns.JoinPattern == "" {
+		return nil
+	}
+	return &patterns
+}
+
+// ToJSONB encodes the patterns for storage; nil for nil patterns.
+func (p *PlayerEventPatterns) ToJSONB() JSONB {
+	if p == nil {
+		return nil
+	}
+	return encodeJSONB(p)
+}
+
+// Validate checks there is a join pattern and every pattern compiles with a player group
+func (p *PlayerEventPatterns) Validate() error {
+	if p.JoinPattern == "" {
+		return fmt.Errorf("join_pattern is required")
+	}
+	for name, pattern := range map[string]string{
+		"join_pattern":  p.JoinPattern,
+		"leave_pattern": p.LeavePattern,
+		"chat_pattern":  p.ChatPattern,
+	} {
+		if pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", name, err)
+		}
+		if re.SubexpIndex(PlayerGroupName) < 0 {
+			return fmt.Errorf("%s must capture the player's name in a (?P<%s>...) group", name, PlayerGroupName)
+		}
+	}
+	return nil
+}
+
+// PlayerSession is one stay of a player on a session, from a join line to the matching
+// leave line or the session ending
+type PlayerSession struct {
+	PlayerSessionID int64      `db:"player_session_id"`
+	SessionID       int64      `db:"session_id"`
+	SGCID           int64      `db:"sgc_id"`
+	PlayerName      string     `db:"player_name"`
+	PlayerID        *string    `db:"player_id"`
+	JoinedAt        time.Time  `db:"joined_at"`
+	LeftAt          *time.Time `db:"left_at"` // nil while the player is online
+	ChatMessages    int32      `db:"chat_messages"`
+	LastSeenAt      time.Time  `db:"last_seen_at"`
+}
+
+// key identifies the player across stays: their ID when the game's patterns capture one
+func (p *PlayerSession) key() string {
+	if p.PlayerID != nil && *p.PlayerID != "" {
+		return "id:" + *p.PlayerID
+	}
+	return "name:" + p.PlayerName
+}
+
+// PlayerStats summarises who played on an SGC between Since and Until
+type PlayerStats struct {
+	SGCID         int64
+	Since         time.Time
+	Until         time.Time
+	UniquePlayers int
+	TotalPlaytime time.Duration
+	Peak          int
+	PeakAt        time.Time // zero when nobody played
+	Players       []PlayerTotal
+	Concurrency   []ConcurrencyPoint
+}
+
+// PlayerTotal is one player's activity within a PlayerStats window
+type PlayerTotal struct {
+	PlayerName   string
+	PlayerID     string
+	Sessions     int
+	Playtime     time.Duration
+	ChatMessages int32
+	LastSeenAt   time.Time
+}
+
+// ConcurrencyPoint is the most players online at once during the bucket starting at BucketStart
+type ConcurrencyPoint struct {
+	BucketStart time.Time
+	Players     int
+}
+
+// DefaultPlayerStatsBucket picks a concurrency bucket width that keeps a window to a
+// readable number of points
+func DefaultPlayerStatsBucket(window time.Duration) time.Duration {
+	switch {
+	case window <= 6*time.Hour:
+		return 5 * time.Minute
+	case window <= 2*24*time.Hour:
+		return 30 * time.Minute
+	case window <= 14*24*time.Hour:
+		return 3 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// ComputePlayerStats builds stats for stays overlapping [since, until). Stays still open
+// count as online until until. Players is sorted by playtime and cut to top (0 keeps all).
+func ComputePlayerStats(sgcID int64, stays []*PlayerSession, since, until time.Time, bucket time.Duration, top int) *PlayerStats {
+	stats := &PlayerStats{SGCID: sgcID, Since: since, Until: until}
+	if bucket <= 0 || !until.After(since) {
+		return stats
+	}
+
+	type event struct {
+		at    time.Time
+		delta int
+	}
+	var events []event
+	totals := make(map[string]*PlayerTotal)
+	for _, stay := range stays {
+		start, end := stay.JoinedAt, until
+		if stay.LeftAt != nil && stay.LeftAt.Before(until) {
+			end = *stay.LeftAt
+		}
+		if start.Before(since) {
+			start = since
+		}
+		if !end.After(start) {
+			continue
+		}
+		events = append(events, event{start, 1}, event{end, -1})
+
+		total, ok := totals[stay.key()]
+		if !ok {
+			total = &PlayerTotal{PlayerName: stay.PlayerName}
+			if stay.PlayerID != nil {
+				total.PlayerID = *stay.PlayerID
+			}
+			totals[stay.key()] = total
+		}
+		total.Sessions++
+		total.Playtime += end.Sub(start)
+		total.ChatMessages += stay.ChatMessages
+		if stay.LastSeenAt.After(total.LastSeenAt) {
+			total.LastSeenAt = stay.LastSeenAt
+			total.PlayerName = stay.PlayerName // latest name wins when the ID is stable
+		}
+		stats.TotalPlaytime += end.Sub(start)
+	}
+
+	// Leaves sort before joins at the same instant so a rejoin doesn't count twice
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].at.Equal(events[j].at) {
+			return events[i].delta < events[j].delta
+		}
+		return events[i].at.Before(events[j].at)
+	})
+
+	current, next := 0, 0
+	for start := since; start.Before(until); start = start.Add(bucket) {
+		end := start.Add(bucket)
+		peak := current
+		for next < len(events) && events[next].at.Before(end) {
+			current += events[next].delta
+			if current > peak {
+				peak = current
+			}
+			if current > stats.Peak {
+				stats.Peak, stats.PeakAt = current, events[next].at
+			}
+			next++
+		}
+		stats.Concurrency = append(stats.Concurrency, ConcurrencyPoint{BucketStart: start, Players: peak})
+	}
+
+	stats.UniquePlayers = len(totals)
+	for _, total := range totals {
+		stats.Players = append(stats.Players, *total)
+	}
+	sort.Slice(stats.Players, func(i, j int) bool {
+		if stats.Players[i].Playtime == stats.Players[j].Playtime {
+			return stats.Players[i].PlayerName < stats.Players[j].PlayerName
+		}
+		return stats.Players[i].Playtime > stats.Players[j].Playtime
+	})
+	if top > 0 && len(stats.Players) > top {
+		stats.Players = stats.Players[:top]
+	}
+	return stats
+}
@@ -0,0 +1,116 @@
+package manman
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxScheduleCatchUp bounds how far back a schedule looks for occurrences it missed,
+// e.g. while the processor was down.
+const maxScheduleCatchUp = 7 * 24 * time.Hour
+
+// SGCSchedule starts and stops an SGC's session on cron schedules
+type SGCSchedule struct {
+	ScheduleID      int64      `db:"schedule_id"`
+	SGCID           int64      `db:"sgc_id"`
+	StartCron       *string    `db:"start_cron"` // nil = this schedule never starts the SGC
+	StopCron        *string    `db:"stop_cron"`  // nil = this schedule never stops the SGC
+	Timezone        string     `db:"timezone"`
+	BackupConfigID  *int64     `db:"backup_config_id"` // backed up before a scheduled stop
+	Enabled         bool       `db:"enabled"`
+	LastEvaluatedAt *time.Time `db:"last_evaluated_at"`
+	LastStartAt     *time.Time `db:"last_start_at"`
+	LastStopAt      *time.Time `db:"last_stop_at"`
+	CreatedAt       time.Time  `db:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at"`
+}
+
+// Validate checks the schedule has at least one cron and that its crons and timezone parse
+func (s *SGCSchedule) Validate() error {
+	if s.StartCron == nil && s.StopCron == nil {
+		return fmt.Errorf("a schedule needs a start_cron, a stop_cron or both")
+	}
+	_, _, err := s.crons()
+	return err
+}
+
+// crons parses the schedule's start and stop crons; either is nil when unset
+func (s *SGCSchedule) crons() (start, stop *CronSchedule, err error) {
+	loc, err := s.Location()
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.StartCron != nil {
+		if start, err = ParseCron(*s.StartCron, loc); err != nil {
+			return nil, nil, fmt.Errorf("invalid start_cron: %w", err)
+		}
+	}
+	if s.StopCron != nil {
+		if stop, err = ParseCron(*s.StopCron, loc); err != nil {
+			return nil, nil, fmt.Errorf("invalid stop_cron: %w", err)
+		}
+	}
+	return start, stop, nil
+}
+
+// Location returns the schedule's timezone, UTC when unset
+func (s *SGCSchedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+	return loc, nil
+}
+
+// DueAction returns the action the schedule should take at now: the latest start or stop
+// occurrence since it was last evaluated (or created). When both fired, whichever came
+// last wins, so a missed start followed by a stop leaves the SGC stopped. Returns "" when
+// nothing is due.
+func (s *SGCSchedule) DueAction(now time.Time) (action string, at time.Time, err error) {
+	start, stop, err := s.crons()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	since := s.CreatedAt
+	if s.LastEvaluatedAt != nil {
+		since = *s.LastEvaluatedAt
+	}
+	if oldest := now.Add(-maxScheduleCatchUp); since.Before(oldest) {
+		since = oldest
+	}
+
+	if start != nil {
+		if t, ok := start.Last(since, now); ok {
+			action, at = ScheduleActionStart, t
+		}
+	}
+	if stop != nil {
+		if t, ok := stop.Last(since, now); ok && !t.Before(at) {
+			action, at = ScheduleActionStop, t
+		}
+	}
+	return action, at, nil
+}
+
+// NextAction returns the next start or stop the schedule will take after now
+func (s *SGCSchedule) NextAction(now time.Time) (action string, at time.Time) {
+	start, stop, err := s.crons()
+	if err != nil {
+		return "", time.Time{}
+	}
+	if start != nil {
+		if t := start.Next(now); !t.IsZero() {
+			action, at = ScheduleActionStart, t
+		}
+	}
+	if stop != nil {
+		if t := stop.Next(now); !t.IsZero() && (at.IsZero() || !t.After(at)) {
+			action, at = ScheduleActionStop, t
+		}
+	}
+	return action, at
+}
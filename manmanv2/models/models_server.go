@@ -1,6 +1,10 @@
 package manman
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Server represents a physical/virtual machine running the host manager
 type Server struct {
@@ -10,18 +14,77 @@ type Server struct {
 	Environment *string    `db:"environment"`
 	LastSeen    *time.Time `db:"last_seen"`
 	IsDefault   bool       `db:"is_default"`
+
+	// Labels describe the host for placement constraints, e.g. {"region": "eu"}
+	Labels map[string]string `db:"labels"`
 }
 
 // ServerCapability represents the resources available on a server
 type ServerCapability struct {
-	CapabilityID           int64      `db:"capability_id"`
-	ServerID               int64      `db:"server_id"`
-	TotalMemoryMB          int32      `db:"total_memory_mb"`
-	AvailableMemoryMB      int32      `db:"available_memory_mb"`
-	CPUCores               int32      `db:"cpu_cores"`
-	AvailableCPUMillicores int32      `db:"available_cpu_millicores"`
-	DockerVersion          string     `db:"docker_version"`
-	RecordedAt             *time.Time `db:"recorded_at"`
+	CapabilityID           int64       `db:"capability_id"`
+	ServerID               int64       `db:"server_id"`
+	TotalMemoryMB          int32       `db:"total_memory_mb"`
+	AvailableMemoryMB      int32       `db:"available_memory_mb"`
+	CPUCores               int32       `db:"cpu_cores"`
+	AvailableCPUMillicores int32       `db:"available_cpu_millicores"`
+	DockerVersion          string      `db:"docker_version"`
+	PortRanges             []PortRange `db:"port_ranges"` // host ports open for game servers; empty if not reported
+	RecordedAt             *time.Time  `db:"recorded_at"`
+}
+
+// PortRange is an inclusive range of host ports for one protocol
+type PortRange struct {
+	Start    int32  `json:"start"`
+	End      int32  `json:"end"`
+	Protocol string `json:"protocol"` // "TCP" | "UDP"
+}
+
+// HasLabels reports whether the server carries every one of labels. An empty value
+// matches any value, so {"gpu": ""} only requires the key.
+func (s *Server) HasLabels(labels map[string]string) bool {
+	for k, v := range labels {
+		got, ok := s.Labels[k]
+		if !ok || (v != "" && got != v) {
+			return false
+		}
+	}
+	return true
+}
+
+// CapacityReservingStatuses are the session statuses whose resource limits count against
+// a server's capacity
+var CapacityReservingStatuses = []string{
+	SessionStatusPending,
+	SessionStatusStarting,
+	SessionStatusRunning,
+	SessionStatusReady,
+	SessionStatusStopping,
+}
+
+// Overcommit compares requested CPU and memory against what the server's totals leave
+// free once committed, the limits other sessions hold, is taken out. A total of 0 means
+// the server did not report it, and that dimension is not checked. Returns a description
+// of what doesn't fit, or "" if requested does.
+func (c *ServerCapability) Overcommit(committed, requested ResourceLimits) string {
+	var problems []string
+
+	totalMillicores := int64(c.CPUCores) * 1000
+	if requested.CPUMillicores > 0 && totalMillicores > 0 {
+		if free := totalMillicores - int64(committed.CPUMillicores); int64(requested.CPUMillicores) > free {
+			problems = append(problems, fmt.Sprintf("cpu: %dm requested, %dm of %dm free",
+				requested.CPUMillicores, max(free, 0), totalMillicores))
+		}
+	}
+
+	totalMemoryMB := int64(c.TotalMemoryMB)
+	if requested.MemoryMB > 0 && totalMemoryMB > 0 {
+		if free := totalMemoryMB - int64(committed.MemoryMB); int64(requested.MemoryMB) > free {
+			problems = append(problems, fmt.Sprintf("memory: %dMB requested, %dMB of %dMB free",
+				requested.MemoryMB, max(free, 0), totalMemoryMB))
+		}
+	}
+
+	return strings.Join(problems, "; ")
 }
 
 // ServerPort represents port allocation tracking at server level
@@ -4,15 +4,24 @@ import "time"
 
 // Session represents an execution of a ServerGameConfig
 type Session struct {
-	SessionID            int64      `db:"session_id"`
-	SGCID                int64      `db:"sgc_id"`
-	StartedAt            *time.Time `db:"started_at"`
-	EndedAt              *time.Time `db:"ended_at"`
-	ExitCode             *int       `db:"exit_code"`
-	Status               string     `db:"status"`
-	RestoredFromBackupID *int64     `db:"restored_from_backup_id"`
-	CreatedAt            time.Time  `db:"created_at"`
-	UpdatedAt            time.Time  `db:"updated_at"`
+	SessionID              int64      `db:"session_id"`
+	SGCID                  int64      `db:"sgc_id"`
+	StartedAt              *time.Time `db:"started_at"`
+	EndedAt                *time.Time `db:"ended_at"`
+	ExitCode               *int       `db:"exit_code"`
+	Status                 string     `db:"status"`
+	RestoredFromBackupID   *int64     `db:"restored_from_backup_id"`
+	PreviousSessionID      *int64     `db:"previous_session_id"` // session this one automatically restarted
+	RestartAttempt         int32      `db:"restart_attempt"`     // 0 for a manual start
+	RestartAbandonedReason *string    `db:"restart_abandoned_reason"`
+	PlayerCount            *int32     `db:"player_count"`  // nil until the host reports a count
+	IdleSince              *time.Time `db:"idle_since"`    // when the player count last dropped to zero
+	MapName                *string    `db:"map_name"`      // from the game config's status query
+	MaxPlayers             *int32     `db:"max_players"`   // from the game config's status query
+	ImageTag               *string    `db:"image_tag"`     // replaces the game config image's tag for this session
+	EnvOverrides           JSONB      `db:"env_overrides"` // merged over the game config's env_template
+	CreatedAt              time.Time  `db:"created_at"`
+	UpdatedAt              time.Time  `db:"updated_at"`
 }
 
 // LogReference represents a reference to a log file for a session
@@ -31,11 +40,21 @@ type LogReference struct {
 	CreatedAt       time.Time  `db:"created_at"`
 }
 
+// ArchivedLogWindow is one archived minute of a session's logs, as found by a log search.
+// A window appended to after its first upload has several log references but one file.
+type ArchivedLogWindow struct {
+	SessionID       int64     `db:"session_id"`
+	SGCID           int64     `db:"sgc_id"`
+	ServerID        int64     `db:"server_id"`
+	FilePath        string    `db:"file_path"`
+	MinuteTimestamp time.Time `db:"minute_timestamp"`
+}
+
 // IsActive returns true if the session is in an active state (not completed or stopped)
 // Note: crashed and lost are still considered active for management purposes
 func (s Session) IsActive() bool {
 	switch s.Status {
-	case SessionStatusPending, SessionStatusStarting, SessionStatusRunning,
+	case SessionStatusPending, SessionStatusStarting, SessionStatusRunning, SessionStatusReady,
 		SessionStatusStopping, SessionStatusCrashed, SessionStatusLost:
 		return true
 	default:
@@ -43,7 +62,12 @@ func (s Session) IsActive() bool {
 	}
 }
 
-// IsAvailable returns true if the session is running and ready for connections
+// IsAvailable returns true if the session is ready for connections
 func (s Session) IsAvailable() bool {
-	return s.Status == SessionStatusRunning
+	return s.Status == SessionStatusReady
+}
+
+// IsLive returns true if the session's container is up (running or ready)
+func (s Session) IsLive() bool {
+	return s.Status == SessionStatusRunning || s.Status == SessionStatusReady
 }
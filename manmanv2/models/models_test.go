@@ -2,6 +2,7 @@ package manman
 
 import (
 	"testing"
+	"time"
 )
 
 func TestSession_IsActive(t *testing.T) {
@@ -12,6 +13,7 @@ func TestSession_IsActive(t *testing.T) {
 		{SessionStatusPending, true},
 		{SessionStatusStarting, true},
 		{SessionStatusRunning, true},
+		{SessionStatusReady, true},
 		{SessionStatusStopping, true},
 		{SessionStatusCrashed, true},
 		{SessionStatusLost, true},
@@ -33,7 +35,8 @@ func TestSession_IsAvailable(t *testing.T) {
 		status   string
 		expected bool
 	}{
-		{SessionStatusRunning, true},
+		{SessionStatusReady, true},
+		{SessionStatusRunning, false}, // started but readiness probe not passed
 		{SessionStatusPending, false},
 		{SessionStatusStarting, false},
 		{SessionStatusStopping, false},
@@ -49,3 +52,460 @@ func TestSession_IsAvailable(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveResourceLimits(t *testing.T) {
+	int32Ptr := func(v int32) *int32 { return &v }
+
+	gc := &GameConfig{
+		CPUMillicores: int32Ptr(2000),
+		MemoryMB:      int32Ptr(4096),
+		Ulimits: JSONB{
+			"nofile": map[string]interface{}{"soft": float64(1024), "hard": float64(4096)},
+			"core":   map[string]interface{}{"soft": float64(0), "hard": float64(0)},
+		},
+	}
+	sgc := &ServerGameConfig{
+		MemoryMB:  int32Ptr(8192),
+		PidsLimit: int32Ptr(512),
+		Ulimits: JSONB{
+			"nofile": map[string]interface{}{"soft": float64(65536), "hard": float64(65536)},
+		},
+	}
+
+	limits := ResolveResourceLimits(gc, sgc)
+	if limits.CPUMillicores != 2000 {
+		t.Errorf("CPUMillicores = %d, want inherited 2000", limits.CPUMillicores)
+	}
+	if limits.MemoryMB != 8192 {
+		t.Errorf("MemoryMB = %d, want SGC override 8192", limits.MemoryMB)
+	}
+	if limits.PidsLimit != 512 {
+		t.Errorf("PidsLimit = %d, want 512", limits.PidsLimit)
+	}
+	if len(limits.Ulimits) != 2 {
+		t.Fatalf("Expected 2 ulimits, got %v", limits.Ulimits)
+	}
+	// Sorted by name: core, nofile
+	if limits.Ulimits[1].Name != "nofile" || limits.Ulimits[1].Soft != 65536 {
+		t.Errorf("Expected SGC nofile override, got %+v", limits.Ulimits[1])
+	}
+
+	if !ResolveResourceLimits(&GameConfig{}, nil).IsZero() {
+		t.Error("Expected no limits when nothing is set")
+	}
+
+	// An SGC's 0 lifts the game config's limit instead of inheriting it
+	if limits := ResolveResourceLimits(gc, &ServerGameConfig{CPUMillicores: int32Ptr(0)}); limits.CPUMillicores != 0 || limits.MemoryMB != 4096 {
+		t.Errorf("Expected unlimited cpu and inherited memory, got %+v", limits)
+	}
+}
+
+func TestServerCapabilityOvercommit(t *testing.T) {
+	capability := &ServerCapability{CPUCores: 4, TotalMemoryMB: 8192}
+
+	if msg := capability.Overcommit(ResourceLimits{CPUMillicores: 3000, MemoryMB: 4096}, ResourceLimits{CPUMillicores: 1000, MemoryMB: 4096}); msg != "" {
+		t.Errorf("Expected an exact fit to pass, got %q", msg)
+	}
+
+	msg := capability.Overcommit(ResourceLimits{CPUMillicores: 3500, MemoryMB: 7000}, ResourceLimits{CPUMillicores: 1000, MemoryMB: 512})
+	if msg != "cpu: 1000m requested, 500m of 4000m free" {
+		t.Errorf("Expected only a cpu overcommit against what's free, got %q", msg)
+	}
+
+	// Unreported totals aren't checked
+	if msg := (&ServerCapability{}).Overcommit(ResourceLimits{}, ResourceLimits{CPUMillicores: 1000, MemoryMB: 1024}); msg != "" {
+		t.Errorf("Expected no overcommit without reported capacity, got %q", msg)
+	}
+}
+
+func TestReadinessProbe(t *testing.T) {
+	probe := ReadinessProbeFromJSONB(JSONB{"type": "log", "log_pattern": `Server started on port \d+`, "timeout_seconds": float64(600)})
+	if probe == nil {
+		t.Fatal("Expected probe to decode")
+	}
+	if err := probe.Validate(); err != nil {
+		t.Errorf("Expected valid probe, got %v", err)
+	}
+	if probe.Timeout().Minutes() != 10 || probe.Interval() != DefaultReadinessInterval {
+		t.Errorf("Unexpected timeout/interval: %v %v", probe.Timeout(), probe.Interval())
+	}
+
+	if ReadinessProbeFromJSONB(nil) != nil {
+		t.Error("Expected nil probe for unset column")
+	}
+
+	invalid := []*ReadinessProbe{
+		{Type: ReadinessProbeLog, LogPattern: "("},
+		{Type: ReadinessProbeTCP},
+		{Type: ReadinessProbeExec},
+		{Type: "http"},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestRestartPolicy(t *testing.T) {
+	policy := RestartPolicyFromJSONB(JSONB{"mode": "on-failure", "max_attempts": float64(3), "backoff_seconds": float64(5), "max_backoff_seconds": float64(30)})
+	if policy == nil {
+		t.Fatal("Expected policy to decode")
+	}
+	if err := policy.Validate(); err != nil {
+		t.Errorf("Expected valid policy, got %v", err)
+	}
+	if policy.RestartsAfter(false) || !policy.RestartsAfter(true) {
+		t.Error("Expected on-failure to restart only failures")
+	}
+	if policy.Attempts() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", policy.Attempts())
+	}
+
+	backoffs := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, want := range backoffs {
+		if got := policy.Backoff(int32(i + 1)); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, want)
+		}
+	}
+
+	always := &RestartPolicy{Mode: RestartPolicyAlways}
+	if !always.RestartsAfter(false) || always.Attempts() != 0 {
+		t.Error("Expected always to restart clean exits without an attempt limit")
+	}
+	if always.CrashWindow() != DefaultRestartCrashWindow || always.MaxCrashes() != DefaultRestartMaxCrashes {
+		t.Errorf("Unexpected crash loop defaults: %v %d", always.CrashWindow(), always.MaxCrashes())
+	}
+
+	var none *RestartPolicy
+	if none.RestartsAfter(true) {
+		t.Error("Expected a nil policy never to restart")
+	}
+
+	invalid := []*RestartPolicy{
+		{Mode: "sometimes"},
+		{Mode: RestartPolicyOnFailure, MaxAttempts: -1},
+		{Mode: RestartPolicyAlways, BackoffSeconds: 60, MaxBackoffSeconds: 10},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	from := time.Date(2025, 6, 13, 17, 30, 0, 0, time.UTC) // a Friday
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"0 18 * * *", time.Date(2025, 6, 13, 18, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 6, 13, 17, 45, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2025, 6, 16, 9, 0, 0, 0, time.UTC)},
+		{"0 10 * * 7", time.Date(2025, 6, 15, 10, 0, 0, 0, time.UTC)},
+		{"0 0 1,15 * *", time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 5", time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)}, // day fields OR together
+		{"@monthly", time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.expr, nil)
+		if err != nil {
+			t.Errorf("ParseCron(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if got := c.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "5-1 * * * *", "*/0 * * * *", "0 0 0 * *"} {
+		if _, err := ParseCron(expr, nil); err == nil {
+			t.Errorf("Expected ParseCron(%q) to fail", expr)
+		}
+	}
+
+	c, _ := ParseCron("0 0 30 2 *", nil)
+	if got := c.Next(from); !got.IsZero() {
+		t.Errorf("Expected an impossible date never to fire, got %v", got)
+	}
+}
+
+func TestParseCronTimezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+	c, err := ParseCron("0 18 * * *", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := c.Next(time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC))
+	if want := time.Date(2025, 6, 13, 22, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("Next = %v, want %v", got, want)
+	}
+}
+
+func TestSGCScheduleDueAction(t *testing.T) {
+	start, stop := "0 18 * * *", "0 23 * * *"
+	created := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	s := &SGCSchedule{StartCron: &start, StopCron: &stop, CreatedAt: created}
+	if err := s.Validate(); err != nil {
+		t.Fatalf("Expected valid schedule, got %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		evaluated *time.Time
+		now       time.Time
+		want      string
+	}{
+		{"nothing due yet", nil, time.Date(2025, 6, 13, 17, 59, 0, 0, time.UTC), ""},
+		{"start fired", nil, time.Date(2025, 6, 13, 18, 0, 0, 0, time.UTC), ScheduleActionStart},
+		{"stop after start wins", nil, time.Date(2025, 6, 13, 23, 30, 0, 0, time.UTC), ScheduleActionStop},
+		{"already evaluated", timePtr(time.Date(2025, 6, 13, 18, 0, 0, 0, time.UTC)), time.Date(2025, 6, 13, 18, 1, 0, 0, time.UTC), ""},
+		{"next day start", timePtr(time.Date(2025, 6, 13, 23, 0, 0, 0, time.UTC)), time.Date(2025, 6, 14, 18, 5, 0, 0, time.UTC), ScheduleActionStart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.LastEvaluatedAt = tt.evaluated
+			got, _, err := s.DueAction(tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("DueAction = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	action, at := s.NextAction(time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC))
+	if action != ScheduleActionStop || at.Hour() != 23 {
+		t.Errorf("NextAction = %s at %v, want stop at 23:00", action, at)
+	}
+
+	invalid := []*SGCSchedule{
+		{},
+		{StartCron: &start, Timezone: "Mars/Olympus_Mons"},
+		{StopCron: strPtr("not a cron")},
+	}
+	for _, s := range invalid {
+		if err := s.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", s)
+		}
+	}
+}
+
+func TestIdleShutdown(t *testing.T) {
+	rule := IdleShutdownFromJSONB(JSONB{
+		"idle_minutes": float64(30),
+		"player_count": map[string]interface{}{"type": "exec", "command": []interface{}{"rcon-cli", "list"}},
+	})
+	if rule == nil {
+		t.Fatal("Expected rule to decode")
+	}
+	if err := rule.Validate(); err != nil {
+		t.Errorf("Expected valid rule, got %v", err)
+	}
+	if rule.IdleTimeout() != 30*time.Minute {
+		t.Errorf("Expected 30m idle timeout, got %v", rule.IdleTimeout())
+	}
+	if rule.PlayerCount.Pattern() != DefaultPlayerCountPattern || rule.PlayerCount.Interval() != DefaultPlayerCountInterval {
+		t.Errorf("Unexpected exec probe defaults: %q %v", rule.PlayerCount.Pattern(), rule.PlayerCount.Interval())
+	}
+
+	if IdleShutdownFromJSONB(JSONB{"idle_minutes": float64(0)}) != nil {
+		t.Error("Expected a zero idle time to decode as no rule")
+	}
+
+	invalid := []*IdleShutdown{
+		{IdleMinutes: 0, PlayerCount: PlayerCountProbe{Type: PlayerCountProbeLog, CountPattern: `(\d+) players`}},
+		{IdleMinutes: 10, PlayerCount: PlayerCountProbe{Type: PlayerCountProbeLog, JoinPattern: "joined"}},
+		{IdleMinutes: 10, PlayerCount: PlayerCountProbe{Type: PlayerCountProbeLog, CountPattern: `\d+ players`}},
+		{IdleMinutes: 10, PlayerCount: PlayerCountProbe{Type: PlayerCountProbeExec}},
+		{IdleMinutes: 10, PlayerCount: PlayerCountProbe{Type: "a2s"}},
+	}
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", r)
+		}
+	}
+}
+
+func TestInputTransportValidate(t *testing.T) {
+	valid := []*InputTransport{
+		{Type: InputTransportStdin},
+		{Type: InputTransportRCON, Port: 25575, PasswordEnv: "RCON_PASSWORD"},
+		{Type: InputTransportWebSocketRCON, Port: 28016, PasswordArg: "+rcon.password"},
+		{Type: InputTransportRCON, Port: 25575, PasswordFile: "/data/server.properties", PasswordKey: "rcon.password"},
+	}
+	for _, tr := range valid {
+		if err := tr.Validate(); err != nil {
+			t.Errorf("Expected %+v to be valid, got %v", tr, err)
+		}
+	}
+
+	invalid := []*InputTransport{
+		{Type: "telnet"},
+		{Type: InputTransportRCON, PasswordEnv: "RCON_PASSWORD"},
+		{Type: InputTransportRCON, Port: 25575},
+		{Type: InputTransportRCON, Port: 25575, PasswordFile: "/data/server.properties"},
+		{Type: InputTransportRCON, Port: 25575, PasswordEnv: "RCON_PASSWORD", PasswordKey: "rcon.password"},
+	}
+	for _, tr := range invalid {
+		if err := tr.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", tr)
+		}
+	}
+
+	if InputTransportFromJSONB(nil) != nil {
+		t.Error("Expected no transport from a nil column")
+	}
+}
+
+func TestStatusQuery(t *testing.T) {
+	q := StatusQueryFromJSONB(JSONB{"type": "a2s", "port": float64(27015)})
+	if q == nil || q.Port != 27015 {
+		t.Fatalf("Expected query to decode, got %+v", q)
+	}
+	if err := q.Validate(); err != nil {
+		t.Errorf("Expected valid query, got %v", err)
+	}
+	if q.Interval() != DefaultStatusQueryInterval {
+		t.Errorf("Expected default interval, got %v", q.Interval())
+	}
+
+	for _, bad := range []*StatusQuery{{Type: "gamespy", Port: 1}, {Type: StatusQueryMinecraft}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", bad)
+		}
+	}
+}
+
+func timePtr(t time.Time) *time.Time { return &t }
+
+func strPtr(s string) *string { return &s }
+
+func TestPlayerEventPatternsValidate(t *testing.T) {
+	valid := &PlayerEventPatterns{
+		JoinPattern:  `(?P<player>\w+) joined the game`,
+		LeavePattern: `(?P<player>\w+) left the game`,
+		ChatPattern:  `<(?P<player>\w+)> (?P<message>.*)`,
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected patterns to be valid, got %v", err)
+	}
+
+	invalid := []*PlayerEventPatterns{
+		{},
+		{JoinPattern: `(\w+) joined`},
+		{JoinPattern: `(?P<player>\w+) joined`, LeavePattern: `(?P<player>`},
+	}
+	for _, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("Expected %+v to be invalid", p)
+		}
+	}
+}
+
+func TestComputePlayerStats(t *testing.T) {
+	since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+	at := func(minutes int) time.Time { return since.Add(time.Duration(minutes) * time.Minute) }
+	ptr := func(t time.Time) *time.Time { return &t }
+	steamID := "76561198000000000"
+
+	stays := []*PlayerSession{
+		// joined before the window; only the part inside counts
+		{PlayerName: "alice", JoinedAt: at(-30), LeftAt: ptr(at(20)), LastSeenAt: at(20), ChatMessages: 2},
+		{PlayerName: "bob", JoinedAt: at(10), LeftAt: ptr(at(40)), LastSeenAt: at(40)},
+		// same player under a new name; still online
+		{PlayerName: "carol", PlayerID: &steamID, JoinedAt: at(15), LeftAt: ptr(at(25)), LastSeenAt: at(25)},
+		{PlayerName: "carol2", PlayerID: &steamID, JoinedAt: at(50), LastSeenAt: at(50)},
+	}
+
+	stats := ComputePlayerStats(1, stays, since, until, 30*time.Minute, 0)
+
+	if stats.UniquePlayers != 3 {
+		t.Errorf("Expected 3 unique players, got %d", stats.UniquePlayers)
+	}
+	if want := (20 + 30 + 10 + 10) * time.Minute; stats.TotalPlaytime != want {
+		t.Errorf("Expected %s total playtime, got %s", want, stats.TotalPlaytime)
+	}
+	if stats.Peak != 3 || !stats.PeakAt.Equal(at(15)) {
+		t.Errorf("Expected peak 3 at %s, got %d at %s", at(15), stats.Peak, stats.PeakAt)
+	}
+	if len(stats.Concurrency) != 2 || stats.Concurrency[0].Players != 3 || stats.Concurrency[1].Players != 1 {
+		t.Errorf("Unexpected concurrency %+v", stats.Concurrency)
+	}
+	if stats.Players[0].PlayerName != "bob" || stats.Players[0].Playtime != 30*time.Minute {
+		t.Errorf("Expected bob to have the most playtime, got %+v", stats.Players[0])
+	}
+	for _, p := range stats.Players {
+		if p.PlayerID == steamID && (p.PlayerName != "carol2" || p.Sessions != 2) {
+			t.Errorf("Expected carol's stays merged under her latest name, got %+v", p)
+		}
+	}
+
+	if top := ComputePlayerStats(1, stays, since, until, 30*time.Minute, 1); len(top.Players) != 1 {
+		t.Errorf("Expected top to limit players to 1, got %d", len(top.Players))
+	}
+}
+
+func TestWebhook_Matches(t *testing.T) {
+	w := Webhook{Enabled: true, EventPatterns: []string{"manman.session.crashed", "manman.backup.*", "manman.host.#"}}
+	tests := []struct {
+		key   string
+		sgcID int64
+		want  bool
+	}{
+		{"manman.session.crashed", 1, true},
+		{"manman.session.ready", 1, false},
+		{"manman.backup.failed", 1, true},
+		{"manman.backup.failed.extra", 1, false},
+		{"manman.host", 0, true},
+		{"manman.host.stale", 0, true},
+	}
+	for _, tt := range tests {
+		if got := w.Matches(tt.key, tt.sgcID); got != tt.want {
+			t.Errorf("Matches(%q) = %v, want %v", tt.key, got, tt.want)
+		}
+	}
+
+	w.SGCIDs = []int64{7}
+	if w.Matches("manman.backup.failed", 8) {
+		t.Error("matched an event for an SGC outside sgc_ids")
+	}
+	if w.Matches("manman.host.stale", 0) {
+		t.Error("an SGC-scoped webhook matched an event without an SGC")
+	}
+	if !w.Matches("manman.backup.failed", 7) {
+		t.Error("didn't match an event for a listed SGC")
+	}
+
+	w.Enabled = false
+	if w.Matches("manman.backup.failed", 7) {
+		t.Error("a disabled webhook matched")
+	}
+}
+
+func TestWebhook_Validate(t *testing.T) {
+	valid := Webhook{Name: "ops", URL: "https://discord.com/api/webhooks/1/abc", Format: WebhookFormatDiscord, EventPatterns: []string{"manman.#"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("valid webhook rejected: %v", err)
+	}
+	for name, mutate := range map[string]func(w *Webhook){
+		"no name":     func(w *Webhook) { w.Name = " " },
+		"relative":    func(w *Webhook) { w.URL = "/hook" },
+		"ftp":         func(w *Webhook) { w.URL = "ftp://example.com/hook" },
+		"format":      func(w *Webhook) { w.Format = "teams" },
+		"no patterns": func(w *Webhook) { w.EventPatterns = nil },
+	} {
+		w := valid
+		mutate(&w)
+		if err := w.Validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -48,6 +48,7 @@ type WorkshopInstallation struct {
 	ErrorMessage        *string    `db:"error_message"`
 	DownloadStartedAt   *time.Time `db:"download_started_at"`
 	DownloadCompletedAt *time.Time `db:"download_completed_at"`
+	UpdateAvailable     bool       `db:"update_available"` // the addon changed upstream since it was installed
 	CreatedAt           time.Time  `db:"created_at"`
 	UpdatedAt           time.Time  `db:"updated_at"`
 }
@@ -41,12 +41,15 @@ const (
 	ServerStatusOnline  = "online"
 	ServerStatusOffline = "offline"
 
-	SGCStatusActive   = "active"
-	SGCStatusInactive = "inactive"
+	SGCStatusActive    = "active"
+	SGCStatusInactive  = "inactive"
+	SGCStatusMigrating = "migrating" // sessions can't be started while its data is moved
+	SGCStatusMigrated  = "migrated"  // replaced by the target SGC of a completed migration
 
 	SessionStatusPending   = "pending"
 	SessionStatusStarting  = "starting"
-	SessionStatusRunning   = "running"
+	SessionStatusRunning   = "running" // container started
+	SessionStatusReady     = "ready"   // readiness probe passed, accepting players
 	SessionStatusStopping  = "stopping"
 	SessionStatusStopped   = "stopped"
 	SessionStatusCrashed   = "crashed"
@@ -85,6 +88,41 @@ const (
 	PatchFormatJSONMergePatch = "json_merge_patch"
 	PatchFormatJSONPatch      = "json_patch"
 	PatchFormatYAMLMerge      = "yaml_merge"
+	PatchFormatProperties     = "properties" // key=value (or flag value) lines, later keys override earlier ones
+
+	// Readiness probe types
+	ReadinessProbeLog  = "log"  // a log line matches log_pattern
+	ReadinessProbeTCP  = "tcp"  // the container port accepts a TCP connection
+	ReadinessProbeUDP  = "udp"  // the container port doesn't refuse a UDP datagram
+	ReadinessProbeExec = "exec" // command exits 0 when run in the container
+
+	// Restart policy modes
+	RestartPolicyNever     = "never"
+	RestartPolicyOnFailure = "on-failure" // restart after a non-zero exit, up to max_attempts
+	RestartPolicyAlways    = "always"     // restart after any unexpected exit
+
+	// Player count probe types
+	PlayerCountProbeLog   = "log"   // join/leave or count patterns matched against log lines
+	PlayerCountProbeExec  = "exec"  // command run in the container, output matched against count_pattern
+	PlayerCountProbeQuery = "query" // the game config's status query reports the count
+
+	// Input transports
+	InputTransportStdin         = "stdin"          // attach to the container's stdin
+	InputTransportRCON          = "rcon"           // Source RCON over TCP
+	InputTransportWebSocketRCON = "websocket-rcon" // WebRCON (JSON over a websocket), as used by Rust
+
+	// Status query protocols
+	StatusQueryA2S       = "a2s"       // Source engine A2S_INFO over UDP
+	StatusQueryMinecraft = "minecraft" // Minecraft server list ping over TCP
+
+	// Player events extracted from session logs
+	PlayerEventJoin  = "join"
+	PlayerEventLeave = "leave"
+	PlayerEventChat  = "chat"
+
+	// Schedule actions
+	ScheduleActionStart = "start"
+	ScheduleActionStop  = "stop"
 
 	// Log archival states
 	LogStateComplete = "complete"
@@ -135,4 +173,30 @@ const (
 
 	// Workshop platform types
 	PlatformTypeSteamWorkshop = "steam_workshop"
+	PlatformTypeModrinth      = "modrinth"
+	PlatformTypeCurseForge    = "curseforge"
+	PlatformTypeURL           = "url"
+
+	// What happens to an SGC's installed addons when they are updated upstream
+	AddonUpdatePolicyOff       = "off"
+	AddonUpdatePolicyNotify    = "notify"    // publish manman.sgc.addon_update_available
+	AddonUpdatePolicyReinstall = "reinstall" // notify, then re-download once no session is active
 )
+
+// IsValidPlatformType reports whether platformType is a known addon platform
+func IsValidPlatformType(platformType string) bool {
+	switch platformType {
+	case PlatformTypeSteamWorkshop, PlatformTypeModrinth, PlatformTypeCurseForge, PlatformTypeURL:
+		return true
+	}
+	return false
+}
+
+// IsValidAddonUpdatePolicy reports whether policy is a known addon update policy
+func IsValidAddonUpdatePolicy(policy string) bool {
+	switch policy {
+	case AddonUpdatePolicyOff, AddonUpdatePolicyNotify, AddonUpdatePolicyReinstall:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Config holds all configuration for the notifier service
+type Config struct {
+	RabbitMQURL      string
+	DatabaseURL      string // PG_DATABASE_URL
+	ExternalExchange string
+	QueueName        string
+	LogLevel         string
+	HealthCheckPort  string
+	PollInterval     int // seconds between checks for due deliveries
+	BatchSize        int
+	RequestTimeout   int // seconds
+	MaxAttempts      int
+	InitialBackoff   int // seconds
+	MaxBackoff       int // seconds
+}
+
+// LoadConfig loads configuration from environment variables
+func LoadConfig() (*Config, error) {
+	cfg := &Config{
+		RabbitMQURL:      getEnv("RABBITMQ_URL", ""),
+		DatabaseURL:      getEnv("PG_DATABASE_URL", ""),
+		ExternalExchange: getEnv("EXTERNAL_EXCHANGE", "external"),
+		QueueName:        getEnv("QUEUE_NAME", "manman-notifier"),
+		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		HealthCheckPort:  getEnv("HEALTH_CHECK_PORT", "8080"),
+		PollInterval:     getEnvInt("NOTIFIER_POLL_INTERVAL_SECONDS", 2),
+		BatchSize:        getEnvInt("NOTIFIER_BATCH_SIZE", 20),
+		RequestTimeout:   getEnvInt("NOTIFIER_REQUEST_TIMEOUT_SECONDS", 10),
+		MaxAttempts:      getEnvInt("NOTIFIER_MAX_ATTEMPTS", 8),
+		InitialBackoff:   getEnvInt("NOTIFIER_INITIAL_BACKOFF_SECONDS", 10),
+		MaxBackoff:       getEnvInt("NOTIFIER_MAX_BACKOFF_SECONDS", 3600),
+	}
+
+	// Validate required fields
+	if cfg.RabbitMQURL == "" {
+		return nil, fmt.Errorf("RABBITMQ_URL is required")
+	}
+	if cfg.DatabaseURL == "" {
+		return nil, fmt.Errorf("PG_DATABASE_URL is required")
+	}
+	if cfg.MaxAttempts < 1 {
+		return nil, fmt.Errorf("NOTIFIER_MAX_ATTEMPTS must be at least 1")
+	}
+
+	return cfg, nil
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intVal, err := strconv.Atoi(value); err == nil {
+			return intVal
+		}
+	}
+	return defaultValue
+}
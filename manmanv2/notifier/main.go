@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/whale-net/everything/libs/go/db"
+	"github.com/whale-net/everything/libs/go/logging"
+	"github.com/whale-net/everything/libs/go/rmq"
+	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/notifier/notify"
+)
+
+func main() {
+	if err := run(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	// Load configuration
+	cfg, err := LoadConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Setup structured logging
+	logLevel := slog.LevelInfo
+	switch cfg.LogLevel {
+	case "debug":
+		logLevel = slog.LevelDebug
+	case "warn":
+		logLevel = slog.LevelWarn
+	case "error":
+		logLevel = slog.LevelError
+	}
+
+	logging.Configure(logging.Config{
+		ServiceName:   "notifier",
+		Domain:        "manmanv2",
+		Level:         logLevel,
+		JSONFormat:    true,
+		EnableOTLP:    true,
+		EnableTracing: true,
+	})
+	defer logging.Shutdown(context.Background())
+	logger := slog.Default()
+
+	logger.Info("starting notifier",
+		"exchange", cfg.ExternalExchange,
+		"queue", cfg.QueueName,
+		"max_attempts", cfg.MaxAttempts,
+	)
+
+	appCtx, appCancel := context.WithCancel(context.Background())
+	defer appCancel()
+
+	// Connect to database
+	dbPool, err := db.NewPool(appCtx, cfg.DatabaseURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer dbPool.Close()
+	repo := postgres.NewRepository(dbPool)
+
+	// Connect to RabbitMQ
+	rmqConn, err := rmq.NewConnectionFromURL(cfg.RabbitMQURL)
+	if err != nil {
+		return fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	defer rmqConn.Close()
+
+	// Every external event is routed; each webhook's own patterns do the filtering
+	consumer, err := rmq.NewConsumer(rmqConn, cfg.QueueName)
+	if err != nil {
+		return fmt.Errorf("failed to create consumer: %w", err)
+	}
+	defer consumer.Close()
+	if err := consumer.BindExchange(cfg.ExternalExchange, []string{"manman.#"}); err != nil {
+		return fmt.Errorf("failed to bind to exchange: %w", err)
+	}
+	router := notify.NewRouter(repo.Webhooks, repo.WebhookDeliveries, logger)
+	consumer.RegisterHandler("#", router.Handle)
+
+	dispatcher := notify.NewDispatcher(repo.Webhooks, repo.WebhookDeliveries, notify.NewRepositoryDirectory(repo), notify.DispatchConfig{
+		PollInterval:   time.Duration(cfg.PollInterval) * time.Second,
+		BatchSize:      cfg.BatchSize,
+		RequestTimeout: time.Duration(cfg.RequestTimeout) * time.Second,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoff) * time.Second,
+		MaxBackoff:     time.Duration(cfg.MaxBackoff) * time.Second,
+	}, logger)
+
+	// Start health check server
+	healthServer := &http.Server{
+		Addr:    fmt.Sprintf(":%s", cfg.HealthCheckPort),
+		Handler: setupHealthCheckRoutes(dbPool, logger),
+	}
+	go func() {
+		logger.Info("starting health check server", "port", cfg.HealthCheckPort)
+		if err := healthServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("health check server error", "error", err)
+		}
+	}()
+
+	if err := consumer.Start(appCtx); err != nil {
+		return fmt.Errorf("failed to start consumer: %w", err)
+	}
+	dispatcherDone := make(chan struct{})
+	go func() {
+		dispatcher.Run(appCtx)
+		close(dispatcherDone)
+	}()
+
+	// Wait for signals
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigChan
+	logger.Info("received shutdown signal", "signal", sig)
+
+	// Graceful shutdown; a delivery interrupted mid-send is reclaimed on the next start
+	appCancel()
+	<-dispatcherDone
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+	if err := healthServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("error shutting down health check server", "error", err)
+	}
+
+	logger.Info("shutdown complete")
+	return nil
+}
+
+func setupHealthCheckRoutes(dbPool *pgxpool.Pool, logger *slog.Logger) http.Handler {
+	mux := http.NewServeMux()
+
+	// Liveness probe - returns 200 if process is running
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	})
+
+	// Readiness probe - returns 200 if DB is accessible
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		if err := dbPool.Ping(ctx); err != nil {
+			logger.Error("readiness check failed", "error", err)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(fmt.Sprintf("database unhealthy: %v", err)))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ready"))
+	})
+
+	return mux
+}
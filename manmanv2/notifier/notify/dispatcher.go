@@ -0,0 +1,214 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// DispatchConfig tunes delivery and retries
+type DispatchConfig struct {
+	PollInterval   time.Duration // how often due deliveries are claimed
+	BatchSize      int           // deliveries claimed per poll
+	RequestTimeout time.Duration
+	MaxAttempts    int           // attempts before a delivery is dead-lettered
+	InitialBackoff time.Duration // wait after the first failure, doubled per attempt
+	MaxBackoff     time.Duration
+}
+
+// Dispatcher sends queued deliveries, retrying failures with exponential backoff and
+// dead-lettering those that keep failing or are rejected outright
+type Dispatcher struct {
+	webhooks   repository.WebhookRepository
+	deliveries repository.WebhookDeliveryRepository
+	dir        Directory
+	client     *http.Client
+	cfg        DispatchConfig
+	logger     *slog.Logger
+	now        func() time.Time
+}
+
+func NewDispatcher(webhooks repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, dir Directory, cfg DispatchConfig, logger *slog.Logger) *Dispatcher {
+	return &Dispatcher{
+		webhooks:   webhooks,
+		deliveries: deliveries,
+		dir:        dir,
+		client:     &http.Client{Timeout: cfg.RequestTimeout},
+		cfg:        cfg,
+		logger:     logger,
+		now:        time.Now,
+	}
+}
+
+// Run dispatches due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+	d.logger.Info("starting webhook dispatcher", "interval", d.cfg.PollInterval, "max_attempts", d.cfg.MaxAttempts)
+
+	for {
+		// Drain everything due before waiting again
+		for {
+			n, err := d.DispatchDue(ctx)
+			if err != nil {
+				d.logger.Error("failed to dispatch deliveries", "error", err)
+			}
+			if err != nil || n < d.cfg.BatchSize || ctx.Err() != nil {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			d.logger.Info("stopping webhook dispatcher")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue claims one batch of due deliveries and attempts each, returning how many
+// were claimed
+func (d *Dispatcher) DispatchDue(ctx context.Context) (int, error) {
+	// A claim older than a few request timeouts belongs to a notifier that died mid-send
+	batch, err := d.deliveries.ClaimDue(ctx, d.cfg.BatchSize, 3*d.cfg.RequestTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	webhooks := map[int64]*manman.Webhook{}
+	for _, delivery := range batch {
+		w, ok := webhooks[delivery.WebhookID]
+		if !ok {
+			w, err = d.webhooks.Get(ctx, delivery.WebhookID)
+			if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				return len(batch), fmt.Errorf("failed to get webhook %d: %w", delivery.WebhookID, err)
+			}
+			webhooks[delivery.WebhookID] = w
+		}
+		d.attempt(ctx, w, delivery)
+	}
+	return len(batch), nil
+}
+
+// attempt sends one delivery and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, w *manman.Webhook, delivery *manman.WebhookDelivery) {
+	log := d.logger.With("delivery_id", delivery.DeliveryID, "webhook_id", delivery.WebhookID, "routing_key", delivery.RoutingKey)
+
+	if w == nil || !w.Enabled {
+		d.markFailed(ctx, delivery, nil, "webhook was disabled or deleted", nil)
+		return
+	}
+
+	statusCode, retryAfter, err := d.send(ctx, w, delivery)
+	if err == nil {
+		if err := d.deliveries.MarkDelivered(ctx, delivery.DeliveryID, statusCode); err != nil {
+			log.Error("failed to mark delivery delivered", "error", err)
+		}
+		log.Info("delivered", "status_code", statusCode, "attempt", delivery.Attempts+1)
+		return
+	}
+
+	var code *int
+	if statusCode != 0 {
+		code = &statusCode
+	}
+	attempt := delivery.Attempts + 1
+	if !Retryable(statusCode) || attempt >= d.cfg.MaxAttempts {
+		log.Warn("dead-lettering delivery", "status_code", statusCode, "attempt", attempt, "error", err)
+		d.markFailed(ctx, delivery, code, err.Error(), nil)
+		return
+	}
+
+	wait := Backoff(attempt, d.cfg.InitialBackoff, d.cfg.MaxBackoff)
+	if retryAfter > wait {
+		wait = retryAfter
+	}
+	next := d.now().Add(wait)
+	log.Warn("delivery failed, will retry", "status_code", statusCode, "attempt", attempt, "retry_in", wait, "error", err)
+	d.markFailed(ctx, delivery, code, err.Error(), &next)
+}
+
+func (d *Dispatcher) markFailed(ctx context.Context, delivery *manman.WebhookDelivery, code *int, msg string, next *time.Time) {
+	if err := d.deliveries.MarkFailed(ctx, delivery.DeliveryID, code, msg, next); err != nil {
+		d.logger.Error("failed to record delivery failure", "delivery_id", delivery.DeliveryID, "error", err)
+	}
+}
+
+// send posts the rendered delivery. It returns the response status (0 when none was
+// received), the server's Retry-After if any, and an error unless the status was 2xx.
+func (d *Dispatcher) send(ctx context.Context, w *manman.Webhook, delivery *manman.WebhookDelivery) (int, time.Duration, error) {
+	event := Event{RoutingKey: delivery.RoutingKey, Data: delivery.Payload}
+	body, err := Render(w.Format, delivery, Describe(ctx, d.dir, event))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "manman-notifier")
+	req.Header.Set("X-ManMan-Event", delivery.RoutingKey)
+	req.Header.Set("X-ManMan-Delivery", strconv.FormatInt(delivery.DeliveryID, 10))
+	req.Header.Set("X-ManMan-Timestamp", strconv.FormatInt(timestamp, 10))
+	if w.Secret != "" {
+		req.Header.Set("X-ManMan-Signature", Sign(w.Secret, timestamp, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	// Keep a little of the response for the delivery history
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp.StatusCode, 0, nil
+	}
+	var retryAfter time.Duration
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+		retryAfter = time.Duration(secs) * time.Second
+	}
+	return resp.StatusCode, retryAfter, fmt.Errorf("endpoint returned %s: %s", resp.Status, bytes.TrimSpace(snippet))
+}
+
+// Retryable reports whether a failed attempt with statusCode (0 when no response was
+// received) may succeed later. Other 4xx responses mean the request itself is wrong.
+func Retryable(statusCode int) bool {
+	switch {
+	case statusCode == 0,
+		statusCode == http.StatusRequestTimeout,
+		statusCode == http.StatusTooEarly,
+		statusCode == http.StatusTooManyRequests,
+		statusCode >= 500:
+		return true
+	default:
+		return false
+	}
+}
+
+// Backoff returns the wait after the given failed attempt (1-based): initial doubled
+// per attempt, capped at max
+func Backoff(attempt int, initial, max time.Duration) time.Duration {
+	wait := initial
+	for i := 1; i < attempt; i++ {
+		wait *= 2
+		if wait >= max {
+			return max
+		}
+	}
+	return wait
+}
@@ -0,0 +1,217 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+)
+
+// Event is a message from the external exchange
+type Event struct {
+	RoutingKey string
+	Data       map[string]interface{}
+}
+
+// ParseEvent decodes an external event body, which is always a JSON object
+func ParseEvent(routingKey string, body []byte) (Event, error) {
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return Event{}, fmt.Errorf("event body is not a JSON object: %w", err)
+	}
+	return Event{RoutingKey: routingKey, Data: data}, nil
+}
+
+// SGCID is the server game config the event concerns, 0 for host events
+func (e Event) SGCID() int64 { return e.intField("sgc_id") }
+
+func (e Event) intField(key string) int64 {
+	if v, ok := e.Data[key].(float64); ok {
+		return int64(v)
+	}
+	return 0
+}
+
+func (e Event) stringField(key string) string {
+	s, _ := e.Data[key].(string)
+	return s
+}
+
+// Severity of a notification, used for embed and attachment colors
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeveritySuccess Severity = "success"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Message is the human-readable form of an event
+type Message struct {
+	Title       string
+	Description string
+	Severity    Severity
+	Fields      []Field
+}
+
+// Field is a short labelled value shown under a message
+type Field struct {
+	Name  string
+	Value string
+}
+
+// SGCInfo names a server game config for messages
+type SGCInfo struct {
+	Label      string   // e.g. "Minecraft (survival)"
+	ServerName string   // the host it's deployed to
+	Ports      []string // host ports, e.g. "25565/TCP"
+}
+
+// Directory resolves the IDs events carry into names
+type Directory interface {
+	SGC(ctx context.Context, sgcID int64) (*SGCInfo, error)
+	ServerName(ctx context.Context, serverID int64) (string, error)
+}
+
+// repositoryDirectory reads names straight from the database
+type repositoryDirectory struct {
+	repo *repository.Repository
+}
+
+// NewRepositoryDirectory returns a Directory backed by repo's servers, games, game
+// configs and server game configs
+func NewRepositoryDirectory(repo *repository.Repository) Directory {
+	return &repositoryDirectory{repo: repo}
+}
+
+func (d *repositoryDirectory) SGC(ctx context.Context, sgcID int64) (*SGCInfo, error) {
+	sgc, err := d.repo.ServerGameConfigs.Get(ctx, sgcID)
+	if err != nil {
+		return nil, err
+	}
+	info := &SGCInfo{Label: fmt.Sprintf("SGC %d", sgcID)}
+	if gc, err := d.repo.GameConfigs.Get(ctx, sgc.GameConfigID); err == nil {
+		info.Label = gc.Name
+		if game, err := d.repo.Games.Get(ctx, gc.GameID); err == nil {
+			info.Label = fmt.Sprintf("%s (%s)", game.Name, gc.Name)
+		}
+	}
+	if server, err := d.repo.Servers.Get(ctx, sgc.ServerID); err == nil {
+		info.ServerName = server.Name
+	}
+	for binding, hostPort := range sgc.PortBindings {
+		// Keys are "<container port>/<protocol>", values the host port
+		port, ok := hostPort.(float64)
+		if !ok {
+			continue
+		}
+		protocol := binding[strings.Index(binding, "/")+1:]
+		info.Ports = append(info.Ports, fmt.Sprintf("%d/%s", int(port), protocol))
+	}
+	sort.Strings(info.Ports)
+	return info, nil
+}
+
+func (d *repositoryDirectory) ServerName(ctx context.Context, serverID int64) (string, error) {
+	server, err := d.repo.Servers.Get(ctx, serverID)
+	if err != nil {
+		return "", err
+	}
+	return server.Name, nil
+}
+
+// Describe turns an event into a message, falling back to IDs when dir can't name them
+func Describe(ctx context.Context, dir Directory, e Event) Message {
+	target := "Server game config"
+	var info *SGCInfo
+	if sgcID := e.SGCID(); sgcID != 0 {
+		target = fmt.Sprintf("SGC %d", sgcID)
+		if i, err := dir.SGC(ctx, sgcID); err == nil {
+			info = i
+			target = i.Label
+			if i.ServerName != "" {
+				target = fmt.Sprintf("%s on %s", i.Label, i.ServerName)
+			}
+		}
+	}
+	session := Field{Name: "Session", Value: fmt.Sprintf("%d", e.intField("session_id"))}
+
+	switch e.RoutingKey {
+	case "manman.session.ready":
+		m := Message{Title: fmt.Sprintf("%s is up", target), Severity: SeveritySuccess, Fields: []Field{session}}
+		if info != nil && len(info.Ports) > 0 {
+			m.Description = fmt.Sprintf("Accepting players on port %s", strings.Join(info.Ports, ", "))
+		} else {
+			m.Description = "Accepting players"
+		}
+		return m
+	case "manman.session.running":
+		return Message{Title: fmt.Sprintf("%s is starting", target), Severity: SeverityInfo, Fields: []Field{session}}
+	case "manman.session.stopped":
+		return Message{Title: fmt.Sprintf("%s stopped", target), Severity: SeverityInfo, Fields: []Field{session}}
+	case "manman.session.crashed":
+		m := Message{Title: fmt.Sprintf("%s crashed", target), Severity: SeverityError, Fields: []Field{session}}
+		if code, ok := e.Data["exit_code"].(float64); ok {
+			m.Fields = append(m.Fields, Field{Name: "Exit code", Value: fmt.Sprintf("%d", int(code))})
+		}
+		return m
+	case "manman.session.lost":
+		return Message{Title: fmt.Sprintf("Lost contact with %s", target), Description: "The host stopped reporting on this session.", Severity: SeverityError, Fields: []Field{session}}
+	case "manman.session.restart_abandoned":
+		return Message{
+			Title:       fmt.Sprintf("%s won't be restarted", target),
+			Description: e.stringField("reason"),
+			Severity:    SeverityError,
+			Fields:      []Field{session, {Name: "Attempt", Value: fmt.Sprintf("%d", e.intField("attempt"))}},
+		}
+	case "manman.session.alert":
+		return Message{
+			Title:       fmt.Sprintf("%s: %s", target, e.stringField("rule_name")),
+			Description: e.stringField("line"),
+			Severity:    SeverityWarning,
+			Fields:      []Field{session, {Name: "Matches", Value: fmt.Sprintf("%d", e.intField("match_count"))}},
+		}
+	case "manman.session.player_count":
+		return Message{Title: fmt.Sprintf("%s: %d players online", target, e.intField("player_count")), Severity: SeverityInfo, Fields: []Field{session}}
+	case "manman.backup.completed":
+		m := Message{Title: fmt.Sprintf("Backup of %s completed", target), Severity: SeveritySuccess}
+		if size := e.intField("size_bytes"); size > 0 {
+			m.Fields = append(m.Fields, Field{Name: "Size", Value: formatBytes(size)})
+		}
+		return m
+	case "manman.backup.failed":
+		return Message{Title: fmt.Sprintf("Backup of %s failed", target), Description: e.stringField("error_message"), Severity: SeverityError}
+	}
+
+	if strings.HasPrefix(e.RoutingKey, "manman.host.") {
+		status := strings.TrimPrefix(e.RoutingKey, "manman.host.")
+		host := fmt.Sprintf("Host %d", e.intField("server_id"))
+		if name, err := dir.ServerName(ctx, e.intField("server_id")); err == nil && name != "" {
+			host = fmt.Sprintf("Host %s", name)
+		}
+		severity := SeverityWarning
+		if status == "online" {
+			severity = SeveritySuccess
+		}
+		return Message{Title: fmt.Sprintf("%s is %s", host, status), Severity: severity}
+	}
+
+	return Message{Title: e.RoutingKey, Severity: SeverityInfo}
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
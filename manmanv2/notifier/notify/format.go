@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// Envelope is the body of a "json" format delivery
+type Envelope struct {
+	DeliveryID  int64                  `json:"delivery_id"`
+	Event       string                 `json:"event"` // the routing key, e.g. "manman.session.crashed"
+	Title       string                 `json:"title"`
+	Description string                 `json:"description,omitempty"`
+	Severity    Severity               `json:"severity"`
+	CreatedAt   time.Time              `json:"created_at"`
+	Data        map[string]interface{} `json:"data"` // the event as published
+}
+
+// Embed and attachment colors by severity
+var (
+	discordColors = map[Severity]int{
+		SeverityInfo:    0x5865F2,
+		SeveritySuccess: 0x57F287,
+		SeverityWarning: 0xFEE75C,
+		SeverityError:   0xED4245,
+	}
+	slackColors = map[Severity]string{
+		SeverityInfo:    "#5865F2",
+		SeveritySuccess: "good",
+		SeverityWarning: "warning",
+		SeverityError:   "danger",
+	}
+)
+
+// Render builds the request body for a delivery in the webhook's format
+func Render(format string, d *manman.WebhookDelivery, msg Message) ([]byte, error) {
+	switch format {
+	case manman.WebhookFormatJSON, "":
+		return json.Marshal(Envelope{
+			DeliveryID:  d.DeliveryID,
+			Event:       d.RoutingKey,
+			Title:       msg.Title,
+			Description: msg.Description,
+			Severity:    msg.Severity,
+			CreatedAt:   d.CreatedAt.UTC(),
+			Data:        d.Payload,
+		})
+	case manman.WebhookFormatDiscord:
+		type field struct {
+			Name   string `json:"name"`
+			Value  string `json:"value"`
+			Inline bool   `json:"inline"`
+		}
+		fields := make([]field, len(msg.Fields))
+		for i, f := range msg.Fields {
+			fields[i] = field{Name: f.Name, Value: f.Value, Inline: true}
+		}
+		return json.Marshal(map[string]interface{}{
+			"embeds": []map[string]interface{}{{
+				"title":       msg.Title,
+				"description": msg.Description,
+				"color":       discordColors[msg.Severity],
+				"fields":      fields,
+				"footer":      map[string]string{"text": d.RoutingKey},
+				"timestamp":   d.CreatedAt.UTC().Format(time.RFC3339),
+			}},
+		})
+	case manman.WebhookFormatSlack:
+		type field struct {
+			Title string `json:"title"`
+			Value string `json:"value"`
+			Short bool   `json:"short"`
+		}
+		fields := make([]field, len(msg.Fields))
+		for i, f := range msg.Fields {
+			fields[i] = field{Title: f.Name, Value: f.Value, Short: true}
+		}
+		return json.Marshal(map[string]interface{}{
+			"text": msg.Title,
+			"attachments": []map[string]interface{}{{
+				"color":  slackColors[msg.Severity],
+				"text":   msg.Description,
+				"fields": fields,
+				"footer": d.RoutingKey,
+				"ts":     d.CreatedAt.Unix(),
+			}},
+		})
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+}
+
+// Sign returns the X-ManMan-Signature value for body sent at timestamp: "sha256=" and
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with secret. Receivers should
+// recompute it and reject stale timestamps to stop replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
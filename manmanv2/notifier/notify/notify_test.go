@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/rmq"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+type fakeDirectory struct{}
+
+func (fakeDirectory) SGC(ctx context.Context, sgcID int64) (*SGCInfo, error) {
+	if sgcID != 7 {
+		return nil, errors.New("not found")
+	}
+	return &SGCInfo{Label: "Minecraft (survival)", ServerName: "box", Ports: []string{"25565/TCP"}}, nil
+}
+
+func (fakeDirectory) ServerName(ctx context.Context, serverID int64) (string, error) {
+	return "box", nil
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		key      string
+		data     map[string]interface{}
+		title    string
+		severity Severity
+	}{
+		{"manman.session.ready", map[string]interface{}{"sgc_id": 7.0, "session_id": 1.0}, "Minecraft (survival) on box is up", SeveritySuccess},
+		{"manman.session.crashed", map[string]interface{}{"sgc_id": 9.0, "exit_code": 137.0}, "SGC 9 crashed", SeverityError},
+		{"manman.session.alert", map[string]interface{}{"sgc_id": 7.0, "rule_name": "Out of memory", "match_count": 3.0}, "Minecraft (survival) on box: Out of memory", SeverityWarning},
+		{"manman.backup.completed", map[string]interface{}{"sgc_id": 7.0, "size_bytes": 2048.0}, "Backup of Minecraft (survival) on box completed", SeveritySuccess},
+		{"manman.host.offline", map[string]interface{}{"server_id": 1.0}, "Host box is offline", SeverityWarning},
+		{"manman.something.new", nil, "manman.something.new", SeverityInfo},
+	}
+	for _, tt := range tests {
+		m := Describe(context.Background(), fakeDirectory{}, Event{RoutingKey: tt.key, Data: tt.data})
+		if m.Title != tt.title || m.Severity != tt.severity {
+			t.Errorf("Describe(%s) = %q/%s, want %q/%s", tt.key, m.Title, m.Severity, tt.title, tt.severity)
+		}
+	}
+
+	m := Describe(context.Background(), fakeDirectory{}, Event{RoutingKey: "manman.session.ready", Data: map[string]interface{}{"sgc_id": 7.0}})
+	if !strings.Contains(m.Description, "25565/TCP") {
+		t.Errorf("ready message should name the port, got %q", m.Description)
+	}
+}
+
+func TestRender(t *testing.T) {
+	d := &manman.WebhookDelivery{DeliveryID: 3, RoutingKey: "manman.session.crashed", Payload: manman.JSONB{"sgc_id": 7.0}, CreatedAt: time.Unix(1700000000, 0)}
+	msg := Message{Title: "crashed", Severity: SeverityError, Fields: []Field{{Name: "Exit code", Value: "1"}}}
+
+	for _, format := range []string{manman.WebhookFormatJSON, manman.WebhookFormatDiscord, manman.WebhookFormatSlack} {
+		body, err := Render(format, d, msg)
+		if err != nil {
+			t.Fatalf("Render(%s): %v", format, err)
+		}
+		var out map[string]interface{}
+		if err := json.Unmarshal(body, &out); err != nil {
+			t.Fatalf("Render(%s) is not JSON: %v", format, err)
+		}
+		switch format {
+		case manman.WebhookFormatJSON:
+			if out["event"] != d.RoutingKey || out["title"] != "crashed" {
+				t.Errorf("json envelope = %v", out)
+			}
+		case manman.WebhookFormatDiscord:
+			embed := out["embeds"].([]interface{})[0].(map[string]interface{})
+			if embed["color"] != float64(discordColors[SeverityError]) {
+				t.Errorf("discord embed = %v", embed)
+			}
+		case manman.WebhookFormatSlack:
+			if out["text"] != "crashed" {
+				t.Errorf("slack body = %v", out)
+			}
+		}
+	}
+
+	if _, err := Render("xml", d, msg); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestSign(t *testing.T) {
+	// printf '1700000000.{}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=b8569b78799ff9e3cbff0fc2d63a33a2b57f3282abd07c37ae5e8e7d79a5f163"
+	if got := Sign("secret", 1700000000, []byte("{}")); got != want {
+		t.Errorf("Sign() = %q, want %q", got, want)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{4, 80 * time.Second},
+		{10, 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempt, 10*time.Second, 5*time.Minute); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for code, want := range map[int]bool{0: true, 400: false, 404: false, 408: true, 429: true, 500: true, 503: true} {
+		if got := Retryable(code); got != want {
+			t.Errorf("Retryable(%d) = %v, want %v", code, got, want)
+		}
+	}
+}
+
+type fakeWebhooks struct {
+	repository.WebhookRepository
+	webhooks map[int64]*manman.Webhook
+}
+
+func (f *fakeWebhooks) Get(ctx context.Context, id int64) (*manman.Webhook, error) {
+	return f.webhooks[id], nil
+}
+
+func (f *fakeWebhooks) ListEnabled(ctx context.Context) ([]*manman.Webhook, error) {
+	var out []*manman.Webhook
+	for _, w := range f.webhooks {
+		if w.Enabled {
+			out = append(out, w)
+		}
+	}
+	return out, nil
+}
+
+type failure struct {
+	code *int
+	next *time.Time
+}
+
+type fakeDeliveries struct {
+	repository.WebhookDeliveryRepository
+	due       []*manman.WebhookDelivery
+	created   []*manman.WebhookDelivery
+	delivered map[int64]int
+	failed    map[int64]failure
+}
+
+func newFakeDeliveries(due ...*manman.WebhookDelivery) *fakeDeliveries {
+	return &fakeDeliveries{due: due, delivered: map[int64]int{}, failed: map[int64]failure{}}
+}
+
+func (f *fakeDeliveries) Create(ctx context.Context, d *manman.WebhookDelivery) (*manman.WebhookDelivery, error) {
+	f.created = append(f.created, d)
+	return d, nil
+}
+
+func (f *fakeDeliveries) ClaimDue(ctx context.Context, limit int, staleAfter time.Duration) ([]*manman.WebhookDelivery, error) {
+	due := f.due
+	f.due = nil
+	return due, nil
+}
+
+func (f *fakeDeliveries) MarkDelivered(ctx context.Context, id int64, code int) error {
+	f.delivered[id] = code
+	return nil
+}
+
+func (f *fakeDeliveries) MarkFailed(ctx context.Context, id int64, code *int, msg string, next *time.Time) error {
+	f.failed[id] = failure{code: code, next: next}
+	return nil
+}
+
+func TestDispatchDue(t *testing.T) {
+	var gotSignature, gotTimestamp string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			gotSignature = r.Header.Get("X-ManMan-Signature")
+			gotTimestamp = r.Header.Get("X-ManMan-Timestamp")
+			gotBody, _ = io.ReadAll(r.Body)
+		case "/busy":
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case "/gone":
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer srv.Close()
+
+	webhooks := &fakeWebhooks{webhooks: map[int64]*manman.Webhook{
+		1: {WebhookID: 1, URL: srv.URL + "/ok", Format: manman.WebhookFormatJSON, Secret: "s3cret", Enabled: true},
+		2: {WebhookID: 2, URL: srv.URL + "/busy", Format: manman.WebhookFormatDiscord, Enabled: true},
+		3: {WebhookID: 3, URL: srv.URL + "/gone", Format: manman.WebhookFormatSlack, Enabled: true},
+		4: {WebhookID: 4, URL: srv.URL + "/ok", Format: manman.WebhookFormatJSON, Enabled: false},
+	}}
+	event := func(id, webhookID int64, attempts int) *manman.WebhookDelivery {
+		return &manman.WebhookDelivery{DeliveryID: id, WebhookID: webhookID, RoutingKey: "manman.session.stopped", Payload: manman.JSONB{"sgc_id": 7.0}, Attempts: attempts}
+	}
+	deliveries := newFakeDeliveries(
+		event(10, 1, 0), // delivered
+		event(20, 2, 0), // 503, retried after Retry-After
+		event(21, 2, 4), // 503 on the last attempt, dead
+		event(30, 3, 0), // 410, dead
+		event(40, 4, 0), // webhook disabled, dead
+	)
+
+	now := time.Unix(1700000000, 0)
+	d := NewDispatcher(webhooks, deliveries, fakeDirectory{}, DispatchConfig{
+		BatchSize:      10,
+		RequestTimeout: 5 * time.Second,
+		MaxAttempts:    5,
+		InitialBackoff: 10 * time.Second,
+		MaxBackoff:     time.Hour,
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	d.now = func() time.Time { return now }
+
+	n, err := d.DispatchDue(context.Background())
+	if err != nil || n != 5 {
+		t.Fatalf("DispatchDue() = %d, %v", n, err)
+	}
+
+	if deliveries.delivered[10] != http.StatusOK {
+		t.Errorf("delivery 10 should be delivered, got %v", deliveries.delivered)
+	}
+	if gotTimestamp != strconv.FormatInt(now.Unix(), 10) || gotSignature != Sign("s3cret", now.Unix(), gotBody) {
+		t.Errorf("bad signature %q for timestamp %q", gotSignature, gotTimestamp)
+	}
+
+	if f := deliveries.failed[20]; f.next == nil || !f.next.Equal(now.Add(120*time.Second)) || *f.code != 503 {
+		t.Errorf("delivery 20 should retry after Retry-After, got %+v", f)
+	}
+	for _, id := range []int64{21, 30, 40} {
+		f, ok := deliveries.failed[id]
+		if !ok || f.next != nil {
+			t.Errorf("delivery %d should be dead-lettered, got %+v (recorded %v)", id, f, ok)
+		}
+	}
+}
+
+func TestRouterHandle(t *testing.T) {
+	webhooks := &fakeWebhooks{webhooks: map[int64]*manman.Webhook{
+		1: {WebhookID: 1, EventPatterns: []string{"manman.session.#"}, Enabled: true},
+		2: {WebhookID: 2, EventPatterns: []string{"manman.session.#"}, SGCIDs: []int64{8}, Enabled: true},
+		3: {WebhookID: 3, EventPatterns: []string{"manman.backup.*"}, Enabled: true},
+	}}
+	deliveries := newFakeDeliveries()
+	r := NewRouter(webhooks, deliveries, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if err := r.Handle(context.Background(), rmq.Message{RoutingKey: "manman.session.crashed", Body: []byte(`{"sgc_id": 7}`)}); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries.created) != 1 || deliveries.created[0].WebhookID != 1 {
+		t.Errorf("expected one delivery to webhook 1, got %+v", deliveries.created)
+	}
+
+	if err := r.Handle(context.Background(), rmq.Message{RoutingKey: "manman.session.crashed", Body: []byte(`not json`)}); err == nil {
+		t.Error("expected an error for a malformed body")
+	}
+}
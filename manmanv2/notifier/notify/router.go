@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/whale-net/everything/libs/go/rmq"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// Router queues a delivery for every enabled webhook an external event matches. It
+// only writes rows; the Dispatcher sends them, so a slow or dead endpoint never holds
+// up the queue.
+type Router struct {
+	webhooks   repository.WebhookRepository
+	deliveries repository.WebhookDeliveryRepository
+	logger     *slog.Logger
+}
+
+func NewRouter(webhooks repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, logger *slog.Logger) *Router {
+	return &Router{webhooks: webhooks, deliveries: deliveries, logger: logger}
+}
+
+// Handle is an rmq.MessageHandler for the external exchange
+func (r *Router) Handle(ctx context.Context, msg rmq.Message) error {
+	event, err := ParseEvent(msg.RoutingKey, msg.Body)
+	if err != nil {
+		return &rmq.PermanentError{Err: err}
+	}
+
+	webhooks, err := r.webhooks.ListEnabled(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	for _, w := range webhooks {
+		if !w.Matches(event.RoutingKey, event.SGCID()) {
+			continue
+		}
+		d, err := r.deliveries.Create(ctx, &manman.WebhookDelivery{
+			WebhookID:  w.WebhookID,
+			RoutingKey: event.RoutingKey,
+			Payload:    manman.JSONB(event.Data),
+		})
+		if err != nil {
+			// Requeued whole; webhooks that already got a row may get the event twice
+			return fmt.Errorf("failed to queue delivery to webhook %d: %w", w.WebhookID, err)
+		}
+		r.logger.Debug("queued delivery", "delivery_id", d.DeliveryID, "webhook_id", w.WebhookID, "routing_key", event.RoutingKey)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/api/workshop"
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+	"github.com/whale-net/everything/manmanv2/processor/handlers"
+)
+
+// ============================================================================
+// Update scan: re-queries Steam for every addon in a library attached to an SGC
+// ============================================================================
+
+type addonUpdateScanArgs struct{}
+
+func (addonUpdateScanArgs) Kind() string { return "addon_update_scan" }
+
+// addonInstaller is the part of the workshop manager the addon jobs use
+type addonInstaller interface {
+	InstallAddon(ctx context.Context, sgcID, addonID int64, forceReinstall, skipDispatch bool, installationPathOverride string, presetIDOverride int64, volumeIDOverride int64) (*manman.WorkshopInstallation, error)
+	FetchAndCreateAddon(ctx context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error)
+}
+
+type addonUpdateScanWorker struct {
+	river.WorkerDefaults[addonUpdateScanArgs]
+	repo      *repository.Repository
+	steam     workshop.SteamClient
+	installer addonInstaller
+	events    handlers.Publisher
+	logger    *slog.Logger
+}
+
+func (w *addonUpdateScanWorker) Work(ctx context.Context, _ *river.Job[addonUpdateScanArgs]) error {
+	addons, err := w.repo.WorkshopAddons.ListInActiveLibraries(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list addons in active libraries: %w", err)
+	}
+
+	policies := make(map[int64]string)
+	for _, addon := range addons {
+		if addon.PlatformType != manman.PlatformTypeSteamWorkshop {
+			continue
+		}
+		if err := w.checkAddon(ctx, addon, policies); err != nil {
+			// Steam may not know the item any more; the next scan tries again
+			w.logger.Warn("failed to check addon for updates", "addon_id", addon.AddonID, "workshop_id", addon.WorkshopID, "error", err)
+		}
+	}
+	return nil
+}
+
+// checkAddon records an addon's latest Steam update time. Installations of an addon that
+// changed are flagged and announced; collections are re-expanded into their libraries.
+func (w *addonUpdateScanWorker) checkAddon(ctx context.Context, addon *manman.WorkshopAddon, policies map[int64]string) error {
+	details, err := w.steam.GetWorkshopItemDetails(ctx, addon.WorkshopID)
+	if err != nil {
+		return err
+	}
+
+	if addon.IsCollection {
+		if err := w.expandCollection(ctx, addon); err != nil {
+			return fmt.Errorf("failed to expand collection: %w", err)
+		}
+	}
+
+	// An addon without a time yet only gets one recorded. Collections are always saved
+	// for their refreshed item list, but aren't installed themselves.
+	updated := addon.LastUpdated != nil && details.TimeUpdated.After(*addon.LastUpdated)
+	if addon.LastUpdated == nil || updated || addon.IsCollection {
+		addon.LastUpdated = &details.TimeUpdated
+		if details.FileSize > 0 {
+			addon.FileSizeBytes = &details.FileSize
+		}
+		if err := w.repo.WorkshopAddons.Update(ctx, addon); err != nil {
+			return fmt.Errorf("failed to update addon: %w", err)
+		}
+	}
+	if !updated || addon.IsCollection {
+		return nil
+	}
+
+	installations, err := w.repo.WorkshopInstallations.MarkUpdateAvailable(ctx, addon.AddonID)
+	if err != nil {
+		return fmt.Errorf("failed to flag installations: %w", err)
+	}
+	w.logger.Info("addon updated upstream", "addon_id", addon.AddonID, "workshop_id", addon.WorkshopID, "installations", len(installations))
+
+	for _, inst := range installations {
+		policy, err := sgcAddonUpdatePolicy(ctx, w.repo, inst.SGCID, policies)
+		if err != nil {
+			w.logger.Warn("failed to get SGC addon update policy", "sgc_id", inst.SGCID, "error", err)
+			continue
+		}
+		if policy == manman.AddonUpdatePolicyOff {
+			continue
+		}
+		event := hostrmq.AddonUpdateEvent{
+			SGCID:          inst.SGCID,
+			InstallationID: inst.InstallationID,
+			AddonID:        addon.AddonID,
+			WorkshopID:     addon.WorkshopID,
+			Name:           addon.Name,
+			TimeUpdated:    details.TimeUpdated,
+			Policy:         policy,
+		}
+		if err := w.events.PublishExternal(ctx, "manman.sgc.addon_update_available", event); err != nil {
+			w.logger.Error("failed to publish addon update to external exchange", "sgc_id", inst.SGCID, "addon_id", addon.AddonID, "error", err)
+		}
+	}
+	return nil
+}
+
+// expandCollection adds items newly added to a Steam collection to every library the
+// collection is in, creating addons for items that don't have one yet
+func (w *addonUpdateScanWorker) expandCollection(ctx context.Context, collection *manman.WorkshopAddon) error {
+	items, err := w.steam.GetCollectionDetails(ctx, collection.WorkshopID)
+	if err != nil {
+		return err
+	}
+	libraries, err := w.repo.WorkshopLibraries.ListByAddon(ctx, collection.AddonID)
+	if err != nil {
+		return fmt.Errorf("failed to list libraries of collection: %w", err)
+	}
+
+	collectionItems := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		collectionItems[i] = map[string]interface{}{
+			"workshop_id": item.WorkshopID,
+			"title":       item.Title,
+		}
+	}
+	if collection.Metadata == nil {
+		collection.Metadata = manman.JSONB{}
+	}
+	collection.Metadata["collection_items"] = collectionItems
+
+	for _, library := range libraries {
+		contents, err := w.repo.WorkshopLibraries.ListAddons(ctx, library.LibraryID)
+		if err != nil {
+			return fmt.Errorf("failed to list addons of library %d: %w", library.LibraryID, err)
+		}
+		inLibrary := make(map[string]struct{}, len(contents))
+		for _, a := range contents {
+			inLibrary[a.WorkshopID] = struct{}{}
+		}
+
+		order := len(contents)
+		for _, item := range items {
+			if _, ok := inLibrary[item.WorkshopID]; ok {
+				continue
+			}
+			child, err := w.collectionItemAddon(ctx, collection.GameID, item.WorkshopID)
+			if err != nil {
+				w.logger.Warn("failed to add collection item", "collection_id", collection.AddonID, "workshop_id", item.WorkshopID, "error", err)
+				continue
+			}
+			if err := w.repo.WorkshopLibraries.AddAddon(ctx, library.LibraryID, child.AddonID, order); err != nil {
+				return fmt.Errorf("failed to add addon %d to library %d: %w", child.AddonID, library.LibraryID, err)
+			}
+			order++
+			inLibrary[item.WorkshopID] = struct{}{}
+			w.logger.Info("collection item joined library", "collection_id", collection.AddonID, "library_id", library.LibraryID, "addon_id", child.AddonID)
+		}
+	}
+	return nil
+}
+
+func (w *addonUpdateScanWorker) collectionItemAddon(ctx context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error) {
+	addon, err := w.repo.WorkshopAddons.GetByWorkshopID(ctx, gameID, workshopID, manman.PlatformTypeSteamWorkshop)
+	if err == nil {
+		return addon, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+	return w.installer.FetchAndCreateAddon(ctx, gameID, workshopID)
+}
+
+// ============================================================================
+// Reinstall job: runs every minute, re-downloads flagged addons of SGCs with the
+// reinstall policy once they have no active session
+// ============================================================================
+
+type addonReinstallArgs struct{}
+
+func (addonReinstallArgs) Kind() string { return "addon_reinstall" }
+
+type addonReinstallWorker struct {
+	river.WorkerDefaults[addonReinstallArgs]
+	repo      *repository.Repository
+	installer addonInstaller
+	logger    *slog.Logger
+}
+
+func (w *addonReinstallWorker) Work(ctx context.Context, _ *river.Job[addonReinstallArgs]) error {
+	installations, err := w.repo.WorkshopInstallations.ListUpdateAvailable(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list out of date installations: %w", err)
+	}
+
+	policies := make(map[int64]string)
+	busy := make(map[int64]bool)
+	for _, inst := range installations {
+		policy, err := sgcAddonUpdatePolicy(ctx, w.repo, inst.SGCID, policies)
+		if err != nil {
+			w.logger.Warn("failed to get SGC addon update policy", "sgc_id", inst.SGCID, "error", err)
+			continue
+		}
+		if policy != manman.AddonUpdatePolicyReinstall {
+			continue
+		}
+
+		active, checked := busy[inst.SGCID]
+		if !checked {
+			active, err = hasActiveSession(ctx, w.repo, inst.SGCID)
+			if err != nil {
+				w.logger.Warn("failed to list SGC sessions", "sgc_id", inst.SGCID, "error", err)
+				continue
+			}
+			busy[inst.SGCID] = active
+		}
+		if active {
+			continue // picked up once the session stops
+		}
+
+		// Reinstall into the same place; the installation is pending until the host reports back
+		if _, err := w.installer.InstallAddon(ctx, inst.SGCID, inst.AddonID, true, false, inst.InstallationPath, 0, 0); err != nil {
+			w.logger.Error("failed to reinstall updated addon", "installation_id", inst.InstallationID, "sgc_id", inst.SGCID, "error", err)
+			continue
+		}
+		w.logger.Info("reinstalling updated addon", "installation_id", inst.InstallationID, "sgc_id", inst.SGCID, "addon_id", inst.AddonID)
+	}
+	return nil
+}
+
+// sgcAddonUpdatePolicy looks up an SGC's addon update policy, caching it in policies
+func sgcAddonUpdatePolicy(ctx context.Context, repo *repository.Repository, sgcID int64, policies map[int64]string) (string, error) {
+	if policy, ok := policies[sgcID]; ok {
+		return policy, nil
+	}
+	sgc, err := repo.ServerGameConfigs.Get(ctx, sgcID)
+	if err != nil {
+		return "", err
+	}
+	policies[sgcID] = sgc.AddonUpdatePolicy
+	return sgc.AddonUpdatePolicy, nil
+}
+
+func hasActiveSession(ctx context.Context, repo *repository.Repository, sgcID int64) (bool, error) {
+	sessions, err := repo.Sessions.List(ctx, &sgcID, 100, 0)
+	if err != nil {
+		return false, err
+	}
+	for _, s := range sessions {
+		if s.IsActive() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/api/steam"
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// The fakes embed their repository interface and implement only what the addon jobs call
+
+type fakeSteam struct {
+	items       map[string]*steam.WorkshopItemMetadata
+	collections map[string][]steam.CollectionItem
+}
+
+func (f *fakeSteam) GetWorkshopItemDetails(_ context.Context, workshopID string) (*steam.WorkshopItemMetadata, error) {
+	return f.items[workshopID], nil
+}
+
+func (f *fakeSteam) GetCollectionDetails(_ context.Context, collectionID string) ([]steam.CollectionItem, error) {
+	return f.collections[collectionID], nil
+}
+
+type fakeAddonRepo struct {
+	repository.WorkshopAddonRepository
+	addons []*manman.WorkshopAddon
+}
+
+func (f *fakeAddonRepo) ListInActiveLibraries(context.Context) ([]*manman.WorkshopAddon, error) {
+	return f.addons, nil
+}
+
+func (f *fakeAddonRepo) GetByWorkshopID(_ context.Context, gameID int64, workshopID, platformType string) (*manman.WorkshopAddon, error) {
+	for _, a := range f.addons {
+		if a.GameID == gameID && a.WorkshopID == workshopID && a.PlatformType == platformType {
+			return a, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (f *fakeAddonRepo) Update(context.Context, *manman.WorkshopAddon) error { return nil }
+
+type fakeInstallationRepo struct {
+	repository.WorkshopInstallationRepository
+	installations []*manman.WorkshopInstallation
+}
+
+func (f *fakeInstallationRepo) MarkUpdateAvailable(_ context.Context, addonID int64) ([]*manman.WorkshopInstallation, error) {
+	var marked []*manman.WorkshopInstallation
+	for _, i := range f.installations {
+		if i.AddonID == addonID && i.Status == manman.InstallationStatusInstalled {
+			i.UpdateAvailable = true
+			marked = append(marked, i)
+		}
+	}
+	return marked, nil
+}
+
+func (f *fakeInstallationRepo) ListUpdateAvailable(context.Context) ([]*manman.WorkshopInstallation, error) {
+	var out []*manman.WorkshopInstallation
+	for _, i := range f.installations {
+		if i.UpdateAvailable && i.Status == manman.InstallationStatusInstalled {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+type fakeLibraryRepo struct {
+	repository.WorkshopLibraryRepository
+	addons *fakeAddonRepo
+	member map[int64][]int64 // library -> addon IDs
+}
+
+func (f *fakeLibraryRepo) ListByAddon(_ context.Context, addonID int64) ([]*manman.WorkshopLibrary, error) {
+	var out []*manman.WorkshopLibrary
+	for libraryID, addonIDs := range f.member {
+		for _, id := range addonIDs {
+			if id == addonID {
+				out = append(out, &manman.WorkshopLibrary{LibraryID: libraryID})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeLibraryRepo) ListAddons(_ context.Context, libraryID int64) ([]*manman.WorkshopAddonWithGame, error) {
+	var out []*manman.WorkshopAddonWithGame
+	for _, id := range f.member[libraryID] {
+		for _, a := range f.addons.addons {
+			if a.AddonID == id {
+				out = append(out, &manman.WorkshopAddonWithGame{WorkshopAddon: *a})
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeLibraryRepo) AddAddon(_ context.Context, libraryID, addonID int64, _ int) error {
+	f.member[libraryID] = append(f.member[libraryID], addonID)
+	return nil
+}
+
+type fakeSGCRepo struct {
+	repository.ServerGameConfigRepository
+	policies map[int64]string
+}
+
+func (f *fakeSGCRepo) Get(_ context.Context, sgcID int64) (*manman.ServerGameConfig, error) {
+	return &manman.ServerGameConfig{SGCID: sgcID, AddonUpdatePolicy: f.policies[sgcID]}, nil
+}
+
+type fakeSessionRepo struct {
+	repository.SessionRepository
+	sessions map[int64][]*manman.Session
+}
+
+func (f *fakeSessionRepo) List(_ context.Context, sgcID *int64, _, _ int) ([]*manman.Session, error) {
+	return f.sessions[*sgcID], nil
+}
+
+type fakeInstaller struct {
+	addons    *fakeAddonRepo
+	installed []int64 // installation IDs reinstalled, in order
+	paths     []string
+	install   map[[2]int64]int64
+}
+
+func (f *fakeInstaller) InstallAddon(_ context.Context, sgcID, addonID int64, forceReinstall, _ bool, installationPathOverride string, _, _ int64) (*manman.WorkshopInstallation, error) {
+	if !forceReinstall {
+		panic("updated addons must be force reinstalled")
+	}
+	f.installed = append(f.installed, f.install[[2]int64{sgcID, addonID}])
+	f.paths = append(f.paths, installationPathOverride)
+	return &manman.WorkshopInstallation{SGCID: sgcID, AddonID: addonID, Status: manman.InstallationStatusPending}, nil
+}
+
+func (f *fakeInstaller) FetchAndCreateAddon(_ context.Context, gameID int64, workshopID string) (*manman.WorkshopAddon, error) {
+	addon := &manman.WorkshopAddon{
+		AddonID:      int64(100 + len(f.addons.addons)),
+		GameID:       gameID,
+		WorkshopID:   workshopID,
+		PlatformType: manman.PlatformTypeSteamWorkshop,
+	}
+	f.addons.addons = append(f.addons.addons, addon)
+	return addon, nil
+}
+
+type recordedEvent struct {
+	routingKey string
+	message    interface{}
+}
+
+type fakeEvents struct{ published []recordedEvent }
+
+func (f *fakeEvents) PublishExternal(_ context.Context, routingKey string, message interface{}) error {
+	f.published = append(f.published, recordedEvent{routingKey, message})
+	return nil
+}
+
+func discardLogger() *slog.Logger { return slog.New(slog.NewTextHandler(io.Discard, nil)) }
+
+func TestAddonUpdateScan(t *testing.T) {
+	installedAt := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	updatedAt := installedAt.Add(48 * time.Hour)
+
+	addons := &fakeAddonRepo{addons: []*manman.WorkshopAddon{
+		{AddonID: 1, GameID: 7, WorkshopID: "111", PlatformType: manman.PlatformTypeSteamWorkshop, Name: "Maps", LastUpdated: &installedAt},
+		{AddonID: 2, GameID: 7, WorkshopID: "222", PlatformType: manman.PlatformTypeSteamWorkshop, Name: "Weapons", LastUpdated: &installedAt},
+		{AddonID: 3, GameID: 7, WorkshopID: "333", PlatformType: manman.PlatformTypeSteamWorkshop, Name: "Pack", IsCollection: true, LastUpdated: &installedAt},
+		{AddonID: 4, GameID: 7, WorkshopID: "sodium", PlatformType: manman.PlatformTypeModrinth},
+	}}
+	installations := &fakeInstallationRepo{installations: []*manman.WorkshopInstallation{
+		{InstallationID: 10, SGCID: 1, AddonID: 1, Status: manman.InstallationStatusInstalled},
+		{InstallationID: 11, SGCID: 2, AddonID: 1, Status: manman.InstallationStatusInstalled},
+		{InstallationID: 12, SGCID: 3, AddonID: 1, Status: manman.InstallationStatusInstalled},
+		{InstallationID: 13, SGCID: 1, AddonID: 1, Status: manman.InstallationStatusFailed},
+		{InstallationID: 14, SGCID: 1, AddonID: 2, Status: manman.InstallationStatusInstalled},
+	}}
+	libraries := &fakeLibraryRepo{addons: addons, member: map[int64][]int64{50: {1, 3}}}
+	repo := &repository.Repository{
+		WorkshopAddons:        addons,
+		WorkshopInstallations: installations,
+		WorkshopLibraries:     libraries,
+		ServerGameConfigs: &fakeSGCRepo{policies: map[int64]string{
+			1: manman.AddonUpdatePolicyNotify,
+			2: manman.AddonUpdatePolicyReinstall,
+			3: manman.AddonUpdatePolicyOff,
+		}},
+	}
+	steamClient := &fakeSteam{
+		items: map[string]*steam.WorkshopItemMetadata{
+			"111": {WorkshopID: "111", TimeUpdated: updatedAt},
+			"222": {WorkshopID: "222", TimeUpdated: installedAt},
+			"333": {WorkshopID: "333", TimeUpdated: installedAt, IsCollection: true},
+		},
+		// 111 is already in the library; 444 was added to the collection on Steam
+		collections: map[string][]steam.CollectionItem{"333": {{WorkshopID: "111"}, {WorkshopID: "444", Title: "New map"}}},
+	}
+	events := &fakeEvents{}
+	worker := &addonUpdateScanWorker{
+		repo:      repo,
+		steam:     steamClient,
+		installer: &fakeInstaller{addons: addons},
+		events:    events,
+		logger:    discardLogger(),
+	}
+
+	if err := worker.Work(context.Background(), nil); err != nil {
+		t.Fatalf("Work: %v", err)
+	}
+
+	if !addons.addons[0].LastUpdated.Equal(updatedAt) {
+		t.Errorf("addon 1 last_updated = %v, want %v", addons.addons[0].LastUpdated, updatedAt)
+	}
+	for _, inst := range installations.installations {
+		want := inst.InstallationID == 10 || inst.InstallationID == 11 || inst.InstallationID == 12
+		if inst.UpdateAvailable != want {
+			t.Errorf("installation %d update_available = %v, want %v", inst.InstallationID, inst.UpdateAvailable, want)
+		}
+	}
+
+	// SGC 3's policy is off, so only SGCs 1 and 2 hear about it
+	if len(events.published) != 2 {
+		t.Fatalf("published %d events, want 2: %+v", len(events.published), events.published)
+	}
+	for i, wantSGC := range []int64{1, 2} {
+		if events.published[i].routingKey != "manman.sgc.addon_update_available" {
+			t.Errorf("event %d routing key = %q", i, events.published[i].routingKey)
+		}
+		event := events.published[i].message.(hostrmq.AddonUpdateEvent)
+		if event.SGCID != wantSGC || event.AddonID != 1 || !event.TimeUpdated.Equal(updatedAt) {
+			t.Errorf("event %d = %+v, want SGC %d addon 1", i, event, wantSGC)
+		}
+	}
+
+	// The new collection item got an addon and joined the collection's library
+	members := libraries.member[50]
+	if len(members) != 3 {
+		t.Fatalf("library 50 has addons %v, want the new collection item added", members)
+	}
+	added, err := addons.GetByWorkshopID(context.Background(), 7, "444", manman.PlatformTypeSteamWorkshop)
+	if err != nil || members[2] != added.AddonID {
+		t.Errorf("library 50 addons = %v, want the addon for 444 last (err %v)", members, err)
+	}
+	items, _ := addons.addons[2].Metadata["collection_items"].([]map[string]interface{})
+	if len(items) != 2 {
+		t.Errorf("collection metadata items = %v, want 2", addons.addons[2].Metadata["collection_items"])
+	}
+}
+
+func TestAddonReinstall(t *testing.T) {
+	installations := &fakeInstallationRepo{installations: []*manman.WorkshopInstallation{
+		{InstallationID: 10, SGCID: 1, AddonID: 1, Status: manman.InstallationStatusInstalled, UpdateAvailable: true, InstallationPath: "/data/addons/1"},
+		{InstallationID: 11, SGCID: 2, AddonID: 1, Status: manman.InstallationStatusInstalled, UpdateAvailable: true, InstallationPath: "/data/addons/1"},
+		{InstallationID: 12, SGCID: 3, AddonID: 1, Status: manman.InstallationStatusInstalled, UpdateAvailable: true, InstallationPath: "/data/addons/1"},
+		{InstallationID: 13, SGCID: 2, AddonID: 2, Status: manman.InstallationStatusPending, UpdateAvailable: true},
+	}}
+	installer := &fakeInstaller{install: map[[2]int64]int64{{1, 1}: 10, {2, 1}: 11, {3, 1}: 12, {2, 2}: 13}}
+	repo := &repository.Repository{
+		WorkshopInstallations: installations,
+		ServerGameConfigs: &fakeSGCRepo{policies: map[int64]string{
+			1: manman.AddonUpdatePolicyReinstall,
+			2: manman.AddonUpdatePolicyReinstall,
+			3: manman.AddonUpdatePolicyNotify,
+		}},
+		Sessions: &fakeSessionRepo{sessions: map[int64][]*manman.Session{
+			1: {{SessionID: 5, Status: manman.SessionStatusRunning}},
+			2: {{SessionID: 6, Status: manman.SessionStatusStopped}},
+		}},
+	}
+	worker := &addonReinstallWorker{repo: repo, installer: installer, logger: discardLogger()}
+
+	if err := worker.Work(context.Background(), nil); err != nil {
+		t.Fatalf("Work: %v", err)
+	}
+
+	// SGC 1 is still running and SGC 3 only notifies; the pending installation is already underway
+	if len(installer.installed) != 1 || installer.installed[0] != 11 {
+		t.Fatalf("reinstalled %v, want [11]", installer.installed)
+	}
+	if installer.paths[0] != "/data/addons/1" {
+		t.Errorf("reinstalled into %q, want the existing installation path", installer.paths[0])
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// backupPrune is a backup selected for removal by the retention job, with the reason recorded on it.
+type backupPrune struct {
+	Backup *manman.Backup
+	Reason string
+}
+
+// planBackupPrune applies a config's retention rules to one SGC's completed backups.
+// backups must be ordered newest first. A backup is kept if any of keep_last, daily,
+// weekly or monthly selects it; the size cap is then applied to what is left, dropping
+// the oldest first. The newest backup is never removed by the size cap.
+func planBackupPrune(cfg *manman.BackupConfig, backups []*manman.Backup, now time.Time) []backupPrune {
+	if !cfg.HasRetention() || len(backups) == 0 {
+		return nil
+	}
+
+	keep := make(map[int64]bool, len(backups))
+	hasSelectionRules := cfg.RetentionKeepLast > 0 || cfg.RetentionDailyDays > 0 ||
+		cfg.RetentionWeeklyWeeks > 0 || cfg.RetentionMonthlyMonths > 0
+
+	if !hasSelectionRules {
+		// Only a size cap is set: everything is a candidate for the cap.
+		for _, b := range backups {
+			keep[b.BackupID] = true
+		}
+	} else {
+		for i, b := range backups {
+			if i < cfg.RetentionKeepLast {
+				keep[b.BackupID] = true
+			}
+		}
+		if cfg.RetentionDailyDays > 0 {
+			keepNewestPerPeriod(backups, keep, now.AddDate(0, 0, -cfg.RetentionDailyDays), func(t time.Time) string {
+				return t.Format("2006-01-02")
+			})
+		}
+		if cfg.RetentionWeeklyWeeks > 0 {
+			keepNewestPerPeriod(backups, keep, now.AddDate(0, 0, -7*cfg.RetentionWeeklyWeeks), func(t time.Time) string {
+				year, week := t.ISOWeek()
+				return fmt.Sprintf("%d-W%02d", year, week)
+			})
+		}
+		if cfg.RetentionMonthlyMonths > 0 {
+			keepNewestPerPeriod(backups, keep, now.AddDate(0, -cfg.RetentionMonthlyMonths, 0), func(t time.Time) string {
+				return t.Format("2006-01")
+			})
+		}
+	}
+
+	var prunes []backupPrune
+	ruleReason := "not retained by " + describeRetentionRules(cfg)
+	for _, b := range backups {
+		if !keep[b.BackupID] {
+			prunes = append(prunes, backupPrune{Backup: b, Reason: ruleReason})
+		}
+	}
+
+	if cfg.RetentionMaxTotalBytes > 0 {
+		sizeReason := fmt.Sprintf("retained backups exceed max_total_bytes=%d", cfg.RetentionMaxTotalBytes)
+		var total int64
+		kept, exceeded := 0, false
+		for _, b := range backups {
+			if !keep[b.BackupID] {
+				continue
+			}
+			var size int64
+			if b.SizeBytes != nil {
+				size = *b.SizeBytes
+			}
+			if !exceeded && (kept == 0 || total+size <= cfg.RetentionMaxTotalBytes) {
+				total += size
+				kept++
+				continue
+			}
+			exceeded = true
+			prunes = append(prunes, backupPrune{Backup: b, Reason: sizeReason})
+		}
+	}
+
+	return prunes
+}
+
+// keepNewestPerPeriod marks the newest backup in each period created after cutoff.
+func keepNewestPerPeriod(backups []*manman.Backup, keep map[int64]bool, cutoff time.Time, period func(time.Time) string) {
+	seen := make(map[string]bool)
+	for _, b := range backups {
+		if b.CreatedAt.Before(cutoff) {
+			continue
+		}
+		key := period(b.CreatedAt.UTC())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		keep[b.BackupID] = true
+	}
+}
+
+// describeRetentionRules renders the enabled selection rules, e.g. "keep_last=5, daily=7d".
+func describeRetentionRules(cfg *manman.BackupConfig) string {
+	var rules []string
+	if cfg.RetentionKeepLast > 0 {
+		rules = append(rules, fmt.Sprintf("keep_last=%d", cfg.RetentionKeepLast))
+	}
+	if cfg.RetentionDailyDays > 0 {
+		rules = append(rules, fmt.Sprintf("daily=%dd", cfg.RetentionDailyDays))
+	}
+	if cfg.RetentionWeeklyWeeks > 0 {
+		rules = append(rules, fmt.Sprintf("weekly=%dw", cfg.RetentionWeeklyWeeks))
+	}
+	if cfg.RetentionMonthlyMonths > 0 {
+		rules = append(rules, fmt.Sprintf("monthly=%dm", cfg.RetentionMonthlyMonths))
+	}
+	return strings.Join(rules, ", ")
+}
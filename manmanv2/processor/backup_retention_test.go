@@ -0,0 +1,123 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// backupsAt builds completed backups at the given ages, newest first, with IDs 1..n.
+func backupsAt(now time.Time, ages []time.Duration, size int64) []*manman.Backup {
+	backups := make([]*manman.Backup, len(ages))
+	for i, age := range ages {
+		s := size
+		backups[i] = &manman.Backup{
+			BackupID:  int64(i + 1),
+			SizeBytes: &s,
+			Status:    manman.BackupStatusCompleted,
+			CreatedAt: now.Add(-age),
+		}
+	}
+	return backups
+}
+
+func prunedIDs(prunes []backupPrune) []int64 {
+	ids := make([]int64, len(prunes))
+	for i, p := range prunes {
+		ids[i] = p.Backup.BackupID
+	}
+	return ids
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPlanBackupPrune_NoRules(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsAt(now, []time.Duration{time.Hour, 2 * time.Hour}, 10)
+
+	if prunes := planBackupPrune(&manman.BackupConfig{}, backups, now); len(prunes) != 0 {
+		t.Errorf("Expected nothing pruned without retention rules, got %v", prunedIDs(prunes))
+	}
+}
+
+func TestPlanBackupPrune_KeepLast(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsAt(now, []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour}, 10)
+	cfg := &manman.BackupConfig{RetentionKeepLast: 2}
+
+	prunes := planBackupPrune(cfg, backups, now)
+	if got, want := prunedIDs(prunes), []int64{3, 4}; !equalIDs(got, want) {
+		t.Fatalf("Expected pruned %v, got %v", want, got)
+	}
+	if !strings.Contains(prunes[0].Reason, "keep_last=2") {
+		t.Errorf("Expected reason to mention keep_last, got %q", prunes[0].Reason)
+	}
+}
+
+func TestPlanBackupPrune_DailyKeepsNewestPerDay(t *testing.T) {
+	now := time.Date(2025, 6, 15, 23, 0, 0, 0, time.UTC)
+	// Two backups on each of the last three days, plus one outside the window.
+	backups := backupsAt(now, []time.Duration{
+		1 * time.Hour, 2 * time.Hour, // Jun 15
+		25 * time.Hour, 26 * time.Hour, // Jun 14
+		49 * time.Hour, 50 * time.Hour, // Jun 13
+		10 * 24 * time.Hour, // Jun 5
+	}, 10)
+	cfg := &manman.BackupConfig{RetentionDailyDays: 3}
+
+	if got, want := prunedIDs(planBackupPrune(cfg, backups, now)), []int64{2, 4, 6, 7}; !equalIDs(got, want) {
+		t.Errorf("Expected pruned %v, got %v", want, got)
+	}
+}
+
+func TestPlanBackupPrune_RulesAreUnioned(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsAt(now, []time.Duration{
+		time.Hour,
+		2 * time.Hour,
+		40 * 24 * time.Hour, // previous month
+		70 * 24 * time.Hour, // early April, outside the two month window
+	}, 10)
+	cfg := &manman.BackupConfig{RetentionKeepLast: 1, RetentionMonthlyMonths: 2}
+
+	// keep_last keeps #1; monthly keeps the newest of June (#1) and May (#3)
+	if got, want := prunedIDs(planBackupPrune(cfg, backups, now)), []int64{2, 4}; !equalIDs(got, want) {
+		t.Errorf("Expected pruned %v, got %v", want, got)
+	}
+}
+
+func TestPlanBackupPrune_SizeCap(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsAt(now, []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour}, 100)
+	cfg := &manman.BackupConfig{RetentionMaxTotalBytes: 250}
+
+	prunes := planBackupPrune(cfg, backups, now)
+	if got, want := prunedIDs(prunes), []int64{3, 4}; !equalIDs(got, want) {
+		t.Fatalf("Expected pruned %v, got %v", want, got)
+	}
+	if !strings.Contains(prunes[0].Reason, "max_total_bytes=250") {
+		t.Errorf("Expected reason to mention size cap, got %q", prunes[0].Reason)
+	}
+}
+
+func TestPlanBackupPrune_SizeCapKeepsNewest(t *testing.T) {
+	now := time.Date(2025, 6, 15, 12, 0, 0, 0, time.UTC)
+	backups := backupsAt(now, []time.Duration{time.Hour, 2 * time.Hour}, 500)
+	cfg := &manman.BackupConfig{RetentionMaxTotalBytes: 100}
+
+	if got, want := prunedIDs(planBackupPrune(cfg, backups, now)), []int64{2}; !equalIDs(got, want) {
+		t.Errorf("Expected pruned %v, got %v", want, got)
+	}
+}
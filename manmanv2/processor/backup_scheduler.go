@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"strings"
 	"text/template"
 	"time"
 
@@ -18,7 +19,10 @@ import (
 	"github.com/whale-net/everything/manmanv2/models"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/api/workshop"
 	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/processor/handlers"
+	pb "github.com/whale-net/everything/manmanv2/protos"
 )
 
 // ============================================================================
@@ -173,11 +177,71 @@ func (w *scheduledBackupWorker) Work(ctx context.Context, job *river.Job[schedul
 	return nil
 }
 
+// ============================================================================
+// Prune job: runs hourly, applies each backup config's retention rules per SGC
+// ============================================================================
+
+type backupPruneArgs struct{}
+
+func (backupPruneArgs) Kind() string { return "backup_prune" }
+
+type backupPruneWorker struct {
+	river.WorkerDefaults[backupPruneArgs]
+	repo     *repository.Repository
+	s3Client *s3lib.Client
+	logger   *slog.Logger
+}
+
+func (w *backupPruneWorker) Work(ctx context.Context, _ *river.Job[backupPruneArgs]) error {
+	cfgs, err := w.repo.BackupConfigs.ListWithRetention(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list backup configs with retention: %w", err)
+	}
+
+	now := time.Now()
+	for _, cfg := range cfgs {
+		backups, err := w.repo.Backups.ListCompletedByConfig(ctx, cfg.BackupConfigID)
+		if err != nil {
+			w.logger.Error("failed to list backups for retention", "backup_config_id", cfg.BackupConfigID, "error", err)
+			continue
+		}
+
+		// Retention is per SGC: one config backs up every SGC of its game config.
+		bySGC := make(map[int64][]*manman.Backup)
+		for _, b := range backups {
+			bySGC[b.ServerGameConfigID] = append(bySGC[b.ServerGameConfigID], b)
+		}
+
+		for sgcID, sgcBackups := range bySGC {
+			for _, p := range planBackupPrune(cfg, sgcBackups, now) {
+				// Delete the object first; if that fails the row stays and the next run retries.
+				if p.Backup.S3URL != nil {
+					s3Key := strings.TrimPrefix(*p.Backup.S3URL, "s3://")
+					if err := w.s3Client.Delete(ctx, s3Key); err != nil {
+						w.logger.Warn("failed to delete pruned backup from S3", "backup_id", p.Backup.BackupID, "s3_key", s3Key, "error", err)
+						continue
+					}
+				}
+				if err := w.repo.Backups.Prune(ctx, p.Backup.BackupID, p.Reason); err != nil {
+					w.logger.Error("failed to mark backup pruned", "backup_id", p.Backup.BackupID, "error", err)
+					continue
+				}
+				w.logger.Info("pruned backup", "backup_id", p.Backup.BackupID, "backup_config_id", cfg.BackupConfigID, "sgc_id", sgcID, "reason", p.Reason)
+			}
+		}
+	}
+	return nil
+}
+
 // ============================================================================
 // Startup
 // ============================================================================
 
-func startBackupScheduler(ctx context.Context, dbPool *pgxpool.Pool, repo *repository.Repository, rmqConn *rmq.Connection, s3Client *s3lib.Client, logger *slog.Logger) (*river.Client[pgx.Tx], error) {
+// startBackupScheduler starts River with the backup, metrics rollup and addon update jobs and,
+// when apiClient is set, the session schedule, idle shutdown and SGC migration jobs (which
+// start and stop sessions through the API). Migrations and addon updates are announced through
+// events. A zero addonUpdateInterval turns the addon update scan off.
+func startBackupScheduler(ctx context.Context, dbPool *pgxpool.Pool, repo *repository.Repository, rmqConn *rmq.Connection, s3Client *s3lib.Client, apiClient pb.ManManAPIClient, events handlers.Publisher, steamClient workshop.SteamClient, addonUpdateInterval time.Duration, logger *slog.Logger) (*river.Client[pgx.Tx], error) {
 	// Run River schema migrations
 	migrator, err := rivermigrate.New(riverpgxv5.New(dbPool), nil)
 	if err != nil {
@@ -211,28 +275,162 @@ func startBackupScheduler(ctx context.Context, dbPool *pgxpool.Pool, repo *repos
 		s3Client:   s3Client,
 		logger:     logger,
 	})
+	river.AddWorker(workers, &backupPruneWorker{
+		repo:     repo,
+		s3Client: s3Client,
+		logger:   logger,
+	})
+	river.AddWorker(workers, &metricsRollupWorker{
+		repo:   repo,
+		logger: logger,
+	})
 
-	riverClient, err = river.NewClient(riverpgxv5.New(dbPool), &river.Config{
-		Queues: map[string]river.QueueConfig{
-			river.QueueDefault: {MaxWorkers: 5},
-		},
-		Workers: workers,
-		PeriodicJobs: []*river.PeriodicJob{
+	workshopManager := workshop.NewWorkshopManager(
+		repo.WorkshopAddons,
+		repo.WorkshopInstallations,
+		repo.WorkshopLibraries,
+		repo.ServerGameConfigs,
+		repo.Games,
+		repo.GameConfigs,
+		repo.GameConfigVolumes,
+		repo.AddonPathPresets,
+		repo.Sessions,
+		steamClient,
+		publisher,
+	)
+	river.AddWorker(workers, &addonUpdateScanWorker{
+		repo:      repo,
+		steam:     steamClient,
+		installer: workshopManager,
+		events:    events,
+		logger:    logger,
+	})
+	river.AddWorker(workers, &addonReinstallWorker{
+		repo:      repo,
+		installer: workshopManager,
+		logger:    logger,
+	})
+
+	periodicJobs := []*river.PeriodicJob{
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Minute),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return backupScanArgs{}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return backupPruneArgs{}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Hour),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return metricsRollupArgs{}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+		river.NewPeriodicJob(
+			river.PeriodicInterval(1*time.Minute),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return addonReinstallArgs{}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		),
+	}
+	if addonUpdateInterval > 0 {
+		periodicJobs = append(periodicJobs, river.NewPeriodicJob(
+			river.PeriodicInterval(addonUpdateInterval),
+			func() (river.JobArgs, *river.InsertOpts) {
+				return addonUpdateScanArgs{}, nil
+			},
+			&river.PeriodicJobOpts{RunOnStart: true},
+		))
+	}
+
+	var scheduleScanWorker *sessionScheduleScanWorker
+	var stopWorker *scheduledStopWorker
+	var migrationScanWorker *sgcMigrationScanWorker
+	if apiClient != nil {
+		scheduleScanWorker = &sessionScheduleScanWorker{
+			repo:   repo,
+			logger: logger,
+		}
+		stopWorker = &scheduledStopWorker{
+			repo:      repo,
+			apiClient: apiClient,
+			logger:    logger,
+		}
+		river.AddWorker(workers, scheduleScanWorker)
+		river.AddWorker(workers, stopWorker)
+		river.AddWorker(workers, &scheduledStartWorker{
+			apiClient: apiClient,
+			logger:    logger,
+		})
+		river.AddWorker(workers, &idleShutdownScanWorker{
+			repo:      repo,
+			apiClient: apiClient,
+			logger:    logger,
+		})
+		migrationScanWorker = &sgcMigrationScanWorker{
+			repo:   repo,
+			logger: logger,
+		}
+		river.AddWorker(workers, migrationScanWorker)
+		river.AddWorker(workers, &sgcMigrationWorker{
+			repo:      repo,
+			apiClient: apiClient,
+			publisher: publisher,
+			events:    events,
+			s3Client:  s3Client,
+			logger:    logger,
+		})
+		periodicJobs = append(periodicJobs,
 			river.NewPeriodicJob(
 				river.PeriodicInterval(1*time.Minute),
 				func() (river.JobArgs, *river.InsertOpts) {
-					return backupScanArgs{}, nil
+					return sessionScheduleScanArgs{}, nil
 				},
 				&river.PeriodicJobOpts{RunOnStart: true},
 			),
+			river.NewPeriodicJob(
+				river.PeriodicInterval(1*time.Minute),
+				func() (river.JobArgs, *river.InsertOpts) {
+					return idleShutdownScanArgs{}, nil
+				},
+				&river.PeriodicJobOpts{RunOnStart: true},
+			),
+			river.NewPeriodicJob(
+				river.PeriodicInterval(15*time.Second),
+				func() (river.JobArgs, *river.InsertOpts) {
+					return sgcMigrationScanArgs{}, nil
+				},
+				&river.PeriodicJobOpts{RunOnStart: true},
+			),
+		)
+	}
+
+	riverClient, err = river.NewClient(riverpgxv5.New(dbPool), &river.Config{
+		Queues: map[string]river.QueueConfig{
+			river.QueueDefault: {MaxWorkers: 5},
 		},
+		Workers:      workers,
+		PeriodicJobs: periodicJobs,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create river client: %w", err)
 	}
 
-	// Wire the client reference into the scan worker
+	// Wire the client reference into the workers that enqueue jobs
 	scanWorker.riverClient = riverClient
+	if apiClient != nil {
+		scheduleScanWorker.riverClient = riverClient
+		stopWorker.riverClient = riverClient
+		migrationScanWorker.riverClient = riverClient
+	}
 
 	if err := riverClient.Start(ctx); err != nil {
 		return nil, fmt.Errorf("failed to start river client: %w", err)
@@ -21,6 +21,13 @@ type Config struct {
 	StaleHostThreshold    int
 	StaleSessionThreshold int
 	ExternalExchange      string
+	APIAddress            string // control API for scheduled and idle starts/stops; empty disables them
+	GRPCAuthMode          string
+	GRPCAuthTokenURL      string
+	GRPCAuthClientID      string
+	GRPCAuthClientSecret  string
+	SteamAPIKey           string
+	AddonUpdateMinutes    int // how often addons in attached libraries are checked for updates; 0 disables
 }
 
 // LoadConfig loads configuration from environment variables
@@ -39,6 +46,13 @@ func LoadConfig() (*Config, error) {
 		StaleHostThreshold:    getEnvInt("STALE_HOST_THRESHOLD_SECONDS", 90),
 		StaleSessionThreshold: getEnvInt("STALE_SESSION_THRESHOLD_SECONDS", 30), // Default 30 seconds
 		ExternalExchange:      getEnv("EXTERNAL_EXCHANGE", "external"),
+		APIAddress:            getEnv("API_ADDRESS", ""),
+		GRPCAuthMode:          getEnv("GRPC_AUTH_MODE", "none"),
+		GRPCAuthTokenURL:      getEnv("GRPC_AUTH_TOKEN_URL", ""),
+		GRPCAuthClientID:      getEnv("GRPC_AUTH_CLIENT_ID", ""),
+		GRPCAuthClientSecret:  getEnv("GRPC_AUTH_CLIENT_SECRET", ""),
+		SteamAPIKey:           getEnv("STEAM_API_KEY", ""),
+		AddonUpdateMinutes:    getEnvInt("ADDON_UPDATE_CHECK_MINUTES", 360),
 	}
 
 	// Validate required fields
@@ -37,6 +37,11 @@ func NewProcessorConsumer(
 		"status.host.#",
 		"status.session.#",
 		"status.backup.#",
+		"status.restart.#",
+		"status.restore.#",
+		"status.players.#",
+		"status.image.#",
+		"status.alert.#",
 		"health.#",
 	}
 
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+// AlertFired is the status.alert.<session_id> message the log-processor publishes when a
+// rule reaches its threshold
+type AlertFired struct {
+	RuleID       int64     `json:"rule_id"`
+	SessionID    int64     `json:"session_id"`
+	SGCID        int64     `json:"sgc_id"`
+	MatchCount   int       `json:"match_count"`
+	FirstMatchAt time.Time `json:"first_match_at"`
+	Line         string    `json:"line"`
+	FiredAt      time.Time `json:"fired_at"`
+}
+
+// AlertNotification is published to the external exchange as manman.session.alert
+type AlertNotification struct {
+	AlertID      int64     `json:"alert_id"`
+	RuleID       int64     `json:"rule_id"`
+	RuleName     string    `json:"rule_name"`
+	SessionID    int64     `json:"session_id"`
+	SGCID        int64     `json:"sgc_id"`
+	MatchCount   int       `json:"match_count"`
+	Line         string    `json:"line"`
+	FirstMatchAt time.Time `json:"first_match_at"`
+	FiredAt      time.Time `json:"fired_at"`
+}
+
+// AlertHandler handles status.alert.* messages. It records each firing, publishes it
+// externally and starts the rule's action and backup through the API.
+type AlertHandler struct {
+	repo      *repository.Repository
+	publisher Publisher
+	apiClient pb.ManManAPIClient // nil when API_ADDRESS isn't set
+	logger    *slog.Logger
+}
+
+// NewAlertHandler creates a new alert handler. apiClient may be nil, in which case
+// alerts are recorded but their actions and backups don't run.
+func NewAlertHandler(repo *repository.Repository, publisher Publisher, apiClient pb.ManManAPIClient, logger *slog.Logger) *AlertHandler {
+	return &AlertHandler{
+		repo:      repo,
+		publisher: publisher,
+		apiClient: apiClient,
+		logger:    logger,
+	}
+}
+
+func (h *AlertHandler) Handle(ctx context.Context, routingKey string, body []byte) error {
+	var msg AlertFired
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to unmarshal alert: %w", err)}
+	}
+
+	rule, err := h.repo.AlertRules.Get(ctx, msg.RuleID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			h.logger.Info("ignoring alert for deleted rule", "rule_id", msg.RuleID, "session_id", msg.SessionID)
+			return nil
+		}
+		return fmt.Errorf("failed to get alert rule: %w", err)
+	}
+	if !rule.Enabled {
+		h.logger.Info("ignoring alert for disabled rule", "rule_id", rule.RuleID, "session_id", msg.SessionID)
+		return nil
+	}
+
+	// The log-processor applies the cooldown per session; this catches a new session, or
+	// a restarted log-processor, firing again inside it
+	lastFired, err := h.repo.AlertEvents.LastFired(ctx, rule.RuleID, msg.SGCID)
+	if err != nil {
+		return fmt.Errorf("failed to get last alert: %w", err)
+	}
+	if lastFired != nil && msg.FiredAt.Sub(*lastFired) < time.Duration(rule.CooldownSeconds)*time.Second {
+		h.logger.Debug("alert within cooldown", "rule_id", rule.RuleID, "sgc_id", msg.SGCID, "last_fired", *lastFired)
+		return nil
+	}
+
+	event, err := h.repo.AlertEvents.Create(ctx, &manman.AlertEvent{
+		RuleID:       rule.RuleID,
+		SessionID:    msg.SessionID,
+		SGCID:        msg.SGCID,
+		MatchCount:   int32(msg.MatchCount),
+		Line:         msg.Line,
+		FirstMatchAt: msg.FirstMatchAt,
+		FiredAt:      msg.FiredAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record alert: %w", err)
+	}
+
+	h.logger.Warn("alert fired",
+		"alert_id", event.AlertID,
+		"rule_id", rule.RuleID,
+		"rule_name", rule.Name,
+		"session_id", msg.SessionID,
+		"sgc_id", msg.SGCID,
+		"match_count", msg.MatchCount,
+	)
+
+	notification := AlertNotification{
+		AlertID:      event.AlertID,
+		RuleID:       rule.RuleID,
+		RuleName:     rule.Name,
+		SessionID:    msg.SessionID,
+		SGCID:        msg.SGCID,
+		MatchCount:   msg.MatchCount,
+		Line:         msg.Line,
+		FirstMatchAt: msg.FirstMatchAt,
+		FiredAt:      msg.FiredAt,
+	}
+	if err := h.publisher.PublishExternal(ctx, "manman.session.alert", notification); err != nil {
+		h.logger.Error("failed to publish alert to external exchange",
+			"error", err,
+			"alert_id", event.AlertID,
+		)
+		// Don't fail the message processing if external publish fails
+	}
+
+	// The alert is recorded, so a failed response is recorded on it rather than retried
+	h.respond(ctx, rule, event)
+	return nil
+}
+
+// respond runs the rule's action on the session and takes its backup, then records
+// what was started or why it wasn't
+func (h *AlertHandler) respond(ctx context.Context, rule *manman.AlertRule, event *manman.AlertEvent) {
+	if rule.ActionID == nil && rule.BackupConfigID == nil {
+		return
+	}
+
+	var executionID, backupID *int64
+	var problems []string
+	if h.apiClient == nil {
+		problems = append(problems, "API not configured")
+	} else {
+		if rule.ActionID != nil {
+			resp, err := h.apiClient.ExecuteAction(ctx, &pb.ExecuteActionRequest{
+				SessionId: event.SessionID,
+				ActionId:  *rule.ActionID,
+			})
+			switch {
+			case err != nil:
+				problems = append(problems, fmt.Sprintf("action %d: %v", *rule.ActionID, err))
+			case !resp.Success:
+				problems = append(problems, fmt.Sprintf("action %d: %s", *rule.ActionID, resp.ErrorMessage))
+			default:
+				executionID = &resp.ExecutionId
+			}
+		}
+		if rule.BackupConfigID != nil {
+			resp, err := h.apiClient.TriggerBackup(ctx, &pb.TriggerBackupRequest{
+				ServerGameConfigId: event.SGCID,
+				BackupConfigId:     *rule.BackupConfigID,
+			})
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("backup config %d: %v", *rule.BackupConfigID, err))
+			} else {
+				backupID = &resp.BackupId
+			}
+		}
+	}
+
+	var responseError *string
+	if len(problems) > 0 {
+		msg := strings.Join(problems, "; ")
+		responseError = &msg
+		h.logger.Warn("alert response failed", "alert_id", event.AlertID, "error", msg)
+	}
+	if err := h.repo.AlertEvents.UpdateResponse(ctx, event.AlertID, executionID, backupID, responseError); err != nil {
+		h.logger.Error("failed to record alert response", "error", err, "alert_id", event.AlertID)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc"
+)
+
+type fakeAlertRules struct {
+	repository.AlertRuleRepository
+	rules map[int64]*manman.AlertRule
+}
+
+func (f *fakeAlertRules) Get(ctx context.Context, ruleID int64) (*manman.AlertRule, error) {
+	rule, ok := f.rules[ruleID]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	return rule, nil
+}
+
+type fakeAlertEvents struct {
+	repository.AlertEventRepository
+	events []*manman.AlertEvent
+}
+
+func (f *fakeAlertEvents) Create(ctx context.Context, e *manman.AlertEvent) (*manman.AlertEvent, error) {
+	e.AlertID = int64(len(f.events) + 1)
+	f.events = append(f.events, e)
+	return e, nil
+}
+
+func (f *fakeAlertEvents) UpdateResponse(ctx context.Context, alertID int64, actionExecutionID, backupID *int64, responseError *string) error {
+	e := f.events[alertID-1]
+	e.ActionExecutionID, e.BackupID, e.ResponseError = actionExecutionID, backupID, responseError
+	return nil
+}
+
+func (f *fakeAlertEvents) LastFired(ctx context.Context, ruleID, sgcID int64) (*time.Time, error) {
+	var last *time.Time
+	for _, e := range f.events {
+		if e.RuleID == ruleID && e.SGCID == sgcID && (last == nil || e.FiredAt.After(*last)) {
+			last = &e.FiredAt
+		}
+	}
+	return last, nil
+}
+
+type fakePublisher struct {
+	keys []string
+}
+
+func (f *fakePublisher) PublishExternal(ctx context.Context, routingKey string, message interface{}) error {
+	f.keys = append(f.keys, routingKey)
+	return nil
+}
+
+type fakeAlertAPI struct {
+	pb.ManManAPIClient
+	actions []*pb.ExecuteActionRequest
+	backups []*pb.TriggerBackupRequest
+}
+
+func (f *fakeAlertAPI) ExecuteAction(ctx context.Context, req *pb.ExecuteActionRequest, opts ...grpc.CallOption) (*pb.ExecuteActionResponse, error) {
+	f.actions = append(f.actions, req)
+	return &pb.ExecuteActionResponse{Success: true, ExecutionId: 40}, nil
+}
+
+func (f *fakeAlertAPI) TriggerBackup(ctx context.Context, req *pb.TriggerBackupRequest, opts ...grpc.CallOption) (*pb.TriggerBackupResponse, error) {
+	f.backups = append(f.backups, req)
+	return &pb.TriggerBackupResponse{BackupId: 50}, nil
+}
+
+func TestAlertHandler(t *testing.T) {
+	actionID, backupConfigID := int64(4), int64(5)
+	rules := &fakeAlertRules{rules: map[int64]*manman.AlertRule{
+		1: {RuleID: 1, Name: "oom", CooldownSeconds: 600, ActionID: &actionID, BackupConfigID: &backupConfigID, Enabled: true},
+		2: {RuleID: 2, Name: "off", Enabled: false},
+	}}
+	events := &fakeAlertEvents{}
+	publisher := &fakePublisher{}
+	api := &fakeAlertAPI{}
+	h := NewAlertHandler(&repository.Repository{AlertRules: rules, AlertEvents: events}, publisher, api, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	firedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	handle := func(ruleID int64, at time.Time) {
+		t.Helper()
+		body, _ := json.Marshal(AlertFired{RuleID: ruleID, SessionID: 7, SGCID: 3, MatchCount: 2, Line: "Out of memory", FiredAt: at})
+		if err := h.Handle(context.Background(), "status.alert.7", body); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+	}
+
+	handle(1, firedAt)
+	if len(events.events) != 1 || len(publisher.keys) != 1 || publisher.keys[0] != "manman.session.alert" {
+		t.Fatalf("got %d events and publishes %v, want one of each", len(events.events), publisher.keys)
+	}
+	e := events.events[0]
+	if e.ActionExecutionID == nil || *e.ActionExecutionID != 40 || e.BackupID == nil || *e.BackupID != 50 || e.ResponseError != nil {
+		t.Errorf("response = %v/%v/%v, want execution 40 and backup 50", e.ActionExecutionID, e.BackupID, e.ResponseError)
+	}
+	if len(api.actions) != 1 || api.actions[0].SessionId != 7 || len(api.backups) != 1 || api.backups[0].ServerGameConfigId != 3 {
+		t.Errorf("API calls = %v / %v, want one action on session 7 and one backup of SGC 3", api.actions, api.backups)
+	}
+
+	// Inside the cooldown, for a disabled rule and for a deleted one, nothing happens
+	handle(1, firedAt.Add(5*time.Minute))
+	handle(2, firedAt)
+	handle(9, firedAt)
+	if len(events.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events.events))
+	}
+
+	// Without an API the alert is still recorded, with why nothing ran
+	h.apiClient = nil
+	handle(1, firedAt.Add(time.Hour))
+	if len(events.events) != 2 || events.events[1].ResponseError == nil {
+		t.Fatalf("events = %v, want a second with a response error", events.events)
+	}
+}
+
+func TestAlertHandlerBadMessage(t *testing.T) {
+	h := NewAlertHandler(&repository.Repository{}, &fakePublisher{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	err := h.Handle(context.Background(), "status.alert.7", []byte("{"))
+	if _, ok := err.(*PermanentError); !ok {
+		t.Errorf("err = %v, want a PermanentError", err)
+	}
+}
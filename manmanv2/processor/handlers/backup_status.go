@@ -13,12 +13,13 @@ import (
 
 // BackupStatusHandler handles status.backup.* messages from the host-manager
 type BackupStatusHandler struct {
-	repo   *repository.Repository
-	logger *slog.Logger
+	repo      *repository.Repository
+	publisher Publisher
+	logger    *slog.Logger
 }
 
-func NewBackupStatusHandler(repo *repository.Repository, logger *slog.Logger) *BackupStatusHandler {
-	return &BackupStatusHandler{repo: repo, logger: logger}
+func NewBackupStatusHandler(repo *repository.Repository, publisher Publisher, logger *slog.Logger) *BackupStatusHandler {
+	return &BackupStatusHandler{repo: repo, publisher: publisher, logger: logger}
 }
 
 func (h *BackupStatusHandler) Handle(ctx context.Context, routingKey string, body []byte) error {
@@ -32,20 +33,37 @@ func (h *BackupStatusHandler) Handle(ctx context.Context, routingKey string, bod
 	if err := h.repo.Backups.UpdateStatus(ctx, msg.BackupID, msg.Status, msg.S3URL, msg.SizeBytes, msg.ErrorMessage); err != nil {
 		return fmt.Errorf("failed to update backup status: %w", err)
 	}
+	if msg.Checksum != nil {
+		if err := h.repo.Backups.SetChecksum(ctx, msg.BackupID, *msg.Checksum); err != nil {
+			return fmt.Errorf("failed to record backup checksum: %w", err)
+		}
+	}
+
+	backup, err := h.repo.Backups.Get(ctx, msg.BackupID)
+	if err != nil {
+		h.logger.Warn("failed to fetch backup after status update", "backup_id", msg.BackupID, "error", err)
+		return nil
+	}
 
 	// On completion, update last_backup_at on the BackupConfig
-	if msg.Status == "completed" {
-		backup, err := h.repo.Backups.Get(ctx, msg.BackupID)
-		if err != nil {
-			h.logger.Warn("failed to fetch backup for last_backup_at update", "backup_id", msg.BackupID, "error", err)
-			return nil
-		}
-		if backup.BackupConfigID != nil {
-			if err := h.repo.BackupConfigs.UpdateLastBackupAt(ctx, *backup.BackupConfigID, time.Now()); err != nil {
-				h.logger.Warn("failed to update last_backup_at", "backup_config_id", *backup.BackupConfigID, "error", err)
-			}
+	if msg.Status == "completed" && backup.BackupConfigID != nil {
+		if err := h.repo.BackupConfigs.UpdateLastBackupAt(ctx, *backup.BackupConfigID, time.Now()); err != nil {
+			h.logger.Warn("failed to update last_backup_at", "backup_config_id", *backup.BackupConfigID, "error", err)
 		}
 	}
 
+	event := rmq.BackupEvent{
+		BackupID:     backup.BackupID,
+		SGCID:        backup.ServerGameConfigID,
+		SessionID:    backup.SessionID,
+		Status:       msg.Status,
+		SizeBytes:    msg.SizeBytes,
+		ErrorMessage: msg.ErrorMessage,
+	}
+	if err := h.publisher.PublishExternal(ctx, fmt.Sprintf("manman.backup.%s", msg.Status), event); err != nil {
+		h.logger.Error("failed to publish backup status to external exchange", "error", err, "backup_id", msg.BackupID)
+		// Don't fail the message processing if external publish fails
+	}
+
 	return nil
 }
@@ -135,6 +135,7 @@ func (h *HealthHandler) Handle(ctx context.Context, routingKey string, body []by
 			"server_id", msg.ServerID,
 			"total", msg.SessionStats.Total,
 			"running", msg.SessionStats.Running,
+			"ready", msg.SessionStats.Ready,
 			"pending", msg.SessionStats.Pending,
 			"stopped", msg.SessionStats.Stopped,
 			"crashed", msg.SessionStats.Crashed,
@@ -200,5 +201,42 @@ func (h *HealthHandler) Handle(ctx context.Context, routingKey string, body []by
 		}
 	}
 
+	if msg.Metrics != nil {
+		// Metrics are a best-effort record; a heartbeat isn't retried for them
+		if err := h.repo.Metrics.Record(ctx, metricsSample(msg.ServerID, msg.Metrics)); err != nil {
+			h.logger.Error("failed to record host metrics",
+				"error", err,
+				"server_id", msg.ServerID,
+			)
+		}
+	}
+
 	return nil
 }
+
+// metricsSample converts a host's metrics message into a sample to record
+func metricsSample(serverID int64, metrics *rmq.HostMetrics) *manman.MetricsSample {
+	sample := &manman.MetricsSample{
+		ServerID:       serverID,
+		SampledAt:      metrics.SampledAt,
+		DiskTotalBytes: int64(metrics.DiskTotalBytes),
+		DiskUsedBytes:  int64(metrics.DiskUsedBytes),
+	}
+	if sample.SampledAt.IsZero() {
+		sample.SampledAt = time.Now()
+	}
+	for _, s := range metrics.Sessions {
+		sample.Sessions = append(sample.Sessions, manman.SessionMetricsSample{
+			SessionID:             s.SessionID,
+			SGCID:                 s.SGCID,
+			CPUPercent:            s.CPUPercent,
+			MemoryBytes:           int64(s.MemoryBytes),
+			MemoryLimitBytes:      int64(s.MemoryLimitBytes),
+			NetworkRxBytesPerSec:  s.NetworkRxBytesPerSec,
+			NetworkTxBytesPerSec:  s.NetworkTxBytesPerSec,
+			BlockReadBytesPerSec:  s.BlockReadBytesPerSec,
+			BlockWriteBytesPerSec: s.BlockWriteBytesPerSec,
+		})
+	}
+	return sample
+}
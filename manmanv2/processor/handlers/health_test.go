@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+type fakeHealthServers struct {
+	repository.ServerRepository
+	lastSeen []int64
+}
+
+func (f *fakeHealthServers) Get(ctx context.Context, serverID int64) (*manman.Server, error) {
+	return &manman.Server{ServerID: serverID, Status: manman.ServerStatusOnline}, nil
+}
+
+func (f *fakeHealthServers) UpdateLastSeen(ctx context.Context, serverID int64, lastSeen time.Time) error {
+	f.lastSeen = append(f.lastSeen, serverID)
+	return nil
+}
+
+type fakeMetrics struct {
+	repository.MetricsRepository
+	samples []*manman.MetricsSample
+}
+
+func (f *fakeMetrics) Record(ctx context.Context, sample *manman.MetricsSample) error {
+	f.samples = append(f.samples, sample)
+	return nil
+}
+
+func TestHealthHandlerRecordsMetrics(t *testing.T) {
+	servers := &fakeHealthServers{}
+	metrics := &fakeMetrics{}
+	h := NewHealthHandler(&repository.Repository{Servers: servers, Metrics: metrics}, &fakePublisher{}, 30, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	handle := func(update rmq.HealthUpdate) {
+		t.Helper()
+		body, _ := json.Marshal(update)
+		if err := h.Handle(context.Background(), "health.host.2", body); err != nil {
+			t.Fatalf("Handle failed: %v", err)
+		}
+	}
+
+	// A plain heartbeat records nothing
+	handle(rmq.HealthUpdate{ServerID: 2, SessionStats: &rmq.SessionStats{Total: 1}})
+	if len(metrics.samples) != 0 {
+		t.Fatalf("got %d samples from a plain heartbeat, want 0", len(metrics.samples))
+	}
+
+	sampledAt := time.Date(2026, 3, 1, 12, 0, 30, 0, time.UTC)
+	handle(rmq.HealthUpdate{ServerID: 2, Metrics: &rmq.HostMetrics{
+		SampledAt:      sampledAt,
+		DiskTotalBytes: 100 << 30,
+		DiskUsedBytes:  40 << 30,
+		Sessions:       []rmq.SessionMetrics{{SessionID: 7, SGCID: 3, CPUPercent: 50, MemoryBytes: 1 << 30, NetworkRxBytesPerSec: 2048}},
+	}})
+	if len(metrics.samples) != 1 || len(servers.lastSeen) != 2 {
+		t.Fatalf("got %d samples and %d last-seen updates, want 1 and 2", len(metrics.samples), len(servers.lastSeen))
+	}
+	got := metrics.samples[0]
+	if got.ServerID != 2 || !got.SampledAt.Equal(sampledAt) || got.DiskUsedBytes != 40<<30 || len(got.Sessions) != 1 {
+		t.Fatalf("sample = %+v, want server 2's sample with one session", got)
+	}
+	if s := got.Sessions[0]; s.SessionID != 7 || s.SGCID != 3 || s.CPUPercent != 50 || s.MemoryBytes != 1<<30 || s.NetworkRxBytesPerSec != 2048 {
+		t.Errorf("session sample = %+v", s)
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// ImageStatusHandler handles status.image.* messages, sent by hosts after each background
+// image refresh
+type ImageStatusHandler struct {
+	repo      *repository.Repository
+	publisher Publisher
+	logger    *slog.Logger
+}
+
+// NewImageStatusHandler creates a new image status handler
+func NewImageStatusHandler(repo *repository.Repository, publisher Publisher, logger *slog.Logger) *ImageStatusHandler {
+	return &ImageStatusHandler{
+		repo:      repo,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Handle stores each SGC's image status and announces SGCs that newly have an update
+func (h *ImageStatusHandler) Handle(ctx context.Context, routingKey string, body []byte) error {
+	var msg rmq.ImageStatusReport
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to unmarshal image status: %w", err)}
+	}
+
+	h.logger.Info("processing image status report", "server_id", msg.ServerID, "images", len(msg.Images))
+
+	for _, img := range msg.Images {
+		previous, err := h.repo.SGCImageStatuses.Get(ctx, img.SGCID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get image status of SGC %d: %w", img.SGCID, err)
+		}
+
+		current := &manman.SGCImageStatus{
+			SGCID:        img.SGCID,
+			Image:        img.Image,
+			LocalDigest:  optionalString(img.LocalDigest),
+			RemoteDigest: optionalString(img.RemoteDigest),
+			ErrorMessage: optionalString(img.Error),
+			CheckedAt:    msg.CheckedAt,
+		}
+		if err := h.repo.SGCImageStatuses.Upsert(ctx, current); err != nil {
+			// The SGC may have been deleted since the host listed it
+			h.logger.Warn("failed to store image status", "sgc_id", img.SGCID, "error", err)
+			continue
+		}
+
+		if current.UpdateAvailable() && (previous == nil || !previous.UpdateAvailable() || *previous.RemoteDigest != *current.RemoteDigest) {
+			if err := h.publisher.PublishExternal(ctx, "manman.sgc.image_update_available", img); err != nil {
+				h.logger.Error("failed to publish image update to external exchange",
+					"error", err,
+					"sgc_id", img.SGCID,
+				)
+				// Don't fail the message processing if external publish fails
+			}
+		}
+	}
+
+	return nil
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+)
+
+// PlayerCountHandler handles status.players.* messages, sent by hosts counting players for
+// an SGC's idle shutdown rule or polling a game config's status query
+type PlayerCountHandler struct {
+	repo      *repository.Repository
+	publisher Publisher
+	logger    *slog.Logger
+}
+
+// NewPlayerCountHandler creates a new player count handler
+func NewPlayerCountHandler(repo *repository.Repository, publisher Publisher, logger *slog.Logger) *PlayerCountHandler {
+	return &PlayerCountHandler{
+		repo:      repo,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Handle records the session's player count (which starts or clears its idle clock), plus
+// the map and max players when reported, and publishes it externally
+func (h *PlayerCountHandler) Handle(ctx context.Context, routingKey string, body []byte) error {
+	var msg rmq.PlayerCountUpdate
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to unmarshal player count: %w", err)}
+	}
+
+	h.logger.Debug("player count update",
+		"session_id", msg.SessionID,
+		"sgc_id", msg.SGCID,
+		"player_count", msg.PlayerCount,
+	)
+
+	if err := h.repo.Sessions.UpdatePlayerCount(ctx, msg.SessionID, msg.PlayerCount, msg.Timestamp); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &PermanentError{Err: fmt.Errorf("session %d not found", msg.SessionID)}
+		}
+		return fmt.Errorf("failed to update player count: %w", err)
+	}
+
+	// Status queries also report the map and max players
+	if msg.MapName != "" || msg.MaxPlayers != 0 {
+		if err := h.repo.Sessions.UpdateServerInfo(ctx, msg.SessionID, msg.MapName, msg.MaxPlayers); err != nil {
+			return fmt.Errorf("failed to update server info: %w", err)
+		}
+	}
+
+	if err := h.publisher.PublishExternal(ctx, "manman.session.player_count", msg); err != nil {
+		h.logger.Error("failed to publish player count to external exchange",
+			"error", err,
+			"session_id", msg.SessionID,
+		)
+		// Don't fail the message processing if external publish fails
+	}
+
+	return nil
+}
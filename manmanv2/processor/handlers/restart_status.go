@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+)
+
+// RestartStatusHandler handles status.restart.* messages, sent when a host gives up
+// automatically restarting a crashed session
+type RestartStatusHandler struct {
+	repo      *repository.Repository
+	publisher Publisher
+	logger    *slog.Logger
+}
+
+// NewRestartStatusHandler creates a new restart status handler
+func NewRestartStatusHandler(repo *repository.Repository, publisher Publisher, logger *slog.Logger) *RestartStatusHandler {
+	return &RestartStatusHandler{
+		repo:      repo,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Handle records the give-up on the crashed session and publishes it externally
+func (h *RestartStatusHandler) Handle(ctx context.Context, routingKey string, body []byte) error {
+	var msg rmq.RestartAbandonedUpdate
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to unmarshal restart status: %w", err)}
+	}
+
+	h.logger.Warn("host gave up restarting session",
+		"session_id", msg.SessionID,
+		"sgc_id", msg.SGCID,
+		"attempt", msg.Attempt,
+		"reason", msg.Reason,
+	)
+
+	if err := h.repo.Sessions.SetRestartAbandoned(ctx, msg.SessionID, msg.Reason); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &PermanentError{Err: fmt.Errorf("session %d not found", msg.SessionID)}
+		}
+		return fmt.Errorf("failed to record abandoned restart: %w", err)
+	}
+
+	if err := h.publisher.PublishExternal(ctx, "manman.session.restart_abandoned", msg); err != nil {
+		h.logger.Error("failed to publish restart abandoned to external exchange",
+			"error", err,
+			"session_id", msg.SessionID,
+		)
+		// Don't fail the message processing if external publish fails
+	}
+
+	return nil
+}
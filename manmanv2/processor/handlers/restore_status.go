@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/host/rmq"
+)
+
+// RestoreStatusHandler handles status.restore.* messages, sent when a host finishes
+// unpacking a backup into a volume
+type RestoreStatusHandler struct {
+	repo      *repository.Repository
+	publisher Publisher
+	logger    *slog.Logger
+}
+
+// NewRestoreStatusHandler creates a new restore status handler
+func NewRestoreStatusHandler(repo *repository.Repository, publisher Publisher, logger *slog.Logger) *RestoreStatusHandler {
+	return &RestoreStatusHandler{
+		repo:      repo,
+		publisher: publisher,
+		logger:    logger,
+	}
+}
+
+// Handle records the result on the migration that asked for the restore, if any, and
+// publishes it externally
+func (h *RestoreStatusHandler) Handle(ctx context.Context, routingKey string, body []byte) error {
+	var msg rmq.RestoreStatusUpdate
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return &PermanentError{Err: fmt.Errorf("failed to unmarshal restore status: %w", err)}
+	}
+
+	h.logger.Info("processing restore status update",
+		"backup_id", msg.BackupID,
+		"sgc_id", msg.SGCID,
+		"migration_id", msg.MigrationID,
+		"status", msg.Status,
+	)
+
+	if msg.MigrationID != 0 {
+		var checksum, errMsg string
+		if msg.Checksum != nil {
+			checksum = *msg.Checksum
+		}
+		if msg.Status != "completed" {
+			errMsg = "restore failed"
+			if msg.ErrorMessage != nil {
+				errMsg = *msg.ErrorMessage
+			}
+		}
+		if err := h.repo.SGCMigrations.RecordRestore(ctx, msg.MigrationID, msg.BackupID, checksum, errMsg); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return &PermanentError{Err: fmt.Errorf("migration %d not found", msg.MigrationID)}
+			}
+			return fmt.Errorf("failed to record restore on migration: %w", err)
+		}
+	}
+
+	if err := h.publisher.PublishExternal(ctx, fmt.Sprintf("manman.restore.%s", msg.Status), msg); err != nil {
+		h.logger.Error("failed to publish restore status to external exchange",
+			"error", err,
+			"backup_id", msg.BackupID,
+		)
+		// Don't fail the message processing if external publish fails
+	}
+
+	return nil
+}
@@ -138,8 +138,9 @@ func (h *SessionStatusHandler) Handle(ctx context.Context, routingKey string, bo
 		return err // Transient error - retry
 	}
 
-	// Publish to external exchange for terminal states and running state
+	// Publish to external exchange for terminal states, running and ready
 	if msg.Status == manman.SessionStatusRunning ||
+		msg.Status == manman.SessionStatusReady ||
 		msg.Status == manman.SessionStatusStopped ||
 		msg.Status == manman.SessionStatusCrashed ||
 		msg.Status == manman.SessionStatusLost {
@@ -240,7 +241,8 @@ func isValidTransition(from, to string) bool {
 	validTransitions := map[string][]string{
 		manman.SessionStatusPending:  {manman.SessionStatusStarting, manman.SessionStatusCrashed, manman.SessionStatusLost},
 		manman.SessionStatusStarting: {manman.SessionStatusRunning, manman.SessionStatusCrashed, manman.SessionStatusLost},
-		manman.SessionStatusRunning:  {manman.SessionStatusStopping, manman.SessionStatusCrashed, manman.SessionStatusLost},
+		manman.SessionStatusRunning:  {manman.SessionStatusReady, manman.SessionStatusStopping, manman.SessionStatusCrashed, manman.SessionStatusLost},
+		manman.SessionStatusReady:    {manman.SessionStatusStopping, manman.SessionStatusCrashed, manman.SessionStatusLost},
 		manman.SessionStatusStopping: {manman.SessionStatusStopped, manman.SessionStatusCrashed, manman.SessionStatusLost},
 		manman.SessionStatusStopped:  {}, // Terminal state
 		manman.SessionStatusCrashed:  {}, // Terminal state
@@ -17,18 +17,22 @@ func TestIsValidTransition(t *testing.T) {
 		{"pending to starting", manman.SessionStatusPending, manman.SessionStatusStarting, true},
 		{"starting to running", manman.SessionStatusStarting, manman.SessionStatusRunning, true},
 		{"running to stopping", manman.SessionStatusRunning, manman.SessionStatusStopping, true},
+		{"running to ready", manman.SessionStatusRunning, manman.SessionStatusReady, true},
+		{"ready to stopping", manman.SessionStatusReady, manman.SessionStatusStopping, true},
 		{"stopping to stopped", manman.SessionStatusStopping, manman.SessionStatusStopped, true},
 
 		// Lost from any non-terminal state
 		{"pending to lost", manman.SessionStatusPending, manman.SessionStatusLost, true},
 		{"starting to lost", manman.SessionStatusStarting, manman.SessionStatusLost, true},
 		{"running to lost", manman.SessionStatusRunning, manman.SessionStatusLost, true},
+		{"ready to lost", manman.SessionStatusReady, manman.SessionStatusLost, true},
 		{"stopping to lost", manman.SessionStatusStopping, manman.SessionStatusLost, true},
 
 		// Crash from any non-terminal state
 		{"pending to crashed", manman.SessionStatusPending, manman.SessionStatusCrashed, true},
 		{"starting to crashed", manman.SessionStatusStarting, manman.SessionStatusCrashed, true},
 		{"running to crashed", manman.SessionStatusRunning, manman.SessionStatusCrashed, true},
+		{"ready to crashed", manman.SessionStatusReady, manman.SessionStatusCrashed, true},
 		{"stopping to crashed", manman.SessionStatusStopping, manman.SessionStatusCrashed, true},
 
 		// Idempotent (same state)
@@ -41,6 +45,8 @@ func TestIsValidTransition(t *testing.T) {
 		{"pending to stopped", manman.SessionStatusPending, manman.SessionStatusStopped, false},
 		{"starting to stopping", manman.SessionStatusStarting, manman.SessionStatusStopping, false},
 		{"running to starting", manman.SessionStatusRunning, manman.SessionStatusStarting, false},
+		{"starting to ready", manman.SessionStatusStarting, manman.SessionStatusReady, false},
+		{"ready to running", manman.SessionStatusReady, manman.SessionStatusRunning, false},
 		{"stopped to running", manman.SessionStatusStopped, manman.SessionStatusRunning, false},
 		{"stopped to starting", manman.SessionStatusStopped, manman.SessionStatusStarting, false},
 		{"crashed to running", manman.SessionStatusCrashed, manman.SessionStatusRunning, false},
@@ -72,6 +78,15 @@ func TestSessionStateTransitionPaths(t *testing.T) {
 			manman.SessionStatusStopping,
 			manman.SessionStatusStopped,
 		},
+		// Readiness probe passes
+		{
+			manman.SessionStatusPending,
+			manman.SessionStatusStarting,
+			manman.SessionStatusRunning,
+			manman.SessionStatusReady,
+			manman.SessionStatusStopping,
+			manman.SessionStatusStopped,
+		},
 		// Crash during starting
 		{
 			manman.SessionStatusPending,
@@ -139,6 +139,10 @@ func (m *MockSessionRepository) Create(ctx context.Context, session *manman.Sess
 	return session, nil
 }
 
+func (m *MockSessionRepository) CreateWithinCapacity(ctx context.Context, session *manman.Session, serverID int64, limits manman.ResourceLimits, patches []*manman.ConfigurationPatch) (*manman.Session, error) {
+	return m.Create(ctx, session)
+}
+
 func (m *MockSessionRepository) Get(ctx context.Context, sessionID int64) (*manman.Session, error) {
 	session, ok := m.sessions[sessionID]
 	if !ok {
@@ -230,6 +234,43 @@ func (m *MockSessionRepository) StopOtherSessionsForSGC(ctx context.Context, ses
 	return nil
 }
 
+func (m *MockSessionRepository) SetRestartAbandoned(ctx context.Context, sessionID int64, reason string) error {
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return &NotFoundError{ID: sessionID}
+	}
+	session.RestartAbandonedReason = &reason
+	return nil
+}
+
+func (m *MockSessionRepository) UpdatePlayerCount(ctx context.Context, sessionID int64, count int32, at time.Time) error {
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return &NotFoundError{ID: sessionID}
+	}
+	session.PlayerCount = &count
+	if count > 0 {
+		session.IdleSince = nil
+	} else if session.IdleSince == nil {
+		session.IdleSince = &at
+	}
+	return nil
+}
+
+func (m *MockSessionRepository) UpdateServerInfo(ctx context.Context, sessionID int64, mapName string, maxPlayers int32) error {
+	session, ok := m.sessions[sessionID]
+	if !ok {
+		return &NotFoundError{ID: sessionID}
+	}
+	session.MapName = &mapName
+	session.MaxPlayers = &maxPlayers
+	return nil
+}
+
+func (m *MockSessionRepository) ListIdle(ctx context.Context, now time.Time) ([]*manman.Session, error) {
+	return nil, nil
+}
+
 // NotFoundError represents an entity not found error
 type NotFoundError struct {
 	ID   int64
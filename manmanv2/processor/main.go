@@ -11,13 +11,17 @@ import (
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/whale-net/everything/libs/go/grpcauth"
+	"github.com/whale-net/everything/libs/go/grpcclient"
 	"github.com/whale-net/everything/libs/go/logging"
 	"github.com/whale-net/everything/libs/go/rmq"
 	s3lib "github.com/whale-net/everything/libs/go/s3"
 	"github.com/whale-net/everything/manmanv2/api/repository"
 	"github.com/whale-net/everything/manmanv2/api/repository/postgres"
+	"github.com/whale-net/everything/manmanv2/api/steam"
 	"github.com/whale-net/everything/manmanv2/processor/consumer"
 	"github.com/whale-net/everything/manmanv2/processor/handlers"
+	pb "github.com/whale-net/everything/manmanv2/protos"
 )
 
 func main() {
@@ -120,6 +124,11 @@ func run() error {
 		BackupConfigs:      postgres.NewBackupConfigRepository(dbPool),
 		GameConfigVolumes:  postgres.NewGameConfigVolumeRepository(dbPool),
 		ServerPorts:        postgres.NewServerPortRepository(dbPool),
+		SGCSchedules:       postgres.NewSGCScheduleRepository(dbPool),
+		SGCMigrations:      postgres.NewSGCMigrationRepository(dbPool),
+		SGCImageStatuses:   postgres.NewSGCImageStatusRepository(dbPool),
+		AlertRules:         postgres.NewAlertRuleRepository(dbPool),
+		AlertEvents:        postgres.NewAlertEventRepository(dbPool),
 	}
 
 	// Initialize publisher for external exchange
@@ -128,6 +137,22 @@ func run() error {
 		return fmt.Errorf("failed to create publisher: %w", err)
 	}
 
+	// Create context for graceful shutdown
+	appCtx, appCancel := context.WithCancel(context.Background())
+	defer appCancel()
+
+	// Schedules, idle shutdown, migrations and alert responses start and stop sessions
+	// through the API, like a user would
+	var apiClient pb.ManManAPIClient
+	if cfg.APIAddress == "" {
+		logger.Warn("API_ADDRESS not set, SGC schedules, idle shutdown, migrations and alert responses will not run")
+	} else {
+		apiClient, err = newAPIClient(appCtx, cfg)
+		if err != nil {
+			logger.Warn("failed to connect to API, SGC schedules, idle shutdown, migrations and alert responses will not run", "error", err)
+		}
+	}
+
 	// Create handler registry
 	handlerRegistry := handlers.NewHandlerRegistry(repo, logger)
 
@@ -141,9 +166,24 @@ func run() error {
 	healthHandler := handlers.NewHealthHandler(repo, publisher, cfg.StaleHostThreshold, logger)
 	handlerRegistry.Register("health.#", healthHandler)
 
-	backupStatusHandler := handlers.NewBackupStatusHandler(repo, logger)
+	backupStatusHandler := handlers.NewBackupStatusHandler(repo, publisher, logger)
 	handlerRegistry.Register("status.backup.#", backupStatusHandler)
 
+	restartStatusHandler := handlers.NewRestartStatusHandler(repo, publisher, logger)
+	handlerRegistry.Register("status.restart.#", restartStatusHandler)
+
+	restoreStatusHandler := handlers.NewRestoreStatusHandler(repo, publisher, logger)
+	handlerRegistry.Register("status.restore.#", restoreStatusHandler)
+
+	playerCountHandler := handlers.NewPlayerCountHandler(repo, publisher, logger)
+	handlerRegistry.Register("status.players.#", playerCountHandler)
+
+	imageStatusHandler := handlers.NewImageStatusHandler(repo, publisher, logger)
+	handlerRegistry.Register("status.image.#", imageStatusHandler)
+
+	alertHandler := handlers.NewAlertHandler(repo, publisher, apiClient, logger)
+	handlerRegistry.Register("status.alert.#", alertHandler)
+
 	// Create consumer
 	processorConsumer, err := consumer.NewProcessorConsumer(
 		rmqConn,
@@ -155,10 +195,6 @@ func run() error {
 		return fmt.Errorf("failed to create consumer: %w", err)
 	}
 
-	// Create context for graceful shutdown
-	appCtx, appCancel := context.WithCancel(context.Background())
-	defer appCancel()
-
 	// Start health check server
 	healthServer := &http.Server{
 		Addr:    fmt.Sprintf(":%s", cfg.HealthCheckPort),
@@ -191,7 +227,9 @@ func run() error {
 		logger.Warn("failed to initialize S3 client, scheduled backups will not run", "error", err)
 		s3Client = nil
 	}
-	riverClient, err := startBackupScheduler(appCtx, dbPool, repo, rmqConn, s3Client, logger)
+	steamClient := steam.NewSteamWorkshopClient(cfg.SteamAPIKey, 30*time.Second)
+	addonUpdateInterval := time.Duration(cfg.AddonUpdateMinutes) * time.Minute
+	riverClient, err := startBackupScheduler(appCtx, dbPool, repo, rmqConn, s3Client, apiClient, publisher, steamClient, addonUpdateInterval, logger)
 	if err != nil {
 		logger.Warn("failed to start backup scheduler, scheduled backups will not run", "error", err)
 	} else {
@@ -243,6 +281,27 @@ func run() error {
 	return nil
 }
 
+// newAPIClient connects to the control API as the processor's service account
+func newAPIClient(ctx context.Context, cfg *Config) (pb.ManManAPIClient, error) {
+	authOpt, err := grpcauth.NewServiceAccountDialOption(grpcauth.ClientConfig{
+		Mode:         grpcauth.AuthMode(cfg.GRPCAuthMode),
+		TokenURL:     cfg.GRPCAuthTokenURL,
+		ClientID:     cfg.GRPCAuthClientID,
+		ClientSecret: cfg.GRPCAuthClientSecret,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create auth dial option: %w", err)
+	}
+
+	connCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	client, err := grpcclient.NewClient(connCtx, cfg.APIAddress, authOpt)
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewManManAPIClient(client.GetConnection()), nil
+}
+
 func setupHealthCheckRoutes(dbPool *pgxpool.Pool, logger *slog.Logger) http.Handler {
 	mux := http.NewServeMux()
 
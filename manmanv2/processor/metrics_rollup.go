@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/riverqueue/river"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+// ============================================================================
+// Metrics rollup job: runs hourly, folds day-old minute buckets into hour buckets
+// and drops hour buckets past retention
+// ============================================================================
+
+type metricsRollupArgs struct{}
+
+func (metricsRollupArgs) Kind() string { return "metrics_rollup" }
+
+type metricsRollupWorker struct {
+	river.WorkerDefaults[metricsRollupArgs]
+	repo   *repository.Repository
+	logger *slog.Logger
+}
+
+func (w *metricsRollupWorker) Work(ctx context.Context, _ *river.Job[metricsRollupArgs]) error {
+	now := time.Now()
+
+	rolled, err := w.repo.Metrics.Rollup(ctx, now.Add(-manman.MetricsMinuteRetention))
+	if err != nil {
+		return fmt.Errorf("failed to roll up metrics: %w", err)
+	}
+	deleted, err := w.repo.Metrics.DeleteBefore(ctx, now.Add(-manman.MetricsHourRetention))
+	if err != nil {
+		return fmt.Errorf("failed to delete old metrics: %w", err)
+	}
+	if rolled > 0 || deleted > 0 {
+		w.logger.Info("rolled up metrics", "minute_buckets", rolled, "expired_buckets", deleted)
+	}
+	return nil
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	"github.com/whale-net/everything/manmanv2/models"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Scheduled stops wait this long for their pre-stop backup before stopping anyway
+const scheduledStopBackupTimeout = 30 * time.Minute
+
+// liveSessionStatuses are the statuses of a session a scheduled or idle stop should stop
+var liveSessionStatuses = []string{
+	manman.SessionStatusPending,
+	manman.SessionStatusStarting,
+	manman.SessionStatusRunning,
+	manman.SessionStatusReady,
+}
+
+// ============================================================================
+// Schedule scan job: runs every minute, enqueues the start or stop each due schedule wants
+// ============================================================================
+
+type sessionScheduleScanArgs struct{}
+
+func (sessionScheduleScanArgs) Kind() string { return "session_schedule_scan" }
+
+type sessionScheduleScanWorker struct {
+	river.WorkerDefaults[sessionScheduleScanArgs]
+	repo        *repository.Repository
+	riverClient *river.Client[pgx.Tx]
+	logger      *slog.Logger
+}
+
+func (w *sessionScheduleScanWorker) Work(ctx context.Context, _ *river.Job[sessionScheduleScanArgs]) error {
+	schedules, err := w.repo.SGCSchedules.ListEnabled(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list enabled schedules: %w", err)
+	}
+
+	now := time.Now()
+	for _, s := range schedules {
+		action, at, err := s.DueAction(now)
+		if err != nil {
+			w.logger.Warn("skipping invalid schedule", "schedule_id", s.ScheduleID, "error", err)
+			continue
+		}
+
+		if action != "" {
+			var args river.JobArgs = scheduledStartArgs{ScheduleID: s.ScheduleID, SGCID: s.SGCID}
+			if action == manman.ScheduleActionStop {
+				args = scheduledStopArgs{ScheduleID: s.ScheduleID, SGCID: s.SGCID, BackupConfigID: s.BackupConfigID}
+			}
+			if _, err := w.riverClient.Insert(ctx, args, nil); err != nil {
+				// Leave the schedule unevaluated so the next scan retries
+				w.logger.Error("failed to enqueue scheduled session job", "schedule_id", s.ScheduleID, "action", action, "error", err)
+				continue
+			}
+			w.logger.Info("schedule due", "schedule_id", s.ScheduleID, "sgc_id", s.SGCID, "action", action, "at", at)
+		}
+
+		if err := w.repo.SGCSchedules.MarkEvaluated(ctx, s.ScheduleID, now, action); err != nil {
+			w.logger.Error("failed to mark schedule evaluated", "schedule_id", s.ScheduleID, "error", err)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Scheduled start job: starts the SGC unless it already has a session
+// ============================================================================
+
+type scheduledStartArgs struct {
+	ScheduleID int64 `json:"schedule_id"`
+	SGCID      int64 `json:"sgc_id"`
+}
+
+func (scheduledStartArgs) Kind() string { return "scheduled_session_start" }
+
+type scheduledStartWorker struct {
+	river.WorkerDefaults[scheduledStartArgs]
+	apiClient pb.ManManAPIClient
+	logger    *slog.Logger
+}
+
+func (w *scheduledStartWorker) Work(ctx context.Context, job *river.Job[scheduledStartArgs]) error {
+	resp, err := w.apiClient.StartSession(ctx, &pb.StartSessionRequest{ServerGameConfigId: job.Args.SGCID})
+	if status.Code(err) == codes.FailedPrecondition {
+		w.logger.Info("scheduled start skipped, SGC already has an active session", "schedule_id", job.Args.ScheduleID, "sgc_id", job.Args.SGCID)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start SGC %d: %w", job.Args.SGCID, err)
+	}
+	w.logger.Info("scheduled start", "schedule_id", job.Args.ScheduleID, "sgc_id", job.Args.SGCID, "session_id", resp.Session.SessionId)
+	return nil
+}
+
+// ============================================================================
+// Scheduled stop job: backs up the SGC first when configured, then stops its session
+// ============================================================================
+
+type scheduledStopArgs struct {
+	ScheduleID     int64  `json:"schedule_id"`
+	SGCID          int64  `json:"sgc_id"`
+	BackupConfigID *int64 `json:"backup_config_id,omitempty"`
+	BackupID       int64  `json:"backup_id,omitempty"` // set once the pre-stop backup has been triggered
+}
+
+func (scheduledStopArgs) Kind() string { return "scheduled_session_stop" }
+
+type scheduledStopWorker struct {
+	river.WorkerDefaults[scheduledStopArgs]
+	repo        *repository.Repository
+	apiClient   pb.ManManAPIClient
+	riverClient *river.Client[pgx.Tx]
+	logger      *slog.Logger
+}
+
+func (w *scheduledStopWorker) Work(ctx context.Context, job *river.Job[scheduledStopArgs]) error {
+	args := job.Args
+
+	session, err := liveSession(ctx, w.repo, args.SGCID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		w.logger.Info("scheduled stop skipped, SGC has no live session", "schedule_id", args.ScheduleID, "sgc_id", args.SGCID)
+		return nil
+	}
+
+	if args.BackupConfigID != nil && args.BackupID == 0 {
+		resp, err := w.apiClient.TriggerBackup(ctx, &pb.TriggerBackupRequest{
+			ServerGameConfigId: args.SGCID,
+			BackupConfigId:     *args.BackupConfigID,
+		})
+		if err != nil {
+			// A failed backup shouldn't keep the server running past its schedule
+			w.logger.Error("pre-stop backup failed to start, stopping anyway", "schedule_id", args.ScheduleID, "sgc_id", args.SGCID, "error", err)
+		} else {
+			args.BackupID = resp.BackupId
+			w.logger.Info("waiting for pre-stop backup", "schedule_id", args.ScheduleID, "sgc_id", args.SGCID, "backup_id", args.BackupID)
+			_, err := w.riverClient.Insert(ctx, args, &river.InsertOpts{ScheduledAt: time.Now().Add(30 * time.Second)})
+			return err
+		}
+	}
+
+	if args.BackupID != 0 {
+		backup, err := w.repo.Backups.Get(ctx, args.BackupID)
+		if err != nil {
+			return fmt.Errorf("backup %d not found: %w", args.BackupID, err)
+		}
+		done := backup.Status == manman.BackupStatusCompleted || backup.Status == manman.BackupStatusFailed
+		if !done && time.Since(backup.CreatedAt) < scheduledStopBackupTimeout {
+			return river.JobSnooze(30 * time.Second)
+		}
+		if backup.Status != manman.BackupStatusCompleted {
+			w.logger.Warn("pre-stop backup did not complete, stopping anyway", "backup_id", args.BackupID, "status", backup.Status)
+		}
+	}
+
+	if _, err := w.apiClient.StopSession(ctx, &pb.StopSessionRequest{SessionId: session.SessionID}); err != nil {
+		return fmt.Errorf("failed to stop session %d: %w", session.SessionID, err)
+	}
+	w.logger.Info("scheduled stop", "schedule_id", args.ScheduleID, "sgc_id", args.SGCID, "session_id", session.SessionID)
+	return nil
+}
+
+// ============================================================================
+// Idle scan job: runs every minute, stops sessions that have had no players for too long
+// ============================================================================
+
+type idleShutdownScanArgs struct{}
+
+func (idleShutdownScanArgs) Kind() string { return "idle_shutdown_scan" }
+
+type idleShutdownScanWorker struct {
+	river.WorkerDefaults[idleShutdownScanArgs]
+	repo      *repository.Repository
+	apiClient pb.ManManAPIClient
+	logger    *slog.Logger
+}
+
+func (w *idleShutdownScanWorker) Work(ctx context.Context, _ *river.Job[idleShutdownScanArgs]) error {
+	sessions, err := w.repo.Sessions.ListIdle(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to list idle sessions: %w", err)
+	}
+	for _, s := range sessions {
+		if _, err := w.apiClient.StopSession(ctx, &pb.StopSessionRequest{SessionId: s.SessionID}); err != nil {
+			w.logger.Error("failed to stop idle session", "session_id", s.SessionID, "sgc_id", s.SGCID, "error", err)
+			continue
+		}
+		w.logger.Info("stopped idle session", "session_id", s.SessionID, "sgc_id", s.SGCID, "idle_since", s.IdleSince)
+	}
+	return nil
+}
+
+// liveSession returns the SGC's pending, starting, running or ready session, or nil
+func liveSession(ctx context.Context, repo *repository.Repository, sgcID int64) (*manman.Session, error) {
+	sessions, err := repo.Sessions.ListWithFilters(ctx, &repository.SessionFilters{
+		SGCID:        &sgcID,
+		StatusFilter: liveSessionStatuses,
+	}, 1, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions for SGC %d: %w", sgcID, err)
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+	return sessions[0], nil
+}
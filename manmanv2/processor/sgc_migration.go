@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/riverqueue/river"
+	"github.com/whale-net/everything/libs/go/rmq"
+	s3lib "github.com/whale-net/everything/libs/go/s3"
+	"github.com/whale-net/everything/manmanv2/api/repository"
+	hostrmq "github.com/whale-net/everything/manmanv2/host/rmq"
+	"github.com/whale-net/everything/manmanv2/models"
+	"github.com/whale-net/everything/manmanv2/processor/handlers"
+	pb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+const (
+	// Migrations check on their hosts this often while waiting on them
+	migrationPollInterval = 10 * time.Second
+	// A migration that makes no progress for this long is failed
+	migrationStepTimeout = 2 * time.Hour
+	// Presigned archive URLs must outlive the step that uses them
+	migrationURLExpiry = migrationStepTimeout
+)
+
+// ============================================================================
+// Migration scan job: runs every 15 seconds, makes sure each in-flight migration has a job
+// ============================================================================
+
+type sgcMigrationScanArgs struct{}
+
+func (sgcMigrationScanArgs) Kind() string { return "sgc_migration_scan" }
+
+type sgcMigrationScanWorker struct {
+	river.WorkerDefaults[sgcMigrationScanArgs]
+	repo        *repository.Repository
+	riverClient *river.Client[pgx.Tx]
+	logger      *slog.Logger
+}
+
+func (w *sgcMigrationScanWorker) Work(ctx context.Context, _ *river.Job[sgcMigrationScanArgs]) error {
+	migrations, err := w.repo.SGCMigrations.ListInFlight(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list in-flight migrations: %w", err)
+	}
+	for _, m := range migrations {
+		// Unique by args: a migration already being worked is left alone
+		_, err := w.riverClient.Insert(ctx, sgcMigrationArgs{MigrationID: m.MigrationID}, &river.InsertOpts{
+			UniqueOpts: river.UniqueOpts{ByArgs: true},
+		})
+		if err != nil {
+			w.logger.Error("failed to enqueue migration job", "migration_id", m.MigrationID, "error", err)
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// Migration job: walks one migration through its steps, snoozing while hosts work
+// ============================================================================
+
+type sgcMigrationArgs struct {
+	MigrationID int64 `json:"migration_id"`
+}
+
+func (sgcMigrationArgs) Kind() string { return "sgc_migration" }
+
+// migrationFailure ends a migration; other errors are retried
+type migrationFailure struct {
+	reason string
+}
+
+func (e *migrationFailure) Error() string { return e.reason }
+
+func failMigration(format string, args ...interface{}) error {
+	return &migrationFailure{reason: fmt.Sprintf(format, args...)}
+}
+
+type sgcMigrationWorker struct {
+	river.WorkerDefaults[sgcMigrationArgs]
+	repo      *repository.Repository
+	apiClient pb.ManManAPIClient
+	publisher *rmq.Publisher     // host commands
+	events    handlers.Publisher // progress events on the external exchange
+	s3Client  *s3lib.Client
+	logger    *slog.Logger
+}
+
+func (w *sgcMigrationWorker) Work(ctx context.Context, job *river.Job[sgcMigrationArgs]) error {
+	m, err := w.repo.SGCMigrations.Get(ctx, job.Args.MigrationID)
+	if err != nil {
+		return fmt.Errorf("migration %d not found: %w", job.Args.MigrationID, err)
+	}
+
+	// Run steps until one has to wait on a host
+	for !m.Done() {
+		from := m.Status
+		var err error
+		switch m.Status {
+		case manman.MigrationStatusPending:
+			err = w.advance(ctx, m, manman.MigrationStatusStopping)
+		case manman.MigrationStatusStopping:
+			err = w.stopSource(ctx, m)
+		case manman.MigrationStatusBackingUp:
+			err = w.awaitBackups(ctx, m)
+		case manman.MigrationStatusCreatingTarget:
+			err = w.createTarget(ctx, m)
+		case manman.MigrationStatusRestoring:
+			err = w.awaitRestores(ctx, m)
+		case manman.MigrationStatusVerifying:
+			err = w.verify(ctx, m)
+		default:
+			err = failMigration("unknown migration status %q", m.Status)
+		}
+
+		var failure *migrationFailure
+		if errors.As(err, &failure) {
+			return w.fail(ctx, m, failure.reason)
+		}
+		if err != nil {
+			return err
+		}
+		if m.Status == from {
+			if time.Since(m.UpdatedAt) > migrationStepTimeout {
+				return w.fail(ctx, m, fmt.Sprintf("timed out while %s", strings.ReplaceAll(m.Status, "_", " ")))
+			}
+			return river.JobSnooze(migrationPollInterval)
+		}
+	}
+	return nil
+}
+
+// stopSource stops the source SGC's session and, once nothing is running, backs up its volumes
+func (w *sgcMigrationWorker) stopSource(ctx context.Context, m *manman.SGCMigration) error {
+	sessions, err := w.repo.Sessions.ListWithFilters(ctx, &repository.SessionFilters{
+		SGCID:        &m.SourceSGCID,
+		StatusFilter: append([]string{manman.SessionStatusStopping}, liveSessionStatuses...),
+	}, 10, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions for SGC %d: %w", m.SourceSGCID, err)
+	}
+	for _, s := range sessions {
+		if s.Status == manman.SessionStatusStopping {
+			continue
+		}
+		if _, err := w.apiClient.StopSession(ctx, &pb.StopSessionRequest{SessionId: s.SessionID}); err != nil {
+			return fmt.Errorf("failed to stop session %d: %w", s.SessionID, err)
+		}
+		w.logger.Info("migration stopped source session", "migration_id", m.MigrationID, "session_id", s.SessionID)
+	}
+	if len(sessions) > 0 {
+		return nil
+	}
+
+	if err := w.startBackups(ctx, m); err != nil {
+		return err
+	}
+	return w.advance(ctx, m, manman.MigrationStatusBackingUp)
+}
+
+// startBackups creates a backup of each volume and sends it to the source host
+func (w *sgcMigrationWorker) startBackups(ctx context.Context, m *manman.SGCMigration) error {
+	// Backups belong to a session; an SGC that has never run has no data to copy
+	sessions, err := w.repo.Sessions.List(ctx, &m.SourceSGCID, 1, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list sessions for SGC %d: %w", m.SourceSGCID, err)
+	}
+	if len(sessions) == 0 || len(m.Volumes) == 0 {
+		w.logger.Info("migration has no data to copy", "migration_id", m.MigrationID, "sgc_id", m.SourceSGCID)
+		return nil
+	}
+	if w.s3Client == nil {
+		return failMigration("object storage is not configured")
+	}
+
+	description := fmt.Sprintf("migration %d to server %d", m.MigrationID, m.TargetServerID)
+	for i := range m.Volumes {
+		v := &m.Volumes[i]
+		if v.BackupID != 0 {
+			continue
+		}
+		volume, err := w.repo.GameConfigVolumes.Get(ctx, v.VolumeID)
+		if err != nil {
+			return failMigration("volume %s not found: %v", v.Name, err)
+		}
+
+		backup, err := w.repo.Backups.Create(ctx, &manman.Backup{
+			SessionID:          sessions[0].SessionID,
+			ServerGameConfigID: m.SourceSGCID,
+			VolumeID:           &volume.VolumeID,
+			Status:             manman.BackupStatusPending,
+			Description:        &description,
+			CreatedAt:          time.Now(),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create backup record for volume %s: %w", v.Name, err)
+		}
+		// Saved before dispatching so a retry after a failure below doesn't back the volume up twice
+		if err := w.repo.SGCMigrations.SetVolumeBackup(ctx, m.MigrationID, v.VolumeID, backup.BackupID); err != nil {
+			return fmt.Errorf("failed to record backup of volume %s: %w", v.Name, err)
+		}
+		v.BackupID = backup.BackupID
+
+		s3Key := fmt.Sprintf("backups/%d/migration-%d/%d.tar.gz", m.SourceSGCID, m.MigrationID, backup.BackupID)
+		presignedURL, err := w.s3Client.PresignPutURL(ctx, s3Key, migrationURLExpiry)
+		if err != nil {
+			_ = w.repo.Backups.UpdateStatus(ctx, backup.BackupID, manman.BackupStatusFailed, nil, nil, strPtr(err.Error()))
+			return failMigration("failed to generate presigned URL for volume %s: %v", v.Name, err)
+		}
+
+		hostPath := ""
+		if volume.HostSubpath != nil {
+			hostPath = *volume.HostSubpath
+		}
+		cmd := &hostrmq.BackupCommand{
+			BackupID:       backup.BackupID,
+			SGCID:          m.SourceSGCID,
+			VolumeType:     volume.VolumeType,
+			VolumeHostPath: hostPath,
+			VolumeName:     volume.Name,
+			BackupPath:     ".",
+			S3Key:          s3Key,
+			PresignedURL:   presignedURL,
+			CreatedAt:      time.Now(),
+		}
+		routingKey := fmt.Sprintf("command.host.%d.backup", m.SourceServerID)
+		if err := w.publisher.Publish(ctx, "manman", routingKey, cmd); err != nil {
+			return fmt.Errorf("failed to dispatch backup of volume %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// awaitBackups moves on once every volume's backup has completed with a checksum
+func (w *sgcMigrationWorker) awaitBackups(ctx context.Context, m *manman.SGCMigration) error {
+	for _, v := range m.Volumes {
+		if v.BackupID == 0 {
+			continue
+		}
+		backup, err := w.repo.Backups.Get(ctx, v.BackupID)
+		if err != nil {
+			return fmt.Errorf("failed to get backup %d: %w", v.BackupID, err)
+		}
+		switch backup.Status {
+		case manman.BackupStatusCompleted:
+			if backup.Checksum == nil {
+				return failMigration("host did not report a checksum for the backup of volume %s", v.Name)
+			}
+		case manman.BackupStatusFailed:
+			msg := "unknown error"
+			if backup.ErrorMessage != nil {
+				msg = *backup.ErrorMessage
+			}
+			return failMigration("backup of volume %s failed: %s", v.Name, msg)
+		default:
+			return nil
+		}
+	}
+	return w.advance(ctx, m, manman.MigrationStatusCreatingTarget)
+}
+
+// createTarget recreates the SGC on the target server and sends it each backup to restore
+func (w *sgcMigrationWorker) createTarget(ctx context.Context, m *manman.SGCMigration) error {
+	if m.TargetSGCID == nil {
+		source, err := w.repo.ServerGameConfigs.Get(ctx, m.SourceSGCID)
+		if err != nil {
+			return failMigration("source server game config not found: %v", err)
+		}
+
+		target, err := w.repo.ServerGameConfigs.Create(ctx, &manman.ServerGameConfig{
+			ServerID:      m.TargetServerID,
+			GameConfigID:  source.GameConfigID,
+			PortBindings:  migrationPortBindings(m.PortBindings),
+			Status:        manman.SGCStatusInactive,
+			CPUMillicores: source.CPUMillicores,
+			MemoryMB:      source.MemoryMB,
+			PidsLimit:     source.PidsLimit,
+			Ulimits:       source.Ulimits,
+			RestartPolicy: source.RestartPolicy,
+			IdleShutdown:  source.IdleShutdown,
+
+			AddonUpdatePolicy: source.AddonUpdatePolicy,
+		})
+		if err != nil {
+			return failMigration("failed to create server game config on server %d: %v", m.TargetServerID, err)
+		}
+		m.TargetSGCID = &target.SGCID
+		if err := w.repo.SGCMigrations.Update(ctx, m); err != nil {
+			return fmt.Errorf("failed to record target SGC: %w", err)
+		}
+
+		attachments, err := w.repo.ServerGameConfigs.GetSGCLibraryAttachments(ctx, source.SGCID)
+		if err != nil {
+			return failMigration("failed to list workshop libraries: %v", err)
+		}
+		for _, a := range attachments {
+			if err := w.repo.ServerGameConfigs.AddLibrary(ctx, target.SGCID, a.LibraryID, a.PresetID, a.VolumeID, a.InstallationPathOverride); err != nil {
+				return failMigration("failed to attach workshop library %d: %v", a.LibraryID, err)
+			}
+		}
+		w.logger.Info("migration created target SGC", "migration_id", m.MigrationID, "sgc_id", target.SGCID, "server_id", m.TargetServerID)
+	}
+
+	for _, v := range m.Volumes {
+		if v.BackupID == 0 {
+			continue
+		}
+		volume, err := w.repo.GameConfigVolumes.Get(ctx, v.VolumeID)
+		if err != nil {
+			return failMigration("volume %s not found: %v", v.Name, err)
+		}
+		backup, err := w.repo.Backups.Get(ctx, v.BackupID)
+		if err != nil {
+			return fmt.Errorf("failed to get backup %d: %w", v.BackupID, err)
+		}
+		if backup.S3URL == nil {
+			return failMigration("backup of volume %s has no archive", v.Name)
+		}
+
+		// The host reports s3_url as s3://{key}, with no bucket segment
+		s3Key := strings.TrimPrefix(*backup.S3URL, "s3://")
+		presignedURL, err := w.s3Client.PresignGetURL(ctx, s3Key, migrationURLExpiry)
+		if err != nil {
+			return failMigration("failed to generate presigned URL for volume %s: %v", v.Name, err)
+		}
+
+		hostPath := ""
+		if volume.HostSubpath != nil {
+			hostPath = *volume.HostSubpath
+		}
+		cmd := &hostrmq.RestoreBackupCommand{
+			BackupID:       backup.BackupID,
+			SGCID:          *m.TargetSGCID,
+			VolumeType:     volume.VolumeType,
+			VolumeHostPath: hostPath,
+			VolumeName:     volume.Name,
+			S3Key:          s3Key,
+			PresignedURL:   presignedURL,
+			MigrationID:    m.MigrationID,
+			CreatedAt:      time.Now(),
+		}
+		routingKey := fmt.Sprintf("command.host.%d.backup.restore", m.TargetServerID)
+		if err := w.publisher.Publish(ctx, "manman", routingKey, cmd); err != nil {
+			return fmt.Errorf("failed to dispatch restore of volume %s: %w", v.Name, err)
+		}
+	}
+	return w.advance(ctx, m, manman.MigrationStatusRestoring)
+}
+
+// awaitRestores moves on once the target host has restored every volume
+func (w *sgcMigrationWorker) awaitRestores(ctx context.Context, m *manman.SGCMigration) error {
+	for _, v := range m.Volumes {
+		if v.BackupID == 0 {
+			continue
+		}
+		if v.Error != "" {
+			return failMigration("restore of volume %s failed: %s", v.Name, v.Error)
+		}
+		if !v.Restored {
+			return nil
+		}
+	}
+	return w.advance(ctx, m, manman.MigrationStatusVerifying)
+}
+
+// verify checks every restored archive against its backup, copies the source's settings
+// across and then switches the SGCs over
+func (w *sgcMigrationWorker) verify(ctx context.Context, m *manman.SGCMigration) error {
+	for _, v := range m.Volumes {
+		if v.BackupID == 0 {
+			continue
+		}
+		backup, err := w.repo.Backups.Get(ctx, v.BackupID)
+		if err != nil {
+			return fmt.Errorf("failed to get backup %d: %w", v.BackupID, err)
+		}
+		if err := verifyRestoredChecksum(v, backup); err != nil {
+			return err
+		}
+	}
+
+	// Copied last so the target's schedules and alert rules can't act on it while it's
+	// still being restored
+	if err := w.repo.SGCMigrations.CopySGCSettings(ctx, m.SourceSGCID, *m.TargetSGCID); err != nil {
+		return fmt.Errorf("failed to copy settings to target SGC: %w", err)
+	}
+
+	target, err := w.repo.ServerGameConfigs.Get(ctx, *m.TargetSGCID)
+	if err != nil {
+		return failMigration("target server game config not found: %v", err)
+	}
+	target.Status = m.SourceStatus
+	if err := w.repo.ServerGameConfigs.Update(ctx, target); err != nil {
+		return fmt.Errorf("failed to activate target SGC: %w", err)
+	}
+	if err := w.setSourceStatus(ctx, m, manman.SGCStatusMigrated); err != nil {
+		return err
+	}
+	if err := w.advance(ctx, m, manman.MigrationStatusCompleted); err != nil {
+		return err
+	}
+	w.logger.Info("migration completed", "migration_id", m.MigrationID, "source_sgc_id", m.SourceSGCID, "target_sgc_id", target.SGCID)
+
+	if m.StartSession {
+		resp, err := w.apiClient.StartSession(ctx, &pb.StartSessionRequest{ServerGameConfigId: target.SGCID})
+		if err != nil {
+			// The data is across; a failed start is for the operator to retry
+			w.logger.Error("failed to start migrated SGC", "migration_id", m.MigrationID, "sgc_id", target.SGCID, "error", err)
+			return nil
+		}
+		w.logger.Info("migration started target session", "migration_id", m.MigrationID, "session_id", resp.Session.SessionId)
+	}
+	return nil
+}
+
+// verifyRestoredChecksum fails unless the archive the target restored is the one the source uploaded
+func verifyRestoredChecksum(v manman.MigrationVolume, backup *manman.Backup) error {
+	if backup.Checksum == nil {
+		return failMigration("backup of volume %s has no checksum", v.Name)
+	}
+	if v.RestoredChecksum == "" {
+		return failMigration("target did not report a checksum for volume %s", v.Name)
+	}
+	if !strings.EqualFold(v.RestoredChecksum, *backup.Checksum) {
+		return failMigration("checksum mismatch for volume %s: backed up %s, restored %s", v.Name, *backup.Checksum, v.RestoredChecksum)
+	}
+	return nil
+}
+
+// fail puts the source SGC back as it was, removes a half-built target and records reason
+func (w *sgcMigrationWorker) fail(ctx context.Context, m *manman.SGCMigration, reason string) error {
+	w.logger.Error("migration failed", "migration_id", m.MigrationID, "sgc_id", m.SourceSGCID, "status", m.Status, "reason", reason)
+
+	if err := w.setSourceStatus(ctx, m, m.SourceStatus); err != nil {
+		return err
+	}
+	if m.TargetSGCID != nil {
+		// Restored files stay on the target host; only the record goes
+		if err := w.repo.ServerGameConfigs.Delete(ctx, *m.TargetSGCID); err != nil {
+			w.logger.Warn("failed to delete target SGC of failed migration", "migration_id", m.MigrationID, "sgc_id", *m.TargetSGCID, "error", err)
+		} else {
+			m.TargetSGCID = nil
+		}
+	}
+	m.ErrorMessage = &reason
+	return w.advance(ctx, m, manman.MigrationStatusFailed)
+}
+
+func (w *sgcMigrationWorker) setSourceStatus(ctx context.Context, m *manman.SGCMigration, status string) error {
+	source, err := w.repo.ServerGameConfigs.Get(ctx, m.SourceSGCID)
+	if err != nil {
+		return fmt.Errorf("failed to get source SGC %d: %w", m.SourceSGCID, err)
+	}
+	source.Status = status
+	if err := w.repo.ServerGameConfigs.Update(ctx, source); err != nil {
+		return fmt.Errorf("failed to set source SGC %d %s: %w", m.SourceSGCID, status, err)
+	}
+	return nil
+}
+
+// advance saves m at status and announces it on the external exchange
+func (w *sgcMigrationWorker) advance(ctx context.Context, m *manman.SGCMigration, status string) error {
+	m.Status = status
+	if err := w.repo.SGCMigrations.Update(ctx, m); err != nil {
+		return fmt.Errorf("failed to update migration %d: %w", m.MigrationID, err)
+	}
+	m.UpdatedAt = time.Now()
+
+	event := hostrmq.MigrationEvent{
+		MigrationID:    m.MigrationID,
+		SGCID:          m.SourceSGCID,
+		SourceServerID: m.SourceServerID,
+		TargetServerID: m.TargetServerID,
+		Status:         m.Status,
+		ErrorMessage:   m.ErrorMessage,
+	}
+	if m.TargetSGCID != nil {
+		event.TargetSGCID = *m.TargetSGCID
+	}
+	if err := w.events.PublishExternal(ctx, fmt.Sprintf("manman.migration.%s", m.Status), event); err != nil {
+		w.logger.Error("failed to publish migration status to external exchange", "migration_id", m.MigrationID, "error", err)
+	}
+	return nil
+}
+
+// migrationPortBindings converts the ports picked for the target to the SGC's JSONB form
+func migrationPortBindings(bindings []manman.PortBinding) manman.JSONB {
+	if len(bindings) == 0 {
+		return nil
+	}
+	result := make(manman.JSONB, len(bindings))
+	for _, b := range bindings {
+		result[fmt.Sprintf("%d/%s", b.ContainerPort, b.Protocol)] = float64(b.HostPort)
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/whale-net/everything/manmanv2/models"
+)
+
+func TestVerifyRestoredChecksum(t *testing.T) {
+	sum := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	tests := []struct {
+		name     string
+		backup   *string
+		restored string
+		wantErr  string
+	}{
+		{name: "match", backup: &sum, restored: sum},
+		{name: "match ignores case", backup: &sum, restored: strings.ToUpper(sum)},
+		{name: "mismatch", backup: &sum, restored: strings.Repeat("0", 64), wantErr: "checksum mismatch"},
+		{name: "backup without checksum", restored: sum, wantErr: "has no checksum"},
+		{name: "restore without checksum", backup: &sum, wantErr: "did not report a checksum"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := manman.MigrationVolume{Name: "data", BackupID: 1, Restored: true, RestoredChecksum: tt.restored}
+			err := verifyRestoredChecksum(v, &manman.Backup{BackupID: 1, Checksum: tt.backup})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			var failure *migrationFailure
+			if !errors.As(err, &failure) {
+				t.Fatalf("expected a migration failure, got %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q does not contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationPortBindings(t *testing.T) {
+	got := migrationPortBindings([]manman.PortBinding{
+		{ContainerPort: 25565, HostPort: 25570, Protocol: "TCP"},
+		{ContainerPort: 19132, HostPort: 19140, Protocol: "UDP"},
+	})
+	if len(got) != 2 || got["25565/TCP"] != float64(25570) || got["19132/UDP"] != float64(19140) {
+		t.Errorf("unexpected bindings: %v", got)
+	}
+	if migrationPortBindings(nil) != nil {
+		t.Error("expected nil bindings for no ports")
+	}
+}
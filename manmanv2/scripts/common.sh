@@ -203,6 +203,32 @@ for c in configs:
 print(found)' "${config_id}" <<< "${resp}"
 }
 
+# ensure_strategy <game_id> <name> <strategy_type> <target_path> <base_template> <apply_order>
+# Prints the strategy_id of the game's configuration strategy with the given name, creating
+# it with the given base template when it doesn't exist yet.
+ensure_strategy() {
+  local game_id="${1}"
+  local name="${2}"
+  local find_strategy='import json,sys; data=json.loads(sys.stdin.read() or "{}");
+for s in data.get("strategies", []):
+    if s.get("name") == sys.argv[1]:
+        print(s.get("strategyId") or s.get("strategy_id") or "")
+        break'
+  local resp
+  resp="$(grpc_call "${CONTROL_API_ADDR}" "manman.v1.ManManAPI/ListConfigurationStrategies" "{\"game_id\": ${game_id}}" 2>&1 || true)"
+  local found
+  found="$(python3 -c "${find_strategy}" "${name}" <<< "${resp}" 2>/dev/null || true)"
+  if [[ -n "${found}" ]]; then
+    echo "${found}"
+    return 0
+  fi
+
+  local payload
+  payload="$(python3 -c 'import json,sys; print(json.dumps({"game_id": int(sys.argv[1]), "name": sys.argv[2], "strategy_type": sys.argv[3], "target_path": sys.argv[4], "base_template": sys.argv[5], "apply_order": int(sys.argv[6])}))' "$@")"
+  resp="$(grpc_call "${CONTROL_API_ADDR}" "manman.v1.ManManAPI/CreateConfigurationStrategy" "${payload}" 2>&1 || true)"
+  python3 -c 'import json,sys; data=json.loads(sys.stdin.read() or "{}"); s=data.get("strategy", {}); print(s.get("strategyId") or s.get("strategy_id") or "")' <<< "${resp}" 2>/dev/null || true
+}
+
 # create_action <json_payload>
 # Calls CreateActionDefinition and prints success/failure.
 create_action() {
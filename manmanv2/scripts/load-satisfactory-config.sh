@@ -124,16 +124,10 @@ if [[ -z "${config_id}" ]]; then
   "image": "${IMAGE}",
   "args_template": "",
   "env_template": {
-    "AUTOSAVENUM": "5",
-    "MAXPLAYERS": "4",
-    "MAXTICKRATE": "30",
     "PGID": "1000",
     "PUID": "1000",
     "SERVERGAMEPORT": "38225",
-    "SERVERMESSAGINGPORT": "38226",
-    "SKIPUPDATE": "false",
-    "STEAMBETA": "false",
-    "TIMEOUT": "30"
+    "SERVERMESSAGINGPORT": "38226"
   },
   "entrypoint": [],
   "command": []
@@ -177,6 +171,22 @@ fi
 echo "✔ Game ID: ${game_id}"
 echo "✔ Config ID: ${config_id}"
 
+# Server settings are an env_vars strategy rather than the config's env_template, so each
+# server can patch its own player limit and save settings without a config of its own.
+echo "Ensuring Satisfactory server settings strategy exists..."
+settings_template="AUTOSAVENUM=5
+MAXPLAYERS=4
+MAXTICKRATE=30
+SKIPUPDATE=false
+STEAMBETA=false
+TIMEOUT=30"
+strategy_id="$(ensure_strategy "${game_id}" "Server Settings" "env_vars" "" "${settings_template}" 1)"
+if [[ -n "${strategy_id}" ]]; then
+  echo "  ✔ Server settings strategy ready (ID: ${strategy_id})"
+else
+  echo "  ⚠️  Could not create or find server settings strategy"
+fi
+
 echo "Checking if config is deployed to default server..."
 sgc_id="$(find_sgc_id "${config_id}")"
 
@@ -275,7 +285,7 @@ else
 fi
 echo ""
 echo "Next steps:"
-echo "  1. Set MAXPLAYERS env var to increase the player limit (default: 4)"
+echo "  1. Patch MAXPLAYERS in the 'Server Settings' strategy to increase the player limit (default: 4)"
 echo "  2. The server uses ~8GB RAM; ensure the host has sufficient memory"
 echo "  3. Run ./scripts/seed_satisfactory_actions.sh to load game actions"
 echo ""
@@ -0,0 +1,37 @@
+package components
+
+import (
+	"github.com/whale-net/everything/manmanv2/api/auth"
+	manmanpb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+// These gate what the UI renders, mirroring the API's role checks (manmanv2/api/auth).
+// They are presentation only: the API rejects the calls regardless. A nil access (the
+// API couldn't be asked) hides every gated control.
+
+// IsAdmin reports whether the caller holds the admin role
+func IsAdmin(access *manmanpb.CallerAccess) bool {
+	return access != nil && auth.ParseLevel(access.Role) >= auth.LevelAdmin
+}
+
+// CanOperate reports whether the caller may start, stop and send input to sgcID's
+// sessions, through the operator role or a grant on that SGC
+func CanOperate(access *manmanpb.CallerAccess, sgcID int64) bool {
+	if access == nil {
+		return false
+	}
+	if auth.ParseLevel(access.Role) >= auth.LevelOperator {
+		return true
+	}
+	for _, id := range access.OperatorSgcIds {
+		if id == sgcID {
+			return true
+		}
+	}
+	return false
+}
+
+// CanOperateAny reports whether the caller may operate at least one SGC
+func CanOperateAny(access *manmanpb.CallerAccess) bool {
+	return access != nil && (auth.ParseLevel(access.Role) >= auth.LevelOperator || len(access.OperatorSgcIds) > 0)
+}
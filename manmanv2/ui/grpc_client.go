@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/whale-net/everything/libs/go/grpcclient"
 	manmanpb "github.com/whale-net/everything/manmanv2/protos"
@@ -82,11 +83,12 @@ func (c *ControlClient) GetGame(ctx context.Context, gameID int64) (*manmanpb.Ga
 }
 
 // CreateGame creates a new game
-func (c *ControlClient) CreateGame(ctx context.Context, name, steamAppID string, metadata *manmanpb.GameMetadata) (*manmanpb.Game, error) {
+func (c *ControlClient) CreateGame(ctx context.Context, name, steamAppID string, metadata *manmanpb.GameMetadata, playerEvents *manmanpb.PlayerEventPatterns) (*manmanpb.Game, error) {
 	resp, err := c.api.CreateGame(ctx, &manmanpb.CreateGameRequest{
-		Name:       name,
-		SteamAppId: steamAppID,
-		Metadata:   metadata,
+		Name:         name,
+		SteamAppId:   steamAppID,
+		Metadata:     metadata,
+		PlayerEvents: playerEvents,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create game: %w", err)
@@ -95,12 +97,13 @@ func (c *ControlClient) CreateGame(ctx context.Context, name, steamAppID string,
 }
 
 // UpdateGame updates an existing game
-func (c *ControlClient) UpdateGame(ctx context.Context, gameID int64, name, steamAppID string, metadata *manmanpb.GameMetadata) (*manmanpb.Game, error) {
+func (c *ControlClient) UpdateGame(ctx context.Context, gameID int64, name, steamAppID string, metadata *manmanpb.GameMetadata, playerEvents *manmanpb.PlayerEventPatterns) (*manmanpb.Game, error) {
 	resp, err := c.api.UpdateGame(ctx, &manmanpb.UpdateGameRequest{
-		GameId:     gameID,
-		Name:       name,
-		SteamAppId: steamAppID,
-		Metadata:   metadata,
+		GameId:       gameID,
+		Name:         name,
+		SteamAppId:   steamAppID,
+		Metadata:     metadata,
+		PlayerEvents: playerEvents,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to update game: %w", err)
@@ -230,18 +233,127 @@ func (c *ControlClient) StopSession(ctx context.Context, sessionID int64) (*manm
 	return resp.Session, nil
 }
 
-// StartSession starts a new session for a server game config.
-func (c *ControlClient) StartSession(ctx context.Context, serverGameConfigID int64, force bool) (*manmanpb.Session, error) {
-	resp, err := c.api.StartSession(ctx, &manmanpb.StartSessionRequest{
-		ServerGameConfigId: serverGameConfigID,
-		Force:              force,
-	})
+// StartSession starts a new session for a server game config, with any overrides set on req.
+func (c *ControlClient) StartSession(ctx context.Context, req *manmanpb.StartSessionRequest) (*manmanpb.Session, error) {
+	resp, err := c.api.StartSession(ctx, req)
 	if err != nil {
 		return nil, fmt.Errorf("failed to start session: %w", err)
 	}
 	return resp.Session, nil
 }
 
+// ListSGCSchedules lists the start/stop schedules of a server game config.
+func (c *ControlClient) ListSGCSchedules(ctx context.Context, sgcID int64) ([]*manmanpb.SGCSchedule, error) {
+	resp, err := c.api.ListSGCSchedules(ctx, &manmanpb.ListSGCSchedulesRequest{
+		ServerGameConfigId: sgcID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list schedules: %w", err)
+	}
+	return resp.Schedules, nil
+}
+
+// MigrateServerGameConfig starts moving a server game config to another server.
+// A targetServerID of 0 lets the API pick one.
+func (c *ControlClient) MigrateServerGameConfig(ctx context.Context, sgcID, targetServerID int64, startSession bool) (*manmanpb.SGCMigration, error) {
+	resp, err := c.api.MigrateServerGameConfig(ctx, &manmanpb.MigrateServerGameConfigRequest{
+		ServerGameConfigId: sgcID,
+		TargetServerId:     targetServerID,
+		StartSession:       startSession,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate server game config: %w", err)
+	}
+	return resp.Migration, nil
+}
+
+// ListSGCMigrations lists the migrations a server game config was the source or target of, newest first.
+func (c *ControlClient) ListSGCMigrations(ctx context.Context, sgcID int64) ([]*manmanpb.SGCMigration, error) {
+	resp, err := c.api.ListSGCMigrations(ctx, &manmanpb.ListSGCMigrationsRequest{
+		ServerGameConfigId: sgcID,
+		PageSize:           10,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list migrations: %w", err)
+	}
+	return resp.Migrations, nil
+}
+
+// GetCallerAccess reports what the logged-in user may do.
+func (c *ControlClient) GetCallerAccess(ctx context.Context) (*manmanpb.CallerAccess, error) {
+	resp, err := c.api.GetCallerAccess(ctx, &manmanpb.GetCallerAccessRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get caller access: %w", err)
+	}
+	return resp.Access, nil
+}
+
+// ListSGCGrants lists the per-SGC operator grants on a server game config.
+func (c *ControlClient) ListSGCGrants(ctx context.Context, sgcID int64) ([]*manmanpb.SGCGrant, error) {
+	resp, err := c.api.ListSGCGrants(ctx, &manmanpb.ListSGCGrantsRequest{
+		ServerGameConfigId: sgcID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list grants: %w", err)
+	}
+	return resp.Grants, nil
+}
+
+// CreateSGCGrant lets subject operate a server game config.
+func (c *ControlClient) CreateSGCGrant(ctx context.Context, sgcID int64, subject, note string) (*manmanpb.SGCGrant, error) {
+	resp, err := c.api.CreateSGCGrant(ctx, &manmanpb.CreateSGCGrantRequest{
+		ServerGameConfigId: sgcID,
+		Subject:            subject,
+		Note:               note,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grant: %w", err)
+	}
+	return resp.Grant, nil
+}
+
+// DeleteSGCGrant revokes a grant.
+func (c *ControlClient) DeleteSGCGrant(ctx context.Context, grantID int64) error {
+	_, err := c.api.DeleteSGCGrant(ctx, &manmanpb.DeleteSGCGrantRequest{GrantId: grantID})
+	if err != nil {
+		return fmt.Errorf("failed to delete grant: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEvents retrieves a page of the audit log, newest first.
+func (c *ControlClient) ListAuditEvents(ctx context.Context, req *manmanpb.ListAuditEventsRequest) (*manmanpb.ListAuditEventsResponse, error) {
+	resp, err := c.api.ListAuditEvents(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	return resp, nil
+}
+
+// ListSessionPlayers lists the players seen in a session's logs.
+func (c *ControlClient) ListSessionPlayers(ctx context.Context, sessionID int64, onlineOnly bool) ([]*manmanpb.PlayerSession, error) {
+	resp, err := c.api.ListSessionPlayers(ctx, &manmanpb.ListSessionPlayersRequest{
+		SessionId:  sessionID,
+		OnlineOnly: onlineOnly,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list session players: %w", err)
+	}
+	return resp.Players, nil
+}
+
+// GetPlayerStats summarises who played on a server game config since the given time.
+func (c *ControlClient) GetPlayerStats(ctx context.Context, sgcID int64, since time.Time) (*manmanpb.PlayerStats, error) {
+	resp, err := c.api.GetPlayerStats(ctx, &manmanpb.GetPlayerStatsRequest{
+		ServerGameConfigId: sgcID,
+		Since:              since.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get player stats: %w", err)
+	}
+	return resp.Stats, nil
+}
+
 // ListConfigurationStrategies retrieves all strategies for a game.
 func (c *ControlClient) ListConfigurationStrategies(ctx context.Context, req *manmanpb.ListConfigurationStrategiesRequest) (*manmanpb.ListConfigurationStrategiesResponse, error) {
 	return c.api.ListConfigurationStrategies(ctx, req)
@@ -316,7 +428,7 @@ func (c *ControlClient) ListServerGameConfigs(ctx context.Context, serverID int6
 	return resp.Configs, nil
 }
 
-// DeployGameConfig deploys a game config to a server.
+// DeployGameConfig deploys a game config to a server; serverID 0 lets the API place it.
 func (c *ControlClient) DeployGameConfig(ctx context.Context, serverID, gameConfigID int64) (*manmanpb.ServerGameConfig, error) {
 	resp, err := c.api.DeployGameConfig(ctx, &manmanpb.DeployGameConfigRequest{
 		ServerId:     serverID,
@@ -340,6 +452,15 @@ func (c *ControlClient) SendInput(ctx context.Context, sessionID int64, input []
 	return resp, nil
 }
 
+// AttachSession opens a session console; the first message sent must name the session
+func (c *ControlClient) AttachSession(ctx context.Context) (manmanpb.ManManAPI_AttachSessionClient, error) {
+	stream, err := c.api.AttachSession(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to attach to session: %w", err)
+	}
+	return stream, nil
+}
+
 // GetSessionActions retrieves available actions for a session
 func (c *ControlClient) GetSessionActions(ctx context.Context, sessionID int64) ([]*manmanpb.ActionDefinition, error) {
 	resp, err := c.api.GetSessionActions(ctx, &manmanpb.GetSessionActionsRequest{
@@ -351,6 +472,19 @@ func (c *ControlClient) GetSessionActions(ctx context.Context, sessionID int64)
 	return resp.Actions, nil
 }
 
+// PreviewConfiguration renders a session's configuration with overrides layered over its
+// own patches
+func (c *ControlClient) PreviewConfiguration(ctx context.Context, sessionID int64, overrides []*manmanpb.SessionPatchOverride) ([]*manmanpb.RenderedConfiguration, error) {
+	resp, err := c.api.PreviewConfiguration(ctx, &manmanpb.PreviewConfigurationRequest{
+		SessionId:          sessionID,
+		ParameterOverrides: overrides,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to preview configuration: %w", err)
+	}
+	return resp.Configurations, nil
+}
+
 // ExecuteAction executes an action on a session
 func (c *ControlClient) ExecuteAction(ctx context.Context, sessionID, actionID int64, inputValues map[string]string) (*manmanpb.ExecuteActionResponse, error) {
 	resp, err := c.api.ExecuteAction(ctx, &manmanpb.ExecuteActionRequest{
@@ -495,7 +629,7 @@ func (c *ControlClient) ResetInstallation(ctx context.Context, installationID in
 	return resp.Installation, nil
 }
 
-func (c *ControlClient) CreateAddon(ctx context.Context, gameID int64, workshopID, platformType, name, description string, fileSizeBytes int64, isCollection bool, installationPath string, presetID int64) (*manmanpb.WorkshopAddon, error) {
+func (c *ControlClient) CreateAddon(ctx context.Context, gameID int64, workshopID, platformType, name, description string, fileSizeBytes int64, isCollection bool, installationPath string, presetID int64, metadata string) (*manmanpb.WorkshopAddon, error) {
 	resp, err := c.workshop.CreateAddon(ctx, &manmanpb.CreateAddonRequest{
 		GameId:           gameID,
 		WorkshopId:       workshopID,
@@ -506,6 +640,7 @@ func (c *ControlClient) CreateAddon(ctx context.Context, gameID int64, workshopI
 		IsCollection:     isCollection,
 		InstallationPath: installationPath,
 		PresetId:         presetID,
+		Metadata:         metadata,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create addon: %w", err)
@@ -791,6 +926,7 @@ func (c *ControlClient) ListBackups(ctx context.Context, sgcID int64) ([]*manman
 	resp, err := c.api.ListBackups(ctx, &manmanpb.ListBackupsRequest{
 		ServerGameConfigId: sgcID,
 		PageSize:           50,
+		IncludePruned:      true,
 	})
 	if err != nil {
 		return nil, err
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/htmxauth"
+	manmanpb "github.com/whale-net/everything/manmanv2/protos"
+	"github.com/whale-net/everything/manmanv2/ui/components"
+	"github.com/whale-net/everything/manmanv2/ui/pages"
+)
+
+// activityWindows are the time ranges offered by the activity feed's filter
+var activityWindows = map[string]time.Duration{
+	"24h": 24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+}
+
+func (app *App) handleActivity(w http.ResponseWriter, r *http.Request) {
+	user := htmxauth.GetUser(r.Context())
+	ctx := r.Context()
+	q := r.URL.Query()
+
+	filters := pages.ActivityFilters{
+		Subject:   strings.TrimSpace(q.Get("subject")),
+		Method:    strings.TrimSpace(q.Get("method")),
+		EntityKey: strings.TrimSpace(q.Get("entity_key")),
+		EntityID:  strings.TrimSpace(q.Get("entity_id")),
+		Window:    q.Get("window"),
+	}
+
+	req := &manmanpb.ListAuditEventsRequest{
+		Subject:   filters.Subject,
+		Method:    filters.Method,
+		PageSize:  50,
+		PageToken: q.Get("page_token"),
+	}
+	if filters.EntityKey != "" && filters.EntityID != "" {
+		entityID, err := strconv.ParseInt(filters.EntityID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid entity_id", http.StatusBadRequest)
+			return
+		}
+		req.EntityKey = filters.EntityKey
+		req.EntityId = entityID
+	}
+	if d, ok := activityWindows[filters.Window]; ok {
+		req.Since = time.Now().Add(-d).Unix()
+	}
+
+	resp, err := app.grpc.ListAuditEvents(ctx, req)
+	if err != nil {
+		log.Printf("Error fetching audit events: %v", err)
+		http.Error(w, "Failed to fetch activity", http.StatusInternalServerError)
+		return
+	}
+
+	breadcrumbs := []components.Breadcrumb{
+		{Label: "Activity", URL: "/activity"},
+	}
+	layoutData, err := app.buildTemplLayoutData(r, "Activity", "Activity", user, breadcrumbs)
+	if err != nil {
+		log.Printf("Error building layout data: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := pages.ActivityPageData{
+		Events:  resp.Events,
+		Filters: filters,
+	}
+	if resp.NextPageToken != "" {
+		next := r.URL.Query()
+		next.Set("page_token", resp.NextPageToken)
+		data.NextPageURL = "/activity?" + next.Encode()
+	}
+
+	if err := RenderTempl(w, r, "Activity", pages.Activity(layoutData, data)); err != nil {
+		log.Printf("Error rendering template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/whale-net/everything/libs/go/htmxauth"
+	manmanpb "github.com/whale-net/everything/manmanv2/protos"
+)
+
+// Browsers can't speak gRPC streams, so each console page holds an SSE stream for
+// output and POSTs its input; both ends meet at one AttachSession stream, found by
+// the console ID the SSE stream announces when it connects.
+
+type console struct {
+	sessionID int64
+	owner     string // sub of the user who opened it
+	stream    manmanpb.ManManAPI_AttachSessionClient
+	sendMu    sync.Mutex
+}
+
+type consoleRegistry struct {
+	mu       sync.Mutex
+	consoles map[string]*console
+}
+
+func newConsoleRegistry() *consoleRegistry {
+	return &consoleRegistry{consoles: make(map[string]*console)}
+}
+
+func (r *consoleRegistry) add(c *console) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(b)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.consoles[id] = c
+	return id, nil
+}
+
+func (r *consoleRegistry) get(id string) *console {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.consoles[id]
+}
+
+func (r *consoleRegistry) remove(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.consoles, id)
+}
+
+// handleSessionConsoleStream attaches to a session and relays its console via Server-Sent Events
+func (app *App) handleSessionConsoleStream(w http.ResponseWriter, r *http.Request, sessionID int64) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	// The server-level WriteTimeout would end the stream; the keepalive handles proxies
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("Failed to disable write deadline for console stream (session %d): %v", sessionID, err)
+	}
+
+	// Parse optional after_sequence_number for reconnect deduplication
+	var afterSequenceNumber int64
+	if s := r.URL.Query().Get("after_sequence_number"); s != "" {
+		afterSequenceNumber, _ = strconv.ParseInt(s, 10, 64)
+	}
+
+	stream, err := app.grpc.AttachSession(r.Context())
+	if err != nil {
+		log.Printf("Failed to attach to session %d: %v", sessionID, err)
+		http.Error(w, "Failed to attach to session", http.StatusInternalServerError)
+		return
+	}
+	if err := stream.Send(&manmanpb.AttachSessionRequest{SessionId: sessionID, AfterSequenceNumber: afterSequenceNumber}); err != nil {
+		log.Printf("Failed to attach to session %d: %v", sessionID, err)
+		http.Error(w, "Failed to attach to session", http.StatusInternalServerError)
+		return
+	}
+
+	c := &console{sessionID: sessionID, stream: stream}
+	if user := htmxauth.GetUser(r.Context()); user != nil {
+		c.owner = user.Sub
+	}
+	consoleID, err := app.consoles.add(c)
+	if err != nil {
+		http.Error(w, "Failed to open console", http.StatusInternalServerError)
+		return
+	}
+	defer app.consoles.remove(consoleID)
+
+	writeEvent(w, map[string]interface{}{"type": "connected", "console_id": consoleID})
+	flusher.Flush()
+
+	type recvResult struct {
+		ev  *manmanpb.AttachSessionEvent
+		err error
+	}
+	recvCh := make(chan recvResult, 1)
+	go func() {
+		for {
+			ev, err := stream.Recv()
+			select {
+			case recvCh <- recvResult{ev, err}:
+			case <-r.Context().Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			fmt.Fprintf(w, ": keepalive\n\n")
+			flusher.Flush()
+		case result := <-recvCh:
+			if result.err != nil {
+				if result.err != io.EOF && r.Context().Err() == nil {
+					log.Printf("Console stream for session %d ended: %v", sessionID, result.err)
+					writeEvent(w, map[string]interface{}{"type": "error", "error": result.err.Error()})
+					flusher.Flush()
+				}
+				return
+			}
+			writeEvent(w, consoleEventJSON(result.ev))
+			flusher.Flush()
+		}
+	}
+}
+
+// handleSessionConsoleInput sends a line typed into a console page to its AttachSession stream
+func (app *App) handleSessionConsoleInput(w http.ResponseWriter, r *http.Request, sessionID int64) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ConsoleID string `json:"console_id"`
+		Input     string `json:"input"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Input) == "" {
+		http.Error(w, "Input cannot be empty", http.StatusBadRequest)
+		return
+	}
+
+	c := app.consoles.get(req.ConsoleID)
+	user := htmxauth.GetUser(r.Context())
+	if c == nil || c.sessionID != sessionID || (user != nil && c.owner != user.Sub) {
+		http.Error(w, "Console not found; reconnect and try again", http.StatusNotFound)
+		return
+	}
+
+	// The API echoes the line, or why it was refused, on the console stream
+	c.sendMu.Lock()
+	err := c.stream.Send(&manmanpb.AttachSessionRequest{Input: []byte(req.Input)})
+	c.sendMu.Unlock()
+	if err != nil {
+		log.Printf("Error sending console input to session %d: %v", sessionID, err)
+		http.Error(w, fmt.Sprintf("Failed to send input: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// consoleEventJSON flattens an AttachSessionEvent for the console page's script
+func consoleEventJSON(ev *manmanpb.AttachSessionEvent) map[string]interface{} {
+	switch {
+	case ev.Output != nil:
+		return map[string]interface{}{
+			"type":            "output",
+			"timestamp":       ev.Output.Timestamp,
+			"source":          ev.Output.Source,
+			"message":         ev.Output.Message,
+			"sequence_number": ev.Output.SequenceNumber,
+		}
+	case ev.Input != nil:
+		return map[string]interface{}{
+			"type":      "input",
+			"timestamp": ev.Input.Timestamp,
+			"username":  ev.Input.Username,
+			"input":     ev.Input.Input,
+			"error":     ev.Input.Error,
+		}
+	case ev.Attendees != nil:
+		return map[string]interface{}{
+			"type":      "attendees",
+			"usernames": ev.Attendees.Usernames,
+		}
+	default:
+		return map[string]interface{}{"type": "unknown"}
+	}
+}
+
+func writeEvent(w io.Writer, data map[string]interface{}) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Failed to marshal console event: %v", err)
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", jsonData)
+}
@@ -124,8 +124,13 @@ func (app *App) handleGameCreate(w http.ResponseWriter, r *http.Request) {
 		Publisher: publisher,
 		Tags:      tagList,
 	}
+	playerEvents := &manmanpb.PlayerEventPatterns{
+		JoinPattern:  strings.TrimSpace(r.FormValue("join_pattern")),
+		LeavePattern: strings.TrimSpace(r.FormValue("leave_pattern")),
+		ChatPattern:  strings.TrimSpace(r.FormValue("chat_pattern")),
+	}
 	
-	game, err := app.grpc.CreateGame(ctx, name, steamAppID, metadata)
+	game, err := app.grpc.CreateGame(ctx, name, steamAppID, metadata, playerEvents)
 	if err != nil {
 		log.Printf("Error creating game: %v", err)
 		http.Error(w, "Failed to create game", http.StatusInternalServerError)
@@ -304,8 +309,13 @@ func (app *App) handleGameEdit(w http.ResponseWriter, r *http.Request, gameIDStr
 		Publisher: publisher,
 		Tags:      tagList,
 	}
+	playerEvents := &manmanpb.PlayerEventPatterns{
+		JoinPattern:  strings.TrimSpace(r.FormValue("join_pattern")),
+		LeavePattern: strings.TrimSpace(r.FormValue("leave_pattern")),
+		ChatPattern:  strings.TrimSpace(r.FormValue("chat_pattern")),
+	}
 	
-	_, err = app.grpc.UpdateGame(ctx, gameID, name, steamAppID, metadata)
+	_, err = app.grpc.UpdateGame(ctx, gameID, name, steamAppID, metadata, playerEvents)
 	if err != nil {
 		log.Printf("Error updating game: %v", err)
 		http.Error(w, "Failed to update game", http.StatusInternalServerError)
@@ -567,10 +577,14 @@ func (app *App) handleGameConfigDeploy(w http.ResponseWriter, r *http.Request, g
 		return
 	}
 
-	serverID, err := strconv.ParseInt(serverIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid server_id", http.StatusBadRequest)
-		return
+	// "auto" leaves server_id at 0 so the API places the config on the best online server
+	var serverID int64
+	if serverIDStr != "auto" {
+		serverID, err = strconv.ParseInt(serverIDStr, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid server_id", http.StatusBadRequest)
+			return
+		}
 	}
 
 	ctx := r.Context()
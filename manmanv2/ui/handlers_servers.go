@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/whale-net/everything/libs/go/htmxauth"
 	"github.com/whale-net/everything/manmanv2/ui/components"
@@ -97,6 +98,18 @@ func (app *App) handleServerDetail(w http.ResponseWriter, r *http.Request) {
 		configsResp = &manmanpb.ListServerGameConfigsResponse{Configs: []*manmanpb.ServerGameConfig{}}
 	}
 	
+	// Resource usage over the last day; the page still renders without it
+	now := time.Now()
+	metrics := pages.ServerMetricsData{Start: now.Add(-24 * time.Hour).Unix(), End: now.Unix()}
+	metrics.Metrics, err = app.grpc.GetAPI().GetServerMetrics(ctx, &manmanpb.GetServerMetricsRequest{
+		ServerId:       serverID,
+		StartTimestamp: metrics.Start,
+		EndTimestamp:   metrics.End,
+	})
+	if err != nil {
+		log.Printf("Error fetching server metrics: %v", err)
+	}
+
 	breadcrumbs := []components.Breadcrumb{
 		{Label: "Servers", URL: "/servers"},
 		{Label: resp.Server.Name, URL: ""},
@@ -109,7 +122,7 @@ func (app *App) handleServerDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := RenderTempl(w, r, resp.Server.Name, pages.ServerDetail(layoutData, resp.Server, configsResp.Configs)); err != nil {
+	if err := RenderTempl(w, r, resp.Server.Name, pages.ServerDetail(layoutData, resp.Server, configsResp.Configs, metrics)); err != nil {
 		log.Printf("Error rendering template: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
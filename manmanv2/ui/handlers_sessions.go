@@ -1,13 +1,12 @@
 package main
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"log"
 	"net/http"
 	"net/url"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -36,6 +35,8 @@ type SessionDetailPageData struct {
 	Actions       []*manmanpb.ActionDefinition
 	Libraries     []*manmanpb.WorkshopLibrary
 	Installations []*manmanpb.WorkshopInstallation
+	Players       []*manmanpb.PlayerSession
+	PlayerStats   *manmanpb.PlayerStats
 }
 
 func (app *App) handleSessions(w http.ResponseWriter, r *http.Request) {
@@ -222,8 +223,18 @@ func (app *App) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(pathParts) > 3 && pathParts[2] == "logs" && pathParts[3] == "stream" {
-		app.handleSessionLogsStream(w, r)
+	if len(pathParts) > 3 && pathParts[2] == "console" && pathParts[3] == "stream" {
+		app.handleSessionConsoleStream(w, r, sessionID)
+		return
+	}
+
+	if len(pathParts) > 3 && pathParts[2] == "console" && pathParts[3] == "input" {
+		app.handleSessionConsoleInput(w, r, sessionID)
+		return
+	}
+
+	if len(pathParts) > 2 && pathParts[2] == "configuration" {
+		app.handleSessionConfiguration(w, r, sessionID)
 		return
 	}
 
@@ -261,6 +272,7 @@ func (app *App) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 	var game *manmanpb.Game
 	var libraries []*manmanpb.WorkshopLibrary
 	var installations []*manmanpb.WorkshopInstallation
+	var playerStats *manmanpb.PlayerStats
 
 	if sessionResp.Session.ServerGameConfigId != 0 {
 		sgcResp, err := app.grpc.GetAPI().GetServerGameConfig(ctx, &manmanpb.GetServerGameConfigRequest{
@@ -301,6 +313,19 @@ func (app *App) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 			log.Printf("Warning: failed to list workshop installations for session detail: %v", err)
 			installations = []*manmanpb.WorkshopInstallation{}
 		}
+
+		// Fetch player concurrency for the last day
+		playerStats, err = app.grpc.GetPlayerStats(ctx, sessionResp.Session.ServerGameConfigId, time.Now().Add(-24*time.Hour))
+		if err != nil {
+			log.Printf("Warning: failed to get player stats for session detail: %v", err)
+		}
+	}
+
+	// Fetch the session's player roster
+	players, err := app.grpc.ListSessionPlayers(ctx, sessionID, false)
+	if err != nil {
+		log.Printf("Warning: failed to list session players for session detail: %v", err)
+		players = []*manmanpb.PlayerSession{}
 	}
 
 	breadcrumbs := []components.Breadcrumb{
@@ -323,6 +348,8 @@ func (app *App) handleSessionDetail(w http.ResponseWriter, r *http.Request) {
 		Actions:       actions,
 		Libraries:     libraries,
 		Installations: installations,
+		Players:       players,
+		PlayerStats:   playerStats,
 	}
 
 	RenderTempl(w, r, "Session "+sessionIDStr, pages.SessionDetail(pageData))
@@ -467,10 +494,17 @@ func (app *App) handleSessionStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	force := r.FormValue("force") == "true"
+	req := &manmanpb.StartSessionRequest{
+		ServerGameConfigId: serverGameConfigID,
+		Force:              r.FormValue("force") == "true",
+	}
+	err = parseStartOverrides(r.PostForm, req)
 
 	ctx := r.Context()
-	session, err := app.grpc.StartSession(ctx, serverGameConfigID, force)
+	var session *manmanpb.Session
+	if err == nil {
+		session, err = app.grpc.StartSession(ctx, req)
+	}
 	if err != nil {
 		log.Printf("Error starting session: %v", err)
 
@@ -511,6 +545,87 @@ func (app *App) handleSessionStart(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+// parseStartOverrides reads the start dialog's "start with overrides" fields into req:
+// image_tag, env as KEY=value lines, and patch_<strategy_id> in the strategy's own format.
+// Blank fields are left out.
+func parseStartOverrides(form url.Values, req *manmanpb.StartSessionRequest) error {
+	req.ImageTag = strings.TrimSpace(form.Get("image_tag"))
+
+	for _, line := range strings.Split(form.Get("env"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return fmt.Errorf("invalid env line %q, expected KEY=value", line)
+		}
+		if req.Env == nil {
+			req.Env = make(map[string]string)
+		}
+		req.Env[strings.TrimSpace(name)] = value
+	}
+
+	patches, err := parsePatchOverrides(form)
+	if err != nil {
+		return err
+	}
+	req.Patches = patches
+	return nil
+}
+
+// parsePatchOverrides reads the non-empty patch_<strategy ID> fields of a form
+func parsePatchOverrides(form url.Values) ([]*manmanpb.SessionPatchOverride, error) {
+	var patches []*manmanpb.SessionPatchOverride
+	for field, values := range form {
+		idStr, ok := strings.CutPrefix(field, "patch_")
+		if !ok || len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		strategyID, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid patch field %q", field)
+		}
+		patches = append(patches, &manmanpb.SessionPatchOverride{
+			StrategyId:   strategyID,
+			PatchContent: values[0],
+		})
+	}
+	// Map order is random; keep the saved patches stable
+	sort.Slice(patches, func(i, j int) bool { return patches[i].StrategyId < patches[j].StrategyId })
+	return patches, nil
+}
+
+// handleSessionConfiguration renders the session's configuration layer by layer. A POST
+// previews the form's overrides layered over the session's own patches without saving them.
+func (app *App) handleSessionConfiguration(w http.ResponseWriter, r *http.Request, sessionID int64) {
+	var overrides []*manmanpb.SessionPatchOverride
+	if r.Method == http.MethodPost {
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "Invalid form data", http.StatusBadRequest)
+			return
+		}
+		var err error
+		overrides, err = parsePatchOverrides(r.PostForm)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	entered := make(map[int64]string, len(overrides))
+	for _, o := range overrides {
+		entered[o.StrategyId] = o.PatchContent
+	}
+
+	configs, err := app.grpc.PreviewConfiguration(r.Context(), sessionID, overrides)
+	if err != nil {
+		log.Printf("Error previewing configuration of session %d: %v", sessionID, err)
+		pages.SessionConfiguration(sessionID, nil, entered, err.Error()).Render(r.Context(), w)
+		return
+	}
+	pages.SessionConfiguration(sessionID, configs, entered, "").Render(r.Context(), w)
+}
+
 // handleCheckActiveSession returns an HTML fragment indicating if there's an active session for the given SGC.
 func (app *App) handleCheckActiveSession(w http.ResponseWriter, r *http.Request) {
 	sgcIDStr := strings.TrimSpace(r.URL.Query().Get("server_game_config_id"))
@@ -575,121 +690,6 @@ func splitCSV(value string) []string {
 	return result
 }
 
-// handleSessionLogsStream streams logs for a session via Server-Sent Events (SSE)
-func (app *App) handleSessionLogsStream(w http.ResponseWriter, r *http.Request) {
-	// Extract session ID from URL path
-	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(pathParts) < 4 || pathParts[2] != "logs" || pathParts[3] != "stream" {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
-	}
-
-	sessionIDStr := pathParts[1]
-	sessionID, err := strconv.ParseInt(sessionIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid session ID", http.StatusBadRequest)
-		return
-	}
-
-	// Set SSE headers
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("X-Accel-Buffering", "no") // Disable nginx buffering
-
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
-		return
-	}
-
-	// Disable the server-level WriteTimeout for this long-lived SSE connection.
-	// WriteTimeout applies to the entire handler duration, which would kill the
-	// stream after 15s. The SSE keepalive comment handles proxy idle timeouts.
-	rc := http.NewResponseController(w)
-	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
-		log.Printf("Failed to disable write deadline for SSE stream (session %d): %v", sessionID, err)
-	}
-
-	// Parse optional after_sequence_number for reconnect deduplication
-	var afterSequenceNumber int64
-	if s := r.URL.Query().Get("after_sequence_number"); s != "" {
-		afterSequenceNumber, _ = strconv.ParseInt(s, 10, 64)
-	}
-
-	// Create gRPC stream directly to log-processor
-	stream, err := app.logProcessor.StreamSessionLogs(r.Context(), &manmanpb.StreamSessionLogsRequest{
-		SessionId:           sessionID,
-		AfterSequenceNumber: afterSequenceNumber,
-	})
-	if err != nil {
-		log.Printf("Failed to create log stream for session %d: %v", sessionID, err)
-		http.Error(w, "Failed to start log stream", http.StatusInternalServerError)
-		return
-	}
-
-	// Send connection acknowledgment
-	fmt.Fprintf(w, "data: {\"type\":\"connected\"}\n\n")
-	flusher.Flush()
-
-	// Receive gRPC messages in a goroutine so we can interleave with keepalive ticks.
-	type recvResult struct {
-		msg *manmanpb.LogMessage
-		err error
-	}
-	recvCh := make(chan recvResult, 1)
-	go func() {
-		for {
-			msg, err := stream.Recv()
-			recvCh <- recvResult{msg, err}
-			if err != nil {
-				return
-			}
-		}
-	}()
-
-	keepalive := time.NewTicker(30 * time.Second)
-	defer keepalive.Stop()
-
-	// Stream logs as SSE events
-	for {
-		select {
-		case <-r.Context().Done():
-			return
-		case <-keepalive.C:
-			// SSE comment keeps the connection alive through proxies
-			fmt.Fprintf(w, ": keepalive\n\n")
-			flusher.Flush()
-		case result := <-recvCh:
-			if result.err != nil {
-				if result.err == io.EOF {
-					log.Printf("Log stream closed for session %d", sessionID)
-				} else if r.Context().Err() == nil {
-					// Only log if it wasn't a client disconnect
-					log.Printf("Error receiving log for session %d: %v", sessionID, result.err)
-				}
-				return
-			}
-
-			// Format as JSON for easier client parsing
-			data := map[string]interface{}{
-				"timestamp":       result.msg.Timestamp,
-				"source":          result.msg.Source,
-				"message":         result.msg.Message,
-				"sequence_number": result.msg.SequenceNumber,
-			}
-			jsonData, err := json.Marshal(data)
-			if err != nil {
-				log.Printf("Failed to marshal log message: %v", err)
-				continue
-			}
-
-			fmt.Fprintf(w, "data: %s\n\n", jsonData)
-			flusher.Flush()
-		}
-	}
-}
-
 // handleHistoricalLogs handles API requests for historical logs
 func (app *App) handleHistoricalLogs(w http.ResponseWriter, r *http.Request) {
 	// Parse query parameters
@@ -899,60 +899,3 @@ func (app *App) handleLoadHistoricalLogs(w http.ResponseWriter, r *http.Request)
 		</div>`, sessionID, startTimestamp, endTimestamp, nextOffset, limit, resp.TotalLines-int32(offset)-limit)
 	}
 }
-
-// handleSessionStdin handles API requests to send stdin to a session
-func (app *App) handleSessionStdin(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Extract session ID from URL path
-	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
-	if len(pathParts) < 3 || pathParts[0] != "api" || pathParts[1] != "sessions" {
-		http.Error(w, "Invalid URL", http.StatusBadRequest)
-		return
-	}
-
-	sessionIDStr := pathParts[2]
-	sessionID, err := strconv.ParseInt(sessionIDStr, 10, 64)
-	if err != nil {
-		http.Error(w, "Invalid session ID", http.StatusBadRequest)
-		return
-	}
-
-	// Parse JSON body
-	var req struct {
-		Input string `json:"input"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
-		return
-	}
-
-	if req.Input == "" {
-		http.Error(w, "Input cannot be empty", http.StatusBadRequest)
-		return
-	}
-
-	// Add newline to input if not present (common for stdin)
-	input := req.Input
-	if !strings.HasSuffix(input, "\n") {
-		input += "\n"
-	}
-
-	// Call gRPC SendInput with 10s timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
-	defer cancel()
-
-	_, err = app.grpc.SendInput(ctx, sessionID, []byte(input))
-	if err != nil {
-		log.Printf("Error sending input to session %d: %v", sessionID, err)
-		http.Error(w, fmt.Sprintf("Failed to send input: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
-}
@@ -106,6 +106,23 @@ func (app *App) handleSGCDetail(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Strategies the start dialog can override for one session; volumes can't be patched
+	var strategies []*manmanpb.ConfigurationStrategy
+	if gameConfig != nil {
+		strategiesResp, err := app.grpc.ListConfigurationStrategies(ctx, &manmanpb.ListConfigurationStrategiesRequest{
+			GameId: gameConfig.GameId,
+		})
+		if err != nil {
+			log.Printf("Warning: failed to list strategies for game %d: %v", gameConfig.GameId, err)
+		} else {
+			for _, s := range strategiesResp.Strategies {
+				if s.StrategyType != "volume" {
+					strategies = append(strategies, s)
+				}
+			}
+		}
+	}
+
 	// Fetch sessions for this SGC
 	sessionsResp, err := app.grpc.ListSessionsWithFilters(ctx, &manmanpb.ListSessionsRequest{
 		ServerGameConfigId: sgc.ServerGameConfigId,
@@ -175,6 +192,11 @@ func (app *App) handleSGCDetail(w http.ResponseWriter, r *http.Request) {
 		recentBackups = []*manmanpb.Backup{}
 	}
 
+	schedules, err := app.grpc.ListSGCSchedules(ctx, sgcID)
+	if err != nil {
+		log.Printf("Warning: failed to list schedules for SGC %d: %v", sgcID, err)
+	}
+
 	// Convert library attachments to templ format
 	var templLibAttachments []pages.LibraryAttachment
 	for _, att := range libraryAttachments {
@@ -214,6 +236,36 @@ func (app *App) handleSGCDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Grants are only listable by admins
+	var grants []*manmanpb.SGCGrant
+	if components.IsAdmin(layoutData.Access) {
+		grants, err = app.grpc.ListSGCGrants(ctx, sgcID)
+		if err != nil {
+			log.Printf("Warning: failed to list grants for SGC %d: %v", sgcID, err)
+		}
+	}
+
+	var activity []*manmanpb.AuditEvent
+	activityResp, err := app.grpc.ListAuditEvents(ctx, &manmanpb.ListAuditEventsRequest{
+		EntityKey: "server_game_config_id",
+		EntityId:  sgcID,
+		PageSize:  10,
+	})
+	if err != nil {
+		log.Printf("Warning: failed to list activity for SGC %d: %v", sgcID, err)
+	} else {
+		activity = activityResp.Events
+	}
+
+	// Migration targets are picked by admins only
+	var servers []*manmanpb.Server
+	if components.IsAdmin(layoutData.Access) {
+		servers, err = app.grpc.ListServers(ctx)
+		if err != nil {
+			log.Printf("Warning: failed to list servers for migration: %v", err)
+		}
+	}
+
 	pageData := pages.SGCDetailPageData{
 		Layout:             layoutData,
 		SGC:                sgc,
@@ -225,6 +277,11 @@ func (app *App) handleSGCDetail(w http.ResponseWriter, r *http.Request) {
 		PendingCount:       pendingCount,
 		BackupConfigs:      templBackupConfigs,
 		RecentBackups:      recentBackups,
+		Schedules:          schedules,
+		Grants:             grants,
+		Activity:           activity,
+		Servers:            servers,
+		Strategies:         strategies,
 	}
 
 	RenderTempl(w, r, fmt.Sprintf("SGC %d", sgcID), pages.SGCDetail(pageData))
@@ -441,6 +498,124 @@ func (app *App) handleSGCRemoveLibrary(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, redirectURL, http.StatusSeeOther)
 }
 
+func (app *App) handleSGCGrantCreate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	sgcID, err := strconv.ParseInt(r.FormValue("sgc_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid sgc_id", http.StatusBadRequest)
+		return
+	}
+	subject := strings.TrimSpace(r.FormValue("subject"))
+	if subject == "" {
+		http.Error(w, "Missing subject", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if _, err := app.grpc.CreateSGCGrant(ctx, sgcID, subject, strings.TrimSpace(r.FormValue("note"))); err != nil {
+		log.Printf("Error granting %s access to SGC %d: %v", subject, sgcID, err)
+		http.Error(w, "Failed to create grant", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/sgc/%d", sgcID), http.StatusSeeOther)
+}
+
+func (app *App) handleSGCMigrate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	sgcID, err := strconv.ParseInt(r.FormValue("sgc_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid sgc_id", http.StatusBadRequest)
+		return
+	}
+	// Empty means let the API place it
+	var targetServerID int64
+	if v := r.FormValue("target_server_id"); v != "" {
+		targetServerID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid target_server_id", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	if _, err := app.grpc.MigrateServerGameConfig(ctx, sgcID, targetServerID, r.FormValue("start_session") == "on"); err != nil {
+		log.Printf("Error migrating SGC %d to server %d: %v", sgcID, targetServerID, err)
+		http.Error(w, fmt.Sprintf("Failed to start migration: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/sgc/%d", sgcID), http.StatusSeeOther)
+}
+
+// handleSGCMigrations renders an SGC's migrations; the detail page polls it while one is running
+func (app *App) handleSGCMigrations(w http.ResponseWriter, r *http.Request) {
+	sgcID, err := strconv.ParseInt(r.URL.Query().Get("sgc_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid sgc_id", http.StatusBadRequest)
+		return
+	}
+
+	migrations, err := app.grpc.ListSGCMigrations(r.Context(), sgcID)
+	if err != nil {
+		log.Printf("Error listing migrations for SGC %d: %v", sgcID, err)
+		http.Error(w, "Failed to list migrations", http.StatusInternalServerError)
+		return
+	}
+
+	pages.SGCMigrationList(sgcID, migrations).Render(r.Context(), w)
+}
+
+func (app *App) handleSGCGrantDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	sgcID, err := strconv.ParseInt(r.FormValue("sgc_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid sgc_id", http.StatusBadRequest)
+		return
+	}
+	grantID, err := strconv.ParseInt(r.FormValue("grant_id"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid grant_id", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if err := app.grpc.DeleteSGCGrant(ctx, grantID); err != nil {
+		log.Printf("Error deleting grant %d: %v", grantID, err)
+		http.Error(w, "Failed to delete grant", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/sgc/%d", sgcID), http.StatusSeeOther)
+}
+
 // computeLibraryAttachments fetches and enriches library attachment data with computed paths
 func (app *App) computeLibraryAttachments(ctx context.Context, sgcID, configID int64) ([]*SGCLibraryAttachment, error) {
 	// Fetch SGC library attachments (with override data)
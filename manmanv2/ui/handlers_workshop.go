@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"html"
 	"log"
 	"net/http"
 	"sort"
@@ -452,7 +454,7 @@ func (app *App) handleFetchAddonMetadata(w http.ResponseWriter, r *http.Request)
 		log.Printf("Error fetching metadata: %v", err)
 		w.Header().Set("Content-Type", "text/html")
 		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`<div style="padding:10px;background:#fef2f2;color:#991b1b;border-radius:4px;margin-top:10px;border:1px solid #fecaca;">Failed to fetch from Steam. Check the Workshop ID and Game.</div>`))
+		w.Write([]byte(`<div style="padding:10px;background:#fef2f2;color:#991b1b;border-radius:4px;margin-top:10px;border:1px solid #fecaca;">Failed to fetch addon metadata. Check the addon ID and Game.</div>`))
 		return
 	}
 
@@ -472,6 +474,20 @@ func (app *App) handleFetchAddonMetadata(w http.ResponseWriter, r *http.Request)
 	}
 	fileSizeBytesStr := strconv.FormatInt(addon.FileSizeBytes, 10)
 
+	// Mod platforms can narrow which file is installed
+	versionFields := ""
+	if platformType == "modrinth" || platformType == "curseforge" {
+		versionFields = `<div>
+<label style="font-size:12px;font-weight:600;color:#374151;display:block;margin-bottom:3px;">Game Version <span style="font-weight:400;color:#6b7280;">(optional)</span></label>
+<input type="text" name="game_version" placeholder="e.g. 1.20.1" style="width:100%;padding:7px 10px;border:1px solid #d1d5db;border-radius:5px;font-size:14px;">
+</div>
+<div>
+<label style="font-size:12px;font-weight:600;color:#374151;display:block;margin-bottom:3px;">Loader <span style="font-weight:400;color:#6b7280;">(optional)</span></label>
+<input type="text" name="loader" placeholder="e.g. fabric" style="width:100%;padding:7px 10px;border:1px solid #d1d5db;border-radius:5px;font-size:14px;">
+</div>
+`
+	}
+
 	presetOptions := ""
 	if len(presets) != 1 {
 		presetOptions = `<option value="0">— none —</option>`
@@ -495,6 +511,7 @@ func (app *App) handleFetchAddonMetadata(w http.ResponseWriter, r *http.Request)
 <input type="hidden" name="platform_type" value="` + platformType + `">
 <input type="hidden" name="file_size_bytes" value="` + fileSizeBytesStr + `">
 <input type="hidden" name="is_collection" value="` + isCollectionStr + `">
+<input type="hidden" name="metadata" value="` + html.EscapeString(addon.Metadata) + `">
 <div>
 <label style="font-size:12px;font-weight:600;color:#374151;display:block;margin-bottom:3px;">Name</label>
 <input type="text" name="name" value="` + addon.Name + `" required style="width:100%;padding:7px 10px;border:1px solid #d1d5db;border-radius:5px;font-size:14px;">
@@ -515,7 +532,7 @@ func (app *App) handleFetchAddonMetadata(w http.ResponseWriter, r *http.Request)
 <label style="font-size:12px;font-weight:600;color:#374151;display:block;margin-bottom:3px;">Installation Path <span style="font-weight:400;color:#6b7280;">(overrides preset)</span></label>
 <input type="text" name="installation_path" placeholder="e.g. /serverfiles/game/addons" style="width:100%;padding:7px 10px;border:1px solid #d1d5db;border-radius:5px;font-size:14px;">
 </div>
-<div style="grid-column:span 2;display:flex;justify-content:flex-end;gap:8px;margin-top:4px;">
+` + versionFields + `<div style="grid-column:span 2;display:flex;justify-content:flex-end;gap:8px;margin-top:4px;">
 <button type="submit" class="btn btn-primary">Save Addon</button>
 </div>
 </form>
@@ -556,7 +573,13 @@ func (app *App) handleCreateAddon(w http.ResponseWriter, r *http.Request) {
 		presetID, _ = strconv.ParseInt(presetIDStr, 10, 64)
 	}
 
-	addon, err := app.grpc.CreateAddon(ctx, gameID, workshopID, platformType, name, description, fileSizeBytes, isCollection, installationPath, presetID)
+	metadata, err := addonFormMetadata(r.FormValue("metadata"), r.FormValue("game_version"), r.FormValue("loader"))
+	if err != nil {
+		http.Error(w, "Invalid metadata", http.StatusBadRequest)
+		return
+	}
+
+	addon, err := app.grpc.CreateAddon(ctx, gameID, workshopID, platformType, name, description, fileSizeBytes, isCollection, installationPath, presetID, metadata)
 	if err != nil {
 		log.Printf("Error creating addon: %v", err)
 		http.Error(w, "Failed to create addon", http.StatusInternalServerError)
@@ -567,6 +590,28 @@ func (app *App) handleCreateAddon(w http.ResponseWriter, r *http.Request) {
 	http.Redirect(w, r, "/workshop/addon?addon_id="+addonIDStr, http.StatusSeeOther)
 }
 
+// addonFormMetadata adds the optional version filters from the addon form to the metadata
+// its platform returned when it was fetched
+func addonFormMetadata(fetched, gameVersion, loader string) (string, error) {
+	if gameVersion == "" && loader == "" {
+		return fetched, nil
+	}
+	metadata := make(map[string]interface{})
+	if fetched != "" {
+		if err := json.Unmarshal([]byte(fetched), &metadata); err != nil {
+			return "", err
+		}
+	}
+	if gameVersion != "" {
+		metadata["game_version"] = gameVersion
+	}
+	if loader != "" {
+		metadata["loader"] = loader
+	}
+	b, err := json.Marshal(metadata)
+	return string(b), err
+}
+
 func (app *App) handleUpdateAddonDetails(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -15,7 +15,6 @@ import (
 
 	"github.com/whale-net/everything/libs/go/db"
 	"github.com/whale-net/everything/libs/go/grpcauth"
-	"github.com/whale-net/everything/libs/go/grpcclient"
 	"github.com/whale-net/everything/libs/go/htmxauth"
 	"github.com/whale-net/everything/libs/go/htmxbase"
 	"github.com/whale-net/everything/libs/go/logging"
@@ -45,9 +44,6 @@ type Config struct {
 	// Control API (gRPC)
 	ControlAPIURL string
 
-	// Log Processor (gRPC)
-	LogProcessorURL string
-
 	// gRPC auth mode for forwarding user tokens
 	GRPCAuthMode string
 
@@ -67,7 +63,6 @@ func LoadConfig() *Config {
 		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URI", "http://localhost:8000/auth/callback"),
 		SessionSecret:    getEnv("SECRET_KEY", "dev-secret-key-change-in-production"),
 		ControlAPIURL:    getEnv("CONTROL_API_URL", "control-api-dev-service:50051"),
-		LogProcessorURL:  getEnv("LOG_PROCESSOR_URL", "log-processor:50053"),
 		GRPCAuthMode:     strings.ToLower(getEnv("GRPC_AUTH_MODE", "none")),
 		DatabaseURL:      getEnv("PG_DATABASE_URL", ""),
 	}
@@ -82,11 +77,11 @@ func getEnv(key, defaultValue string) string {
 
 // App holds the application state
 type App struct {
-	config       *Config
-	auth         *htmxauth.Authenticator
-	grpc         *ControlClient
-	logProcessor manmanpb.LogProcessorClient
-	userAuthOpt  grpc.DialOption
+	config      *Config
+	auth        *htmxauth.Authenticator
+	grpc        *ControlClient
+	consoles    *consoleRegistry
+	userAuthOpt grpc.DialOption
 }
 
 // NewApp creates a new application instance
@@ -148,19 +143,12 @@ func NewApp(ctx context.Context, config *Config) (*App, error) {
 		return nil, fmt.Errorf("failed to initialize gRPC client: %w", err)
 	}
 
-	// Initialize log-processor gRPC client
-	logProcessorConn, err := grpcclient.NewClient(ctx, config.LogProcessorURL, userAuthOpt)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to log-processor: %w", err)
-	}
-	logProcessorClient := manmanpb.NewLogProcessorClient(logProcessorConn.GetConnection())
-
 	return &App{
-		config:       config,
-		auth:         auth,
-		grpc:         grpcClient,
-		logProcessor: logProcessorClient,
-		userAuthOpt:  userAuthOpt,
+		config:      config,
+		auth:        auth,
+		grpc:        grpcClient,
+		consoles:    newConsoleRegistry(),
+		userAuthOpt: userAuthOpt,
 	}, nil
 }
 
@@ -257,9 +245,9 @@ func (app *App) setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/sessions/start", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSessionStart)))
 	mux.HandleFunc("/api/sessions/check-active", app.auth.RequireAuthFunc(app.withAccessToken(app.handleCheckActiveSession)))
 	mux.HandleFunc("/api/sessions/historical-logs", app.auth.RequireAuthFunc(app.withAccessToken(app.handleHistoricalLogs)))
-	mux.HandleFunc("/api/sessions/", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSessionStdin)))
 
-	// Note: Log streaming endpoint is handled by handleSessionDetail which routes to handleSessionLogsStream
+	// Note: the console endpoints (/sessions/{id}/console/stream and /input) are handled by
+	// handleSessionDetail, which routes to handleSessionConsoleStream and handleSessionConsoleInput
 
 	// Protected routes - Games
 	mux.HandleFunc("/games", app.auth.RequireAuthFunc(app.withAccessToken(app.handleGames)))
@@ -277,6 +265,9 @@ func (app *App) setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/servers", app.auth.RequireAuthFunc(app.withAccessToken(app.handleServers)))
 	mux.HandleFunc("/servers/", app.auth.RequireAuthFunc(app.withAccessToken(app.handleServerDetail)))
 
+	// Protected routes - Activity (audit log)
+	mux.HandleFunc("/activity", app.auth.RequireAuthFunc(app.withAccessToken(app.handleActivity)))
+
 	// Protected routes - Workshop
 	mux.HandleFunc("/workshop/library", app.auth.RequireAuthFunc(app.withAccessToken(app.handleWorkshopLibrary)))
 	mux.HandleFunc("/workshop/search", app.auth.RequireAuthFunc(app.withAccessToken(app.handleWorkshopSearch)))
@@ -305,6 +296,10 @@ func (app *App) setupRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("/sgc/", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCRoutes)))
 	mux.HandleFunc("/sgc/add-library", app.auth.RequireAuthFunc(app.withAccessToken(app.handleAddLibraryToSGC)))
 	mux.HandleFunc("/sgc/remove-library", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCRemoveLibrary)))
+	mux.HandleFunc("/sgc/grants/create", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCGrantCreate)))
+	mux.HandleFunc("/sgc/grants/delete", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCGrantDelete)))
+	mux.HandleFunc("/sgc/migrate", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCMigrate)))
+	mux.HandleFunc("/sgc/api/migrations", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCMigrations)))
 	mux.HandleFunc("/sgc/api/available-libraries", app.auth.RequireAuthFunc(app.withAccessToken(app.handleSGCAvailableLibraries)))
 
 	// Backup config management
@@ -457,6 +452,11 @@ func (app *App) buildTemplLayoutData(r *http.Request, title, active string, user
 
 	selectedServer := app.getSelectedServer(r, servers)
 
+	access, err := app.grpc.GetCallerAccess(r.Context())
+	if err != nil {
+		log.Printf("Warning: failed to get caller access for layout: %v", err)
+	}
+
 	return components.LayoutData{
 		Title:          title,
 		Active:         active,
@@ -464,5 +464,6 @@ func (app *App) buildTemplLayoutData(r *http.Request, title, active string, user
 		Servers:        servers,
 		SelectedServer: selectedServer,
 		Breadcrumbs:    breadcrumbs,
+		Access:         access,
 	}, nil
 }
@@ -1,8 +1,14 @@
 package pages
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
+
+	manmanpb "github.com/whale-net/everything/manmanv2/protos"
+	"github.com/whale-net/everything/manmanv2/ui/components"
 )
 
 func timeAgo(timestamp int64) string {
@@ -33,3 +39,380 @@ func timeAgo(timestamp int64) string {
 		return fmt.Sprintf("%d days ago", days)
 	}
 }
+
+// restartPolicySummary describes an SGC's crash restart policy in one line
+func restartPolicySummary(policy *manmanpb.RestartPolicy) string {
+	if policy == nil || policy.Mode == "" || policy.Mode == "never" {
+		return "Never restart"
+	}
+	summary := "Always restart"
+	if policy.Mode == "on-failure" {
+		summary = "Restart on failure"
+		if policy.MaxAttempts > 0 {
+			summary += fmt.Sprintf(", up to %d attempts", policy.MaxAttempts)
+		}
+	}
+	if policy.BackoffSeconds > 0 {
+		summary += fmt.Sprintf(", backoff from %ds", policy.BackoffSeconds)
+	}
+	if policy.MaxCrashesInWindow > 0 && policy.CrashWindowSeconds > 0 {
+		summary += fmt.Sprintf(", stop after %d crashes in %ds", policy.MaxCrashesInWindow, policy.CrashWindowSeconds)
+	}
+	return summary
+}
+
+// idleShutdownSummary describes an SGC's idle shutdown rule in one line
+func idleShutdownSummary(rule *manmanpb.IdleShutdown) string {
+	if rule == nil || rule.IdleMinutes <= 0 {
+		return "Never"
+	}
+	summary := fmt.Sprintf("After %d minutes with no players", rule.IdleMinutes)
+	if rule.PlayerCount != nil {
+		summary += fmt.Sprintf(" (%s probe)", rule.PlayerCount.Type)
+	}
+	return summary
+}
+
+// addonUpdatePolicySummary describes what happens when an SGC's installed addons are updated
+func addonUpdatePolicySummary(policy string) string {
+	switch policy {
+	case "off":
+		return "Ignored"
+	case "reinstall":
+		return "Notify, then reinstall when the session stops"
+	}
+	return "Notify"
+}
+
+// consoleCompletions returns the commands the session console completes with Tab as a
+// JSON array: each enabled action's command template up to its first parameter
+func consoleCompletions(actions []*manmanpb.ActionDefinition) string {
+	seen := map[string]bool{}
+	completions := []string{}
+	for _, a := range actions {
+		if !a.Enabled {
+			continue
+		}
+		cmd, _, hasParams := strings.Cut(a.CommandTemplate, "{{")
+		cmd = strings.TrimSpace(cmd)
+		if cmd != "" && hasParams {
+			cmd += " "
+		}
+		if cmd != "" && !seen[cmd] {
+			seen[cmd] = true
+			completions = append(completions, cmd)
+		}
+	}
+	sort.Strings(completions)
+	out, _ := json.Marshal(completions)
+	return string(out)
+}
+
+// imageStatusSummary describes an SGC's image digests as last reported by its host
+func imageStatusSummary(s *manmanpb.ImageStatus) string {
+	summary := s.Image
+	if s.LocalDigest != "" {
+		summary += " @ " + shortChecksum(strings.TrimPrefix(s.LocalDigest, "sha256:"))
+	}
+	switch {
+	case s.Error != "":
+		summary += " (check failed: " + s.Error + ")"
+	case s.UpdateAvailable:
+		summary += fmt.Sprintf(" (registry has %s)", shortChecksum(strings.TrimPrefix(s.RemoteDigest, "sha256:")))
+	default:
+		summary += " (up to date)"
+	}
+	return summary
+}
+
+// scheduleSummary describes an SGC schedule's crons and next action in one line
+func scheduleSummary(s *manmanpb.SGCSchedule) string {
+	summary := ""
+	if s.StartCron != "" {
+		summary = fmt.Sprintf("start %q", s.StartCron)
+	}
+	if s.StopCron != "" {
+		if summary != "" {
+			summary += ", "
+		}
+		summary += fmt.Sprintf("stop %q", s.StopCron)
+		if s.BackupConfigId > 0 {
+			summary += " after backup"
+		}
+	}
+	summary += " " + s.Timezone
+	if !s.Enabled {
+		return summary + " (disabled)"
+	}
+	if s.NextActionAt > 0 {
+		summary += fmt.Sprintf("; next %s at %s", s.NextAction, time.Unix(s.NextActionAt, 0).UTC().Format("2006-01-02 15:04 MST"))
+	}
+	return summary
+}
+
+// playerCountSummary shows a session's last reported player count (out of max players, on its
+// map, when a status query reports them) and how long it has been empty
+func playerCountSummary(s *manmanpb.Session) string {
+	summary := fmt.Sprintf("%d", s.GetPlayerCount())
+	if s.MaxPlayers > 0 {
+		summary += fmt.Sprintf("/%d", s.MaxPlayers)
+	}
+	if s.MapName != "" {
+		summary += " on " + s.MapName
+	}
+	if s.IdleSince > 0 {
+		summary += fmt.Sprintf(" (empty since %s)", timeAgo(s.IdleSince))
+	}
+	return summary
+}
+
+// onlinePlayerCount counts the players in a roster who haven't left yet
+func onlinePlayerCount(players []*manmanpb.PlayerSession) int {
+	online := 0
+	for _, p := range players {
+		if p.LeftAt == 0 {
+			online++
+		}
+	}
+	return online
+}
+
+// concurrencyBarHeight scales a concurrency bucket against the peak as a CSS height,
+// keeping non-empty buckets visible
+func concurrencyBarHeight(players, peak int32) string {
+	if players <= 0 || peak <= 0 {
+		return "0%"
+	}
+	pct := int(players) * 100 / int(peak)
+	if pct < 2 {
+		pct = 2
+	}
+	return fmt.Sprintf("%d%%", pct)
+}
+
+// operableSGCOptions keeps the SGCs the caller may start sessions on
+func operableSGCOptions(access *manmanpb.CallerAccess, options []SGCDisplayInfo) []SGCDisplayInfo {
+	var operable []SGCDisplayInfo
+	for _, o := range options {
+		if components.CanOperate(access, o.ServerGameConfigId) {
+			operable = append(operable, o)
+		}
+	}
+	return operable
+}
+
+// auditActor names who made an audited call
+func auditActor(e *manmanpb.AuditEvent) string {
+	if e.Username != "" {
+		return e.Username
+	}
+	if e.Subject != "" {
+		return e.Subject
+	}
+	return "anonymous"
+}
+
+// auditEntityLink is one target of an audited call, linked when the UI has a page for it
+type auditEntityLink struct {
+	Label string
+	URL   string
+}
+
+// auditEntityPages maps entity keys to their detail page prefixes
+var auditEntityPages = map[string]string{
+	"session_id":            "/sessions/",
+	"server_game_config_id": "/sgc/",
+	"game_id":               "/games/",
+	"server_id":             "/servers/",
+}
+
+// auditEntityLinks lists an event's entities, ordered by key
+func auditEntityLinks(e *manmanpb.AuditEvent) []auditEntityLink {
+	keys := make([]string, 0, len(e.Entities))
+	for k := range e.Entities {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	links := make([]auditEntityLink, len(keys))
+	for i, k := range keys {
+		id := e.Entities[k]
+		links[i] = auditEntityLink{Label: fmt.Sprintf("%s %d", strings.TrimSuffix(k, "_id"), id)}
+		if prefix, ok := auditEntityPages[k]; ok {
+			links[i].URL = fmt.Sprintf("%s%d", prefix, id)
+		}
+	}
+	return links
+}
+
+// auditChange is one field an audited update changed
+type auditChange struct {
+	Field  string
+	Before string
+	After  string
+}
+
+// auditChanges lists an event's changed fields, ordered by name
+func auditChanges(e *manmanpb.AuditEvent) []auditChange {
+	var changes map[string]struct {
+		Before interface{} `json:"before"`
+		After  interface{} `json:"after"`
+	}
+	if e.ChangesJson == "" || json.Unmarshal([]byte(e.ChangesJson), &changes) != nil {
+		return nil
+	}
+	show := func(v interface{}) string {
+		if v == nil {
+			return "unset"
+		}
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+	out := make([]auditChange, 0, len(changes))
+	for field, c := range changes {
+		out = append(out, auditChange{Field: field, Before: show(c.Before), After: show(c.After)})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// migrationInFlight reports whether any migration is still running, so its list keeps polling
+func migrationInFlight(migrations []*manmanpb.SGCMigration) bool {
+	for _, m := range migrations {
+		if m.Status != "completed" && m.Status != "failed" {
+			return true
+		}
+	}
+	return false
+}
+
+// migrationVolumeSummary describes how far a volume has got through a migration
+func migrationVolumeSummary(v *manmanpb.SGCMigrationVolume) string {
+	switch {
+	case v.Error != "":
+		return "failed: " + v.Error
+	case v.Restored:
+		return "restored"
+	case v.BackupId == 0:
+		return "nothing to copy"
+	case v.BackupStatus != "":
+		return "backup " + v.BackupStatus
+	default:
+		return "waiting"
+	}
+}
+
+// patchLayerTitle names the patch a configuration layer applied; a patch without an ID
+// is an override being previewed
+func patchLayerTitle(layer *manmanpb.PatchLayer) string {
+	name := fmt.Sprintf("patch %d", layer.PatchId)
+	if layer.PatchId == 0 {
+		name = "previewed override"
+	}
+	return fmt.Sprintf("%s, %s, order %d", name, layer.PatchFormat, layer.PatchOrder)
+}
+
+// shortChecksum abbreviates a hex digest the way git abbreviates commits
+func shortChecksum(sum string) string {
+	if len(sum) > 12 {
+		return sum[:12]
+	}
+	return sum
+}
+
+// Resource graphs are drawn in a fixed viewBox and stretched to their container
+const (
+	metricsGraphWidth  = 300
+	metricsGraphHeight = 60
+)
+
+// metricsPolyline plots values at timestamps across [start, end] as SVG polyline points,
+// scaled so max reaches the top of the graph
+func metricsPolyline(timestamps []int64, values []float64, start, end int64, max float64) string {
+	if end <= start || max <= 0 {
+		return ""
+	}
+	points := make([]string, 0, len(values))
+	for i, v := range values {
+		x := float64(timestamps[i]-start) / float64(end-start) * metricsGraphWidth
+		y := metricsGraphHeight - v/max*metricsGraphHeight
+		if y < 0 {
+			y = 0
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+	return strings.Join(points, " ")
+}
+
+// cpuPolyline plots a session's average CPU, scaled to its peak or one core, whichever
+// is more
+func cpuPolyline(series *manmanpb.SessionMetricsSeries, start, end int64) string {
+	timestamps := make([]int64, len(series.Points))
+	values := make([]float64, len(series.Points))
+	max := 100.0
+	for i, p := range series.Points {
+		timestamps[i], values[i] = p.Timestamp, p.CpuPercentAvg
+		if p.CpuPercentMax > max {
+			max = p.CpuPercentMax
+		}
+	}
+	return metricsPolyline(timestamps, values, start, end, max)
+}
+
+// memoryPolyline plots a session's average memory, scaled to its limit
+func memoryPolyline(series *manmanpb.SessionMetricsSeries, start, end int64) string {
+	timestamps := make([]int64, len(series.Points))
+	values := make([]float64, len(series.Points))
+	var max float64
+	for i, p := range series.Points {
+		timestamps[i], values[i] = p.Timestamp, float64(p.MemoryBytesAvg)
+		if float64(p.MemoryLimitBytes) > max {
+			max = float64(p.MemoryLimitBytes)
+		}
+		if float64(p.MemoryBytesMax) > max {
+			max = float64(p.MemoryBytesMax)
+		}
+	}
+	return metricsPolyline(timestamps, values, start, end, max)
+}
+
+// latestSessionMetrics summarizes a session's newest point in one line
+func latestSessionMetrics(series *manmanpb.SessionMetricsSeries) string {
+	if len(series.Points) == 0 {
+		return "-"
+	}
+	p := series.Points[len(series.Points)-1]
+	summary := fmt.Sprintf("CPU %.0f%%, memory %s", p.CpuPercentAvg, formatBytes(p.MemoryBytesAvg))
+	if p.MemoryLimitBytes > 0 {
+		summary += " of " + formatBytes(p.MemoryLimitBytes)
+	}
+	return summary + fmt.Sprintf(", net %s/s in %s/s out, disk %s/s read %s/s write",
+		formatBytes(int64(p.NetworkRxBytesPerSec)), formatBytes(int64(p.NetworkTxBytesPerSec)),
+		formatBytes(int64(p.BlockReadBytesPerSec)), formatBytes(int64(p.BlockWriteBytesPerSec)))
+}
+
+// formatBytes renders a byte count with a binary unit, e.g. 1.5 GiB
+func formatBytes(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
+
+// capacityBarWidth is used as a share of total for a capacity bar, capped at 100%
+func capacityBarWidth(used, total float64) string {
+	if used <= 0 || total <= 0 {
+		return "0%"
+	}
+	pct := int(used * 100 / total)
+	if pct > 100 {
+		pct = 100
+	}
+	return fmt.Sprintf("%d%%", pct)
+}
@@ -0,0 +1,91 @@
+package pages
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/a-h/templ"
+
+	"github.com/whale-net/everything/manmanv2/api/auth"
+	manmanpb "github.com/whale-net/everything/manmanv2/protos"
+	"github.com/whale-net/everything/manmanv2/ui/components"
+)
+
+// Role gating is presentation only (the API enforces it), but a viewer should not be
+// offered controls every click of which is refused. These render the pages that branch on
+// CallerAccess for each kind of caller and check which controls come out.
+
+const gatedSGCID = 42
+
+func accessAs(level auth.Level, grantedSGCs ...int64) *manmanpb.CallerAccess {
+	return &manmanpb.CallerAccess{Subject: "someone", Role: level.String(), OperatorSgcIds: grantedSGCs}
+}
+
+func renderPage(t *testing.T, comp templ.Component) string {
+	t.Helper()
+	var buf strings.Builder
+	if err := comp.Render(context.Background(), &buf); err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	return buf.String()
+}
+
+func renderSGCDetail(t *testing.T, access *manmanpb.CallerAccess) string {
+	t.Helper()
+	return renderPage(t, SGCDetail(SGCDetailPageData{
+		Layout: components.LayoutData{Title: "SGC", Access: access},
+		SGC:    &manmanpb.ServerGameConfig{ServerGameConfigId: gatedSGCID, ServerId: 1, Status: "active"},
+		Sessions: []*manmanpb.Session{
+			{SessionId: 7, ServerGameConfigId: gatedSGCID, Status: "running"},
+		},
+	}))
+}
+
+// Markers for the controls each page gates
+var (
+	operatorControls = []string{`action="/sessions/start"`, "Start with Overrides", "⚠️ Danger Zone</h2>"}
+	adminControls    = []string{`action="/sgc/grants/create"`, `action="/sgc/migrate"`}
+)
+
+func TestSGCDetailRoleGating(t *testing.T) {
+	tests := []struct {
+		name          string
+		access        *manmanpb.CallerAccess
+		canOperate    bool
+		canAdminister bool
+	}{
+		{name: "access unknown", access: nil},
+		{name: "no role", access: accessAs(auth.LevelNone)},
+		{name: "viewer", access: accessAs(auth.LevelViewer)},
+		{name: "viewer granted another SGC", access: accessAs(auth.LevelViewer, gatedSGCID+1)},
+		{name: "viewer granted this SGC", access: accessAs(auth.LevelViewer, gatedSGCID), canOperate: true},
+		{name: "operator", access: accessAs(auth.LevelOperator), canOperate: true},
+		{name: "admin", access: accessAs(auth.LevelAdmin), canOperate: true, canAdminister: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := renderSGCDetail(t, tt.access)
+			for _, marker := range operatorControls {
+				if got := strings.Contains(html, marker); got != tt.canOperate {
+					t.Errorf("operator control %q rendered = %v, want %v", marker, got, tt.canOperate)
+				}
+			}
+			for _, marker := range adminControls {
+				if got := strings.Contains(html, marker); got != tt.canAdminister {
+					t.Errorf("admin control %q rendered = %v, want %v", marker, got, tt.canAdminister)
+				}
+			}
+		})
+	}
+}
+
+func TestGamesRoleGating(t *testing.T) {
+	for _, level := range []auth.Level{auth.LevelNone, auth.LevelViewer, auth.LevelOperator, auth.LevelAdmin} {
+		html := renderPage(t, Games(components.LayoutData{Title: "Games", Access: accessAs(level)}, nil))
+		want := level == auth.LevelAdmin
+		if got := strings.Contains(html, `href="/games/new"`); got != want {
+			t.Errorf("%q: create game link rendered = %v, want %v", level, got, want)
+		}
+	}
+}
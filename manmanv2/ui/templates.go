@@ -650,7 +650,7 @@ func formatDuration(value int, unit string) string {
 
 func statusBadge(status string) string {
 	switch status {
-	case "online", "active", "running", "completed":
+	case "online", "active", "running", "ready", "completed":
 		return "bg-green-100 text-green-800 dark:bg-green-900 dark:text-green-200"
 	case "offline", "inactive", "stopped":
 		return "bg-slate-100 text-slate-800 dark:bg-slate-700 dark:text-slate-300"